@@ -39,6 +39,7 @@ export type inputType = {
     resourceConf?: util.ResourceConf,
     pprofHeapAllocThresholdMegaBytes?: number,
     tlsCertK8sSecretName: string | pulumi.Output<string>,
+    nodeInstanceRole: pulumi.Input<string>,
     envVars?: pulumi.Input<k8s.types.input.core.v1.EnvVar>[],
 }
 
@@ -59,6 +60,29 @@ export const setup = async (input: inputType) => {
 
     // Create a private ECR repository.
     const prefix = util.getPrefix(util.Scope.MOTHERSHIP, input.mothershipId)
+
+    // the bridge keeps the api keys it uses to call the data plane of the tiers in secrets manager
+    const secretsPolicy = new aws.iam.Policy(`${prefix}-bridge-server-secrets-policy`, {
+        namePrefix: `${prefix}-bridge-server-secrets-policy`,
+        policy: JSON.stringify({
+            Version: "2012-10-17",
+            Statement: [
+                {
+                    Effect: "Allow",
+                    Action: [
+                        "secretsmanager:CreateSecret",
+                        "secretsmanager:PutSecretValue",
+                        "secretsmanager:GetSecretValue",
+                    ],
+                    Resource: `arn:aws:secretsmanager:${input.region}:*:secret:t_*/mothership_api_key*`
+                }
+            ],
+        }),
+    }, { provider: awsProvider });
+    new aws.iam.RolePolicyAttachment(`${prefix}-bridge-server-secrets-policy-attach`, {
+        policyArn: secretsPolicy.arn,
+        role: input.nodeInstanceRole,
+    }, { provider: awsProvider });
     const repo = new aws.ecr.Repository(`${prefix}-bridge-server-repo`, {
         imageScanningConfiguration: {
             scanOnPush: true
@@ -181,7 +205,9 @@ export const setup = async (input: inputType) => {
     const linkerdPreStopDelaySecs = 1;
 
     const bridgeServerDepName = "bridge-server";
-    let envVars: pulumi.Input<k8s.types.input.core.v1.EnvVar>[] = serviceEnvs;
+    let envVars: pulumi.Input<k8s.types.input.core.v1.EnvVar>[] = serviceEnvs.concat([
+        { name: "AWS_REGION", value: input.region },
+    ]);
 
     const appDep = image.imageName.apply(() => {
         return new k8s.apps.v1.Deployment("bridge-server-deployment", {
@@ -150,6 +150,7 @@ export const setup = async (input: inputType) => {
     const appLabels = { app: name };
     const metricsPort = 2112;
     const appPort = 2425;
+    const grpcPort = 2426;
     const healthPort = 8082;
 
     const timeoutSeconds = 30;
@@ -246,6 +247,10 @@ export const setup = async (input: inputType) => {
                                     containerPort: appPort,
                                     protocol: "TCP",
                                 },
+                                {
+                                    containerPort: grpcPort,
+                                    protocol: "TCP",
+                                },
                                 {
                                     containerPort: metricsPort,
                                     protocol: "TCP",
@@ -293,7 +298,10 @@ export const setup = async (input: inputType) => {
             },
             spec: {
                 type: "ClusterIP",
-                ports: [{ port: appPort, targetPort: appPort, protocol: "TCP" }],
+                ports: [
+                    { name: "http", port: appPort, targetPort: appPort, protocol: "TCP" },
+                    { name: "grpc", port: grpcPort, targetPort: grpcPort, protocol: "TCP" },
+                ],
                 selector: appLabels,
             },
         }, { provider: k8sProvider, deleteBeforeReplace: true })
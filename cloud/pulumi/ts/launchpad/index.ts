@@ -444,6 +444,11 @@ async function setupTierWrapperFn(tierConf: TierConf, dataplane: OutputMap, plan
             name: `t_${tierId}_profilelog`,
             retention_ms: -1
         },
+        // change data capture of profiles, consumers can replay a week of changes
+        {
+            name: `t_${tierId}_profile_cdc`,
+            retention_ms: 604800000  // 7 days retention
+        },
         {
             name: `t_${tierId}_actionlog_json`,
             retention_ms: 432000000  // 5 days retention
@@ -164,6 +164,7 @@ const setupResources = async () => {
                 nodeLabels: input.bridgeServerConf?.podConf?.nodeLabels,
                 pprofHeapAllocThresholdMegaBytes: input.bridgeServerConf?.podConf?.pprofHeapAllocThresholdMegaBytes,
                 tlsCertK8sSecretName: ingressOutput.tlsK8sSecretRef,
+                nodeInstanceRole: eksOutput.instanceRole,
                 envVars: input.bridgeServerConf.envVars,
             });
 
@@ -115,6 +115,7 @@ export const setup = async (input: inputType) => {
     const appLabels = { app: name };
     const metricsPort = 2112;
     const appPort = 2425;
+    const grpcPort = 2426;
     const healthPort = 8082;
 
     const timeoutSeconds = 30;
@@ -210,6 +211,10 @@ export const setup = async (input: inputType) => {
                                 containerPort: appPort,
                                 protocol: "TCP",
                             },
+                            {
+                                containerPort: grpcPort,
+                                protocol: "TCP",
+                            },
                             {
                                 containerPort: metricsPort,
                                 protocol: "TCP",
@@ -255,7 +260,10 @@ export const setup = async (input: inputType) => {
         },
         spec: {
             type: "ClusterIP",
-            ports: [{ port: appPort, targetPort: appPort, protocol: "TCP" }],
+            ports: [
+                { name: "http", port: appPort, targetPort: appPort, protocol: "TCP" },
+                { name: "grpc", port: grpcPort, targetPort: grpcPort, protocol: "TCP" },
+            ],
             selector: appLabels,
         },
     }, { provider: k8sProvider, deleteBeforeReplace: true, dependsOn: appDep });
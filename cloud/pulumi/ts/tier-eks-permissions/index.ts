@@ -86,6 +86,17 @@ export const setup = async (input: inputType): Promise<pulumi.Output<outputType>
                         "glue:DeleteTrigger"
                     ],
                     Resource: "*"
+                },
+                {
+                    // credentials of the sources read by the native connectors of the tier
+                    Effect: "Allow",
+                    Action: [
+                        "secretsmanager:CreateSecret",
+                        "secretsmanager:PutSecretValue",
+                        "secretsmanager:GetSecretValue",
+                        "secretsmanager:DeleteSecret"
+                    ],
+                    Resource: `arn:aws:secretsmanager:${input.region}:*:secret:t_${input.tierId}/*`
                 }
             ],
         }),
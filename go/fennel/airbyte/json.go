@@ -147,6 +147,8 @@ func NewS3ConnectorConfig(src data_integration.S3) (S3ConnectorConfig, error) {
 		config.Format = NewS3ParquetConfig(src)
 	case "avro":
 		config.Format = NewS3AvroConfig()
+	case "json":
+		config.Format = NewS3JsonlConfig()
 	default:
 		config.Format = NewS3CSVConfig(src)
 	}
@@ -207,6 +209,26 @@ func NewS3AvroConfig() S3AvroFormat {
 	}
 }
 
+type S3JsonlFormat struct {
+	BlockSize               int    `json:"block_size"`
+	NewlinesInValues        bool   `json:"newlines_in_values"`
+	UnexpectedFieldBehavior string `json:"unexpected_field_behavior"`
+	FileType                string `json:"filetype"`
+}
+
+func (c S3JsonlFormat) GetFileType() string {
+	return c.FileType
+}
+
+func NewS3JsonlConfig() S3JsonlFormat {
+	return S3JsonlFormat{
+		BlockSize:               10000,
+		NewlinesInValues:        false,
+		UnexpectedFieldBehavior: "infer",
+		FileType:                "jsonl",
+	}
+}
+
 func NewS3CSVConfig(src data_integration.S3) S3CSVFormat {
 	if src.Delimiter == "" {
 		src.Delimiter = ","
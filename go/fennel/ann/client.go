@@ -0,0 +1,489 @@
+package ann
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sync"
+	"time"
+
+	"fennel/lib/aggregate"
+	"fennel/lib/ftypes"
+	"fennel/lib/hnsw"
+	hp "fennel/lib/hyperparam"
+	"fennel/lib/value"
+	"fennel/s3"
+
+	"go.uber.org/zap"
+)
+
+/*
+	Client is an embedded alternative to the milvus client for knn aggregates - indices are kept in
+	memory as HNSW graphs and persisted to a Store, so knn aggregates can be used without a Milvus
+	cluster (e.g. locally or on small tiers). A knn aggregate opts into it by setting the "backend"
+	hyper parameter to "embedded".
+
+	Rows are inserted by countaggr, which persists the indices it wrote to every persist interval,
+	and every server reloads an index when it finds that its version in the store has changed,
+	checking at most once every refresh interval. So on tiers with more than one server the store
+	should be shared (i.e. S3) and queries see inserted rows after up to the sum of the intervals.
+*/
+
+type AnnArgs struct {
+	AnnDir    string `arg:"--ann-dir,env:ANN_DIR,help:Local directory to persist embedded knn indices in, only for tiers that run on a single host"`
+	AnnBucket string `arg:"--ann-bucket,env:ANN_BUCKET,help:S3 bucket to persist embedded knn indices in"`
+	// zero persists indices after every insert and checks for newer versions on every read
+	AnnPersistInterval time.Duration `arg:"--ann-persist-interval,env:ANN_PERSIST_INTERVAL" default:"10s" json:"ann_persist_interval,omitempty"`
+	AnnRefreshInterval time.Duration `arg:"--ann-refresh-interval,env:ANN_REFRESH_INTERVAL" default:"30s" json:"ann_refresh_interval,omitempty"`
+}
+
+const (
+	MILVUS   = "milvus"
+	EMBEDDED = "embedded"
+)
+
+// same field names as milvus so that results don't depend on the backend
+const (
+	PrimaryField = `item`
+	ScoreField   = `score`
+)
+
+var supportedHyperParameters = hp.HyperParamRegistry{
+	"knn": map[string]hp.HyperParameterInfo{
+		"backend":        {Default: MILVUS, Type: reflect.String, Options: []string{MILVUS, EMBEDDED}},
+		"metric":         {Default: "ip", Type: reflect.String, Options: []string{"ip", "l2", "cosine"}},
+		"index":          {Default: "hnsw", Type: reflect.String, Options: []string{"flat", "hnsw"}},
+		"M":              {Default: 32, Type: reflect.Int, Options: nil},
+		"efConstruction": {Default: 128, Type: reflect.Int, Options: nil},
+	},
+}
+
+var knnIndexSearchParams = hp.HyperParamRegistry{
+	"flat": map[string]hp.HyperParameterInfo{},
+	"hnsw": map[string]hp.HyperParameterInfo{
+		"ef": {Default: 128, Type: reflect.Int, Options: nil},
+	},
+}
+
+type Client struct {
+	store   Store
+	args    AnnArgs
+	mu      *sync.RWMutex
+	indices map[string]*entry
+	stop    chan struct{}
+	stopped *sync.WaitGroup
+}
+
+// entry is an index loaded in memory, its fields other than idx are guarded by the mutex of the client
+type entry struct {
+	idx *hnsw.Index
+	// version is the version of the index in the store that idx is the same as, unless dirty
+	version string
+	checked time.Time
+	// dirty is set when rows were inserted in idx that are not persisted yet
+	dirty bool
+}
+
+// NewClient returns a client that persists indices in the bucket if one is set, and in the
+// local directory otherwise
+func NewClient(args AnnArgs, s3client s3.Client) (Client, error) {
+	if args.AnnBucket != "" {
+		return NewClientWithStore(NewS3Store(s3client, args.AnnBucket), args), nil
+	}
+	store, err := NewDiskStore(args.AnnDir)
+	if err != nil {
+		return Client{}, err
+	}
+	return NewClientWithStore(store, args), nil
+}
+
+func NewClientWithStore(store Store, args AnnArgs) Client {
+	c := Client{
+		store:   store,
+		args:    args,
+		mu:      &sync.RWMutex{},
+		indices: make(map[string]*entry),
+		stop:    make(chan struct{}),
+		stopped: &sync.WaitGroup{},
+	}
+	if args.AnnPersistInterval > 0 {
+		c.stopped.Add(1)
+		go c.persistLoop()
+	}
+	return c
+}
+
+// Backend returns the backend ("milvus" or "embedded") that the knn aggregate is configured to use
+func Backend(agg aggregate.Aggregate) (string, error) {
+	if len(agg.Options.HyperParameters) == 0 {
+		return MILVUS, nil
+	}
+	var params struct {
+		Backend string `json:"backend"`
+	}
+	if err := json.Unmarshal([]byte(agg.Options.HyperParameters), &params); err != nil {
+		return "", fmt.Errorf("aggregate type: knn, failed to parse aggregate tuning params: %v", err)
+	}
+	switch params.Backend {
+	case "":
+		return MILVUS, nil
+	case MILVUS, EMBEDDED:
+		return params.Backend, nil
+	default:
+		return "", fmt.Errorf("aggregate type: knn, hyperparameter backend must be one of %v", []string{MILVUS, EMBEDDED})
+	}
+}
+
+//================================================
+// Public API
+//================================================
+
+// Close persists the indices with rows that are not persisted yet
+func (c Client) Close() error {
+	close(c.stop)
+	c.stopped.Wait()
+	return c.Flush(context.Background())
+}
+
+// Flush persists the indices with rows that are not persisted yet
+func (c Client) Flush(ctx context.Context) error {
+	c.mu.Lock()
+	dirty := make(map[string]*entry)
+	for name, e := range c.indices {
+		if e.dirty {
+			dirty[name] = e
+			// rows inserted while the index is persisted mark it dirty again
+			e.dirty = false
+		}
+	}
+	c.mu.Unlock()
+	var errs []error
+	for name, e := range dirty {
+		version, err := c.save(ctx, name, e.idx)
+		c.mu.Lock()
+		if err != nil {
+			e.dirty = true
+			errs = append(errs, err)
+		} else {
+			// the process that saved the index does not need to load it back
+			e.version, e.checked = version, time.Now()
+		}
+		c.mu.Unlock()
+	}
+	if len(errs) > 0 {
+		return fmt.Errorf("failed to persist %d knn indices, first error: %w", len(errs), errs[0])
+	}
+	return nil
+}
+
+func (c Client) persistLoop() {
+	defer c.stopped.Done()
+	ticker := time.NewTicker(c.args.AnnPersistInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-c.stop:
+			return
+		case <-ticker.C:
+			if err := c.Flush(context.Background()); err != nil {
+				zap.L().Warn("failed to persist embedded knn indices", zap.Error(err))
+			}
+		}
+	}
+}
+
+func getCollectionName(aggName ftypes.AggName, tierId ftypes.RealmID) string {
+	return "t_" + fmt.Sprint(tierId) + "$" + string(aggName)
+}
+
+func (c Client) CreateKNNIndex(ctx context.Context, agg aggregate.Aggregate, tierId ftypes.RealmID) error {
+	hyperparameters, err := hp.GetHyperParameters("knn", agg.Options.HyperParameters, supportedHyperParameters)
+	if err != nil {
+		return err
+	}
+	metric, err := getMetric(hyperparameters["metric"].(string))
+	if err != nil {
+		return err
+	}
+	idx, err := hnsw.New(int(agg.Options.Dim), metric, hyperparameters["M"].(int), hyperparameters["efConstruction"].(int))
+	if err != nil {
+		return err
+	}
+	name := getCollectionName(agg.Name, tierId)
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if _, ok := c.indices[name]; ok {
+		return fmt.Errorf("knn index for aggregate %s already exists", agg.Name)
+	}
+	// the empty index is persisted right away so that every server finds the collection
+	version, err := c.save(ctx, name, idx)
+	if err != nil {
+		return err
+	}
+	c.indices[name] = &entry{idx: idx, version: version, checked: time.Now()}
+	return nil
+}
+
+func (c Client) InsertStream(ctx context.Context, agg aggregate.Aggregate, table value.List, tierId ftypes.RealmID) error {
+	if table.Len() == 0 {
+		return nil
+	}
+	name := getCollectionName(agg.Name, tierId)
+	e, err := c.entry(ctx, name)
+	if err != nil {
+		return err
+	}
+	c.mu.Lock()
+	e.dirty = true
+	c.mu.Unlock()
+	idx := e.idx
+	for i := 0; i < table.Len(); i++ {
+		rowVal, _ := table.At(i)
+		row, ok := rowVal.(value.Dict)
+		if !ok {
+			return fmt.Errorf("%s expected to be dict but found: '%v'", agg.Source, rowVal)
+		}
+		id, ok := row.Get("value")
+		if !ok || value.Types.Int.Validate(id) != nil {
+			return fmt.Errorf("%s '%v' does not have a field called 'value' with datatype of 'int'", agg.Source, rowVal)
+		}
+		v, ok := row.Get("groupkey")
+		if !ok {
+			return fmt.Errorf("%s '%v' does not have a field called 'groupkey'", agg.Source, rowVal)
+		}
+		vector, err := toVector(v)
+		if err != nil {
+			return fmt.Errorf("%s '%v' expected to have a vector or a list of numbers as 'groupkey': %w", agg.Source, rowVal, err)
+		}
+		if err = idx.Add(int64(id.(value.Int)), vector); err != nil {
+			return err
+		}
+	}
+	if c.args.AnnPersistInterval > 0 {
+		return nil
+	}
+	return c.Flush(ctx)
+}
+
+// GetNeighbors returns the topK neighbors of each of the vectors. Neighbors are found
+// approximately unless the index is 'flat' or the kwarg 'exact' is set to true
+func (c Client) GetNeighbors(ctx context.Context, agg aggregate.Aggregate, vectors []value.Value, kwarg value.Dict, tierId ftypes.RealmID) ([]value.Value, error) {
+	hyperparameters, err := hp.GetHyperParameters("knn", agg.Options.HyperParameters, supportedHyperParameters)
+	if err != nil {
+		return nil, err
+	}
+	indexType := hyperparameters["index"].(string)
+
+	var inputSp value.Dict
+	if inpParams, ok := kwarg.Get("searchParams"); !ok {
+		inputSp = value.Dict{}
+	} else if inputSp, ok = inpParams.(value.Dict); !ok {
+		return nil, fmt.Errorf("expected searchParams to be a dict but found: '%v'", inpParams)
+	}
+	searchParams, err := hp.GetHyperParametersFromMap(indexType, inputSp, knnIndexSearchParams)
+	if err != nil {
+		return nil, err
+	}
+
+	var topK int
+	if tmp, ok := kwarg.Get("topK"); !ok {
+		return nil, fmt.Errorf("Expected topK to be passed as kwarg")
+	} else {
+		t, err := getInt(tmp)
+		if err != nil {
+			return nil, err
+		}
+		topK = int(t)
+	}
+	exact := indexType == "flat"
+	if e, ok := kwarg.Get("exact"); ok {
+		b, ok := e.(value.Bool)
+		if !ok {
+			return nil, fmt.Errorf("expected exact to be a bool but found: '%v'", e)
+		}
+		exact = exact || bool(b)
+	}
+
+	e, err := c.entry(ctx, getCollectionName(agg.Name, tierId))
+	if err != nil {
+		return nil, err
+	}
+	idx := e.idx
+	allResults := make([]value.Value, len(vectors))
+	for i, v := range vectors {
+		q, err := toVector(v)
+		if err != nil {
+			return nil, fmt.Errorf("invalid query vector: %w", err)
+		}
+		var found []hnsw.Result
+		if exact {
+			found, err = idx.Exact(q, topK)
+		} else {
+			found, err = idx.Search(q, topK, searchParams["ef"].(int))
+		}
+		if err != nil {
+			return nil, err
+		}
+		var knnResult value.List
+		knnResult.Grow(len(found))
+		for _, r := range found {
+			knnResult.Append(value.NewDict(map[string]value.Value{PrimaryField: value.Int(r.ID), ScoreField: value.Double(r.Score)}))
+		}
+		allResults[i] = knnResult
+	}
+	return allResults, nil
+}
+
+// GetEmbedding returns the vectors of the given ids, nil for ids that are not in the index
+func (c Client) GetEmbedding(ctx context.Context, agg aggregate.Aggregate, keys value.List, tierId ftypes.RealmID) ([]value.Value, error) {
+	e, err := c.entry(ctx, getCollectionName(agg.Name, tierId))
+	if err != nil {
+		return nil, err
+	}
+	idx := e.idx
+	allResults := make([]value.Value, keys.Len())
+	for i := 0; i < keys.Len(); i++ {
+		idVal, _ := keys.At(i)
+		id, err := getInt(idVal)
+		if err != nil {
+			return nil, err
+		}
+		if vec, ok := idx.Get(id); ok {
+			allResults[i] = value.Vector(vec)
+		} else {
+			allResults[i] = value.Nil
+		}
+	}
+	return allResults, nil
+}
+
+func (c Client) DeleteCollection(ctx context.Context, aggName ftypes.AggName, tierId ftypes.RealmID) error {
+	name := getCollectionName(aggName, tierId)
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	delete(c.indices, name)
+	return c.store.Delete(ctx, name)
+}
+
+//================================================
+// Private helpers/interface
+//================================================
+
+// entry returns the index of the collection, loading it from the store if it is not in memory or
+// if its version in the store changed since it was loaded
+func (c Client) entry(ctx context.Context, name string) (*entry, error) {
+	c.mu.RLock()
+	e, ok := c.indices[name]
+	fresh := ok && c.fresh(e)
+	c.mu.RUnlock()
+	if fresh {
+		return e, nil
+	}
+	c.mu.Lock()
+	if e, ok = c.indices[name]; ok {
+		if c.fresh(e) {
+			c.mu.Unlock()
+			return e, nil
+		}
+		// other readers keep using the loaded index while this one checks the store
+		e.checked = time.Now()
+	}
+	c.mu.Unlock()
+
+	version, err := c.store.Version(ctx, name)
+	if err != nil {
+		if ok {
+			zap.L().Warn("failed to check version of knn index, using the loaded one", zap.String("index", name), zap.Error(err))
+			return e, nil
+		}
+		return nil, fmt.Errorf("failed to load knn index %s: %w", name, err)
+	}
+	if ok && version == e.version {
+		return e, nil
+	}
+	if version == "" {
+		// the collection was deleted by another process
+		c.mu.Lock()
+		if cur, ok := c.indices[name]; ok && !cur.dirty {
+			delete(c.indices, name)
+		}
+		c.mu.Unlock()
+		return nil, fmt.Errorf("knn index %s does not exist", name)
+	}
+	data, err := c.store.Load(ctx, name)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load knn index %s: %w", name, err)
+	}
+	if data == nil {
+		return nil, fmt.Errorf("knn index %s does not exist", name)
+	}
+	idx, err := hnsw.Load(data)
+	if err != nil {
+		return nil, err
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	// rows inserted in this process while the index was loaded are never replaced
+	if cur, ok := c.indices[name]; ok && cur.dirty {
+		return cur, nil
+	}
+	e = &entry{idx: idx, version: version, checked: time.Now()}
+	c.indices[name] = e
+	return e, nil
+}
+
+// fresh returns whether the entry can be used without checking the store, must be called with
+// the mutex held
+func (c Client) fresh(e *entry) bool {
+	return e.dirty || time.Since(e.checked) < c.args.AnnRefreshInterval
+}
+
+// save persists the index and returns its version in the store
+func (c Client) save(ctx context.Context, name string, idx *hnsw.Index) (string, error) {
+	data, err := idx.MarshalBinary()
+	if err != nil {
+		return "", err
+	}
+	if err = c.store.Save(ctx, name, data); err != nil {
+		return "", fmt.Errorf("failed to persist knn index %s: %w", name, err)
+	}
+	version, err := c.store.Version(ctx, name)
+	if err != nil {
+		return "", fmt.Errorf("failed to get version of knn index %s: %w", name, err)
+	}
+	return version, nil
+}
+
+func getMetric(metric string) (hnsw.Metric, error) {
+	switch metric {
+	case "ip":
+		return hnsw.IP, nil
+	case "l2":
+		return hnsw.L2, nil
+	case "cosine":
+		return hnsw.Cosine, nil
+	default:
+		return hnsw.L2, fmt.Errorf("unsupported metric %s", metric)
+	}
+}
+
+// getInt returns ints as is, so that ids are exact, and truncates doubles
+func getInt(v value.Value) (int64, error) {
+	if i, ok := v.(value.Int); ok {
+		return int64(i), nil
+	}
+	if d, ok := v.(value.Double); ok {
+		return int64(float64(d)), nil
+	}
+	return 0, fmt.Errorf("value [%s] is not a number", v.String())
+}
+
+// toVector returns the float32s of a vector or of a list of numbers
+func toVector(v value.Value) ([]float32, error) {
+	vec, err := value.ToVector(v)
+	if err != nil {
+		return nil, err
+	}
+	return []float32(vec), nil
+}
@@ -0,0 +1,209 @@
+package ann
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"fennel/engine/ast"
+	"fennel/hangar/encoders"
+	"fennel/hangar/mem"
+	"fennel/lib/aggregate"
+	"fennel/lib/ftypes"
+	"fennel/lib/value"
+	"fennel/s3"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const TEST_TIER_ID = 123
+
+func knnAgg(name string, hyperparameters string) aggregate.Aggregate {
+	return aggregate.Aggregate{
+		Name:  ftypes.AggName(name),
+		Query: ast.MakeInt(0),
+		Options: aggregate.Options{
+			AggType:         "knn",
+			Dim:             2,
+			HyperParameters: hyperparameters,
+		},
+	}
+}
+
+func row(id int64, x, y float64) value.Value {
+	return value.NewDict(map[string]value.Value{
+		"value":     value.Int(id),
+		"groupkey":  value.NewList(value.Double(x), value.Double(y)),
+		"timestamp": value.Int(0),
+	})
+}
+
+func testClient(t *testing.T, makeClient func() Client) {
+	ctx := context.Background()
+	c := makeClient()
+	agg := knnAgg("knn_l2", `{"backend": "embedded", "metric": "l2", "M": 4}`)
+	assert.NoError(t, c.CreateKNNIndex(ctx, agg, TEST_TIER_ID))
+	assert.Error(t, c.CreateKNNIndex(ctx, agg, TEST_TIER_ID))
+
+	assert.NoError(t, c.InsertStream(ctx, agg, value.NewList(row(1, 0, 0), row(2, 1, 0), row(3, 5, 5)), TEST_TIER_ID))
+	assert.Error(t, c.InsertStream(ctx, agg, value.NewList(value.Int(1)), TEST_TIER_ID))
+
+	queries := []value.Value{value.NewList(value.Int(0), value.Int(0)), value.NewList(value.Double(5), value.Double(4))}
+	expected := []value.Value{
+		value.NewList(
+			value.NewDict(map[string]value.Value{PrimaryField: value.Int(1), ScoreField: value.Double(0)}),
+			value.NewDict(map[string]value.Value{PrimaryField: value.Int(2), ScoreField: value.Double(1)}),
+		),
+		value.NewList(
+			value.NewDict(map[string]value.Value{PrimaryField: value.Int(3), ScoreField: value.Double(1)}),
+			value.NewDict(map[string]value.Value{PrimaryField: value.Int(2), ScoreField: value.Double(32)}),
+		),
+	}
+	for _, kwargs := range []value.Dict{
+		value.NewDict(map[string]value.Value{"topK": value.Int(2)}),
+		value.NewDict(map[string]value.Value{"topK": value.Int(2), "exact": value.Bool(true)}),
+		value.NewDict(map[string]value.Value{"topK": value.Int(2), "searchParams": value.NewDict(map[string]value.Value{"ef": value.Int(8)})}),
+	} {
+		found, err := c.GetNeighbors(ctx, agg, queries, kwargs, TEST_TIER_ID)
+		assert.NoError(t, err)
+		assert.Equal(t, expected, found, kwargs.String())
+	}
+	_, err := c.GetNeighbors(ctx, agg, queries, value.NewDict(nil), TEST_TIER_ID)
+	assert.Error(t, err)
+	_, err = c.GetNeighbors(ctx, agg, queries, value.NewDict(map[string]value.Value{
+		"topK": value.Int(2), "searchParams": value.NewDict(map[string]value.Value{"nprobe": value.Int(8)}),
+	}), TEST_TIER_ID)
+	assert.Error(t, err)
+
+	embeddings, err := c.GetEmbedding(ctx, agg, value.NewList(value.Int(3), value.Int(7)), TEST_TIER_ID)
+	assert.NoError(t, err)
+	assert.Equal(t, []value.Value{value.Vector{5, 5}, value.Nil}, embeddings)
+
+	// a new client with the same store sees the persisted index
+	c2 := NewClientWithStore(c.store, AnnArgs{})
+	found, err := c2.GetNeighbors(ctx, agg, queries, value.NewDict(map[string]value.Value{"topK": value.Int(2)}), TEST_TIER_ID)
+	assert.NoError(t, err)
+	assert.Equal(t, expected, found)
+
+	assert.NoError(t, c.DeleteCollection(ctx, agg.Name, TEST_TIER_ID))
+	_, err = c.GetEmbedding(ctx, agg, value.NewList(value.Int(3)), TEST_TIER_ID)
+	assert.Error(t, err)
+	c3 := NewClientWithStore(c.store, AnnArgs{})
+	_, err = c3.GetEmbedding(ctx, agg, value.NewList(value.Int(3)), TEST_TIER_ID)
+	assert.Error(t, err)
+
+	// only metrics of float vectors are supported
+	assert.Error(t, c.CreateKNNIndex(ctx, knnAgg("knn_hamming", `{"backend": "embedded", "metric": "hamming"}`), TEST_TIER_ID))
+}
+
+func TestClient_Disk(t *testing.T) {
+	testClient(t, func() Client {
+		c, err := NewClient(AnnArgs{AnnDir: t.TempDir()}, s3.Client{})
+		require.NoError(t, err)
+		return c
+	})
+}
+
+func TestClient_Hangar(t *testing.T) {
+	testClient(t, func() Client {
+		db, err := mem.NewHangar(TEST_TIER_ID, 4, encoders.Default())
+		require.NoError(t, err)
+		return NewClientWithStore(NewHangarStore(db), AnnArgs{})
+	})
+}
+
+func TestClient_Reload(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+	store, err := NewDiskStore(dir)
+	require.NoError(t, err)
+	// the writer persists on a timer and the reader checks for new versions on every read
+	writer := NewClientWithStore(store, AnnArgs{AnnPersistInterval: time.Hour})
+	reader := NewClientWithStore(store, AnnArgs{})
+	agg := knnAgg("knn_reload", `{"backend": "embedded", "metric": "l2", "index": "flat"}`)
+	require.NoError(t, writer.CreateKNNIndex(ctx, agg, TEST_TIER_ID))
+	ids := value.NewList(value.Int(1), value.Int(2))
+	embeddings, err := reader.GetEmbedding(ctx, agg, ids, TEST_TIER_ID)
+	require.NoError(t, err)
+	assert.Equal(t, []value.Value{value.Nil, value.Nil}, embeddings)
+
+	// inserted rows are not persisted until the index is flushed
+	require.NoError(t, writer.InsertStream(ctx, agg, value.NewList(row(1, 1, 1)), TEST_TIER_ID))
+	embeddings, err = reader.GetEmbedding(ctx, agg, ids, TEST_TIER_ID)
+	require.NoError(t, err)
+	assert.Equal(t, []value.Value{value.Nil, value.Nil}, embeddings)
+	require.NoError(t, writer.Flush(ctx))
+	embeddings, err = reader.GetEmbedding(ctx, agg, ids, TEST_TIER_ID)
+	require.NoError(t, err)
+	assert.Equal(t, []value.Value{value.Vector{1, 1}, value.Nil}, embeddings)
+
+	// a reader that refreshes less often keeps serving the index it loaded
+	lazy := NewClientWithStore(store, AnnArgs{AnnRefreshInterval: time.Hour})
+	_, err = lazy.GetEmbedding(ctx, agg, ids, TEST_TIER_ID)
+	require.NoError(t, err)
+	require.NoError(t, writer.InsertStream(ctx, agg, value.NewList(row(2, 2, 2)), TEST_TIER_ID))
+	require.NoError(t, writer.Close())
+	embeddings, err = lazy.GetEmbedding(ctx, agg, ids, TEST_TIER_ID)
+	require.NoError(t, err)
+	assert.Equal(t, []value.Value{value.Vector{1, 1}, value.Nil}, embeddings)
+	embeddings, err = reader.GetEmbedding(ctx, agg, ids, TEST_TIER_ID)
+	require.NoError(t, err)
+	assert.Equal(t, []value.Value{value.Vector{1, 1}, value.Vector{2, 2}}, embeddings)
+
+	// deleting the collection in one process is seen by the others
+	require.NoError(t, reader.DeleteCollection(ctx, agg.Name, TEST_TIER_ID))
+	_, err = NewClientWithStore(store, AnnArgs{}).GetEmbedding(ctx, agg, ids, TEST_TIER_ID)
+	assert.Error(t, err)
+}
+
+func TestClient_Cosine(t *testing.T) {
+	ctx := context.Background()
+	c, err := NewClient(AnnArgs{AnnDir: t.TempDir()}, s3.Client{})
+	require.NoError(t, err)
+	agg := knnAgg("knn_cosine", `{"backend": "embedded", "metric": "cosine", "index": "flat"}`)
+	assert.NoError(t, c.CreateKNNIndex(ctx, agg, TEST_TIER_ID))
+	assert.NoError(t, c.InsertStream(ctx, agg, value.NewList(row(1, 10, 0), row(2, 1, 1), row(3, -1, 0)), TEST_TIER_ID))
+	found, err := c.GetNeighbors(ctx, agg, []value.Value{value.NewList(value.Int(2), value.Int(0))}, value.NewDict(map[string]value.Value{"topK": value.Int(1)}), TEST_TIER_ID)
+	assert.NoError(t, err)
+	assert.Equal(t, []value.Value{value.NewList(
+		value.NewDict(map[string]value.Value{PrimaryField: value.Int(1), ScoreField: value.Double(1)}),
+	)}, found)
+}
+
+func TestClient_LargeIds(t *testing.T) {
+	ctx := context.Background()
+	c, err := NewClient(AnnArgs{AnnDir: t.TempDir()}, s3.Client{})
+	require.NoError(t, err)
+	agg := knnAgg("knn_large_ids", `{"backend": "embedded", "metric": "l2", "index": "flat"}`)
+	require.NoError(t, c.CreateKNNIndex(ctx, agg, TEST_TIER_ID))
+	// ids above 2^24 are not exact as float32s
+	require.NoError(t, c.InsertStream(ctx, agg, value.NewList(row(1<<24, 1, 1), row(1<<24+1, 2, 2)), TEST_TIER_ID))
+	embeddings, err := c.GetEmbedding(ctx, agg, value.NewList(value.Int(1<<24+1), value.Int(1<<24)), TEST_TIER_ID)
+	require.NoError(t, err)
+	assert.Equal(t, []value.Value{value.Vector{2, 2}, value.Vector{1, 1}}, embeddings)
+}
+
+func TestBackend(t *testing.T) {
+	scenarios := []struct {
+		hyperparameters string
+		backend         string
+		err             bool
+	}{
+		{"", MILVUS, false},
+		{`{"metric": "l2"}`, MILVUS, false},
+		{`{"backend": "milvus"}`, MILVUS, false},
+		{`{"backend": "embedded"}`, EMBEDDED, false},
+		{`{"backend": "faiss"}`, "", true},
+		{`{"backend"`, "", true},
+	}
+	for _, scenario := range scenarios {
+		backend, err := Backend(knnAgg("agg", scenario.hyperparameters))
+		if scenario.err {
+			assert.Error(t, err, scenario.hyperparameters)
+		} else {
+			assert.NoError(t, err, scenario.hyperparameters)
+			assert.Equal(t, scenario.backend, backend)
+		}
+	}
+}
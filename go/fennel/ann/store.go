@@ -0,0 +1,180 @@
+package ann
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"time"
+
+	"fennel/hangar"
+	"fennel/s3"
+
+	"github.com/samber/mo"
+)
+
+// Store persists serialized indices by collection name
+type Store interface {
+	// Load returns nil data (and no error) if the collection was never saved
+	Load(ctx context.Context, name string) ([]byte, error)
+	// Version returns an opaque version of the collection that changes every time it is saved, or
+	// an empty string if the collection was never saved. It is cheap compared to Load so servers
+	// can poll it to find out when to reload an index saved by another process.
+	Version(ctx context.Context, name string) (string, error)
+	Save(ctx context.Context, name string, data []byte) error
+	Delete(ctx context.Context, name string) error
+}
+
+// diskStore keeps one file per collection in a local directory, so it is only shared by processes
+// on the same host
+type diskStore struct {
+	dir string
+}
+
+var _ Store = diskStore{}
+
+func NewDiskStore(dir string) (Store, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create ann index dir '%s': %w", dir, err)
+	}
+	return diskStore{dir: dir}, nil
+}
+
+func (d diskStore) path(name string) string {
+	return filepath.Join(d.dir, name+".hnsw")
+}
+
+func (d diskStore) Load(_ context.Context, name string) ([]byte, error) {
+	data, err := os.ReadFile(d.path(name))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	return data, err
+}
+
+func (d diskStore) Version(_ context.Context, name string) (string, error) {
+	info, err := os.Stat(d.path(name))
+	if errors.Is(err, os.ErrNotExist) {
+		return "", nil
+	} else if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("%d-%d", info.ModTime().UnixNano(), info.Size()), nil
+}
+
+func (d diskStore) Save(_ context.Context, name string, data []byte) error {
+	// write to a temporary file first so that a crash never leaves a partially written index behind
+	tmp, err := os.CreateTemp(d.dir, name+".*.tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err = tmp.Write(data); err != nil {
+		_ = tmp.Close()
+		return err
+	}
+	if err = tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), d.path(name))
+}
+
+func (d diskStore) Delete(_ context.Context, name string) error {
+	err := os.Remove(d.path(name))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	return err
+}
+
+// hangarStore keeps each collection as a single field of a hangar key
+type hangarStore struct {
+	db hangar.Hangar
+}
+
+var _ Store = hangarStore{}
+
+var (
+	indexField   = []byte("index")
+	versionField = []byte("version")
+)
+
+func NewHangarStore(db hangar.Hangar) Store {
+	return hangarStore{db: db}
+}
+
+func (h hangarStore) key(name string) hangar.Key {
+	return hangar.Key{Data: []byte("ann:" + name)}
+}
+
+func (h hangarStore) Load(ctx context.Context, name string) ([]byte, error) {
+	vgs, err := h.db.GetMany(ctx, []hangar.KeyGroup{{Prefix: h.key(name), Fields: mo.Some(hangar.Fields{indexField})}})
+	if err != nil {
+		return nil, err
+	}
+	if len(vgs) == 0 || len(vgs[0].Values) == 0 {
+		return nil, nil
+	}
+	return vgs[0].Values[0], nil
+}
+
+func (h hangarStore) Version(ctx context.Context, name string) (string, error) {
+	vgs, err := h.db.GetMany(ctx, []hangar.KeyGroup{{Prefix: h.key(name), Fields: mo.Some(hangar.Fields{versionField})}})
+	if err != nil {
+		return "", err
+	}
+	if len(vgs) == 0 || len(vgs[0].Values) == 0 {
+		return "", nil
+	}
+	return string(vgs[0].Values[0]), nil
+}
+
+func (h hangarStore) Save(ctx context.Context, name string, data []byte) error {
+	version := []byte(strconv.FormatInt(time.Now().UnixNano(), 10))
+	vg := hangar.ValGroup{Fields: hangar.Fields{indexField, versionField}, Values: hangar.Values{data, version}}
+	return h.db.SetMany(hangar.NewWriteContext(ctx), []hangar.Key{h.key(name)}, []hangar.ValGroup{vg})
+}
+
+func (h hangarStore) Delete(ctx context.Context, name string) error {
+	return h.db.DelMany(hangar.NewWriteContext(ctx), []hangar.KeyGroup{{Prefix: h.key(name)}})
+}
+
+// s3Store keeps one object per collection in a bucket, so that the index written by countaggr is
+// seen by every server of the tier
+type s3Store struct {
+	client s3.Client
+	bucket string
+}
+
+var _ Store = s3Store{}
+
+func NewS3Store(client s3.Client, bucket string) Store {
+	return s3Store{client: client, bucket: bucket}
+}
+
+func (s s3Store) path(name string) string {
+	return "ann/" + name + ".hnsw"
+}
+
+func (s s3Store) Load(ctx context.Context, name string) ([]byte, error) {
+	// downloads of missing objects fail, so check that the object exists first
+	if version, err := s.Version(ctx, name); err != nil || version == "" {
+		return nil, err
+	}
+	return s.client.Download(s.path(name), s.bucket)
+}
+
+func (s s3Store) Version(_ context.Context, name string) (string, error) {
+	return s.client.ETag(s.path(name), s.bucket)
+}
+
+func (s s3Store) Save(_ context.Context, name string, data []byte) error {
+	return s.client.Upload(bytes.NewReader(data), s.path(name), s.bucket)
+}
+
+func (s s3Store) Delete(_ context.Context, name string) error {
+	return s.client.Delete(s.path(name), s.bucket)
+}
@@ -7,17 +7,22 @@ import (
 	"io/ioutil"
 	"net/http"
 	"net/url"
+	"strconv"
 
 	"fennel/controller/mock"
 	"fennel/engine/ast"
 
 	"fennel/lib/action"
 	"fennel/lib/aggregate"
+	"fennel/lib/apikey"
+	"fennel/lib/budget"
 	"fennel/lib/feature"
 	"fennel/lib/ftypes"
+	"fennel/lib/ingest"
 	"fennel/lib/profile"
 	profileLib "fennel/lib/profile"
 	"fennel/lib/query"
+	"fennel/lib/schema"
 	"fennel/lib/sql"
 	"fennel/lib/value"
 )
@@ -25,6 +30,7 @@ import (
 type Client struct {
 	httpclient *http.Client
 	url        *url.URL
+	apiKey     string
 }
 
 func NewClient(hostport string, httpclient *http.Client) (*Client, error) {
@@ -38,6 +44,11 @@ func NewClient(hostport string, httpclient *http.Client) (*Client, error) {
 	}, nil
 }
 
+// SetApiKey sets the api key that is sent with every request, it is required by tiers that have api keys
+func (c *Client) SetApiKey(key string) {
+	c.apiKey = key
+}
+
 func (c Client) logURL() string {
 	url := *c.url
 	url.Path = url.Path + "/log"
@@ -140,26 +151,113 @@ func (c Client) runQueryURL() string {
 	return url.String()
 }
 
+func (c Client) queryVersionsURL(name string) string {
+	rawQuery := url.Values{"name": []string{name}}.Encode()
+	url := *c.url
+	url.Path = url.Path + "/internal/v1/query/versions"
+	url.RawQuery = rawQuery
+	return url.String()
+}
+
+// queryAliasURL returns the url to set aliases or, if name is given, to list the aliases of the query
+func (c Client) queryAliasURL(name string) string {
+	var rawQuery string
+	if len(name) > 0 {
+		rawQuery = url.Values{"name": []string{name}}.Encode()
+	}
+	url := *c.url
+	url.Path = url.Path + "/internal/v1/query/alias"
+	url.RawQuery = rawQuery
+	return url.String()
+}
+
+func (c Client) rollbackQueryAliasURL() string {
+	url := *c.url
+	url.Path = url.Path + "/internal/v1/query/alias/rollback"
+	return url.String()
+}
+
+// queryBudgetURL returns the url to set budgets or, if name is given, to get the budget of the query
+func (c Client) queryBudgetURL(name string) string {
+	var rawQuery string
+	if len(name) > 0 {
+		rawQuery = url.Values{"name": []string{name}}.Encode()
+	}
+	url := *c.url
+	url.Path = url.Path + "/internal/v1/query/budget"
+	url.RawQuery = rawQuery
+	return url.String()
+}
+
+// profileJobsURL returns the url to start profile jobs or, if id is given, to get the job
+func (c Client) profileJobsURL(id uint64) string {
+	var rawQuery string
+	if id > 0 {
+		rawQuery = url.Values{"id": []string{strconv.FormatUint(id, 10)}}.Encode()
+	}
+	url := *c.url
+	url.Path = url.Path + "/internal/v1/profiles/jobs"
+	url.RawQuery = rawQuery
+	return url.String()
+}
+
+func (c Client) storeSchemaURL() string {
+	url := *c.url
+	url.Path = url.Path + "/store_schema"
+	return url.String()
+}
+
+func (c Client) schemasURL() string {
+	url := *c.url
+	url.Path = url.Path + "/schemas"
+	return url.String()
+}
+
+func (c Client) apiKeysURL() string {
+	url := *c.url
+	url.Path = url.Path + "/internal/v1/api_keys"
+	return url.String()
+}
+
+func (c Client) do(method, url string, body []byte) (*http.Response, error) {
+	req, err := http.NewRequest(method, url, bytes.NewBuffer(body))
+	if err != nil {
+		return nil, err
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	if len(c.apiKey) > 0 {
+		req.Header.Set("Authorization", "Bearer "+c.apiKey)
+	}
+	return c.httpclient.Do(req)
+}
+
 func (c Client) postJSON(data []byte, url string) ([]byte, error) {
-	reqBody := bytes.NewBuffer(data)
-	response, err := c.httpclient.Post(url, "application/json", reqBody)
+	body, _, err := c.post(data, url)
+	return body, err
+}
+
+// post is like postJSON but also returns the headers of the response
+func (c Client) post(data []byte, url string) ([]byte, http.Header, error) {
+	response, err := c.do(http.MethodPost, url, data)
 	if err != nil {
-		return nil, fmt.Errorf("server error: %v", err)
+		return nil, nil, fmt.Errorf("server error: %v", err)
 	}
 	defer response.Body.Close()
 	body, err := ioutil.ReadAll(response.Body)
 	if err != nil {
-		return nil, fmt.Errorf("could not read server response: %v", err)
+		return nil, nil, fmt.Errorf("could not read server response: %v", err)
 	}
 	// handle http error given by the server
 	if response.StatusCode < 200 || response.StatusCode >= 300 {
-		return nil, fmt.Errorf("%s: %s", http.StatusText(response.StatusCode), string(body))
+		return nil, nil, fmt.Errorf("%s: %s", http.StatusText(response.StatusCode), string(body))
 	}
-	return body, nil
+	return body, response.Header, nil
 }
 
 func (c Client) Get(url string) ([]byte, error) {
-	response, err := c.httpclient.Get(url)
+	response, err := c.do(http.MethodGet, url, nil)
 	if err != nil {
 		return nil, fmt.Errorf("server error: %v", err)
 	}
@@ -222,7 +320,7 @@ func (c *Client) GetProfile(request *profileLib.ProfileItemKey) (*profile.Profil
 		return nil, nil
 	}
 	// now try to read response as a JSON object and convert to value
-	v, err := value.FromJSON(response)
+	v, err := value.FromTaggedJSON(response)
 	if err != nil {
 		return nil, err
 	} else {
@@ -261,30 +359,37 @@ func (c *Client) Query(reqAst ast.Ast, reqArgs value.Dict, reqMock mock.Data) (v
 		return nil, err
 	}
 	// now try to read response as a JSON object and convert to value
-	v, err := value.FromJSON(response)
+	v, err := value.FromTaggedJSON(response)
 	if err != nil {
 		return nil, fmt.Errorf("error parsing value json: %v", err)
 	}
 	return v, nil
 }
 
-func (c *Client) StoreQuery(name string, tree ast.Ast, description string) error {
+// StoreQuery stores the query under the given name. Queries that declare params are run with
+// their args checked against the params and with defaults filled in.
+func (c *Client) StoreQuery(name string, tree ast.Ast, description string, params ...query.Param) error {
 	if tree == nil {
 		return fmt.Errorf("'tree' cannot be nil")
 	}
+	if err := query.Params(params).Validate(); err != nil {
+		return err
+	}
 	type ReqObject struct {
-		Name  string `json:"name"`
-		Query string `json:"query"`
-		Desc  string `json:"description"`
+		Name   string       `json:"name"`
+		Query  string       `json:"query"`
+		Desc   string       `json:"description"`
+		Params query.Params `json:"params,omitempty"`
 	}
 	qStr, err := query.ToString(tree)
 	if err != nil {
 		return err
 	}
 	reqObj := ReqObject{
-		Name:  name,
-		Query: qStr,
-		Desc:  description,
+		Name:   name,
+		Query:  qStr,
+		Desc:   description,
+		Params: params,
 	}
 	req, err := json.Marshal(reqObj)
 	if err != nil {
@@ -294,32 +399,136 @@ func (c *Client) StoreQuery(name string, tree ast.Ast, description string) error
 	return err
 }
 
+// RunQuery runs the version of the stored query that is served by default
 func (c *Client) RunQuery(name string, args value.Dict) (value.Value, error) {
+	v, _, err := c.RunQueryVersion(name, "", args)
+	return v, err
+}
+
+// RunQueryVersion runs the given version of the stored query and returns the result along with
+// the version that was run. version is either a version number, an alias or empty for the
+// default alias; aliases that split traffic choose the version based on the args.
+func (c *Client) RunQueryVersion(name, version string, args value.Dict) (value.Value, uint32, error) {
 	type ReqObject struct {
-		Name string     `json:"name"`
-		Args value.Dict `json:"args"`
+		Name    string     `json:"name"`
+		Args    value.Dict `json:"args"`
+		Version string     `json:"version,omitempty"`
 	}
 	if args.Iter() == nil {
 		args = value.NewDict(nil)
 	}
 	reqObj := ReqObject{
-		Name: name,
-		Args: args,
+		Name:    name,
+		Args:    args,
+		Version: version,
 	}
 	req, err := json.Marshal(reqObj)
 	if err != nil {
-		return nil, err
+		return nil, 0, err
 	}
-	response, err := c.postJSON(req, c.runQueryURL())
+	response, header, err := c.post(req, c.runQueryURL())
 	if err != nil {
-		return nil, fmt.Errorf("query post error: %w", err)
+		return nil, 0, fmt.Errorf("query post error: %w", err)
 	}
 	// now try to read response as a JSON object and convert to value
-	v, err := value.FromJSON(response)
+	v, err := value.FromTaggedJSON(response)
 	if err != nil {
-		return nil, fmt.Errorf("error parsing value json: %v", err)
+		return nil, 0, fmt.Errorf("error parsing value json: %v", err)
 	}
-	return v, nil
+	ran, err := strconv.ParseUint(header.Get("X-Query-Version"), 10, 32)
+	if err != nil {
+		return nil, 0, fmt.Errorf("error parsing query version: %v", err)
+	}
+	return v, uint32(ran), nil
+}
+
+// QueryVersions returns all versions of the stored query, oldest first
+func (c *Client) QueryVersions(name string) ([]query.QuerySer, error) {
+	response, err := c.Get(c.queryVersionsURL(name))
+	if err != nil {
+		return nil, err
+	}
+	var versions []query.QuerySer
+	if err := json.Unmarshal(response, &versions); err != nil {
+		return nil, fmt.Errorf("error parsing json, %v", err)
+	}
+	return versions, nil
+}
+
+// QueryAliases returns the current target of every alias of the stored query
+func (c *Client) QueryAliases(name string) ([]query.Alias, error) {
+	response, err := c.Get(c.queryAliasURL(name))
+	if err != nil {
+		return nil, err
+	}
+	var aliases []query.Alias
+	if err := json.Unmarshal(response, &aliases); err != nil {
+		return nil, fmt.Errorf("error parsing json, %v", err)
+	}
+	return aliases, nil
+}
+
+// SetQueryAlias points an alias of a stored query at a version or splits its traffic between versions
+func (c *Client) SetQueryAlias(alias query.Alias) (query.Alias, error) {
+	if err := alias.Validate(); err != nil {
+		return query.Alias{}, err
+	}
+	req, err := json.Marshal(alias)
+	if err != nil {
+		return query.Alias{}, err
+	}
+	return c.postAlias(req, c.queryAliasURL(""))
+}
+
+// RollbackQueryAlias points an alias of a stored query back at its previous target
+func (c *Client) RollbackQueryAlias(name, alias string) (query.Alias, error) {
+	req, err := json.Marshal(map[string]string{"query_name": name, "alias": alias})
+	if err != nil {
+		return query.Alias{}, err
+	}
+	return c.postAlias(req, c.rollbackQueryAliasURL())
+}
+
+// QueryBudget returns the budget set on the stored query and the budget it is run with, which has
+// the limits that are not set on the query taken from the tier
+func (c *Client) QueryBudget(name string) (budget.Budget, budget.Budget, error) {
+	response, err := c.Get(c.queryBudgetURL(name))
+	if err != nil {
+		return budget.Budget{}, budget.Budget{}, err
+	}
+	var ret struct {
+		Budget    budget.Budget `json:"budget"`
+		RunBudget budget.Budget `json:"run_budget"`
+	}
+	if err := json.Unmarshal(response, &ret); err != nil {
+		return budget.Budget{}, budget.Budget{}, fmt.Errorf("error parsing json, %v", err)
+	}
+	return ret.Budget, ret.RunBudget, nil
+}
+
+// SetQueryBudget sets the budget of a stored query, limits that are not set are taken from the tier
+func (c *Client) SetQueryBudget(name string, b budget.Budget) error {
+	if err := b.Validate(); err != nil {
+		return err
+	}
+	req, err := json.Marshal(map[string]interface{}{"query_name": name, "budget": b})
+	if err != nil {
+		return err
+	}
+	_, err = c.postJSON(req, c.queryBudgetURL(""))
+	return err
+}
+
+func (c *Client) postAlias(req []byte, url string) (query.Alias, error) {
+	response, err := c.postJSON(req, url)
+	if err != nil {
+		return query.Alias{}, err
+	}
+	var alias query.Alias
+	if err := json.Unmarshal(response, &alias); err != nil {
+		return query.Alias{}, fmt.Errorf("error parsing json, %v", err)
+	}
+	return alias, nil
 }
 
 func (c *Client) SetProfile(request *profileLib.ProfileItem) error {
@@ -335,22 +544,47 @@ func (c *Client) SetProfile(request *profileLib.ProfileItem) error {
 	return err
 }
 
-func (c *Client) SetProfiles(request []profileLib.ProfileItem) error {
-	// validate and convert to json
-	profiles := make([]profile.ProfileItem, 0)
-	for _, p := range request {
-		if err := p.Validate(); err != nil {
-			return fmt.Errorf("invalid request: %v", err)
-		}
-		profiles = append(profiles, p)
+// SetProfiles sets the profiles and returns the status of each of them. Profiles that are invalid
+// are reported in the result, and the batch fails with an error if it is atomic and any of them is.
+func (c *Client) SetProfiles(request []profileLib.ProfileItem, opts ingest.BatchOptions) (ingest.BatchResult, error) {
+	req, err := json.Marshal(request)
+	if err != nil {
+		return ingest.BatchResult{}, fmt.Errorf("could not convert request to json: %v", err)
 	}
+	return c.postBatch(req, c.setProfilesURL(), opts)
+}
 
-	req, err := json.Marshal(profiles)
+// StartProfileJob starts a bulk import or export of profiles and returns the pending job
+func (c *Client) StartProfileJob(request profileLib.JobRequest) (profileLib.Job, error) {
+	if err := request.Validate(); err != nil {
+		return profileLib.Job{}, fmt.Errorf("invalid request: %v", err)
+	}
+	req, err := json.Marshal(request)
 	if err != nil {
-		return fmt.Errorf("could not convert request to json: %v", err)
+		return profileLib.Job{}, fmt.Errorf("could not convert request to json: %v", err)
 	}
-	_, err = c.postJSON(req, c.setProfilesURL())
-	return err
+	response, err := c.postJSON(req, c.profileJobsURL(0))
+	if err != nil {
+		return profileLib.Job{}, err
+	}
+	var job profileLib.Job
+	if err := json.Unmarshal(response, &job); err != nil {
+		return profileLib.Job{}, fmt.Errorf("error parsing json, %v", err)
+	}
+	return job, nil
+}
+
+// GetProfileJob returns the status and progress of a bulk profile job
+func (c *Client) GetProfileJob(id uint64) (profileLib.Job, error) {
+	response, err := c.Get(c.profileJobsURL(id))
+	if err != nil {
+		return profileLib.Job{}, err
+	}
+	var job profileLib.Job
+	if err := json.Unmarshal(response, &job); err != nil {
+		return profileLib.Job{}, fmt.Errorf("error parsing json, %v", err)
+	}
+	return job, nil
 }
 
 func (c *Client) FetchActions(request action.ActionFetchRequest) ([]action.Action, error) {
@@ -391,8 +625,9 @@ func (c *Client) LogAction(request action.Action, dedupKey string) error {
 	return err
 }
 
-// LogActions takes a list of actions and dedup keys and makes the http request to server to log the given actions
-func (c *Client) LogActions(request []action.Action, dedupKeys []string) error {
+// LogActions takes a list of actions and dedup keys, makes the http request to server to log the
+// given actions and returns the status of each of them
+func (c *Client) LogActions(request []action.Action, dedupKeys []string, opts ingest.BatchOptions) (ingest.BatchResult, error) {
 	if dedupKeys == nil {
 		dedupKeys = make([]string, len(request))
 	}
@@ -402,18 +637,63 @@ func (c *Client) LogActions(request []action.Action, dedupKeys []string) error {
 		value.Bool // Dummy field to prevent Action.MarshalJSON() from being called
 	}, len(request))
 	for i, a := range request {
-		if err := a.Validate(); err != nil {
-			return fmt.Errorf("can not log invalid action: %v", err)
-		}
 		dedupReq[i].Action = a
 		dedupReq[i].DedupKey = dedupKeys[i]
 	}
 	req, err := json.Marshal(dedupReq)
 	if err != nil {
-		return fmt.Errorf("could not convert request to json: %v", err)
+		return ingest.BatchResult{}, fmt.Errorf("could not convert request to json: %v", err)
 	}
-	_, err = c.postJSON(req, c.logMultiURL())
-	return err
+	return c.postBatch(req, c.logMultiURL(), opts)
+}
+
+// postBatch posts a batch of records with the options and parses the status of each record from
+// the response, also when an atomic batch was rejected
+func (c Client) postBatch(data []byte, u string, opts ingest.BatchOptions) (ingest.BatchResult, error) {
+	if err := opts.Validate(); err != nil {
+		return ingest.BatchResult{}, err
+	}
+	if opts.Atomic {
+		parsed, err := url.Parse(u)
+		if err != nil {
+			return ingest.BatchResult{}, err
+		}
+		q := parsed.Query()
+		q.Set(ingest.AtomicParam, "true")
+		parsed.RawQuery = q.Encode()
+		u = parsed.String()
+	}
+	req, err := http.NewRequest(http.MethodPost, u, bytes.NewBuffer(data))
+	if err != nil {
+		return ingest.BatchResult{}, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if len(c.apiKey) > 0 {
+		req.Header.Set("Authorization", "Bearer "+c.apiKey)
+	}
+	if len(opts.IdempotencyKey) > 0 {
+		req.Header.Set(ingest.IdempotencyKeyHeader, opts.IdempotencyKey)
+	}
+	response, err := c.httpclient.Do(req)
+	if err != nil {
+		return ingest.BatchResult{}, fmt.Errorf("server error: %v", err)
+	}
+	defer response.Body.Close()
+	body, err := ioutil.ReadAll(response.Body)
+	if err != nil {
+		return ingest.BatchResult{}, fmt.Errorf("could not read server response: %v", err)
+	}
+	var result ingest.BatchResult
+	if response.Header.Get("Content-Type") != "application/json" || json.Unmarshal(body, &result) != nil {
+		if response.StatusCode < 200 || response.StatusCode >= 300 {
+			return ingest.BatchResult{}, fmt.Errorf("%s: %s", http.StatusText(response.StatusCode), string(body))
+		}
+		return ingest.BatchResult{}, fmt.Errorf("could not parse server response: %s", string(body))
+	}
+	if !result.Committed {
+		return result, fmt.Errorf("batch was not logged: %d of %d records are invalid", result.Invalid, len(result.Records))
+	}
+	return result, nil
 }
 
 func (c *Client) StoreAggregate(agg aggregate.Aggregate) error {
@@ -472,6 +752,48 @@ func (c *Client) DeactivateAggregate(aggname ftypes.AggName) error {
 	return err
 }
 
+// StoreSchema creates or evolves the schema and returns it along with its version
+func (c *Client) StoreSchema(sch schema.Schema) (schema.Schema, error) {
+	if err := sch.Validate(); err != nil {
+		return sch, err
+	}
+	req, err := json.Marshal(sch)
+	if err != nil {
+		return sch, err
+	}
+	response, err := c.postJSON(req, c.storeSchemaURL())
+	if err != nil {
+		return sch, err
+	}
+	var ret schema.Schema
+	if err = json.Unmarshal(response, &ret); err != nil {
+		return sch, fmt.Errorf("unmarshal error: %v", err)
+	}
+	return ret, nil
+}
+
+// SyncApiKeys replaces the api keys accepted by the tier, only the hashes of the keys are sent
+func (c *Client) SyncApiKeys(keys []apikey.Key) error {
+	req, err := json.Marshal(keys)
+	if err != nil {
+		return err
+	}
+	_, err = c.postJSON(req, c.apiKeysURL())
+	return err
+}
+
+func (c *Client) ListSchemas() ([]schema.Schema, error) {
+	response, err := c.Get(c.schemasURL())
+	if err != nil {
+		return nil, err
+	}
+	var ret []schema.Schema
+	if err = json.Unmarshal(response, &ret); err != nil {
+		return nil, fmt.Errorf("unmarshal error: %v", err)
+	}
+	return ret, nil
+}
+
 func (c *Client) GetAggregateValue(aggname ftypes.AggName, key value.Value, kwargs value.Dict) (value.Value, error) {
 	// convert to json request and send to server
 	aggreq := aggregate.GetAggValueRequest{AggName: aggname, Key: key, Kwargs: kwargs}
@@ -485,7 +807,7 @@ func (c *Client) GetAggregateValue(aggname ftypes.AggName, key value.Value, kwar
 		return value.Nil, err
 	}
 	// convert server response back to a value object and return
-	ret, err := value.FromJSON(response)
+	ret, err := value.FromTaggedJSON(response)
 	if err != nil {
 		return nil, fmt.Errorf("error parsing value json: %v", ret)
 	}
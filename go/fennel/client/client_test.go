@@ -2,13 +2,17 @@ package client
 
 import (
 	"encoding/json"
+	"errors"
 	"io/ioutil"
 	"net/http"
 	"net/http/httptest"
 	"testing"
 
+	"fennel/lib/action"
 	"fennel/lib/aggregate"
 	"fennel/lib/ftypes"
+	"fennel/lib/ingest"
+	"fennel/lib/profile"
 	"fennel/lib/value"
 
 	"github.com/stretchr/testify/assert"
@@ -47,3 +51,97 @@ func TestClient_GetAggregateValue(t *testing.T) {
 	assert.NoError(t, err)
 	assert.Equal(t, expected, found)
 }
+
+func TestClient_RunQueryVersion(t *testing.T) {
+	args := value.NewDict(map[string]value.Value{"uid": value.Int(12)})
+	svr := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		req, err := ioutil.ReadAll(r.Body)
+		assert.NoError(t, err)
+		assert.JSONEq(t, `{"name": "ranking", "args": {"uid": 12}, "version": "prod"}`, string(req))
+		w.Header().Set("X-Query-Version", "3")
+		_, err = w.Write(value.ToJSON(value.Double(0.5)))
+		assert.NoError(t, err)
+	}))
+	defer svr.Close()
+	c, err := NewClient(svr.URL, svr.Client())
+	assert.NoError(t, err)
+
+	found, version, err := c.RunQueryVersion("ranking", "prod", args)
+	assert.NoError(t, err)
+	assert.Equal(t, value.Double(0.5), found)
+	assert.Equal(t, uint32(3), version)
+}
+
+func TestClient_ProfileJob(t *testing.T) {
+	request := profile.JobRequest{Type: profile.ImportJob, Format: profile.CSV, Location: "s3://bucket/profiles"}
+	svr := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/internal/v1/profiles/jobs", r.URL.Path)
+		job := profile.Job{ID: 7, JobRequest: request, Status: profile.JobPending}
+		if r.Method == http.MethodGet {
+			assert.Equal(t, "7", r.URL.Query().Get("id"))
+			job.Status, job.RowsWritten = profile.JobSucceeded, 10
+		} else {
+			req, err := ioutil.ReadAll(r.Body)
+			assert.NoError(t, err)
+			assert.JSONEq(t, `{"type": "import", "format": "csv", "location": "s3://bucket/profiles"}`, string(req))
+		}
+		ser, err := json.Marshal(job)
+		assert.NoError(t, err)
+		_, err = w.Write(ser)
+		assert.NoError(t, err)
+	}))
+	defer svr.Close()
+	c, err := NewClient(svr.URL, svr.Client())
+	assert.NoError(t, err)
+
+	job, err := c.StartProfileJob(request)
+	assert.NoError(t, err)
+	assert.Equal(t, uint64(7), job.ID)
+	assert.Equal(t, profile.JobPending, job.Status)
+	job, err = c.GetProfileJob(7)
+	assert.NoError(t, err)
+	assert.Equal(t, profile.JobSucceeded, job.Status)
+	assert.Equal(t, uint64(10), job.RowsWritten)
+
+	_, err = c.StartProfileJob(profile.JobRequest{Type: profile.ExportJob, Format: profile.CSV, Location: "/tmp"})
+	assert.Error(t, err)
+}
+
+func TestClient_LogActions(t *testing.T) {
+	svr := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/log_multi", r.URL.Path)
+		assert.Equal(t, "batch-1", r.Header.Get(ingest.IdempotencyKeyHeader))
+		result := ingest.NewBatchResult(2)
+		result.SetInvalid(1, errors.New("invalid action"))
+		status := http.StatusOK
+		if r.URL.Query().Get(ingest.AtomicParam) == "true" {
+			result.Abort()
+			status = http.StatusBadRequest
+		}
+		ser, err := json.Marshal(result)
+		assert.NoError(t, err)
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(status)
+		_, err = w.Write(ser)
+		assert.NoError(t, err)
+	}))
+	defer svr.Close()
+	c, err := NewClient(svr.URL, svr.Client())
+	assert.NoError(t, err)
+
+	actions := []action.Action{{ActionType: "click"}, {}}
+	result, err := c.LogActions(actions, nil, ingest.BatchOptions{IdempotencyKey: "batch-1"})
+	assert.NoError(t, err)
+	assert.True(t, result.Committed)
+	assert.Equal(t, 1, result.Accepted)
+	assert.Equal(t, []ingest.RecordResult{{Status: ingest.Accepted}, {Status: ingest.Invalid, Reason: "invalid action"}}, result.Records)
+
+	// the records of rejected atomic batches are reported along with an error
+	result, err = c.LogActions(actions, nil, ingest.BatchOptions{IdempotencyKey: "batch-1", Atomic: true})
+	assert.Error(t, err)
+	assert.False(t, result.Committed)
+	assert.Equal(t, ingest.Skipped, result.Records[0].Status)
+
+	_, err = c.LogActions(actions, nil, ingest.BatchOptions{IdempotencyKey: "invalid key"})
+	assert.Error(t, err)
+}
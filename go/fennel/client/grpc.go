@@ -0,0 +1,180 @@
+package client
+
+import (
+	"context"
+	"fmt"
+
+	"fennel/engine/ast"
+	"fennel/lib/action"
+	"fennel/lib/aggregate"
+	profileLib "fennel/lib/profile"
+	"fennel/lib/rpc"
+	"fennel/lib/value"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+)
+
+// GrpcClient talks to the rpc interface of the data plane, it supports the same logging & query
+// calls as Client but avoids encoding asts and values as json
+type GrpcClient struct {
+	conn   *grpc.ClientConn
+	client rpc.DataPlaneClient
+	apiKey string
+}
+
+// NewGrpcClient connects to the data plane at hostport, connections are insecure unless dial
+// options with transport credentials are given
+func NewGrpcClient(hostport string, opts ...grpc.DialOption) (*GrpcClient, error) {
+	if len(opts) == 0 {
+		opts = []grpc.DialOption{grpc.WithTransportCredentials(insecure.NewCredentials())}
+	}
+	conn, err := grpc.Dial(hostport, opts...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to [%s]: %v", hostport, err)
+	}
+	return &GrpcClient{conn: conn, client: rpc.NewDataPlaneClient(conn)}, nil
+}
+
+// SetApiKey sets the api key that is sent with every request, it is required by tiers that have api keys
+func (c *GrpcClient) SetApiKey(key string) {
+	c.apiKey = key
+}
+
+func (c *GrpcClient) Close() error {
+	return c.conn.Close()
+}
+
+func (c *GrpcClient) withKey(ctx context.Context) context.Context {
+	if len(c.apiKey) == 0 {
+		return ctx
+	}
+	return metadata.AppendToOutgoingContext(ctx, "authorization", "Bearer "+c.apiKey)
+}
+
+// LogActions logs the actions, dedupKeys is either empty or has one (possibly empty) key per action
+func (c *GrpcClient) LogActions(ctx context.Context, actions []action.Action, dedupKeys []string) error {
+	if len(dedupKeys) > 0 && len(dedupKeys) != len(actions) {
+		return fmt.Errorf("expected %d dedup keys but got %d", len(actions), len(dedupKeys))
+	}
+	req := &rpc.LogActionsRequest{Actions: make([]*action.ProtoAction, len(actions)), DedupKeys: dedupKeys}
+	for i, a := range actions {
+		if err := a.Validate(); err != nil {
+			return fmt.Errorf("invalid action: %v", err)
+		}
+		pa, err := action.ToProtoAction(a)
+		if err != nil {
+			return err
+		}
+		req.Actions[i] = &pa
+	}
+	_, err := c.client.LogActions(c.withKey(ctx), req)
+	return err
+}
+
+func (c *GrpcClient) SetProfiles(ctx context.Context, profiles []profileLib.ProfileItem) error {
+	req := &rpc.SetProfilesRequest{Profiles: make([]*profileLib.ProtoProfileItem, len(profiles))}
+	for i := range profiles {
+		if err := profiles[i].Validate(); err != nil {
+			return err
+		}
+		pp, err := profileLib.ToProtoProfileItem(&profiles[i])
+		if err != nil {
+			return err
+		}
+		req.Profiles[i] = &pp
+	}
+	_, err := c.client.SetProfiles(c.withKey(ctx), req)
+	return err
+}
+
+// GetProfiles returns the values of the profiles in the same order as the keys, the value of a
+// profile that is not set is value.Nil
+func (c *GrpcClient) GetProfiles(ctx context.Context, keys []profileLib.ProfileItemKey) ([]value.Value, error) {
+	req := &rpc.GetProfilesRequest{Keys: make([]*rpc.ProfileKey, len(keys))}
+	for i, k := range keys {
+		req.Keys[i] = &rpc.ProfileKey{Otype: string(k.OType), Oid: string(k.Oid), Key: k.Key}
+	}
+	resp, err := c.client.GetProfiles(c.withKey(ctx), req)
+	if err != nil {
+		return nil, err
+	}
+	return fromProtoValues(resp.Values)
+}
+
+func (c *GrpcClient) Query(ctx context.Context, tree ast.Ast, args value.Dict) (value.Value, error) {
+	past, err := ast.ToProtoAst(tree)
+	if err != nil {
+		return nil, err
+	}
+	pargs, err := value.ToProtoDict(args)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := c.client.Query(c.withKey(ctx), &rpc.QueryRequest{Ast: &past, Args: &pargs})
+	if err != nil {
+		return nil, err
+	}
+	return value.FromProtoValue(resp.Result)
+}
+
+// RunQuery runs the version of the stored query that is served by default
+func (c *GrpcClient) RunQuery(ctx context.Context, name string, args value.Dict) (value.Value, error) {
+	v, _, err := c.RunQueryVersion(ctx, name, "", args)
+	return v, err
+}
+
+// RunQueryVersion runs the given version of the stored query and returns the result along with
+// the version that was run, see Client.RunQueryVersion
+func (c *GrpcClient) RunQueryVersion(ctx context.Context, name, version string, args value.Dict) (value.Value, uint32, error) {
+	pargs, err := value.ToProtoDict(args)
+	if err != nil {
+		return nil, 0, err
+	}
+	resp, err := c.client.RunStoredQuery(c.withKey(ctx), &rpc.RunStoredQueryRequest{Name: name, Args: &pargs, Version: version})
+	if err != nil {
+		return nil, 0, err
+	}
+	v, err := value.FromProtoValue(resp.Result)
+	if err != nil {
+		return nil, 0, err
+	}
+	return v, resp.Version, nil
+}
+
+func (c *GrpcClient) BatchAggregateValue(ctx context.Context, batch []aggregate.GetAggValueRequest) ([]value.Value, error) {
+	req := &rpc.BatchAggregateValueRequest{Requests: make([]*rpc.AggregateValueRequest, len(batch))}
+	for i, r := range batch {
+		key, err := value.ToProtoValue(r.Key)
+		if err != nil {
+			return nil, err
+		}
+		kwargs := value.NewDict(nil)
+		if r.Kwargs.Len() > 0 {
+			kwargs = r.Kwargs
+		}
+		pkwargs, err := value.ToProtoDict(kwargs)
+		if err != nil {
+			return nil, err
+		}
+		req.Requests[i] = &rpc.AggregateValueRequest{AggName: string(r.AggName), Key: &key, Kwargs: &pkwargs}
+	}
+	resp, err := c.client.BatchAggregateValue(c.withKey(ctx), req)
+	if err != nil {
+		return nil, err
+	}
+	return fromProtoValues(resp.Values)
+}
+
+func fromProtoValues(pvalues []*value.PValue) ([]value.Value, error) {
+	ret := make([]value.Value, len(pvalues))
+	for i, pv := range pvalues {
+		v, err := value.FromProtoValue(pv)
+		if err != nil {
+			return nil, err
+		}
+		ret[i] = v
+	}
+	return ret, nil
+}
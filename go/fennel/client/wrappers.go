@@ -0,0 +1,160 @@
+package client
+
+import (
+	"bytes"
+	"fmt"
+	"go/format"
+	"strings"
+	"text/template"
+	"unicode"
+
+	"fennel/lib/query"
+	"fennel/lib/value"
+)
+
+var wrappersTemplate = template.Must(template.New("wrappers").Parse(`// Code generated by fennel/client. DO NOT EDIT.
+
+package {{ .Package }}
+
+import (
+	"fennel/client"
+	"fennel/lib/value"
+)
+{{ range .Funcs }}
+// {{ .Name }} runs the stored query '{{ .Query }}'.
+{{- range .Doc }}
+// {{ . }}
+{{- end }}
+func {{ .Name }}(c *client.Client{{ range .Args }}, {{ .Var }} {{ .GoType }}{{ end }}) (value.Value, error) {
+	args := value.NewDict(nil)
+{{- range .Args }}
+{{- if .Optional }}
+	if {{ .Var }} != nil {
+		args.Set({{ printf "%q" .Name }}, {{ .Convert }})
+	}
+{{- else }}
+	args.Set({{ printf "%q" .Name }}, {{ .Convert }})
+{{- end }}
+{{- end }}
+	return c.RunQuery({{ printf "%q" .Query }}, args)
+}
+{{ end }}`))
+
+type wrapperArg struct {
+	Name     string
+	Var      string
+	GoType   string
+	Convert  string
+	Optional bool
+}
+
+type wrapperFunc struct {
+	Name  string
+	Query string
+	Doc   []string
+	Args  []wrapperArg
+}
+
+// QueryWrappers returns the source of a go package with a typed function for each stored query
+// that declares params, see GenerateQueryWrappers
+func (c *Client) QueryWrappers(pkg string) ([]byte, error) {
+	queries, err := c.FetchStoredQueries()
+	if err != nil {
+		return nil, err
+	}
+	return GenerateQueryWrappers(pkg, queries)
+}
+
+// GenerateQueryWrappers returns the source of a go package with a typed function for each query
+// that declares params. Params of type Int, Double, String and Bool are passed as the
+// corresponding go types and params of other types as value.Value. Optional params are passed as
+// pointers (or nil values) and are omitted when nil so that their defaults are used.
+func GenerateQueryWrappers(pkg string, queries []query.QuerySer) ([]byte, error) {
+	var funcs []wrapperFunc
+	for _, q := range queries {
+		if len(q.Params) == 0 {
+			continue
+		}
+		f := wrapperFunc{Name: "Run" + exportedName(q.Name), Query: q.Name}
+		if len(q.Description) > 0 {
+			f.Doc = append(f.Doc, strings.Split(q.Description, "\n")...)
+		}
+		for _, p := range q.Params {
+			arg, err := newWrapperArg(p)
+			if err != nil {
+				return nil, fmt.Errorf("query '%s': %w", q.Name, err)
+			}
+			if len(p.Description) > 0 {
+				f.Doc = append(f.Doc, fmt.Sprintf("%s: %s", arg.Var, p.Description))
+			}
+			f.Args = append(f.Args, arg)
+		}
+		funcs = append(funcs, f)
+	}
+	var buf bytes.Buffer
+	err := wrappersTemplate.Execute(&buf, struct {
+		Package string
+		Funcs   []wrapperFunc
+	}{pkg, funcs})
+	if err != nil {
+		return nil, err
+	}
+	return format.Source(buf.Bytes())
+}
+
+func newWrapperArg(p query.Param) (wrapperArg, error) {
+	typ, err := value.ParseType(p.Type)
+	if err != nil {
+		return wrapperArg{}, err
+	}
+	arg := wrapperArg{Name: p.Name, Var: unexportedName(p.Name), Optional: p.Optional()}
+	deref := arg.Var
+	if arg.Optional {
+		deref = "*" + arg.Var
+	}
+	switch value.TypeName(typ) {
+	case "Int":
+		arg.GoType, arg.Convert = "int64", fmt.Sprintf("value.Int(%s)", deref)
+	case "Double":
+		arg.GoType, arg.Convert = "float64", fmt.Sprintf("value.Double(%s)", deref)
+	case "String":
+		arg.GoType, arg.Convert = "string", fmt.Sprintf("value.String(%s)", deref)
+	case "Bool":
+		arg.GoType, arg.Convert = "bool", fmt.Sprintf("value.Bool(%s)", deref)
+	default:
+		// values can be nil so they are not passed as pointers
+		return wrapperArg{Name: p.Name, Var: arg.Var, GoType: "value.Value", Convert: arg.Var, Optional: arg.Optional}, nil
+	}
+	if arg.Optional {
+		arg.GoType = "*" + arg.GoType
+	}
+	return arg, nil
+}
+
+// exportedName converts names like "top_items" to "TopItems"
+func exportedName(name string) string {
+	var sb strings.Builder
+	for _, part := range strings.FieldsFunc(name, func(r rune) bool { return !unicode.IsLetter(r) && !unicode.IsDigit(r) }) {
+		rs := []rune(part)
+		sb.WriteRune(unicode.ToUpper(rs[0]))
+		sb.WriteString(string(rs[1:]))
+	}
+	ret := sb.String()
+	if len(ret) == 0 || unicode.IsDigit([]rune(ret)[0]) {
+		ret = "Q" + ret
+	}
+	return ret
+}
+
+// unexportedName converts names like "max_items" to "maxItems"
+func unexportedName(name string) string {
+	rs := []rune(exportedName(name))
+	rs[0] = unicode.ToLower(rs[0])
+	ret := string(rs)
+	switch ret {
+	case "c", "args", "value", "client":
+		// avoid shadowing the names used by the wrapper
+		ret += "_"
+	}
+	return ret
+}
@@ -0,0 +1,48 @@
+package client
+
+import (
+	"strings"
+	"testing"
+
+	"fennel/lib/query"
+	"fennel/lib/value"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGenerateQueryWrappers(t *testing.T) {
+	queries := []query.QuerySer{
+		{
+			Name:        "top_items",
+			Description: "returns the top items of a user",
+			Params: query.Params{
+				{Name: "uid", Type: "Int", Description: "id of the user"},
+				{Name: "max_items", Type: "Double", Default: value.Double(10)},
+				{Name: "filters", Type: "Dict", Default: value.NewDict(nil)},
+			},
+		},
+		// queries without params are skipped
+		{Name: "untyped"},
+	}
+	src, err := GenerateQueryWrappers("queries", queries)
+	assert.NoError(t, err)
+	s := string(src)
+	assert.True(t, strings.HasPrefix(s, "// Code generated by fennel/client. DO NOT EDIT."))
+	assert.Contains(t, s, "package queries")
+	assert.Contains(t, s, "func RunTopItems(c *client.Client, uid int64, maxItems *float64, filters value.Value) (value.Value, error)")
+	assert.Contains(t, s, "// uid: id of the user")
+	assert.Contains(t, s, `args.Set("max_items", value.Double(*maxItems))`)
+	assert.Contains(t, s, `return c.RunQuery("top_items", args)`)
+	assert.NotContains(t, s, "Untyped")
+
+	_, err = GenerateQueryWrappers("queries", []query.QuerySer{{Name: "bad", Params: query.Params{{Name: "x", Type: "Integer"}}}})
+	assert.Error(t, err)
+}
+
+func TestExportedName(t *testing.T) {
+	assert.Equal(t, "TopItems", exportedName("top_items"))
+	assert.Equal(t, "TopItemsV2", exportedName("top-items.v2"))
+	assert.Equal(t, "Q1st", exportedName("1st"))
+	assert.Equal(t, "maxItems", unexportedName("max_items"))
+	assert.Equal(t, "c_", unexportedName("c"))
+}
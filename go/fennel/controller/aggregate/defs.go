@@ -14,6 +14,8 @@ import (
 	"fennel/lib/phaser"
 	modelAgg "fennel/model/aggregate"
 	"fennel/tier"
+
+	"go.uber.org/zap"
 )
 
 // Data is kept in redis OFFLINE_AGG_TTL_MULTIPLIER times the update frequency
@@ -50,13 +52,15 @@ func Store(ctx context.Context, tier tier.Tier, agg aggregate.Aggregate) error {
 	if err != nil {
 		if errors.Is(err, aggregate.ErrNotFound) {
 			if agg.IsOffline() {
-				// If offline aggregate, write to AWS Glue
-				err := tier.GlueClient.ScheduleOfflineAggregate(tier.ID, agg)
-				if err != nil {
-					return err
+				// If offline aggregate, write to AWS Glue unless countaggr computes it natively
+				if !tier.Args.IsNative() {
+					err := tier.GlueClient.ScheduleOfflineAggregate(tier.ID, agg)
+					if err != nil {
+						return err
+					}
 				}
 				for _, duration := range agg.Options.Durations {
-					prefix := fmt.Sprintf("t_%d/%s-%d", int(tier.ID), agg.Name, duration)
+					prefix := offlinePrefix(tier.ID, agg.Name, duration)
 					aggPhaserIdentifier := fmt.Sprintf("%s-%d", agg.Name, duration)
 					ttl, err := getUpdateFrequency(agg.Options.CronSchedule)
 					if err != nil {
@@ -97,14 +101,13 @@ func Store(ctx context.Context, tier tier.Tier, agg aggregate.Aggregate) error {
 			}
 			agg.Active = true
 			if agg.Options.AggType == "knn" {
-				if tier.MilvusClient.IsAbsent() {
-					return fmt.Errorf("milvus client is not configured for this tier")
-				} else {
-					// Call into Milvus to create the knn index
-					err = tier.MilvusClient.MustGet().CreateKNNIndex(ctx, agg, tier.ID)
-					if err != nil {
-						return err
-					}
+				index, err := getKNNIndex(tier, agg)
+				if err != nil {
+					return err
+				}
+				// Call into the backend to create the knn index
+				if err = index.CreateKNNIndex(ctx, agg, tier.ID); err != nil {
+					return err
 				}
 			}
 			// Store aggregate in db.
@@ -112,6 +115,7 @@ func Store(ctx context.Context, tier tier.Tier, agg aggregate.Aggregate) error {
 			if err != nil {
 				return err
 			}
+			tier.Invalidations.Invalidate(ctx, missingCacheNamespace, missingCacheKey(agg.Name))
 			// Forward online aggregate definition to nitrous.
 			if !agg.IsOffline() && !agg.IsAutoML() && !agg.IsForever() {
 				// Note: we retrieve the aggregate back from the db since agg.Id
@@ -138,6 +142,7 @@ func Store(ctx context.Context, tier tier.Tier, agg aggregate.Aggregate) error {
 				if err != nil {
 					return fmt.Errorf("failed to reactivate aggregate '%s': %w", agg.Name, err)
 				}
+				tier.Invalidations.Invalidate(ctx, missingCacheNamespace, missingCacheKey(agg.Name))
 			}
 			// Forward online aggregates to nitrous if the client has been initialized.
 			// We do this even if the aggregate has been previously defined.
@@ -151,6 +156,19 @@ func Store(ctx context.Context, tier tier.Tier, agg aggregate.Aggregate) error {
 	}
 }
 
+const missingCacheNamespace = "AggMissing"
+const missingCacheDuration = 10 * time.Second
+
+// missingAggregate is the cached result of retrieving an aggregate that is not found or not active
+type missingAggregate struct {
+	agg aggregate.Aggregate
+	err error
+}
+
+func missingCacheKey(name ftypes.AggName) string {
+	return fmt.Sprintf("aggregate_missing:%s", name)
+}
+
 func Retrieve(ctx context.Context, tier tier.Tier, aggname ftypes.AggName) (aggregate.Aggregate, error) {
 	empty := aggregate.Aggregate{}
 	if len(aggname) == 0 {
@@ -158,12 +176,24 @@ func Retrieve(ctx context.Context, tier tier.Tier, aggname ftypes.AggName) (aggr
 	}
 	var agg aggregate.Aggregate
 	if def, ok := tier.AggregateDefs.Load(aggname); !ok {
+		// aggregates that are missing or inactive are cached briefly so that requests for them
+		// do not all hit the DB
+		if v, ok := tier.PCache.Get(missingCacheKey(aggname), missingCacheNamespace); ok {
+			if m, ok := v.(missingAggregate); ok {
+				return m.agg, m.err
+			}
+		}
 		var err error
 		agg, err = modelAgg.Retrieve(ctx, tier, aggname)
-		if err != nil {
+		if errors.Is(err, aggregate.ErrNotFound) {
+			err = fmt.Errorf("failed to get aggregate: %w", err)
+			tier.PCache.SetWithTTL(missingCacheKey(aggname), missingAggregate{empty, err}, 0, missingCacheDuration, missingCacheNamespace)
+			return empty, err
+		} else if err != nil {
 			return empty, fmt.Errorf("failed to get aggregate: %w", err)
 		}
 		if !agg.Active {
+			tier.PCache.SetWithTTL(missingCacheKey(aggname), missingAggregate{agg, aggregate.ErrNotActive}, 0, missingCacheDuration, missingCacheNamespace)
 			return agg, aggregate.ErrNotActive
 		}
 		tier.AggregateDefs.Store(aggname, agg)
@@ -196,6 +226,16 @@ func RunAggregate(ctx context.Context, tier tier.Tier, aggname ftypes.AggName, d
 	if !found {
 		return fmt.Errorf("duration %d not found in aggregate %s", duration, aggname)
 	}
+	if tier.Args.IsNative() {
+		// the run can take long, so it continues after the request returns
+		agg.Options.Durations = []uint32{uint32(duration)}
+		go func() {
+			if err := RunOffline(context.Background(), tier, agg); err != nil {
+				tier.Logger.Error("failed to run offline aggregate", zap.String("name", string(aggname)), zap.Int("duration", duration), zap.Error(err))
+			}
+		}()
+		return nil
+	}
 	return tier.GlueClient.StartAggregate(tier.ID, agg, duration)
 }
 
@@ -227,8 +267,10 @@ func Deactivate(ctx context.Context, tier tier.Tier, aggname ftypes.AggName) err
 	} else {
 		// deactive trigger only if the aggregate is offline
 		if agg.IsOffline() {
-			if err := tier.GlueClient.DeactivateOfflineAggregate(tier.ID, string(aggname)); err != nil {
-				return err
+			if !tier.Args.IsNative() {
+				if err := tier.GlueClient.DeactivateOfflineAggregate(tier.ID, string(aggname)); err != nil {
+					return err
+				}
 			}
 			for _, duration := range agg.Options.Durations {
 				aggPhaserIdentifier := fmt.Sprintf("%s-%d", agg.Name, duration)
@@ -239,19 +281,23 @@ func Deactivate(ctx context.Context, tier tier.Tier, aggname ftypes.AggName) err
 			}
 		}
 		if agg.Options.AggType == "knn" {
-			if tier.MilvusClient.IsAbsent() {
-				return fmt.Errorf("milvus client is not configured for this tier")
-			} else {
-				// Call into Milvus to delete the knn index
-				err = tier.MilvusClient.MustGet().DeleteCollection(ctx, agg.Name, tier.ID)
-				if err != nil {
-					return err
-				}
+			index, err := getKNNIndex(tier, agg)
+			if err != nil {
+				return err
+			}
+			// Call into the backend to delete the knn index
+			if err = index.DeleteCollection(ctx, agg.Name, tier.ID); err != nil {
+				return err
 			}
 		}
 
 		// Disable online & offline aggregates
-		err = modelAgg.Deactivate(ctx, tier, aggname)
-		return err
+		if err = modelAgg.Deactivate(ctx, tier, aggname); err != nil {
+			return err
+		}
+		// other processes should stop serving the aggregate as well
+		tier.Invalidations.Invalidate(ctx, aggregate.DEFS_CACHE_NAMESPACE, string(aggname))
+		tier.Invalidations.Invalidate(ctx, cacheNamespace)
+		return nil
 	}
 }
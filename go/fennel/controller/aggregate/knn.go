@@ -0,0 +1,78 @@
+package aggregate
+
+import (
+	"context"
+	"fmt"
+
+	"fennel/ann"
+	"fennel/lib/aggregate"
+	"fennel/lib/ftypes"
+	"fennel/lib/value"
+	"fennel/tier"
+)
+
+// knnIndex is implemented by each backend that can serve knn aggregates
+type knnIndex interface {
+	CreateKNNIndex(ctx context.Context, agg aggregate.Aggregate, tierId ftypes.RealmID) error
+	InsertStream(ctx context.Context, agg aggregate.Aggregate, table value.List, tierId ftypes.RealmID) error
+	GetNeighbors(ctx context.Context, agg aggregate.Aggregate, vectors []value.Value, kwarg value.Dict, tierId ftypes.RealmID) ([]value.Value, error)
+	GetEmbedding(ctx context.Context, agg aggregate.Aggregate, keys value.List, tierId ftypes.RealmID) ([]value.Value, error)
+	DeleteCollection(ctx context.Context, aggName ftypes.AggName, tierId ftypes.RealmID) error
+}
+
+// getKNNIndex returns the backend configured for the knn aggregate - milvus unless the aggregate
+// sets the "backend" hyper parameter to "embedded"
+func getKNNIndex(tier tier.Tier, agg aggregate.Aggregate) (knnIndex, error) {
+	backend, err := ann.Backend(agg)
+	if err != nil {
+		return nil, err
+	}
+	if backend == ann.EMBEDDED {
+		if tier.AnnClient.IsAbsent() {
+			return nil, fmt.Errorf("embedded knn index is not configured for this tier")
+		}
+		return tier.AnnClient.MustGet(), nil
+	}
+	if tier.MilvusClient.IsAbsent() {
+		return nil, fmt.Errorf("milvus client is not configured for this tier")
+	}
+	return tier.MilvusClient.MustGet(), nil
+}
+
+// Neighbors returns the k nearest neighbors of each of the vectors in the knn aggregate. If exact
+// is set, neighbors are found by comparing with every vector in the index (only supported by the
+// embedded backend, milvus always searches its index)
+func Neighbors(ctx context.Context, tier tier.Tier, aggName ftypes.AggName, vectors []value.Value, k int, exact bool) ([]value.Value, error) {
+	agg, err := Retrieve(ctx, tier, aggName)
+	if err != nil {
+		return nil, err
+	}
+	if agg.Options.AggType != aggregate.KNN {
+		return nil, fmt.Errorf("aggregate %s is of type %s, expected knn", aggName, agg.Options.AggType)
+	}
+	index, err := getKNNIndex(tier, agg)
+	if err != nil {
+		return nil, err
+	}
+	kwargs := value.NewDict(map[string]value.Value{"topK": value.Int(k)})
+	if exact {
+		kwargs.Set("exact", value.Bool(true))
+	}
+	return index.GetNeighbors(ctx, agg, vectors, kwargs, tier.ID)
+}
+
+// Embeddings returns the vectors stored in the knn aggregate for the given ids
+func Embeddings(ctx context.Context, tier tier.Tier, aggName ftypes.AggName, ids value.List) ([]value.Value, error) {
+	agg, err := Retrieve(ctx, tier, aggName)
+	if err != nil {
+		return nil, err
+	}
+	if agg.Options.AggType != aggregate.KNN {
+		return nil, fmt.Errorf("aggregate %s is of type %s, expected knn", aggName, agg.Options.AggType)
+	}
+	index, err := getKNNIndex(tier, agg)
+	if err != nil {
+		return nil, err
+	}
+	return index.GetEmbedding(ctx, agg, ids, tier.ID)
+}
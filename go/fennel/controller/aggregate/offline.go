@@ -0,0 +1,161 @@
+package aggregate
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"fennel/lib/aggregate"
+	"fennel/lib/ftypes"
+	"fennel/lib/offline"
+	"fennel/lib/phaser"
+	"fennel/lib/value"
+	modelAction "fennel/model/action"
+	"fennel/tier"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"go.uber.org/zap"
+)
+
+// offlineScanBatchSize is the number of actions read from the db, and transformed, at a time
+const offlineScanBatchSize = 10000
+
+var offlineRunSeconds = promauto.NewGaugeVec(prometheus.GaugeOpts{
+	Name: "offline_aggregate_run_seconds",
+	Help: "Duration of the last run of offline aggregates by the native engine",
+}, []string{"aggregate"})
+
+var offlineRunRows = promauto.NewGaugeVec(prometheus.GaugeOpts{
+	Name: "offline_aggregate_rows",
+	Help: "Number of rows published by the last run of offline aggregates by the native engine",
+}, []string{"aggregate", "duration"})
+
+// offlinePrefix is the S3 prefix that is served by the phaser of the aggregate & duration
+func offlinePrefix(tierID ftypes.RealmID, aggName ftypes.AggName, duration uint32) string {
+	return fmt.Sprintf("t_%d/%s-%d", int(tierID), aggName, duration)
+}
+
+// RunOffline computes the offline aggregate for each of its durations with the native engine. The
+// actions of the longest duration are scanned in ranges of action ID and transformed by the query of
+// the aggregate, the results are published under the S3 prefixes served by the phasers of the aggregate.
+func RunOffline(ctx context.Context, tier tier.Tier, agg aggregate.Aggregate) error {
+	if !agg.IsOffline() {
+		return fmt.Errorf("only offline computed aggregates can be run")
+	}
+	if agg.Source == aggregate.SOURCE_PROFILE {
+		return fmt.Errorf("offline aggregates of profiles are not supported")
+	}
+	start := tier.Clock.Now()
+	args := tier.Args.OfflineArgs
+	dir, err := os.MkdirTemp(args.OfflineAggSpillDir, "offline-agg")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(dir)
+
+	var maxDuration uint32
+	aggregators := make([]offline.Aggregator, len(agg.Options.Durations))
+	for i, d := range agg.Options.Durations {
+		aggregators[i], err = offline.NewAggregator(agg.Options.AggType, agg.Options.HyperParameters, int(agg.Options.Limit), dir, args.OfflineAggMaxEntries)
+		if err != nil {
+			return err
+		}
+		if d > maxDuration {
+			maxDuration = d
+		}
+	}
+	now := ftypes.Timestamp(start.Unix())
+	var after ftypes.IDType
+	for {
+		actions, err := modelAction.Scan(ctx, tier, after, now-ftypes.Timestamp(maxDuration), offlineScanBatchSize)
+		if err != nil {
+			return fmt.Errorf("failed to scan actions: %w", err)
+		}
+		if len(actions) == 0 {
+			break
+		}
+		after = actions[len(actions)-1].ActionID
+		table, err := Transform(tier, actions, agg.Query)
+		if err != nil {
+			return fmt.Errorf("failed to transform actions: %w", err)
+		}
+		for i := 0; i < table.Len(); i++ {
+			row, _ := table.At(i)
+			rowDict, ok := row.(value.Dict)
+			if !ok {
+				return fmt.Errorf("query of aggregate should produce dicts but produced: %v", row)
+			}
+			ts, ok := rowDict.GetUnsafe("timestamp").(value.Int)
+			if !ok {
+				return fmt.Errorf("timestamp of row should be an int but was: %v", rowDict.GetUnsafe("timestamp"))
+			}
+			for j, d := range agg.Options.Durations {
+				if ftypes.Timestamp(ts) <= now-ftypes.Timestamp(d) {
+					continue
+				}
+				if err = aggregators[j].Add(rowDict.GetUnsafe("groupkey"), rowDict.GetUnsafe("value")); err != nil {
+					return err
+				}
+			}
+		}
+		if len(actions) < offlineScanBatchSize {
+			break
+		}
+	}
+	for i, d := range agg.Options.Durations {
+		if err = publishOffline(tier, agg, d, aggregators[i], dir, start); err != nil {
+			return fmt.Errorf("failed to publish aggregate '%s' of duration %d: %w", agg.Name, d, err)
+		}
+	}
+	offlineRunSeconds.WithLabelValues(string(agg.Name)).Set(tier.Clock.Now().Sub(start).Seconds())
+	return nil
+}
+
+// publishOffline writes the result of the aggregator as json lines of {"key", "value"} and uploads it
+// along with a success file named after the version, which makes the phaser serve the new version
+func publishOffline(tier tier.Tier, agg aggregate.Aggregate, duration uint32, aggregator offline.Aggregator, dir string, now time.Time) error {
+	f, err := os.CreateTemp(dir, "result-*.json")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(f.Name())
+	defer f.Close()
+	w := bufio.NewWriter(f)
+	rows := 0
+	err = aggregator.Emit(func(key value.Value, v value.Value) error {
+		rows++
+		row := value.NewDict(map[string]value.Value{"key": key, "value": v})
+		if _, err := w.Write(value.ToJSON(row)); err != nil {
+			return err
+		}
+		return w.WriteByte('\n')
+	})
+	if err != nil {
+		return err
+	}
+	if err = w.Flush(); err != nil {
+		return err
+	}
+	if _, err = f.Seek(0, 0); err != nil {
+		return err
+	}
+	now = now.UTC()
+	prefix := fmt.Sprintf("%s/month=%02d/day=%02d/%02d:%02d/%s", offlinePrefix(tier.ID, agg.Name, duration),
+		int(now.Month()), now.Day(), now.Hour(), now.Minute(), strings.ToLower(string(agg.Options.AggType)))
+	bucket := tier.Args.OfflineAggBucket
+	if err = tier.S3Client.Upload(f, prefix+"/part-00000.json", bucket); err != nil {
+		return err
+	}
+	success := fmt.Sprintf("%s/%s%d", prefix, phaser.SUCCESS_PREFIX, now.Unix())
+	if err = tier.S3Client.Upload(bytes.NewReader(nil), success, bucket); err != nil {
+		return err
+	}
+	offlineRunRows.WithLabelValues(string(agg.Name), fmt.Sprint(duration)).Set(float64(rows))
+	tier.Logger.Info("Published offline aggregate", zap.String("name", string(agg.Name)), zap.Uint32("duration", duration), zap.Int("rows", rows))
+	return nil
+}
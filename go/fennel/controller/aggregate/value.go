@@ -20,6 +20,7 @@ import (
 	"fennel/lib/phaser"
 	"fennel/lib/profile"
 	"fennel/lib/value"
+	"fennel/pcache"
 	"fennel/tier"
 
 	"go.uber.org/zap"
@@ -27,6 +28,25 @@ import (
 
 const cacheValueDuration = 2 * time.Minute
 
+// namespace of aggregate values in the process-level cache
+const cacheNamespace = "AggValue"
+
+// values are served for a while after they expire, while they are refreshed in the background,
+// to avoid a spike in latency for popular keys every time their value expires
+const cacheGraceDuration = 1 * time.Minute
+
+var cacheOptions = pcache.LoadOptions{
+	Namespace: cacheNamespace,
+	TTL:       cacheValueDuration,
+	Grace:     cacheGraceDuration,
+	Cost: func(key string, v interface{}) int64 {
+		if val, ok := v.(value.Value); ok {
+			return int64(len(key) + len(val.String()))
+		}
+		return int64(len(key))
+	},
+}
+
 // increment this to invalidate all existing cache keys for aggregate
 var cacheVersion = 0
 
@@ -42,22 +62,21 @@ func Value(
 	}
 
 	ckey := makeCacheKey(name, key, kwargs)
-	// If already present in cache and no failure interpreting it, return directly
-	if v, ok := tier.PCache.Get(ckey, "AggValue"); ok {
-		if val, ok2 := fromCacheValue(tier, v); ok2 {
-			return val, nil
+	vals, err := tier.PCache.GetOrLoad(ctx, []string{ckey}, cacheOptions, func(ctx context.Context, _ []string) ([]interface{}, error) {
+		val, err := unitValue(ctx, tier, name, key, kwargs)
+		if err != nil {
+			return nil, err
 		}
-	}
-	// otherwise compute value and store in cache
-	val, err := unitValue(ctx, tier, name, key, kwargs)
+		return []interface{}{val}, nil
+	})
 	if err != nil {
 		return nil, err
 	}
-
-	if !tier.PCache.SetWithTTL(ckey, val, int64(len(ckey)+len(val.String())), cacheValueDuration, "AggValue") {
-		tier.Logger.Debug(fmt.Sprintf("failed to set aggregate value in cache: key: '%s' value: '%s'", ckey, val.String()))
+	if val, ok := fromCacheValue(tier, vals[0]); ok {
+		return val, nil
 	}
-	return val, nil
+	// the cached value could not be interpreted, so compute it again
+	return unitValue(ctx, tier, name, key, kwargs)
 }
 
 func BatchValue(ctx context.Context, tier tier.Tier, batch []aggregate.GetAggValueRequest) ([]value.Value, error) {
@@ -65,50 +84,40 @@ func BatchValue(ctx context.Context, tier tier.Tier, batch []aggregate.GetAggVal
 		return batchValue(ctx, tier, batch)
 	}
 
-	ret := make([]value.Value, len(batch))
-	uckeys := make([]string, len(batch))
-	uncachedReqs := make([]aggregate.GetAggValueRequest, len(batch))
-	ptr := make([]int, len(batch))
-	seen := make(map[string]int, len(batch))
-
-	j := 0
+	ckeys := make([]string, len(batch))
+	reqs := make(map[string]aggregate.GetAggValueRequest, len(batch))
 	for i, req := range batch {
-		ckey := makeCacheKey(req.AggName, req.Key, req.Kwargs)
-		if v, ok := tier.PCache.Get(ckey, "AggValue"); ok {
-			if val, found := fromCacheValue(tier, v); found {
-				ret[i] = val
-				ptr[i] = -1
-			}
+		ckeys[i] = makeCacheKey(req.AggName, req.Key, req.Kwargs)
+		reqs[ckeys[i]] = req
+	}
+	// duplicate and concurrent requests of the same key are loaded once
+	vals, err := tier.PCache.GetOrLoad(ctx, ckeys, cacheOptions, func(ctx context.Context, keys []string) ([]interface{}, error) {
+		uncached := make([]aggregate.GetAggValueRequest, len(keys))
+		for i, k := range keys {
+			uncached[i] = reqs[k]
 		}
-		// check if we could get it from cache or not
-		if ptr[i] != -1 {
-			// not in cache, so we may do a ground truth pull
-			// but first check if this is a duplicate request
-			if idx, repeat := seen[ckey]; repeat {
-				ptr[i] = idx // duplicate, so use the same index
-			} else {
-				// if not duplicate, add to uncached requests
-				seen[ckey] = j
-				uckeys[j] = ckey
-				uncachedReqs[j] = req
-				ptr[i] = j
-				j += 1
-			}
+		ucvals, err := batchValue(ctx, tier, uncached)
+		if err != nil {
+			return nil, err
 		}
-	}
-	ucvals, err := batchValue(ctx, tier, uncachedReqs[:j])
+		ret := make([]interface{}, len(ucvals))
+		for i, v := range ucvals {
+			ret[i] = v
+		}
+		return ret, nil
+	})
 	if err != nil {
 		return nil, err
 	}
-	for i := range batch {
-		idx := ptr[i]
-		if idx < 0 {
-			continue
-		}
-		ret[i] = ucvals[idx]
-		if ok := tier.PCache.SetWithTTL(uckeys[idx], ucvals[idx], int64(len(uckeys[idx])+len(ucvals[idx].String())), cacheValueDuration, "AggValue"); !ok {
-			tier.Logger.Debug(fmt.Sprintf("failed to set aggregate value in cache: key: '%s' value: '%s'", uckeys[idx], ucvals[idx].String()))
+	ret := make([]value.Value, len(batch))
+	for i, v := range vals {
+		val, ok := fromCacheValue(tier, v)
+		if !ok {
+			if val, err = unitValue(ctx, tier, batch[i].AggName, batch[i].Key, batch[i].Kwargs); err != nil {
+				return nil, err
+			}
 		}
+		ret[i] = val
 	}
 	return ret, nil
 }
@@ -290,10 +299,11 @@ func fetchForeverAggregates(ctx context.Context, tier tier.Tier, aggMap map[ftyp
 	}
 
 	if len(foreverPtr) > 0 {
-		if tier.MilvusClient.IsAbsent() {
-			return numSlotsLeft, fmt.Errorf("error: Milvus client is not initialized")
+		index, err := getKNNIndex(tier, foreverAgg)
+		if err != nil {
+			return numSlotsLeft, err
 		}
-		nn, err := tier.MilvusClient.MustGet().GetNeighbors(ctx, foreverAgg, foreverKeys, foreverKwarags, tier.ID)
+		nn, err := index.GetNeighbors(ctx, foreverAgg, foreverKeys, foreverKwarags, tier.ID)
 		if err != nil {
 			return numSlotsLeft, err
 		}
@@ -378,14 +388,13 @@ func Update[I action.Action | profile.ProfileItem](ctx context.Context, tier tie
 			return fmt.Errorf("forever aggregates are not supported for aggregate %s", agg.Name)
 		}
 		// Update the aggregate
-		if tier.MilvusClient.IsAbsent() {
-			return fmt.Errorf("error: Milvus client is not initialized")
-		} else {
-			// Use milvus library to update the index with all actions
-			err = tier.MilvusClient.MustGet().InsertStream(ctx, agg, table, tier.ID)
-			if err != nil {
-				return fmt.Errorf("failed to insert stream into milvus: %w", err)
-			}
+		index, err := getKNNIndex(tier, agg)
+		if err != nil {
+			return err
+		}
+		// Update the index with all actions
+		if err = index.InsertStream(ctx, agg, table, tier.ID); err != nil {
+			return fmt.Errorf("failed to insert stream into knn index: %w", err)
 		}
 		return nil
 	} else if agg.IsAutoML() {
@@ -223,7 +223,7 @@ func TestCachedValueAll(t *testing.T) {
 	// test TTL set properly
 	ttl, ok := tier.PCache.GetTTL(makeCacheKey(agg.Name, key, kwargs))
 	assert.True(t, ok)
-	assert.LessOrEqual(t, ttl, cacheValueDuration)
+	assert.LessOrEqual(t, ttl, cacheValueDuration+cacheGraceDuration)
 
 	// test batch now
 	agg1, agg2, agg3 := agg, agg, agg
@@ -274,7 +274,7 @@ func TestCachedValueAll(t *testing.T) {
 	for _, req := range reqs {
 		ttl, ok := tier.PCache.GetTTL(makeCacheKey(req.AggName, req.Key, req.Kwargs))
 		assert.True(t, ok)
-		assert.LessOrEqual(t, ttl, cacheValueDuration)
+		assert.LessOrEqual(t, ttl, cacheValueDuration+cacheGraceDuration)
 	}
 }
 
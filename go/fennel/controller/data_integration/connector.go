@@ -5,7 +5,9 @@ import (
 	"errors"
 	"fennel/kafka"
 	"fennel/lib/data_integration"
+	"fennel/lib/ftypes"
 	"fennel/lib/value"
+	"fennel/model/checkpoint"
 	connectorModel "fennel/model/data_integration"
 	"github.com/zeebo/xxh3"
 	"time"
@@ -26,19 +28,27 @@ func StoreConnector(ctx context.Context, tier tier.Tier, conn data_integration.C
 		return err
 	}
 
-	if tier.AirbyteClient.IsAbsent() {
-		return fmt.Errorf("error: Airbyte client is not initialized")
-	}
-
 	source, err := connectorModel.RetrieveSource(ctx, tier, conn.SourceName)
 	if err != nil {
 		return fmt.Errorf("error: failed to retrieve source: %w", err)
 	}
+	native := isNative(source)
+	if native {
+		if err = validateNativeConnector(source, conn); err != nil {
+			return err
+		}
+	} else if tier.AirbyteClient.IsAbsent() {
+		return fmt.Errorf("error: Airbyte client is not initialized")
+	}
 	conn2, err := connectorModel.Retrieve(ctx, tier, conn.Name)
 
 	if err != nil {
 		if errors.Is(err, data_integration.ErrConnNotFound) {
 			tier.Logger.Debug("Storing new connector: " + conn.Name)
+			if native {
+				// native connectors have no airbyte connection id, they are run by countaggr
+				return connectorModel.Store(ctx, tier, conn, "")
+			}
 			// Write the connector to Airbyte
 			connId, err := tier.AirbyteClient.MustGet().CreateConnector(source, conn)
 			if err != nil {
@@ -59,7 +69,14 @@ func StoreConnector(ctx context.Context, tier tier.Tier, conn data_integration.C
 			return nil
 		} else {
 			// Update the connector in Airbyte
-			if conn.Version > conn2.Version {
+			if conn.Version > conn2.Version && native {
+				tier.Logger.Debug("Updating native connector: " + conn.Name)
+				// the checkpointed cursor is kept, so only data that arrives after the update uses the new query
+				if err = connectorModel.Update(ctx, tier, conn); err != nil {
+					return fmt.Errorf("failed to update connector '%s': %w", conn.Name, err)
+				}
+				return nil
+			} else if conn.Version > conn2.Version {
 				tier.Logger.Debug("Updating connector: " + conn.Name)
 				conn.ConnId = conn2.ConnId
 				if err = tier.AirbyteClient.MustGet().DisableConnector(source, conn); err != nil {
@@ -95,6 +112,9 @@ func DisableConnector(ctx context.Context, tier tier.Tier, name string) error {
 		return nil
 	}
 	tier.Logger.Debug("Disabling active connector: " + conn.Name)
+	if isNativeConnector(conn) {
+		return connectorModel.Disable(ctx, tier, conn.Name)
+	}
 	if tier.AirbyteClient.IsAbsent() {
 		return fmt.Errorf("error: Airbyte client is not initialized")
 	}
@@ -114,11 +134,16 @@ func DeleteConnector(ctx context.Context, tier tier.Tier, name string) error {
 	if err != nil {
 		return fmt.Errorf("failed to retrieve connector: %w", err)
 	}
-	if tier.AirbyteClient.IsAbsent() {
-		return fmt.Errorf("error: Airbyte client is not initialized")
+	if !isNativeConnector(conn) {
+		if tier.AirbyteClient.IsAbsent() {
+			return fmt.Errorf("error: Airbyte client is not initialized")
+		}
+		if err = tier.AirbyteClient.MustGet().DeleteConnector(conn); err != nil {
+			return fmt.Errorf("error: failed to delete connector: %w", err)
+		}
 	}
-	if err = tier.AirbyteClient.MustGet().DeleteConnector(conn); err != nil {
-		return fmt.Errorf("error: failed to delete connector: %w", err)
+	if err = checkpoint.Set(ctx, tier, NATIVE_CHECKPOINT_TYPE, ftypes.AggName(name), 0); err != nil {
+		return fmt.Errorf("failed to reset cursor of connector: %w", err)
 	}
 	return connectorModel.Delete(ctx, tier, name)
 }
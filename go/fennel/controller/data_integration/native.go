@@ -0,0 +1,620 @@
+package data_integration
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"fennel/airbyte"
+	actionController "fennel/controller/action"
+	"fennel/controller/aggregate"
+	profileController "fennel/controller/profile"
+	"fennel/lib/action"
+	"fennel/lib/data_integration"
+	"fennel/lib/ftypes"
+	"fennel/lib/profile"
+	"fennel/lib/utils"
+	"fennel/lib/value"
+	"fennel/model/checkpoint"
+	connectorModel "fennel/model/data_integration"
+	"fennel/s3"
+	"fennel/tier"
+
+	"github.com/lib/pq"
+	"go.uber.org/zap"
+)
+
+/*
+	Native connectors are run by countaggr itself instead of by Airbyte - they are used for sources
+	created on tiers without an Airbyte server and for sources that Airbyte can not read (Local).
+	A native source/connector is one without an Airbyte id. The position up to which a connector has
+	read its source is stored as a checkpoint so that reading resumes from there after restarts:
+	  - for file sources (S3 & Local) it is the last modified time (in unix nanos) of the files read
+	  - for postgres it is the next value of the connector's cursor column to read from
+	The credentials of native postgres sources are kept in the secrets store of the tier, the db only
+	has the name of the secret.
+*/
+
+const (
+	NATIVE_CHECKPOINT_TYPE = ftypes.AggType("_native_connector")
+	// same field that airbyte sets on records read from S3
+	FILE_URL_FIELD = "_ab_source_file_url"
+)
+
+// isNative returns true if the source is read by the native connector runtime
+func isNative(src data_integration.Source) bool {
+	return src.GetSourceId() == ""
+}
+
+// isNativeConnector returns true if the connector is run by the native connector runtime
+func isNativeConnector(conn data_integration.Connector) bool {
+	return conn.ConnId == ""
+}
+
+// readNatively returns true if a new source should be read by the native connector runtime
+func readNatively(tier tier.Tier, src data_integration.Source) bool {
+	if _, ok := src.(data_integration.Local); ok {
+		return true
+	}
+	return tier.AirbyteClient.IsAbsent()
+}
+
+// localSourceDir returns the dir of a Local source, which must be under the local source dir of the
+// tier so that sources can not read arbitrary files of the host
+func localSourceDir(tier tier.Tier, dir string) (string, error) {
+	if len(tier.Args.LocalSourceDir) == 0 {
+		return "", fmt.Errorf("local sources are not supported as the local source dir is not set")
+	}
+	local, err := utils.WithinDir(tier.Args.LocalSourceDir, dir)
+	if err != nil {
+		return "", fmt.Errorf("dir '%s' is outside of the local source dir", dir)
+	}
+	return local, nil
+}
+
+func validateNativeSource(tier tier.Tier, src data_integration.Source) error {
+	switch s := src.(type) {
+	case data_integration.S3:
+		if s.Format != "csv" && s.Format != "json" {
+			return fmt.Errorf("format %s is not supported without airbyte, only csv and json are", s.Format)
+		}
+		return nil
+	case data_integration.Local:
+		_, err := localSourceDir(tier, s.Dir)
+		return err
+	case data_integration.Postgres:
+		if s.Username == "" {
+			return fmt.Errorf("username is required")
+		}
+		return nil
+	default:
+		return fmt.Errorf("source type %T is not supported without airbyte", src)
+	}
+}
+
+func validateNativeConnector(src data_integration.Source, conn data_integration.Connector) error {
+	if _, ok := src.(data_integration.Postgres); ok {
+		if conn.StreamName == "" {
+			return fmt.Errorf("stream_name is required and should be the table to read from")
+		}
+		if conn.CursorField == "" {
+			return fmt.Errorf("cursor_field is required and should be an integer or timestamp column of the table")
+		}
+	}
+	return nil
+}
+
+type credentials struct {
+	Username string `json:"username"`
+	Password string `json:"password"`
+}
+
+func credentialsSecret(tier tier.Tier, name string) string {
+	return fmt.Sprintf("t_%d/postgres_source/%s", tier.ID, name)
+}
+
+// storeCredentials moves the credentials of a native source to the secrets store and returns the
+// source with only the name of the secret set
+func storeCredentials(ctx context.Context, tier tier.Tier, src data_integration.Source) (data_integration.Source, error) {
+	s, ok := src.(data_integration.Postgres)
+	if !ok {
+		return src, nil
+	}
+	ser, err := json.Marshal(credentials{Username: s.Username, Password: s.Password})
+	if err != nil {
+		return nil, err
+	}
+	s.CredentialsSecret = credentialsSecret(tier, s.Name)
+	if err = tier.SecretsClient.Set(ctx, s.CredentialsSecret, string(ser)); err != nil {
+		return nil, fmt.Errorf("failed to store credentials of source: %w", err)
+	}
+	s.Username, s.Password = "", ""
+	return s, nil
+}
+
+func loadCredentials(ctx context.Context, tier tier.Tier, src data_integration.Postgres) (credentials, error) {
+	var c credentials
+	ser, err := tier.SecretsClient.Get(ctx, src.CredentialsSecret)
+	if err != nil {
+		return c, fmt.Errorf("failed to get credentials of source '%s': %w", src.Name, err)
+	}
+	err = json.Unmarshal([]byte(ser), &c)
+	return c, err
+}
+
+// deleteCredentials deletes the credentials of a native source from the secrets store
+func deleteCredentials(ctx context.Context, tier tier.Tier, src data_integration.Source) error {
+	if s, ok := src.(data_integration.Postgres); ok && s.CredentialsSecret != "" {
+		return tier.SecretsClient.Delete(ctx, s.CredentialsSecret)
+	}
+	return nil
+}
+
+// reader reads the records of a native source in the order of its cursor
+type reader interface {
+	// Read returns roughly n records starting at the cursor along with the cursor to read the next records from.
+	// Records sharing the same cursor position are always returned together
+	Read(ctx context.Context, cursor uint64, n int) ([]value.Value, uint64, error)
+	Close() error
+}
+
+func newReader(ctx context.Context, tier tier.Tier, src data_integration.Source, conn data_integration.Connector) (reader, error) {
+	switch s := src.(type) {
+	case data_integration.Local:
+		// the local source dir may have changed since the source was created
+		dir, err := localSourceDir(tier, s.Dir)
+		if err != nil {
+			return nil, err
+		}
+		return fileReader{store: localStore{dir: dir}, format: s.Format, delimiter: s.Delimiter}, nil
+	case data_integration.S3:
+		// native S3 sources are read with the credentials of the tier
+		store := s3Store{client: tier.S3Client, bucket: s.Bucket, prefix: s.PathPrefix}
+		return fileReader{store: store, format: s.Format, delimiter: s.Delimiter}, nil
+	case data_integration.Postgres:
+		c, err := loadCredentials(ctx, tier, s)
+		if err != nil {
+			return nil, err
+		}
+		return newPostgresReader(ctx, s, c, conn)
+	default:
+		return nil, fmt.Errorf("source type %T is not supported without airbyte", src)
+	}
+}
+
+// NativeRunner runs a native connector batch after batch. The reader of the source (e.g. the
+// connection pool of a postgres source) is kept open between batches, and the connector is
+// reloaded before every batch so that updates of the connector apply from the next batch on.
+type NativeRunner struct {
+	tier tier.Tier
+	name string
+	conn data_integration.Connector
+	src  data_integration.Source
+	r    reader
+}
+
+func NewNativeRunner(tier tier.Tier, name string) *NativeRunner {
+	return &NativeRunner{tier: tier, name: name}
+}
+
+// Run reads the next batch of records of the connector from its source, transforms them using the
+// query of the connector and writes them to its destination. It returns the number of records
+// that were read, zero if the connector has caught up with its source.
+func (n *NativeRunner) Run(ctx context.Context, batchSize int) (int, error) {
+	conn, err := connectorModel.Retrieve(ctx, n.tier, n.name)
+	if err != nil {
+		return 0, fmt.Errorf("failed to retrieve connector: %w", err)
+	}
+	// the reader depends on the stream and cursor of the connector, which can change on update
+	if n.r != nil && conn.Version != n.conn.Version {
+		_ = n.Close()
+	}
+	n.conn = conn
+	if n.r == nil {
+		n.src, err = connectorModel.RetrieveSource(ctx, n.tier, conn.SourceName)
+		if err != nil {
+			return 0, fmt.Errorf("failed to retrieve source: %w", err)
+		}
+		if n.r, err = newReader(ctx, n.tier, n.src, conn); err != nil {
+			return 0, err
+		}
+	}
+	cursor, err := checkpoint.Get(ctx, n.tier, NATIVE_CHECKPOINT_TYPE, ftypes.AggName(conn.Name))
+	if err != nil {
+		return 0, fmt.Errorf("failed to get cursor of connector: %w", err)
+	}
+	records, next, err := n.r.Read(ctx, uint64(cursor), batchSize)
+	if err != nil {
+		return 0, fmt.Errorf("failed to read source '%s': %w", n.src.GetSourceName(), err)
+	}
+	if len(records) == 0 {
+		return 0, nil
+	}
+	for _, record := range records {
+		d := record.(value.Dict)
+		d.Set(AIRBYTE_CONNECTOR_STREAM_FIELD, value.String(conn.StreamName))
+		d.Set(AIRBYTE_CONNECTOR_NAME_FIELD, value.String(conn.Name))
+	}
+	table, err := aggregate.Transform(n.tier, records, conn.Query)
+	if err != nil {
+		return 0, fmt.Errorf("failed to transform records: %w", err)
+	}
+	if err = ingest(ctx, n.tier, conn, table); err != nil {
+		return 0, err
+	}
+	if err = checkpoint.Set(ctx, n.tier, NATIVE_CHECKPOINT_TYPE, ftypes.AggName(conn.Name), ftypes.IDType(next)); err != nil {
+		return 0, fmt.Errorf("failed to set cursor of connector: %w", err)
+	}
+	return len(records), nil
+}
+
+// Close closes the reader of the source, a later Run opens it again
+func (n *NativeRunner) Close() error {
+	if n.r == nil {
+		return nil
+	}
+	err := n.r.Close()
+	n.r = nil
+	return err
+}
+
+// ingest writes the transformed records to the destination of the connector, rows that are not
+// valid actions/profiles are logged and skipped
+func ingest(ctx context.Context, tier tier.Tier, conn data_integration.Connector, table value.List) error {
+	switch conn.Destination {
+	case airbyte.ACTION_DESTINATION:
+		actions := make([]action.Action, 0, table.Len())
+		for i := 0; i < table.Len(); i++ {
+			row, _ := table.At(i)
+			d, ok := row.(value.Dict)
+			if !ok {
+				tier.Logger.Error("Connector query returned a non-dict row", zap.String("connector", conn.Name), zap.String("row", row.String()))
+				continue
+			}
+			a, err := action.FromValueDict(d)
+			if err != nil {
+				tier.Logger.Error("Error while converting to action:", zap.String("connector", conn.Name), zap.Error(err))
+				continue
+			}
+			actions = append(actions, a)
+		}
+		if err := actionController.BatchInsert(ctx, tier, actions); err != nil {
+			return fmt.Errorf("failed to insert actions: %w", err)
+		}
+	case airbyte.PROFILE_DESTINATION:
+		profiles := make([]profile.ProfileItem, 0, table.Len())
+		for i := 0; i < table.Len(); i++ {
+			row, _ := table.At(i)
+			d, ok := row.(value.Dict)
+			if !ok {
+				tier.Logger.Error("Connector query returned a non-dict row", zap.String("connector", conn.Name), zap.String("row", row.String()))
+				continue
+			}
+			p, err := profile.FromValueDict(d)
+			if err != nil {
+				tier.Logger.Error("Error while converting to profile:", zap.String("connector", conn.Name), zap.Error(err))
+				continue
+			}
+			profiles = append(profiles, p)
+		}
+		if err := profileController.SetMulti(ctx, tier, profiles); err != nil {
+			return fmt.Errorf("failed to set profiles: %w", err)
+		}
+	default:
+		return fmt.Errorf("invalid destination: %s", conn.Destination)
+	}
+	return nil
+}
+
+//================================================
+// File sources
+//================================================
+
+type file struct {
+	name         string
+	url          string
+	lastModified time.Time
+}
+
+type fileStore interface {
+	list() ([]file, error)
+	read(f file) ([]byte, error)
+}
+
+type localStore struct {
+	dir string
+}
+
+func (l localStore) list() ([]file, error) {
+	var files []file
+	err := filepath.WalkDir(l.dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			return nil
+		}
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		abs, err := filepath.Abs(path)
+		if err != nil {
+			return err
+		}
+		files = append(files, file{name: path, url: "file://" + abs, lastModified: info.ModTime()})
+		return nil
+	})
+	return files, err
+}
+
+func (l localStore) read(f file) ([]byte, error) {
+	return os.ReadFile(f.name)
+}
+
+type s3Store struct {
+	client s3.Client
+	bucket string
+	prefix string
+}
+
+func (s s3Store) list() ([]file, error) {
+	objects, err := s.client.ListObjects(s.bucket, s.prefix)
+	if err != nil {
+		return nil, err
+	}
+	files := make([]file, 0, len(objects))
+	for _, o := range objects {
+		// skip "directories"
+		if strings.HasSuffix(o.Key, "/") {
+			continue
+		}
+		files = append(files, file{name: o.Key, url: fmt.Sprintf("s3://%s/%s", s.bucket, o.Key), lastModified: o.LastModified})
+	}
+	return files, nil
+}
+
+func (s s3Store) read(f file) ([]byte, error) {
+	return s.client.Download(f.name, s.bucket)
+}
+
+// fileReader reads files in the order of their last modified time, the cursor is the last modified
+// time of the files that have been read. Files modified at the same time are read together.
+type fileReader struct {
+	store     fileStore
+	format    string
+	delimiter string
+}
+
+func (r fileReader) Read(_ context.Context, cursor uint64, n int) ([]value.Value, uint64, error) {
+	files, err := r.store.list()
+	if err != nil {
+		return nil, cursor, err
+	}
+	pending := make([]file, 0, len(files))
+	for _, f := range files {
+		if uint64(f.lastModified.UnixNano()) > cursor {
+			pending = append(pending, f)
+		}
+	}
+	sort.Slice(pending, func(i, j int) bool {
+		if !pending[i].lastModified.Equal(pending[j].lastModified) {
+			return pending[i].lastModified.Before(pending[j].lastModified)
+		}
+		return pending[i].name < pending[j].name
+	})
+	var records []value.Value
+	next := cursor
+	for _, f := range pending {
+		modified := uint64(f.lastModified.UnixNano())
+		if len(records) >= n && modified != next {
+			break
+		}
+		data, err := r.store.read(f)
+		if err != nil {
+			return nil, cursor, fmt.Errorf("failed to read file %s: %w", f.url, err)
+		}
+		rows, err := parse(data, r.format, r.delimiter)
+		if err != nil {
+			return nil, cursor, fmt.Errorf("failed to parse file %s: %w", f.url, err)
+		}
+		for _, row := range rows {
+			row.Set(FILE_URL_FIELD, value.String(f.url))
+			row.Set(data_integration.S3_CURSOR_FIELD, value.String(f.lastModified.UTC().Format(time.RFC3339Nano)))
+			records = append(records, row)
+		}
+		next = modified
+	}
+	return records, next, nil
+}
+
+func (r fileReader) Close() error {
+	return nil
+}
+
+// parse parses the records of a file which is either newline delimited json or csv with a header
+func parse(data []byte, format, delimiter string) ([]value.Dict, error) {
+	var rows []value.Dict
+	switch format {
+	case "json":
+		for i, line := range bytes.Split(data, []byte("\n")) {
+			if len(bytes.TrimSpace(line)) == 0 {
+				continue
+			}
+			v, err := value.FromJSON(line)
+			if err != nil {
+				return nil, fmt.Errorf("line %d: %w", i+1, err)
+			}
+			d, ok := v.(value.Dict)
+			if !ok {
+				return nil, fmt.Errorf("line %d: expected a json object but found: %s", i+1, v.String())
+			}
+			rows = append(rows, d)
+		}
+	case "csv":
+		cr := csv.NewReader(bytes.NewReader(data))
+		if len(delimiter) > 0 {
+			cr.Comma = rune(delimiter[0])
+		}
+		header, err := cr.Read()
+		if err == io.EOF {
+			return nil, nil
+		} else if err != nil {
+			return nil, err
+		}
+		for {
+			record, err := cr.Read()
+			if err == io.EOF {
+				break
+			} else if err != nil {
+				return nil, err
+			}
+			d := value.NewDict(make(map[string]value.Value, len(header)))
+			for i, col := range header {
+				d.Set(col, value.String(record[i]))
+			}
+			rows = append(rows, d)
+		}
+	default:
+		return nil, fmt.Errorf("unsupported format: %s", format)
+	}
+	return rows, nil
+}
+
+//================================================
+// Postgres
+//================================================
+
+// postgresReader incrementally reads a table in the order of an integer or timestamp cursor column.
+// Timestamps are read as microseconds since epoch. Only rows with non-negative cursor values are read.
+type postgresReader struct {
+	db     *sql.DB
+	table  string
+	column string
+	// key is the expression that evaluates the cursor column of a row as a bigint
+	key string
+	// lower is the expression of the smallest value of the cursor column that should be read
+	lower string
+}
+
+func newPostgresReader(ctx context.Context, src data_integration.Postgres, c credentials, conn data_integration.Connector) (reader, error) {
+	params, err := url.ParseQuery(src.JdbcParams)
+	if err != nil {
+		return nil, fmt.Errorf("invalid jdbc_params: %w", err)
+	}
+	// timestamps without time zone are interpreted as UTC
+	params.Set("timezone", "UTC")
+	dsn := url.URL{
+		Scheme:   "postgres",
+		User:     url.UserPassword(c.Username, c.Password),
+		Host:     src.Host + ":" + strconv.Itoa(src.Port),
+		Path:     "/" + src.Dbname,
+		RawQuery: params.Encode(),
+	}
+	db, err := sql.Open("postgres", dsn.String())
+	if err != nil {
+		return nil, err
+	}
+	// the reader is kept open across batches by NativeRunner and reads one query at a time
+	db.SetMaxOpenConns(1)
+	schema, table := "public", conn.StreamName
+	if i := strings.Index(table, "."); i >= 0 {
+		schema, table = table[:i], table[i+1:]
+	}
+	r := postgresReader{
+		db:     db,
+		table:  pq.QuoteIdentifier(schema) + "." + pq.QuoteIdentifier(table),
+		column: "t." + pq.QuoteIdentifier(conn.CursorField),
+	}
+	var dataType string
+	row := db.QueryRowContext(ctx, `SELECT data_type FROM information_schema.columns WHERE table_schema = $1 AND table_name = $2 AND column_name = $3`,
+		schema, table, conn.CursorField)
+	if err = row.Scan(&dataType); err != nil {
+		db.Close()
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, fmt.Errorf("column %s not found in table %s", conn.CursorField, conn.StreamName)
+		}
+		return nil, err
+	}
+	switch dataType {
+	case "smallint", "integer", "bigint":
+		r.key = r.column + "::bigint"
+		r.lower = "$1"
+	case "date", "timestamp without time zone", "timestamp with time zone":
+		r.key = "(extract(epoch from " + r.column + ") * 1000000)::bigint"
+		r.lower = "to_timestamp($1::double precision / 1000000)"
+	default:
+		db.Close()
+		return nil, fmt.Errorf("cursor column %s is of type %s, expected an integer or timestamp", conn.CursorField, dataType)
+	}
+	return r, nil
+}
+
+func (r postgresReader) Read(ctx context.Context, cursor uint64, n int) ([]value.Value, uint64, error) {
+	records, keys, err := r.query(ctx,
+		fmt.Sprintf(`SELECT row_to_json(t)::text, %s FROM %s t WHERE %s >= %s ORDER BY %s LIMIT $2`, r.key, r.table, r.column, r.lower, r.column),
+		int64(cursor), n)
+	if err != nil {
+		return nil, cursor, err
+	}
+	if len(records) == 0 {
+		return nil, cursor, nil
+	}
+	last := keys[len(keys)-1]
+	if len(records) == n {
+		// rows sharing the last cursor value may have been cut off by the limit so they are read separately
+		for len(keys) > 0 && keys[len(keys)-1] == last {
+			keys = keys[:len(keys)-1]
+			records = records[:len(records)-1]
+		}
+		rest, _, err := r.query(ctx,
+			fmt.Sprintf(`SELECT row_to_json(t)::text, %s FROM %s t WHERE %s >= %s AND %s = $1`, r.key, r.table, r.column, r.lower, r.key),
+			last)
+		if err != nil {
+			return nil, cursor, err
+		}
+		records = append(records, rest...)
+	}
+	return records, uint64(last) + 1, nil
+}
+
+func (r postgresReader) query(ctx context.Context, query string, args ...any) ([]value.Value, []int64, error) {
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer rows.Close()
+	var records []value.Value
+	var keys []int64
+	for rows.Next() {
+		var data string
+		var key int64
+		if err = rows.Scan(&data, &key); err != nil {
+			return nil, nil, err
+		}
+		record, err := value.FromJSON([]byte(data))
+		if err != nil {
+			return nil, nil, err
+		}
+		records = append(records, record)
+		keys = append(keys, key)
+	}
+	return records, keys, rows.Err()
+}
+
+func (r postgresReader) Close() error {
+	return r.db.Close()
+}
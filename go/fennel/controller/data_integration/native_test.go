@@ -0,0 +1,162 @@
+package data_integration
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"fennel/lib/data_integration"
+	"fennel/lib/value"
+	"fennel/secrets"
+	"fennel/tier"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func writeFile(t *testing.T, dir, name, content string, modified time.Time) {
+	path := filepath.Join(dir, name)
+	require.NoError(t, os.MkdirAll(filepath.Dir(path), 0o755))
+	require.NoError(t, os.WriteFile(path, []byte(content), 0o644))
+	require.NoError(t, os.Chtimes(path, modified, modified))
+}
+
+func TestParse(t *testing.T) {
+	rows, err := parse([]byte("{\"a\": 1, \"b\": \"x\"}\n\n{\"a\": [1, 2]}\n"), "json", "")
+	assert.NoError(t, err)
+	assert.Equal(t, []value.Dict{
+		value.NewDict(map[string]value.Value{"a": value.Int(1), "b": value.String("x")}),
+		value.NewDict(map[string]value.Value{"a": value.NewList(value.Int(1), value.Int(2))}),
+	}, rows)
+	_, err = parse([]byte("[1, 2]"), "json", "")
+	assert.Error(t, err)
+	_, err = parse([]byte("{\"a\": "), "json", "")
+	assert.Error(t, err)
+
+	rows, err = parse([]byte("a|b\n1|x\n2|\"y|z\"\n"), "csv", "|")
+	assert.NoError(t, err)
+	assert.Equal(t, []value.Dict{
+		value.NewDict(map[string]value.Value{"a": value.String("1"), "b": value.String("x")}),
+		value.NewDict(map[string]value.Value{"a": value.String("2"), "b": value.String("y|z")}),
+	}, rows)
+	rows, err = parse([]byte(""), "csv", ",")
+	assert.NoError(t, err)
+	assert.Empty(t, rows)
+	_, err = parse([]byte("a,b\n1\n"), "csv", ",")
+	assert.Error(t, err)
+
+	_, err = parse([]byte(""), "avro", "")
+	assert.Error(t, err)
+}
+
+func TestFileReader(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+	t0 := time.Date(2022, 6, 1, 0, 0, 0, 0, time.UTC)
+	writeFile(t, dir, "b.json", "{\"x\": 2}\n{\"x\": 3}\n", t0.Add(time.Minute))
+	writeFile(t, dir, "a.json", "{\"x\": 1}\n", t0)
+	writeFile(t, dir, "nested/c.json", "{\"x\": 4}\n", t0.Add(time.Minute))
+	writeFile(t, dir, "d.json", "{\"x\": 5}\n", t0.Add(time.Hour))
+
+	r := fileReader{store: localStore{dir: dir}, format: "json"}
+	xs := func(records []value.Value) []int {
+		var ret []int
+		for _, r := range records {
+			ret = append(ret, int(r.(value.Dict).GetUnsafe("x").(value.Int)))
+		}
+		return ret
+	}
+
+	records, cursor, err := r.Read(ctx, 0, 1)
+	assert.NoError(t, err)
+	assert.Equal(t, []int{1}, xs(records))
+	assert.Equal(t, uint64(t0.UnixNano()), cursor)
+	url := records[0].(value.Dict).GetUnsafe(FILE_URL_FIELD).(value.String)
+	assert.Equal(t, "file://"+filepath.Join(dir, "a.json"), string(url))
+	assert.Equal(t, value.String("2022-06-01T00:00:00Z"), records[0].(value.Dict).GetUnsafe(data_integration.S3_CURSOR_FIELD))
+
+	// files modified at the same time are read together even if that exceeds the batch size
+	records, cursor, err = r.Read(ctx, cursor, 1)
+	assert.NoError(t, err)
+	assert.Equal(t, []int{2, 3, 4}, xs(records))
+	assert.Equal(t, uint64(t0.Add(time.Minute).UnixNano()), cursor)
+
+	records, cursor, err = r.Read(ctx, cursor, 10)
+	assert.NoError(t, err)
+	assert.Equal(t, []int{5}, xs(records))
+
+	// nothing new to read
+	records, cursor2, err := r.Read(ctx, cursor, 10)
+	assert.NoError(t, err)
+	assert.Empty(t, records)
+	assert.Equal(t, cursor, cursor2)
+
+	// files added later are picked up
+	writeFile(t, dir, "e.json", "{\"x\": 6}\n", t0.Add(2*time.Hour))
+	records, _, err = r.Read(ctx, cursor, 10)
+	assert.NoError(t, err)
+	assert.Equal(t, []int{6}, xs(records))
+
+	// all files are read in order from the start
+	records, _, err = r.Read(ctx, 0, 100)
+	assert.NoError(t, err)
+	assert.Equal(t, []int{1, 2, 3, 4, 5, 6}, xs(records))
+}
+
+func TestValidateNative(t *testing.T) {
+	tr := tier.Tier{}
+	// local sources need a local source dir and can only read under it
+	local := data_integration.Local{Name: "local", Dir: "data", Format: "json"}
+	assert.Error(t, validateNativeSource(tr, local))
+	tr.Args.LocalSourceDir = "/srv/sources"
+	assert.NoError(t, validateNativeSource(tr, local))
+	local.Dir = "/srv/sources/data"
+	assert.NoError(t, validateNativeSource(tr, local))
+	for _, dir := range []string{"/var/run/secrets", "../secrets", "/srv/sources/../secrets", "/srv/sources-other"} {
+		local.Dir = dir
+		assert.Error(t, validateNativeSource(tr, local), dir)
+	}
+	_, err := newReader(context.Background(), tr, local, data_integration.Connector{})
+	assert.Error(t, err)
+
+	assert.NoError(t, validateNativeSource(tr, data_integration.S3{Name: "s3", Format: "csv"}))
+	assert.Error(t, validateNativeSource(tr, data_integration.S3{Name: "s3", Format: "parquet"}))
+	assert.Error(t, validateNativeSource(tr, data_integration.BigQuery{Name: "bq"}))
+	pg := data_integration.Postgres{SQLSource: data_integration.SQLSource{Name: "pg", Host: "localhost", Dbname: "db"}}
+	assert.Error(t, validateNativeSource(tr, pg))
+	pg.Username = "user"
+	assert.NoError(t, validateNativeSource(tr, pg))
+
+	assert.Error(t, validateNativeConnector(pg, data_integration.Connector{StreamName: "users"}))
+	assert.Error(t, validateNativeConnector(pg, data_integration.Connector{CursorField: "id"}))
+	assert.NoError(t, validateNativeConnector(pg, data_integration.Connector{StreamName: "users", CursorField: "id"}))
+	assert.NoError(t, validateNativeConnector(data_integration.Local{}, data_integration.Connector{}))
+}
+
+func TestCredentials(t *testing.T) {
+	ctx := context.Background()
+	tr := tier.Tier{ID: 1, SecretsClient: secrets.NewMemClient()}
+	pg := data_integration.Postgres{SQLSource: data_integration.SQLSource{Name: "pg", Username: "user", Password: "pass"}}
+	src, err := storeCredentials(ctx, tr, pg)
+	require.NoError(t, err)
+	// only the name of the secret is left in the source that is stored in the db
+	stored := src.(data_integration.Postgres)
+	assert.Equal(t, "", stored.Username)
+	assert.Equal(t, "", stored.Password)
+	assert.Equal(t, "t_1/postgres_source/pg", stored.CredentialsSecret)
+	c, err := loadCredentials(ctx, tr, stored)
+	require.NoError(t, err)
+	assert.Equal(t, credentials{Username: "user", Password: "pass"}, c)
+
+	require.NoError(t, deleteCredentials(ctx, tr, stored))
+	_, err = loadCredentials(ctx, tr, stored)
+	assert.ErrorIs(t, err, secrets.ErrNotFound)
+
+	// other sources have no credentials to store
+	local := data_integration.Local{Name: "local", Dir: "/tmp", Format: "json"}
+	src, err = storeCredentials(ctx, tr, local)
+	require.NoError(t, err)
+	assert.Equal(t, local, src)
+}
@@ -37,6 +37,10 @@ func UnmarshalSource(data []byte) (data_integration.Source, error) {
 		src := data_integration.Snowflake{}
 		err = json.Unmarshal(data, &src)
 		return src, err
+	case "Local":
+		src := data_integration.Local{}
+		err = json.Unmarshal(data, &src)
+		return src, err
 	default:
 		return nil, fmt.Errorf("unknown source type: %s", srcInfo["type"])
 	}
@@ -52,6 +56,21 @@ func StoreSource(ctx context.Context, tier tier.Tier, src data_integration.Sourc
 		if errors.Is(err, data_integration.ErrSrcNotFound) {
 			tier.Logger.Debug("Storing new src " + src.GetSourceName())
 
+			if readNatively(tier, src) {
+				if err = validateNativeSource(tier, src); err != nil {
+					return err
+				}
+				if src, err = storeCredentials(ctx, tier, src); err != nil {
+					return err
+				}
+				// native sources have no airbyte source id
+				if err = diModel.StoreSource(ctx, tier, src, ""); err != nil {
+					_ = deleteCredentials(ctx, tier, src)
+					return err
+				}
+				return nil
+			}
+
 			// Write the source to Airbyte
 			if tier.AirbyteClient.IsAbsent() {
 				return fmt.Errorf("error: Airbyte client is not initialized")
@@ -83,11 +102,16 @@ func DeleteSource(ctx context.Context, tier tier.Tier, name string) error {
 	if err != nil {
 		return fmt.Errorf("failed to retrieve source: %w", err)
 	}
-	if tier.AirbyteClient.IsAbsent() {
-		return fmt.Errorf("error: Airbyte client is not initialized")
+	if !isNative(src) {
+		if tier.AirbyteClient.IsAbsent() {
+			return fmt.Errorf("error: Airbyte client is not initialized")
+		}
+		if err = tier.AirbyteClient.MustGet().DeleteSource(src); err != nil {
+			return fmt.Errorf("error: failed to delete source: %w", err)
+		}
 	}
-	if err = tier.AirbyteClient.MustGet().DeleteSource(src); err != nil {
-		return fmt.Errorf("error: failed to delete source: %w", err)
+	if err = diModel.DeleteSource(ctx, tier, src); err != nil {
+		return err
 	}
-	return diModel.DeleteSource(ctx, tier, src)
+	return deleteCredentials(ctx, tier, src)
 }
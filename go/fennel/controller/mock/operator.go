@@ -0,0 +1,190 @@
+package mock
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"fennel/engine/operators"
+	"fennel/lib/value"
+)
+
+// Mockable are the operators that read data from outside the query, or write data outside of it,
+// and so can be mocked
+var Mockable = []string{"std.profile", "std.aggregate", "model.predict", "remote.http", "embedding.knn", "feature.log"}
+
+// OPERAND_KWARG is the kwarg a mocked response can match the operand of a row on, e.g. to mock
+// std.aggregate for a groupkey that is not set as a kwarg
+const OPERAND_KWARG = "__operand__"
+
+// Response is the mocked output of an operator for the rows whose kwargs match all of Kwargs
+type Response struct {
+	Kwargs value.Dict  `json:"kwargs"`
+	Output value.Value `json:"output"`
+}
+
+func (r *Response) UnmarshalJSON(data []byte) error {
+	var ser struct {
+		Kwargs json.RawMessage `json:"kwargs"`
+		Output json.RawMessage `json:"output"`
+	}
+	if err := json.Unmarshal(data, &ser); err != nil {
+		return err
+	}
+	r.Kwargs = value.NewDict(nil)
+	if len(ser.Kwargs) > 0 {
+		v, err := value.FromJSON(ser.Kwargs)
+		if err != nil {
+			return fmt.Errorf("invalid kwargs of mocked response: %w", err)
+		}
+		kwargs, ok := v.(value.Dict)
+		if !ok {
+			return fmt.Errorf("expected kwargs of mocked response to be a dict but found: '%s'", v)
+		}
+		r.Kwargs = kwargs
+	}
+	r.Output = value.Nil
+	if len(ser.Output) > 0 {
+		v, err := value.FromJSON(ser.Output)
+		if err != nil {
+			return fmt.Errorf("invalid output of mocked response: %w", err)
+		}
+		r.Output = v
+	}
+	return nil
+}
+
+// Operators are the mocked responses of operators keyed by the operator, e.g. "std.profile". The
+// first response that matches the kwargs of a row is used.
+type Operators map[string][]Response
+
+// Validate returns an error if any of the operators can not be mocked
+func (o Operators) Validate() error {
+	for name := range o {
+		if !isMockable(name) {
+			return fmt.Errorf("operator '%s' can not be mocked, only %s can be", name, strings.Join(Mockable, ", "))
+		}
+	}
+	return nil
+}
+
+// Merge returns the mocked responses of both, with the responses of o tried before those of other
+func (o Operators) Merge(other Operators) Operators {
+	ret := make(Operators, len(o)+len(other))
+	for name, responses := range o {
+		ret[name] = append(ret[name], responses...)
+	}
+	for name, responses := range other {
+		ret[name] = append(ret[name], responses...)
+	}
+	return ret
+}
+
+// Strict returns the mocked responses of o, with the mockable operators that o does not mock mocked
+// without responses so that running them fails instead of reading or writing the data of the tier
+func (o Operators) Strict() Operators {
+	ret := o.Merge(nil)
+	for _, name := range Mockable {
+		if _, ok := ret[name]; !ok {
+			ret[name] = nil
+		}
+	}
+	return ret
+}
+
+// Wrap returns the operator that serves the mocked responses in place of op, or op itself if it is
+// not mocked
+func (o Operators) Wrap(op operators.Operator) operators.Operator {
+	sig := op.Signature()
+	responses, ok := o[sig.Module+"."+sig.Name]
+	if !ok {
+		return op
+	}
+	return mockOp{op: op, responses: responses}
+}
+
+func isMockable(name string) bool {
+	for _, m := range Mockable {
+		if m == name {
+			return true
+		}
+	}
+	return false
+}
+
+// mockOp has the signature of the mocked operator, so kwargs are evaluated and type checked as they
+// are for the real operator, but its output comes from the mocked responses
+type mockOp struct {
+	op        operators.Operator
+	responses []Response
+}
+
+var _ operators.Operator = mockOp{}
+
+func (m mockOp) New(args value.Dict, bootargs map[string]interface{}) (operators.Operator, error) {
+	// the real operator is not created since it may need resources that are not there in tests
+	return m, nil
+}
+
+func (m mockOp) Apply(_ context.Context, staticKwargs operators.Kwargs, in operators.InputIter, outs *value.List) error {
+	sig := m.op.Signature()
+	if len(m.responses) == 0 {
+		return fmt.Errorf("operator '%s.%s' is not mocked, it reads or writes data outside the query and has to be mocked", sig.Module, sig.Name)
+	}
+	for in.HasMore() {
+		heads, contextKwargs, err := in.Next()
+		if err != nil {
+			return err
+		}
+		kwargs := value.NewDict(nil)
+		for _, p := range sig.StaticKwargs {
+			kwargs.Set(p.Name, staticKwargs.GetUnsafe(p.Name))
+		}
+		for _, p := range sig.ContextKwargs {
+			kwargs.Set(p.Name, contextKwargs.GetUnsafe(p.Name))
+		}
+		out, ok := m.match(heads[0], kwargs)
+		if !ok {
+			return fmt.Errorf("no mocked response of '%s.%s' matches operand: '%s' with kwargs: '%s'", sig.Module, sig.Name, heads[0], kwargs)
+		}
+		// as with the real operators, null outputs are replaced by the default, if there is one
+		if d, ok := kwargs.Get("default"); ok && out == value.Nil {
+			out = d
+		}
+		if field, ok := kwargs.GetUnsafe("field").(value.String); ok && len(field) > 0 {
+			row, ok := heads[0].(value.Dict)
+			if !ok {
+				return fmt.Errorf("operator '%s.%s' expects operands to be dicts when setting a field", sig.Module, sig.Name)
+			}
+			row.Set(string(field), out)
+			out = row
+		}
+		outs.Append(out)
+	}
+	return nil
+}
+
+func (m mockOp) match(operand value.Value, kwargs value.Dict) (value.Value, bool) {
+	for _, r := range m.responses {
+		matches := true
+		for k, v := range r.Kwargs.Iter() {
+			found, ok := kwargs.Get(k)
+			if k == OPERAND_KWARG {
+				found, ok = operand, true
+			}
+			if !ok || !v.Equal(found) {
+				matches = false
+				break
+			}
+		}
+		if matches {
+			return r.Output, true
+		}
+	}
+	return nil, false
+}
+
+func (m mockOp) Signature() *operators.Signature {
+	return m.op.Signature()
+}
@@ -0,0 +1,174 @@
+package profile
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"time"
+
+	"fennel/kafka"
+	profilelib "fennel/lib/profile"
+	"fennel/resource"
+	"fennel/tier"
+
+	confluent "github.com/confluentinc/confluent-kafka-go/kafka"
+	"go.uber.org/zap"
+)
+
+// maxChangesLimit is the maximum number of changes returned by a single read
+const maxChangesLimit = 10000
+
+var subscriberRegex = regexp.MustCompile(`^[a-zA-Z0-9_\-]{1,64}$`)
+
+// publishChanges publishes the changes to the change data capture topic, partitioned by profile
+func publishChanges(ctx context.Context, tr tier.Tier, changes []profilelib.Change) error {
+	if len(changes) == 0 {
+		return nil
+	}
+	producer := tr.Producers[profilelib.PROFILE_CDC_KAFKA_TOPIC]
+	for _, c := range changes {
+		ser, err := json.Marshal(c)
+		if err != nil {
+			return err
+		}
+		if err = producer.Log(ctx, ser, []byte(fmt.Sprintf("%s/%s", c.OType, c.Oid))); err != nil {
+			return fmt.Errorf("failed to publish profile change: %w", err)
+		}
+	}
+	return producer.Flush(10 * time.Second)
+}
+
+// ChangeRequest is a read of the profile changes of a subscriber. Each subscriber reads the
+// changes from where its previous read stopped, starting at the earliest or latest change (From)
+// on its first read. Setting Offsets moves the subscriber to those offsets before reading.
+type ChangeRequest struct {
+	Subscriber string
+	From       string
+	Offsets    profilelib.Offsets
+	Filter     profilelib.ChangeFilter
+	Limit      int
+	Timeout    time.Duration
+}
+
+func (r ChangeRequest) Validate() error {
+	if !subscriberRegex.MatchString(r.Subscriber) {
+		return fmt.Errorf("invalid subscriber: '%s', expected upto 64 letters, digits, '_' or '-'", r.Subscriber)
+	}
+	if r.From != kafka.EarliestOffsetPolicy && r.From != kafka.LatestOffsetPolicy {
+		return fmt.Errorf("invalid from: '%s', expected '%s' or '%s'", r.From, kafka.EarliestOffsetPolicy, kafka.LatestOffsetPolicy)
+	}
+	if r.Limit <= 0 || r.Limit > maxChangesLimit {
+		return fmt.Errorf("limit should be between 1 and %d", maxChangesLimit)
+	}
+	return nil
+}
+
+// ChangeBatch are the changes returned by a read along with the offsets of the subscriber after
+// the read, which can be used to read from the same position again
+type ChangeBatch struct {
+	Changes []profilelib.Change `json:"changes"`
+	Offsets string              `json:"offsets"`
+}
+
+// ChangeReader reads the profile changes of a subscriber for the duration of a request. The reader
+// is assigned every partition of the change topic and starts where the previous read of the
+// subscriber stopped, as committed by the subscriber's consumer group, so readers of a subscriber
+// on different replicas never split the partitions, and so the changes, between them.
+type ChangeReader struct {
+	tier     tier.Tier
+	req      ChangeRequest
+	consumer kafka.FConsumer
+}
+
+// NewChangeReader creates the reader of the changes of the subscriber of the request, moving the
+// subscriber to the offsets of the request, if any
+func NewChangeReader(tr tier.Tier, req ChangeRequest) (*ChangeReader, error) {
+	if err := req.Validate(); err != nil {
+		return nil, err
+	}
+	config := kafka.ConsumerConfig{
+		Scope:            resource.NewTierScope(tr.ID),
+		Topic:            profilelib.PROFILE_CDC_KAFKA_TOPIC,
+		GroupID:          "profile_cdc_" + req.Subscriber,
+		OffsetPolicy:     req.From,
+		AssignPartitions: true,
+	}
+	if len(req.Offsets) > 0 {
+		// the offsets are committed by a separate consumer so that the reader starts at them
+		consumer, err := tr.NewKafkaConsumer(config)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create consumer: %w", err)
+		}
+		err = commitOffsets(consumer, req.Offsets)
+		_ = consumer.Close()
+		if err != nil {
+			return nil, err
+		}
+	}
+	consumer, err := tr.NewKafkaConsumer(config)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create consumer: %w", err)
+	}
+	return &ChangeReader{tier: tr, req: req, consumer: consumer}, nil
+}
+
+// Read returns the next changes of the subscriber that match the filter, waiting up to the timeout
+// for changes to be published
+func (r *ChangeReader) Read(ctx context.Context) (ChangeBatch, error) {
+	msgs, err := r.consumer.ReadBatch(ctx, r.req.Limit, r.req.Timeout)
+	if err != nil {
+		return ChangeBatch{}, err
+	}
+	batch := ChangeBatch{Changes: make([]profilelib.Change, 0, len(msgs))}
+	for _, msg := range msgs {
+		var c profilelib.Change
+		if err = json.Unmarshal(msg, &c); err != nil {
+			r.tier.Logger.Error("failed to parse profile change", zap.Error(err))
+			continue
+		}
+		if r.req.Filter.Matches(c) {
+			batch.Changes = append(batch.Changes, c)
+		}
+	}
+	if len(msgs) > 0 {
+		if _, err = r.consumer.Commit(); err != nil {
+			return ChangeBatch{}, fmt.Errorf("failed to commit offsets: %w", err)
+		}
+	}
+	toppars, err := r.consumer.Offsets()
+	if err != nil {
+		return ChangeBatch{}, err
+	}
+	offsets := make(profilelib.Offsets, len(toppars))
+	for _, tp := range toppars {
+		if tp.Offset >= 0 {
+			offsets[tp.Partition] = int64(tp.Offset)
+		}
+	}
+	batch.Offsets = offsets.String()
+	return batch, nil
+}
+
+// Close closes the consumer of the reader
+func (r *ChangeReader) Close() error {
+	return r.consumer.Close()
+}
+
+func commitOffsets(consumer kafka.FConsumer, offsets profilelib.Offsets) error {
+	partitions, err := consumer.GetPartitions()
+	if err != nil || len(partitions) == 0 {
+		return fmt.Errorf("failed to get partitions: %v", err)
+	}
+	toppars := make(confluent.TopicPartitions, 0, len(offsets))
+	for p, o := range offsets {
+		if int(p) >= len(partitions) {
+			return fmt.Errorf("invalid partition: %d, topic has %d partitions", p, len(partitions))
+		}
+		toppars = append(toppars, confluent.TopicPartition{Topic: partitions[0].Topic, Partition: p, Offset: confluent.Offset(o)})
+	}
+	if _, err = consumer.CommitOffsets(toppars); err != nil {
+		return fmt.Errorf("failed to move to offsets: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,79 @@
+package profile
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"fennel/kafka"
+	"fennel/lib/ftypes"
+	profilelib "fennel/lib/profile"
+	"fennel/lib/utils"
+	"fennel/lib/value"
+	"fennel/resource"
+	"fennel/test"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTransferToDBPublishesChanges(t *testing.T) {
+	tier := test.Tier(t)
+	defer test.Teardown(tier)
+	ctx := context.Background()
+
+	// every read has its own reader, which starts where the previous one stopped
+	read := func(filter profilelib.ChangeFilter) []profilelib.Change {
+		reader, err := NewChangeReader(tier, ChangeRequest{
+			Subscriber: "test", From: kafka.EarliestOffsetPolicy, Filter: filter, Limit: 10, Timeout: time.Second,
+		})
+		assert.NoError(t, err)
+		defer reader.Close()
+		batch, err := reader.Read(ctx)
+		assert.NoError(t, err)
+		return batch.Changes
+	}
+
+	consumer, err := tier.NewKafkaConsumer(kafka.ConsumerConfig{
+		Scope:        resource.NewTierScope(tier.ID),
+		Topic:        profilelib.PROFILELOG_KAFKA_TOPIC,
+		GroupID:      utils.RandString(6),
+		OffsetPolicy: kafka.DefaultOffsetPolicy,
+	})
+	assert.NoError(t, err)
+	defer consumer.Close()
+
+	assert.NoError(t, SetMulti(ctx, tier, []profilelib.ProfileItem{
+		profilelib.NewProfileItem("user", "1", "age", value.Int(1), 10),
+		profilelib.NewProfileItem("user", "1", "age", value.Int(2), 20),
+		profilelib.NewProfileItem("video", "1", "age", value.Int(3), 10),
+	}))
+	assert.NoError(t, TransferToDB(ctx, tier, consumer))
+	// only the latest value of a profile in a batch is a change
+	assert.ElementsMatch(t, []profilelib.Change{
+		{OType: "user", Oid: "1", Key: "age", OldValue: value.Nil, NewValue: value.Int(2), UpdateTime: 20},
+		{OType: "video", Oid: "1", Key: "age", OldValue: value.Nil, NewValue: value.Int(3), UpdateTime: 10},
+	}, read(profilelib.ChangeFilter{}))
+
+	// older values are not applied and so are not published
+	assert.NoError(t, SetMulti(ctx, tier, []profilelib.ProfileItem{
+		profilelib.NewProfileItem("user", "1", "age", value.Int(0), 5),
+		profilelib.NewProfileItem("user", "1", "name", value.String("a"), 30),
+		profilelib.NewProfileItem("video", "1", "age", value.Int(4), 30),
+	}))
+	assert.NoError(t, TransferToDB(ctx, tier, consumer))
+	assert.Equal(t, []profilelib.Change{
+		{OType: "video", Oid: "1", Key: "age", OldValue: value.Int(3), NewValue: value.Int(4), OldUpdateTime: 10, UpdateTime: 30},
+	}, read(profilelib.ChangeFilter{OTypes: []ftypes.OType{"video"}}))
+	assert.Empty(t, read(profilelib.ChangeFilter{}))
+
+	// so is the deletion of expired profiles
+	n, err := DeleteExpired(ctx, tier, "video", "age", nil, time.UnixMicro(31), 10)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, n)
+	assert.Equal(t, []profilelib.Change{
+		{OType: "video", Oid: "1", Key: "age", OldValue: value.Int(4), NewValue: value.Nil, OldUpdateTime: 30, UpdateTime: uint64(tier.Clock.Now().UnixMicro())},
+	}, read(profilelib.ChangeFilter{}))
+
+	_, err = NewChangeReader(tier, ChangeRequest{Subscriber: "has space", From: kafka.EarliestOffsetPolicy, Limit: 10})
+	assert.Error(t, err)
+}
@@ -0,0 +1,400 @@
+package profile
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"fennel/controller/schema"
+	profilelib "fennel/lib/profile"
+	"fennel/lib/utils"
+	"fennel/model/profile"
+	"fennel/tier"
+
+	"go.uber.org/zap"
+)
+
+const (
+	// jobBatchSize is the number of profiles written to (or read from) the database at a time
+	jobBatchSize = 1000
+	// exportRowsPerFile is the maximum number of profiles in each exported file
+	exportRowsPerFile = 1_000_000
+	// jobsLimit is the number of jobs returned by Jobs
+	jobsLimit = 100
+	// jobLease is how long a job is reserved for the process that claimed it. The lease is renewed
+	// while the job runs so that only jobs of processes that stopped are claimed again.
+	jobLease = 5 * time.Minute
+)
+
+// InvalidJobError is returned when a job is requested with an invalid type, format or location
+type InvalidJobError struct {
+	Err error
+}
+
+func (e InvalidJobError) Error() string {
+	return fmt.Sprintf("invalid profile job: %v", e.Err)
+}
+
+func (e InvalidJobError) Unwrap() error {
+	return e.Err
+}
+
+// StartJob queues a bulk import or export of profiles and returns the pending job, jobs are run in
+// the background by RunNextJob. Imports set the profiles directly in the database in large batches
+// instead of going through kafka. The progress of the job can be followed with GetJob.
+func StartJob(ctx context.Context, tier tier.Tier, request profilelib.JobRequest) (profilelib.Job, error) {
+	if err := request.Validate(); err != nil {
+		return profilelib.Job{}, InvalidJobError{Err: err}
+	}
+	location, err := jobLocation(tier, request.Location)
+	if err != nil {
+		return profilelib.Job{}, InvalidJobError{Err: err}
+	}
+	request.Location = location
+	id, err := profile.InsertJob(ctx, tier, request)
+	if err != nil {
+		return profilelib.Job{}, err
+	}
+	return profilelib.Job{ID: id, JobRequest: request, Status: profilelib.JobPending, ErrorSamples: []string{}}, nil
+}
+
+// RunNextJob claims the oldest pending job, or a job whose lease expired, and runs it. It returns
+// false if there was no job to run.
+func RunNextJob(ctx context.Context, tier tier.Tier) (bool, error) {
+	now := tier.Clock.Now()
+	job, ok, err := profile.ClaimJob(ctx, tier, now.Unix(), now.Add(jobLease).Unix())
+	if err != nil || !ok {
+		return false, err
+	}
+	runJob(ctx, tier, job)
+	return true, nil
+}
+
+func GetJob(ctx context.Context, tier tier.Tier, id uint64) (profilelib.Job, error) {
+	return profile.RetrieveJob(ctx, tier, id)
+}
+
+// Jobs returns the latest jobs, newest first
+func Jobs(ctx context.Context, tier tier.Tier) ([]profilelib.Job, error) {
+	return profile.RetrieveJobs(ctx, tier, jobsLimit)
+}
+
+// jobLocation returns the location of a job. Local paths are relative to the profile job dir of the
+// tier and can't be outside of it, S3 locations are returned as is.
+func jobLocation(tier tier.Tier, location string) (string, error) {
+	if strings.HasPrefix(location, profilelib.S3Scheme) {
+		return location, nil
+	}
+	if len(tier.Args.ProfileJobDir) == 0 {
+		return "", fmt.Errorf("location '%s' is not an s3 location of the form %s<bucket>/<path>", location, profilelib.S3Scheme)
+	}
+	local, err := utils.WithinDir(tier.Args.ProfileJobDir, location)
+	if err != nil {
+		return "", fmt.Errorf("location '%s' is outside of the profile job dir", location)
+	}
+	return local, nil
+}
+
+func runJob(ctx context.Context, tier tier.Tier, job profilelib.Job) {
+	logger := tier.Logger.With(zap.Uint64("job", job.ID), zap.String("type", string(job.Type)))
+	stop := make(chan struct{})
+	defer close(stop)
+	go renewLease(tier, job.ID, stop, logger)
+
+	var err error
+	// the profile job dir may have changed since the job was requested
+	if job.Location, err = jobLocation(tier, job.Location); err == nil {
+		switch job.Type {
+		case profilelib.ImportJob:
+			err = runImport(ctx, tier, &job)
+		case profilelib.ExportJob:
+			err = runExport(ctx, tier, &job)
+		default:
+			err = fmt.Errorf("invalid job type: '%s'", job.Type)
+		}
+	}
+	if err != nil {
+		logger.Error("profile job failed", zap.Error(err))
+		job.Status, job.Error = profilelib.JobFailed, err.Error()
+	} else {
+		logger.Info("profile job succeeded", zap.Uint64("rows_written", job.RowsWritten), zap.Uint64("rows_failed", job.RowsFailed))
+		job.Status = profilelib.JobSucceeded
+	}
+	if err = profile.UpdateJob(ctx, tier, job); err != nil {
+		logger.Error("failed to update profile job", zap.Error(err))
+	}
+}
+
+// renewLease renews the lease of the job until stop is closed
+func renewLease(tier tier.Tier, id uint64, stop <-chan struct{}, logger *zap.Logger) {
+	ticker := time.NewTicker(jobLease / 3)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+			leaseUntil := tier.Clock.Now().Add(jobLease).Unix()
+			if err := profile.RenewJobLease(context.Background(), tier, id, leaseUntil); err != nil {
+				logger.Error("failed to renew the lease of profile job", zap.Error(err))
+			}
+		}
+	}
+}
+
+func runImport(ctx context.Context, tier tier.Tier, job *profilelib.Job) error {
+	batch := make([]profilelib.ProfileItem, 0, jobBatchSize)
+	// imported profiles are checked against their schemas and written like profiles that are logged
+	flush := func() error {
+		if len(batch) == 0 {
+			return nil
+		}
+		errs, err := schema.CheckProfilesEach(ctx, tier, batch, false)
+		if err != nil {
+			return err
+		}
+		valid := batch[:0]
+		for i := range batch {
+			if errs[i] != nil {
+				job.AddError(errs[i])
+			} else {
+				valid = append(valid, batch[i])
+			}
+		}
+		if len(valid) > 0 {
+			if err = writeBatch(ctx, tier, valid); err != nil {
+				return err
+			}
+		}
+		job.RowsWritten += uint64(len(valid))
+		batch = batch[:0]
+		return profile.UpdateJob(ctx, tier, *job)
+	}
+	importFile := func(name string) error {
+		r, err := profilelib.NewItemReader(job.Format, name)
+		if err != nil {
+			return fmt.Errorf("failed to open '%s': %w", name, err)
+		}
+		defer r.Close()
+		for {
+			item, err := r.Read()
+			if err == io.EOF {
+				return nil
+			}
+			job.RowsRead++
+			var rowErr *profilelib.RowError
+			if errors.As(err, &rowErr) {
+				job.AddError(fmt.Errorf("%s: %w", path.Base(name), rowErr))
+				continue
+			} else if err != nil {
+				return fmt.Errorf("failed to read '%s': %w", name, err)
+			}
+			if batch = append(batch, item); len(batch) == jobBatchSize {
+				if err = flush(); err != nil {
+					return err
+				}
+			}
+		}
+	}
+	if strings.HasPrefix(job.Location, profilelib.S3Scheme) {
+		err := importS3(tier, job, importFile)
+		if err != nil {
+			return err
+		}
+	} else {
+		files, err := localFiles(job.Location, job.Format)
+		if err != nil {
+			return err
+		}
+		for _, f := range files {
+			if err = importFile(f); err != nil {
+				return err
+			}
+		}
+	}
+	return flush()
+}
+
+// importS3 downloads the files under the S3 location one at a time and imports them
+func importS3(tier tier.Tier, job *profilelib.Job, importFile func(string) error) error {
+	bucket, prefix, err := profilelib.ParseS3Location(job.Location)
+	if err != nil {
+		return err
+	}
+	files, err := tier.S3Client.ListFiles(bucket, prefix, "")
+	if err != nil {
+		return fmt.Errorf("failed to list files in '%s': %w", job.Location, err)
+	}
+	sort.Strings(files)
+	dir, err := os.MkdirTemp("", "profile-import")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(dir)
+	for _, f := range files {
+		if !strings.HasSuffix(f, job.Format.Ext()) {
+			continue
+		}
+		if err = tier.S3Client.BatchDiskDownload([]string{f}, bucket, dir); err != nil {
+			return fmt.Errorf("failed to download '%s': %w", f, err)
+		}
+		local := filepath.Join(dir, path.Base(f))
+		err = importFile(local)
+		_ = os.Remove(local)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// localFiles returns the file at location, or the files with the extension of the format in it
+// if it is a directory
+func localFiles(location string, format profilelib.Format) ([]string, error) {
+	info, err := os.Stat(location)
+	if err != nil {
+		return nil, err
+	}
+	if !info.IsDir() {
+		return []string{location}, nil
+	}
+	files, err := filepath.Glob(filepath.Join(location, "*"+format.Ext()))
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(files)
+	return files, nil
+}
+
+func runExport(ctx context.Context, tier tier.Tier, job *profilelib.Job) error {
+	out, err := newExportOutput(tier, job.Location, job.Format)
+	if err != nil {
+		return err
+	}
+	defer out.abort()
+	var last profilelib.ProfileItem
+	for {
+		items, err := profile.Scan(ctx, tier, job.OType, last.Oid, last.Key, jobBatchSize)
+		if err != nil {
+			return err
+		}
+		for _, item := range items {
+			if err = out.write(item); err != nil {
+				return err
+			}
+		}
+		job.RowsRead += uint64(len(items))
+		job.RowsWritten += uint64(len(items))
+		if len(items) < jobBatchSize {
+			return out.close()
+		}
+		last = items[len(items)-1]
+		if err = profile.UpdateJob(ctx, tier, *job); err != nil {
+			return err
+		}
+	}
+}
+
+// exportOutput writes the exported profiles to files named part-<n>.<format> under a local
+// directory or an S3 prefix, starting a new file every exportRowsPerFile profiles. Files are first
+// written locally and uploaded to S3 once complete.
+type exportOutput struct {
+	tier   tier.Tier
+	format profilelib.Format
+	dir    string
+	bucket string
+	prefix string
+
+	file   *os.File
+	writer profilelib.ItemWriter
+	rows   int
+	part   int
+}
+
+func newExportOutput(tier tier.Tier, location string, format profilelib.Format) (*exportOutput, error) {
+	out := &exportOutput{tier: tier, format: format}
+	if strings.HasPrefix(location, profilelib.S3Scheme) {
+		bucket, prefix, err := profilelib.ParseS3Location(location)
+		if err != nil {
+			return nil, err
+		}
+		if out.dir, err = os.MkdirTemp("", "profile-export"); err != nil {
+			return nil, err
+		}
+		out.bucket, out.prefix = bucket, prefix
+	} else {
+		if err := os.MkdirAll(location, 0755); err != nil {
+			return nil, err
+		}
+		out.dir = location
+	}
+	return out, nil
+}
+
+func (o *exportOutput) write(item profilelib.ProfileItem) error {
+	if o.writer != nil && o.rows == exportRowsPerFile {
+		if err := o.closeFile(); err != nil {
+			return err
+		}
+	}
+	if o.writer == nil {
+		var err error
+		o.file, err = os.Create(filepath.Join(o.dir, fmt.Sprintf("part-%05d%s", o.part, o.format.Ext())))
+		if err != nil {
+			return err
+		}
+		if o.writer, err = profilelib.NewItemWriter(o.format, o.file); err != nil {
+			return err
+		}
+		o.part++
+		o.rows = 0
+	}
+	o.rows++
+	return o.writer.Write(item)
+}
+
+func (o *exportOutput) closeFile() error {
+	if err := o.writer.Close(); err != nil {
+		return err
+	}
+	o.writer = nil
+	if len(o.bucket) == 0 {
+		return o.file.Close()
+	}
+	// upload the complete file to s3
+	if _, err := o.file.Seek(0, io.SeekStart); err != nil {
+		return err
+	}
+	name := filepath.Base(o.file.Name())
+	err := o.tier.S3Client.Upload(o.file, path.Join(o.prefix, name), o.bucket)
+	_ = o.file.Close()
+	_ = os.Remove(o.file.Name())
+	if err != nil {
+		return fmt.Errorf("failed to upload '%s': %w", name, err)
+	}
+	return nil
+}
+
+func (o *exportOutput) close() error {
+	if o.writer == nil {
+		return nil
+	}
+	return o.closeFile()
+}
+
+// abort cleans up after a failed export, it is a no-op after close
+func (o *exportOutput) abort() {
+	if o.file != nil {
+		_ = o.file.Close()
+	}
+	if len(o.bucket) > 0 {
+		_ = os.RemoveAll(o.dir)
+	}
+}
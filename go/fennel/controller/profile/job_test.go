@@ -0,0 +1,125 @@
+package profile
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+
+	"fennel/lib/ftypes"
+	profilelib "fennel/lib/profile"
+	"fennel/lib/value"
+	"fennel/model/profile"
+	"fennel/test"
+	"fennel/tier"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// runNextJob runs the next job and returns the job with the given id once it is done
+func runNextJob(t *testing.T, ctx context.Context, tier tier.Tier, id uint64) profilelib.Job {
+	ran, err := RunNextJob(ctx, tier)
+	assert.NoError(t, err)
+	assert.True(t, ran)
+	job, err := GetJob(ctx, tier, id)
+	assert.NoError(t, err)
+	assert.True(t, job.Done())
+	return job
+}
+
+func TestImportExport(t *testing.T) {
+	tier := test.Tier(t)
+	defer test.Teardown(tier)
+	ctx := context.Background()
+
+	dir := t.TempDir()
+	tier.Args.ProfileJobDir = dir
+	f, err := os.Create(filepath.Join(dir, "profiles.jsonl"))
+	assert.NoError(t, err)
+	w, err := profilelib.NewItemWriter(profilelib.JSONL, f)
+	assert.NoError(t, err)
+	n := jobBatchSize + 10
+	for i := 0; i < n; i++ {
+		assert.NoError(t, w.Write(profilelib.NewProfileItem("user", ftypes.OidType(strconv.Itoa(i)), "age", value.Int(i), 1)))
+	}
+	assert.NoError(t, w.Close())
+	// an invalid row is skipped and reported
+	_, err = f.WriteString(`{"OType":"user","Oid":"","Key":"age","Value":1,"UpdateTime":1}` + "\n")
+	assert.NoError(t, err)
+	assert.NoError(t, f.Close())
+
+	job, err := StartJob(ctx, tier, profilelib.JobRequest{Type: profilelib.ImportJob, Format: profilelib.JSONL, Location: dir})
+	assert.NoError(t, err)
+	job = runNextJob(t, ctx, tier, job.ID)
+	assert.Equal(t, profilelib.JobSucceeded, job.Status)
+	assert.Equal(t, uint64(n+1), job.RowsRead)
+	assert.Equal(t, uint64(n), job.RowsWritten)
+	assert.Equal(t, uint64(1), job.RowsFailed)
+	assert.Len(t, job.ErrorSamples, 1)
+	checkGet(t, ctx, tier, profilelib.NewProfileItemKey("user", "7", "age"), value.Int(7))
+
+	out := filepath.Join(dir, "export")
+	job, err = StartJob(ctx, tier, profilelib.JobRequest{Type: profilelib.ExportJob, Format: profilelib.CSV, Location: out, OType: "user"})
+	assert.NoError(t, err)
+	job = runNextJob(t, ctx, tier, job.ID)
+	assert.Equal(t, profilelib.JobSucceeded, job.Status)
+	assert.Equal(t, uint64(n), job.RowsWritten)
+	r, err := profilelib.NewItemReader(profilelib.CSV, filepath.Join(out, "part-00000.csv"))
+	assert.NoError(t, err)
+	defer r.Close()
+	item, err := r.Read()
+	assert.NoError(t, err)
+	assert.Equal(t, profilelib.NewProfileItemKey("user", "0", "age"), item.GetProfileKey())
+
+	// jobs on missing files fail
+	job, err = StartJob(ctx, tier, profilelib.JobRequest{Type: profilelib.ImportJob, Format: profilelib.CSV, Location: "missing.csv"})
+	assert.NoError(t, err)
+	job = runNextJob(t, ctx, tier, job.ID)
+	assert.Equal(t, profilelib.JobFailed, job.Status)
+	assert.NotEmpty(t, job.Error)
+
+	jobs, err := Jobs(ctx, tier)
+	assert.NoError(t, err)
+	assert.Len(t, jobs, 3)
+
+	// there are no more jobs to run
+	ran, err := RunNextJob(ctx, tier)
+	assert.NoError(t, err)
+	assert.False(t, ran)
+
+	// local locations can't be outside of the profile job dir
+	for _, location := range []string{"../profiles.jsonl", "/etc/passwd", filepath.Join(dir, "..")} {
+		_, err = StartJob(ctx, tier, profilelib.JobRequest{Type: profilelib.ImportJob, Format: profilelib.JSONL, Location: location})
+		assert.ErrorAs(t, err, &InvalidJobError{}, location)
+	}
+	tier.Args.ProfileJobDir = ""
+	_, err = StartJob(ctx, tier, profilelib.JobRequest{Type: profilelib.ImportJob, Format: profilelib.JSONL, Location: dir})
+	assert.ErrorAs(t, err, &InvalidJobError{})
+}
+
+func TestClaimJob(t *testing.T) {
+	tier := test.Tier(t)
+	defer test.Teardown(tier)
+	ctx := context.Background()
+	tier.Args.ProfileJobDir = t.TempDir()
+
+	job, err := StartJob(ctx, tier, profilelib.JobRequest{Type: profilelib.ImportJob, Format: profilelib.CSV, Location: "missing.csv"})
+	assert.NoError(t, err)
+	now := tier.Clock.Now()
+	claimed, ok, err := profile.ClaimJob(ctx, tier, now.Unix(), now.Add(jobLease).Unix())
+	assert.NoError(t, err)
+	assert.True(t, ok)
+	assert.Equal(t, job.ID, claimed.ID)
+	// the job is not claimed again while its lease holds
+	_, ok, err = profile.ClaimJob(ctx, tier, now.Unix(), now.Add(jobLease).Unix())
+	assert.NoError(t, err)
+	assert.False(t, ok)
+	// but is once the process that claimed it stops renewing the lease
+	later := now.Add(2 * jobLease)
+	claimed, ok, err = profile.ClaimJob(ctx, tier, later.Unix(), later.Add(jobLease).Unix())
+	assert.NoError(t, err)
+	assert.True(t, ok)
+	assert.Equal(t, job.ID, claimed.ID)
+	assert.Equal(t, profilelib.JobRunning, claimed.Status)
+}
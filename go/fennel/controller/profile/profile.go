@@ -16,6 +16,14 @@ import (
 	"google.golang.org/protobuf/proto"
 )
 
+// CACHE_NAMESPACE is the namespace of profiles in the process-level cache
+const CACHE_NAMESPACE = "Profile"
+
+// CacheKey returns the key of the profile in the process-level cache
+func CacheKey(pk profilelib.ProfileItemKey) string {
+	return fmt.Sprintf("profile:%s:%s:%s", pk.OType, pk.Oid, pk.Key)
+}
+
 func Get(ctx context.Context, tier tier.Tier, pk profilelib.ProfileItemKey) (profilelib.ProfileItem, error) {
 	ctx, t := timer.Start(ctx, tier.ID, "controller.profile.get")
 	defer t.Stop()
@@ -108,18 +116,84 @@ func TransferToDB(ctx context.Context, tr tier.Tier, consumer kafka.FConsumer) e
 
 	tr.Logger.Info(fmt.Sprintf("writing %d profiles to DB", len(profiles)))
 
-	if err = profile.SetBatch(ctx, tr, profiles); err != nil {
+	// changes are published before the batch is committed so that none are lost, but they may be
+	// published more than once if the batch is retried
+	if err = writeBatch(ctx, tr, profiles); err != nil {
 		return err
 	}
 	_, err = consumer.Commit()
 	return err
 }
 
+// writeBatch sets the profiles in the db, invalidates them in the process-level cache of every
+// process and publishes their changes
+func writeBatch(ctx context.Context, tr tier.Tier, profiles []profilelib.ProfileItem) error {
+	changes, err := profile.SetBatchWithChanges(ctx, tr, profiles)
+	if err != nil {
+		return err
+	}
+	keys := make([]string, len(profiles))
+	for i := range profiles {
+		keys[i] = CacheKey(profiles[i].GetProfileKey())
+	}
+	tr.Invalidations.Invalidate(ctx, CACHE_NAMESPACE, keys...)
+	return publishChanges(ctx, tr, changes)
+}
+
+// DeleteExpired deletes up to limit profiles of the otype and key (see profile.DeleteExpired) that
+// were last updated before cutoff and publishes their deletion. It returns the number of profiles
+// that were deleted.
+func DeleteExpired(ctx context.Context, tr tier.Tier, otype ftypes.OType, key string, exclude []string, cutoff time.Time, limit int) (int, error) {
+	changes, err := profile.DeleteExpired(ctx, tr, otype, key, exclude, uint64(cutoff.UnixMicro()), limit)
+	if err != nil {
+		return 0, err
+	}
+	keys := make([]string, len(changes))
+	for i, c := range changes {
+		keys[i] = CacheKey(profilelib.NewProfileItemKey(c.OType, c.Oid, c.Key))
+	}
+	tr.Invalidations.Invalidate(ctx, CACHE_NAMESPACE, keys...)
+	return len(changes), publishChanges(ctx, tr, changes)
+}
+
 // If profile item doesn't exist and hence the value, is not found, profileItem with value nil is returned.
 func GetBatch(ctx context.Context, tier tier.Tier, requests []profilelib.ProfileItemKey) ([]profilelib.ProfileItem, error) {
 	return profile.GetBatch(ctx, tier, requests)
 }
 
+// GetBatchAsOf returns the values the profiles had at asOf, the value of profiles that were not
+// set at that time is value.Nil
+func GetBatchAsOf(ctx context.Context, tier tier.Tier, requests []profilelib.ProfileItemKey, asOf time.Time) ([]profilelib.ProfileItem, error) {
+	ctx, t := timer.Start(ctx, tier.ID, "controller.profile.get_batch_as_of")
+	defer t.Stop()
+	return profile.GetBatchAsOf(ctx, tier, requests, uint64(asOf.UnixMicro()))
+}
+
+// GetHistory returns the values of the profile that were set between start and end, oldest first
+func GetHistory(ctx context.Context, tier tier.Tier, pk profilelib.ProfileItemKey, start, end time.Time) ([]profilelib.ProfileItem, error) {
+	ctx, t := timer.Start(ctx, tier.ID, "controller.profile.get_history")
+	defer t.Stop()
+	if err := pk.Validate(); err != nil {
+		return nil, err
+	}
+	return profile.GetHistory(ctx, tier, pk, uint64(start.UnixMicro()), uint64(end.UnixMicro()))
+}
+
+func SetPolicy(ctx context.Context, tier tier.Tier, policy profilelib.Policy) error {
+	if err := policy.Validate(); err != nil {
+		return err
+	}
+	return profile.SetPolicy(ctx, tier, policy)
+}
+
+func DeletePolicy(ctx context.Context, tier tier.Tier, otype ftypes.OType, key string) error {
+	return profile.DeletePolicy(ctx, tier, otype, key)
+}
+
+func Policies(ctx context.Context, tier tier.Tier) (profilelib.Policies, error) {
+	return profile.RetrievePolicies(ctx, tier)
+}
+
 func setBatch(ctx context.Context, tier tier.Tier, requests []profilelib.ProfileItem) error {
 	return profile.SetBatch(ctx, tier, requests)
 }
@@ -0,0 +1,78 @@
+package query
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"fennel/lib/budget"
+	"fennel/lib/ftypes"
+	"fennel/model/query"
+	"fennel/tier"
+
+	"go.uber.org/zap"
+)
+
+// GetBudget returns the budget set on the query, which is empty if the query runs with the default
+// budget of the tier
+func GetBudget(ctx context.Context, tier tier.Tier, name string) (budget.Budget, error) {
+	key := budgetKey(name)
+	if v, ok := tier.PCache.Get(key, cacheNamespace); ok {
+		if b, ok := v.(budget.Budget); ok {
+			return b, nil
+		}
+		tier.Logger.Error("query cache error: ", zap.Error(fmt.Errorf("value not of type budget.Budget: %v", v)))
+	}
+	var b budget.Budget
+	ser, err := query.RetrieveBudget(ctx, tier, name)
+	switch {
+	case err == query.ErrNotFound:
+	case err != nil:
+		return budget.Budget{}, fmt.Errorf("failed to get budget: %w", err)
+	default:
+		if err = json.Unmarshal(ser, &b); err != nil {
+			return budget.Budget{}, fmt.Errorf("failed to unmarshal budget: %w", err)
+		}
+	}
+	if !tier.PCache.SetWithTTL(key, b, 0, aliasCacheDuration, cacheNamespace) {
+		tier.Logger.Debug(fmt.Sprintf("failed to set query budget in cache: key: '%s'", key))
+	}
+	return b, nil
+}
+
+// SetBudget sets the budget of the query, limits that are not set are taken from the default
+// budget of the tier. Budgets are cached, so servers that miss the invalidation see the change
+// within aliasCacheDuration.
+func SetBudget(ctx context.Context, tier tier.Tier, name string, b budget.Budget) error {
+	if err := b.Validate(); err != nil {
+		return fmt.Errorf("invalid budget: %w", err)
+	}
+	if _, err := query.Retrieve(ctx, tier, name); err == query.ErrNotFound {
+		return fmt.Errorf("query with name '%s' not found", name)
+	} else if err != nil {
+		return fmt.Errorf("failed to get query: %w", err)
+	}
+	ser, err := json.Marshal(b)
+	if err != nil {
+		return fmt.Errorf("failed to marshal budget: %w", err)
+	}
+	if err = query.InsertBudget(ctx, tier, name, ser, ftypes.Timestamp(tier.Clock.Now().Unix())); err != nil {
+		return err
+	}
+	tier.Invalidations.Invalidate(ctx, cacheNamespace, budgetKey(name))
+	return nil
+}
+
+// RunBudget returns the budget the query is run with: the default budget of the tier with the
+// limits set on the query replacing those of the tier
+func RunBudget(ctx context.Context, tier tier.Tier, name string) (budget.Budget, error) {
+	b, err := GetBudget(ctx, tier, name)
+	if err != nil {
+		return budget.Budget{}, err
+	}
+	return tier.Args.BudgetArgs.Default().Override(b), nil
+}
+
+func budgetKey(name string) string {
+	return name + "$budget"
+}
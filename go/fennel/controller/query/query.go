@@ -2,30 +2,59 @@ package query
 
 import (
 	"context"
+	"encoding/json"
 	libquery "fennel/lib/query"
 	"fmt"
 	"go.uber.org/zap"
+	"strconv"
 	"time"
 
 	"fennel/engine/ast"
 	"fennel/lib/ftypes"
+	"fennel/lib/value"
 	"fennel/model/query"
 	"fennel/tier"
 )
 
 const cacheValueDuration = 2 * time.Minute
 
-func Insert(ctx context.Context, tier tier.Tier, name string, tree ast.Ast, description string) (uint64, error) {
+// aliases and the latest version of a query can change, so they are cached for much less time
+// than versions which never change; they are also invalidated in every process when they change
+const aliasCacheDuration = 10 * time.Second
+
+// namespace of stored queries in the process-level cache
+const cacheNamespace = "QueryStore"
+
+// storedQuery is the cached value of a version of a stored query
+type storedQuery struct {
+	tree    ast.Ast
+	params  libquery.Params
+	version uint32
+}
+
+// cachedAlias is the cached value of an alias, aliases that don't exist are cached as well
+type cachedAlias struct {
+	alias libquery.Alias
+	found bool
+}
+
+// Insert stores the query as a new version of the query with the given name and returns the
+// version. Versions are immutable, storing a query identical to the latest version returns the
+// latest version instead of creating a new one. The DefaultAlias of a new query points at its
+// first version, later versions are rolled out by moving the alias.
+func Insert(ctx context.Context, tier tier.Tier, name string, tree ast.Ast, description string, params libquery.Params) (uint32, error) {
+	if err := params.Validate(); err != nil {
+		return 0, fmt.Errorf("invalid params: %w", err)
+	}
 	ret, err := query.Retrieve(ctx, tier, name)
 	if err == nil {
 		var tree2 ast.Ast
 		err = ast.Unmarshal(ret.QuerySer, &tree2)
-		if ret.Description == description && err == nil && tree2.Equals(tree) {
-			return ret.QueryId, nil
+		params2, perr := unmarshalParams(ret.ParamsSer)
+		if ret.Description == description && err == nil && tree2.Equals(tree) && perr == nil && params2.Equals(params) {
+			return ret.Version, nil
 		}
-		return 0, fmt.Errorf("query with name '%s' already exists with a different config", name)
-	}
-	if err != query.ErrNotFound {
+	} else if err != query.ErrNotFound {
 		return 0, fmt.Errorf("failed to get query: %w", err)
 	}
 	ts := ftypes.Timestamp(tier.Clock.Now().Unix())
@@ -33,47 +62,296 @@ func Insert(ctx context.Context, tier tier.Tier, name string, tree ast.Ast, desc
 	if err != nil {
 		return 0, fmt.Errorf("failed to marshal ast: %w", err)
 	}
-	return query.Insert(tier, name, ts, treeSer, description)
+	var paramsSer []byte
+	if len(params) > 0 {
+		if paramsSer, err = json.Marshal(params); err != nil {
+			return 0, fmt.Errorf("failed to marshal params: %w", err)
+		}
+	}
+	_, version, err := query.Insert(tier, name, ts, treeSer, description, paramsSer)
+	if err != nil {
+		return 0, err
+	}
+	tier.Invalidations.Invalidate(ctx, cacheNamespace, latestKey(name))
+	if version == 1 {
+		// the missing default alias may be cached
+		tier.Invalidations.Invalidate(ctx, cacheNamespace, aliasKey(name, libquery.DefaultAlias))
+	}
+	return version, nil
 }
 
+// Get returns the version of the query that is run when no version is requested
 func Get(ctx context.Context, tier tier.Tier, name string) (ast.Ast, error) {
+	version, err := resolve(ctx, tier, name, "", value.NewDict(nil))
+	if err != nil {
+		return nil, err
+	}
+	sq, err := get(ctx, tier, name, version)
+	if err != nil {
+		return nil, err
+	}
+	return sq.tree, nil
+}
+
+// Bind returns the version of the stored query that ref resolves to along with the args checked
+// against its params and with defaults filled in. ref is either a version number, an alias or
+// empty for the DefaultAlias (or the latest version if the query has no DefaultAlias). Aliases
+// that split traffic choose the version based on the args.
+func Bind(ctx context.Context, tier tier.Tier, name, ref string, args value.Dict) (ast.Ast, value.Dict, uint32, error) {
+	version, err := resolve(ctx, tier, name, ref, args)
+	if err != nil {
+		return nil, value.Dict{}, 0, err
+	}
+	sq, err := get(ctx, tier, name, version)
+	if err != nil {
+		return nil, value.Dict{}, 0, err
+	}
+	bound, err := sq.params.Bind(args)
+	if err != nil {
+		return nil, value.Dict{}, 0, InvalidArgsError{Name: name, Err: err}
+	}
+	return sq.tree, bound, sq.version, nil
+}
+
+// InvalidArgsError is returned when the args of a stored query don't match its params
+type InvalidArgsError struct {
+	Name string
+	Err  error
+}
+
+func (e InvalidArgsError) Error() string {
+	return fmt.Sprintf("invalid args for query '%s': %v", e.Name, e.Err)
+}
+
+func (e InvalidArgsError) Unwrap() error {
+	return e.Err
+}
+
+// resolve returns the version that ref resolves to, 0 stands for the latest version
+func resolve(ctx context.Context, tier tier.Tier, name, ref string, args value.Dict) (uint32, error) {
+	if len(ref) == 0 {
+		alias, found, err := getAlias(ctx, tier, name, libquery.DefaultAlias)
+		if err != nil || !found {
+			return 0, err
+		}
+		return alias.Choose(args), nil
+	}
+	if version, err := strconv.ParseUint(ref, 10, 32); err == nil {
+		if version == 0 {
+			return 0, fmt.Errorf("versions of query '%s' start at 1", name)
+		}
+		return uint32(version), nil
+	}
+	alias, found, err := getAlias(ctx, tier, name, ref)
+	if err != nil {
+		return 0, err
+	}
+	if !found {
+		return 0, fmt.Errorf("query with name '%s' has no alias '%s'", name, ref)
+	}
+	return alias.Choose(args), nil
+}
+
+func get(ctx context.Context, tier tier.Tier, name string, version uint32) (storedQuery, error) {
+	key, ttl := fmt.Sprintf("%s@%d", name, version), cacheValueDuration
+	if version == 0 {
+		key, ttl = latestKey(name), aliasCacheDuration
+	}
 	// if found in cache, return directly
-	if v, ok := tier.PCache.Get(name, "QueryStore"); ok {
-		if tree, ok := fromCacheValue(tier, v); ok {
-			return tree, nil
+	if v, ok := tier.PCache.Get(key, cacheNamespace); ok {
+		if sq, ok := fromCacheValue(tier, v); ok {
+			return sq, nil
 		}
 	}
 	// otherwise, store in cache and return
-	ret, err := query.Retrieve(ctx, tier, name)
+	var ret libquery.QuerySer
+	var err error
+	if version == 0 {
+		ret, err = query.Retrieve(ctx, tier, name)
+	} else {
+		ret, err = query.RetrieveVersion(ctx, tier, name, version)
+	}
 	if err == query.ErrNotFound {
-		return nil, fmt.Errorf("query with name '%s' not found", name)
+		if version == 0 {
+			return storedQuery{}, fmt.Errorf("query with name '%s' not found", name)
+		}
+		return storedQuery{}, fmt.Errorf("query with name '%s' has no version %d", name, version)
 	} else if err != nil {
-		return nil, fmt.Errorf("failed to get query: %w", err)
+		return storedQuery{}, fmt.Errorf("failed to get query: %w", err)
 	}
 
 	var tree ast.Ast
 	err = ast.Unmarshal(ret.QuerySer, &tree)
 	if err != nil {
-		return nil, fmt.Errorf("failed to unmarshall ast: %w", err)
+		return storedQuery{}, fmt.Errorf("failed to unmarshall ast: %w", err)
+	}
+	params, err := unmarshalParams(ret.ParamsSer)
+	if err != nil {
+		return storedQuery{}, err
 	}
-	if !tier.PCache.SetWithTTL(name, tree, 0, cacheValueDuration, "QueryStore") {
-		tier.Logger.Debug(fmt.Sprintf("failed to set query in cache: key: '%s' value: '%v'", name, tree))
+	sq := storedQuery{tree: tree, params: params, version: ret.Version}
+	if !tier.PCache.SetWithTTL(key, sq, 0, ttl, cacheNamespace) {
+		tier.Logger.Debug(fmt.Sprintf("failed to set query in cache: key: '%s' value: '%v'", key, tree))
 	}
-	return tree, nil
+	return sq, nil
 }
 
+func getAlias(ctx context.Context, tier tier.Tier, name, alias string) (libquery.Alias, bool, error) {
+	key := aliasKey(name, alias)
+	if v, ok := tier.PCache.Get(key, cacheNamespace); ok {
+		if ca, ok := v.(cachedAlias); ok {
+			return ca.alias, ca.found, nil
+		}
+		tier.Logger.Error("query cache error: ", zap.Error(fmt.Errorf("value not of type cachedAlias: %v", v)))
+	}
+	ret, err := query.RetrieveAlias(ctx, tier, name, alias)
+	found := err == nil
+	if err == query.ErrNotFound {
+		err = nil
+	} else if err == nil {
+		err = unmarshalSplit(&ret)
+	}
+	if err != nil {
+		return libquery.Alias{}, false, fmt.Errorf("failed to get alias: %w", err)
+	}
+	if !tier.PCache.SetWithTTL(key, cachedAlias{ret, found}, 0, aliasCacheDuration, cacheNamespace) {
+		tier.Logger.Debug(fmt.Sprintf("failed to set query alias in cache: key: '%s'", key))
+	}
+	return ret, found, nil
+}
+
+// SetAlias points the alias at a version of the query or sets the traffic split of the alias.
+// Aliases are cached, so servers that miss the invalidation see the change within aliasCacheDuration.
+func SetAlias(ctx context.Context, tier tier.Tier, alias libquery.Alias) (libquery.Alias, error) {
+	if err := alias.Validate(); err != nil {
+		return libquery.Alias{}, fmt.Errorf("invalid alias: %w", err)
+	}
+	for _, v := range alias.Versions() {
+		if _, err := query.RetrieveVersion(ctx, tier, alias.QueryName, v); err == query.ErrNotFound {
+			return libquery.Alias{}, fmt.Errorf("query with name '%s' has no version %d", alias.QueryName, v)
+		} else if err != nil {
+			return libquery.Alias{}, fmt.Errorf("failed to get query: %w", err)
+		}
+	}
+	alias.SplitSer = nil
+	if alias.Split != nil {
+		var err error
+		if alias.SplitSer, err = json.Marshal(alias.Split); err != nil {
+			return libquery.Alias{}, fmt.Errorf("failed to marshal split: %w", err)
+		}
+	}
+	alias.UpdatedAt = ftypes.Timestamp(tier.Clock.Now().Unix())
+	id, err := query.InsertAlias(ctx, tier, alias)
+	if err != nil {
+		return libquery.Alias{}, err
+	}
+	alias.ID = id
+	tier.Invalidations.Invalidate(ctx, cacheNamespace, aliasKey(alias.QueryName, alias.Alias))
+	return alias, nil
+}
+
+// Rollback points the alias back at its previous target and returns the alias. Rolling back twice
+// restores the target that was rolled back.
+func Rollback(ctx context.Context, tier tier.Tier, name, alias string) (libquery.Alias, error) {
+	history, err := query.RetrieveAliasHistory(ctx, tier, name, alias, 2)
+	if err != nil {
+		return libquery.Alias{}, err
+	}
+	if len(history) == 0 {
+		return libquery.Alias{}, fmt.Errorf("query with name '%s' has no alias '%s'", name, alias)
+	}
+	if len(history) < 2 {
+		return libquery.Alias{}, fmt.Errorf("alias '%s' of query '%s' has no previous target", alias, name)
+	}
+	prev := history[1]
+	if err = unmarshalSplit(&prev); err != nil {
+		return libquery.Alias{}, err
+	}
+	return SetAlias(ctx, tier, prev)
+}
+
+// Aliases returns the current target of every alias of the query
+func Aliases(ctx context.Context, tier tier.Tier, name string) ([]libquery.Alias, error) {
+	aliases, err := query.RetrieveAliases(ctx, tier, name)
+	if err != nil {
+		return nil, err
+	}
+	for i := range aliases {
+		if err = unmarshalSplit(&aliases[i]); err != nil {
+			return nil, err
+		}
+	}
+	return aliases, nil
+}
+
+// List returns the latest version of all stored queries along with their params
 func List(ctx context.Context, tier tier.Tier) ([]libquery.QuerySer, error) {
-	return query.RetrieveAll(ctx, tier)
+	queries, err := query.RetrieveAll(ctx, tier)
+	if err != nil {
+		return nil, err
+	}
+	return withParams(queries)
+}
+
+// Versions returns all versions of the query, oldest first
+func Versions(ctx context.Context, tier tier.Tier, name string) ([]libquery.QuerySer, error) {
+	queries, err := query.RetrieveVersions(ctx, tier, name)
+	if err != nil {
+		return nil, err
+	}
+	return withParams(queries)
+}
+
+func withParams(queries []libquery.QuerySer) ([]libquery.QuerySer, error) {
+	var err error
+	for i := range queries {
+		if queries[i].Params, err = unmarshalParams(queries[i].ParamsSer); err != nil {
+			return nil, err
+		}
+	}
+	return queries, nil
+}
+
+func unmarshalParams(data []byte) (libquery.Params, error) {
+	if len(data) == 0 {
+		return nil, nil
+	}
+	var params libquery.Params
+	if err := json.Unmarshal(data, &params); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal params: %w", err)
+	}
+	return params, nil
+}
+
+func unmarshalSplit(alias *libquery.Alias) error {
+	alias.Split = nil
+	if len(alias.SplitSer) == 0 {
+		return nil
+	}
+	var split libquery.Split
+	if err := json.Unmarshal(alias.SplitSer, &split); err != nil {
+		return fmt.Errorf("failed to unmarshal split: %w", err)
+	}
+	alias.Split = &split
+	return nil
+}
+
+func latestKey(name string) string {
+	return name + "@latest"
+}
+
+func aliasKey(name, alias string) string {
+	return fmt.Sprintf("%s#%s", name, alias)
 }
 
-func fromCacheValue(tier tier.Tier, v interface{}) (ast.Ast, bool) {
+func fromCacheValue(tier tier.Tier, v interface{}) (storedQuery, bool) {
 	switch v := v.(type) {
-	case ast.Ast:
+	case storedQuery:
 		return v, true
 	default:
 		// log unexpected error
-		err := fmt.Errorf("value not of type stringe: %v", v)
+		err := fmt.Errorf("value not of type storedQuery: %v", v)
 		tier.Logger.Error("query cache error: ", zap.Error(err))
-		return nil, false
+		return storedQuery{}, false
 	}
 }
@@ -5,6 +5,8 @@ import (
 	"testing"
 
 	"fennel/engine/ast"
+	libquery "fennel/lib/query"
+	"fennel/lib/value"
 	"fennel/test"
 
 	"github.com/stretchr/testify/assert"
@@ -20,22 +22,180 @@ func TestQuery(t *testing.T) {
 
 	// store query now
 	tree := ast.MakeInt(5)
-	qID, err := Insert(ctx, tier, "name", tree, "description")
+	version, err := Insert(ctx, tier, "name", tree, "description", nil)
 	assert.NoError(t, err)
-	assert.Equal(t, uint64(1), qID)
+	assert.Equal(t, uint32(1), version)
 
-	qID2, err := Insert(ctx, tier, "name", tree, "description")
+	// storing the same query again does not create a new version
+	version, err = Insert(ctx, tier, "name", tree, "description", nil)
 	assert.NoError(t, err)
-	assert.Equal(t, uint64(1), qID2)
+	assert.Equal(t, uint32(1), version)
 
-	_, err = Insert(ctx, tier, "name", ast.MakeInt(6), "description")
-	assert.Error(t, err)
+	// but any change does
+	version, err = Insert(ctx, tier, "name", ast.MakeInt(6), "description", nil)
+	assert.NoError(t, err)
+	assert.Equal(t, uint32(2), version)
+
+	version, err = Insert(ctx, tier, "name", ast.MakeInt(6), "description2", nil)
+	assert.NoError(t, err)
+	assert.Equal(t, uint32(3), version)
 
-	_, err = Insert(ctx, tier, "name", tree, "description2")
+	_, err = Insert(ctx, tier, "name", tree, "description", libquery.Params{{Name: "x", Type: "Integer"}})
 	assert.Error(t, err)
 
-	// check get works
+	// check get works and returns the version of the default alias, which is the first version
 	found, err := Get(ctx, tier, "name")
 	assert.NoError(t, err)
 	assert.Equal(t, tree, found)
+
+	versions, err := Versions(ctx, tier, "name")
+	assert.NoError(t, err)
+	assert.Len(t, versions, 3)
+	for i, v := range versions {
+		assert.Equal(t, uint32(i+1), v.Version)
+	}
+	queries, err := List(ctx, tier)
+	assert.NoError(t, err)
+	assert.Len(t, queries, 1)
+	assert.Equal(t, uint32(3), queries[0].Version)
+}
+
+func TestAlias(t *testing.T) {
+	tier := test.Tier(t)
+	defer test.Teardown(tier)
+	ctx := context.Background()
+
+	for i := 1; i <= 3; i++ {
+		_, err := Insert(ctx, tier, "ranking", ast.MakeInt(int32(i)), "", nil)
+		assert.NoError(t, err)
+	}
+	args := value.NewDict(nil)
+	// the default alias of a new query points at its first version
+	tree, _, version, err := Bind(ctx, tier, "ranking", "", args)
+	assert.NoError(t, err)
+	assert.Equal(t, uint32(1), version)
+	assert.Equal(t, ast.MakeInt(1), tree)
+
+	// versions can be requested explicitly
+	tree, _, version, err = Bind(ctx, tier, "ranking", "1", args)
+	assert.NoError(t, err)
+	assert.Equal(t, uint32(1), version)
+	assert.Equal(t, ast.MakeInt(1), tree)
+	_, _, _, err = Bind(ctx, tier, "ranking", "4", args)
+	assert.Error(t, err)
+	_, _, _, err = Bind(ctx, tier, "ranking", "staging", args)
+	assert.Error(t, err)
+
+	// aliases can only point to existing versions
+	_, err = SetAlias(ctx, tier, libquery.Alias{QueryName: "ranking", Alias: libquery.DefaultAlias, Version: 4})
+	assert.Error(t, err)
+	_, err = SetAlias(ctx, tier, libquery.Alias{QueryName: "ranking", Alias: libquery.DefaultAlias, Version: 2})
+	assert.NoError(t, err)
+	_, _, version, err = Bind(ctx, tier, "ranking", "", args)
+	assert.NoError(t, err)
+	assert.Equal(t, uint32(2), version)
+	_, err = SetAlias(ctx, tier, libquery.Alias{QueryName: "ranking", Alias: "staging", Version: 3})
+	assert.NoError(t, err)
+	_, _, version, err = Bind(ctx, tier, "ranking", "staging", args)
+	assert.NoError(t, err)
+	assert.Equal(t, uint32(3), version)
+
+	// split traffic between versions 1 & 2 on the user id
+	split := &libquery.Split{Arg: "uid", Weights: []libquery.VersionWeight{{Version: 1, Weight: 1}, {Version: 2, Weight: 1}}}
+	_, err = SetAlias(ctx, tier, libquery.Alias{QueryName: "ranking", Alias: "experiment", Split: split})
+	assert.NoError(t, err)
+	seen := make(map[uint32]int)
+	for uid := 0; uid < 100; uid++ {
+		uidArgs := value.NewDict(map[string]value.Value{"uid": value.Int(uid)})
+		_, _, version, err = Bind(ctx, tier, "ranking", "experiment", uidArgs)
+		assert.NoError(t, err)
+		seen[version]++
+		// the same user always gets the same version
+		_, _, again, err := Bind(ctx, tier, "ranking", "experiment", uidArgs)
+		assert.NoError(t, err)
+		assert.Equal(t, version, again)
+	}
+	assert.Len(t, seen, 2)
+
+	// rollback restores the previous target
+	_, err = SetAlias(ctx, tier, libquery.Alias{QueryName: "ranking", Alias: "staging", Version: 2})
+	assert.NoError(t, err)
+	alias, err := Rollback(ctx, tier, "ranking", "staging")
+	assert.NoError(t, err)
+	assert.Equal(t, uint32(3), alias.Version)
+	_, err = Rollback(ctx, tier, "ranking", "other")
+	assert.Error(t, err)
+
+	aliases, err := Aliases(ctx, tier, "ranking")
+	assert.NoError(t, err)
+	assert.Len(t, aliases, 3)
+	for _, a := range aliases {
+		switch a.Alias {
+		case "experiment":
+			assert.Equal(t, split, a.Split)
+		case "staging":
+			assert.Equal(t, uint32(3), a.Version)
+		case libquery.DefaultAlias:
+			assert.Equal(t, uint32(2), a.Version)
+		}
+	}
+}
+
+func TestInsert_DefaultAlias(t *testing.T) {
+	tier := test.Tier(t)
+	defer test.Teardown(tier)
+	ctx := context.Background()
+
+	args := value.NewDict(nil)
+	_, err := Insert(ctx, tier, "ranking", ast.MakeInt(1), "", nil)
+	assert.NoError(t, err)
+	_, _, version, err := Bind(ctx, tier, "ranking", "", args)
+	assert.NoError(t, err)
+	assert.Equal(t, uint32(1), version)
+
+	// a new version does not run by default until the default alias is moved to it
+	version, err = Insert(ctx, tier, "ranking", ast.MakeInt(2), "", nil)
+	assert.NoError(t, err)
+	assert.Equal(t, uint32(2), version)
+	tree, _, version, err := Bind(ctx, tier, "ranking", "", args)
+	assert.NoError(t, err)
+	assert.Equal(t, uint32(1), version)
+	assert.Equal(t, ast.MakeInt(1), tree)
+	_, err = SetAlias(ctx, tier, libquery.Alias{QueryName: "ranking", Alias: libquery.DefaultAlias, Version: 2})
+	assert.NoError(t, err)
+	tree, _, version, err = Bind(ctx, tier, "ranking", "", args)
+	assert.NoError(t, err)
+	assert.Equal(t, uint32(2), version)
+	assert.Equal(t, ast.MakeInt(2), tree)
+}
+
+func TestBind(t *testing.T) {
+	tier := test.Tier(t)
+	defer test.Teardown(tier)
+	ctx := context.Background()
+
+	tree := &ast.Var{Name: "x"}
+	params := libquery.Params{
+		{Name: "x", Type: "Double", Description: "some number"},
+		{Name: "k", Type: "Int", Default: value.Int(10)},
+	}
+	_, err := Insert(ctx, tier, "typed", tree, "", libquery.Params{{Name: "x", Type: "Float"}})
+	assert.Error(t, err)
+	_, err = Insert(ctx, tier, "typed", tree, "", params)
+	assert.NoError(t, err)
+
+	found, args, version, err := Bind(ctx, tier, "typed", "", value.NewDict(map[string]value.Value{"x": value.Int(1)}))
+	assert.NoError(t, err)
+	assert.Equal(t, tree, found)
+	assert.Equal(t, uint32(1), version)
+	assert.Equal(t, value.NewDict(map[string]value.Value{"x": value.Double(1), "k": value.Int(10)}), args)
+
+	_, _, _, err = Bind(ctx, tier, "typed", "", value.NewDict(nil))
+	var invalid InvalidArgsError
+	assert.ErrorAs(t, err, &invalid)
+
+	queries, err := List(ctx, tier)
+	assert.NoError(t, err)
+	assert.Len(t, queries, 1)
+	assert.True(t, params.Equals(queries[0].Params))
 }
@@ -0,0 +1,161 @@
+package querytest
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"fennel/controller/mock"
+	"fennel/engine"
+	"fennel/engine/ast"
+	"fennel/engine/interpreter/bootarg"
+	"fennel/lib/query"
+	"fennel/lib/value"
+	"fennel/tier"
+)
+
+// Suite is a test file for a query: the query, the responses of the operators it calls that are
+// mocked and the cases it is run with
+type Suite struct {
+	// Query is the base64 encoded ast of the query, same as the Ast of a bound query
+	Query string `json:"query"`
+	// Mocks are the mocked responses shared by all cases
+	Mocks mock.Operators `json:"mocks"`
+	Cases []Case         `json:"cases"`
+}
+
+// Case is a run of the query with some args
+type Case struct {
+	Name string
+	Args value.Dict
+	// Mocks are the mocked responses of this case, these are tried before the ones of the suite
+	Mocks mock.Operators
+	// Expected is the output the query is expected to return, nil if the output is not checked
+	Expected value.Value
+	// Error, if set, is a part of the error the query is expected to fail with
+	Error string
+}
+
+func (c *Case) UnmarshalJSON(data []byte) error {
+	var ser struct {
+		Name     string          `json:"name"`
+		Args     json.RawMessage `json:"args"`
+		Mocks    mock.Operators  `json:"mocks"`
+		Expected json.RawMessage `json:"expected"`
+		Error    string          `json:"error"`
+	}
+	if err := json.Unmarshal(data, &ser); err != nil {
+		return err
+	}
+	c.Name, c.Mocks, c.Error = ser.Name, ser.Mocks, ser.Error
+	c.Args = value.NewDict(nil)
+	if len(ser.Args) > 0 {
+		v, err := value.FromJSON(ser.Args)
+		if err != nil {
+			return fmt.Errorf("invalid args of case '%s': %w", c.Name, err)
+		}
+		args, ok := v.(value.Dict)
+		if !ok {
+			return fmt.Errorf("expected args of case '%s' to be a dict but found: '%s'", c.Name, v)
+		}
+		c.Args = args
+	}
+	c.Expected = nil
+	if len(ser.Expected) > 0 {
+		v, err := value.FromJSON(ser.Expected)
+		if err != nil {
+			return fmt.Errorf("invalid expected output of case '%s': %w", c.Name, err)
+		}
+		c.Expected = v
+	}
+	return nil
+}
+
+// Result is the outcome of running a case
+type Result struct {
+	Name   string      `json:"name"`
+	Passed bool        `json:"passed"`
+	Output value.Value `json:"output,omitempty"`
+	// Error is the error the query failed with, if any
+	Error string `json:"error,omitempty"`
+	// Message says why the case failed
+	Message string `json:"message,omitempty"`
+}
+
+// Report has the results of all the cases of a suite
+type Report struct {
+	Passed  int      `json:"passed"`
+	Failed  int      `json:"failed"`
+	Results []Result `json:"results"`
+}
+
+// Parse parses a test file and validates its mocks
+func Parse(data []byte) (Suite, error) {
+	var suite Suite
+	if err := json.Unmarshal(data, &suite); err != nil {
+		return Suite{}, fmt.Errorf("invalid query test: %w", err)
+	}
+	if len(suite.Query) == 0 {
+		return Suite{}, fmt.Errorf("invalid query test: query not set")
+	}
+	if err := suite.Mocks.Validate(); err != nil {
+		return Suite{}, fmt.Errorf("invalid query test: %w", err)
+	}
+	for i, c := range suite.Cases {
+		if err := c.Mocks.Validate(); err != nil {
+			return Suite{}, fmt.Errorf("invalid query test: case %d: %w", i, err)
+		}
+	}
+	return suite, nil
+}
+
+// Run runs every case of the suite through the interpreter, with the mocked operators swapped in.
+// Operators that read or write data outside the query fail if they are not mocked, so that tests
+// never read or change the data of the tier.
+func Run(ctx context.Context, tr tier.Tier, suite Suite) (Report, error) {
+	tree, err := query.FromString(suite.Query)
+	if err != nil {
+		return Report{}, fmt.Errorf("invalid query: %w", err)
+	}
+	var report Report
+	for i, c := range suite.Cases {
+		res := runCase(ctx, tr, tree, suite.Mocks, c)
+		if len(res.Name) == 0 {
+			res.Name = fmt.Sprintf("case %d", i)
+		}
+		if res.Passed {
+			report.Passed++
+		} else {
+			report.Failed++
+		}
+		report.Results = append(report.Results, res)
+	}
+	return report, nil
+}
+
+func runCase(ctx context.Context, tr tier.Tier, tree ast.Ast, mocks mock.Operators, c Case) Result {
+	bootargs := bootarg.Create(tr)
+	bootarg.SetMocks(bootargs, c.Mocks.Merge(mocks).Strict())
+	out, err := engine.NewQueryExecutor(bootargs).Exec(ctx, tree, c.Args)
+	res := Result{Name: c.Name}
+	switch {
+	case err != nil:
+		res.Error = err.Error()
+		if len(c.Error) == 0 {
+			res.Message = "query failed"
+		} else if !strings.Contains(res.Error, c.Error) {
+			res.Message = fmt.Sprintf("expected query to fail with '%s'", c.Error)
+		}
+	case len(c.Error) > 0:
+		res.Output = out
+		res.Message = fmt.Sprintf("expected query to fail with '%s' but it succeeded", c.Error)
+	default:
+		res.Output = out
+		if c.Expected != nil && !c.Expected.Equal(out) {
+			res.Message = fmt.Sprintf("expected output: '%s'", c.Expected)
+		}
+	}
+	res.Passed = len(res.Message) == 0
+	return res
+}
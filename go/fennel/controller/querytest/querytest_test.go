@@ -0,0 +1,203 @@
+package querytest
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"fennel/engine/ast"
+	"fennel/lib/query"
+	"fennel/lib/value"
+	_ "fennel/opdefs/aggregate"
+	_ "fennel/opdefs/feature"
+	_ "fennel/opdefs/model"
+	_ "fennel/opdefs/std/profile"
+	"fennel/tier"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRun(t *testing.T) {
+	// sets the clicks and the age of each user
+	withClicks := &ast.OpCall{
+		Namespace: "std",
+		Name:      "aggregate",
+		Operands:  []ast.Ast{ast.MakeVar("users")},
+		Vars:      []string{"u"},
+		Kwargs: ast.MakeDict(map[string]ast.Ast{
+			"name":     ast.MakeString("clicks"),
+			"groupkey": ast.MakeLookup(ast.MakeVar("u"), "uid"),
+			"kwargs":   ast.MakeDict(map[string]ast.Ast{"duration": ast.MakeInt(3600)}),
+			"field":    ast.MakeString("clicks"),
+		}),
+	}
+	withAge := &ast.OpCall{
+		Namespace: "std",
+		Name:      "profile",
+		Operands:  []ast.Ast{ast.MakeVar("with_clicks")},
+		Vars:      []string{"u"},
+		Kwargs: ast.MakeDict(map[string]ast.Ast{
+			"otype":   ast.MakeString("user"),
+			"oid":     ast.MakeLookup(ast.MakeVar("u"), "uid"),
+			"key":     ast.MakeString("age"),
+			"field":   ast.MakeString("age"),
+			"default": ast.MakeInt(0),
+		}),
+	}
+	tree := ast.MakeQuery([]*ast.Statement{
+		ast.MakeStatement("with_clicks", withClicks),
+		ast.MakeStatement("", withAge),
+	})
+	q, err := query.ToString(tree)
+	require.NoError(t, err)
+
+	suite, err := Parse([]byte(fmt.Sprintf(`{
+		"query": "%s",
+		"mocks": {
+			"std.aggregate": [
+				{"kwargs": {"name": "clicks", "groupkey": 1, "kwargs": {"duration": 3600}}, "output": 5},
+				{"kwargs": {"name": "clicks"}, "output": 0}
+			],
+			"std.profile": [
+				{"kwargs": {"otype": "user", "oid": 1, "key": "age"}, "output": 30},
+				{"kwargs": {"key": "age"}, "output": null}
+			]
+		},
+		"cases": [
+			{
+				"name": "shared mocks",
+				"args": {"users": [{"uid": 1}, {"uid": 2}]},
+				"expected": [{"uid": 1, "clicks": 5, "age": 30}, {"uid": 2, "clicks": 0, "age": 0}]
+			},
+			{
+				"name": "case mocks",
+				"args": {"users": [{"uid": 2}]},
+				"mocks": {"std.aggregate": [{"kwargs": {"groupkey": 2}, "output": 7}]},
+				"expected": [{"uid": 2, "clicks": 7, "age": 0}]
+			},
+			{
+				"name": "wrong output",
+				"args": {"users": [{"uid": 1}]},
+				"expected": [{"uid": 1, "clicks": 0, "age": 30}]
+			},
+			{
+				"name": "not mocked",
+				"args": {"users": [{"uid": 1}]},
+				"mocks": {"std.profile": [{"kwargs": {"key": "age"}, "output": 1}]},
+				"error": "no mocked response"
+			},
+			{
+				"args": {"users": [{"uid": "1"}]},
+				"expected": [{"uid": "1", "clicks": 0, "age": 0}]
+			}
+		]
+	}`, q)))
+	require.NoError(t, err)
+	// the case mocks are tried first, so the profile of the user in the fourth case is mocked
+	report, err := Run(context.Background(), tier.Tier{}, suite)
+	require.NoError(t, err)
+	assert.Equal(t, 3, report.Passed)
+	assert.Equal(t, 2, report.Failed)
+	require.Len(t, report.Results, 5)
+
+	assert.True(t, report.Results[0].Passed, report.Results[0].Message)
+	assert.True(t, report.Results[1].Passed, report.Results[1].Message)
+
+	assert.False(t, report.Results[2].Passed)
+	assert.Equal(t, "wrong output", report.Results[2].Name)
+	assert.Contains(t, report.Results[2].Message, "expected output")
+	expected, _ := value.FromJSON([]byte(`[{"uid": 1, "clicks": 5, "age": 30}]`))
+	assert.True(t, expected.Equal(report.Results[2].Output))
+
+	assert.False(t, report.Results[3].Passed)
+	assert.Contains(t, report.Results[3].Message, "but it succeeded")
+
+	assert.Equal(t, "case 4", report.Results[4].Name)
+	assert.True(t, report.Results[4].Passed, report.Results[4].Message)
+}
+
+func TestRun_Operand(t *testing.T) {
+	tree := &ast.OpCall{
+		Namespace: "model",
+		Name:      "predict",
+		Operands:  []ast.Ast{ast.MakeList(ast.MakeInt(1), ast.MakeInt(2), ast.MakeInt(3))},
+		Vars:      []string{"x"},
+		Kwargs:    ast.MakeDict(map[string]ast.Ast{"model": ast.MakeString("ranker")}),
+	}
+	q, err := query.ToString(tree)
+	require.NoError(t, err)
+	suite, err := Parse([]byte(fmt.Sprintf(`{
+		"query": "%s",
+		"mocks": {"model.predict": [
+			{"kwargs": {"model": "ranker", "__operand__": 1}, "output": 0.5},
+			{"kwargs": {"model": "ranker", "__operand__": 3}, "output": 0.1}
+		]},
+		"cases": [{"expected": [0.5, 0.1, 0.2]}, {"error": "operand: '2'"}]
+	}`, q)))
+	require.NoError(t, err)
+	report, err := Run(context.Background(), tier.Tier{}, suite)
+	require.NoError(t, err)
+	assert.Equal(t, 1, report.Passed)
+	assert.Equal(t, 1, report.Failed)
+	assert.False(t, report.Results[0].Passed)
+	assert.Contains(t, report.Results[0].Error, "no mocked response of 'model.predict' matches operand: '2'")
+	assert.True(t, report.Results[1].Passed, report.Results[1].Message)
+}
+
+func TestRun_NotMocked(t *testing.T) {
+	// operators that read or write the data of the tier fail instead of running against it
+	for _, tree := range []ast.Ast{
+		&ast.OpCall{
+			Namespace: "std",
+			Name:      "profile",
+			Operands:  []ast.Ast{ast.MakeList(ast.MakeInt(1))},
+			Vars:      []string{"x"},
+			Kwargs: ast.MakeDict(map[string]ast.Ast{
+				"otype": ast.MakeString("user"), "oid": ast.MakeVar("x"), "key": ast.MakeString("age"),
+			}),
+		},
+		&ast.OpCall{
+			Namespace: "feature",
+			Name:      "log",
+			Operands:  []ast.Ast{ast.MakeList(ast.MakeInt(1))},
+			Vars:      []string{"x"},
+			Kwargs: ast.MakeDict(map[string]ast.Ast{
+				"context_otype": ast.MakeString("user"), "context_oid": ast.MakeVar("x"),
+				"candidate_otype": ast.MakeString("video"), "candidate_oid": ast.MakeInt(2),
+				"features": ast.MakeDict(nil), "workflow": ast.MakeString("home"),
+				"request_id": ast.MakeInt(3), "model_name": ast.MakeString(""), "model_version": ast.MakeString(""),
+				"model_prediction": ast.MakeDouble(0.5),
+			}),
+		},
+	} {
+		q, err := query.ToString(tree)
+		require.NoError(t, err)
+		suite, err := Parse([]byte(fmt.Sprintf(`{"query": "%s", "cases": [{}]}`, q)))
+		require.NoError(t, err)
+		report, err := Run(context.Background(), tier.Tier{}, suite)
+		require.NoError(t, err)
+		require.Len(t, report.Results, 1)
+		assert.False(t, report.Results[0].Passed)
+		assert.Contains(t, report.Results[0].Error, "is not mocked")
+	}
+}
+
+func TestParse_Invalid(t *testing.T) {
+	for _, data := range []string{
+		`not json`,
+		`{"cases": []}`,
+		`{"query": "abc", "mocks": {"std.map": []}}`,
+		`{"query": "abc", "cases": [{"mocks": {"std.filter": []}}]}`,
+		`{"query": "abc", "cases": [{"args": [1]}]}`,
+		`{"query": "abc", "mocks": {"std.profile": [{"kwargs": 1}]}}`,
+	} {
+		_, err := Parse([]byte(data))
+		assert.Error(t, err, data)
+	}
+
+	suite, err := Parse([]byte(`{"query": "not an ast", "cases": [{}]}`))
+	require.NoError(t, err)
+	_, err = Run(context.Background(), tier.Tier{}, suite)
+	assert.Error(t, err)
+}
@@ -0,0 +1,308 @@
+package schema
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	actionlib "fennel/lib/action"
+	"fennel/lib/ftypes"
+	profilelib "fennel/lib/profile"
+	"fennel/lib/schema"
+	"fennel/lib/value"
+	model "fennel/model/schema"
+	"fennel/tier"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"go.uber.org/zap"
+)
+
+const (
+	cacheValueDuration = time.Minute
+	cacheNamespace     = "SchemaRegistry"
+)
+
+var invalidRecords = promauto.NewCounterVec(
+	prometheus.CounterOpts{
+		Name: "schema_invalid_records_total",
+		Help: "Total number of records that did not satisfy their schema.",
+	},
+	[]string{"name", "on_invalid"},
+)
+
+// InvalidRecordError is returned when a record of a batch does not satisfy its schema
+type InvalidRecordError struct {
+	Index int
+	Name  string
+	Err   error
+	// Quarantined is set if the schema quarantines invalid records instead of rejecting them
+	Quarantined bool
+}
+
+func (e InvalidRecordError) Error() string {
+	return fmt.Sprintf("record %d does not satisfy schema '%s': %v", e.Index, e.Name, e.Err)
+}
+
+func (e InvalidRecordError) Unwrap() error {
+	return e.Err
+}
+
+// InvalidEvolutionError is returned when a schema is updated in a way that existing records may
+// not satisfy
+type InvalidEvolutionError struct {
+	Name string
+	Err  error
+}
+
+func (e InvalidEvolutionError) Error() string {
+	return fmt.Sprintf("invalid update of schema '%s': %v", e.Name, e.Err)
+}
+
+func (e InvalidEvolutionError) Unwrap() error {
+	return e.Err
+}
+
+// cached is what is stored in the cache, schemas that don't exist are cached too since most
+// action types & profile keys are not expected to have one
+type cached struct {
+	schema schema.Schema
+	found  bool
+}
+
+// Store creates the schema or updates it if it already exists and the update is a valid evolution
+// of the existing schema. It returns the stored schema along with its version.
+func Store(ctx context.Context, tier tier.Tier, s schema.Schema) (schema.Schema, error) {
+	if err := s.Validate(); err != nil {
+		return s, err
+	}
+	if s.OnInvalid == "" {
+		s.OnInvalid = schema.REJECT
+	}
+	s.Version = 1
+	existing, err := Retrieve(ctx, tier, s.Name())
+	if err == nil {
+		if err = existing.CheckEvolution(s); err != nil {
+			return s, InvalidEvolutionError{Name: s.Name(), Err: err}
+		}
+		s.Version = existing.Version
+		if equal(existing, s) {
+			return existing, nil
+		}
+		s.Version = existing.Version + 1
+	} else if !errors.Is(err, schema.ErrNotFound) {
+		return s, err
+	}
+	ser, err := json.Marshal(s)
+	if err != nil {
+		return s, err
+	}
+	if err = model.Set(ctx, tier, s.Name(), s.Version, ser); err != nil {
+		return s, fmt.Errorf("failed to store schema '%s': %w", s.Name(), err)
+	}
+	tier.PCache.SetWithTTL(cacheKey(s.Name()), cached{schema: s, found: true}, 0, cacheValueDuration, cacheNamespace)
+	return s, nil
+}
+
+func Retrieve(ctx context.Context, tier tier.Tier, name string) (schema.Schema, error) {
+	ser, err := model.Retrieve(ctx, tier, name)
+	if err != nil {
+		return schema.Schema{}, err
+	}
+	return fromSer(ser)
+}
+
+func List(ctx context.Context, tier tier.Tier) ([]schema.Schema, error) {
+	sers, err := model.RetrieveAll(ctx, tier)
+	if err != nil {
+		return nil, err
+	}
+	schemas := make([]schema.Schema, len(sers))
+	for i, ser := range sers {
+		if schemas[i], err = fromSer(ser); err != nil {
+			return nil, err
+		}
+	}
+	return schemas, nil
+}
+
+func Delete(ctx context.Context, tier tier.Tier, name string) error {
+	if err := model.Delete(ctx, tier, name); err != nil {
+		return err
+	}
+	tier.PCache.SetWithTTL(cacheKey(name), cached{}, 0, cacheValueDuration, cacheNamespace)
+	return nil
+}
+
+func RetrieveQuarantined(ctx context.Context, tier tier.Tier, name string, limit uint64) ([]schema.QuarantinedRecord, error) {
+	return model.RetrieveQuarantined(ctx, tier, name, limit)
+}
+
+// CheckActions checks the metadata of the actions against the schemas of their action types and returns
+// which of the actions are valid. If any action is invalid and its schema rejects invalid records, an
+// InvalidRecordError is returned and none of the actions should be logged. Otherwise, invalid actions
+// are quarantined and only the valid actions should be logged.
+func CheckActions(ctx context.Context, tier tier.Tier, actions []actionlib.Action) ([]bool, error) {
+	return check(ctx, tier, len(actions), func(i int) (string, value.Value, interface{}) {
+		return schema.ActionSchemaName(actions[i].ActionType), actions[i].Metadata, actions[i]
+	})
+}
+
+// CheckProfiles is like CheckActions but checks the values of the profiles against the schemas of their otype & key
+func CheckProfiles(ctx context.Context, tier tier.Tier, profiles []profilelib.ProfileItem) ([]bool, error) {
+	return check(ctx, tier, len(profiles), func(i int) (string, value.Value, interface{}) {
+		return schema.ProfileSchemaName(profiles[i].OType, profiles[i].Key), profiles[i].Value, profiles[i]
+	})
+}
+
+// CheckActionsEach is like CheckActions but returns the error of each action instead of failing the
+// batch, the error of valid actions is nil. Invalid actions are InvalidRecordErrors and are quarantined
+// if their schema quarantines invalid records, unless the batch is atomic and has invalid actions since
+// none of its actions will be logged.
+func CheckActionsEach(ctx context.Context, tier tier.Tier, actions []actionlib.Action, atomic bool) ([]error, error) {
+	return checkEach(ctx, tier, len(actions), atomic, func(i int) (string, value.Value, interface{}) {
+		return schema.ActionSchemaName(actions[i].ActionType), actions[i].Metadata, actions[i]
+	})
+}
+
+// CheckProfilesEach is like CheckActionsEach but for profiles
+func CheckProfilesEach(ctx context.Context, tier tier.Tier, profiles []profilelib.ProfileItem, atomic bool) ([]error, error) {
+	return checkEach(ctx, tier, len(profiles), atomic, func(i int) (string, value.Value, interface{}) {
+		return schema.ProfileSchemaName(profiles[i].OType, profiles[i].Key), profiles[i].Value, profiles[i]
+	})
+}
+
+// check checks n records, record returns the name of the schema of the i-th record, the value to check and the record itself
+func check(ctx context.Context, tier tier.Tier, n int, record func(i int) (string, value.Value, interface{})) ([]bool, error) {
+	errs, quarantined, err := checkRecords(ctx, tier, n, record)
+	if err != nil {
+		return nil, err
+	}
+	valid := make([]bool, n)
+	for i, err := range errs {
+		var invalid InvalidRecordError
+		if errors.As(err, &invalid) && !invalid.Quarantined {
+			return nil, invalid
+		}
+		valid[i] = err == nil
+	}
+	if err := quarantine(ctx, tier, quarantined); err != nil {
+		return nil, err
+	}
+	return valid, nil
+}
+
+func checkEach(ctx context.Context, tier tier.Tier, n int, atomic bool, record func(i int) (string, value.Value, interface{})) ([]error, error) {
+	errs, quarantined, err := checkRecords(ctx, tier, n, record)
+	if err != nil {
+		return nil, err
+	}
+	if atomic {
+		for _, err := range errs {
+			if err != nil {
+				return errs, nil
+			}
+		}
+	}
+	if err := quarantine(ctx, tier, quarantined); err != nil {
+		return nil, err
+	}
+	return errs, nil
+}
+
+// checkRecords returns the error of each of the n records along with the invalid records that should
+// be quarantined, it does not quarantine them
+func checkRecords(ctx context.Context, tier tier.Tier, n int, record func(i int) (string, value.Value, interface{})) ([]error, []schema.QuarantinedRecord, error) {
+	errs := make([]error, n)
+	var quarantined []schema.QuarantinedRecord
+	for i := 0; i < n; i++ {
+		name, v, r := record(i)
+		s, found, err := get(ctx, tier, name)
+		if err != nil {
+			return nil, nil, err
+		}
+		if !found {
+			continue
+		}
+		if err = s.Check(v); err == nil {
+			continue
+		}
+		invalidRecords.WithLabelValues(s.Name(), s.OnInvalid).Inc()
+		errs[i] = InvalidRecordError{Index: i, Name: s.Name(), Err: err, Quarantined: s.Quarantine()}
+		if !s.Quarantine() {
+			continue
+		}
+		ser, merr := json.Marshal(r)
+		if merr != nil {
+			return nil, nil, merr
+		}
+		quarantined = append(quarantined, schema.QuarantinedRecord{
+			Name:      s.Name(),
+			Record:    string(ser),
+			Error:     err.Error(),
+			Timestamp: ftypes.Timestamp(tier.Clock.Now().Unix()),
+		})
+	}
+	return errs, quarantined, nil
+}
+
+func quarantine(ctx context.Context, tier tier.Tier, records []schema.QuarantinedRecord) error {
+	if len(records) == 0 {
+		return nil
+	}
+	tier.Logger.Warn("Quarantining records that do not satisfy their schema", zap.Int("count", len(records)), zap.String("name", records[0].Name))
+	if err := model.Quarantine(ctx, tier, records); err != nil {
+		return fmt.Errorf("failed to quarantine invalid records: %w", err)
+	}
+	return nil
+}
+
+// get returns the schema with the given name from the cache, falling back to the db
+func get(ctx context.Context, tier tier.Tier, name string) (schema.Schema, bool, error) {
+	if v, ok := tier.PCache.Get(cacheKey(name), cacheNamespace); ok {
+		if c, ok := v.(cached); ok {
+			return c.schema, c.found, nil
+		}
+		tier.Logger.Error("schema cache error: ", zap.Error(fmt.Errorf("value not of type cached: %v", v)))
+	}
+	s, err := Retrieve(ctx, tier, name)
+	found := true
+	if errors.Is(err, schema.ErrNotFound) {
+		found = false
+	} else if err != nil {
+		return s, false, fmt.Errorf("failed to get schema '%s': %w", name, err)
+	}
+	if !tier.PCache.SetWithTTL(cacheKey(name), cached{schema: s, found: found}, 0, cacheValueDuration, cacheNamespace) {
+		tier.Logger.Debug(fmt.Sprintf("failed to set schema in cache: key: '%s'", name))
+	}
+	return s, found, nil
+}
+
+// cacheKey namespaces the name since the cache is shared with queries, aggregates etc.
+func cacheKey(name string) string {
+	return "schema:" + name
+}
+
+func fromSer(ser schema.SchemaSer) (schema.Schema, error) {
+	var s schema.Schema
+	if err := json.Unmarshal(ser.SchemaSer, &s); err != nil {
+		return s, fmt.Errorf("failed to unmarshal schema '%s': %w", ser.Name, err)
+	}
+	s.Version = ser.Version
+	return s, nil
+}
+
+func equal(a, b schema.Schema) bool {
+	sa, err := json.Marshal(a)
+	if err != nil {
+		return false
+	}
+	sb, err := json.Marshal(b)
+	if err != nil {
+		return false
+	}
+	return bytes.Equal(sa, sb)
+}
@@ -0,0 +1,107 @@
+package schema
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	actionlib "fennel/lib/action"
+	profilelib "fennel/lib/profile"
+	"fennel/lib/schema"
+	"fennel/lib/value"
+	"fennel/test"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestStore(t *testing.T) {
+	tier := test.Tier(t)
+	defer test.Teardown(tier)
+	ctx := context.Background()
+
+	_, err := Retrieve(ctx, tier, schema.ActionSchemaName("click"))
+	assert.True(t, errors.Is(err, schema.ErrNotFound))
+
+	s := schema.Schema{Target: schema.ACTION, ActionType: "click", Fields: []schema.Field{{Name: "x", Type: "Int"}}}
+	stored, err := Store(ctx, tier, s)
+	require.NoError(t, err)
+	assert.Equal(t, uint64(1), stored.Version)
+	assert.Equal(t, schema.REJECT, stored.OnInvalid)
+
+	// storing the same schema again is a no-op
+	stored, err = Store(ctx, tier, s)
+	require.NoError(t, err)
+	assert.Equal(t, uint64(1), stored.Version)
+
+	// compatible updates bump the version
+	s.Fields = []schema.Field{{Name: "x", Type: "Double"}, {Name: "y", Type: "String", Optional: true}}
+	stored, err = Store(ctx, tier, s)
+	require.NoError(t, err)
+	assert.Equal(t, uint64(2), stored.Version)
+	found, err := Retrieve(ctx, tier, s.Name())
+	require.NoError(t, err)
+	assert.Equal(t, stored, found)
+
+	// incompatible updates fail
+	s.Fields = []schema.Field{{Name: "x", Type: "Double"}}
+	_, err = Store(ctx, tier, s)
+	assert.ErrorAs(t, err, &InvalidEvolutionError{})
+
+	p := schema.Schema{Target: schema.PROFILE, OType: "user", Key: "age", Type: "Int"}
+	_, err = Store(ctx, tier, p)
+	require.NoError(t, err)
+	all, err := List(ctx, tier)
+	require.NoError(t, err)
+	assert.Len(t, all, 2)
+
+	require.NoError(t, Delete(ctx, tier, p.Name()))
+	assert.Error(t, Delete(ctx, tier, p.Name()))
+	all, err = List(ctx, tier)
+	require.NoError(t, err)
+	assert.Len(t, all, 1)
+}
+
+func TestCheck(t *testing.T) {
+	tier := test.Tier(t)
+	defer test.Teardown(tier)
+	ctx := context.Background()
+
+	_, err := Store(ctx, tier, schema.Schema{Target: schema.ACTION, ActionType: "click", Type: "Int"})
+	require.NoError(t, err)
+	_, err = Store(ctx, tier, schema.Schema{Target: schema.PROFILE, OType: "user", Key: "age", Type: "Int", OnInvalid: schema.QUARANTINE})
+	require.NoError(t, err)
+
+	// actions without a schema are always valid
+	actions := []actionlib.Action{
+		{ActionType: "click", Metadata: value.Int(1)},
+		{ActionType: "view", Metadata: value.String("anything")},
+	}
+	valid, err := CheckActions(ctx, tier, actions)
+	require.NoError(t, err)
+	assert.Equal(t, []bool{true, true}, valid)
+
+	// invalid actions are rejected
+	actions = append(actions, actionlib.Action{ActionType: "click", Metadata: value.String("1")})
+	_, err = CheckActions(ctx, tier, actions)
+	var invalid InvalidRecordError
+	require.True(t, errors.As(err, &invalid))
+	assert.Equal(t, 2, invalid.Index)
+	assert.Equal(t, "action/click", invalid.Name)
+
+	// invalid profiles are quarantined
+	profiles := []profilelib.ProfileItem{
+		{OType: "user", Oid: "1", Key: "age", Value: value.Int(30)},
+		{OType: "user", Oid: "2", Key: "age", Value: value.String("thirty")},
+		{OType: "user", Oid: "2", Key: "name", Value: value.String("thirty")},
+	}
+	valid, err = CheckProfiles(ctx, tier, profiles)
+	require.NoError(t, err)
+	assert.Equal(t, []bool{true, false, true}, valid)
+	quarantined, err := RetrieveQuarantined(ctx, tier, "profile/user/age", 10)
+	require.NoError(t, err)
+	require.Len(t, quarantined, 1)
+	var p profilelib.ProfileItem
+	require.NoError(t, p.UnmarshalJSON([]byte(quarantined[0].Record)))
+	assert.True(t, p.Equals(&profiles[1]))
+}
@@ -17,6 +17,7 @@ type VisitorString interface {
 	VisitQuery(statements []*Statement) string
 	VisitLookup(on Ast, property string) string
 	VisitIfelse(condition Ast, thenDo Ast, elseDo Ast) string
+	VisitCall(module, name string, args []Ast) string
 }
 
 type VisitorValue interface {
@@ -31,6 +32,7 @@ type VisitorValue interface {
 	VisitQuery(statements []*Statement) (value.Value, error)
 	VisitLookup(on Ast, property string) (value.Value, error)
 	VisitIfelse(condition Ast, thenDo Ast, elseDo Ast) (value.Value, error)
+	VisitCall(module, name string, args []Ast) (value.Value, error)
 }
 
 type Ast interface {
@@ -51,6 +53,7 @@ var _ Ast = (*Statement)(nil)
 var _ Ast = (*Query)(nil)
 var _ Ast = (*Lookup)(nil)
 var _ Ast = (*IfElse)(nil)
+var _ Ast = (*Call)(nil)
 
 type Lookup struct {
 	On       Ast
@@ -342,3 +345,30 @@ func (ifelse *IfElse) Equals(ast Ast) bool {
 		return false
 	}
 }
+
+// Call is a call to a builtin function (e.g. str.lower) with positional arguments.
+// Unlike OpCall, it is evaluated on a single value and not on a list of rows.
+type Call struct {
+	Module string
+	Name   string
+	Args   []Ast
+}
+
+func (c *Call) AcceptValue(v VisitorValue) (value.Value, error) {
+	return v.VisitCall(c.Module, c.Name, c.Args)
+}
+
+func (c *Call) AcceptString(v VisitorString) string {
+	return v.VisitCall(c.Module, c.Name, c.Args)
+}
+
+func (c *Call) Equals(ast Ast) bool {
+	switch c2 := ast.(type) {
+	case *Call:
+		l1 := &List{Values: c.Args}
+		l2 := &List{Values: c2.Args}
+		return c.Module == c2.Module && c.Name == c2.Name && l1.Equals(l2)
+	default:
+		return false
+	}
+}
@@ -7,13 +7,12 @@ import (
 
 type Printer struct{}
 
-func (p Printer) VisitFnCall(module, name string, kwargs map[string]Ast) string {
-	sb := strings.Builder{}
-	for k, v := range kwargs {
-		sb.WriteString(fmt.Sprintf("%s=%s, ", k, v.AcceptString(p)))
+func (p Printer) VisitCall(module, name string, args []Ast) string {
+	strs := make([]string, len(args))
+	for i := range args {
+		strs[i] = args[i].AcceptString(p)
 	}
-	kstr := strings.TrimSuffix(sb.String(), ", ")
-	return fmt.Sprintf("%s.%s(%s)", module, name, kstr)
+	return fmt.Sprintf("%s.%s(%s)", module, name, strings.Join(strs, ", "))
 }
 
 func (p Printer) VisitLookup(on Ast, property string) string {
@@ -37,6 +37,7 @@ type Ast struct {
 	//	*Ast_Lookup
 	//	*Ast_Ifelse
 	//	*Ast_Unary
+	//	*Ast_Call
 	Node isAst_Node `protobuf_oneof:"node"`
 }
 
@@ -156,6 +157,13 @@ func (x *Ast) GetUnary() *Unary {
 	return nil
 }
 
+func (x *Ast) GetCall() *Call {
+	if x, ok := x.GetNode().(*Ast_Call); ok {
+		return x.Call
+	}
+	return nil
+}
+
 type isAst_Node interface {
 	isAst_Node()
 }
@@ -205,7 +213,12 @@ type Ast_Ifelse struct {
 type Ast_Unary struct {
 	// FnCall fncall = 13; [deprecated now]
 	// HighFnCall hfncall = 14; [deprecated now]
-	Unary *Unary `protobuf:"bytes,15,opt,name=unary,proto3,oneof"` // Tuple tuple = 16; [deprecated now]
+	Unary *Unary `protobuf:"bytes,15,opt,name=unary,proto3,oneof"`
+}
+
+type Ast_Call struct {
+	// Tuple tuple = 16; [deprecated now]
+	Call *Call `protobuf:"bytes,17,opt,name=call,proto3,oneof"`
 }
 
 func (*Ast_Atom) isAst_Node() {}
@@ -230,6 +243,8 @@ func (*Ast_Ifelse) isAst_Node() {}
 
 func (*Ast_Unary) isAst_Node() {}
 
+func (*Ast_Call) isAst_Node() {}
+
 type Unary struct {
 	state         protoimpl.MessageState
 	sizeCache     protoimpl.SizeCache
@@ -732,6 +747,70 @@ func (x *OpCall) GetVars() []string {
 	return nil
 }
 
+// call to a builtin function e.g. str.lower(x)
+type Call struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Module string `protobuf:"bytes,1,opt,name=module,proto3" json:"module,omitempty"`
+	Name   string `protobuf:"bytes,2,opt,name=name,proto3" json:"name,omitempty"`
+	Args   []*Ast `protobuf:"bytes,3,rep,name=args,proto3" json:"args,omitempty"`
+}
+
+func (x *Call) Reset() {
+	*x = Call{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_ast_proto_msgTypes[9]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *Call) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Call) ProtoMessage() {}
+
+func (x *Call) ProtoReflect() protoreflect.Message {
+	mi := &file_ast_proto_msgTypes[9]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Call.ProtoReflect.Descriptor instead.
+func (*Call) Descriptor() ([]byte, []int) {
+	return file_ast_proto_rawDescGZIP(), []int{9}
+}
+
+func (x *Call) GetModule() string {
+	if x != nil {
+		return x.Module
+	}
+	return ""
+}
+
+func (x *Call) GetName() string {
+	if x != nil {
+		return x.Name
+	}
+	return ""
+}
+
+func (x *Call) GetArgs() []*Ast {
+	if x != nil {
+		return x.Args
+	}
+	return nil
+}
+
 type Var struct {
 	state         protoimpl.MessageState
 	sizeCache     protoimpl.SizeCache
@@ -743,7 +822,7 @@ type Var struct {
 func (x *Var) Reset() {
 	*x = Var{}
 	if protoimpl.UnsafeEnabled {
-		mi := &file_ast_proto_msgTypes[9]
+		mi := &file_ast_proto_msgTypes[10]
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		ms.StoreMessageInfo(mi)
 	}
@@ -756,7 +835,7 @@ func (x *Var) String() string {
 func (*Var) ProtoMessage() {}
 
 func (x *Var) ProtoReflect() protoreflect.Message {
-	mi := &file_ast_proto_msgTypes[9]
+	mi := &file_ast_proto_msgTypes[10]
 	if protoimpl.UnsafeEnabled && x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -769,7 +848,7 @@ func (x *Var) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use Var.ProtoReflect.Descriptor instead.
 func (*Var) Descriptor() ([]byte, []int) {
-	return file_ast_proto_rawDescGZIP(), []int{9}
+	return file_ast_proto_rawDescGZIP(), []int{10}
 }
 
 func (x *Var) GetName() string {
@@ -791,7 +870,7 @@ type Lookup struct {
 func (x *Lookup) Reset() {
 	*x = Lookup{}
 	if protoimpl.UnsafeEnabled {
-		mi := &file_ast_proto_msgTypes[10]
+		mi := &file_ast_proto_msgTypes[11]
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		ms.StoreMessageInfo(mi)
 	}
@@ -804,7 +883,7 @@ func (x *Lookup) String() string {
 func (*Lookup) ProtoMessage() {}
 
 func (x *Lookup) ProtoReflect() protoreflect.Message {
-	mi := &file_ast_proto_msgTypes[10]
+	mi := &file_ast_proto_msgTypes[11]
 	if protoimpl.UnsafeEnabled && x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -817,7 +896,7 @@ func (x *Lookup) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use Lookup.ProtoReflect.Descriptor instead.
 func (*Lookup) Descriptor() ([]byte, []int) {
-	return file_ast_proto_rawDescGZIP(), []int{10}
+	return file_ast_proto_rawDescGZIP(), []int{11}
 }
 
 func (x *Lookup) GetOn() *Ast {
@@ -847,7 +926,7 @@ type IfElse struct {
 func (x *IfElse) Reset() {
 	*x = IfElse{}
 	if protoimpl.UnsafeEnabled {
-		mi := &file_ast_proto_msgTypes[11]
+		mi := &file_ast_proto_msgTypes[12]
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		ms.StoreMessageInfo(mi)
 	}
@@ -860,7 +939,7 @@ func (x *IfElse) String() string {
 func (*IfElse) ProtoMessage() {}
 
 func (x *IfElse) ProtoReflect() protoreflect.Message {
-	mi := &file_ast_proto_msgTypes[11]
+	mi := &file_ast_proto_msgTypes[12]
 	if protoimpl.UnsafeEnabled && x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -873,7 +952,7 @@ func (x *IfElse) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use IfElse.ProtoReflect.Descriptor instead.
 func (*IfElse) Descriptor() ([]byte, []int) {
-	return file_ast_proto_rawDescGZIP(), []int{11}
+	return file_ast_proto_rawDescGZIP(), []int{12}
 }
 
 func (x *IfElse) GetCondition() *Ast {
@@ -911,7 +990,7 @@ type FnCall struct {
 func (x *FnCall) Reset() {
 	*x = FnCall{}
 	if protoimpl.UnsafeEnabled {
-		mi := &file_ast_proto_msgTypes[12]
+		mi := &file_ast_proto_msgTypes[13]
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		ms.StoreMessageInfo(mi)
 	}
@@ -924,7 +1003,7 @@ func (x *FnCall) String() string {
 func (*FnCall) ProtoMessage() {}
 
 func (x *FnCall) ProtoReflect() protoreflect.Message {
-	mi := &file_ast_proto_msgTypes[12]
+	mi := &file_ast_proto_msgTypes[13]
 	if protoimpl.UnsafeEnabled && x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -937,7 +1016,7 @@ func (x *FnCall) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use FnCall.ProtoReflect.Descriptor instead.
 func (*FnCall) Descriptor() ([]byte, []int) {
-	return file_ast_proto_rawDescGZIP(), []int{12}
+	return file_ast_proto_rawDescGZIP(), []int{13}
 }
 
 func (x *FnCall) GetModule() string {
@@ -964,7 +1043,7 @@ func (x *FnCall) GetKwargs() map[string]*Ast {
 var File_ast_proto protoreflect.FileDescriptor
 
 var file_ast_proto_rawDesc = []byte{
-	0x0a, 0x09, 0x61, 0x73, 0x74, 0x2e, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x22, 0xf6, 0x02, 0x0a, 0x03,
+	0x0a, 0x09, 0x61, 0x73, 0x74, 0x2e, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x22, 0x93, 0x03, 0x0a, 0x03,
 	0x41, 0x73, 0x74, 0x12, 0x1b, 0x0a, 0x04, 0x61, 0x74, 0x6f, 0x6d, 0x18, 0x01, 0x20, 0x01, 0x28,
 	0x0b, 0x32, 0x05, 0x2e, 0x41, 0x74, 0x6f, 0x6d, 0x48, 0x00, 0x52, 0x04, 0x61, 0x74, 0x6f, 0x6d,
 	0x12, 0x21, 0x0a, 0x06, 0x62, 0x69, 0x6e, 0x61, 0x72, 0x79, 0x18, 0x02, 0x20, 0x01, 0x28, 0x0b,
@@ -987,74 +1066,80 @@ var file_ast_proto_rawDesc = []byte{
 	0x6c, 0x73, 0x65, 0x18, 0x0c, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x07, 0x2e, 0x49, 0x66, 0x45, 0x6c,
 	0x73, 0x65, 0x48, 0x00, 0x52, 0x06, 0x69, 0x66, 0x65, 0x6c, 0x73, 0x65, 0x12, 0x1e, 0x0a, 0x05,
 	0x75, 0x6e, 0x61, 0x72, 0x79, 0x18, 0x0f, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x06, 0x2e, 0x55, 0x6e,
-	0x61, 0x72, 0x79, 0x48, 0x00, 0x52, 0x05, 0x75, 0x6e, 0x61, 0x72, 0x79, 0x42, 0x06, 0x0a, 0x04,
-	0x6e, 0x6f, 0x64, 0x65, 0x22, 0x37, 0x0a, 0x05, 0x55, 0x6e, 0x61, 0x72, 0x79, 0x12, 0x0e, 0x0a,
-	0x02, 0x6f, 0x70, 0x18, 0x01, 0x20, 0x01, 0x28, 0x09, 0x52, 0x02, 0x6f, 0x70, 0x12, 0x1e, 0x0a,
-	0x07, 0x6f, 0x70, 0x65, 0x72, 0x61, 0x6e, 0x64, 0x18, 0x02, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x04,
-	0x2e, 0x41, 0x73, 0x74, 0x52, 0x07, 0x6f, 0x70, 0x65, 0x72, 0x61, 0x6e, 0x64, 0x22, 0x4e, 0x0a,
-	0x06, 0x42, 0x69, 0x6e, 0x61, 0x72, 0x79, 0x12, 0x18, 0x0a, 0x04, 0x6c, 0x65, 0x66, 0x74, 0x18,
-	0x01, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x04, 0x2e, 0x41, 0x73, 0x74, 0x52, 0x04, 0x6c, 0x65, 0x66,
-	0x74, 0x12, 0x1a, 0x0a, 0x05, 0x72, 0x69, 0x67, 0x68, 0x74, 0x18, 0x02, 0x20, 0x01, 0x28, 0x0b,
-	0x32, 0x04, 0x2e, 0x41, 0x73, 0x74, 0x52, 0x05, 0x72, 0x69, 0x67, 0x68, 0x74, 0x12, 0x0e, 0x0a,
-	0x02, 0x6f, 0x70, 0x18, 0x03, 0x20, 0x01, 0x28, 0x09, 0x52, 0x02, 0x6f, 0x70, 0x22, 0x39, 0x0a,
-	0x09, 0x53, 0x74, 0x61, 0x74, 0x65, 0x6d, 0x65, 0x6e, 0x74, 0x12, 0x12, 0x0a, 0x04, 0x6e, 0x61,
-	0x6d, 0x65, 0x18, 0x01, 0x20, 0x01, 0x28, 0x09, 0x52, 0x04, 0x6e, 0x61, 0x6d, 0x65, 0x12, 0x18,
-	0x0a, 0x04, 0x62, 0x6f, 0x64, 0x79, 0x18, 0x02, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x04, 0x2e, 0x41,
-	0x73, 0x74, 0x52, 0x04, 0x62, 0x6f, 0x64, 0x79, 0x22, 0x33, 0x0a, 0x05, 0x51, 0x75, 0x65, 0x72,
-	0x79, 0x12, 0x2a, 0x0a, 0x0a, 0x73, 0x74, 0x61, 0x74, 0x65, 0x6d, 0x65, 0x6e, 0x74, 0x73, 0x18,
-	0x01, 0x20, 0x03, 0x28, 0x0b, 0x32, 0x0a, 0x2e, 0x53, 0x74, 0x61, 0x74, 0x65, 0x6d, 0x65, 0x6e,
-	0x74, 0x52, 0x0a, 0x73, 0x74, 0x61, 0x74, 0x65, 0x6d, 0x65, 0x6e, 0x74, 0x73, 0x22, 0x6d, 0x0a,
-	0x04, 0x41, 0x74, 0x6f, 0x6d, 0x12, 0x12, 0x0a, 0x03, 0x69, 0x6e, 0x74, 0x18, 0x01, 0x20, 0x01,
-	0x28, 0x03, 0x48, 0x00, 0x52, 0x03, 0x69, 0x6e, 0x74, 0x12, 0x18, 0x0a, 0x06, 0x73, 0x74, 0x72,
-	0x69, 0x6e, 0x67, 0x18, 0x02, 0x20, 0x01, 0x28, 0x09, 0x48, 0x00, 0x52, 0x06, 0x73, 0x74, 0x72,
-	0x69, 0x6e, 0x67, 0x12, 0x14, 0x0a, 0x04, 0x62, 0x6f, 0x6f, 0x6c, 0x18, 0x03, 0x20, 0x01, 0x28,
-	0x08, 0x48, 0x00, 0x52, 0x04, 0x62, 0x6f, 0x6f, 0x6c, 0x12, 0x18, 0x0a, 0x06, 0x64, 0x6f, 0x75,
-	0x62, 0x6c, 0x65, 0x18, 0x04, 0x20, 0x01, 0x28, 0x01, 0x48, 0x00, 0x52, 0x06, 0x64, 0x6f, 0x75,
-	0x62, 0x6c, 0x65, 0x42, 0x07, 0x0a, 0x05, 0x69, 0x6e, 0x6e, 0x65, 0x72, 0x22, 0x24, 0x0a, 0x04,
-	0x4c, 0x69, 0x73, 0x74, 0x12, 0x1c, 0x0a, 0x06, 0x76, 0x61, 0x6c, 0x75, 0x65, 0x73, 0x18, 0x01,
-	0x20, 0x03, 0x28, 0x0b, 0x32, 0x04, 0x2e, 0x41, 0x73, 0x74, 0x52, 0x06, 0x76, 0x61, 0x6c, 0x75,
-	0x65, 0x73, 0x22, 0x72, 0x0a, 0x04, 0x44, 0x69, 0x63, 0x74, 0x12, 0x29, 0x0a, 0x06, 0x76, 0x61,
-	0x6c, 0x75, 0x65, 0x73, 0x18, 0x01, 0x20, 0x03, 0x28, 0x0b, 0x32, 0x11, 0x2e, 0x44, 0x69, 0x63,
-	0x74, 0x2e, 0x56, 0x61, 0x6c, 0x75, 0x65, 0x73, 0x45, 0x6e, 0x74, 0x72, 0x79, 0x52, 0x06, 0x76,
-	0x61, 0x6c, 0x75, 0x65, 0x73, 0x1a, 0x3f, 0x0a, 0x0b, 0x56, 0x61, 0x6c, 0x75, 0x65, 0x73, 0x45,
-	0x6e, 0x74, 0x72, 0x79, 0x12, 0x10, 0x0a, 0x03, 0x6b, 0x65, 0x79, 0x18, 0x01, 0x20, 0x01, 0x28,
-	0x09, 0x52, 0x03, 0x6b, 0x65, 0x79, 0x12, 0x1a, 0x0a, 0x05, 0x76, 0x61, 0x6c, 0x75, 0x65, 0x18,
-	0x02, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x04, 0x2e, 0x41, 0x73, 0x74, 0x52, 0x05, 0x76, 0x61, 0x6c,
-	0x75, 0x65, 0x3a, 0x02, 0x38, 0x01, 0x22, 0x8f, 0x01, 0x0a, 0x06, 0x4f, 0x70, 0x43, 0x61, 0x6c,
-	0x6c, 0x12, 0x1c, 0x0a, 0x09, 0x6e, 0x61, 0x6d, 0x65, 0x73, 0x70, 0x61, 0x63, 0x65, 0x18, 0x02,
-	0x20, 0x01, 0x28, 0x09, 0x52, 0x09, 0x6e, 0x61, 0x6d, 0x65, 0x73, 0x70, 0x61, 0x63, 0x65, 0x12,
-	0x12, 0x0a, 0x04, 0x6e, 0x61, 0x6d, 0x65, 0x18, 0x03, 0x20, 0x01, 0x28, 0x09, 0x52, 0x04, 0x6e,
-	0x61, 0x6d, 0x65, 0x12, 0x1d, 0x0a, 0x06, 0x6b, 0x77, 0x61, 0x72, 0x67, 0x73, 0x18, 0x04, 0x20,
-	0x01, 0x28, 0x0b, 0x32, 0x05, 0x2e, 0x44, 0x69, 0x63, 0x74, 0x52, 0x06, 0x6b, 0x77, 0x61, 0x72,
-	0x67, 0x73, 0x12, 0x20, 0x0a, 0x08, 0x6f, 0x70, 0x65, 0x72, 0x61, 0x6e, 0x64, 0x73, 0x18, 0x05,
-	0x20, 0x03, 0x28, 0x0b, 0x32, 0x04, 0x2e, 0x41, 0x73, 0x74, 0x52, 0x08, 0x6f, 0x70, 0x65, 0x72,
-	0x61, 0x6e, 0x64, 0x73, 0x12, 0x12, 0x0a, 0x04, 0x76, 0x61, 0x72, 0x73, 0x18, 0x06, 0x20, 0x03,
-	0x28, 0x09, 0x52, 0x04, 0x76, 0x61, 0x72, 0x73, 0x22, 0x19, 0x0a, 0x03, 0x56, 0x61, 0x72, 0x12,
-	0x12, 0x0a, 0x04, 0x6e, 0x61, 0x6d, 0x65, 0x18, 0x01, 0x20, 0x01, 0x28, 0x09, 0x52, 0x04, 0x6e,
-	0x61, 0x6d, 0x65, 0x22, 0x3a, 0x0a, 0x06, 0x4c, 0x6f, 0x6f, 0x6b, 0x75, 0x70, 0x12, 0x14, 0x0a,
-	0x02, 0x6f, 0x6e, 0x18, 0x01, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x04, 0x2e, 0x41, 0x73, 0x74, 0x52,
-	0x02, 0x6f, 0x6e, 0x12, 0x1a, 0x0a, 0x08, 0x70, 0x72, 0x6f, 0x70, 0x65, 0x72, 0x74, 0x79, 0x18,
-	0x02, 0x20, 0x01, 0x28, 0x09, 0x52, 0x08, 0x70, 0x72, 0x6f, 0x70, 0x65, 0x72, 0x74, 0x79, 0x22,
-	0x6a, 0x0a, 0x06, 0x49, 0x66, 0x45, 0x6c, 0x73, 0x65, 0x12, 0x22, 0x0a, 0x09, 0x63, 0x6f, 0x6e,
-	0x64, 0x69, 0x74, 0x69, 0x6f, 0x6e, 0x18, 0x01, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x04, 0x2e, 0x41,
-	0x73, 0x74, 0x52, 0x09, 0x63, 0x6f, 0x6e, 0x64, 0x69, 0x74, 0x69, 0x6f, 0x6e, 0x12, 0x1d, 0x0a,
-	0x07, 0x74, 0x68, 0x65, 0x6e, 0x5f, 0x64, 0x6f, 0x18, 0x02, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x04,
-	0x2e, 0x41, 0x73, 0x74, 0x52, 0x06, 0x74, 0x68, 0x65, 0x6e, 0x44, 0x6f, 0x12, 0x1d, 0x0a, 0x07,
-	0x65, 0x6c, 0x73, 0x65, 0x5f, 0x64, 0x6f, 0x18, 0x03, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x04, 0x2e,
-	0x41, 0x73, 0x74, 0x52, 0x06, 0x65, 0x6c, 0x73, 0x65, 0x44, 0x6f, 0x22, 0xa2, 0x01, 0x0a, 0x06,
-	0x46, 0x6e, 0x43, 0x61, 0x6c, 0x6c, 0x12, 0x16, 0x0a, 0x06, 0x6d, 0x6f, 0x64, 0x75, 0x6c, 0x65,
-	0x18, 0x01, 0x20, 0x01, 0x28, 0x09, 0x52, 0x06, 0x6d, 0x6f, 0x64, 0x75, 0x6c, 0x65, 0x12, 0x12,
-	0x0a, 0x04, 0x6e, 0x61, 0x6d, 0x65, 0x18, 0x02, 0x20, 0x01, 0x28, 0x09, 0x52, 0x04, 0x6e, 0x61,
-	0x6d, 0x65, 0x12, 0x2b, 0x0a, 0x06, 0x6b, 0x77, 0x61, 0x72, 0x67, 0x73, 0x18, 0x03, 0x20, 0x03,
-	0x28, 0x0b, 0x32, 0x13, 0x2e, 0x46, 0x6e, 0x43, 0x61, 0x6c, 0x6c, 0x2e, 0x4b, 0x77, 0x61, 0x72,
-	0x67, 0x73, 0x45, 0x6e, 0x74, 0x72, 0x79, 0x52, 0x06, 0x6b, 0x77, 0x61, 0x72, 0x67, 0x73, 0x1a,
-	0x3f, 0x0a, 0x0b, 0x4b, 0x77, 0x61, 0x72, 0x67, 0x73, 0x45, 0x6e, 0x74, 0x72, 0x79, 0x12, 0x10,
-	0x0a, 0x03, 0x6b, 0x65, 0x79, 0x18, 0x01, 0x20, 0x01, 0x28, 0x09, 0x52, 0x03, 0x6b, 0x65, 0x79,
-	0x12, 0x1a, 0x0a, 0x05, 0x76, 0x61, 0x6c, 0x75, 0x65, 0x18, 0x02, 0x20, 0x01, 0x28, 0x0b, 0x32,
-	0x04, 0x2e, 0x41, 0x73, 0x74, 0x52, 0x05, 0x76, 0x61, 0x6c, 0x75, 0x65, 0x3a, 0x02, 0x38, 0x01,
-	0x42, 0x19, 0x5a, 0x17, 0x66, 0x65, 0x6e, 0x6e, 0x65, 0x6c, 0x2f, 0x65, 0x6e, 0x67, 0x69, 0x6e,
-	0x65, 0x2f, 0x61, 0x73, 0x74, 0x2f, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x62, 0x06, 0x70, 0x72, 0x6f,
-	0x74, 0x6f, 0x33,
+	0x61, 0x72, 0x79, 0x48, 0x00, 0x52, 0x05, 0x75, 0x6e, 0x61, 0x72, 0x79, 0x12, 0x1b, 0x0a, 0x04,
+	0x63, 0x61, 0x6c, 0x6c, 0x18, 0x11, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x05, 0x2e, 0x43, 0x61, 0x6c,
+	0x6c, 0x48, 0x00, 0x52, 0x04, 0x63, 0x61, 0x6c, 0x6c, 0x42, 0x06, 0x0a, 0x04, 0x6e, 0x6f, 0x64,
+	0x65, 0x22, 0x37, 0x0a, 0x05, 0x55, 0x6e, 0x61, 0x72, 0x79, 0x12, 0x0e, 0x0a, 0x02, 0x6f, 0x70,
+	0x18, 0x01, 0x20, 0x01, 0x28, 0x09, 0x52, 0x02, 0x6f, 0x70, 0x12, 0x1e, 0x0a, 0x07, 0x6f, 0x70,
+	0x65, 0x72, 0x61, 0x6e, 0x64, 0x18, 0x02, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x04, 0x2e, 0x41, 0x73,
+	0x74, 0x52, 0x07, 0x6f, 0x70, 0x65, 0x72, 0x61, 0x6e, 0x64, 0x22, 0x4e, 0x0a, 0x06, 0x42, 0x69,
+	0x6e, 0x61, 0x72, 0x79, 0x12, 0x18, 0x0a, 0x04, 0x6c, 0x65, 0x66, 0x74, 0x18, 0x01, 0x20, 0x01,
+	0x28, 0x0b, 0x32, 0x04, 0x2e, 0x41, 0x73, 0x74, 0x52, 0x04, 0x6c, 0x65, 0x66, 0x74, 0x12, 0x1a,
+	0x0a, 0x05, 0x72, 0x69, 0x67, 0x68, 0x74, 0x18, 0x02, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x04, 0x2e,
+	0x41, 0x73, 0x74, 0x52, 0x05, 0x72, 0x69, 0x67, 0x68, 0x74, 0x12, 0x0e, 0x0a, 0x02, 0x6f, 0x70,
+	0x18, 0x03, 0x20, 0x01, 0x28, 0x09, 0x52, 0x02, 0x6f, 0x70, 0x22, 0x39, 0x0a, 0x09, 0x53, 0x74,
+	0x61, 0x74, 0x65, 0x6d, 0x65, 0x6e, 0x74, 0x12, 0x12, 0x0a, 0x04, 0x6e, 0x61, 0x6d, 0x65, 0x18,
+	0x01, 0x20, 0x01, 0x28, 0x09, 0x52, 0x04, 0x6e, 0x61, 0x6d, 0x65, 0x12, 0x18, 0x0a, 0x04, 0x62,
+	0x6f, 0x64, 0x79, 0x18, 0x02, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x04, 0x2e, 0x41, 0x73, 0x74, 0x52,
+	0x04, 0x62, 0x6f, 0x64, 0x79, 0x22, 0x33, 0x0a, 0x05, 0x51, 0x75, 0x65, 0x72, 0x79, 0x12, 0x2a,
+	0x0a, 0x0a, 0x73, 0x74, 0x61, 0x74, 0x65, 0x6d, 0x65, 0x6e, 0x74, 0x73, 0x18, 0x01, 0x20, 0x03,
+	0x28, 0x0b, 0x32, 0x0a, 0x2e, 0x53, 0x74, 0x61, 0x74, 0x65, 0x6d, 0x65, 0x6e, 0x74, 0x52, 0x0a,
+	0x73, 0x74, 0x61, 0x74, 0x65, 0x6d, 0x65, 0x6e, 0x74, 0x73, 0x22, 0x6d, 0x0a, 0x04, 0x41, 0x74,
+	0x6f, 0x6d, 0x12, 0x12, 0x0a, 0x03, 0x69, 0x6e, 0x74, 0x18, 0x01, 0x20, 0x01, 0x28, 0x03, 0x48,
+	0x00, 0x52, 0x03, 0x69, 0x6e, 0x74, 0x12, 0x18, 0x0a, 0x06, 0x73, 0x74, 0x72, 0x69, 0x6e, 0x67,
+	0x18, 0x02, 0x20, 0x01, 0x28, 0x09, 0x48, 0x00, 0x52, 0x06, 0x73, 0x74, 0x72, 0x69, 0x6e, 0x67,
+	0x12, 0x14, 0x0a, 0x04, 0x62, 0x6f, 0x6f, 0x6c, 0x18, 0x03, 0x20, 0x01, 0x28, 0x08, 0x48, 0x00,
+	0x52, 0x04, 0x62, 0x6f, 0x6f, 0x6c, 0x12, 0x18, 0x0a, 0x06, 0x64, 0x6f, 0x75, 0x62, 0x6c, 0x65,
+	0x18, 0x04, 0x20, 0x01, 0x28, 0x01, 0x48, 0x00, 0x52, 0x06, 0x64, 0x6f, 0x75, 0x62, 0x6c, 0x65,
+	0x42, 0x07, 0x0a, 0x05, 0x69, 0x6e, 0x6e, 0x65, 0x72, 0x22, 0x24, 0x0a, 0x04, 0x4c, 0x69, 0x73,
+	0x74, 0x12, 0x1c, 0x0a, 0x06, 0x76, 0x61, 0x6c, 0x75, 0x65, 0x73, 0x18, 0x01, 0x20, 0x03, 0x28,
+	0x0b, 0x32, 0x04, 0x2e, 0x41, 0x73, 0x74, 0x52, 0x06, 0x76, 0x61, 0x6c, 0x75, 0x65, 0x73, 0x22,
+	0x72, 0x0a, 0x04, 0x44, 0x69, 0x63, 0x74, 0x12, 0x29, 0x0a, 0x06, 0x76, 0x61, 0x6c, 0x75, 0x65,
+	0x73, 0x18, 0x01, 0x20, 0x03, 0x28, 0x0b, 0x32, 0x11, 0x2e, 0x44, 0x69, 0x63, 0x74, 0x2e, 0x56,
+	0x61, 0x6c, 0x75, 0x65, 0x73, 0x45, 0x6e, 0x74, 0x72, 0x79, 0x52, 0x06, 0x76, 0x61, 0x6c, 0x75,
+	0x65, 0x73, 0x1a, 0x3f, 0x0a, 0x0b, 0x56, 0x61, 0x6c, 0x75, 0x65, 0x73, 0x45, 0x6e, 0x74, 0x72,
+	0x79, 0x12, 0x10, 0x0a, 0x03, 0x6b, 0x65, 0x79, 0x18, 0x01, 0x20, 0x01, 0x28, 0x09, 0x52, 0x03,
+	0x6b, 0x65, 0x79, 0x12, 0x1a, 0x0a, 0x05, 0x76, 0x61, 0x6c, 0x75, 0x65, 0x18, 0x02, 0x20, 0x01,
+	0x28, 0x0b, 0x32, 0x04, 0x2e, 0x41, 0x73, 0x74, 0x52, 0x05, 0x76, 0x61, 0x6c, 0x75, 0x65, 0x3a,
+	0x02, 0x38, 0x01, 0x22, 0x8f, 0x01, 0x0a, 0x06, 0x4f, 0x70, 0x43, 0x61, 0x6c, 0x6c, 0x12, 0x1c,
+	0x0a, 0x09, 0x6e, 0x61, 0x6d, 0x65, 0x73, 0x70, 0x61, 0x63, 0x65, 0x18, 0x02, 0x20, 0x01, 0x28,
+	0x09, 0x52, 0x09, 0x6e, 0x61, 0x6d, 0x65, 0x73, 0x70, 0x61, 0x63, 0x65, 0x12, 0x12, 0x0a, 0x04,
+	0x6e, 0x61, 0x6d, 0x65, 0x18, 0x03, 0x20, 0x01, 0x28, 0x09, 0x52, 0x04, 0x6e, 0x61, 0x6d, 0x65,
+	0x12, 0x1d, 0x0a, 0x06, 0x6b, 0x77, 0x61, 0x72, 0x67, 0x73, 0x18, 0x04, 0x20, 0x01, 0x28, 0x0b,
+	0x32, 0x05, 0x2e, 0x44, 0x69, 0x63, 0x74, 0x52, 0x06, 0x6b, 0x77, 0x61, 0x72, 0x67, 0x73, 0x12,
+	0x20, 0x0a, 0x08, 0x6f, 0x70, 0x65, 0x72, 0x61, 0x6e, 0x64, 0x73, 0x18, 0x05, 0x20, 0x03, 0x28,
+	0x0b, 0x32, 0x04, 0x2e, 0x41, 0x73, 0x74, 0x52, 0x08, 0x6f, 0x70, 0x65, 0x72, 0x61, 0x6e, 0x64,
+	0x73, 0x12, 0x12, 0x0a, 0x04, 0x76, 0x61, 0x72, 0x73, 0x18, 0x06, 0x20, 0x03, 0x28, 0x09, 0x52,
+	0x04, 0x76, 0x61, 0x72, 0x73, 0x22, 0x4c, 0x0a, 0x04, 0x43, 0x61, 0x6c, 0x6c, 0x12, 0x16, 0x0a,
+	0x06, 0x6d, 0x6f, 0x64, 0x75, 0x6c, 0x65, 0x18, 0x01, 0x20, 0x01, 0x28, 0x09, 0x52, 0x06, 0x6d,
+	0x6f, 0x64, 0x75, 0x6c, 0x65, 0x12, 0x12, 0x0a, 0x04, 0x6e, 0x61, 0x6d, 0x65, 0x18, 0x02, 0x20,
+	0x01, 0x28, 0x09, 0x52, 0x04, 0x6e, 0x61, 0x6d, 0x65, 0x12, 0x18, 0x0a, 0x04, 0x61, 0x72, 0x67,
+	0x73, 0x18, 0x03, 0x20, 0x03, 0x28, 0x0b, 0x32, 0x04, 0x2e, 0x41, 0x73, 0x74, 0x52, 0x04, 0x61,
+	0x72, 0x67, 0x73, 0x22, 0x19, 0x0a, 0x03, 0x56, 0x61, 0x72, 0x12, 0x12, 0x0a, 0x04, 0x6e, 0x61,
+	0x6d, 0x65, 0x18, 0x01, 0x20, 0x01, 0x28, 0x09, 0x52, 0x04, 0x6e, 0x61, 0x6d, 0x65, 0x22, 0x3a,
+	0x0a, 0x06, 0x4c, 0x6f, 0x6f, 0x6b, 0x75, 0x70, 0x12, 0x14, 0x0a, 0x02, 0x6f, 0x6e, 0x18, 0x01,
+	0x20, 0x01, 0x28, 0x0b, 0x32, 0x04, 0x2e, 0x41, 0x73, 0x74, 0x52, 0x02, 0x6f, 0x6e, 0x12, 0x1a,
+	0x0a, 0x08, 0x70, 0x72, 0x6f, 0x70, 0x65, 0x72, 0x74, 0x79, 0x18, 0x02, 0x20, 0x01, 0x28, 0x09,
+	0x52, 0x08, 0x70, 0x72, 0x6f, 0x70, 0x65, 0x72, 0x74, 0x79, 0x22, 0x6a, 0x0a, 0x06, 0x49, 0x66,
+	0x45, 0x6c, 0x73, 0x65, 0x12, 0x22, 0x0a, 0x09, 0x63, 0x6f, 0x6e, 0x64, 0x69, 0x74, 0x69, 0x6f,
+	0x6e, 0x18, 0x01, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x04, 0x2e, 0x41, 0x73, 0x74, 0x52, 0x09, 0x63,
+	0x6f, 0x6e, 0x64, 0x69, 0x74, 0x69, 0x6f, 0x6e, 0x12, 0x1d, 0x0a, 0x07, 0x74, 0x68, 0x65, 0x6e,
+	0x5f, 0x64, 0x6f, 0x18, 0x02, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x04, 0x2e, 0x41, 0x73, 0x74, 0x52,
+	0x06, 0x74, 0x68, 0x65, 0x6e, 0x44, 0x6f, 0x12, 0x1d, 0x0a, 0x07, 0x65, 0x6c, 0x73, 0x65, 0x5f,
+	0x64, 0x6f, 0x18, 0x03, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x04, 0x2e, 0x41, 0x73, 0x74, 0x52, 0x06,
+	0x65, 0x6c, 0x73, 0x65, 0x44, 0x6f, 0x22, 0xa2, 0x01, 0x0a, 0x06, 0x46, 0x6e, 0x43, 0x61, 0x6c,
+	0x6c, 0x12, 0x16, 0x0a, 0x06, 0x6d, 0x6f, 0x64, 0x75, 0x6c, 0x65, 0x18, 0x01, 0x20, 0x01, 0x28,
+	0x09, 0x52, 0x06, 0x6d, 0x6f, 0x64, 0x75, 0x6c, 0x65, 0x12, 0x12, 0x0a, 0x04, 0x6e, 0x61, 0x6d,
+	0x65, 0x18, 0x02, 0x20, 0x01, 0x28, 0x09, 0x52, 0x04, 0x6e, 0x61, 0x6d, 0x65, 0x12, 0x2b, 0x0a,
+	0x06, 0x6b, 0x77, 0x61, 0x72, 0x67, 0x73, 0x18, 0x03, 0x20, 0x03, 0x28, 0x0b, 0x32, 0x13, 0x2e,
+	0x46, 0x6e, 0x43, 0x61, 0x6c, 0x6c, 0x2e, 0x4b, 0x77, 0x61, 0x72, 0x67, 0x73, 0x45, 0x6e, 0x74,
+	0x72, 0x79, 0x52, 0x06, 0x6b, 0x77, 0x61, 0x72, 0x67, 0x73, 0x1a, 0x3f, 0x0a, 0x0b, 0x4b, 0x77,
+	0x61, 0x72, 0x67, 0x73, 0x45, 0x6e, 0x74, 0x72, 0x79, 0x12, 0x10, 0x0a, 0x03, 0x6b, 0x65, 0x79,
+	0x18, 0x01, 0x20, 0x01, 0x28, 0x09, 0x52, 0x03, 0x6b, 0x65, 0x79, 0x12, 0x1a, 0x0a, 0x05, 0x76,
+	0x61, 0x6c, 0x75, 0x65, 0x18, 0x02, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x04, 0x2e, 0x41, 0x73, 0x74,
+	0x52, 0x05, 0x76, 0x61, 0x6c, 0x75, 0x65, 0x3a, 0x02, 0x38, 0x01, 0x42, 0x19, 0x5a, 0x17, 0x66,
+	0x65, 0x6e, 0x6e, 0x65, 0x6c, 0x2f, 0x65, 0x6e, 0x67, 0x69, 0x6e, 0x65, 0x2f, 0x61, 0x73, 0x74,
+	0x2f, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x62, 0x06, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x33,
 }
 
 var (
@@ -1069,7 +1154,7 @@ func file_ast_proto_rawDescGZIP() []byte {
 	return file_ast_proto_rawDescData
 }
 
-var file_ast_proto_msgTypes = make([]protoimpl.MessageInfo, 15)
+var file_ast_proto_msgTypes = make([]protoimpl.MessageInfo, 16)
 var file_ast_proto_goTypes = []interface{}{
 	(*Ast)(nil),       // 0: Ast
 	(*Unary)(nil),     // 1: Unary
@@ -1080,12 +1165,13 @@ var file_ast_proto_goTypes = []interface{}{
 	(*List)(nil),      // 6: List
 	(*Dict)(nil),      // 7: Dict
 	(*OpCall)(nil),    // 8: OpCall
-	(*Var)(nil),       // 9: Var
-	(*Lookup)(nil),    // 10: Lookup
-	(*IfElse)(nil),    // 11: IfElse
-	(*FnCall)(nil),    // 12: FnCall
-	nil,               // 13: Dict.ValuesEntry
-	nil,               // 14: FnCall.KwargsEntry
+	(*Call)(nil),      // 9: Call
+	(*Var)(nil),       // 10: Var
+	(*Lookup)(nil),    // 11: Lookup
+	(*IfElse)(nil),    // 12: IfElse
+	(*FnCall)(nil),    // 13: FnCall
+	nil,               // 14: Dict.ValuesEntry
+	nil,               // 15: FnCall.KwargsEntry
 }
 var file_ast_proto_depIdxs = []int32{
 	5,  // 0: Ast.atom:type_name -> Atom
@@ -1095,31 +1181,33 @@ var file_ast_proto_depIdxs = []int32{
 	6,  // 4: Ast.list:type_name -> List
 	7,  // 5: Ast.dict:type_name -> Dict
 	8,  // 6: Ast.opcall:type_name -> OpCall
-	9,  // 7: Ast.var:type_name -> Var
-	10, // 8: Ast.lookup:type_name -> Lookup
-	11, // 9: Ast.ifelse:type_name -> IfElse
+	10, // 7: Ast.var:type_name -> Var
+	11, // 8: Ast.lookup:type_name -> Lookup
+	12, // 9: Ast.ifelse:type_name -> IfElse
 	1,  // 10: Ast.unary:type_name -> Unary
-	0,  // 11: Unary.operand:type_name -> Ast
-	0,  // 12: Binary.left:type_name -> Ast
-	0,  // 13: Binary.right:type_name -> Ast
-	0,  // 14: Statement.body:type_name -> Ast
-	3,  // 15: Query.statements:type_name -> Statement
-	0,  // 16: List.values:type_name -> Ast
-	13, // 17: Dict.values:type_name -> Dict.ValuesEntry
-	7,  // 18: OpCall.kwargs:type_name -> Dict
-	0,  // 19: OpCall.operands:type_name -> Ast
-	0,  // 20: Lookup.on:type_name -> Ast
-	0,  // 21: IfElse.condition:type_name -> Ast
-	0,  // 22: IfElse.then_do:type_name -> Ast
-	0,  // 23: IfElse.else_do:type_name -> Ast
-	14, // 24: FnCall.kwargs:type_name -> FnCall.KwargsEntry
-	0,  // 25: Dict.ValuesEntry.value:type_name -> Ast
-	0,  // 26: FnCall.KwargsEntry.value:type_name -> Ast
-	27, // [27:27] is the sub-list for method output_type
-	27, // [27:27] is the sub-list for method input_type
-	27, // [27:27] is the sub-list for extension type_name
-	27, // [27:27] is the sub-list for extension extendee
-	0,  // [0:27] is the sub-list for field type_name
+	9,  // 11: Ast.call:type_name -> Call
+	0,  // 12: Unary.operand:type_name -> Ast
+	0,  // 13: Binary.left:type_name -> Ast
+	0,  // 14: Binary.right:type_name -> Ast
+	0,  // 15: Statement.body:type_name -> Ast
+	3,  // 16: Query.statements:type_name -> Statement
+	0,  // 17: List.values:type_name -> Ast
+	14, // 18: Dict.values:type_name -> Dict.ValuesEntry
+	7,  // 19: OpCall.kwargs:type_name -> Dict
+	0,  // 20: OpCall.operands:type_name -> Ast
+	0,  // 21: Call.args:type_name -> Ast
+	0,  // 22: Lookup.on:type_name -> Ast
+	0,  // 23: IfElse.condition:type_name -> Ast
+	0,  // 24: IfElse.then_do:type_name -> Ast
+	0,  // 25: IfElse.else_do:type_name -> Ast
+	15, // 26: FnCall.kwargs:type_name -> FnCall.KwargsEntry
+	0,  // 27: Dict.ValuesEntry.value:type_name -> Ast
+	0,  // 28: FnCall.KwargsEntry.value:type_name -> Ast
+	29, // [29:29] is the sub-list for method output_type
+	29, // [29:29] is the sub-list for method input_type
+	29, // [29:29] is the sub-list for extension type_name
+	29, // [29:29] is the sub-list for extension extendee
+	0,  // [0:29] is the sub-list for field type_name
 }
 
 func init() { file_ast_proto_init() }
@@ -1237,7 +1325,7 @@ func file_ast_proto_init() {
 			}
 		}
 		file_ast_proto_msgTypes[9].Exporter = func(v interface{}, i int) interface{} {
-			switch v := v.(*Var); i {
+			switch v := v.(*Call); i {
 			case 0:
 				return &v.state
 			case 1:
@@ -1249,7 +1337,7 @@ func file_ast_proto_init() {
 			}
 		}
 		file_ast_proto_msgTypes[10].Exporter = func(v interface{}, i int) interface{} {
-			switch v := v.(*Lookup); i {
+			switch v := v.(*Var); i {
 			case 0:
 				return &v.state
 			case 1:
@@ -1261,7 +1349,7 @@ func file_ast_proto_init() {
 			}
 		}
 		file_ast_proto_msgTypes[11].Exporter = func(v interface{}, i int) interface{} {
-			switch v := v.(*IfElse); i {
+			switch v := v.(*Lookup); i {
 			case 0:
 				return &v.state
 			case 1:
@@ -1273,6 +1361,18 @@ func file_ast_proto_init() {
 			}
 		}
 		file_ast_proto_msgTypes[12].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*IfElse); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_ast_proto_msgTypes[13].Exporter = func(v interface{}, i int) interface{} {
 			switch v := v.(*FnCall); i {
 			case 0:
 				return &v.state
@@ -1297,6 +1397,7 @@ func file_ast_proto_init() {
 		(*Ast_Lookup)(nil),
 		(*Ast_Ifelse)(nil),
 		(*Ast_Unary)(nil),
+		(*Ast_Call)(nil),
 	}
 	file_ast_proto_msgTypes[5].OneofWrappers = []interface{}{
 		(*Atom_Int)(nil),
@@ -1310,7 +1411,7 @@ func file_ast_proto_init() {
 			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
 			RawDescriptor: file_ast_proto_rawDesc,
 			NumEnums:      0,
-			NumMessages:   15,
+			NumMessages:   16,
 			NumExtensions: 0,
 			NumServices:   0,
 		},
@@ -38,6 +38,8 @@ func FromProtoAst(past *proto.Ast) (Ast, error) {
 		return fromProtoLookup(n)
 	case *proto.Ast_Ifelse:
 		return fromProtoIfelse(n)
+	case *proto.Ast_Call:
+		return fromProtoCall(n)
 	default:
 		return null, fmt.Errorf("invalid proto ast: %v", past)
 	}
@@ -211,6 +213,22 @@ func (ifelse *IfElse) toProto() (proto.Ast, error) {
 	}}}, nil
 }
 
+func (c *Call) toProto() (proto.Ast, error) {
+	pargs := make([]*proto.Ast, len(c.Args))
+	for i, arg := range c.Args {
+		parg, err := ToProtoAst(arg)
+		if err != nil {
+			return pnull(), err
+		}
+		pargs[i] = &parg
+	}
+	return proto.Ast{Node: &proto.Ast_Call{Call: &proto.Call{
+		Module: c.Module,
+		Name:   c.Name,
+		Args:   pargs,
+	}}}, nil
+}
+
 // =============================
 // More private helpers below
 // =============================
@@ -372,3 +390,19 @@ func fromProtoIfelse(pifelse *proto.Ast_Ifelse) (Ast, error) {
 		ElseDo:    elseDo,
 	}, nil
 }
+
+func fromProtoCall(pcall *proto.Ast_Call) (Ast, error) {
+	args := make([]Ast, len(pcall.Call.Args))
+	for i, parg := range pcall.Call.Args {
+		arg, err := FromProtoAst(parg)
+		if err != nil {
+			return null, err
+		}
+		args[i] = arg
+	}
+	return &Call{
+		Module: pcall.Call.Module,
+		Name:   pcall.Call.Name,
+		Args:   args,
+	}, nil
+}
@@ -66,6 +66,13 @@ func MakeLookup(on Ast, property string) *Lookup {
 	}
 }
 
+func MakeCall(module, name string, args ...Ast) *Call {
+	if len(args) == 0 {
+		args = []Ast{}
+	}
+	return &Call{Module: module, Name: name, Args: args}
+}
+
 func MakeStatement(name string, body Ast) *Statement {
 	return &Statement{
 		Name: name,
@@ -135,6 +142,9 @@ func init() {
 			ThenDo:    MakeInt(9),
 			ElseDo:    MakeInt(5),
 		},
+		MakeCall("str", "lower", MakeString("HI")),
+		MakeCall("str", "split", MakeVar("x"), MakeString(",")),
+		MakeCall("my module", "my name"),
 	}
 
 	lookups := make([]Ast, 0)
@@ -7,6 +7,7 @@ import (
 	"fennel/engine/ast"
 	"fennel/engine/interpreter"
 	"fennel/engine/interpreter/bootarg"
+	"fennel/lib/budget"
 	"fennel/lib/timer"
 	"fennel/lib/value"
 )
@@ -26,7 +27,21 @@ func (ex QueryExecutor) Exec(ctx context.Context, query ast.Ast, args value.Dict
 	}
 	ctx, t := timer.Start(ctx, tier.ID, "interpreter.eval")
 	defer t.Stop()
-	ip, err := interpreter.NewInterpreter(ctx, ex.bootargs, args)
+	bootargs := ex.bootargs
+	if b, ok := bootarg.GetBudget(ex.bootargs); ok {
+		// every run gets its own tracker, so the bootargs are copied to not share it between runs
+		bootargs = make(map[string]interface{}, len(ex.bootargs)+1)
+		for k, v := range ex.bootargs {
+			bootargs[k] = v
+		}
+		bootarg.SetTracker(bootargs, budget.NewTracker(b))
+		if b.TimeoutMs > 0 {
+			var cancel context.CancelFunc
+			ctx, cancel = context.WithTimeout(ctx, b.Timeout())
+			defer cancel()
+		}
+	}
+	ip, err := interpreter.NewInterpreter(ctx, bootargs, args)
 	if err != nil {
 		return value.Nil, fmt.Errorf("could not create interpreter: %v", err)
 	}
@@ -2,14 +2,25 @@ package engine
 
 import (
 	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
 	"testing"
+	"time"
 
 	"fennel/engine/ast"
 	"fennel/engine/interpreter/bootarg"
+	"fennel/lib/budget"
 	"fennel/lib/value"
+	_ "fennel/opdefs/remote"
+	_ "fennel/opdefs/std"
+	_ "fennel/opdefs/std/repeat"
+	"fennel/pcache"
 	"fennel/tier"
 
+	"github.com/raulk/clock"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func TestQueryExecution(t *testing.T) {
@@ -65,3 +76,103 @@ func TestQueryExecution(t *testing.T) {
 	assert.NoError(t, err)
 	assert.True(t, expected3.Equal(found3))
 }
+
+func TestQueryExecution_Budget(t *testing.T) {
+	cache, err := pcache.NewPCache(1<<20, 1<<10)
+	require.NoError(t, err)
+	tr := tier.Tier{PCache: cache, Clock: clock.NewMock()}
+	exec := func(ctx context.Context, b budget.Budget, query ast.Ast) (value.Value, error) {
+		bootargs := bootarg.Create(tr)
+		bootarg.SetBudget(bootargs, b)
+		return NewQueryExecutor(bootargs).Exec(ctx, query, value.NewDict(nil))
+	}
+	exceeded := func(t *testing.T, err error, op, resource string) {
+		var e budget.ExceededError
+		require.True(t, errors.As(err, &e), err)
+		assert.Equal(t, op, e.Operator)
+		assert.Equal(t, resource, e.Resource)
+	}
+	// repeats [1, 2] three times and explodes the rows of the repeated list
+	repeat := &ast.OpCall{
+		Namespace: "std",
+		Name:      "repeat",
+		Operands:  []ast.Ast{ast.MakeList(ast.MakeInt(1), ast.MakeInt(2))},
+		Vars:      []string{"x"},
+		Kwargs:    ast.MakeDict(map[string]ast.Ast{"count": ast.MakeInt(3)}),
+	}
+	explode := &ast.OpCall{
+		Namespace: "std",
+		Name:      "explode",
+		Operands:  []ast.Ast{ast.MakeList(ast.MakeDict(map[string]ast.Ast{"x": repeat}))},
+		Kwargs:    ast.MakeDict(map[string]ast.Ast{"field": ast.MakeString("x")}),
+	}
+
+	found, err := exec(context.Background(), budget.Budget{MaxRows: 6, MaxValues: 100}, explode)
+	assert.NoError(t, err)
+	assert.Equal(t, 6, found.(value.List).Len())
+	// the error names the operator that exceeded the budget, even when it is nested in another one
+	_, err = exec(context.Background(), budget.Budget{MaxRows: 5}, explode)
+	exceeded(t, err, "std.repeat", budget.ROWS)
+	_, err = exec(context.Background(), budget.Budget{MaxRows: 5}, &ast.OpCall{
+		Namespace: "std",
+		Name:      "explode",
+		Operands:  []ast.Ast{ast.MakeList(ast.MakeDict(map[string]ast.Ast{"x": ast.MakeList(ast.MakeInt(1), ast.MakeInt(2), ast.MakeInt(3), ast.MakeInt(4), ast.MakeInt(5), ast.MakeInt(6))}))},
+		Kwargs:    ast.MakeDict(map[string]ast.Ast{"field": ast.MakeString("x")}),
+	})
+	exceeded(t, err, "std.explode", budget.ROWS)
+	// repeat allocates its two counts and six rows
+	_, err = exec(context.Background(), budget.Budget{MaxValues: 7}, repeat)
+	exceeded(t, err, "std.repeat", budget.VALUES)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err = exec(ctx, budget.Budget{}, repeat)
+	assert.ErrorIs(t, err, context.Canceled)
+	assert.Contains(t, err.Error(), "std.repeat")
+}
+
+func TestQueryExecution_BudgetRemote(t *testing.T) {
+	cache, err := pcache.NewPCache(1<<20, 1<<10)
+	require.NoError(t, err)
+	tr := tier.Tier{PCache: cache, Clock: clock.NewMock()}
+	exec := func(b budget.Budget, url string) error {
+		bootargs := bootarg.Create(tr)
+		bootarg.SetBudget(bootargs, b)
+		_, err := NewQueryExecutor(bootargs).Exec(context.Background(), &ast.OpCall{
+			Namespace: "remote",
+			Name:      "http",
+			Operands:  []ast.Ast{ast.MakeList(ast.MakeInt(1), ast.MakeInt(2), ast.MakeInt(3))},
+			Vars:      []string{"x"},
+			Kwargs:    ast.MakeDict(map[string]ast.Ast{"url": ast.MakeString(url), "default": ast.MakeInt(0)}),
+		}, value.NewDict(nil))
+		return err
+	}
+	fast := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte("1"))
+	}))
+	defer fast.Close()
+	slow := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-time.After(5 * time.Second):
+		case <-r.Context().Done():
+		}
+		_, _ = w.Write([]byte("1"))
+	}))
+	defer slow.Close()
+
+	var exceeded budget.ExceededError
+	assert.NoError(t, exec(budget.Budget{MaxRemoteCalls: 3}, fast.URL))
+	err = exec(budget.Budget{MaxRemoteCalls: 2}, fast.URL)
+	require.True(t, errors.As(err, &exceeded), err)
+	assert.Equal(t, "remote.http", exceeded.Operator)
+	assert.Equal(t, budget.REMOTE_CALLS, exceeded.Resource)
+
+	// the request in flight is cancelled when the query runs out of time
+	start := time.Now()
+	err = exec(budget.Budget{TimeoutMs: 50}, slow.URL)
+	assert.Less(t, time.Since(start), 5*time.Second)
+	require.True(t, errors.As(err, &exceeded), err)
+	assert.Equal(t, "remote.http", exceeded.Operator)
+	assert.Equal(t, budget.TIMEOUT_MS, exceeded.Resource)
+	assert.Equal(t, int64(50), exceeded.Limit)
+}
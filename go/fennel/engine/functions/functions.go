@@ -0,0 +1,135 @@
+package functions
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"fennel/lib/value"
+)
+
+/*
+	Functions are builtins that can be called from within any RQL expression (e.g. kwargs of an
+	operator) as `module.name(arg1, arg2, ...)`. Unlike operators, a function is evaluated on
+	individual values rather than on lists of rows.
+*/
+
+func init() {
+	registry = make(map[string]map[string]Function)
+}
+
+var registry map[string]map[string]Function
+
+type Arg struct {
+	Name     string
+	Type     value.Type
+	Optional bool
+	Default  value.Value
+}
+
+type Function struct {
+	Module string
+	Name   string
+	Help   string
+	// Args are positional - all optional args (if any) should come after all required args
+	Args []Arg
+	Fn   func(args []value.Value) (value.Value, error)
+}
+
+func Register(fn Function) error {
+	if _, ok := registry[fn.Module]; !ok {
+		registry[fn.Module] = make(map[string]Function)
+	}
+	if _, ok := registry[fn.Module][fn.Name]; ok {
+		return fmt.Errorf("can not register function: module: '%s' & name: '%s' already taken", fn.Module, fn.Name)
+	}
+	seenOptional := false
+	for _, a := range fn.Args {
+		if seenOptional && !a.Optional {
+			return fmt.Errorf("can not register function '%s.%s': required arg '%s' after optional args", fn.Module, fn.Name, a.Name)
+		}
+		seenOptional = seenOptional || a.Optional
+	}
+	registry[fn.Module][fn.Name] = fn
+	return nil
+}
+
+func mustRegister(fns ...Function) {
+	for _, fn := range fns {
+		if err := Register(fn); err != nil {
+			panic(err)
+		}
+	}
+}
+
+func Locate(module, name string) (Function, error) {
+	if m, ok := registry[module]; !ok {
+		return Function{}, fmt.Errorf("unregistered function module: '%s'", module)
+	} else {
+		if ret, ok := m[name]; !ok {
+			return Function{}, fmt.Errorf("unregistered function '%s' in module: '%s'", name, module)
+		} else {
+			return ret, nil
+		}
+	}
+}
+
+// Call validates the given args against the signature of the function, fills in defaults of
+// optional args that were not given and then calls the function
+func (f Function) Call(args []value.Value) (value.Value, error) {
+	if len(args) > len(f.Args) {
+		return value.Nil, fmt.Errorf("function '%s.%s' expects at most '%d' args but received '%d'", f.Module, f.Name, len(f.Args), len(args))
+	}
+	vals := make([]value.Value, len(f.Args))
+	for i, a := range f.Args {
+		if i >= len(args) {
+			if !a.Optional {
+				return value.Nil, fmt.Errorf("arg '%s' not provided for function '%s.%s'", a.Name, f.Module, f.Name)
+			}
+			vals[i] = a.Default
+			continue
+		}
+		if a.Optional && args[i] == value.Nil {
+			vals[i] = a.Default
+			continue
+		}
+		if err := a.Type.Validate(args[i]); err != nil {
+			return value.Nil, fmt.Errorf("function '%s.%s' expects arg '%s' to be of type '%s': %w", f.Module, f.Name, a.Name, a.Type, err)
+		}
+		vals[i] = args[i]
+	}
+	ret, err := f.Fn(vals)
+	if err != nil {
+		return value.Nil, fmt.Errorf("function '%s.%s' failed: %w", f.Module, f.Name, err)
+	}
+	return ret, nil
+}
+
+type arg struct {
+	Name     string `json:"Name"`
+	Type     string `json:"Type"`
+	Optional bool   `json:"Optional"`
+}
+
+type function struct {
+	Help string `json:"Help"`
+	Args []arg  `json:"Args"`
+}
+
+func GetFunctionsJSON() ([]byte, error) {
+	return json.Marshal(GetFunctions())
+}
+
+func GetFunctions() map[string]map[string]function {
+	ret := make(map[string]map[string]function, len(registry))
+	for module, fns := range registry {
+		ret[module] = make(map[string]function, len(fns))
+		for name, fn := range fns {
+			args := make([]arg, len(fn.Args))
+			for i, a := range fn.Args {
+				args[i] = arg{Name: a.Name, Type: a.Type.String(), Optional: a.Optional}
+			}
+			ret[module][name] = function{Help: fn.Help, Args: args}
+		}
+	}
+	return ret
+}
@@ -0,0 +1,189 @@
+package functions
+
+import (
+	"regexp"
+	"testing"
+
+	"fennel/lib/value"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func call(t *testing.T, module, name string, args ...value.Value) (value.Value, error) {
+	fn, err := Locate(module, name)
+	require.NoError(t, err)
+	return fn.Call(args)
+}
+
+func verify(t *testing.T, module, name string, expected value.Value, args ...value.Value) {
+	found, err := call(t, module, name, args...)
+	assert.NoError(t, err, "%s.%s(%v)", module, name, args)
+	assert.True(t, expected.Equal(found), "%s.%s(%v): expected: %s, found: %s", module, name, args, expected, found)
+}
+
+func verifyError(t *testing.T, module, name string, args ...value.Value) {
+	_, err := call(t, module, name, args...)
+	assert.Error(t, err, "%s.%s(%v)", module, name, args)
+}
+
+func TestRegister(t *testing.T) {
+	fn := Function{
+		Module: "test", Name: "fn",
+		Args: []Arg{{Name: "a", Type: value.Types.Int}, {Name: "b", Type: value.Types.Int, Optional: true, Default: value.Int(2)}},
+		Fn: func(args []value.Value) (value.Value, error) {
+			return args[0].Op("+", args[1])
+		},
+	}
+	assert.NoError(t, Register(fn))
+	// can not register the same name again
+	assert.Error(t, Register(fn))
+	// required args can not follow optional ones
+	assert.Error(t, Register(Function{
+		Module: "test", Name: "fn2",
+		Args: []Arg{{Name: "a", Type: value.Types.Int, Optional: true, Default: value.Nil}, {Name: "b", Type: value.Types.Int}},
+	}))
+
+	verify(t, "test", "fn", value.Int(3), value.Int(1))
+	verify(t, "test", "fn", value.Int(6), value.Int(1), value.Int(5))
+	// nil is replaced by default for optional args
+	verify(t, "test", "fn", value.Int(3), value.Int(1), value.Nil)
+	verifyError(t, "test", "fn", value.Nil)
+	verifyError(t, "test", "fn", value.Double(1.0))
+	verifyError(t, "test", "fn", value.Int(1), value.Int(2), value.Int(3))
+
+	_, err := Locate("test", "missing")
+	assert.Error(t, err)
+	_, err = GetFunctionsJSON()
+	assert.NoError(t, err)
+}
+
+func TestStr(t *testing.T) {
+	t.Parallel()
+	s := func(str string) value.Value { return value.String(str) }
+	verify(t, "str", "lower", s("abc ß"), s("ABC ß"))
+	verify(t, "str", "upper", s("ABC"), s("aBc"))
+	verify(t, "str", "strip", s("a b"), s("  a b\n"))
+	verify(t, "str", "strip", s("a/b"), s("//a/b/"), s("/"))
+	verify(t, "str", "len", value.Int(4), s("héll"))
+	verify(t, "str", "split", value.NewList(s("a"), s("b"), s("c")), s("a/b/c"), s("/"))
+	verify(t, "str", "split", value.NewList(s("a"), s("b/c")), s("a/b/c"), s("/"), value.Int(2))
+	verify(t, "str", "join", s("a-b"), value.NewList(s("a"), s("b")), s("-"))
+	verify(t, "str", "join", s("ab"), value.NewList(s("a"), s("b")))
+	verify(t, "str", "replace", s("x-x"), s("a-a"), s("a"), s("x"))
+	verify(t, "str", "contains", value.Bool(true), s("shoes/sports"), s("sport"))
+	verify(t, "str", "startswith", value.Bool(false), s("shoes/sports"), s("sport"))
+	verify(t, "str", "endswith", value.Bool(true), s("shoes/sports"), s("sports"))
+	verify(t, "str", "substr", s("ll"), s("hello"), value.Int(2), value.Int(4))
+	verify(t, "str", "substr", s("llo"), s("hello"), value.Int(-3))
+	verify(t, "str", "substr", s(""), s("hello"), value.Int(4), value.Int(2))
+	verify(t, "str", "substr", s("hello"), s("hello"), value.Int(-10), value.Int(10))
+	verify(t, "str", "to_int", value.Int(-42), s(" -42 "))
+	verify(t, "str", "to_double", value.Double(4.5), s("4.5"))
+
+	verifyError(t, "str", "split", s("a"), s("/"), value.Int(0))
+	verifyError(t, "str", "join", value.NewList(s("a"), value.Int(1)), s("-"))
+	verifyError(t, "str", "to_int", s("4.5"))
+	verifyError(t, "str", "lower", value.Int(1))
+}
+
+func TestRegex(t *testing.T) {
+	t.Parallel()
+	s := func(str string) value.Value { return value.String(str) }
+	verify(t, "re", "match", value.Bool(true), s("order-123"), s(`^order-\d+$`))
+	verify(t, "re", "match", value.Bool(false), s("order-x"), s(`^order-\d+$`))
+	verify(t, "re", "extract", s("order-123"), s("id: order-123"), s(`order-(\d+)`))
+	verify(t, "re", "extract", s("123"), s("id: order-123"), s(`order-(\d+)`), value.Int(1))
+	verify(t, "re", "extract", value.Nil, s("id: order-x"), s(`order-(\d+)`), value.Int(1))
+	verify(t, "re", "findall", value.NewList(s("1"), s("22")), s("a1b22"), s(`\d+`))
+	verify(t, "re", "replace", s("a-b-"), s("a1b22"), s(`\d+`), s("-"))
+	verify(t, "re", "replace", s("b=a"), s("a=b"), s(`(\w)=(\w)`), s("$2=$1"))
+
+	verifyError(t, "re", "match", s("x"), s("("))
+	verifyError(t, "re", "extract", s("x"), s("(x)"), value.Int(2))
+}
+
+func TestRegex_CacheBounded(t *testing.T) {
+	c := newCompiledCache(2)
+	for _, p := range []string{"a", "b", "a", "c"} {
+		c.add(p, regexp.MustCompile(p))
+	}
+	// the least recently used pattern is evicted
+	assert.Equal(t, 2, c.entries.Len())
+	_, ok := c.get("b")
+	assert.False(t, ok)
+	re, ok := c.get("a")
+	assert.True(t, ok)
+	assert.Equal(t, "a", re.String())
+	_, ok = c.get("c")
+	assert.True(t, ok)
+}
+
+func TestTime(t *testing.T) {
+	t.Parallel()
+	// 2022-03-14T23:30:00Z, a Monday
+	ts := value.Int(1647300600)
+	kolkata := value.String("Asia/Kolkata")
+	verify(t, "time", "year", value.Int(2022), ts)
+	verify(t, "time", "month", value.Int(3), ts)
+	verify(t, "time", "day", value.Int(14), ts)
+	verify(t, "time", "day", value.Int(15), ts, kolkata)
+	verify(t, "time", "weekday", value.Int(0), ts)
+	verify(t, "time", "weekday", value.Int(1), ts, kolkata)
+	verify(t, "time", "hour", value.Int(23), ts)
+	verify(t, "time", "hour", value.Int(5), ts, kolkata)
+	verify(t, "time", "minute", value.Int(0), ts, kolkata)
+	verify(t, "time", "bucket", value.Int(1647298800), ts, value.Int(3600))
+	// day buckets start at midnight of the time zone
+	verify(t, "time", "bucket", value.Int(1647282600), ts, value.Int(86400), kolkata)
+	verify(t, "time", "bucket", value.Int(-3600), value.Int(-1), value.Int(3600))
+	// noon of 2022-03-13 in New York is in EDT but the midnight before it is in EST
+	verify(t, "time", "bucket", value.Int(1647147600), value.Int(1647187200), value.Int(86400), value.String("America/New_York"))
+	verify(t, "time", "format", value.String("2022-03-14 23:30:00"), ts, value.String("%Y-%m-%d %H:%M:%S"))
+	verify(t, "time", "format", value.String("Tue, 15 Mar 2022 05:00 AM IST (100%)"), ts, value.String("%a, %d %b %Y %I:%M %p %Z (100%%)"), kolkata)
+	verify(t, "time", "parse", ts, value.String("2022-03-14 23:30:00"), value.String("%Y-%m-%d %H:%M:%S"))
+	verify(t, "time", "parse", ts, value.String("2022-03-15 05:00"), value.String("%Y-%m-%d %H:%M"), kolkata)
+	verify(t, "time", "parse", ts, value.String("2022-03-15T05:00:00+0530"), value.String("%Y-%m-%dT%H:%M:%S%z"))
+
+	verifyError(t, "time", "hour", ts, value.String("Mars/Olympus"))
+	verifyError(t, "time", "bucket", ts, value.Int(0))
+	verifyError(t, "time", "format", ts, value.String("%Q"))
+	verifyError(t, "time", "format", ts, value.String("%"))
+	verifyError(t, "time", "parse", value.String("yesterday"), value.String("%Y-%m-%d"))
+}
+
+func TestHash(t *testing.T) {
+	t.Parallel()
+	verify(t, "hash", "md5", value.String("900150983cd24fb0d6963f7d28e17f72"), value.String("abc"))
+	verify(t, "hash", "sha1", value.String("a9993e364706816aba3e25717850c26c9cd0d89d"), value.String("abc"))
+	verify(t, "hash", "sha256", value.String("ba7816bf8f01cfea414140de5dae2223b00361a396177a9cb410ff61f20015ad"), value.String("abc"))
+	// non strings are hashed by their string representation
+	verify(t, "hash", "md5", value.String("c4ca4238a0b923820dcc509a6f75849b"), value.Int(1))
+
+	b1, err := call(t, "hash", "bucket", value.String("user-1"), value.Int(10))
+	assert.NoError(t, err)
+	b2, err := call(t, "hash", "bucket", value.String("user-1"), value.Int(10))
+	assert.NoError(t, err)
+	assert.Equal(t, b1, b2)
+	assert.True(t, b1.(value.Int) >= 0 && b1.(value.Int) < 10)
+	verifyError(t, "hash", "bucket", value.String("user-1"), value.Int(0))
+}
+
+func TestJSON(t *testing.T) {
+	t.Parallel()
+	doc := value.String(`{"a": {"b": [1, {"c": "x"}]}, "d": 2.5}`)
+	verify(t, "json", "parse", value.NewDict(map[string]value.Value{
+		"a": value.NewDict(map[string]value.Value{
+			"b": value.NewList(value.Int(1), value.NewDict(map[string]value.Value{"c": value.String("x")})),
+		}),
+		"d": value.Double(2.5),
+	}), doc)
+	verify(t, "json", "get", value.String("x"), doc, value.String("a.b.[1].c"))
+	verify(t, "json", "get", value.Double(2.5), doc, value.String("d"))
+	verify(t, "json", "get", value.Nil, doc, value.String("a.missing"))
+	verify(t, "json", "dumps", value.String(`{"a":[1,"x"]}`), value.NewDict(map[string]value.Value{
+		"a": value.NewList(value.Int(1), value.String("x")),
+	}))
+
+	verifyError(t, "json", "parse", value.String("{not json"))
+}
@@ -0,0 +1,63 @@
+package functions
+
+import (
+	"crypto/md5"
+	"crypto/sha1"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"hash"
+
+	"fennel/lib/value"
+
+	"github.com/zeebo/xxh3"
+)
+
+func init() {
+	v := Arg{Name: "v", Type: value.Types.Any}
+	mustRegister(
+		Function{
+			Module: "hash", Name: "md5", Help: "Hex encoded md5 digest of the value (strings are hashed as is, other values by their string representation)",
+			Args: []Arg{v},
+			Fn:   digest(md5.New),
+		},
+		Function{
+			Module: "hash", Name: "sha1", Help: "Hex encoded sha1 digest of the value (strings are hashed as is, other values by their string representation)",
+			Args: []Arg{v},
+			Fn:   digest(sha1.New),
+		},
+		Function{
+			Module: "hash", Name: "sha256", Help: "Hex encoded sha256 digest of the value (strings are hashed as is, other values by their string representation)",
+			Args: []Arg{v},
+			Fn:   digest(sha256.New),
+		},
+		Function{
+			Module: "hash", Name: "bucket", Help: "Deterministically assigns the value to one of 'n' buckets numbered 0 to n-1",
+			Args: []Arg{v, {Name: "n", Type: value.Types.Int}},
+			Fn: func(args []value.Value) (value.Value, error) {
+				n := int64(args[1].(value.Int))
+				if n <= 0 {
+					return value.Nil, fmt.Errorf("number of buckets should be positive but got: %d", n)
+				}
+				return value.Int(xxh3.HashString(hashable(args[0])) % uint64(n)), nil
+			},
+		},
+	)
+}
+
+// hashable returns the raw string for strings so that hashes match those computed outside of RQL,
+// and the unique string representation for everything else
+func hashable(v value.Value) string {
+	if s, ok := v.(value.String); ok {
+		return string(s)
+	}
+	return v.String()
+}
+
+func digest(h func() hash.Hash) func([]value.Value) (value.Value, error) {
+	return func(args []value.Value) (value.Value, error) {
+		hasher := h()
+		hasher.Write([]byte(hashable(args[0])))
+		return value.String(hex.EncodeToString(hasher.Sum(nil))), nil
+	}
+}
@@ -0,0 +1,48 @@
+package functions
+
+import (
+	"errors"
+	"strings"
+
+	"fennel/lib/value"
+
+	"github.com/buger/jsonparser"
+)
+
+func init() {
+	mustRegister(
+		Function{
+			Module: "json", Name: "parse", Help: "Parses the JSON string to a value",
+			Args: []Arg{{Name: "s", Type: value.Types.String}},
+			Fn: func(args []value.Value) (value.Value, error) {
+				return value.FromJSON([]byte(args[0].(value.String)))
+			},
+		},
+		Function{
+			Module: "json", Name: "dumps", Help: "Serializes the value to a JSON string",
+			Args: []Arg{{Name: "v", Type: value.Types.Any}},
+			Fn: func(args []value.Value) (value.Value, error) {
+				return value.String(value.ToJSON(args[0])), nil
+			},
+		},
+		Function{
+			Module: "json", Name: "get", Help: "Value at the dot separated 'path' (array indices written as [i], e.g. 'items.[0].id') of the JSON string, null if the path does not exist",
+			Args: []Arg{{Name: "s", Type: value.Types.String}, {Name: "path", Type: value.Types.String}},
+			Fn:   get,
+		},
+	)
+}
+
+func get(args []value.Value) (value.Value, error) {
+	var keys []string
+	if path := string(args[1].(value.String)); path != "" {
+		keys = strings.Split(path, ".")
+	}
+	vdata, vtype, _, err := jsonparser.Get([]byte(args[0].(value.String)), keys...)
+	if errors.Is(err, jsonparser.KeyPathNotFoundError) {
+		return value.Nil, nil
+	} else if err != nil {
+		return value.Nil, err
+	}
+	return value.ParseJSON(vdata, vtype)
+}
@@ -0,0 +1,137 @@
+package functions
+
+import (
+	"container/list"
+	"fmt"
+	"regexp"
+	"sync"
+
+	"fennel/lib/value"
+)
+
+// maxCompiled bounds the number of compiled regexes that are cached, patterns can come from rows
+// and not only from query literals
+const maxCompiled = 1024
+
+type entry struct {
+	pattern string
+	re      *regexp.Regexp
+}
+
+// compiledCache is an LRU of compiled regexes: patterns are typically query literals and the same
+// few patterns are evaluated for every row, so compiled regexes are cached across calls
+type compiledCache struct {
+	mu        sync.Mutex
+	capacity  int
+	entries   list.List
+	byPattern map[string]*list.Element
+}
+
+func newCompiledCache(capacity int) *compiledCache {
+	return &compiledCache{capacity: capacity, byPattern: make(map[string]*list.Element)}
+}
+
+func (c *compiledCache) get(pattern string) (*regexp.Regexp, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	e, ok := c.byPattern[pattern]
+	if !ok {
+		return nil, false
+	}
+	c.entries.MoveToFront(e)
+	return e.Value.(entry).re, true
+}
+
+func (c *compiledCache) add(pattern string, re *regexp.Regexp) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if e, ok := c.byPattern[pattern]; ok {
+		c.entries.MoveToFront(e)
+		return
+	}
+	c.byPattern[pattern] = c.entries.PushFront(entry{pattern, re})
+	if c.entries.Len() > c.capacity {
+		oldest := c.entries.Back()
+		c.entries.Remove(oldest)
+		delete(c.byPattern, oldest.Value.(entry).pattern)
+	}
+}
+
+var compiled = newCompiledCache(maxCompiled)
+
+func compile(pattern string) (*regexp.Regexp, error) {
+	if re, ok := compiled.get(pattern); ok {
+		return re, nil
+	}
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return nil, fmt.Errorf("invalid regex '%s': %w", pattern, err)
+	}
+	compiled.add(pattern, re)
+	return re, nil
+}
+
+func init() {
+	s := value.Types.String
+	mustRegister(
+		Function{
+			Module: "re", Name: "match", Help: "True if the string contains any match of the regex 'pattern'",
+			Args: []Arg{{Name: "s", Type: s}, {Name: "pattern", Type: s}},
+			Fn: func(args []value.Value) (value.Value, error) {
+				re, err := compile(string(args[1].(value.String)))
+				if err != nil {
+					return value.Nil, err
+				}
+				return value.Bool(re.MatchString(string(args[0].(value.String)))), nil
+			},
+		},
+		Function{
+			Module: "re", Name: "extract", Help: "Text of the capture 'group' (0 for the whole match) of the first match of 'pattern', null if there is no match",
+			Args: []Arg{{Name: "s", Type: s}, {Name: "pattern", Type: s}, {Name: "group", Type: value.Types.Int, Optional: true, Default: value.Int(0)}},
+			Fn:   extract,
+		},
+		Function{
+			Module: "re", Name: "findall", Help: "List of the text of all non-overlapping matches of 'pattern'",
+			Args: []Arg{{Name: "s", Type: s}, {Name: "pattern", Type: s}},
+			Fn: func(args []value.Value) (value.Value, error) {
+				re, err := compile(string(args[1].(value.String)))
+				if err != nil {
+					return value.Nil, err
+				}
+				matches := re.FindAllString(string(args[0].(value.String)), -1)
+				ret := make([]value.Value, len(matches))
+				for i, m := range matches {
+					ret[i] = value.String(m)
+				}
+				return value.NewList(ret...), nil
+			},
+		},
+		Function{
+			Module: "re", Name: "replace", Help: "Replaces all matches of 'pattern' with 'repl', which can refer to capture groups as $1",
+			Args: []Arg{{Name: "s", Type: s}, {Name: "pattern", Type: s}, {Name: "repl", Type: s}},
+			Fn: func(args []value.Value) (value.Value, error) {
+				re, err := compile(string(args[1].(value.String)))
+				if err != nil {
+					return value.Nil, err
+				}
+				return value.String(re.ReplaceAllString(string(args[0].(value.String)), string(args[2].(value.String)))), nil
+			},
+		},
+	)
+}
+
+func extract(args []value.Value) (value.Value, error) {
+	re, err := compile(string(args[1].(value.String)))
+	if err != nil {
+		return value.Nil, err
+	}
+	group := int(args[2].(value.Int))
+	if group < 0 || group > re.NumSubexp() {
+		return value.Nil, fmt.Errorf("regex '%s' has no group '%d'", re, group)
+	}
+	match := re.FindStringSubmatchIndex(string(args[0].(value.String)))
+	if match == nil || match[2*group] < 0 {
+		return value.Nil, nil
+	}
+	return value.String(string(args[0].(value.String))[match[2*group]:match[2*group+1]]), nil
+}
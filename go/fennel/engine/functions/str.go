@@ -0,0 +1,168 @@
+package functions
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"fennel/lib/value"
+)
+
+func init() {
+	s := value.Types.String
+	mustRegister(
+		Function{
+			Module: "str", Name: "lower", Help: "Lowercases the string",
+			Args: []Arg{{Name: "s", Type: s}},
+			Fn:   stringFn(strings.ToLower),
+		},
+		Function{
+			Module: "str", Name: "upper", Help: "Uppercases the string",
+			Args: []Arg{{Name: "s", Type: s}},
+			Fn:   stringFn(strings.ToUpper),
+		},
+		Function{
+			Module: "str", Name: "strip", Help: "Removes leading & trailing characters in 'chars' (whitespace by default)",
+			Args: []Arg{{Name: "s", Type: s}, {Name: "chars", Type: s, Optional: true, Default: value.Nil}},
+			Fn:   strip,
+		},
+		Function{
+			Module: "str", Name: "len", Help: "Number of unicode characters in the string",
+			Args: []Arg{{Name: "s", Type: s}},
+			Fn: func(args []value.Value) (value.Value, error) {
+				return value.Int(len([]rune(args[0].(value.String)))), nil
+			},
+		},
+		Function{
+			Module: "str", Name: "split", Help: "Splits the string around each instance of 'sep', into at most 'limit' parts if limit is positive",
+			Args: []Arg{{Name: "s", Type: s}, {Name: "sep", Type: s}, {Name: "limit", Type: value.Types.Int, Optional: true, Default: value.Int(-1)}},
+			Fn:   split,
+		},
+		Function{
+			Module: "str", Name: "join", Help: "Concatenates a list of strings with 'sep' in between",
+			Args: []Arg{{Name: "parts", Type: value.Types.List}, {Name: "sep", Type: s, Optional: true, Default: value.String("")}},
+			Fn:   join,
+		},
+		Function{
+			Module: "str", Name: "replace", Help: "Replaces all non-overlapping instances of 'old' with 'new'",
+			Args: []Arg{{Name: "s", Type: s}, {Name: "old", Type: s}, {Name: "new", Type: s}},
+			Fn: func(args []value.Value) (value.Value, error) {
+				return value.String(strings.ReplaceAll(string(args[0].(value.String)), string(args[1].(value.String)), string(args[2].(value.String)))), nil
+			},
+		},
+		Function{
+			Module: "str", Name: "contains", Help: "True if 'sub' is within the string",
+			Args: []Arg{{Name: "s", Type: s}, {Name: "sub", Type: s}},
+			Fn:   predicateFn(strings.Contains),
+		},
+		Function{
+			Module: "str", Name: "startswith", Help: "True if the string begins with 'prefix'",
+			Args: []Arg{{Name: "s", Type: s}, {Name: "prefix", Type: s}},
+			Fn:   predicateFn(strings.HasPrefix),
+		},
+		Function{
+			Module: "str", Name: "endswith", Help: "True if the string ends with 'suffix'",
+			Args: []Arg{{Name: "s", Type: s}, {Name: "suffix", Type: s}},
+			Fn:   predicateFn(strings.HasSuffix),
+		},
+		Function{
+			Module: "str", Name: "substr", Help: "Unicode characters in [start, end). Negative indices count from the end of the string",
+			Args: []Arg{{Name: "s", Type: s}, {Name: "start", Type: value.Types.Int}, {Name: "end", Type: value.Types.Int, Optional: true, Default: value.Nil}},
+			Fn:   substr,
+		},
+		Function{
+			Module: "str", Name: "to_int", Help: "Parses the string as a base 10 integer",
+			Args: []Arg{{Name: "s", Type: s}},
+			Fn: func(args []value.Value) (value.Value, error) {
+				n, err := strconv.ParseInt(strings.TrimSpace(string(args[0].(value.String))), 10, 64)
+				if err != nil {
+					return value.Nil, err
+				}
+				return value.Int(n), nil
+			},
+		},
+		Function{
+			Module: "str", Name: "to_double", Help: "Parses the string as a floating point number",
+			Args: []Arg{{Name: "s", Type: s}},
+			Fn: func(args []value.Value) (value.Value, error) {
+				f, err := strconv.ParseFloat(strings.TrimSpace(string(args[0].(value.String))), 64)
+				if err != nil {
+					return value.Nil, err
+				}
+				return value.Double(f), nil
+			},
+		},
+	)
+}
+
+func stringFn(fn func(string) string) func([]value.Value) (value.Value, error) {
+	return func(args []value.Value) (value.Value, error) {
+		return value.String(fn(string(args[0].(value.String)))), nil
+	}
+}
+
+func predicateFn(fn func(string, string) bool) func([]value.Value) (value.Value, error) {
+	return func(args []value.Value) (value.Value, error) {
+		return value.Bool(fn(string(args[0].(value.String)), string(args[1].(value.String)))), nil
+	}
+}
+
+func strip(args []value.Value) (value.Value, error) {
+	s := string(args[0].(value.String))
+	if args[1] == value.Nil {
+		return value.String(strings.TrimSpace(s)), nil
+	}
+	return value.String(strings.Trim(s, string(args[1].(value.String)))), nil
+}
+
+func split(args []value.Value) (value.Value, error) {
+	s, sep := string(args[0].(value.String)), string(args[1].(value.String))
+	limit := int(args[2].(value.Int))
+	if limit == 0 {
+		return value.Nil, fmt.Errorf("limit can not be zero")
+	}
+	parts := strings.SplitN(s, sep, limit)
+	ret := make([]value.Value, len(parts))
+	for i, p := range parts {
+		ret[i] = value.String(p)
+	}
+	return value.NewList(ret...), nil
+}
+
+func join(args []value.Value) (value.Value, error) {
+	parts := args[0].(value.List)
+	strs := make([]string, parts.Len())
+	for i, p := range parts.Values() {
+		ps, ok := p.(value.String)
+		if !ok {
+			return value.Nil, fmt.Errorf("element '%d' of the list is not a string: '%s'", i, p)
+		}
+		strs[i] = string(ps)
+	}
+	return value.String(strings.Join(strs, string(args[1].(value.String)))), nil
+}
+
+func substr(args []value.Value) (value.Value, error) {
+	runes := []rune(string(args[0].(value.String)))
+	n := len(runes)
+	clamp := func(idx int) int {
+		if idx < 0 {
+			idx += n
+		}
+		if idx < 0 {
+			return 0
+		}
+		if idx > n {
+			return n
+		}
+		return idx
+	}
+	start, end := clamp(int(args[1].(value.Int))), n
+	if args[2] != value.Nil {
+		end = clamp(int(args[2].(value.Int)))
+	}
+	if start >= end {
+		return value.String(""), nil
+	}
+	return value.String(runes[start:end]), nil
+}
@@ -0,0 +1,217 @@
+package functions
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+	// embed the timezone database so that time zones work even in containers without tzdata
+	_ "time/tzdata"
+
+	"fennel/lib/value"
+)
+
+/*
+	All timestamps are integer seconds since epoch (same as action timestamps) and every function
+	that depends on wall clock takes an optional IANA time zone name (e.g. "Asia/Kolkata"), UTC
+	being the default.
+*/
+
+var locations sync.Map
+
+func location(tz value.Value) (*time.Location, error) {
+	name := string(tz.(value.String))
+	if loc, ok := locations.Load(name); ok {
+		return loc.(*time.Location), nil
+	}
+	loc, err := time.LoadLocation(name)
+	if err != nil {
+		return nil, fmt.Errorf("invalid time zone '%s': %w", name, err)
+	}
+	locations.Store(name, loc)
+	return loc, nil
+}
+
+func init() {
+	ts := Arg{Name: "ts", Type: value.Types.Int}
+	tz := Arg{Name: "tz", Type: value.Types.String, Optional: true, Default: value.String("UTC")}
+	mustRegister(
+		Function{
+			Module: "time", Name: "year", Help: "Year of the timestamp in the time zone",
+			Args: []Arg{ts, tz},
+			Fn:   component(func(t time.Time) int { return t.Year() }),
+		},
+		Function{
+			Module: "time", Name: "month", Help: "Month (1-12) of the timestamp in the time zone",
+			Args: []Arg{ts, tz},
+			Fn:   component(func(t time.Time) int { return int(t.Month()) }),
+		},
+		Function{
+			Module: "time", Name: "day", Help: "Day of month (1-31) of the timestamp in the time zone",
+			Args: []Arg{ts, tz},
+			Fn:   component(func(t time.Time) int { return t.Day() }),
+		},
+		Function{
+			Module: "time", Name: "weekday", Help: "Day of week of the timestamp in the time zone, Monday is 0 and Sunday is 6",
+			Args: []Arg{ts, tz},
+			Fn:   component(func(t time.Time) int { return (int(t.Weekday()) + 6) % 7 }),
+		},
+		Function{
+			Module: "time", Name: "hour", Help: "Hour of day (0-23) of the timestamp in the time zone",
+			Args: []Arg{ts, tz},
+			Fn:   component(func(t time.Time) int { return t.Hour() }),
+		},
+		Function{
+			Module: "time", Name: "minute", Help: "Minute (0-59) of the timestamp in the time zone",
+			Args: []Arg{ts, tz},
+			Fn:   component(func(t time.Time) int { return t.Minute() }),
+		},
+		Function{
+			Module: "time", Name: "bucket", Help: "Start of the bucket of 'width' seconds containing the timestamp, buckets are aligned to midnight in the time zone",
+			Args: []Arg{ts, {Name: "width", Type: value.Types.Int}, tz},
+			Fn:   bucket,
+		},
+		Function{
+			Module: "time", Name: "format", Help: "Formats the timestamp with a strftime style layout e.g. '%Y-%m-%d %H:%M:%S'",
+			Args: []Arg{ts, {Name: "layout", Type: value.Types.String}, tz},
+			Fn:   format,
+		},
+		Function{
+			Module: "time", Name: "parse", Help: "Parses the string with a strftime style layout to a timestamp, the time zone is used if the layout has none",
+			Args: []Arg{{Name: "s", Type: value.Types.String}, {Name: "layout", Type: value.Types.String}, tz},
+			Fn:   parse,
+		},
+	)
+}
+
+func component(fn func(time.Time) int) func([]value.Value) (value.Value, error) {
+	return func(args []value.Value) (value.Value, error) {
+		loc, err := location(args[1])
+		if err != nil {
+			return value.Nil, err
+		}
+		return value.Int(fn(time.Unix(int64(args[0].(value.Int)), 0).In(loc))), nil
+	}
+}
+
+func bucket(args []value.Value) (value.Value, error) {
+	ts, width := int64(args[0].(value.Int)), int64(args[1].(value.Int))
+	if width <= 0 {
+		return value.Nil, fmt.Errorf("bucket width should be positive but got: %d", width)
+	}
+	loc, err := location(args[2])
+	if err != nil {
+		return value.Nil, err
+	}
+	// floor the wall clock of the time zone and convert the floored wall clock back, which may have
+	// a different offset than the timestamp if they are on different sides of a DST transition
+	t := time.Unix(ts, 0).In(loc)
+	_, offset := t.Zone()
+	local := ts + int64(offset)
+	floored := local - local%width
+	if local%width < 0 {
+		floored -= width
+	}
+	wall := time.Unix(floored, 0).UTC()
+	start := time.Date(wall.Year(), wall.Month(), wall.Day(), wall.Hour(), wall.Minute(), wall.Second(), 0, loc)
+	return value.Int(start.Unix()), nil
+}
+
+func format(args []value.Value) (value.Value, error) {
+	loc, err := location(args[2])
+	if err != nil {
+		return value.Nil, err
+	}
+	t := time.Unix(int64(args[0].(value.Int)), 0).In(loc)
+	// format directive by directive so that literal text in the layout is never mistaken
+	// for parts of the reference time
+	layout := string(args[1].(value.String))
+	var sb strings.Builder
+	for i := 0; i < len(layout); i++ {
+		if layout[i] != '%' {
+			sb.WriteByte(layout[i])
+			continue
+		}
+		if i+1 == len(layout) {
+			return value.Nil, fmt.Errorf("layout '%s' ends with a dangling '%%'", layout)
+		}
+		i++
+		d, ok := directives[layout[i]]
+		if !ok {
+			return value.Nil, fmt.Errorf("unsupported directive '%%%c' in layout '%s'", layout[i], layout)
+		}
+		switch layout[i] {
+		case '%':
+			sb.WriteByte('%')
+		case 'f':
+			// the time package only formats fractional seconds after a '.'
+			sb.WriteString(t.Format(".000000")[1:])
+		default:
+			sb.WriteString(t.Format(d))
+		}
+	}
+	return value.String(sb.String()), nil
+}
+
+func parse(args []value.Value) (value.Value, error) {
+	layout, err := strftime(string(args[1].(value.String)))
+	if err != nil {
+		return value.Nil, err
+	}
+	loc, err := location(args[2])
+	if err != nil {
+		return value.Nil, err
+	}
+	t, err := time.ParseInLocation(layout, string(args[0].(value.String)), loc)
+	if err != nil {
+		return value.Nil, err
+	}
+	return value.Int(t.Unix()), nil
+}
+
+var directives = map[byte]string{
+	'Y': "2006",
+	'y': "06",
+	'm': "01",
+	'd': "02",
+	'H': "15",
+	'I': "03",
+	'M': "04",
+	'S': "05",
+	'p': "PM",
+	'b': "Jan",
+	'B': "January",
+	'a': "Mon",
+	'A': "Monday",
+	'j': "002",
+	'z': "-0700",
+	'Z': "MST",
+	'f': "000000",
+	'%': "%",
+}
+
+// strftime converts a strftime style layout to the reference time layout used by the time package
+// for parsing. Note that digits or month/day names in the literal text of the layout are ambiguous
+func strftime(layout string) (string, error) {
+	var sb strings.Builder
+	for i := 0; i < len(layout); i++ {
+		if layout[i] != '%' {
+			sb.WriteByte(layout[i])
+			continue
+		}
+		if i+1 == len(layout) {
+			return "", fmt.Errorf("layout '%s' ends with a dangling '%%'", layout)
+		}
+		i++
+		d, ok := directives[layout[i]]
+		if !ok {
+			return "", fmt.Errorf("unsupported directive '%%%c' in layout '%s'", layout[i], layout)
+		}
+		if layout[i] == 'f' && !strings.HasSuffix(sb.String(), ".") {
+			// fractional seconds can only be parsed after a '.' by the time package
+			return "", fmt.Errorf("'%%f' should follow a '.' in layout '%s'", layout)
+		}
+		sb.WriteString(d)
+	}
+	return sb.String(), nil
+}
@@ -2,7 +2,10 @@ package bootarg
 
 import (
 	"fmt"
+	"math/rand"
 
+	"fennel/controller/mock"
+	"fennel/lib/budget"
 	"fennel/tier"
 )
 
@@ -23,3 +26,114 @@ func GetTier(bootargs map[string]interface{}) (tier.Tier, error) {
 	}
 	return ret, nil
 }
+
+// RequireTier returns the tier of operators that read or write its data, which fails if the query
+// is run without a tier, e.g. by query tests that run locally
+func RequireTier(bootargs map[string]interface{}) (tier.Tier, error) {
+	tr, err := GetTier(bootargs)
+	if err != nil {
+		return tr, err
+	}
+	if tr.Clock == nil {
+		return tier.Tier{}, fmt.Errorf("operator needs a tier but the query is run without one")
+	}
+	return tr, nil
+}
+
+// QueryVersion identifies the version of a stored query that is being run
+type QueryVersion struct {
+	Name    string
+	Version uint32
+}
+
+// SetQueryVersion records the version of the stored query that is being run so that operators such
+// as feature.log can report it
+func SetQueryVersion(bootargs map[string]interface{}, qv QueryVersion) {
+	bootargs["__query_version__"] = qv
+}
+
+// GetQueryVersion returns the version of the stored query that is being run, the bool is false if
+// the bootargs are not running a stored query
+func GetQueryVersion(bootargs map[string]interface{}) (QueryVersion, bool) {
+	qv, ok := bootargs["__query_version__"].(QueryVersion)
+	return qv, ok
+}
+
+// SetMocks swaps the mocked operators for ones that serve the mocked responses when the query is run
+func SetMocks(bootargs map[string]interface{}, mocks mock.Operators) {
+	bootargs["__mocks__"] = mocks
+}
+
+// GetMocks returns the mocked operators, the bool is false if no operator is mocked
+func GetMocks(bootargs map[string]interface{}) (mock.Operators, bool) {
+	mocks, ok := bootargs["__mocks__"].(mock.Operators)
+	return mocks, ok
+}
+
+// SetBudget bounds the resources the query can use, it is enforced by the executor when the query is run
+func SetBudget(bootargs map[string]interface{}, b budget.Budget) {
+	bootargs["__budget__"] = b
+}
+
+// GetBudget returns the budget of the query, the bool is false if the query is not bounded
+func GetBudget(bootargs map[string]interface{}) (budget.Budget, bool) {
+	b, ok := bootargs["__budget__"].(budget.Budget)
+	return b, ok
+}
+
+// SetTracker sets the tracker of the resources used by the run of the query
+func SetTracker(bootargs map[string]interface{}, t *budget.Tracker) {
+	bootargs["__budget_tracker__"] = t
+}
+
+// GetTracker returns the tracker of the run of the query, which is nil if the query is not bounded.
+// The methods of a nil tracker can still be called and never fail.
+func GetTracker(bootargs map[string]interface{}) *budget.Tracker {
+	t, _ := bootargs["__budget_tracker__"].(*budget.Tracker)
+	return t
+}
+
+// SEED_ARG is the query arg that sets the seed of the query when it is not set in the bootargs
+const SEED_ARG = "__seed__"
+
+// SetSeed makes the query reproducible: operators that consume randomness draw from sources that
+// are seeded by the seed and their position in the query, so every run with the seed has the same result
+func SetSeed(bootargs map[string]interface{}, seed int64) {
+	bootargs["__seed__"] = seed
+}
+
+// GetSeed returns the seed the query is run with, the bool is false if the query is not seeded
+func GetSeed(bootargs map[string]interface{}) (int64, bool) {
+	seed, ok := bootargs["__seed__"].(int64)
+	return seed, ok
+}
+
+// ForOperator returns the bootargs of the operator at the given position of a query run with seed
+func ForOperator(bootargs map[string]interface{}, seed int64, position int) map[string]interface{} {
+	ret := make(map[string]interface{}, len(bootargs)+2)
+	for k, v := range bootargs {
+		ret[k] = v
+	}
+	SetSeed(ret, seed)
+	ret["__operator_seed__"] = operatorSeed(seed, position)
+	return ret
+}
+
+// Rand returns the source of randomness of the operator being created, which is nil if the query
+// is not seeded and the operator should use the global source
+func Rand(bootargs map[string]interface{}) *rand.Rand {
+	seed, ok := bootargs["__operator_seed__"].(int64)
+	if !ok {
+		return nil
+	}
+	return rand.New(rand.NewSource(seed))
+}
+
+// operatorSeed mixes the position into the seed with splitmix64 so that operators at nearby
+// positions get unrelated sources
+func operatorSeed(seed int64, position int) int64 {
+	z := uint64(seed) + uint64(position+1)*0x9e3779b97f4a7c15
+	z = (z ^ (z >> 30)) * 0xbf58476d1ce4e5b9
+	z = (z ^ (z >> 27)) * 0x94d049bb133111eb
+	return int64(z ^ (z >> 31))
+}
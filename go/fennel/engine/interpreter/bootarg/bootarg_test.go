@@ -5,7 +5,9 @@ import (
 
 	"github.com/raulk/clock"
 
+	"fennel/controller/mock"
 	"fennel/db"
+	"fennel/lib/value"
 	"fennel/redis"
 	"fennel/tier"
 
@@ -13,7 +15,7 @@ import (
 )
 
 func Test_Create_GetInstance(t *testing.T) {
-	tier := tier.Tier{
+	tr := tier.Tier{
 		DB:               db.Connection{},
 		Redis:            redis.Client{},
 		Cache:            nil,
@@ -21,10 +23,65 @@ func Test_Create_GetInstance(t *testing.T) {
 		NewKafkaConsumer: nil,
 		Clock:            clock.NewMock(),
 	}
-	b := Create(tier)
+	b := Create(tr)
 	assert.Len(t, b, 1)
 
 	found1, err := GetTier(b)
 	assert.NoError(t, err)
-	assert.Equal(t, tier, found1)
+	assert.Equal(t, tr, found1)
+	found1, err = RequireTier(b)
+	assert.NoError(t, err)
+	assert.Equal(t, tr, found1)
+
+	// queries can run without a tier but operators that need one fail
+	_, err = GetTier(Create(tier.Tier{}))
+	assert.NoError(t, err)
+	_, err = RequireTier(Create(tier.Tier{}))
+	assert.Error(t, err)
+}
+
+func Test_QueryVersion(t *testing.T) {
+	b := Create(tier.Tier{})
+	_, ok := GetQueryVersion(b)
+	assert.False(t, ok)
+
+	SetQueryVersion(b, QueryVersion{Name: "some_query", Version: 3})
+	found, ok := GetQueryVersion(b)
+	assert.True(t, ok)
+	assert.Equal(t, QueryVersion{Name: "some_query", Version: 3}, found)
+}
+
+func Test_Mocks(t *testing.T) {
+	b := Create(tier.Tier{})
+	_, ok := GetMocks(b)
+	assert.False(t, ok)
+
+	mocks := mock.Operators{"std.profile": []mock.Response{{Kwargs: value.NewDict(nil), Output: value.Int(1)}}}
+	SetMocks(b, mocks)
+	found, ok := GetMocks(b)
+	assert.True(t, ok)
+	assert.Equal(t, mocks, found)
+}
+
+func Test_Seed(t *testing.T) {
+	b := Create(tier.Tier{})
+	_, ok := GetSeed(b)
+	assert.False(t, ok)
+	assert.Nil(t, Rand(b))
+
+	SetSeed(b, 42)
+	seed, ok := GetSeed(b)
+	assert.True(t, ok)
+	assert.Equal(t, int64(42), seed)
+	// the source of an operator is only set for the operator
+	assert.Nil(t, Rand(b))
+
+	first, second := ForOperator(b, 42, 0), ForOperator(b, 42, 1)
+	assert.Len(t, b, 2)
+	seed, ok = GetSeed(first)
+	assert.True(t, ok)
+	assert.Equal(t, int64(42), seed)
+	assert.Equal(t, Rand(first).Int63(), Rand(ForOperator(b, 42, 0)).Int63())
+	assert.NotEqual(t, Rand(first).Int63(), Rand(second).Int63())
+	assert.NotEqual(t, Rand(first).Int63(), Rand(ForOperator(b, 43, 0)).Int63())
 }
@@ -2,6 +2,7 @@ package interpreter
 
 import (
 	"context"
+	"errors"
 	"fennel/lib/arena"
 	"fmt"
 	"strconv"
@@ -10,7 +11,10 @@ import (
 	"go.opentelemetry.io/otel/attribute"
 
 	"fennel/engine/ast"
+	"fennel/engine/functions"
+	"fennel/engine/interpreter/bootarg"
 	"fennel/engine/operators"
+	"fennel/lib/budget"
 	"fennel/lib/value"
 
 	"go.opentelemetry.io/otel"
@@ -21,6 +25,8 @@ type Interpreter struct {
 	env      *Env
 	bootargs map[string]interface{}
 	ctx      context.Context
+	// positions of the opcalls of the query, only set if the query is seeded
+	positions map[*ast.Dict]int
 }
 
 func NewInterpreter(ctx context.Context, bootargs map[string]interface{}, args value.Dict) (*Interpreter, error) {
@@ -112,6 +118,12 @@ func (i *Interpreter) VisitQuery(statements []*ast.Statement) (value.Value, erro
 	if len(statements) == 0 {
 		return value.Nil, fmt.Errorf("query can not be empty")
 	}
+	if _, ok := i.seed(); ok && i.positions == nil {
+		i.positions = opcallPositions(statements)
+	}
+	if g, ok := newStatementGraph(statements); ok {
+		return i.visitConcurrently(statements, g)
+	}
 	var exp value.Value
 	var err error
 	for _, statement := range statements {
@@ -211,7 +223,11 @@ func (i *Interpreter) VisitOpcall(operands []ast.Ast, vars []string, namespace,
 	if len(vars) > 0 && len(operands) != len(vars) {
 		return nil, fmt.Errorf("operator '%s.%s' can not be applied: different number of operands and variables", namespace, name)
 	}
-	cCtx, span := otel.Tracer("fennel").Start(i.ctx, fmt.Sprintf("%s.%s", namespace, name))
+	opname := fmt.Sprintf("%s.%s", namespace, name)
+	if err := i.interrupted(opname); err != nil {
+		return value.Nil, err
+	}
+	cCtx, span := otel.Tracer("fennel").Start(i.ctx, opname)
 	defer span.End()
 
 	// eval all operands
@@ -232,7 +248,7 @@ func (i *Interpreter) VisitOpcall(operands []ast.Ast, vars []string, namespace,
 	szOperands := voperands[0].Len()
 
 	// find & init the operator
-	op, err := i.getOperator(namespace, name)
+	op, err := i.getOperator(namespace, name, kwargs)
 	if err != nil {
 		return value.Nil, err
 	}
@@ -243,22 +259,55 @@ func (i *Interpreter) VisitOpcall(operands []ast.Ast, vars []string, namespace,
 	}
 	// and same for inputs + dynamic kwargs to create InputTable
 	// just pre-create space for all context kwargs
-	kwargVals := arena.Values.Alloc(0, szOperands*len(op.Signature().ContextKwargs))
+	tracker := bootarg.GetTracker(i.bootargs)
+	szKwargs := szOperands * len(op.Signature().ContextKwargs)
+	if err = tracker.Values(opname, szKwargs); err != nil {
+		return value.Nil, err
+	}
+	kwargVals := arena.Values.Alloc(0, szKwargs)
 	defer arena.Values.Free(kwargVals)
 	inputTable, err := i.getContextKwargs(op, kwargs, voperands, vars, kwargVals)
 	if err != nil {
 		return value.Nil, err
 	}
+	if err = i.interrupted(opname); err != nil {
+		return value.Nil, err
+	}
 	// finally, call the operator
 	// typing of input / context kwargs is verified element by element inside the iter
 	outtable := value.NewList()
 	outtable.Grow(inputTable.Len())
 	if err = op.Apply(cCtx, staticKwargs, inputTable.Iter(), &outtable); err != nil {
+		// operators that stop because the query ran out of time fail with the error of the context,
+		// which is replaced by one naming the operator
+		var exceeded budget.ExceededError
+		if ierr := i.interrupted(opname); ierr != nil && !errors.As(err, &exceeded) {
+			return value.Nil, ierr
+		}
+		return value.Nil, err
+	}
+	if err = tracker.Rows(opname, outtable.Len()); err != nil {
+		return value.Nil, err
+	}
+	if err = tracker.Values(opname, outtable.Len()); err != nil {
 		return value.Nil, err
 	}
 	return outtable, nil
 }
 
+// interrupted returns an error naming the operator if the query timed out or was cancelled
+func (i *Interpreter) interrupted(opname string) error {
+	err := i.ctx.Err()
+	if err == nil {
+		return nil
+	}
+	tracker := bootarg.GetTracker(i.bootargs)
+	if errors.Is(err, context.DeadlineExceeded) && tracker.Budget().TimeoutMs > 0 {
+		return tracker.Timeout(opname)
+	}
+	return fmt.Errorf("operator '%s' was interrupted: %w", opname, err)
+}
+
 func (i *Interpreter) VisitVar(name string) (value.Value, error) {
 	return i.env.Lookup(name)
 }
@@ -285,6 +334,21 @@ func (i *Interpreter) VisitIfelse(condition ast.Ast, thenDo ast.Ast, elseDo ast.
 	}
 }
 
+func (i *Interpreter) VisitCall(module, name string, args []ast.Ast) (value.Value, error) {
+	fn, err := functions.Locate(module, name)
+	if err != nil {
+		return value.Nil, err
+	}
+	vals := make([]value.Value, len(args))
+	for j, arg := range args {
+		vals[j], err = arg.AcceptValue(i)
+		if err != nil {
+			return value.Nil, err
+		}
+	}
+	return fn.Call(vals)
+}
+
 type RequiredKwargNotProvidedError struct {
 	ParamName string
 	OpModule  string
@@ -316,7 +380,7 @@ func (i *Interpreter) getStaticKwargs(op operators.Operator, kwargs *ast.Dict) (
 		case ok:
 			val, err := tree.AcceptValue(i)
 			if err != nil {
-				return operators.Kwargs{}, fmt.Errorf("error while evaluating kwarg '%s' for operator '%s.%s': %s", k, sig.Module, sig.Name, err)
+				return operators.Kwargs{}, fmt.Errorf("error while evaluating kwarg '%s' for operator '%s.%s': %w", k, sig.Module, sig.Name, err)
 			}
 			vals = append(vals, val)
 		}
@@ -343,7 +407,7 @@ func (i *Interpreter) getContextKwargs(op operators.Operator, trees *ast.Dict, i
 		for idx := range inputs {
 			val, err := inputs[idx].At(j)
 			if err != nil {
-				return operators.ZipTable{}, fmt.Errorf("error while evaluating operand %d for operator '%s.%s': %s", idx, sig.Module, sig.Name, err)
+				return operators.ZipTable{}, fmt.Errorf("error while evaluating operand %d for operator '%s.%s': %w", idx, sig.Module, sig.Name, err)
 			}
 			data[ptr] = val
 			ptr++
@@ -369,7 +433,7 @@ func (i *Interpreter) getContextKwargs(op operators.Operator, trees *ast.Dict, i
 				if done {
 					if err != nil {
 						return operators.ZipTable{}, fmt.Errorf(
-							"error while evaluating kwarg '%s' for operator '%s.%s': %s", k, sig.Module, sig.Name, err,
+							"error while evaluating kwarg '%s' for operator '%s.%s': %w", k, sig.Module, sig.Name, err,
 						)
 					}
 					kwargVals = append(kwargVals, val)
@@ -377,7 +441,7 @@ func (i *Interpreter) getContextKwargs(op operators.Operator, trees *ast.Dict, i
 					val, err := i.visitInContext(tree, vars, varvals)
 					if err != nil {
 						return operators.ZipTable{}, fmt.Errorf(
-							"error while evaluating kwarg '%s' for operator '%s.%s': %s", k, sig.Module, sig.Name, err,
+							"error while evaluating kwarg '%s' for operator '%s.%s': %w", k, sig.Module, sig.Name, err,
 						)
 					}
 					kwargVals = append(kwargVals, val)
@@ -420,12 +484,15 @@ func (i *Interpreter) fastKwargEval(tree ast.Ast, vars []string, varvals []value
 	return nil, false, nil
 }
 
-func (i *Interpreter) getOperator(namespace, name string) (operators.Operator, error) {
+func (i *Interpreter) getOperator(namespace, name string, kwargs *ast.Dict) (operators.Operator, error) {
 	op, err := operators.Locate(namespace, name)
 	if err != nil {
 		return op, err
 	}
-	ret, err := op.New(i.queryArgs(), i.bootargs)
+	if mocks, ok := bootarg.GetMocks(i.bootargs); ok {
+		op = mocks.Wrap(op)
+	}
+	ret, err := op.New(i.queryArgs(), i.operatorBootargs(kwargs))
 	return ret, err
 }
 
@@ -439,7 +506,7 @@ func (i *Interpreter) visitAll(trees []ast.Ast, ctx context.Context) ([]value.Va
 	var err error
 	if len(trees) == 1 {
 		// Create a new interpreter to pass the new context used in the trace
-		subtreeInterpreter := Interpreter{i.env, i.bootargs, cCtx}
+		subtreeInterpreter := Interpreter{i.env, i.bootargs, cCtx, i.positions}
 		vals[0], err = trees[0].AcceptValue(&subtreeInterpreter)
 	} else {
 		// Eval trees in parallel if more than 1.
@@ -451,7 +518,7 @@ func (i *Interpreter) visitAll(trees []ast.Ast, ctx context.Context) ([]value.Va
 				// same Env except the current one.
 				subtreeCtx, subtreeSpan := tracer.Start(cCtx, fmt.Sprintf("subtree_%d", idx))
 				defer subtreeSpan.End()
-				subtreeInterpreter := Interpreter{i.env, i.bootargs, subtreeCtx}
+				subtreeInterpreter := Interpreter{i.env, i.bootargs, subtreeCtx, i.positions}
 				var err error
 				vals[idx], err = trees[idx].AcceptValue(&subtreeInterpreter)
 				return err
@@ -317,6 +317,27 @@ func TestInterpreter_VisitIfelse(t *testing.T) {
 	testDualBranchEvaluation(t)
 }
 
+func TestInterpreter_VisitCall(t *testing.T) {
+	testValid(t, ast.MakeCall("str", "lower", ast.MakeString("HeLLo")), value.String("hello"))
+	// args can be arbitrary expressions, including nested calls
+	testValid(t, ast.MakeCall("str", "split",
+		ast.MakeCall("str", "upper", ast.MakeString("a/b")),
+		ast.MakeBinary("+", ast.MakeString(""), ast.MakeString("/")),
+	), value.NewList(value.String("A"), value.String("B")))
+	// optional args can be skipped
+	testValid(t, ast.MakeCall("time", "hour", ast.MakeInt(3600*5)), value.Int(5))
+
+	// unknown module or function
+	testError(t, ast.MakeCall("nope", "lower", ast.MakeString("x")))
+	testError(t, ast.MakeCall("str", "nope", ast.MakeString("x")))
+	// wrong type or number of args
+	testError(t, ast.MakeCall("str", "lower", ast.MakeInt(1)))
+	testError(t, ast.MakeCall("str", "lower"))
+	testError(t, ast.MakeCall("str", "lower", ast.MakeString("x"), ast.MakeString("y")))
+	// errors in evaluating args are propagated
+	testError(t, ast.MakeCall("str", "lower", ast.MakeVar("undefined")))
+}
+
 // Test that only one of the then/else branches is evaluated
 func testDualBranchEvaluation(t *testing.T) {
 	i := getInterpreter(nil, value.Dict{})
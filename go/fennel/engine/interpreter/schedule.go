@@ -0,0 +1,222 @@
+package interpreter
+
+import (
+	"fmt"
+	"sort"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+
+	"fennel/engine/ast"
+	"fennel/lib/utils/parallel"
+	"fennel/lib/value"
+)
+
+// STATEMENT_QUOTA is the quota of lib/utils/parallel that bounds the number of statements that are
+// run concurrently across all queries. A query runs one statement at a time without the quota, so
+// that it always makes progress, and takes a unit of the quota for every other statement it runs
+// at the same time. The quota is unbounded unless it is initialized with parallel.InitQuota.
+const STATEMENT_QUOTA = "query_statements"
+
+// maxConcurrentStatements bounds the number of statements of a single query that run concurrently
+const maxConcurrentStatements = 8
+
+// sideEffects are the operators that write data outside of the query, statements that call them
+// only run after every earlier statement succeeded, as they would when statements run in order
+var sideEffects = map[string]struct{}{
+	"feature.log": {},
+	"remote.http": {},
+}
+
+// statementGraph is the dependency graph of the statements of a query: a statement depends on the
+// earlier statements whose names it references, and statements with side effects depend on every
+// earlier statement
+type statementGraph struct {
+	deps       [][]int
+	dependents [][]int
+	// cost estimates how long a statement takes to run as the number of opcalls in it, statements
+	// without opcalls are cheap enough to not be worth running concurrently
+	cost []int
+	// priority is the cost of the most costly chain of statements that starts at the statement, so
+	// ready statements that hold up the most work are started first
+	priority []int
+}
+
+// newStatementGraph returns the dependency graph of the statements, the bool is false if the
+// statements should run in order: either because names are redefined, which only the sequential
+// run reports correctly, or because less than two statements are costly enough to overlap
+func newStatementGraph(statements []*ast.Statement) (statementGraph, bool) {
+	n := len(statements)
+	g := statementGraph{
+		deps:       make([][]int, n),
+		dependents: make([][]int, n),
+		cost:       make([]int, n),
+		priority:   make([]int, n),
+	}
+	defined := make(map[string]int, n)
+	costly := 0
+	for k, s := range statements {
+		refs := make(map[int]struct{})
+		sideEffect := false
+		inspect(s.Body, func(tree ast.Ast) {
+			switch t := tree.(type) {
+			case *ast.OpCall:
+				g.cost[k]++
+				if _, ok := sideEffects[t.Namespace+"."+t.Name]; ok {
+					sideEffect = true
+				}
+			case *ast.Var:
+				// lambda variables of opcalls that shadow statements add dependencies which are not
+				// needed, but never drop ones that are
+				if d, ok := defined[t.Name]; ok {
+					refs[d] = struct{}{}
+				}
+			}
+		})
+		if sideEffect {
+			for d := 0; d < k; d++ {
+				refs[d] = struct{}{}
+			}
+		}
+		for d := range refs {
+			g.deps[k] = append(g.deps[k], d)
+			g.dependents[d] = append(g.dependents[d], k)
+		}
+		sort.Ints(g.deps[k])
+		if g.cost[k] > 0 {
+			costly++
+		}
+		if len(s.Name) > 0 {
+			if _, ok := defined[s.Name]; ok {
+				return statementGraph{}, false
+			}
+			defined[s.Name] = k
+		}
+	}
+	if costly < 2 {
+		return statementGraph{}, false
+	}
+	// dependents come after the statements they depend on, so priorities are computed backwards
+	for k := n - 1; k >= 0; k-- {
+		next := 0
+		for _, d := range g.dependents[k] {
+			if g.priority[d] > next {
+				next = g.priority[d]
+			}
+		}
+		g.priority[k] = g.cost[k] + next
+	}
+	return g, true
+}
+
+// visitConcurrently runs the statements as soon as the statements they depend on are done, with
+// the most costly ones first. Errors are the same as those of running the statements in order: if
+// statements fail, the error of the first one is returned and statements after it that have not
+// started yet are not run, so statements with side effects never run after a failure.
+func (i *Interpreter) visitConcurrently(statements []*ast.Statement, g statementGraph) (value.Value, error) {
+	tracer := otel.Tracer("fennel")
+	cCtx, span := tracer.Start(i.ctx, "statementsVisit")
+	defer span.End()
+	span.SetAttributes(attribute.Int("numStatements", len(statements)))
+
+	n := len(statements)
+	vals := make([]value.Value, n)
+	errs := make([]error, n)
+	pending := make([]int, n)
+	var ready []int
+	for k := range statements {
+		pending[k] = len(g.deps[k])
+		if pending[k] == 0 {
+			ready = append(ready, k)
+		}
+	}
+	// failed is the first statement that failed, or n if none has
+	failed := n
+	complete := func(k int) {
+		if errs[k] != nil {
+			if k < failed {
+				failed = k
+			}
+			return
+		}
+		for _, d := range g.dependents[k] {
+			if pending[d]--; pending[d] == 0 {
+				ready = append(ready, d)
+			}
+		}
+	}
+	run := func(k int, env *Env) {
+		sCtx, sSpan := tracer.Start(cCtx, fmt.Sprintf("statement_%d", k))
+		defer sSpan.End()
+		sSpan.SetAttributes(attribute.String("name", statements[k].Name), attribute.Int("cost", g.cost[k]))
+		sub := Interpreter{env, i.bootargs, sCtx, i.positions}
+		vals[k], errs[k] = statements[k].AcceptValue(&sub)
+		_, _ = env.PopEnv()
+	}
+
+	done := make(chan int, n)
+	running := 0
+	for len(ready) > 0 || running > 0 {
+		sort.SliceStable(ready, func(a, b int) bool {
+			return g.priority[ready[a]] > g.priority[ready[b]]
+		})
+		for len(ready) > 0 && running < maxConcurrentStatements {
+			k := ready[0]
+			ready = ready[1:]
+			if k > failed {
+				continue
+			}
+			// every statement sees the values of the statements it depends on, which are done and
+			// are not written again, and only reads the environment of the query otherwise
+			env := NewEnv(i.env)
+			for _, d := range g.deps[k] {
+				// statements with side effects also depend on unnamed statements
+				if len(statements[d].Name) == 0 {
+					continue
+				}
+				if err := env.Define(statements[d].Name, vals[d]); err != nil {
+					return value.Nil, err
+				}
+			}
+			if g.cost[k] == 0 {
+				run(k, env)
+				complete(k)
+				continue
+			}
+			quota := running > 0
+			if quota {
+				if err := parallel.Acquire(cCtx, STATEMENT_QUOTA, 1); err != nil {
+					// the query was cancelled, wait for the running statements to stop
+					errs[k] = err
+					complete(k)
+					_, _ = env.PopEnv()
+					break
+				}
+			}
+			running++
+			go func(k int, env *Env, quota bool) {
+				if quota {
+					defer parallel.Release(STATEMENT_QUOTA, 1)
+				}
+				run(k, env)
+				done <- k
+			}(k, env, quota)
+		}
+		if running > 0 {
+			k := <-done
+			running--
+			complete(k)
+		}
+	}
+	if failed < n {
+		return value.Nil, errs[failed]
+	}
+	for k, s := range statements {
+		if len(s.Name) > 0 {
+			if err := i.env.Define(s.Name, vals[k]); err != nil {
+				return value.Nil, err
+			}
+		}
+	}
+	return vals[n-1], nil
+}
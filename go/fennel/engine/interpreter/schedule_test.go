@@ -0,0 +1,215 @@
+package interpreter
+
+import (
+	"context"
+	"fmt"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"fennel/engine/ast"
+	"fennel/engine/operators"
+	"fennel/lib/value"
+)
+
+func init() {
+	if err := operators.Register(sleeper{}); err != nil {
+		panic(err)
+	}
+	if err := operators.Register(logger{}); err != nil {
+		panic(err)
+	}
+}
+
+var logged int64
+
+// logger stands in for feature.log, which is not linked in these tests, and counts its rows
+type logger struct{}
+
+func (l logger) New(args value.Dict, bootargs map[string]interface{}) (operators.Operator, error) {
+	return l, nil
+}
+
+func (l logger) Signature() *operators.Signature {
+	return operators.NewSignature("feature", "log")
+}
+
+func (l logger) Apply(ctx context.Context, kwargs operators.Kwargs, in operators.InputIter, out *value.List) error {
+	for in.HasMore() {
+		heads, _, err := in.Next()
+		if err != nil {
+			return err
+		}
+		atomic.AddInt64(&logged, 1)
+		out.Append(heads[0])
+	}
+	return nil
+}
+
+var sleepersRunning, maxSleepersRunning int64
+
+// sleeper passes its rows through after sleeping, or fails with the given error
+type sleeper struct{}
+
+func (s sleeper) New(args value.Dict, bootargs map[string]interface{}) (operators.Operator, error) {
+	return s, nil
+}
+
+func (s sleeper) Signature() *operators.Signature {
+	return operators.NewSignature("test", "sleep").
+		Param("ms", value.Types.Int, true, false, value.Nil).
+		Param("fail", value.Types.String, true, true, value.String(""))
+}
+
+func (s sleeper) Apply(ctx context.Context, kwargs operators.Kwargs, in operators.InputIter, out *value.List) error {
+	running := atomic.AddInt64(&sleepersRunning, 1)
+	defer atomic.AddInt64(&sleepersRunning, -1)
+	for max := atomic.LoadInt64(&maxSleepersRunning); running > max; max = atomic.LoadInt64(&maxSleepersRunning) {
+		if atomic.CompareAndSwapInt64(&maxSleepersRunning, max, running) {
+			break
+		}
+	}
+	select {
+	case <-time.After(time.Duration(kwargs.GetUnsafe("ms").(value.Int)) * time.Millisecond):
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+	if fail := kwargs.GetUnsafe("fail").(value.String); len(fail) > 0 {
+		return fmt.Errorf("%s", string(fail))
+	}
+	for in.HasMore() {
+		heads, _, err := in.Next()
+		if err != nil {
+			return err
+		}
+		out.Append(heads[0])
+	}
+	return nil
+}
+
+func sleep(operand ast.Ast, ms int, fail string) *ast.OpCall {
+	return &ast.OpCall{
+		Namespace: "test",
+		Name:      "sleep",
+		Operands:  []ast.Ast{operand},
+		Kwargs:    ast.MakeDict(map[string]ast.Ast{"ms": ast.MakeInt(int32(ms)), "fail": ast.MakeString(fail)}),
+	}
+}
+
+func TestStatementGraph(t *testing.T) {
+	list := ast.MakeList(ast.MakeInt(1))
+	statements := []*ast.Statement{
+		ast.MakeStatement("a", sleep(list, 1, "")),
+		ast.MakeStatement("b", sleep(sleep(list, 1, ""), 1, "")),
+		ast.MakeStatement("c", sleep(ast.MakeVar("a"), 1, "")),
+		ast.MakeStatement("", ast.MakeList(ast.MakeVar("b"), ast.MakeVar("c"), ast.MakeVar("x"))),
+	}
+	g, ok := newStatementGraph(statements)
+	require.True(t, ok)
+	assert.Equal(t, [][]int{nil, nil, {0}, {1, 2}}, g.deps)
+	assert.Equal(t, [][]int{{2}, {3}, {3}, nil}, g.dependents)
+	assert.Equal(t, []int{1, 2, 1, 0}, g.cost)
+	assert.Equal(t, []int{2, 2, 1, 0}, g.priority)
+
+	// statements with side effects depend on every earlier statement
+	g, ok = newStatementGraph(append(statements[:3:3], ast.MakeStatement("", &ast.OpCall{
+		Namespace: "feature",
+		Name:      "log",
+		Operands:  []ast.Ast{list},
+		Kwargs:    ast.MakeDict(nil),
+	})))
+	require.True(t, ok)
+	assert.Equal(t, []int{0, 1, 2}, g.deps[3])
+
+	// statements run in order if names are redefined or only one statement is costly
+	_, ok = newStatementGraph([]*ast.Statement{statements[0], statements[1], ast.MakeStatement("a", list)})
+	assert.False(t, ok)
+	_, ok = newStatementGraph([]*ast.Statement{statements[0], ast.MakeStatement("d", list), statements[3]})
+	assert.False(t, ok)
+}
+
+func TestInterpreter_VisitQuery_Concurrent(t *testing.T) {
+	atomic.StoreInt64(&maxSleepersRunning, 0)
+	query := ast.MakeQuery([]*ast.Statement{
+		ast.MakeStatement("a", sleep(ast.MakeVar("x"), 50, "")),
+		ast.MakeStatement("b", sleep(ast.MakeList(ast.MakeInt(2)), 50, "")),
+		ast.MakeStatement("c", sleep(ast.MakeList(ast.MakeInt(3)), 50, "")),
+		ast.MakeStatement("d", sleep(ast.MakeVar("a"), 1, "")),
+		ast.MakeStatement("", ast.MakeList(ast.MakeVar("d"), ast.MakeVar("b"), ast.MakeVar("c"))),
+	})
+	i := getInterpreter(nil, value.NewDict(map[string]value.Value{"x": value.NewList(value.Int(1))}))
+	out, err := query.AcceptValue(i)
+	require.NoError(t, err)
+	expected := value.NewList(value.NewList(value.Int(1)), value.NewList(value.Int(2)), value.NewList(value.Int(3)))
+	assert.True(t, expected.Equal(out), out)
+	// the first three statements are independent and run at the same time
+	assert.Equal(t, int64(3), atomic.LoadInt64(&maxSleepersRunning))
+	// and statements are defined after the query as if they were run in order
+	d, err := i.env.Lookup("d")
+	assert.NoError(t, err)
+	assert.True(t, value.NewList(value.Int(1)).Equal(d))
+}
+
+func TestInterpreter_VisitQuery_ConcurrentErrors(t *testing.T) {
+	list := ast.MakeList(ast.MakeInt(1))
+	// the error of the first statement that fails is returned even if a later one fails first
+	query := ast.MakeQuery([]*ast.Statement{
+		ast.MakeStatement("a", sleep(list, 50, "first")),
+		ast.MakeStatement("b", sleep(list, 1, "second")),
+		ast.MakeStatement("", sleep(ast.MakeVar("b"), 1, "")),
+	})
+	_, err := query.AcceptValue(getInterpreter(nil, value.NewDict(nil)))
+	assert.EqualError(t, err, "first")
+
+	// statements can only see the statements before them
+	query = ast.MakeQuery([]*ast.Statement{
+		ast.MakeStatement("a", sleep(ast.MakeVar("b"), 1, "")),
+		ast.MakeStatement("b", sleep(list, 1, "")),
+	})
+	_, err = query.AcceptValue(getInterpreter(nil, value.NewDict(nil)))
+	assert.EqualError(t, err, "undefined variable: 'b'")
+	query = ast.MakeQuery([]*ast.Statement{
+		ast.MakeStatement("a", sleep(ast.MakeVar("b"), 1, "")),
+		ast.MakeStatement("b", sleep(list, 1, "")),
+	})
+	out, err := query.AcceptValue(getInterpreter(nil, value.NewDict(map[string]value.Value{"b": value.NewList(value.Int(2))})))
+	assert.NoError(t, err)
+	assert.True(t, value.NewList(value.Int(1)).Equal(out))
+
+	// reserved names fail the same as when statements run in order
+	query = ast.MakeQuery([]*ast.Statement{
+		ast.MakeStatement("__a__", sleep(list, 1, "")),
+		ast.MakeStatement("b", sleep(list, 1, "")),
+	})
+	_, err = query.AcceptValue(getInterpreter(nil, value.NewDict(nil)))
+	assert.Error(t, err)
+}
+
+func TestInterpreter_VisitQuery_SideEffectsAfterErrors(t *testing.T) {
+	atomic.StoreInt64(&logged, 0)
+	list := ast.MakeList(ast.MakeInt(1))
+	// the log does not use the failing statement but is not run since it fails
+	query := ast.MakeQuery([]*ast.Statement{
+		ast.MakeStatement("a", sleep(list, 50, "failed")),
+		ast.MakeStatement("b", sleep(list, 1, "")),
+		ast.MakeStatement("", &ast.OpCall{
+			Namespace: "feature",
+			Name:      "log",
+			Operands:  []ast.Ast{ast.MakeVar("b")},
+			Kwargs:    ast.MakeDict(nil),
+		}),
+	})
+	_, err := query.AcceptValue(getInterpreter(nil, value.NewDict(nil)))
+	assert.EqualError(t, err, "failed")
+	assert.Equal(t, int64(0), atomic.LoadInt64(&logged))
+
+	// and is run once every statement before it succeeded
+	query.Statements[0] = ast.MakeStatement("a", sleep(list, 1, ""))
+	out, err := query.AcceptValue(getInterpreter(nil, value.NewDict(nil)))
+	require.NoError(t, err)
+	assert.True(t, value.NewList(value.Int(1)).Equal(out), out)
+	assert.Equal(t, int64(1), atomic.LoadInt64(&logged))
+}
@@ -0,0 +1,99 @@
+package interpreter
+
+import (
+	"sort"
+
+	"fennel/engine/ast"
+	"fennel/engine/interpreter/bootarg"
+	"fennel/lib/value"
+)
+
+// seed returns the seed of the query, which is set either in the bootargs or in the query args
+func (i *Interpreter) seed() (int64, bool) {
+	if seed, ok := bootarg.GetSeed(i.bootargs); ok {
+		return seed, true
+	}
+	if seed, ok := i.queryArgs().GetUnsafe(bootarg.SEED_ARG).(value.Int); ok {
+		return int64(seed), true
+	}
+	return 0, false
+}
+
+// operatorBootargs returns the bootargs of the operator of the opcall with the given kwargs. If the
+// query is seeded, these carry a seed derived from the position of the opcall in the query, so the
+// randomness an operator draws does not depend on the order opcalls are evaluated in.
+func (i *Interpreter) operatorBootargs(kwargs *ast.Dict) map[string]interface{} {
+	seed, ok := i.seed()
+	if !ok {
+		return i.bootargs
+	}
+	// opcalls are identified by their kwargs since VisitOpcall is not passed the opcall itself
+	position, ok := i.positions[kwargs]
+	if !ok {
+		// the query is not evaluated from the root, e.g. in tests of operators
+		position = -1
+	}
+	return bootarg.ForOperator(i.bootargs, seed, position)
+}
+
+// opcallPositions numbers the opcalls of the query in depth first order
+func opcallPositions(statements []*ast.Statement) map[*ast.Dict]int {
+	positions := make(map[*ast.Dict]int)
+	for _, s := range statements {
+		inspect(s, func(tree ast.Ast) {
+			if opcall, ok := tree.(*ast.OpCall); ok {
+				positions[opcall.Kwargs] = len(positions)
+			}
+		})
+	}
+	return positions
+}
+
+// inspect calls f on every node of the tree in depth first order, parents before their children.
+// Values of dicts are visited in the order of their keys, so that the order is the same every time.
+func inspect(tree ast.Ast, f func(ast.Ast)) {
+	f(tree)
+	switch t := tree.(type) {
+	case *ast.Statement:
+		inspect(t.Body, f)
+	case *ast.Query:
+		for _, s := range t.Statements {
+			inspect(s, f)
+		}
+	case *ast.OpCall:
+		for _, operand := range t.Operands {
+			inspect(operand, f)
+		}
+		if t.Kwargs != nil {
+			inspect(t.Kwargs, f)
+		}
+	case *ast.List:
+		for _, v := range t.Values {
+			inspect(v, f)
+		}
+	case *ast.Dict:
+		keys := make([]string, 0, len(t.Values))
+		for k := range t.Values {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		for _, k := range keys {
+			inspect(t.Values[k], f)
+		}
+	case *ast.Unary:
+		inspect(t.Operand, f)
+	case *ast.Binary:
+		inspect(t.Left, f)
+		inspect(t.Right, f)
+	case *ast.Lookup:
+		inspect(t.On, f)
+	case *ast.IfElse:
+		inspect(t.Condition, f)
+		inspect(t.ThenDo, f)
+		inspect(t.ElseDo, f)
+	case *ast.Call:
+		for _, arg := range t.Args {
+			inspect(arg, f)
+		}
+	}
+}
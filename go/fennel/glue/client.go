@@ -5,20 +5,15 @@ import (
 	"fennel/lib/aggregate"
 	"fennel/lib/ftypes"
 	hp "fennel/lib/hyperparam"
+	"fennel/lib/offline"
 	"fmt"
 	"github.com/aws/aws-sdk-go/aws"
 	"github.com/aws/aws-sdk-go/aws/session"
 	"github.com/aws/aws-sdk-go/service/glue"
-	"reflect"
 	"strings"
 )
 
-var supportedHyperParameters = hp.HyperParamRegistry{
-	"cf": map[string]hp.HyperParameterInfo{
-		"min_co_occurence":          {Default: 3, Type: reflect.Int, Options: []string{}},
-		"object_normalization_func": {Default: "sqrt", Type: reflect.String, Options: []string{"none", "log", "sqrt", "identity"}},
-	},
-}
+var supportedHyperParameters = offline.SupportedHyperParameters
 
 type GlueArgs struct {
 	Region string `arg:"--region,env:AWS_REGION,help:AWS region"`
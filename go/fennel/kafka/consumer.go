@@ -241,6 +241,10 @@ type ConsumerConfig struct {
 	Topic        string
 	RebalanceCb  mo.Option[func(c *kafka.Consumer, e kafka.Event) error]
 	Configs      ConsumerConfigs
+	// AssignPartitions assigns every partition of the topic to the consumer, starting at the offsets
+	// committed by the group, instead of subscribing to the topic. Consumers of the group then don't
+	// split the partitions between them and each of them reads all messages.
+	AssignPartitions bool
 }
 
 type RemoteConsumerConfig struct {
@@ -326,6 +330,23 @@ func (conf RemoteConsumerConfig) Materialize() (resource.Resource, error) {
 			return nil
 		}
 	}
+	if conf.AssignPartitions {
+		rc := RemoteConsumer{consumer, conf.Scope, topic, conf.GroupID, nil}
+		toppars, err := rc.GetPartitions()
+		if err != nil {
+			_ = consumer.Close()
+			return nil, err
+		}
+		for i := range toppars {
+			// start at the committed offset, or as per auto.offset.reset if there is none
+			toppars[i].Offset = kafka.OffsetStored
+		}
+		if err = consumer.Assign(toppars); err != nil {
+			_ = consumer.Close()
+			return nil, fmt.Errorf("failed to assign partitions of topic [%s]: %v", topic, err)
+		}
+		return rc, nil
+	}
 	if err = consumer.Subscribe(topic, rebalanceCb); err != nil {
 		return nil, fmt.Errorf("failed to subscripe to topic [%s]: %v", topic, err)
 	}
@@ -86,6 +86,7 @@ var ALL_TOPICS = []TopicConf{
 		},
 	},
 	{Scope: resource.TierScope{}, Topic: profile.PROFILELOG_KAFKA_TOPIC},
+	{Scope: resource.TierScope{}, Topic: profile.PROFILE_CDC_KAFKA_TOPIC},
 	{Scope: resource.TierScope{}, Topic: counter.AGGREGATE_OFFLINE_TRANSFORM_TOPIC_NAME},
 	{Scope: resource.TierScope{}, Topic: usage.HOURLY_USAGE_LOG_KAFKA_TOPIC},
 	{Scope: resource.TierScope{}, Topic: airbyte.AIRBYTE_KAFKA_TOPIC},
@@ -37,10 +37,16 @@ func NewMockTopicBroker() MockBroker {
 	}
 }
 
-func (l *MockBroker) InitConsumer(groupID string) {
+// InitConsumer starts the consumers of the group at the first message, or after the last commit
+// of the group if resume is set
+func (l *MockBroker) InitConsumer(groupID string, resume bool) {
 	l.mutex.Lock()
 	defer l.mutex.Unlock()
-	l.nexts[groupID] = 0
+	if offset, ok := l.commits[groupID]; resume && ok {
+		l.nexts[groupID] = offset + 1
+	} else {
+		l.nexts[groupID] = 0
+	}
 }
 
 func (l *MockBroker) Log(msg []byte) {
@@ -209,11 +215,14 @@ type MockConsumerConfig struct {
 	Topic   string
 	GroupID string
 	Scope   resource.Scope
+	// AssignPartitions resumes the consumer after the last commit of its group, as consumers with
+	// the partitions assigned do
+	AssignPartitions bool
 }
 
 func (l MockConsumerConfig) Materialize() (resource.Resource, error) {
 	topic := l.Scope.PrefixedName(l.Topic)
-	l.Broker.InitConsumer(l.GroupID)
+	l.Broker.InitConsumer(l.GroupID, l.AssignPartitions)
 	return mockConsumer{l.Scope, l.GroupID, topic, l.Broker}, nil
 }
 
@@ -248,6 +248,9 @@ func FromValueDict(dict value.Dict) (Action, error) {
 				return action, fmt.Errorf("timestamp must be an integer or RFC3339 formatted string: %w", err)
 			}
 			action.Timestamp = ftypes.Timestamp(timeParsed.Unix())
+		} else if ts, ok := timestamp.(value.Time); ok {
+			// times are unambiguous, unlike integers whose unit has to be guessed
+			action.Timestamp = ftypes.Timestamp(ts.Time().Unix())
 		} else {
 			return action, fmt.Errorf("action timestamp must be an integer")
 		}
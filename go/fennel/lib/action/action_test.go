@@ -260,6 +260,28 @@ func TestActionTimestampResolved(t *testing.T) {
 			Timestamp:  ftypes.Timestamp(ts.Unix()),
 			RequestID:  "10",
 			Metadata:   value.Int(8),
+		},
+	}, {
+		v: value.NewDict(map[string]value.Value{
+			"action_id":   value.Int(1),
+			"actor_id":    value.String("aditya"),
+			"actor_type":  value.String("user"),
+			"target_id":   value.String("f9rp2"),
+			"target_type": value.String("video"),
+			"action_type": value.String("like5"),
+			"timestamp":   value.NewTime(ts.In(time.FixedZone("", 19800))),
+			"request_id":  value.Int(10),
+			"metadata":    value.Int(8),
+		}), a: Action{
+			ActionID:   1,
+			ActorID:    `"aditya"`,
+			ActorType:  "user",
+			TargetID:   `"f9rp2"`,
+			TargetType: "video",
+			ActionType: "like5",
+			Timestamp:  ftypes.Timestamp(ts.Unix()),
+			RequestID:  "10",
+			Metadata:   value.Int(8),
 		}},
 	}
 	for _, test := range tests {
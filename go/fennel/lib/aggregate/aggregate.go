@@ -56,6 +56,9 @@ const (
 	SOURCE_PROFILE = ftypes.Source("profile")
 )
 
+// DEFS_CACHE_NAMESPACE is the namespace of invalidations of the aggregate definitions cached by each tier
+const DEFS_CACHE_NAMESPACE = "AggregateDefs"
+
 var ErrNotFound = errors.New("aggregate not found")
 var ErrNotActive = errors.New("aggregate is not active")
 
@@ -0,0 +1,127 @@
+package apikey
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"fmt"
+	"strings"
+	"time"
+)
+
+/*
+	API keys are issued for a tier by the mothership and look like "fnl_<id>_<secret>". The id is
+	public and used to look up the key, only the sha256 hash of the full key is stored (both in the
+	mothership and in the tier) so a key can't be recovered once it is issued.
+*/
+
+type ApiKeyArgs struct {
+	// DisableAuth lets requests through without an api key, it is only meant for local tiers & tests
+	DisableAuth bool `arg:"--disable-auth,env:DISABLE_AUTH" json:"disable_auth,omitempty"`
+}
+
+// MothershipKeySecret is the name of the secret that keeps the admin key the mothership uses to
+// call the data plane of the tier. The mothership creates it before it syncs the keys of the tier
+// for the first time and the data plane always accepts it, so the first sync is authenticated too.
+func MothershipKeySecret(tierID uint64) string {
+	return fmt.Sprintf("t_%d/mothership_api_key", tierID)
+}
+
+type Scope string
+
+const (
+	// SCOPE_LOG allows logging actions & setting profiles
+	SCOPE_LOG Scope = "log"
+	// SCOPE_QUERY allows running queries and reading profiles, actions & aggregate values
+	SCOPE_QUERY Scope = "query"
+	// SCOPE_ADMIN allows everything, including changing aggregates, queries, models & connectors
+	SCOPE_ADMIN Scope = "admin"
+)
+
+var Scopes = []Scope{SCOPE_LOG, SCOPE_QUERY, SCOPE_ADMIN}
+
+const (
+	prefix    = "fnl"
+	idLen     = 6
+	secretLen = 24
+)
+
+// Key is an API key as known to the data plane
+type Key struct {
+	ID     string `db:"key_id" json:"id"`
+	Hash   string `db:"hash" json:"hash"`
+	Scopes string `db:"scopes" json:"scopes"`
+	// ExpiresAt is the unix time (in seconds) after which the key is no longer valid, zero if it never expires
+	ExpiresAt int64 `db:"expires_at" json:"expires_at"`
+}
+
+// Generate returns a new key in plain text along with its id & hash
+func Generate() (key string, id string, hash string, err error) {
+	b := make([]byte, idLen+secretLen)
+	if _, err = rand.Read(b); err != nil {
+		return "", "", "", err
+	}
+	id = hex.EncodeToString(b[:idLen])
+	key = fmt.Sprintf("%s_%s_%s", prefix, id, hex.EncodeToString(b[idLen:]))
+	return key, id, Hash(key), nil
+}
+
+// ParseID returns the id of the key in plain text
+func ParseID(key string) (string, error) {
+	parts := strings.Split(key, "_")
+	if len(parts) != 3 || parts[0] != prefix || len(parts[1]) != 2*idLen || len(parts[2]) != 2*secretLen {
+		return "", fmt.Errorf("malformed api key")
+	}
+	return parts[1], nil
+}
+
+func Hash(key string) string {
+	h := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(h[:])
+}
+
+// ParseScopes parses comma separated scopes
+func ParseScopes(scopes string) ([]Scope, error) {
+	var ret []Scope
+	for _, s := range strings.Split(scopes, ",") {
+		s = strings.TrimSpace(s)
+		if len(s) == 0 {
+			continue
+		}
+		valid := false
+		for _, scope := range Scopes {
+			if Scope(s) == scope {
+				valid = true
+			}
+		}
+		if !valid {
+			return nil, fmt.Errorf("invalid scope: '%s', should be one of %v", s, Scopes)
+		}
+		ret = append(ret, Scope(s))
+	}
+	if len(ret) == 0 {
+		return nil, fmt.Errorf("at least one scope is required")
+	}
+	return ret, nil
+}
+
+// Matches returns true if the key in plain text is this key
+func (k Key) Matches(key string) bool {
+	return subtle.ConstantTimeCompare([]byte(Hash(key)), []byte(k.Hash)) == 1
+}
+
+// Allows returns true if the key grants the scope, admin keys grant every scope
+func (k Key) Allows(scope Scope) bool {
+	for _, s := range strings.Split(k.Scopes, ",") {
+		s = strings.TrimSpace(s)
+		if Scope(s) == scope || Scope(s) == SCOPE_ADMIN {
+			return true
+		}
+	}
+	return false
+}
+
+func (k Key) Expired(now time.Time) bool {
+	return k.ExpiresAt != 0 && now.Unix() >= k.ExpiresAt
+}
@@ -0,0 +1,58 @@
+package apikey
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGenerate(t *testing.T) {
+	key, id, hash, err := Generate()
+	require.NoError(t, err)
+	parsed, err := ParseID(key)
+	require.NoError(t, err)
+	assert.Equal(t, id, parsed)
+	assert.Equal(t, Hash(key), hash)
+
+	other, otherID, _, err := Generate()
+	require.NoError(t, err)
+	assert.NotEqual(t, key, other)
+	assert.NotEqual(t, id, otherID)
+
+	k := Key{ID: id, Hash: hash, Scopes: "log"}
+	assert.True(t, k.Matches(key))
+	assert.False(t, k.Matches(other))
+
+	for _, malformed := range []string{"", "fnl", "abc_" + id + "_" + key[len(key)-48:], "fnl_" + id + "_abc", key + "_x"} {
+		_, err = ParseID(malformed)
+		assert.Error(t, err, malformed)
+	}
+}
+
+func TestScopes(t *testing.T) {
+	scopes, err := ParseScopes("log, query")
+	require.NoError(t, err)
+	assert.Equal(t, []Scope{SCOPE_LOG, SCOPE_QUERY}, scopes)
+	_, err = ParseScopes("")
+	assert.Error(t, err)
+	_, err = ParseScopes("log,write")
+	assert.Error(t, err)
+
+	k := Key{Scopes: "log,query"}
+	assert.True(t, k.Allows(SCOPE_LOG))
+	assert.True(t, k.Allows(SCOPE_QUERY))
+	assert.False(t, k.Allows(SCOPE_ADMIN))
+	k.Scopes = "admin"
+	for _, s := range Scopes {
+		assert.True(t, k.Allows(s))
+	}
+}
+
+func TestExpired(t *testing.T) {
+	now := time.Unix(1000, 0)
+	assert.False(t, Key{}.Expired(now))
+	assert.False(t, Key{ExpiresAt: 1001}.Expired(now))
+	assert.True(t, Key{ExpiresAt: 1000}.Expired(now))
+}
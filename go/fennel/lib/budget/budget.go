@@ -0,0 +1,153 @@
+package budget
+
+import (
+	"fmt"
+	"sync/atomic"
+	"time"
+)
+
+/*
+	Budget bounds the resources a run of a query can use, so that a query that explodes the number
+	of rows or calls remote services in a loop fails fast instead of taking the server down with it.
+	Every tier has a default budget, which stored queries can override.
+*/
+
+// BudgetArgs are the default budget of the queries of a tier, zero means unbounded
+type BudgetArgs struct {
+	QueryMaxRows        int64         `arg:"--query-max-rows,env:QUERY_MAX_ROWS" default:"0" json:"query_max_rows,omitempty"`
+	QueryMaxValues      int64         `arg:"--query-max-values,env:QUERY_MAX_VALUES" default:"0" json:"query_max_values,omitempty"`
+	QueryMaxRemoteCalls int64         `arg:"--query-max-remote-calls,env:QUERY_MAX_REMOTE_CALLS" default:"0" json:"query_max_remote_calls,omitempty"`
+	QueryTimeout        time.Duration `arg:"--query-timeout,env:QUERY_TIMEOUT" default:"0s" json:"query_timeout,omitempty"`
+}
+
+// Default returns the budget queries of the tier are run with unless they override it
+func (args BudgetArgs) Default() Budget {
+	return Budget{
+		MaxRows:        args.QueryMaxRows,
+		MaxValues:      args.QueryMaxValues,
+		MaxRemoteCalls: args.QueryMaxRemoteCalls,
+		TimeoutMs:      args.QueryTimeout.Milliseconds(),
+	}
+}
+
+const (
+	ROWS         = "rows"
+	VALUES       = "values"
+	REMOTE_CALLS = "remote_calls"
+	TIMEOUT_MS   = "timeout_ms"
+)
+
+// Budget is the limit of each resource, zero means unbounded
+type Budget struct {
+	// MaxRows is the maximum number of rows in the output of any operator
+	MaxRows int64 `json:"max_rows,omitempty"`
+	// MaxValues is the maximum number of values allocated by the interpreter and operators over the
+	// whole query
+	MaxValues int64 `json:"max_values,omitempty"`
+	// MaxRemoteCalls is the maximum number of calls made to remote services over the whole query
+	MaxRemoteCalls int64 `json:"max_remote_calls,omitempty"`
+	// TimeoutMs is the maximum time the query can run for
+	TimeoutMs int64 `json:"timeout_ms,omitempty"`
+}
+
+func (b Budget) Validate() error {
+	if b.MaxRows < 0 || b.MaxValues < 0 || b.MaxRemoteCalls < 0 || b.TimeoutMs < 0 {
+		return fmt.Errorf("budget limits can not be negative")
+	}
+	return nil
+}
+
+// Override returns the budget with the limits that are set in other replaced by those of other
+func (b Budget) Override(other Budget) Budget {
+	if other.MaxRows > 0 {
+		b.MaxRows = other.MaxRows
+	}
+	if other.MaxValues > 0 {
+		b.MaxValues = other.MaxValues
+	}
+	if other.MaxRemoteCalls > 0 {
+		b.MaxRemoteCalls = other.MaxRemoteCalls
+	}
+	if other.TimeoutMs > 0 {
+		b.TimeoutMs = other.TimeoutMs
+	}
+	return b
+}
+
+func (b Budget) Timeout() time.Duration {
+	return time.Duration(b.TimeoutMs) * time.Millisecond
+}
+
+// ExceededError is returned when an operator exceeds the budget of the query
+type ExceededError struct {
+	// Operator is the operator that exceeded the budget, e.g. "std.explode"
+	Operator string
+	// Resource is one of ROWS, VALUES, REMOTE_CALLS or TIMEOUT_MS
+	Resource string
+	Limit    int64
+	Used     int64
+}
+
+func (e ExceededError) Error() string {
+	return fmt.Sprintf("operator '%s' exceeded the query budget of %d %s, used: %d", e.Operator, e.Limit, e.Resource, e.Used)
+}
+
+// Tracker tracks the resources used by a run of a query against its budget. It is safe to use
+// concurrently and a nil Tracker tracks nothing.
+type Tracker struct {
+	budget      Budget
+	start       time.Time
+	values      int64
+	remoteCalls int64
+}
+
+func NewTracker(b Budget) *Tracker {
+	return &Tracker{budget: b, start: time.Now()}
+}
+
+func (t *Tracker) Budget() Budget {
+	if t == nil {
+		return Budget{}
+	}
+	return t.budget
+}
+
+// Rows checks the number of rows in the output of the operator
+func (t *Tracker) Rows(op string, n int) error {
+	if t == nil || t.budget.MaxRows == 0 || int64(n) <= t.budget.MaxRows {
+		return nil
+	}
+	return ExceededError{Operator: op, Resource: ROWS, Limit: t.budget.MaxRows, Used: int64(n)}
+}
+
+// Values records that the operator allocated n values
+func (t *Tracker) Values(op string, n int) error {
+	if t == nil {
+		return nil
+	}
+	used := atomic.AddInt64(&t.values, int64(n))
+	if t.budget.MaxValues == 0 || used <= t.budget.MaxValues {
+		return nil
+	}
+	return ExceededError{Operator: op, Resource: VALUES, Limit: t.budget.MaxValues, Used: used}
+}
+
+// RemoteCall records that the operator is about to call a remote service
+func (t *Tracker) RemoteCall(op string) error {
+	if t == nil {
+		return nil
+	}
+	used := atomic.AddInt64(&t.remoteCalls, 1)
+	if t.budget.MaxRemoteCalls == 0 || used <= t.budget.MaxRemoteCalls {
+		return nil
+	}
+	return ExceededError{Operator: op, Resource: REMOTE_CALLS, Limit: t.budget.MaxRemoteCalls, Used: used}
+}
+
+// Timeout returns the error of the operator that was running when the query ran out of time
+func (t *Tracker) Timeout(op string) error {
+	if t == nil {
+		return ExceededError{Operator: op, Resource: TIMEOUT_MS}
+	}
+	return ExceededError{Operator: op, Resource: TIMEOUT_MS, Limit: t.budget.TimeoutMs, Used: time.Since(t.start).Milliseconds()}
+}
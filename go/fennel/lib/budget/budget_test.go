@@ -0,0 +1,62 @@
+package budget
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBudget_Override(t *testing.T) {
+	tierBudget := BudgetArgs{QueryMaxRows: 100, QueryMaxValues: 1000, QueryTimeout: 2 * time.Second}.Default()
+	assert.Equal(t, Budget{MaxRows: 100, MaxValues: 1000, TimeoutMs: 2000}, tierBudget)
+
+	// limits that are not set on the query are taken from the tier
+	b := tierBudget.Override(Budget{MaxRows: 10, MaxRemoteCalls: 5})
+	assert.Equal(t, Budget{MaxRows: 10, MaxValues: 1000, MaxRemoteCalls: 5, TimeoutMs: 2000}, b)
+	assert.Equal(t, tierBudget, tierBudget.Override(Budget{}))
+	assert.Equal(t, 2*time.Second, b.Timeout())
+
+	assert.NoError(t, b.Validate())
+	assert.Error(t, Budget{MaxRows: -1}.Validate())
+	assert.Error(t, Budget{TimeoutMs: -1}.Validate())
+}
+
+func TestTracker(t *testing.T) {
+	tracker := NewTracker(Budget{MaxRows: 3, MaxValues: 10, MaxRemoteCalls: 2})
+	assert.NoError(t, tracker.Rows("std.explode", 3))
+	assert.Equal(t, ExceededError{Operator: "std.explode", Resource: ROWS, Limit: 3, Used: 4}, tracker.Rows("std.explode", 4))
+
+	// values and remote calls add up over the query
+	assert.NoError(t, tracker.Values("std.map", 6))
+	assert.NoError(t, tracker.Values("std.filter", 4))
+	err := tracker.Values("std.repeat", 1)
+	assert.Equal(t, ExceededError{Operator: "std.repeat", Resource: VALUES, Limit: 10, Used: 11}, err)
+	assert.EqualError(t, err, "operator 'std.repeat' exceeded the query budget of 10 values, used: 11")
+
+	assert.NoError(t, tracker.RemoteCall("remote.http"))
+	assert.NoError(t, tracker.RemoteCall("remote.http"))
+	err = fmt.Errorf("http error: %w", tracker.RemoteCall("remote.http"))
+	var exceeded ExceededError
+	assert.True(t, errors.As(err, &exceeded))
+	assert.Equal(t, "remote.http", exceeded.Operator)
+	assert.Equal(t, REMOTE_CALLS, exceeded.Resource)
+
+	timeout := tracker.Timeout("remote.http")
+	assert.True(t, errors.As(timeout, &exceeded))
+	assert.Equal(t, TIMEOUT_MS, exceeded.Resource)
+}
+
+func TestTracker_Unbounded(t *testing.T) {
+	// limits that are zero and nil trackers never fail
+	for _, tracker := range []*Tracker{NewTracker(Budget{}), nil} {
+		assert.NoError(t, tracker.Rows("std.explode", 1<<30))
+		assert.NoError(t, tracker.Values("std.explode", 1<<30))
+		for i := 0; i < 10; i++ {
+			assert.NoError(t, tracker.RemoteCall("remote.http"))
+		}
+	}
+	assert.Equal(t, Budget{}, (*Tracker)(nil).Budget())
+}
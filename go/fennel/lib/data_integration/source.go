@@ -28,6 +28,7 @@ var _ Source = BigQuery{}
 var _ Source = Postgres{}
 var _ Source = MySQL{}
 var _ Source = Snowflake{}
+var _ Source = Local{}
 
 type S3 struct {
 	Name               string `db:"name" json:"name"`
@@ -77,8 +78,8 @@ func (s S3) Validate() error {
 	if s.Name == "" {
 		return fmt.Errorf("source name is required")
 	}
-	if s.Format != "csv" && s.Format != "parquet" && s.Format != "avro" {
-		return fmt.Errorf("invalid format: %s we only support csv, parquet, or avro", s.Format)
+	if s.Format != "csv" && s.Format != "parquet" && s.Format != "avro" && s.Format != "json" {
+		return fmt.Errorf("invalid format: %s we only support csv, parquet, avro or json", s.Format)
 	}
 	if s.Delimiter != "," && s.Delimiter != "|" && s.Delimiter != "\t" {
 		return fmt.Errorf("invalid delimiter: %s", s.Delimiter)
@@ -146,15 +147,18 @@ func (s BigQuery) Validate() error {
 }
 
 type SQLSource struct {
-	Name        string `db:"name" json:"name"`
-	SourceId    string `db:"source_id" json:"source_id"`
-	Host        string `db:"host" json:"host"`
-	Dbname      string `db:"db_name" json:"db_name"`
-	Username    string `json:"username"`
-	Password    string `json:"password"`
-	JdbcParams  string `db:"jdbc_params" json:"jdbc_params"`
-	Port        int    `db:"port" json:"port"`
-	LastUpdated string `db:"last_updated" json:"last_updated"`
+	Name     string `db:"name" json:"name"`
+	SourceId string `db:"source_id" json:"source_id"`
+	Host     string `db:"host" json:"host"`
+	Dbname   string `db:"db_name" json:"db_name"`
+	// Credentials are never stored in the db, airbyte keeps its own copy of them and those of
+	// sources read by the native connector runtime are kept in the secret named CredentialsSecret
+	Username          string `json:"username"`
+	Password          string `json:"password"`
+	CredentialsSecret string `db:"credentials_secret" json:"-"`
+	JdbcParams        string `db:"jdbc_params" json:"jdbc_params"`
+	Port              int    `db:"port" json:"port"`
+	LastUpdated       string `db:"last_updated" json:"last_updated"`
 }
 
 type Postgres struct {
@@ -297,3 +301,57 @@ func (s Snowflake) Equals(src Source) error {
 		return fmt.Errorf("source type mismatch")
 	}
 }
+
+// Local reads newline delimited json or csv files from a directory on the local disk.
+// It is only supported by the native connector runtime (i.e. not by Airbyte) and is meant for
+// local development & testing of connectors
+type Local struct {
+	Name        string `db:"name" json:"name"`
+	SourceId    string `db:"source_id" json:"source_id"`
+	Dir         string `db:"dir" json:"dir"`
+	Format      string `db:"format" json:"format"`
+	Delimiter   string `db:"delimiter" json:"delimiter"`
+	LastUpdated string `db:"last_updated" json:"last_updated"`
+}
+
+func (s Local) GetSourceName() string {
+	return s.Name
+}
+
+func (s Local) GetSourceId() string {
+	return s.SourceId
+}
+
+func (s Local) GetDefaultCursorField() string {
+	return S3_CURSOR_FIELD
+}
+
+func (s Local) Equals(src Source) error {
+	if src.GetSourceName() != s.Name {
+		return fmt.Errorf("source name mismatch")
+	}
+	if s2, ok := src.(Local); ok {
+		if s.Dir == s2.Dir && s.Format == s2.Format && s.Delimiter == s2.Delimiter {
+			return nil
+		}
+		return fmt.Errorf("local fields do not match")
+	} else {
+		return fmt.Errorf("source type mismatch")
+	}
+}
+
+func (s Local) Validate() error {
+	if s.Name == "" {
+		return fmt.Errorf("source name is required")
+	}
+	if len(s.Dir) == 0 {
+		return fmt.Errorf("dir is required")
+	}
+	if s.Format != "csv" && s.Format != "json" {
+		return fmt.Errorf("invalid format: %s we only support csv or json", s.Format)
+	}
+	if s.Format == "csv" && s.Delimiter != "," && s.Delimiter != "|" && s.Delimiter != "\t" {
+		return fmt.Errorf("invalid delimiter: %s", s.Delimiter)
+	}
+	return nil
+}
@@ -0,0 +1,129 @@
+package dedup
+
+import (
+	"math"
+	"sync"
+	"time"
+
+	"github.com/zeebo/xxh3"
+)
+
+// bloom is a bloom filter, it never has false negatives and has false positives at about the rate
+// it was sized for when it holds its capacity of keys
+type bloom struct {
+	bits []uint64
+	k    uint64
+	// n is the number of keys added, keys that may have been added before are not counted
+	n uint
+}
+
+func newBloom(capacity uint, fpRate float64) *bloom {
+	if capacity == 0 {
+		capacity = 1
+	}
+	// optimal number of bits and hashes for the capacity and false positive rate
+	m := math.Ceil(-float64(capacity) * math.Log(fpRate) / (math.Ln2 * math.Ln2))
+	k := math.Max(1, math.Round(m/float64(capacity)*math.Ln2))
+	return &bloom{bits: make([]uint64, uint64(m)/64+1), k: uint64(k)}
+}
+
+// locations returns the bits of the key using double hashing
+func (b *bloom) locations(key string, f func(word, mask uint64) bool) bool {
+	h := xxh3.HashString128(key)
+	n := uint64(len(b.bits)) * 64
+	for i := uint64(0); i < b.k; i++ {
+		loc := (h.Lo + i*h.Hi) % n
+		if !f(loc/64, 1<<(loc%64)) {
+			return false
+		}
+	}
+	return true
+}
+
+func (b *bloom) add(key string) {
+	added := false
+	b.locations(key, func(word, mask uint64) bool {
+		added = added || b.bits[word]&mask == 0
+		b.bits[word] |= mask
+		return true
+	})
+	if added {
+		b.n++
+	}
+}
+
+func (b *bloom) test(key string) bool {
+	return b.locations(key, func(word, mask uint64) bool {
+		return b.bits[word]&mask != 0
+	})
+}
+
+// Filter remembers the keys added to it for at least half a window and at most a window using two
+// generations of bloom filters, each half a window long. Keys are forgotten once the generation
+// after theirs ends, so a key the filter remembers was added within the last window. A generation
+// that reaches the capacity of the filter is rotated early, so that false positives stay at about
+// the rate the filter was sized for at the cost of forgetting keys sooner. It is safe for
+// concurrent use.
+type Filter struct {
+	mu         sync.Mutex
+	generation time.Duration
+	capacity   uint
+	rotated    time.Time
+	curr       *bloom
+	prev       *bloom
+}
+
+// filterFPRate is the false positive rate of each generation of a Filter at capacity
+const filterFPRate = 0.001
+
+// NewFilter returns a filter that holds up to capacity keys per generation
+func NewFilter(capacity uint, window time.Duration, now time.Time) *Filter {
+	generation := window / 2
+	if generation <= 0 {
+		generation = 1
+	}
+	return &Filter{
+		generation: generation,
+		capacity:   capacity,
+		rotated:    now,
+		curr:       newBloom(capacity, filterFPRate),
+		prev:       newBloom(capacity, filterFPRate),
+	}
+}
+
+func (f *Filter) rotate(now time.Time) {
+	n := now.Sub(f.rotated) / f.generation
+	if n <= 0 {
+		return
+	}
+	if n >= 2 {
+		// nothing added in the last two generations needs to be remembered
+		f.prev = newBloom(f.capacity, filterFPRate)
+	} else {
+		f.prev = f.curr
+	}
+	f.curr = newBloom(f.capacity, filterFPRate)
+	// generations start at fixed times so that keys are never remembered for more than a window
+	f.rotated = f.rotated.Add(n * f.generation)
+}
+
+// Test returns whether the key may have been added within the last window
+func (f *Filter) Test(key string, now time.Time) bool {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.rotate(now)
+	return f.curr.test(key) || f.prev.test(key)
+}
+
+// Add adds the key to the filter
+func (f *Filter) Add(key string, now time.Time) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.rotate(now)
+	if f.curr.n >= f.capacity {
+		// the generation ends as scheduled, the full filter is only remembered until then
+		f.prev = f.curr
+		f.curr = newBloom(f.capacity, filterFPRate)
+	}
+	f.curr.add(key)
+}
@@ -0,0 +1,217 @@
+package dedup
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"fennel/lib/ftypes"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"go.uber.org/zap"
+)
+
+/*
+	Actions are deduplicated by their dedup key within a window that can be configured per action
+	type. The first action with a dedup key claims the key in the store (redis) for the window of
+	its action type, later actions with the same key in that window are duplicates. Keys of a
+	batch are claimed with a single pipelined round-trip.
+
+	Claimed keys are also added to a local filter of the keys that this replica saw within the
+	window of their action type. The filter has false positives, so the store decides whether keys
+	are duplicates and the filter is only used if the store can not be reached: dedup then runs in
+	degraded mode where keys in the filter are duplicates, and the other keys are accepted but
+	flagged as possible duplicates since they may have been logged on another replica.
+*/
+
+type DedupArgs struct {
+	// window in which actions with the same dedup key are duplicates
+	DedupWindow time.Duration `arg:"--dedup-window,env:DEDUP_WINDOW" default:"6h" json:"dedup_window,omitempty"`
+	// windows of action types that differ from the default, e.g. "click=1h,view=10m"
+	DedupWindows string `arg:"--dedup-windows,env:DEDUP_WINDOWS" json:"dedup_windows,omitempty"`
+	// number of dedup keys a replica expects to see per half window, sizes the local filter
+	DedupFilterCapacity uint `arg:"--dedup-filter-capacity,env:DEDUP_FILTER_CAPACITY" default:"1000000" json:"dedup_filter_capacity,omitempty"`
+}
+
+type Status int
+
+const (
+	// Unique is the status of actions that were not logged before or have no dedup key
+	Unique Status = iota
+	// Duplicate is the status of actions whose dedup key was logged before in the window
+	Duplicate
+	// PossibleDuplicate is the status of actions that were checked in degraded mode and whose
+	// dedup key may have been logged on another replica
+	PossibleDuplicate
+)
+
+func (s Status) String() string {
+	switch s {
+	case Unique:
+		return "unique"
+	case Duplicate:
+		return "duplicate"
+	case PossibleDuplicate:
+		return "possible_duplicate"
+	default:
+		return "unknown"
+	}
+}
+
+var checkedKeys = promauto.NewCounterVec(
+	prometheus.CounterOpts{
+		Name: "dedup_checked_keys_total",
+		Help: "Total number of dedup keys checked by status.",
+	},
+	[]string{"status"},
+)
+
+var degradedKeys = promauto.NewCounter(
+	prometheus.CounterOpts{
+		Name: "dedup_degraded_keys_total",
+		Help: "Total number of dedup keys checked in degraded mode as the store failed.",
+	},
+)
+
+// Windows are the dedup windows of action types
+type Windows struct {
+	Default time.Duration
+	ByType  map[ftypes.ActionType]time.Duration
+}
+
+// ParseWindows parses windows of action types of the form "click=1h,view=10m"
+func ParseWindows(def time.Duration, s string) (Windows, error) {
+	if def <= 0 {
+		return Windows{}, fmt.Errorf("dedup window should be positive but was %s", def)
+	}
+	w := Windows{Default: def, ByType: make(map[ftypes.ActionType]time.Duration)}
+	if len(strings.TrimSpace(s)) == 0 {
+		return w, nil
+	}
+	for _, part := range strings.Split(s, ",") {
+		kv := strings.SplitN(strings.TrimSpace(part), "=", 2)
+		if len(kv) != 2 || len(kv[0]) == 0 {
+			return Windows{}, fmt.Errorf("invalid dedup window: '%s', expected <action type>=<duration>", part)
+		}
+		d, err := time.ParseDuration(kv[1])
+		if err != nil || d <= 0 {
+			return Windows{}, fmt.Errorf("invalid dedup window of '%s': '%s'", kv[0], kv[1])
+		}
+		w.ByType[ftypes.ActionType(kv[0])] = d
+	}
+	return w, nil
+}
+
+func (w Windows) For(actionType ftypes.ActionType) time.Duration {
+	if d, ok := w.ByType[actionType]; ok {
+		return d
+	}
+	return w.Default
+}
+
+// Max returns the longest window
+func (w Windows) Max() time.Duration {
+	ret := w.Default
+	for _, d := range w.ByType {
+		if d > ret {
+			ret = d
+		}
+	}
+	return ret
+}
+
+// Store claims dedup keys
+type Store interface {
+	// Claim sets the keys that are not set yet, with the given ttls, and returns which keys were
+	// set. errs is set for keys that could not be checked, err if no key could be checked.
+	Claim(ctx context.Context, keys []string, ttls []time.Duration) (set []bool, errs []error, err error)
+}
+
+// Key returns the key of an action in the store, the action type is part of the key so that
+// actions are deduplicated at the granularity of action type
+func Key(dedupKey string, actionType ftypes.ActionType) string {
+	var b strings.Builder
+	b.WriteString(dedupKey)
+	b.WriteString(":")
+	b.WriteString(string(actionType))
+	return b.String()
+}
+
+type Deduper struct {
+	store   Store
+	windows Windows
+	// filters has a filter for each window so that keys are only remembered for their window
+	filters map[time.Duration]*Filter
+	logger  *zap.Logger
+	now     func() time.Time
+}
+
+func NewDeduper(store Store, windows Windows, filterCapacity uint, logger *zap.Logger, now func() time.Time) *Deduper {
+	filters := map[time.Duration]*Filter{windows.Default: NewFilter(filterCapacity, windows.Default, now())}
+	for _, w := range windows.ByType {
+		if _, ok := filters[w]; !ok {
+			filters[w] = NewFilter(filterCapacity, w, now())
+		}
+	}
+	return &Deduper{
+		store:   store,
+		windows: windows,
+		filters: filters,
+		logger:  logger,
+		now:     now,
+	}
+}
+
+// Check returns the status of each action given its dedup key and action type. Actions with an
+// empty dedup key are always unique, and of actions with the same key in the batch only the first
+// can be unique.
+func (d *Deduper) Check(ctx context.Context, dedupKeys []string, actionTypes []ftypes.ActionType) []Status {
+	statuses := make([]Status, len(dedupKeys))
+	now := d.now()
+	var keys []string
+	var ttls []time.Duration
+	var ids []int
+	inBatch := make(map[string]struct{})
+	for i, dk := range dedupKeys {
+		if len(dk) == 0 {
+			continue
+		}
+		key := Key(dk, actionTypes[i])
+		if _, ok := inBatch[key]; ok {
+			statuses[i] = Duplicate
+			continue
+		}
+		inBatch[key] = struct{}{}
+		keys = append(keys, key)
+		ttls = append(ttls, d.windows.For(actionTypes[i]))
+		ids = append(ids, i)
+	}
+	if len(keys) > 0 {
+		set, errs, err := d.store.Claim(ctx, keys, ttls)
+		if err != nil {
+			d.logger.Warn("failed to claim dedup keys, running in degraded mode", zap.Int("keys", len(keys)), zap.Error(err))
+		}
+		for j, key := range keys {
+			filter := d.filters[ttls[j]]
+			switch {
+			case err != nil || errs[j] != nil:
+				// only in degraded mode the filter decides on its own
+				degradedKeys.Inc()
+				if filter.Test(key, now) {
+					statuses[ids[j]] = Duplicate
+				} else {
+					statuses[ids[j]] = PossibleDuplicate
+				}
+			case !set[j]:
+				statuses[ids[j]] = Duplicate
+			}
+			filter.Add(key, now)
+		}
+	}
+	for _, s := range statuses {
+		checkedKeys.WithLabelValues(s.String()).Inc()
+	}
+	return statuses
+}
@@ -0,0 +1,181 @@
+package dedup
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"fennel/lib/ftypes"
+	"fennel/redis"
+	"fennel/resource"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+func TestParseWindows(t *testing.T) {
+	w, err := ParseWindows(time.Hour, "click=10m, view=2h")
+	require.NoError(t, err)
+	assert.Equal(t, 10*time.Minute, w.For("click"))
+	assert.Equal(t, 2*time.Hour, w.For("view"))
+	assert.Equal(t, time.Hour, w.For("like"))
+	assert.Equal(t, 2*time.Hour, w.Max())
+
+	w, err = ParseWindows(time.Hour, "")
+	require.NoError(t, err)
+	assert.Equal(t, time.Hour, w.For("click"))
+
+	for _, s := range []string{"click", "=1h", "click=abc", "click=-1h", "click=1h,"} {
+		_, err = ParseWindows(time.Hour, s)
+		assert.Error(t, err, s)
+	}
+	_, err = ParseWindows(0, "")
+	assert.Error(t, err)
+}
+
+func TestFilter(t *testing.T) {
+	now := time.Unix(1000, 0)
+	f := NewFilter(1000, time.Hour, now)
+	for i := 0; i < 1000; i++ {
+		f.Add(fmt.Sprintf("key%d", i), now)
+	}
+	for i := 0; i < 1000; i++ {
+		assert.True(t, f.Test(fmt.Sprintf("key%d", i), now))
+	}
+	// at capacity, false positives stay close to the rate the filter was sized for
+	fp := 0
+	for i := 1000; i < 11000; i++ {
+		if f.curr.test(fmt.Sprintf("key%d", i)) {
+			fp++
+		}
+	}
+	assert.Less(t, fp, 50)
+
+	// keys are remembered for at least half a window and never for more than a window
+	f = NewFilter(1000, time.Hour, now)
+	f.Add("a", now.Add(29*time.Minute))
+	assert.True(t, f.Test("a", now.Add(59*time.Minute)))
+	assert.False(t, f.Test("a", now.Add(60*time.Minute)))
+	f.Add("b", now.Add(61*time.Minute))
+	assert.True(t, f.Test("b", now.Add(91*time.Minute)))
+	assert.False(t, f.Test("b", now.Add(5*time.Hour)))
+}
+
+func TestFilter_Overfilled(t *testing.T) {
+	now := time.Unix(1000, 0)
+	f := NewFilter(100, time.Hour, now)
+	for i := 0; i < 10000; i++ {
+		f.Add(fmt.Sprintf("key%d", i), now)
+	}
+	// the filter rotates early instead of filling up, the latest keys are still remembered
+	assert.LessOrEqual(t, f.curr.n, uint(100))
+	assert.True(t, f.Test("key9999", now))
+	fp := 0
+	for i := 10000; i < 11000; i++ {
+		if f.Test(fmt.Sprintf("key%d", i), now) {
+			fp++
+		}
+	}
+	assert.Less(t, fp, 20)
+}
+
+// mapStore claims keys in a map and fails all claims if err is set
+type mapStore struct {
+	claimed map[string]struct{}
+	err     error
+}
+
+func (m *mapStore) Claim(ctx context.Context, keys []string, ttls []time.Duration) ([]bool, []error, error) {
+	if m.err != nil {
+		return nil, nil, m.err
+	}
+	set := make([]bool, len(keys))
+	for i, k := range keys {
+		if _, ok := m.claimed[k]; !ok {
+			m.claimed[k] = struct{}{}
+			set[i] = true
+		}
+	}
+	return set, make([]error, len(keys)), nil
+}
+
+func TestDeduper_Overfilled(t *testing.T) {
+	ctx := context.Background()
+	now := time.Unix(1000, 0)
+	windows, err := ParseWindows(time.Hour, "")
+	require.NoError(t, err)
+	store := &mapStore{claimed: make(map[string]struct{})}
+	d := NewDeduper(store, windows, 10, zap.NewNop(), func() time.Time { return now })
+
+	// far more keys than the filter holds are all unique as the store confirms hits of the filter
+	keys := make([]string, 1000)
+	actionTypes := make([]ftypes.ActionType, 1000)
+	for i := range keys {
+		keys[i], actionTypes[i] = fmt.Sprintf("key%d", i), "view"
+	}
+	for _, s := range d.Check(ctx, keys, actionTypes) {
+		assert.Equal(t, Unique, s)
+	}
+	for i := range keys {
+		keys[i] = fmt.Sprintf("other%d", i)
+	}
+	for _, s := range d.Check(ctx, keys, actionTypes) {
+		assert.Equal(t, Unique, s)
+	}
+	for _, s := range d.Check(ctx, keys, actionTypes) {
+		assert.Equal(t, Duplicate, s)
+	}
+
+	// in degraded mode, keys that are not in the filter are possible duplicates
+	store.err = fmt.Errorf("store is down")
+	statuses := d.Check(ctx, []string{"other999", "new"}, []ftypes.ActionType{"view", "view"})
+	assert.Equal(t, []Status{Duplicate, PossibleDuplicate}, statuses)
+}
+
+func TestDeduper(t *testing.T) {
+	ctx := context.Background()
+	mr, err := miniredis.Run()
+	require.NoError(t, err)
+	defer mr.Close()
+	client, err := redis.MiniRedisConfig{MiniRedis: mr, Scope: resource.NewTierScope(ftypes.RealmID(1))}.Materialize()
+	require.NoError(t, err)
+	defer client.Close()
+
+	now := time.Unix(1000, 0)
+	windows, err := ParseWindows(time.Hour, "click=1m")
+	require.NoError(t, err)
+	d := NewDeduper(NewRedisStore(client.(redis.Client)), windows, 1000, zap.NewNop(), func() time.Time { return now })
+
+	// actions without a dedup key are always unique and repeated keys in a batch are duplicates
+	statuses := d.Check(ctx, []string{"", "", "a", "a", "b"}, []ftypes.ActionType{"view", "view", "view", "view", "click"})
+	assert.Equal(t, []Status{Unique, Unique, Unique, Duplicate, Unique}, statuses)
+
+	// keys are deduplicated per action type
+	statuses = d.Check(ctx, []string{"a", "a", "b"}, []ftypes.ActionType{"view", "click", "click"})
+	assert.Equal(t, []Status{Duplicate, Unique, Duplicate}, statuses)
+
+	// keys expire after the window of their action type
+	mr.FastForward(2 * time.Minute)
+	now = now.Add(2 * time.Minute)
+	statuses = d.Check(ctx, []string{"a", "b"}, []ftypes.ActionType{"view", "click"})
+	assert.Equal(t, []Status{Duplicate, Unique}, statuses)
+
+	// keys claimed by another replica are duplicates once this deduper checks the store
+	other := NewDeduper(NewRedisStore(client.(redis.Client)), windows, 1000, zap.NewNop(), func() time.Time { return now })
+	statuses = other.Check(ctx, []string{"a"}, []ftypes.ActionType{"view"})
+	assert.Equal(t, []Status{Duplicate}, statuses)
+
+	// when redis is down, keys in the local filter are duplicates
+	mr.Close()
+	statuses = d.Check(ctx, []string{"a"}, []ftypes.ActionType{"view"})
+	assert.Equal(t, []Status{Duplicate}, statuses)
+
+	// when redis is down, keys not in the local filter are possible duplicates
+	statuses = d.Check(ctx, []string{"c", "c", ""}, []ftypes.ActionType{"view", "view", "view"})
+	assert.Equal(t, []Status{PossibleDuplicate, Duplicate, Unique}, statuses)
+	statuses = d.Check(ctx, []string{"c"}, []ftypes.ActionType{"view"})
+	assert.Equal(t, []Status{Duplicate}, statuses)
+}
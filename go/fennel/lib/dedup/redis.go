@@ -0,0 +1,42 @@
+package dedup
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"fennel/redis"
+)
+
+// RedisStore claims dedup keys in redis so that actions are deduplicated across replicas
+type RedisStore struct {
+	client redis.Client
+}
+
+var _ Store = RedisStore{}
+
+func NewRedisStore(client redis.Client) RedisStore {
+	return RedisStore{client: client}
+}
+
+func (s RedisStore) Claim(ctx context.Context, keys []string, ttls []time.Duration) ([]bool, []error, error) {
+	vals := make([]interface{}, len(keys))
+	for i := range vals {
+		vals[i] = 1
+	}
+	res, err := s.client.SetNXPipelined(ctx, keys, vals, ttls)
+	if err != nil {
+		return nil, nil, err
+	}
+	set := make([]bool, len(keys))
+	errs := make([]error, len(keys))
+	for i, r := range res {
+		switch r {
+		case redis.NotFoundSet:
+			set[i] = true
+		case redis.Error:
+			errs[i] = fmt.Errorf("failed to set dedup key: '%s'", keys[i])
+		}
+	}
+	return set, errs, nil
+}
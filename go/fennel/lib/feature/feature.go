@@ -25,10 +25,17 @@ type Row struct {
 	ModelName       ftypes.ModelName    `json:"model_name"`
 	ModelVersion    ftypes.ModelVersion `json:"model_version"`
 	ModelPrediction float64             `json:"model_prediction"`
+	// QueryName and QueryVersion identify the stored query that logged the row, they are empty
+	// for rows logged by ad-hoc queries
+	QueryName    string `json:"query_name"`
+	QueryVersion uint32 `json:"query_version"`
+	// Seed is the seed of the query that logged the row, running the query with it reproduces the
+	// row. It is 0 if the query was not seeded.
+	Seed int64 `json:"seed"`
 }
 
 func (r *Row) UnmarshalJSON(bytes []byte) error {
-	d, err := value.FromJSON(bytes)
+	d, err := value.FromTaggedJSON(bytes)
 	if err != nil {
 		return err
 	}
@@ -99,6 +106,24 @@ func (r *Row) UnmarshalJSON(bytes []byte) error {
 				return fmt.Errorf("can not unmarshal feature row, expected integer for timestamp but found: %v", v)
 			}
 			r.Timestamp = ftypes.Timestamp(n)
+		case "query_name":
+			s, ok := v.(value.String)
+			if !ok {
+				return fmt.Errorf("can not unmarshal feature row, expected string for query_name but found: %v", v)
+			}
+			r.QueryName = string(s)
+		case "query_version":
+			n, ok := v.(value.Int)
+			if !ok || n < 0 {
+				return fmt.Errorf("can not unmarshal feature row, expected non-negative integer for query_version but found: %v", v)
+			}
+			r.QueryVersion = uint32(n)
+		case "seed":
+			n, ok := v.(value.Int)
+			if !ok {
+				return fmt.Errorf("can not unmarshal feature row, expected integer for seed but found: %v", v)
+			}
+			r.Seed = int64(n)
 		case "model_prediction":
 			switch p := v.(type) {
 			case value.Double:
@@ -147,6 +172,13 @@ func (r Row) GetValue() (value.Value, error) {
 	d.Set("model_name", value.String(r.ModelName))
 	d.Set("model_version", value.String(r.ModelVersion))
 	d.Set("model_prediction", value.Double(r.ModelPrediction))
+	if len(r.QueryName) > 0 {
+		d.Set("query_name", value.String(r.QueryName))
+		d.Set("query_version", value.Int(r.QueryVersion))
+	}
+	if r.Seed != 0 {
+		d.Set("seed", value.Int(r.Seed))
+	}
 	return d, nil
 }
 
@@ -186,6 +218,9 @@ func FromProtoRow(pr *ProtoRow) (*Row, error) {
 		ModelName:       ftypes.ModelName(pr.ModelName),
 		ModelVersion:    ftypes.ModelVersion(pr.ModelVersion),
 		ModelPrediction: pr.ModelPrediction,
+		QueryName:       pr.QueryName,
+		QueryVersion:    pr.QueryVersion,
+		Seed:            pr.Seed,
 	}, nil
 }
 
@@ -206,5 +241,8 @@ func ToProto(r Row) (*ProtoRow, error) {
 		ModelName:       string(r.ModelName),
 		ModelVersion:    string(r.ModelVersion),
 		ModelPrediction: r.ModelPrediction,
+		QueryName:       r.QueryName,
+		QueryVersion:    r.QueryVersion,
+		Seed:            r.Seed,
 	}, nil
 }
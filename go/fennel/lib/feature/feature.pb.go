@@ -37,6 +37,9 @@ type ProtoRow struct {
 	ModelName       string        `protobuf:"bytes,9,opt,name=ModelName,proto3" json:"ModelName,omitempty"`
 	ModelVersion    string        `protobuf:"bytes,10,opt,name=ModelVersion,proto3" json:"ModelVersion,omitempty"`
 	ModelPrediction float64       `protobuf:"fixed64,11,opt,name=ModelPrediction,proto3" json:"ModelPrediction,omitempty"`
+	QueryName       string        `protobuf:"bytes,12,opt,name=QueryName,proto3" json:"QueryName,omitempty"`
+	QueryVersion    uint32        `protobuf:"varint,13,opt,name=QueryVersion,proto3" json:"QueryVersion,omitempty"`
+	Seed            int64         `protobuf:"varint,14,opt,name=Seed,proto3" json:"Seed,omitempty"`
 }
 
 func (x *ProtoRow) Reset() {
@@ -148,11 +151,32 @@ func (x *ProtoRow) GetModelPrediction() float64 {
 	return 0
 }
 
+func (x *ProtoRow) GetQueryName() string {
+	if x != nil {
+		return x.QueryName
+	}
+	return ""
+}
+
+func (x *ProtoRow) GetQueryVersion() uint32 {
+	if x != nil {
+		return x.QueryVersion
+	}
+	return 0
+}
+
+func (x *ProtoRow) GetSeed() int64 {
+	if x != nil {
+		return x.Seed
+	}
+	return 0
+}
+
 var File_feature_proto protoreflect.FileDescriptor
 
 var file_feature_proto_rawDesc = []byte{
 	0x0a, 0x0d, 0x66, 0x65, 0x61, 0x74, 0x75, 0x72, 0x65, 0x2e, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x1a,
-	0x0b, 0x76, 0x61, 0x6c, 0x75, 0x65, 0x2e, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x22, 0x83, 0x03, 0x0a,
+	0x0b, 0x76, 0x61, 0x6c, 0x75, 0x65, 0x2e, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x22, 0xd9, 0x03, 0x0a,
 	0x08, 0x50, 0x72, 0x6f, 0x74, 0x6f, 0x52, 0x6f, 0x77, 0x12, 0x22, 0x0a, 0x0c, 0x43, 0x6f, 0x6e,
 	0x74, 0x65, 0x78, 0x74, 0x4f, 0x54, 0x79, 0x70, 0x65, 0x18, 0x01, 0x20, 0x01, 0x28, 0x09, 0x52,
 	0x0c, 0x43, 0x6f, 0x6e, 0x74, 0x65, 0x78, 0x74, 0x4f, 0x54, 0x79, 0x70, 0x65, 0x12, 0x1e, 0x0a,
@@ -177,8 +201,14 @@ var file_feature_proto_rawDesc = []byte{
 	0x6c, 0x56, 0x65, 0x72, 0x73, 0x69, 0x6f, 0x6e, 0x12, 0x28, 0x0a, 0x0f, 0x4d, 0x6f, 0x64, 0x65,
 	0x6c, 0x50, 0x72, 0x65, 0x64, 0x69, 0x63, 0x74, 0x69, 0x6f, 0x6e, 0x18, 0x0b, 0x20, 0x01, 0x28,
 	0x01, 0x52, 0x0f, 0x4d, 0x6f, 0x64, 0x65, 0x6c, 0x50, 0x72, 0x65, 0x64, 0x69, 0x63, 0x74, 0x69,
-	0x6f, 0x6e, 0x42, 0x14, 0x5a, 0x12, 0x66, 0x65, 0x6e, 0x6e, 0x65, 0x6c, 0x2f, 0x6c, 0x69, 0x62,
-	0x2f, 0x66, 0x65, 0x61, 0x74, 0x75, 0x72, 0x65, 0x62, 0x06, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x33,
+	0x6f, 0x6e, 0x12, 0x1c, 0x0a, 0x09, 0x51, 0x75, 0x65, 0x72, 0x79, 0x4e, 0x61, 0x6d, 0x65, 0x18,
+	0x0c, 0x20, 0x01, 0x28, 0x09, 0x52, 0x09, 0x51, 0x75, 0x65, 0x72, 0x79, 0x4e, 0x61, 0x6d, 0x65,
+	0x12, 0x22, 0x0a, 0x0c, 0x51, 0x75, 0x65, 0x72, 0x79, 0x56, 0x65, 0x72, 0x73, 0x69, 0x6f, 0x6e,
+	0x18, 0x0d, 0x20, 0x01, 0x28, 0x0d, 0x52, 0x0c, 0x51, 0x75, 0x65, 0x72, 0x79, 0x56, 0x65, 0x72,
+	0x73, 0x69, 0x6f, 0x6e, 0x12, 0x12, 0x0a, 0x04, 0x53, 0x65, 0x65, 0x64, 0x18, 0x0e, 0x20, 0x01,
+	0x28, 0x03, 0x52, 0x04, 0x53, 0x65, 0x65, 0x64, 0x42, 0x14, 0x5a, 0x12, 0x66, 0x65, 0x6e, 0x6e,
+	0x65, 0x6c, 0x2f, 0x6c, 0x69, 0x62, 0x2f, 0x66, 0x65, 0x61, 0x74, 0x75, 0x72, 0x65, 0x62, 0x06,
+	0x70, 0x72, 0x6f, 0x74, 0x6f, 0x33,
 }
 
 var (
@@ -60,6 +60,15 @@ func (this *ProtoRow) EqualVT(that *ProtoRow) bool {
 	if this.ModelPrediction != that.ModelPrediction {
 		return false
 	}
+	if this.QueryName != that.QueryName {
+		return false
+	}
+	if this.QueryVersion != that.QueryVersion {
+		return false
+	}
+	if this.Seed != that.Seed {
+		return false
+	}
 	return string(this.unknownFields) == string(that.unknownFields)
 }
 
@@ -93,6 +102,23 @@ func (m *ProtoRow) MarshalToSizedBufferVT(dAtA []byte) (int, error) {
 		i -= len(m.unknownFields)
 		copy(dAtA[i:], m.unknownFields)
 	}
+	if m.Seed != 0 {
+		i = encodeVarint(dAtA, i, uint64(m.Seed))
+		i--
+		dAtA[i] = 0x70
+	}
+	if m.QueryVersion != 0 {
+		i = encodeVarint(dAtA, i, uint64(m.QueryVersion))
+		i--
+		dAtA[i] = 0x68
+	}
+	if len(m.QueryName) > 0 {
+		i -= len(m.QueryName)
+		copy(dAtA[i:], m.QueryName)
+		i = encodeVarint(dAtA, i, uint64(len(m.QueryName)))
+		i--
+		dAtA[i] = 0x62
+	}
 	if m.ModelPrediction != 0 {
 		i -= 8
 		binary.LittleEndian.PutUint64(dAtA[i:], uint64(math.Float64bits(float64(m.ModelPrediction))))
@@ -232,6 +258,16 @@ func (m *ProtoRow) SizeVT() (n int) {
 	if m.ModelPrediction != 0 {
 		n += 9
 	}
+	l = len(m.QueryName)
+	if l > 0 {
+		n += 1 + l + sov(uint64(l))
+	}
+	if m.QueryVersion != 0 {
+		n += 1 + sov(uint64(m.QueryVersion))
+	}
+	if m.Seed != 0 {
+		n += 1 + sov(uint64(m.Seed))
+	}
 	if m.unknownFields != nil {
 		n += len(m.unknownFields)
 	}
@@ -595,6 +631,76 @@ func (m *ProtoRow) UnmarshalVT(dAtA []byte) error {
 			v = uint64(binary.LittleEndian.Uint64(dAtA[iNdEx:]))
 			iNdEx += 8
 			m.ModelPrediction = float64(math.Float64frombits(v))
+		case 12:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field QueryName", wireType)
+			}
+			var stringLen uint64
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflow
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				stringLen |= uint64(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			intStringLen := int(stringLen)
+			if intStringLen < 0 {
+				return ErrInvalidLength
+			}
+			postIndex := iNdEx + intStringLen
+			if postIndex < 0 {
+				return ErrInvalidLength
+			}
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			m.QueryName = string(dAtA[iNdEx:postIndex])
+			iNdEx = postIndex
+		case 13:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field QueryVersion", wireType)
+			}
+			m.QueryVersion = 0
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflow
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				m.QueryVersion |= uint32(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+		case 14:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Seed", wireType)
+			}
+			m.Seed = 0
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflow
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				m.Seed |= int64(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
 		default:
 			iNdEx = preIndex
 			skippy, err := skip(dAtA[iNdEx:])
@@ -0,0 +1,493 @@
+package hnsw
+
+import (
+	"bytes"
+	"container/heap"
+	"encoding/gob"
+	"fmt"
+	"math"
+	"math/rand"
+	"sort"
+	"sync"
+	"time"
+)
+
+/*
+	Index is an in-memory Hierarchical Navigable Small World graph (https://arxiv.org/abs/1603.09320)
+	for approximate nearest neighbor search over float32 vectors.
+
+	Vectors are identified by int64 ids. Re-inserting an existing id replaces its vector - the old
+	node is tombstoned and continues to be used for navigating the graph but is never returned.
+*/
+
+type Metric uint8
+
+const (
+	L2 Metric = iota
+	IP
+	Cosine
+)
+
+func (m Metric) String() string {
+	switch m {
+	case L2:
+		return "l2"
+	case IP:
+		return "ip"
+	case Cosine:
+		return "cosine"
+	default:
+		return fmt.Sprintf("Metric(%d)", m)
+	}
+}
+
+// Result is a neighbor of the query vector. Score follows the same convention as Milvus - squared
+// euclidean distance for L2 (lower is closer) and inner product/cosine similarity for IP/Cosine
+// (higher is closer)
+type Result struct {
+	ID    int64
+	Score float32
+}
+
+type node struct {
+	id  int64
+	vec []float32
+	// inverse of the norm of vec for cosine, 1 otherwise
+	inv     float32
+	friends [][]uint32
+	deleted bool
+}
+
+type Index struct {
+	mu             sync.RWMutex
+	dim            int
+	metric         Metric
+	m              int
+	mMax0          int
+	efConstruction int
+	levelMult      float64
+	nodes          []node
+	ids            map[int64]uint32
+	entry          int
+	maxLevel       int
+	rng            *rand.Rand
+}
+
+// New creates an empty index of vectors of size dim. m is the number of neighbors of each node in
+// upper layers (twice as many in the bottom layer) and efConstruction the size of the candidate
+// list while inserting - larger values build a more accurate but slower index
+func New(dim int, metric Metric, m, efConstruction int) (*Index, error) {
+	if dim <= 0 {
+		return nil, fmt.Errorf("dim must be positive but got: %d", dim)
+	}
+	if metric > Cosine {
+		return nil, fmt.Errorf("unsupported metric: %s", metric)
+	}
+	if m < 2 {
+		return nil, fmt.Errorf("M must be at least 2 but got: %d", m)
+	}
+	if efConstruction < 1 {
+		return nil, fmt.Errorf("efConstruction must be positive but got: %d", efConstruction)
+	}
+	return &Index{
+		dim:            dim,
+		metric:         metric,
+		m:              m,
+		mMax0:          2 * m,
+		efConstruction: efConstruction,
+		levelMult:      1 / math.Log(float64(m)),
+		ids:            make(map[int64]uint32),
+		entry:          -1,
+		rng:            rand.New(rand.NewSource(time.Now().UnixNano())),
+	}, nil
+}
+
+func (idx *Index) Dim() int {
+	return idx.dim
+}
+
+func (idx *Index) Metric() Metric {
+	return idx.metric
+}
+
+// Len returns the number of distinct ids in the index
+func (idx *Index) Len() int {
+	idx.mu.RLock()
+	defer idx.mu.RUnlock()
+	return len(idx.ids)
+}
+
+// Add inserts the vector with the given id, replacing the previous vector of the id if any
+func (idx *Index) Add(id int64, vec []float32) error {
+	if len(vec) != idx.dim {
+		return fmt.Errorf("expected vector of dim %d but got: %d", idx.dim, len(vec))
+	}
+	v := make([]float32, len(vec))
+	copy(v, vec)
+
+	idx.mu.Lock()
+	defer idx.mu.Unlock()
+	if old, ok := idx.ids[id]; ok {
+		idx.nodes[old].deleted = true
+	}
+	level := int(math.Floor(-math.Log(1-idx.rng.Float64()) * idx.levelMult))
+	cur := uint32(len(idx.nodes))
+	idx.nodes = append(idx.nodes, node{id: id, vec: v, inv: idx.inverse(v), friends: make([][]uint32, level+1)})
+	idx.ids[id] = cur
+	if idx.entry < 0 {
+		idx.entry, idx.maxLevel = int(cur), level
+		return nil
+	}
+
+	q := &idx.nodes[cur]
+	eps := []uint32{uint32(idx.entry)}
+	for l := idx.maxLevel; l > level; l-- {
+		eps = []uint32{idx.searchLayer(q.vec, q.inv, eps, 1, l, false)[0].id}
+	}
+	for l := min(level, idx.maxLevel); l >= 0; l-- {
+		candidates := idx.searchLayer(q.vec, q.inv, eps, idx.efConstruction, l, false)
+		neighbors := idx.selectNeighbors(candidates, idx.m)
+		friends := make([]uint32, len(neighbors))
+		for i, n := range neighbors {
+			friends[i] = n.id
+		}
+		idx.nodes[cur].friends[l] = friends
+		for _, n := range neighbors {
+			idx.link(n.id, cur, l)
+		}
+		eps = eps[:0]
+		for _, c := range candidates {
+			eps = append(eps, c.id)
+		}
+	}
+	if level > idx.maxLevel {
+		idx.entry, idx.maxLevel = int(cur), level
+	}
+	return nil
+}
+
+// Get returns the vector of the id
+func (idx *Index) Get(id int64) ([]float32, bool) {
+	idx.mu.RLock()
+	defer idx.mu.RUnlock()
+	n, ok := idx.ids[id]
+	if !ok {
+		return nil, false
+	}
+	ret := make([]float32, idx.dim)
+	copy(ret, idx.nodes[n].vec)
+	return ret, true
+}
+
+// Search returns (approximately) the k closest vectors to q, closest first. ef is the size of the
+// candidate list in the bottom layer - larger values are more accurate but slower
+func (idx *Index) Search(q []float32, k, ef int) ([]Result, error) {
+	if len(q) != idx.dim {
+		return nil, fmt.Errorf("expected query vector of dim %d but got: %d", idx.dim, len(q))
+	}
+	if k <= 0 {
+		return nil, nil
+	}
+	if ef < k {
+		ef = k
+	}
+	idx.mu.RLock()
+	defer idx.mu.RUnlock()
+	if idx.entry < 0 {
+		return nil, nil
+	}
+	inv := idx.inverse(q)
+	eps := []uint32{uint32(idx.entry)}
+	for l := idx.maxLevel; l > 0; l-- {
+		eps = []uint32{idx.searchLayer(q, inv, eps, 1, l, false)[0].id}
+	}
+	found := idx.searchLayer(q, inv, eps, ef, 0, true)
+	if len(found) > k {
+		found = found[:k]
+	}
+	return idx.results(found), nil
+}
+
+// Exact returns the k closest vectors to q by comparing q with every vector in the index
+func (idx *Index) Exact(q []float32, k int) ([]Result, error) {
+	if len(q) != idx.dim {
+		return nil, fmt.Errorf("expected query vector of dim %d but got: %d", idx.dim, len(q))
+	}
+	if k <= 0 {
+		return nil, nil
+	}
+	idx.mu.RLock()
+	defer idx.mu.RUnlock()
+	inv := idx.inverse(q)
+	h := &maxHeap{}
+	for i := range idx.nodes {
+		if idx.nodes[i].deleted {
+			continue
+		}
+		d := idx.distance(q, inv, uint32(i))
+		if h.Len() < k {
+			heap.Push(h, candidate{uint32(i), d})
+		} else if d < (*h)[0].dist {
+			(*h)[0] = candidate{uint32(i), d}
+			heap.Fix(h, 0)
+		}
+	}
+	found := []candidate(*h)
+	sort.Slice(found, func(i, j int) bool { return found[i].dist < found[j].dist })
+	return idx.results(found), nil
+}
+
+type snapshot struct {
+	Dim            int
+	Metric         Metric
+	M              int
+	EfConstruction int
+	Entry          int
+	MaxLevel       int
+	Nodes          []snapshotNode
+}
+
+type snapshotNode struct {
+	ID      int64
+	Vec     []float32
+	Friends [][]uint32
+	Deleted bool
+}
+
+func (idx *Index) MarshalBinary() ([]byte, error) {
+	idx.mu.RLock()
+	defer idx.mu.RUnlock()
+	s := snapshot{
+		Dim: idx.dim, Metric: idx.metric, M: idx.m, EfConstruction: idx.efConstruction,
+		Entry: idx.entry, MaxLevel: idx.maxLevel, Nodes: make([]snapshotNode, len(idx.nodes)),
+	}
+	for i, n := range idx.nodes {
+		s.Nodes[i] = snapshotNode{ID: n.id, Vec: n.vec, Friends: n.friends, Deleted: n.deleted}
+	}
+	var buf bytes.Buffer
+	if err := gob.NewEncoder(&buf).Encode(s); err != nil {
+		return nil, fmt.Errorf("failed to encode index: %w", err)
+	}
+	return buf.Bytes(), nil
+}
+
+// Load restores an index previously serialized with MarshalBinary
+func Load(data []byte) (*Index, error) {
+	var s snapshot
+	if err := gob.NewDecoder(bytes.NewReader(data)).Decode(&s); err != nil {
+		return nil, fmt.Errorf("failed to decode index: %w", err)
+	}
+	idx, err := New(s.Dim, s.Metric, s.M, s.EfConstruction)
+	if err != nil {
+		return nil, err
+	}
+	idx.entry, idx.maxLevel = s.Entry, s.MaxLevel
+	idx.nodes = make([]node, len(s.Nodes))
+	for i, n := range s.Nodes {
+		if len(n.Vec) != s.Dim {
+			return nil, fmt.Errorf("corrupt index: vector of id %d has dim %d instead of %d", n.ID, len(n.Vec), s.Dim)
+		}
+		idx.nodes[i] = node{id: n.ID, vec: n.Vec, inv: idx.inverse(n.Vec), friends: n.Friends, deleted: n.Deleted}
+		if !n.Deleted {
+			idx.ids[n.ID] = uint32(i)
+		}
+	}
+	return idx, nil
+}
+
+//================================================
+// Private helpers
+//================================================
+
+type candidate struct {
+	id   uint32
+	dist float32
+}
+
+// minHeap pops the closest candidate first
+type minHeap []candidate
+
+func (h minHeap) Len() int            { return len(h) }
+func (h minHeap) Less(i, j int) bool  { return h[i].dist < h[j].dist }
+func (h minHeap) Swap(i, j int)       { h[i], h[j] = h[j], h[i] }
+func (h *minHeap) Push(x interface{}) { *h = append(*h, x.(candidate)) }
+func (h *minHeap) Pop() interface{} {
+	old := *h
+	x := old[len(old)-1]
+	*h = old[:len(old)-1]
+	return x
+}
+
+// maxHeap pops the furthest candidate first
+type maxHeap []candidate
+
+func (h maxHeap) Len() int            { return len(h) }
+func (h maxHeap) Less(i, j int) bool  { return h[i].dist > h[j].dist }
+func (h maxHeap) Swap(i, j int)       { h[i], h[j] = h[j], h[i] }
+func (h *maxHeap) Push(x interface{}) { *h = append(*h, x.(candidate)) }
+func (h *maxHeap) Pop() interface{} {
+	old := *h
+	x := old[len(old)-1]
+	*h = old[:len(old)-1]
+	return x
+}
+
+func (idx *Index) inverse(v []float32) float32 {
+	if idx.metric != Cosine {
+		return 1
+	}
+	var sum float32
+	for _, x := range v {
+		sum += x * x
+	}
+	if sum == 0 {
+		return 0
+	}
+	return float32(1 / math.Sqrt(float64(sum)))
+}
+
+// distance is smaller for closer vectors for all metrics
+func (idx *Index) distance(q []float32, inv float32, n uint32) float32 {
+	v := idx.nodes[n].vec
+	switch idx.metric {
+	case L2:
+		var sum float32
+		for i := range q {
+			d := q[i] - v[i]
+			sum += d * d
+		}
+		return sum
+	default:
+		var dot float32
+		for i := range q {
+			dot += q[i] * v[i]
+		}
+		return -dot * inv * idx.nodes[n].inv
+	}
+}
+
+func (idx *Index) score(dist float32) float32 {
+	if idx.metric == L2 {
+		return dist
+	}
+	return -dist
+}
+
+func (idx *Index) results(found []candidate) []Result {
+	ret := make([]Result, len(found))
+	for i, c := range found {
+		ret[i] = Result{ID: idx.nodes[c.id].id, Score: idx.score(c.dist)}
+	}
+	return ret
+}
+
+// searchLayer returns up to ef closest nodes to q in the given layer sorted by distance. If live is
+// set, tombstoned nodes are traversed but not returned
+func (idx *Index) searchLayer(q []float32, inv float32, eps []uint32, ef, level int, live bool) []candidate {
+	visited := make(map[uint32]struct{}, ef*idx.m)
+	candidates := &minHeap{}
+	found := &maxHeap{}
+	for _, ep := range eps {
+		visited[ep] = struct{}{}
+		c := candidate{ep, idx.distance(q, inv, ep)}
+		heap.Push(candidates, c)
+		if !live || !idx.nodes[ep].deleted {
+			heap.Push(found, c)
+		}
+	}
+	for found.Len() > ef {
+		heap.Pop(found)
+	}
+	for candidates.Len() > 0 {
+		c := heap.Pop(candidates).(candidate)
+		if found.Len() >= ef && c.dist > (*found)[0].dist {
+			break
+		}
+		if level >= len(idx.nodes[c.id].friends) {
+			continue
+		}
+		for _, f := range idx.nodes[c.id].friends[level] {
+			if _, ok := visited[f]; ok {
+				continue
+			}
+			visited[f] = struct{}{}
+			d := idx.distance(q, inv, f)
+			if found.Len() < ef || d < (*found)[0].dist {
+				heap.Push(candidates, candidate{f, d})
+				if !live || !idx.nodes[f].deleted {
+					heap.Push(found, candidate{f, d})
+					if found.Len() > ef {
+						heap.Pop(found)
+					}
+				}
+			}
+		}
+	}
+	ret := []candidate(*found)
+	sort.Slice(ret, func(i, j int) bool { return ret[i].dist < ret[j].dist })
+	return ret
+}
+
+// selectNeighbors picks up to m of the candidates (sorted by distance) using the heuristic of the
+// paper which prefers candidates that are closer to the query than to any already selected
+// neighbor. This keeps the graph connected across clusters. Left over slots are filled with the
+// closest pruned candidates
+func (idx *Index) selectNeighbors(candidates []candidate, m int) []candidate {
+	if len(candidates) <= m {
+		return candidates
+	}
+	selected := make([]candidate, 0, m)
+	var pruned []candidate
+	for _, c := range candidates {
+		if len(selected) == m {
+			break
+		}
+		good := true
+		for _, s := range selected {
+			if idx.distance(idx.nodes[c.id].vec, idx.nodes[c.id].inv, s.id) < c.dist {
+				good = false
+				break
+			}
+		}
+		if good {
+			selected = append(selected, c)
+		} else {
+			pruned = append(pruned, c)
+		}
+	}
+	for i := 0; len(selected) < m && i < len(pruned); i++ {
+		selected = append(selected, pruned[i])
+	}
+	return selected
+}
+
+// link adds an edge from n to friend in the given layer, pruning the neighbors of n if it now has
+// too many
+func (idx *Index) link(n, friend uint32, level int) {
+	friends := append(idx.nodes[n].friends[level], friend)
+	limit := idx.m
+	if level == 0 {
+		limit = idx.mMax0
+	}
+	if len(friends) > limit {
+		vec, inv := idx.nodes[n].vec, idx.nodes[n].inv
+		candidates := make([]candidate, len(friends))
+		for i, f := range friends {
+			candidates[i] = candidate{f, idx.distance(vec, inv, f)}
+		}
+		sort.Slice(candidates, func(i, j int) bool { return candidates[i].dist < candidates[j].dist })
+		candidates = idx.selectNeighbors(candidates, limit)
+		friends = friends[:0]
+		for _, c := range candidates {
+			friends = append(friends, c.id)
+		}
+	}
+	idx.nodes[n].friends[level] = friends
+}
+
+func min(a, b int) int {
+	if a < b {
+		return a
+	}
+	return b
+}
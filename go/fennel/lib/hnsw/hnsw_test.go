@@ -0,0 +1,159 @@
+package hnsw
+
+import (
+	"math/rand"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func randomVectors(rng *rand.Rand, n, dim int) [][]float32 {
+	ret := make([][]float32, n)
+	for i := range ret {
+		ret[i] = make([]float32, dim)
+		for j := range ret[i] {
+			ret[i][j] = rng.Float32()*2 - 1
+		}
+	}
+	return ret
+}
+
+func TestIndex_Basic(t *testing.T) {
+	t.Parallel()
+	idx, err := New(2, L2, 4, 16)
+	require.NoError(t, err)
+
+	found, err := idx.Search([]float32{0, 0}, 3, 10)
+	assert.NoError(t, err)
+	assert.Empty(t, found)
+
+	assert.NoError(t, idx.Add(1, []float32{0, 0}))
+	assert.NoError(t, idx.Add(2, []float32{1, 0}))
+	assert.NoError(t, idx.Add(3, []float32{3, 4}))
+	assert.Error(t, idx.Add(4, []float32{1, 2, 3}))
+	assert.Equal(t, 3, idx.Len())
+
+	found, err = idx.Search([]float32{0.1, 0}, 2, 10)
+	assert.NoError(t, err)
+	assert.Equal(t, []int64{1, 2}, ids(found))
+	assert.InDelta(t, 0.01, found[0].Score, 1e-6)
+
+	// re-inserting an id replaces its vector
+	assert.NoError(t, idx.Add(1, []float32{3, 3}))
+	assert.Equal(t, 3, idx.Len())
+	found, err = idx.Search([]float32{3, 4}, 3, 10)
+	assert.NoError(t, err)
+	assert.Equal(t, []int64{3, 1, 2}, ids(found))
+	exact, err := idx.Exact([]float32{3, 4}, 3)
+	assert.NoError(t, err)
+	assert.Equal(t, found, exact)
+
+	vec, ok := idx.Get(1)
+	assert.True(t, ok)
+	assert.Equal(t, []float32{3, 3}, vec)
+	_, ok = idx.Get(5)
+	assert.False(t, ok)
+
+	_, err = idx.Search([]float32{1}, 1, 10)
+	assert.Error(t, err)
+}
+
+func TestIndex_Metrics(t *testing.T) {
+	t.Parallel()
+	vecs := map[int64][]float32{1: {1, 0}, 2: {10, 1}, 3: {-1, 0}}
+	scenarios := []struct {
+		metric   Metric
+		expected []int64
+		score    float32
+	}{
+		{L2, []int64{1, 3, 2}, 0},
+		// inner product favors larger vectors
+		{IP, []int64{2, 1, 3}, 10},
+		// cosine only depends on the direction
+		{Cosine, []int64{1, 2, 3}, 1},
+	}
+	for _, scenario := range scenarios {
+		idx, err := New(2, scenario.metric, 4, 16)
+		require.NoError(t, err)
+		for id, v := range vecs {
+			assert.NoError(t, idx.Add(id, v))
+		}
+		found, err := idx.Exact([]float32{1, 0}, 3)
+		assert.NoError(t, err)
+		assert.Equal(t, scenario.expected, ids(found), scenario.metric.String())
+		assert.InDelta(t, scenario.score, found[0].Score, 1e-6, scenario.metric.String())
+		approx, err := idx.Search([]float32{1, 0}, 3, 16)
+		assert.NoError(t, err)
+		assert.Equal(t, found, approx, scenario.metric.String())
+	}
+}
+
+func TestIndex_Recall(t *testing.T) {
+	t.Parallel()
+	rng := rand.New(rand.NewSource(42))
+	dim, n, k := 16, 2000, 10
+	for _, metric := range []Metric{L2, IP, Cosine} {
+		idx, err := New(dim, metric, 16, 128)
+		require.NoError(t, err)
+		for i, v := range randomVectors(rng, n, dim) {
+			require.NoError(t, idx.Add(int64(i), v))
+		}
+		hits, total := 0, 0
+		for _, q := range randomVectors(rng, 50, dim) {
+			exact, err := idx.Exact(q, k)
+			require.NoError(t, err)
+			approx, err := idx.Search(q, k, 64)
+			require.NoError(t, err)
+			expected := make(map[int64]bool, k)
+			for _, r := range exact {
+				expected[r.ID] = true
+			}
+			for _, r := range approx {
+				if expected[r.ID] {
+					hits++
+				}
+			}
+			total += k
+		}
+		recall := float64(hits) / float64(total)
+		assert.Greater(t, recall, 0.9, "recall for %s", metric)
+	}
+}
+
+func TestIndex_MarshalBinary(t *testing.T) {
+	t.Parallel()
+	rng := rand.New(rand.NewSource(7))
+	idx, err := New(8, Cosine, 8, 32)
+	require.NoError(t, err)
+	for i, v := range randomVectors(rng, 200, 8) {
+		require.NoError(t, idx.Add(int64(i%150), v))
+	}
+	data, err := idx.MarshalBinary()
+	require.NoError(t, err)
+	loaded, err := Load(data)
+	require.NoError(t, err)
+	assert.Equal(t, idx.Len(), loaded.Len())
+	assert.Equal(t, Cosine, loaded.Metric())
+	for _, q := range randomVectors(rng, 10, 8) {
+		expected, err := idx.Search(q, 5, 32)
+		assert.NoError(t, err)
+		found, err := loaded.Search(q, 5, 32)
+		assert.NoError(t, err)
+		assert.Equal(t, expected, found)
+	}
+	// index can still be updated after loading
+	assert.NoError(t, loaded.Add(1000, make([]float32, 8)))
+	assert.Equal(t, idx.Len()+1, loaded.Len())
+
+	_, err = Load([]byte("garbage"))
+	assert.Error(t, err)
+}
+
+func ids(results []Result) []int64 {
+	ret := make([]int64, len(results))
+	for i, r := range results {
+		ret[i] = r.ID
+	}
+	return ret
+}
@@ -14,6 +14,7 @@ import (
 
 const (
 	PORT          = 2425
+	GRPC_PORT     = 2426
 	COUNTAGG_PORT = 3425
 )
 
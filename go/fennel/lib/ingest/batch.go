@@ -0,0 +1,116 @@
+package ingest
+
+import (
+	"fmt"
+	"regexp"
+)
+
+/*
+	Batch endpoints report the status of each record of a batch. Clients can send an idempotency
+	key with a batch so that retries of the batch return the result of the first attempt instead
+	of logging the records again, and can ask for a batch to be atomic so that either all of its
+	records are logged or, if any record is invalid, none of them are.
+*/
+
+const (
+	// IdempotencyKeyHeader is the header with the idempotency key of a batch
+	IdempotencyKeyHeader = "Idempotency-Key"
+	// AtomicParam is the query parameter that makes a batch all-or-nothing
+	AtomicParam = "atomic"
+)
+
+var idempotencyKeyRegex = regexp.MustCompile(`^[a-zA-Z0-9_\-:.]{1,128}$`)
+
+type Status string
+
+const (
+	// Accepted records were logged
+	Accepted Status = "accepted"
+	// Duplicate records were dropped since a record with the same dedup key was logged before
+	Duplicate Status = "duplicate"
+	// Invalid records were not logged, the reason says why
+	Invalid Status = "invalid"
+	// Skipped records were valid but not logged since the atomic batch had invalid records
+	Skipped Status = "skipped"
+)
+
+type BatchOptions struct {
+	IdempotencyKey string
+	Atomic         bool
+}
+
+func (o BatchOptions) Validate() error {
+	if len(o.IdempotencyKey) > 0 && !idempotencyKeyRegex.MatchString(o.IdempotencyKey) {
+		return fmt.Errorf("invalid idempotency key: '%s', expected upto 128 letters, digits, '_', '-', ':' or '.'", o.IdempotencyKey)
+	}
+	return nil
+}
+
+type RecordResult struct {
+	Status Status `json:"status"`
+	Reason string `json:"reason,omitempty"`
+	// PossibleDuplicate is set for accepted records whose dedup key could not be checked against
+	// the records logged by other replicas
+	PossibleDuplicate bool `json:"possible_duplicate,omitempty"`
+}
+
+// BatchResult is the status of each record of a batch, in the order of the batch. Committed is
+// false if the records of an atomic batch were not logged. Replayed is true if the result is that
+// of an earlier request with the same idempotency key.
+type BatchResult struct {
+	Committed  bool           `json:"committed"`
+	Replayed   bool           `json:"replayed,omitempty"`
+	Accepted   int            `json:"accepted"`
+	Duplicates int            `json:"duplicates"`
+	Invalid    int            `json:"invalid"`
+	Records    []RecordResult `json:"records"`
+}
+
+// NewBatchResult returns the result of a batch of n records, all of which are accepted
+func NewBatchResult(n int) BatchResult {
+	records := make([]RecordResult, n)
+	for i := range records {
+		records[i].Status = Accepted
+	}
+	return BatchResult{Committed: true, Accepted: n, Records: records}
+}
+
+func (r *BatchResult) SetInvalid(i int, err error) {
+	r.set(i, Invalid, err.Error())
+}
+
+func (r *BatchResult) SetDuplicate(i int) {
+	r.set(i, Duplicate, "")
+}
+
+func (r *BatchResult) SetPossibleDuplicate(i int) {
+	r.Records[i].PossibleDuplicate = true
+}
+
+func (r *BatchResult) set(i int, status Status, reason string) {
+	r.count(r.Records[i].Status, -1)
+	r.Records[i] = RecordResult{Status: status, Reason: reason}
+	r.count(status, 1)
+}
+
+func (r *BatchResult) count(status Status, delta int) {
+	switch status {
+	case Accepted:
+		r.Accepted += delta
+	case Duplicate:
+		r.Duplicates += delta
+	case Invalid:
+		r.Invalid += delta
+	}
+}
+
+// Abort marks the batch as not committed, its accepted records are skipped
+func (r *BatchResult) Abort() {
+	r.Committed = false
+	for i := range r.Records {
+		if r.Records[i].Status == Accepted {
+			r.Records[i] = RecordResult{Status: Skipped, Reason: "batch has invalid records"}
+		}
+	}
+	r.Accepted = 0
+}
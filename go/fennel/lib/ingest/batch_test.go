@@ -0,0 +1,35 @@
+package ingest
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBatchResult(t *testing.T) {
+	r := NewBatchResult(4)
+	assert.True(t, r.Committed)
+	assert.Equal(t, 4, r.Accepted)
+
+	r.SetInvalid(1, errors.New("bad"))
+	r.SetDuplicate(2)
+	r.SetPossibleDuplicate(3)
+	assert.Equal(t, 2, r.Accepted)
+	assert.Equal(t, 1, r.Invalid)
+	assert.Equal(t, 1, r.Duplicates)
+	assert.Equal(t, RecordResult{Status: Invalid, Reason: "bad"}, r.Records[1])
+	// possible duplicates are accepted
+	assert.Equal(t, RecordResult{Status: Accepted, PossibleDuplicate: true}, r.Records[3])
+
+	r.Abort()
+	assert.False(t, r.Committed)
+	assert.Equal(t, 0, r.Accepted)
+	assert.Equal(t, []Status{Skipped, Invalid, Duplicate, Skipped}, []Status{r.Records[0].Status, r.Records[1].Status, r.Records[2].Status, r.Records[3].Status})
+}
+
+func TestBatchOptions_Validate(t *testing.T) {
+	assert.NoError(t, BatchOptions{}.Validate())
+	assert.NoError(t, BatchOptions{IdempotencyKey: "batch-1:retry.2"}.Validate())
+	assert.Error(t, BatchOptions{IdempotencyKey: "has space"}.Validate())
+}
@@ -0,0 +1,167 @@
+package offline
+
+import (
+	"bufio"
+	"fmt"
+	"math"
+	"os"
+
+	"fennel/lib/value"
+)
+
+const (
+	NormalizeNone     = "none"
+	NormalizeIdentity = "identity"
+	NormalizeLog      = "log"
+	NormalizeSqrt     = "sqrt"
+)
+
+// CF computes item-item collaborative filtering scores from the co-occurrence of objects (group
+// keys) in contexts. Rows are values with a "context" list and a "weight". The score of o2 for o1
+// is the sum over their shared contexts of P(o1, c) / P(c) * P(o2, c) / P(o2), where P(c) and
+// P(o2) are the total weights of the context and of the object, normalized by the normalization
+// function. Objects in at most minCoOccurrence contexts are not recommended. With the "none"
+// normalization, the raw weights are multiplied instead.
+type CF struct {
+	dir             string
+	maxEntries      int
+	limit           int
+	minCoOccurrence int
+	normalization   string
+	// weights of objects in contexts keyed by pairKey of the context and the object
+	weights *Summer
+}
+
+func NewCF(dir string, maxEntries, limit, minCoOccurrence int, normalization string) (*CF, error) {
+	switch normalization {
+	case NormalizeNone, NormalizeIdentity, NormalizeLog, NormalizeSqrt:
+	default:
+		return nil, fmt.Errorf("invalid normalization function: '%s'", normalization)
+	}
+	return &CF{
+		dir:             dir,
+		maxEntries:      maxEntries,
+		limit:           limit,
+		minCoOccurrence: minCoOccurrence,
+		normalization:   normalization,
+		weights:         NewSummer(dir, maxEntries),
+	}, nil
+}
+
+func (c *CF) Add(groupkey value.Value, v value.Value) error {
+	d, ok := v.(value.Dict)
+	if !ok {
+		return fmt.Errorf("value of cf should be a dict with 'context' and 'weight' but was: %v", v)
+	}
+	context, ok := d.Get("context")
+	if !ok {
+		return fmt.Errorf("value of cf is missing 'context': %v", v)
+	}
+	weight, err := toFloat(d.GetUnsafe("weight"))
+	if err != nil {
+		return fmt.Errorf("invalid weight of cf: %w", err)
+	}
+	return c.weights.Add(pairKey(context, groupkey), weight)
+}
+
+func (c *CF) normalize(x float64) float64 {
+	switch c.normalization {
+	case NormalizeLog:
+		return math.Log(x)
+	case NormalizeSqrt:
+		return math.Sqrt(x)
+	default:
+		return x
+	}
+}
+
+func (c *CF) Emit(emit EmitFunc) error {
+	// the condensed weights are kept on disk, sorted by context, for the second pass
+	condensed, err := os.CreateTemp(c.dir, "cf-condensed-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(condensed.Name())
+	defer condensed.Close()
+	w := bufio.NewWriter(condensed)
+	objects := NewSummer(c.dir, c.maxEntries)
+	defer objects.Close()
+	err = c.weights.Iterate(func(e Entry) error {
+		_, object := splitPairKey(e.Key)
+		if err := objects.Add(object, e.Sum); err != nil {
+			return err
+		}
+		return writeEntry(w, &e)
+	})
+	if err != nil {
+		return err
+	}
+	if err = w.Flush(); err != nil {
+		return err
+	}
+	// P(o) of the objects that can be recommended
+	pObj := make(map[string]float64)
+	err = objects.Iterate(func(e Entry) error {
+		if c.normalization == NormalizeNone {
+			pObj[e.Key] = 1
+		} else if e.Count > int64(c.minCoOccurrence) {
+			pObj[e.Key] = c.normalize(e.Sum)
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	if _, err = condensed.Seek(0, 0); err != nil {
+		return err
+	}
+	scores := NewSummer(c.dir, c.maxEntries)
+	defer scores.Close()
+	r := &spillReader{r: bufio.NewReader(condensed)}
+	var context string
+	var group []scored
+	crossJoin := func() error {
+		pContext := 1.0
+		if c.normalization != NormalizeNone {
+			pContext = 0
+			for _, o := range group {
+				pContext += o.score
+			}
+		}
+		for _, o1 := range group {
+			for _, o2 := range group {
+				p, ok := pObj[o2.item]
+				// like in sql, objects with a zero normalized weight have no score
+				if !ok || p == 0 || pContext == 0 || o1.item == o2.item {
+					continue
+				}
+				if err := scores.Add(o1.item+sep+o2.item, o1.score/pContext*o2.score/p); err != nil {
+					return err
+				}
+			}
+		}
+		group = group[:0]
+		return nil
+	}
+	for {
+		ok, err := r.next()
+		if err != nil {
+			return err
+		}
+		if !ok {
+			break
+		}
+		ctx, object := splitPairKey(r.curr.Key)
+		if ctx != context {
+			if err = crossJoin(); err != nil {
+				return err
+			}
+			context = ctx
+		}
+		group = append(group, scored{item: object, score: r.curr.Sum})
+	}
+	if err = crossJoin(); err != nil {
+		return err
+	}
+	return topGroups(scores, c.limit, emit)
+}
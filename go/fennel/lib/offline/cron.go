@@ -0,0 +1,122 @@
+package offline
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Cron is a cron schedule of the form "<minute> <hour> <day of month> <month> <day of week>", in
+// UTC. Fields are '*' (or '?'), a value, a range 'a-b', a step '*/n' or 'a-b/n', or a comma
+// separated list of those.
+type Cron struct {
+	minute, hour, dom, month, dow [64]bool
+	// domAny & dowAny are set if the day of month or week is unrestricted
+	domAny, dowAny bool
+}
+
+func ParseCron(s string) (Cron, error) {
+	fields := strings.Fields(s)
+	if len(fields) != 5 {
+		return Cron{}, fmt.Errorf("invalid cron schedule: '%s', expected 5 fields", s)
+	}
+	var c Cron
+	var err error
+	parts := []struct {
+		set      *[64]bool
+		min, max int
+	}{
+		{&c.minute, 0, 59},
+		{&c.hour, 0, 23},
+		{&c.dom, 1, 31},
+		{&c.month, 1, 12},
+		{&c.dow, 0, 6},
+	}
+	for i, p := range parts {
+		if err = parseCronField(fields[i], p.min, p.max, p.set); err != nil {
+			return Cron{}, fmt.Errorf("invalid cron schedule: '%s': %w", s, err)
+		}
+	}
+	c.domAny = fields[2] == "*" || fields[2] == "?"
+	c.dowAny = fields[4] == "*" || fields[4] == "?"
+	return c, nil
+}
+
+func parseCronField(field string, min, max int, set *[64]bool) error {
+	for _, part := range strings.Split(field, ",") {
+		step := 1
+		if i := strings.Index(part, "/"); i >= 0 {
+			var err error
+			if step, err = strconv.Atoi(part[i+1:]); err != nil || step <= 0 {
+				return fmt.Errorf("invalid step: '%s'", part)
+			}
+			part = part[:i]
+		}
+		lo, hi := min, max
+		if part != "*" && part != "?" {
+			bounds := strings.SplitN(part, "-", 2)
+			var err error
+			if lo, err = strconv.Atoi(bounds[0]); err != nil {
+				return fmt.Errorf("invalid value: '%s'", part)
+			}
+			hi = lo
+			if len(bounds) == 2 {
+				if hi, err = strconv.Atoi(bounds[1]); err != nil {
+					return fmt.Errorf("invalid range: '%s'", part)
+				}
+			} else if step > 1 {
+				hi = max
+			}
+		}
+		if lo < min || hi > max || lo > hi {
+			return fmt.Errorf("'%s' is out of range [%d, %d]", part, min, max)
+		}
+		for v := lo; v <= hi; v += step {
+			set[v] = true
+		}
+	}
+	return nil
+}
+
+func (c Cron) matchesDay(t time.Time) bool {
+	dom, dow := c.dom[t.Day()], c.dow[int(t.Weekday())]
+	switch {
+	case c.domAny && c.dowAny:
+		return true
+	case c.domAny:
+		return dow
+	case c.dowAny:
+		return dom
+	default:
+		// like cron, either restricted day matches
+		return dom || dow
+	}
+}
+
+// Next returns the first time strictly after t that matches the schedule, or the zero time if no
+// time in the next 5 years does
+func (c Cron) Next(t time.Time) time.Time {
+	t = t.UTC().Truncate(time.Minute).Add(time.Minute)
+	end := t.AddDate(5, 0, 0)
+	for t.Before(end) {
+		if !c.month[int(t.Month())] {
+			t = time.Date(t.Year(), t.Month()+1, 1, 0, 0, 0, 0, time.UTC)
+			continue
+		}
+		if !c.matchesDay(t) {
+			t = time.Date(t.Year(), t.Month(), t.Day()+1, 0, 0, 0, 0, time.UTC)
+			continue
+		}
+		if !c.hour[t.Hour()] {
+			t = time.Date(t.Year(), t.Month(), t.Day(), t.Hour()+1, 0, 0, 0, time.UTC)
+			continue
+		}
+		if !c.minute[t.Minute()] {
+			t = t.Add(time.Minute)
+			continue
+		}
+		return t
+	}
+	return time.Time{}
+}
@@ -0,0 +1,71 @@
+package offline
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+
+	"fennel/lib/aggregate"
+	"fennel/lib/ftypes"
+	hp "fennel/lib/hyperparam"
+	"fennel/lib/value"
+)
+
+/*
+	Offline aggregates (topk & cf) are computed in batch on their cron schedule. The rows produced by
+	the query of an aggregate are added to an Aggregator for each duration of the aggregate, whose
+	results are then published for the phaser of the aggregate & duration to serve. Aggregators
+	keep a bounded number of entries in memory and spill the rest to disk.
+*/
+
+type OfflineArgs struct {
+	// engine that computes offline aggregates, "glue" to run pyspark jobs or "native" to compute
+	// them in countaggr
+	OfflineAggEngine string `arg:"--offline-agg-engine,env:OFFLINE_AGG_ENGINE" default:"glue" json:"offline_agg_engine,omitempty"`
+	// directory in which the native engine spills entries, the temp directory if empty
+	OfflineAggSpillDir string `arg:"--offline-agg-spill-dir,env:OFFLINE_AGG_SPILL_DIR" json:"offline_agg_spill_dir,omitempty"`
+	// number of entries each aggregator of the native engine keeps in memory before spilling
+	OfflineAggMaxEntries int `arg:"--offline-agg-max-entries,env:OFFLINE_AGG_MAX_ENTRIES" default:"1000000" json:"offline_agg_max_entries,omitempty"`
+}
+
+const NativeEngine = "native"
+
+func (a OfflineArgs) IsNative() bool {
+	return a.OfflineAggEngine == NativeEngine
+}
+
+// SupportedHyperParameters are the hyperparameters of offline aggregates
+var SupportedHyperParameters = hp.HyperParamRegistry{
+	string(aggregate.CF): map[string]hp.HyperParameterInfo{
+		"min_co_occurence":          {Default: 3, Type: reflect.Int, Options: []string{}},
+		"object_normalization_func": {Default: "sqrt", Type: reflect.String, Options: []string{NormalizeNone, NormalizeLog, NormalizeSqrt, NormalizeIdentity}},
+	},
+}
+
+// Aggregator computes an offline aggregate from the rows of its query
+type Aggregator interface {
+	Add(groupkey value.Value, v value.Value) error
+	// Emit calls emit with each key & value of the result, it can only be called once
+	Emit(emit EmitFunc) error
+}
+
+var _ Aggregator = &TopK{}
+var _ Aggregator = &CF{}
+
+// NewAggregator returns the aggregator of the aggregate type
+func NewAggregator(aggType ftypes.AggType, hyperparameters string, limit int, dir string, maxEntries int) (Aggregator, error) {
+	switch ftypes.AggType(strings.ToLower(string(aggType))) {
+	case aggregate.TOPK:
+		return NewTopK(dir, maxEntries, limit), nil
+	case aggregate.CF:
+		params, err := hp.GetHyperParameters(string(aggregate.CF), hyperparameters, SupportedHyperParameters)
+		if err != nil {
+			return nil, err
+		}
+		minCoOccurrence, _ := params["min_co_occurence"].(int)
+		normalization, _ := params["object_normalization_func"].(string)
+		return NewCF(dir, maxEntries, limit, minCoOccurrence, normalization)
+	default:
+		return nil, fmt.Errorf("unsupported offline aggregate type: '%s'", aggType)
+	}
+}
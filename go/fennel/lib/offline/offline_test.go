@@ -0,0 +1,155 @@
+package offline
+
+import (
+	"fmt"
+	"os"
+	"testing"
+	"time"
+
+	"fennel/lib/value"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSummer(t *testing.T) {
+	dir := t.TempDir()
+	s := NewSummer(dir, 3)
+	expected := make(map[string]float64)
+	for i := 0; i < 100; i++ {
+		key := fmt.Sprintf("k%02d", i%17)
+		require.NoError(t, s.Add(key, float64(i)))
+		expected[key] += float64(i)
+	}
+	assert.Greater(t, s.Spills(), 0)
+
+	var keys []string
+	var count int64
+	require.NoError(t, s.Iterate(func(e Entry) error {
+		keys = append(keys, e.Key)
+		count += e.Count
+		assert.Equal(t, expected[e.Key], e.Sum)
+		return nil
+	}))
+	assert.Len(t, keys, 17)
+	assert.IsIncreasing(t, keys)
+	assert.Equal(t, int64(100), count)
+	// spill files are removed once iterated
+	files, err := os.ReadDir(dir)
+	require.NoError(t, err)
+	assert.Empty(t, files)
+	assert.Error(t, s.Add("k", 1))
+}
+
+func TestTopK(t *testing.T) {
+	for _, maxEntries := range []int{1, 1000} {
+		topk := NewTopK(t.TempDir(), maxEntries, 2)
+		rows := []struct {
+			groupkey string
+			item     value.Value
+			score    value.Value
+		}{
+			{"u1", value.String("a"), value.Int(1)},
+			{"u1", value.String("b"), value.Double(2.5)},
+			{"u1", value.String("c"), value.Int(2)},
+			{"u1", value.String("a"), value.Int(2)},
+			{"u2", value.Int(7), value.Int(1)},
+		}
+		for _, r := range rows {
+			require.NoError(t, topk.Add(value.String(r.groupkey), value.NewDict(map[string]value.Value{"item": r.item, "score": r.score})))
+		}
+		assert.Error(t, topk.Add(value.String("u1"), value.NewDict(map[string]value.Value{"item": value.String("a")})))
+
+		found := make(map[string]value.Value)
+		require.NoError(t, topk.Emit(func(key value.Value, v value.Value) error {
+			found[key.String()] = v
+			return nil
+		}))
+		assert.Equal(t, map[string]value.Value{
+			value.String("u1").String(): value.NewList(
+				value.NewDict(map[string]value.Value{"item": value.String("a"), "score": value.Double(3)}),
+				value.NewDict(map[string]value.Value{"item": value.String("b"), "score": value.Double(2.5)}),
+			),
+			value.String("u2").String(): value.NewList(
+				value.NewDict(map[string]value.Value{"item": value.Int(7), "score": value.Double(1)}),
+			),
+		}, found)
+	}
+}
+
+func TestCF(t *testing.T) {
+	for _, maxEntries := range []int{1, 1000} {
+		cf, err := NewCF(t.TempDir(), maxEntries, 10, 0, NormalizeIdentity)
+		require.NoError(t, err)
+		rows := []struct {
+			object  string
+			context string
+			weight  value.Value
+		}{
+			{"A", "c1", value.Int(1)},
+			{"B", "c1", value.Int(1)},
+			{"A", "c2", value.Int(1)},
+			{"C", "c2", value.Double(2)},
+		}
+		for _, r := range rows {
+			v := value.NewDict(map[string]value.Value{"context": value.NewList(value.String(r.context)), "weight": r.weight})
+			require.NoError(t, cf.Add(value.String(r.object), v))
+		}
+
+		found := make(map[string][]float64)
+		require.NoError(t, cf.Emit(func(key value.Value, v value.Value) error {
+			for _, e := range v.(value.List).Values() {
+				found[key.String()] = append(found[key.String()], float64(e.(value.Dict).GetUnsafe("score").(value.Double)))
+			}
+			return nil
+		}))
+		// A & B share c1 of total weight 2, A & C share c2 of total weight 3
+		assert.Len(t, found, 3)
+		assert.InDeltaSlice(t, []float64{0.5, 1.0 / 3}, found[value.String("A").String()], 1e-9)
+		assert.InDeltaSlice(t, []float64{0.25}, found[value.String("B").String()], 1e-9)
+		assert.InDeltaSlice(t, []float64{1.0 / 3}, found[value.String("C").String()], 1e-9)
+	}
+	_, err := NewCF(t.TempDir(), 10, 10, 0, "cube")
+	assert.Error(t, err)
+}
+
+func TestNewAggregator(t *testing.T) {
+	_, err := NewAggregator("TOPK", "", 10, t.TempDir(), 10)
+	assert.NoError(t, err)
+	_, err = NewAggregator("cf", `{"min_co_occurence": 5, "object_normalization_func": "log"}`, 10, t.TempDir(), 10)
+	assert.NoError(t, err)
+	_, err = NewAggregator("cf", `{"object_normalization_func": "cube"}`, 10, t.TempDir(), 10)
+	assert.Error(t, err)
+	_, err = NewAggregator("sum", "", 10, t.TempDir(), 10)
+	assert.Error(t, err)
+}
+
+func TestCron(t *testing.T) {
+	at := func(s string) time.Time {
+		ts, err := time.Parse(time.RFC3339, s)
+		require.NoError(t, err)
+		return ts
+	}
+	scenarios := []struct {
+		cron     string
+		from     string
+		expected string
+	}{
+		{"*/15 * * * *", "2022-05-10T10:07:30Z", "2022-05-10T10:15:00Z"},
+		{"0 */6 * * *", "2022-05-10T10:07:00Z", "2022-05-10T12:00:00Z"},
+		{"30 2 * * ?", "2022-05-10T02:30:00Z", "2022-05-11T02:30:00Z"},
+		{"0 0 1 * *", "2022-12-15T00:00:00Z", "2023-01-01T00:00:00Z"},
+		// 2022-05-10 is a tuesday
+		{"0 9 * * 1,5", "2022-05-10T10:00:00Z", "2022-05-13T09:00:00Z"},
+		{"0 0 29 2 *", "2022-03-01T00:00:00Z", "2024-02-29T00:00:00Z"},
+	}
+	for _, scenario := range scenarios {
+		c, err := ParseCron(scenario.cron)
+		require.NoError(t, err, scenario.cron)
+		assert.Equal(t, at(scenario.expected), c.Next(at(scenario.from)), scenario.cron)
+	}
+	for _, invalid := range []string{"* * * *", "60 * * * *", "a * * * *", "*/0 * * * *", "5-1 * * * *"} {
+		_, err := ParseCron(invalid)
+		assert.Error(t, err, invalid)
+	}
+}
@@ -0,0 +1,226 @@
+package offline
+
+import (
+	"bufio"
+	"container/heap"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"sort"
+)
+
+// Entry is the sum of the values added for a key along with the number of values added
+type Entry struct {
+	Key   string
+	Sum   float64
+	Count int64
+}
+
+// Summer sums values by key with bounded memory. Once it holds maxEntries keys, the entries are
+// sorted and spilled to a file in dir. Iterate merges the spilled files and returns the entries in
+// the order of their keys.
+type Summer struct {
+	dir        string
+	maxEntries int
+	entries    map[string]*Entry
+	spills     []string
+	done       bool
+}
+
+func NewSummer(dir string, maxEntries int) *Summer {
+	if maxEntries <= 0 {
+		maxEntries = 1
+	}
+	return &Summer{dir: dir, maxEntries: maxEntries, entries: make(map[string]*Entry)}
+}
+
+// Add adds the value to the sum of the key
+func (s *Summer) Add(key string, v float64) error {
+	return s.add(key, v, 1)
+}
+
+func (s *Summer) add(key string, v float64, count int64) error {
+	if s.done {
+		return errors.New("summer is already iterated")
+	}
+	if e, ok := s.entries[key]; ok {
+		e.Sum += v
+		e.Count += count
+		return nil
+	}
+	if len(s.entries) >= s.maxEntries {
+		if err := s.spill(); err != nil {
+			return err
+		}
+	}
+	s.entries[key] = &Entry{Key: key, Sum: v, Count: count}
+	return nil
+}
+
+// Spills returns the number of times the entries were spilled to disk
+func (s *Summer) Spills() int {
+	return len(s.spills)
+}
+
+func (s *Summer) sorted() []*Entry {
+	sorted := make([]*Entry, 0, len(s.entries))
+	for _, e := range s.entries {
+		sorted = append(sorted, e)
+	}
+	sort.Slice(sorted, func(i, j int) bool {
+		return sorted[i].Key < sorted[j].Key
+	})
+	return sorted
+}
+
+func (s *Summer) spill() error {
+	f, err := os.CreateTemp(s.dir, "spill-*")
+	if err != nil {
+		return fmt.Errorf("failed to create spill file: %w", err)
+	}
+	defer f.Close()
+	w := bufio.NewWriter(f)
+	for _, e := range s.sorted() {
+		if err = writeEntry(w, e); err != nil {
+			return fmt.Errorf("failed to spill entries: %w", err)
+		}
+	}
+	if err = w.Flush(); err != nil {
+		return fmt.Errorf("failed to spill entries: %w", err)
+	}
+	s.spills = append(s.spills, f.Name())
+	s.entries = make(map[string]*Entry)
+	return nil
+}
+
+// Iterate calls f with the entries in the order of their keys, stopping at the first error. The
+// summer can not be used after, and its spill files are removed.
+func (s *Summer) Iterate(f func(Entry) error) error {
+	if s.done {
+		return errors.New("summer is already iterated")
+	}
+	s.done = true
+	defer s.Close()
+	if len(s.spills) == 0 {
+		for _, e := range s.sorted() {
+			if err := f(*e); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	if len(s.entries) > 0 {
+		if err := s.spill(); err != nil {
+			return err
+		}
+	}
+	s.entries = nil
+	h := make(mergeHeap, 0, len(s.spills))
+	for _, name := range s.spills {
+		file, err := os.Open(name)
+		if err != nil {
+			return err
+		}
+		defer file.Close()
+		r := &spillReader{r: bufio.NewReader(file)}
+		if ok, err := r.next(); err != nil {
+			return err
+		} else if ok {
+			h = append(h, r)
+		}
+	}
+	heap.Init(&h)
+	var curr *Entry
+	for h.Len() > 0 {
+		r := h[0]
+		e := r.curr
+		if curr != nil && curr.Key == e.Key {
+			curr.Sum += e.Sum
+			curr.Count += e.Count
+		} else {
+			if curr != nil {
+				if err := f(*curr); err != nil {
+					return err
+				}
+			}
+			curr = &e
+		}
+		if ok, err := r.next(); err != nil {
+			return err
+		} else if ok {
+			heap.Fix(&h, 0)
+		} else {
+			heap.Pop(&h)
+		}
+	}
+	if curr != nil {
+		return f(*curr)
+	}
+	return nil
+}
+
+// Close removes the spill files
+func (s *Summer) Close() {
+	for _, name := range s.spills {
+		_ = os.Remove(name)
+	}
+	s.spills = nil
+}
+
+func writeEntry(w *bufio.Writer, e *Entry) error {
+	var buf [binary.MaxVarintLen64]byte
+	n := binary.PutUvarint(buf[:], uint64(len(e.Key)))
+	if _, err := w.Write(buf[:n]); err != nil {
+		return err
+	}
+	if _, err := w.WriteString(e.Key); err != nil {
+		return err
+	}
+	var fixed [16]byte
+	binary.LittleEndian.PutUint64(fixed[:8], math.Float64bits(e.Sum))
+	binary.LittleEndian.PutUint64(fixed[8:], uint64(e.Count))
+	_, err := w.Write(fixed[:])
+	return err
+}
+
+type spillReader struct {
+	r    *bufio.Reader
+	curr Entry
+}
+
+// next reads the next entry of the spill file, it returns false at the end of the file
+func (r *spillReader) next() (bool, error) {
+	n, err := binary.ReadUvarint(r.r)
+	if err == io.EOF {
+		return false, nil
+	} else if err != nil {
+		return false, err
+	}
+	buf := make([]byte, int(n)+16)
+	if _, err = io.ReadFull(r.r, buf); err != nil {
+		return false, fmt.Errorf("failed to read spill file: %w", err)
+	}
+	r.curr = Entry{
+		Key:   string(buf[:n]),
+		Sum:   math.Float64frombits(binary.LittleEndian.Uint64(buf[n : n+8])),
+		Count: int64(binary.LittleEndian.Uint64(buf[n+8:])),
+	}
+	return true, nil
+}
+
+type mergeHeap []*spillReader
+
+func (h mergeHeap) Len() int            { return len(h) }
+func (h mergeHeap) Less(i, j int) bool  { return h[i].curr.Key < h[j].curr.Key }
+func (h mergeHeap) Swap(i, j int)       { h[i], h[j] = h[j], h[i] }
+func (h *mergeHeap) Push(x interface{}) { *h = append(*h, x.(*spillReader)) }
+func (h *mergeHeap) Pop() interface{} {
+	old := *h
+	n := len(old)
+	x := old[n-1]
+	*h = old[:n-1]
+	return x
+}
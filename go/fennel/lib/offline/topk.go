@@ -0,0 +1,146 @@
+package offline
+
+import (
+	"container/heap"
+	"fmt"
+	"sort"
+	"strings"
+
+	"fennel/lib/value"
+)
+
+// sep separates the parts of composite keys, it never occurs in json
+const sep = "\x00"
+
+// EmitFunc is called with the key and the value of each row of the result of an aggregate
+type EmitFunc func(key value.Value, v value.Value) error
+
+// TopK computes, for each group key, the items with the highest total score. Rows are values with
+// an "item" and a "score", the result of a group is a list of {"item", "score"} dicts in
+// decreasing order of score.
+type TopK struct {
+	scores *Summer
+	limit  int
+}
+
+func NewTopK(dir string, maxEntries int, limit int) *TopK {
+	return &TopK{scores: NewSummer(dir, maxEntries), limit: limit}
+}
+
+func (t *TopK) Add(groupkey value.Value, v value.Value) error {
+	d, ok := v.(value.Dict)
+	if !ok {
+		return fmt.Errorf("value of topk should be a dict with 'item' and 'score' but was: %v", v)
+	}
+	item, ok := d.Get("item")
+	if !ok {
+		return fmt.Errorf("value of topk is missing 'item': %v", v)
+	}
+	score, err := toFloat(d.GetUnsafe("score"))
+	if err != nil {
+		return fmt.Errorf("invalid score of topk: %w", err)
+	}
+	return t.scores.Add(pairKey(groupkey, item), score)
+}
+
+func (t *TopK) Emit(emit EmitFunc) error {
+	return topGroups(t.scores, t.limit, emit)
+}
+
+func pairKey(a, b value.Value) string {
+	return string(value.ToJSON(a)) + sep + string(value.ToJSON(b))
+}
+
+func splitPairKey(key string) (string, string) {
+	parts := strings.SplitN(key, sep, 2)
+	return parts[0], parts[1]
+}
+
+func toFloat(v value.Value) (float64, error) {
+	switch t := v.(type) {
+	case value.Int:
+		return float64(t), nil
+	case value.Double:
+		return float64(t), nil
+	default:
+		return 0, fmt.Errorf("expected a number but got: %v", v)
+	}
+}
+
+type scored struct {
+	item  string
+	score float64
+}
+
+// scoreHeap is a min heap of scored items, the lowest score is at the top
+type scoreHeap []scored
+
+func (h scoreHeap) Len() int { return len(h) }
+func (h scoreHeap) Less(i, j int) bool {
+	if h[i].score != h[j].score {
+		return h[i].score < h[j].score
+	}
+	return h[i].item > h[j].item
+}
+func (h scoreHeap) Swap(i, j int)       { h[i], h[j] = h[j], h[i] }
+func (h *scoreHeap) Push(x interface{}) { *h = append(*h, x.(scored)) }
+func (h *scoreHeap) Pop() interface{} {
+	old := *h
+	n := len(old)
+	x := old[n-1]
+	*h = old[:n-1]
+	return x
+}
+
+// topGroups emits the limit items with the highest scores of each group, scores is keyed by pairKey
+// of the group and the item
+func topGroups(scores *Summer, limit int, emit EmitFunc) error {
+	var group string
+	h := make(scoreHeap, 0, limit)
+	flush := func() error {
+		if len(h) == 0 {
+			return nil
+		}
+		key, err := value.FromJSON([]byte(group))
+		if err != nil {
+			return err
+		}
+		items := make([]scored, len(h))
+		copy(items, h)
+		// highest scores first, ties broken by item
+		sort.Slice(items, func(i, j int) bool {
+			return scoreHeap(items).Less(j, i)
+		})
+		values := make([]value.Value, len(items))
+		for i, s := range items {
+			item, err := value.FromJSON([]byte(s.item))
+			if err != nil {
+				return err
+			}
+			values[i] = value.NewDict(map[string]value.Value{"item": item, "score": value.Double(s.score)})
+		}
+		h = h[:0]
+		return emit(key, value.NewList(values...))
+	}
+	err := scores.Iterate(func(e Entry) error {
+		g, item := splitPairKey(e.Key)
+		if g != group {
+			if err := flush(); err != nil {
+				return err
+			}
+			group = g
+		}
+		s := scored{item: item, score: e.Sum}
+		if len(h) < limit {
+			heap.Push(&h, s)
+		} else if limit > 0 && scoreHeap([]scored{h[0], s}).Less(0, 1) {
+			h[0] = s
+			heap.Fix(&h, 0)
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	return flush()
+}
@@ -97,7 +97,7 @@ func BatchGet(tr tier.Tier, namespaces, identifiers []string, keys []value.Value
 			if err != nil {
 				return nil, err
 			}
-			val, err := value.FromJSON(deser)
+			val, err := value.FromTaggedJSON(deser)
 			if err != nil {
 				return nil, err
 			}
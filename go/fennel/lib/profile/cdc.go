@@ -0,0 +1,132 @@
+package profile
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"fennel/lib/ftypes"
+	"fennel/lib/value"
+
+	"github.com/buger/jsonparser"
+)
+
+// PROFILE_CDC_KAFKA_TOPIC is the topic to which every change applied to the profiles in the
+// database is published
+const PROFILE_CDC_KAFKA_TOPIC = "profile_cdc"
+
+// Change is a change of the value of a profile. OldValue is value.Nil and OldUpdateTime is zero if
+// the profile was not set before the change.
+type Change struct {
+	OType         ftypes.OType   `json:"OType"`
+	Oid           ftypes.OidType `json:"Oid"`
+	Key           string         `json:"Key"`
+	OldValue      value.Value    `json:"OldValue"`
+	NewValue      value.Value    `json:"NewValue"`
+	OldUpdateTime uint64         `json:"OldUpdateTime"`
+	UpdateTime    uint64         `json:"UpdateTime"`
+}
+
+func (c Change) MarshalJSON() ([]byte, error) {
+	type Change_ Change
+	c_ := Change_(c)
+	c_.OldValue = value.Clean(c.OldValue)
+	c_.NewValue = value.Clean(c.NewValue)
+	return json.Marshal(c_)
+}
+
+func (c *Change) UnmarshalJSON(data []byte) error {
+	var fields struct {
+		OType         ftypes.OType   `json:"OType"`
+		Oid           ftypes.OidType `json:"Oid"`
+		Key           string         `json:"Key"`
+		OldUpdateTime uint64         `json:"OldUpdateTime"`
+		UpdateTime    uint64         `json:"UpdateTime"`
+	}
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return fmt.Errorf("error unmarshalling profile change json: %v", err)
+	}
+	c.OType, c.Oid, c.Key = fields.OType, fields.Oid, fields.Key
+	c.OldUpdateTime, c.UpdateTime = fields.OldUpdateTime, fields.UpdateTime
+	for name, dst := range map[string]*value.Value{"OldValue": &c.OldValue, "NewValue": &c.NewValue} {
+		vdata, vtype, _, err := jsonparser.Get(data, name)
+		if err != nil {
+			return fmt.Errorf("error getting %s from profile change json: %v", name, err)
+		}
+		if *dst, err = value.ParseTaggedJSON(vdata, vtype); err != nil {
+			return fmt.Errorf("error parsing %s from profile change json: %v", name, err)
+		}
+	}
+	return nil
+}
+
+// ChangeFilter selects the changes of profiles with any of the otypes and any of the keys, an
+// empty list matches everything
+type ChangeFilter struct {
+	OTypes []ftypes.OType `json:"otypes"`
+	Keys   []string       `json:"keys"`
+}
+
+func (f ChangeFilter) Matches(c Change) bool {
+	if len(f.OTypes) > 0 {
+		found := false
+		for _, otype := range f.OTypes {
+			found = found || otype == c.OType
+		}
+		if !found {
+			return false
+		}
+	}
+	if len(f.Keys) > 0 {
+		found := false
+		for _, key := range f.Keys {
+			found = found || key == c.Key
+		}
+		if !found {
+			return false
+		}
+	}
+	return true
+}
+
+// Offsets are the positions, by partition, in the change stream. They are written as a comma
+// separated list of <partition>:<offset>.
+type Offsets map[int32]int64
+
+func (o Offsets) String() string {
+	partitions := make([]int, 0, len(o))
+	for p := range o {
+		partitions = append(partitions, int(p))
+	}
+	sort.Ints(partitions)
+	parts := make([]string, len(partitions))
+	for i, p := range partitions {
+		parts[i] = fmt.Sprintf("%d:%d", p, o[int32(p)])
+	}
+	return strings.Join(parts, ",")
+}
+
+func ParseOffsets(s string) (Offsets, error) {
+	offsets := make(Offsets)
+	if len(s) == 0 {
+		return offsets, nil
+	}
+	for _, part := range strings.Split(s, ",") {
+		po := strings.SplitN(part, ":", 2)
+		if len(po) != 2 {
+			return nil, fmt.Errorf("invalid offset: '%s', expected <partition>:<offset>", part)
+		}
+		p, err := strconv.ParseInt(po[0], 10, 32)
+		if err != nil || p < 0 {
+			return nil, fmt.Errorf("invalid partition in offset: '%s'", part)
+		}
+		o, err := strconv.ParseInt(po[1], 10, 64)
+		if err != nil || o < 0 {
+			return nil, fmt.Errorf("invalid offset: '%s'", part)
+		}
+		offsets[int32(p)] = o
+	}
+	return offsets, nil
+}
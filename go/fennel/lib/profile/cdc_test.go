@@ -0,0 +1,49 @@
+package profile
+
+import (
+	"encoding/json"
+	"testing"
+
+	"fennel/lib/ftypes"
+	"fennel/lib/value"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestChange_JSON(t *testing.T) {
+	changes := []Change{
+		{OType: "user", Oid: "1", Key: "age", OldValue: value.Nil, NewValue: value.Int(2), UpdateTime: 20},
+		{OType: "user", Oid: "1", Key: "tags", OldValue: value.NewList(value.String("a")), NewValue: value.NewDict(map[string]value.Value{"x": value.Double(1.5)}), OldUpdateTime: 10, UpdateTime: 20},
+	}
+	for _, c := range changes {
+		ser, err := json.Marshal(c)
+		assert.NoError(t, err)
+		var found Change
+		assert.NoError(t, json.Unmarshal(ser, &found))
+		assert.Equal(t, c, found)
+	}
+}
+
+func TestChangeFilter_Matches(t *testing.T) {
+	c := Change{OType: "user", Oid: "1", Key: "age"}
+	assert.True(t, ChangeFilter{}.Matches(c))
+	assert.True(t, ChangeFilter{OTypes: []ftypes.OType{"video", "user"}}.Matches(c))
+	assert.True(t, ChangeFilter{OTypes: []ftypes.OType{"user"}, Keys: []string{"age"}}.Matches(c))
+	assert.False(t, ChangeFilter{OTypes: []ftypes.OType{"video"}}.Matches(c))
+	assert.False(t, ChangeFilter{OTypes: []ftypes.OType{"user"}, Keys: []string{"name"}}.Matches(c))
+}
+
+func TestOffsets(t *testing.T) {
+	offsets, err := ParseOffsets("1:20,0:5")
+	assert.NoError(t, err)
+	assert.Equal(t, Offsets{0: 5, 1: 20}, offsets)
+	assert.Equal(t, "0:5,1:20", offsets.String())
+
+	offsets, err = ParseOffsets("")
+	assert.NoError(t, err)
+	assert.Empty(t, offsets)
+	for _, s := range []string{"1", "a:1", "1:b", "-1:2", "1:-2"} {
+		_, err = ParseOffsets(s)
+		assert.Error(t, err, s)
+	}
+}
@@ -0,0 +1,347 @@
+package profile
+
+import (
+	"bufio"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+
+	"fennel/lib/ftypes"
+	"fennel/lib/value"
+
+	"github.com/xitongsys/parquet-go/reader"
+	"github.com/xitongsys/parquet-go/source"
+	"github.com/xitongsys/parquet-go/writer"
+)
+
+// Format is the format of the files of bulk profile jobs. In all formats a row has the fields
+// OType, Oid, Key, Value and UpdateTime. Values are JSON in CSV and Parquet files.
+type Format string
+
+const (
+	JSONL   Format = "jsonl"
+	CSV     Format = "csv"
+	Parquet Format = "parquet"
+)
+
+var csvHeader = []string{"OType", "Oid", "Key", "Value", "UpdateTime"}
+
+func (f Format) Validate() error {
+	switch f {
+	case JSONL, CSV, Parquet:
+		return nil
+	default:
+		return fmt.Errorf("invalid format: '%s', expected one of '%s', '%s' or '%s'", f, JSONL, CSV, Parquet)
+	}
+}
+
+// Ext returns the file extension of the format
+func (f Format) Ext() string {
+	return "." + string(f)
+}
+
+// RowError is returned by ItemReader when a row is malformed or invalid, the rest of the file can
+// still be read
+type RowError struct {
+	Row int
+	Err error
+}
+
+func (e *RowError) Error() string {
+	return fmt.Sprintf("row %d: %v", e.Row, e.Err)
+}
+
+func (e *RowError) Unwrap() error {
+	return e.Err
+}
+
+// ItemReader reads profiles from a file. Read returns io.EOF after the last row and a *RowError for
+// rows that are malformed or fail validation, any other error means that the file can not be read.
+type ItemReader interface {
+	Read() (ProfileItem, error)
+	Close() error
+}
+
+// ItemWriter writes profiles to a file, Close must be called to flush the profiles
+type ItemWriter interface {
+	Write(item ProfileItem) error
+	Close() error
+}
+
+// NewItemReader returns a reader of the profiles in the file at path
+func NewItemReader(format Format, path string) (ItemReader, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	switch format {
+	case JSONL:
+		return &jsonlReader{f: f, s: bufio.NewScanner(f)}, nil
+	case CSV:
+		return newCSVReader(f)
+	case Parquet:
+		return newParquetReader(f, path)
+	default:
+		_ = f.Close()
+		return nil, format.Validate()
+	}
+}
+
+// NewItemWriter returns a writer of profiles to w in the format
+func NewItemWriter(format Format, w io.Writer) (ItemWriter, error) {
+	switch format {
+	case JSONL:
+		return &jsonlWriter{w: bufio.NewWriter(w)}, nil
+	case CSV:
+		cw := csv.NewWriter(w)
+		if err := cw.Write(csvHeader); err != nil {
+			return nil, err
+		}
+		return &csvWriter{w: cw}, nil
+	case Parquet:
+		pw, err := writer.NewParquetWriterFromWriter(w, new(parquetItem), 1)
+		if err != nil {
+			return nil, err
+		}
+		return &parquetWriter{w: pw}, nil
+	default:
+		return nil, format.Validate()
+	}
+}
+
+func validRow(row int, item ProfileItem) (ProfileItem, error) {
+	if err := item.Validate(); err != nil {
+		return ProfileItem{}, &RowError{row, err}
+	}
+	return item, nil
+}
+
+type jsonlReader struct {
+	f   *os.File
+	s   *bufio.Scanner
+	row int
+}
+
+func (r *jsonlReader) Read() (ProfileItem, error) {
+	for r.s.Scan() {
+		r.row++
+		line := r.s.Bytes()
+		if len(line) == 0 {
+			continue
+		}
+		var item ProfileItem
+		if err := json.Unmarshal(line, &item); err != nil {
+			return ProfileItem{}, &RowError{r.row, err}
+		}
+		return validRow(r.row, item)
+	}
+	if err := r.s.Err(); err != nil {
+		return ProfileItem{}, err
+	}
+	return ProfileItem{}, io.EOF
+}
+
+func (r *jsonlReader) Close() error {
+	return r.f.Close()
+}
+
+type jsonlWriter struct {
+	w *bufio.Writer
+}
+
+func (w *jsonlWriter) Write(item ProfileItem) error {
+	ser, err := json.Marshal(item)
+	if err != nil {
+		return err
+	}
+	if _, err = w.w.Write(ser); err != nil {
+		return err
+	}
+	return w.w.WriteByte('\n')
+}
+
+func (w *jsonlWriter) Close() error {
+	return w.w.Flush()
+}
+
+type csvReader struct {
+	f      *os.File
+	r      *csv.Reader
+	fields map[string]int
+	row    int
+}
+
+func newCSVReader(f *os.File) (*csvReader, error) {
+	r := csv.NewReader(f)
+	r.FieldsPerRecord = -1
+	header, err := r.Read()
+	if err != nil {
+		_ = f.Close()
+		return nil, fmt.Errorf("failed to read csv header: %w", err)
+	}
+	fields := make(map[string]int, len(header))
+	for i, h := range header {
+		fields[h] = i
+	}
+	for _, h := range csvHeader[:4] {
+		if _, ok := fields[h]; !ok {
+			_ = f.Close()
+			return nil, fmt.Errorf("csv header is missing the column '%s'", h)
+		}
+	}
+	return &csvReader{f: f, r: r, fields: fields}, nil
+}
+
+func (r *csvReader) Read() (ProfileItem, error) {
+	record, err := r.r.Read()
+	if err == io.EOF {
+		return ProfileItem{}, io.EOF
+	}
+	r.row++
+	if err != nil {
+		if _, ok := err.(*csv.ParseError); ok {
+			return ProfileItem{}, &RowError{r.row, err}
+		}
+		return ProfileItem{}, err
+	}
+	field := func(name string) string {
+		if i, ok := r.fields[name]; ok && i < len(record) {
+			return record[i]
+		}
+		return ""
+	}
+	v, err := value.FromJSON([]byte(field("Value")))
+	if err != nil {
+		return ProfileItem{}, &RowError{r.row, fmt.Errorf("invalid value: %w", err)}
+	}
+	var updateTime uint64
+	if ut := field("UpdateTime"); len(ut) > 0 {
+		if updateTime, err = strconv.ParseUint(ut, 10, 64); err != nil {
+			return ProfileItem{}, &RowError{r.row, fmt.Errorf("invalid update time: %w", err)}
+		}
+	}
+	item := NewProfileItem(ftypes.OType(field("OType")), ftypes.OidType(field("Oid")), field("Key"), v, updateTime)
+	return validRow(r.row, item)
+}
+
+func (r *csvReader) Close() error {
+	return r.f.Close()
+}
+
+type csvWriter struct {
+	w *csv.Writer
+}
+
+func (w *csvWriter) Write(item ProfileItem) error {
+	return w.w.Write([]string{
+		string(item.OType), string(item.Oid), item.Key,
+		string(value.ToJSON(value.Clean(item.Value))), strconv.FormatUint(item.UpdateTime, 10),
+	})
+}
+
+func (w *csvWriter) Close() error {
+	w.w.Flush()
+	return w.w.Error()
+}
+
+type parquetItem struct {
+	OType      string `parquet:"name=OType, type=BYTE_ARRAY, convertedtype=UTF8"`
+	Oid        string `parquet:"name=Oid, type=BYTE_ARRAY, convertedtype=UTF8"`
+	Key        string `parquet:"name=Key, type=BYTE_ARRAY, convertedtype=UTF8"`
+	Value      string `parquet:"name=Value, type=BYTE_ARRAY, convertedtype=UTF8"`
+	UpdateTime int64  `parquet:"name=UpdateTime, type=INT64"`
+}
+
+// parquetBatchSize is the number of rows read from parquet files at a time
+const parquetBatchSize = 1000
+
+type parquetReader struct {
+	f     *parquetFile
+	r     *reader.ParquetReader
+	batch []parquetItem
+	row   int
+	left  int64
+}
+
+func newParquetReader(f *os.File, path string) (*parquetReader, error) {
+	pf := &parquetFile{File: f, path: path}
+	r, err := reader.NewParquetReader(pf, new(parquetItem), 1)
+	if err != nil {
+		_ = f.Close()
+		return nil, fmt.Errorf("failed to read parquet file: %w", err)
+	}
+	return &parquetReader{f: pf, r: r, left: r.GetNumRows()}, nil
+}
+
+func (r *parquetReader) Read() (ProfileItem, error) {
+	if len(r.batch) == 0 {
+		if r.left == 0 {
+			return ProfileItem{}, io.EOF
+		}
+		n := r.left
+		if n > parquetBatchSize {
+			n = parquetBatchSize
+		}
+		r.batch = make([]parquetItem, n)
+		if err := r.r.Read(&r.batch); err != nil {
+			return ProfileItem{}, err
+		}
+		r.left -= n
+	}
+	p := r.batch[0]
+	r.batch = r.batch[1:]
+	r.row++
+	v, err := value.FromJSON([]byte(p.Value))
+	if err != nil {
+		return ProfileItem{}, &RowError{r.row, fmt.Errorf("invalid value: %w", err)}
+	}
+	item := NewProfileItem(ftypes.OType(p.OType), ftypes.OidType(p.Oid), p.Key, v, uint64(p.UpdateTime))
+	return validRow(r.row, item)
+}
+
+func (r *parquetReader) Close() error {
+	r.r.ReadStop()
+	return r.f.Close()
+}
+
+type parquetWriter struct {
+	w *writer.ParquetWriter
+}
+
+func (w *parquetWriter) Write(item ProfileItem) error {
+	return w.w.Write(parquetItem{
+		OType:      string(item.OType),
+		Oid:        string(item.Oid),
+		Key:        item.Key,
+		Value:      string(value.ToJSON(value.Clean(item.Value))),
+		UpdateTime: int64(item.UpdateTime),
+	})
+}
+
+func (w *parquetWriter) Close() error {
+	return w.w.WriteStop()
+}
+
+// parquetFile is a local file that can be read by the parquet reader, which opens the file again
+// to read columns concurrently
+type parquetFile struct {
+	*os.File
+	path string
+}
+
+var _ source.ParquetFile = (*parquetFile)(nil)
+
+func (f *parquetFile) Open(string) (source.ParquetFile, error) {
+	file, err := os.Open(f.path)
+	if err != nil {
+		return nil, err
+	}
+	return &parquetFile{File: file, path: f.path}, nil
+}
+
+func (f *parquetFile) Create(string) (source.ParquetFile, error) {
+	return nil, fmt.Errorf("can not create files from a parquet reader")
+}
@@ -0,0 +1,103 @@
+package profile
+
+import (
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"fennel/lib/value"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFormat_RoundTrip(t *testing.T) {
+	items := []ProfileItem{
+		NewProfileItem("user", "1", "age", value.Int(23), 100),
+		NewProfileItem("user", "2", "tags", value.NewList(value.String("a"), value.Double(1.5)), 200),
+		NewProfileItem("user", "3", "info", value.NewDict(map[string]value.Value{"x": value.Bool(true)}), 300),
+	}
+	for _, format := range []Format{JSONL, CSV, Parquet} {
+		t.Run(string(format), func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "profiles"+format.Ext())
+			f, err := os.Create(path)
+			assert.NoError(t, err)
+			w, err := NewItemWriter(format, f)
+			assert.NoError(t, err)
+			for _, item := range items {
+				assert.NoError(t, w.Write(item))
+			}
+			assert.NoError(t, w.Close())
+			assert.NoError(t, f.Close())
+
+			r, err := NewItemReader(format, path)
+			assert.NoError(t, err)
+			defer r.Close()
+			for _, expected := range items {
+				found, err := r.Read()
+				assert.NoError(t, err)
+				assert.True(t, expected.Equals(&found), "expected: %v, found: %v", expected, found)
+			}
+			_, err = r.Read()
+			assert.Equal(t, io.EOF, err)
+		})
+	}
+}
+
+func TestFormat_RowErrors(t *testing.T) {
+	dir := t.TempDir()
+	jsonl := filepath.Join(dir, "profiles.jsonl")
+	assert.NoError(t, os.WriteFile(jsonl, []byte(`{"OType":"user","Oid":"1","Key":"age","Value":1,"UpdateTime":1}
+not json
+{"OType":"user","Oid":"","Key":"age","Value":1,"UpdateTime":1}
+{"OType":"user","Oid":"2","Key":"age","Value":2,"UpdateTime":1}
+`), 0644))
+	csv := filepath.Join(dir, "profiles.csv")
+	assert.NoError(t, os.WriteFile(csv, []byte(`OType,Oid,Key,Value,UpdateTime
+user,1,age,1,1
+user,1,age,{bad,1
+user,1,,1,1
+user,2,age,2,1
+`), 0644))
+	for format, path := range map[Format]string{JSONL: jsonl, CSV: csv} {
+		t.Run(string(format), func(t *testing.T) {
+			r, err := NewItemReader(format, path)
+			assert.NoError(t, err)
+			defer r.Close()
+			var found []ProfileItem
+			var rowErrs []int
+			for {
+				item, err := r.Read()
+				if err == io.EOF {
+					break
+				}
+				var rowErr *RowError
+				if errors.As(err, &rowErr) {
+					rowErrs = append(rowErrs, rowErr.Row)
+					continue
+				}
+				assert.NoError(t, err)
+				found = append(found, item)
+			}
+			assert.Equal(t, []int{2, 3}, rowErrs)
+			assert.Len(t, found, 2)
+		})
+	}
+
+	// the csv header must have the profile columns
+	bad := filepath.Join(dir, "bad.csv")
+	assert.NoError(t, os.WriteFile(bad, []byte("OType,Oid\nuser,1\n"), 0644))
+	_, err := NewItemReader(CSV, bad)
+	assert.Error(t, err)
+}
+
+func TestJobRequest_Validate(t *testing.T) {
+	assert.NoError(t, JobRequest{Type: ImportJob, Format: CSV, Location: "/tmp/profiles.csv"}.Validate())
+	assert.NoError(t, JobRequest{Type: ExportJob, Format: Parquet, Location: "s3://bucket/export", OType: "user"}.Validate())
+	assert.Error(t, JobRequest{Type: ExportJob, Format: Parquet, Location: "s3://bucket/export"}.Validate())
+	assert.Error(t, JobRequest{Type: "copy", Format: CSV, Location: "/tmp"}.Validate())
+	assert.Error(t, JobRequest{Type: ImportJob, Format: "xml", Location: "/tmp"}.Validate())
+	assert.Error(t, JobRequest{Type: ImportJob, Format: CSV, Location: "s3://bucket"}.Validate())
+	assert.Error(t, JobRequest{Type: ImportJob, Format: CSV}.Validate())
+}
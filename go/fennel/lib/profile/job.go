@@ -0,0 +1,107 @@
+package profile
+
+import (
+	"fmt"
+	"strings"
+
+	"fennel/lib/ftypes"
+)
+
+type JobType string
+
+const (
+	ImportJob JobType = "import"
+	ExportJob JobType = "export"
+)
+
+type JobStatus string
+
+const (
+	JobPending   JobStatus = "pending"
+	JobRunning   JobStatus = "running"
+	JobSucceeded JobStatus = "succeeded"
+	JobFailed    JobStatus = "failed"
+)
+
+// MaxErrorSamples is the number of row errors that are kept on a job
+const MaxErrorSamples = 10
+
+// JobRequest is the request to start a bulk import or export of profiles. Location is either an
+// S3 location of the form "s3://<bucket>/<path>" or a path under the profile job dir of the tier. Imports read the file at the
+// location, or all files under it if it is a directory or S3 prefix, and exports write files under
+// the location.
+type JobRequest struct {
+	Type     JobType      `db:"type" json:"type"`
+	Format   Format       `db:"format" json:"format"`
+	Location string       `db:"location" json:"location"`
+	OType    ftypes.OType `db:"otype" json:"otype,omitempty"`
+}
+
+func (r JobRequest) Validate() error {
+	switch r.Type {
+	case ImportJob:
+	case ExportJob:
+		if len(r.OType) == 0 {
+			return fmt.Errorf("otype is required to export profiles")
+		}
+	default:
+		return fmt.Errorf("invalid job type: '%s', expected '%s' or '%s'", r.Type, ImportJob, ExportJob)
+	}
+	if err := r.Format.Validate(); err != nil {
+		return err
+	}
+	if len(r.Location) == 0 {
+		return fmt.Errorf("location can not be empty")
+	}
+	if strings.HasPrefix(r.Location, S3Scheme) {
+		if _, _, err := ParseS3Location(r.Location); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Job is a bulk import or export of profiles. Jobs run in the background, their progress is
+// updated as batches of rows are processed.
+type Job struct {
+	ID uint64 `db:"id" json:"id"`
+	JobRequest
+	Status JobStatus `db:"status" json:"status"`
+	// RowsRead is the number of rows read from the files (import) or the database (export)
+	RowsRead uint64 `db:"rows_read" json:"rows_read"`
+	// RowsWritten is the number of profiles that were set (import) or written to files (export)
+	RowsWritten uint64 `db:"rows_written" json:"rows_written"`
+	// RowsFailed is the number of rows that were skipped as they were malformed or invalid
+	RowsFailed   uint64   `db:"rows_failed" json:"rows_failed"`
+	ErrorSamples []string `db:"-" json:"error_samples"`
+	// Error is set when the job fails
+	Error     string `db:"error" json:"error,omitempty"`
+	CreatedAt int64  `db:"created_at" json:"created_at"`
+	UpdatedAt int64  `db:"updated_at" json:"updated_at"`
+	// LeaseUntil is when the job can be claimed again if the process running it stops renewing its lease
+	LeaseUntil int64 `db:"lease_until" json:"-"`
+}
+
+// AddError records that a row failed, keeping up to MaxErrorSamples errors
+func (j *Job) AddError(err error) {
+	j.RowsFailed++
+	if len(j.ErrorSamples) < MaxErrorSamples {
+		j.ErrorSamples = append(j.ErrorSamples, err.Error())
+	}
+}
+
+func (j *Job) Done() bool {
+	return j.Status == JobSucceeded || j.Status == JobFailed
+}
+
+// S3Scheme is the prefix of S3 locations
+const S3Scheme = "s3://"
+
+// ParseS3Location returns the bucket and path of an S3 location of the form "s3://<bucket>/<path>"
+func ParseS3Location(location string) (string, string, error) {
+	parts := strings.SplitN(strings.TrimPrefix(location, S3Scheme), "/", 2)
+	if len(parts) != 2 || len(parts[0]) == 0 {
+		return "", "", fmt.Errorf("invalid s3 location: '%s', expected s3://<bucket>/<path>", location)
+	}
+	return parts[0], parts[1], nil
+}
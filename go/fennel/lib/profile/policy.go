@@ -0,0 +1,67 @@
+package profile
+
+import (
+	"fmt"
+
+	"fennel/lib/ftypes"
+)
+
+// AnyKey is the key of policies that apply to all keys of an otype
+const AnyKey = "*"
+
+// Policy configures how long the values of profiles with an otype and key are kept. Policies with
+// AnyKey apply to every key of the otype that has no policy of its own.
+type Policy struct {
+	OType ftypes.OType `db:"otype" json:"otype"`
+	Key   string       `db:"zkey" json:"key"`
+	// HistoryRetention is how long, in seconds, old values are kept to be read as of a past time.
+	// Zero disables history.
+	HistoryRetention uint64 `db:"history_retention" json:"history_retention"`
+	// TTL is how long, in seconds, a profile is kept after its last update. Zero keeps it forever.
+	TTL uint64 `db:"ttl" json:"ttl"`
+}
+
+func (p Policy) Validate() error {
+	if len(p.OType) == 0 {
+		return fmt.Errorf("otype can not be empty")
+	}
+	if len(p.Key) == 0 {
+		return fmt.Errorf("key can not be empty, use '%s' for all keys", AnyKey)
+	}
+	if len(p.Key) > 255 || len(p.OType) > 255 {
+		return fmt.Errorf("otype and key can only be upto 255 chars")
+	}
+	return nil
+}
+
+type Policies []Policy
+
+// For returns the policy of the profile key, if any
+func (ps Policies) For(otype ftypes.OType, key string) (Policy, bool) {
+	var ret Policy
+	found := false
+	for _, p := range ps {
+		if p.OType != otype {
+			continue
+		}
+		if p.Key == key {
+			return p, true
+		}
+		if p.Key == AnyKey {
+			ret, found = p, true
+		}
+	}
+	return ret, found
+}
+
+// Overrides returns the keys of the otype that have their own policy, these are not governed by
+// the AnyKey policy of the otype
+func (ps Policies) Overrides(otype ftypes.OType) []string {
+	var keys []string
+	for _, p := range ps {
+		if p.OType == otype && p.Key != AnyKey {
+			keys = append(keys, p.Key)
+		}
+	}
+	return keys
+}
@@ -116,3 +116,28 @@ func TestProfileFromValueDict(t *testing.T) {
 		assert.Equal(t, test.p, p)
 	}
 }
+
+func TestPolicies_For(t *testing.T) {
+	ps := Policies{
+		{OType: "user", Key: AnyKey, HistoryRetention: 100},
+		{OType: "user", Key: "age", TTL: 10},
+		{OType: "video", Key: "views", HistoryRetention: 5},
+	}
+	p, ok := ps.For("user", "age")
+	assert.True(t, ok)
+	assert.Equal(t, ps[1], p)
+	p, ok = ps.For("user", "city")
+	assert.True(t, ok)
+	assert.Equal(t, ps[0], p)
+	_, ok = ps.For("video", "likes")
+	assert.False(t, ok)
+	_, ok = ps.For("post", "age")
+	assert.False(t, ok)
+
+	assert.Equal(t, []string{"age"}, ps.Overrides("user"))
+	assert.Empty(t, ps.Overrides("post"))
+
+	assert.NoError(t, ps[0].Validate())
+	assert.Error(t, Policy{OType: "user"}.Validate())
+	assert.Error(t, Policy{Key: "age"}.Validate())
+}
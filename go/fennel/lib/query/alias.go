@@ -0,0 +1,133 @@
+package query
+
+import (
+	"fmt"
+	"hash/fnv"
+	"strconv"
+
+	"fennel/lib/ftypes"
+	"fennel/lib/value"
+)
+
+// DefaultAlias is the alias used to run a stored query when no version is requested, queries
+// without this alias run their latest version
+const DefaultAlias = "prod"
+
+// Alias is a movable pointer, such as "prod", to a version of a stored query. Instead of a single
+// version an alias can split traffic between versions. Every change of an alias is kept so that it
+// can be rolled back.
+type Alias struct {
+	ID        uint64           `db:"id" json:"-"`
+	QueryName string           `db:"query_name" json:"query_name"`
+	Alias     string           `db:"alias" json:"alias"`
+	Version   uint32           `db:"version" json:"version,omitempty"`
+	UpdatedAt ftypes.Timestamp `db:"updated_at" json:"updated_at"`
+	// SplitSer is the json encoded Split
+	SplitSer []byte `db:"split_ser" json:"-"`
+	Split    *Split `db:"-" json:"split,omitempty"`
+}
+
+func (a Alias) Validate() error {
+	if len(a.QueryName) == 0 {
+		return fmt.Errorf("query name is required")
+	}
+	if len(a.Alias) == 0 {
+		return fmt.Errorf("alias is required")
+	}
+	if _, err := strconv.ParseUint(a.Alias, 10, 32); err == nil {
+		return fmt.Errorf("alias '%s' can not be a number since numbers refer to versions", a.Alias)
+	}
+	if a.Split == nil {
+		if a.Version == 0 {
+			return fmt.Errorf("alias '%s' must point to a version or split traffic between versions", a.Alias)
+		}
+		return nil
+	}
+	if a.Version != 0 {
+		return fmt.Errorf("alias '%s' can not both point to a version and split traffic", a.Alias)
+	}
+	return a.Split.Validate()
+}
+
+// Versions returns all versions the alias points to
+func (a Alias) Versions() []uint32 {
+	if a.Split == nil {
+		return []uint32{a.Version}
+	}
+	ret := make([]uint32, len(a.Split.Weights))
+	for i, w := range a.Split.Weights {
+		ret[i] = w.Version
+	}
+	return ret
+}
+
+// Choose returns the version that serves a request with the given args
+func (a Alias) Choose(args value.Dict) uint32 {
+	if a.Split == nil {
+		return a.Version
+	}
+	return a.Split.choose(a.QueryName+"/"+a.Alias, args)
+}
+
+// Split divides traffic between versions in proportion to their weights. The version serving a
+// request is picked by hashing the value of Arg (e.g. the user id) so that the same value is
+// always served by the same version. Requests without Arg are served by the first version.
+type Split struct {
+	Arg     string          `json:"arg"`
+	Weights []VersionWeight `json:"weights"`
+}
+
+type VersionWeight struct {
+	Version uint32 `json:"version"`
+	Weight  uint32 `json:"weight"`
+}
+
+func (s Split) Validate() error {
+	if len(s.Arg) == 0 {
+		return fmt.Errorf("split arg is required")
+	}
+	if len(s.Weights) == 0 {
+		return fmt.Errorf("split needs at least one version")
+	}
+	seen := make(map[uint32]struct{}, len(s.Weights))
+	total := uint64(0)
+	for _, w := range s.Weights {
+		if w.Version == 0 {
+			return fmt.Errorf("split version is required")
+		}
+		if _, ok := seen[w.Version]; ok {
+			return fmt.Errorf("version %d is in the split more than once", w.Version)
+		}
+		seen[w.Version] = struct{}{}
+		total += uint64(w.Weight)
+	}
+	if total == 0 {
+		return fmt.Errorf("split weights can not all be zero")
+	}
+	return nil
+}
+
+func (s Split) choose(salt string, args value.Dict) uint32 {
+	v, ok := args.Get(s.Arg)
+	if !ok {
+		return s.Weights[0].Version
+	}
+	total := uint64(0)
+	for _, w := range s.Weights {
+		total += uint64(w.Weight)
+	}
+	// salt with the query & alias so that different experiments split users independently
+	h := fnv.New64a()
+	_, _ = h.Write([]byte(salt))
+	_, _ = h.Write([]byte{0})
+	_, _ = h.Write(value.ToJSON(v))
+	bucket := h.Sum64() % total
+	for _, w := range s.Weights {
+		if bucket < uint64(w.Weight) {
+			return w.Version
+		}
+		bucket -= uint64(w.Weight)
+	}
+	// unreachable since bucket < total
+	return s.Weights[len(s.Weights)-1].Version
+}
@@ -0,0 +1,54 @@
+package query
+
+import (
+	"testing"
+
+	"fennel/lib/value"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAlias_Validate(t *testing.T) {
+	split := &Split{Arg: "uid", Weights: []VersionWeight{{Version: 1, Weight: 90}, {Version: 2, Weight: 10}}}
+	assert.NoError(t, Alias{QueryName: "q", Alias: "prod", Version: 1}.Validate())
+	assert.NoError(t, Alias{QueryName: "q", Alias: "prod", Split: split}.Validate())
+
+	for _, a := range []Alias{
+		{Alias: "prod", Version: 1},
+		{QueryName: "q", Version: 1},
+		{QueryName: "q", Alias: "12", Version: 1},
+		{QueryName: "q", Alias: "prod"},
+		{QueryName: "q", Alias: "prod", Version: 1, Split: split},
+		{QueryName: "q", Alias: "prod", Split: &Split{Weights: split.Weights}},
+		{QueryName: "q", Alias: "prod", Split: &Split{Arg: "uid"}},
+		{QueryName: "q", Alias: "prod", Split: &Split{Arg: "uid", Weights: []VersionWeight{{Version: 1}, {Version: 2}}}},
+		{QueryName: "q", Alias: "prod", Split: &Split{Arg: "uid", Weights: []VersionWeight{{Version: 1, Weight: 1}, {Version: 1, Weight: 1}}}},
+	} {
+		assert.Error(t, a.Validate(), a)
+	}
+}
+
+func TestAlias_Choose(t *testing.T) {
+	a := Alias{QueryName: "q", Alias: "prod", Version: 4}
+	assert.Equal(t, uint32(4), a.Choose(value.NewDict(nil)))
+
+	a = Alias{QueryName: "q", Alias: "prod", Split: &Split{
+		Arg:     "uid",
+		Weights: []VersionWeight{{Version: 1, Weight: 80}, {Version: 2, Weight: 20}, {Version: 3, Weight: 0}},
+	}}
+	counts := make(map[uint32]int)
+	n := 10000
+	for uid := 0; uid < n; uid++ {
+		args := value.NewDict(map[string]value.Value{"uid": value.Int(uid)})
+		v := a.Choose(args)
+		// choice is deterministic
+		assert.Equal(t, v, a.Choose(args))
+		counts[v]++
+	}
+	assert.InDelta(t, 0.8, float64(counts[1])/float64(n), 0.03)
+	assert.InDelta(t, 0.2, float64(counts[2])/float64(n), 0.03)
+	assert.Zero(t, counts[3])
+
+	// requests without the arg go to the first version
+	assert.Equal(t, uint32(1), a.Choose(value.NewDict(map[string]value.Value{"other": value.Int(1)})))
+}
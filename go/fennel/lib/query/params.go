@@ -0,0 +1,154 @@
+package query
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"fennel/lib/value"
+)
+
+// Param is a declared parameter of a stored query. Type is named as in value.ParseType and
+// parameters with a default are optional.
+type Param struct {
+	Name        string
+	Type        string
+	Default     value.Value
+	Description string
+}
+
+type paramJSON struct {
+	Name        string          `json:"name"`
+	Type        string          `json:"type"`
+	Default     json.RawMessage `json:"default,omitempty"`
+	Description string          `json:"description,omitempty"`
+}
+
+func (p Param) MarshalJSON() ([]byte, error) {
+	pj := paramJSON{Name: p.Name, Type: p.Type, Description: p.Description}
+	if p.Default != nil {
+		pj.Default = value.ToJSON(p.Default)
+	}
+	return json.Marshal(pj)
+}
+
+func (p *Param) UnmarshalJSON(data []byte) error {
+	var pj paramJSON
+	if err := json.Unmarshal(data, &pj); err != nil {
+		return err
+	}
+	p.Name, p.Type, p.Description, p.Default = pj.Name, pj.Type, pj.Description, nil
+	if len(pj.Default) > 0 {
+		v, err := value.FromJSON(pj.Default)
+		if err != nil {
+			return fmt.Errorf("invalid default of param '%s': %w", pj.Name, err)
+		}
+		p.Default = v
+	}
+	return nil
+}
+
+// Optional returns true if the param has a default
+func (p Param) Optional() bool {
+	return p.Default != nil
+}
+
+func (p Param) Equals(other Param) bool {
+	if p.Name != other.Name || p.Type != other.Type || p.Description != other.Description {
+		return false
+	}
+	if p.Default == nil || other.Default == nil {
+		return p.Default == nil && other.Default == nil
+	}
+	return p.Default.Equal(other.Default)
+}
+
+// Params is the signature of a stored query. Queries without params accept any args.
+type Params []Param
+
+func (ps Params) Validate() error {
+	seen := make(map[string]struct{}, len(ps))
+	for _, p := range ps {
+		if len(p.Name) == 0 {
+			return fmt.Errorf("param name is required")
+		}
+		if _, ok := seen[p.Name]; ok {
+			return fmt.Errorf("param '%s' is declared more than once", p.Name)
+		}
+		seen[p.Name] = struct{}{}
+		typ, err := value.ParseType(p.Type)
+		if err != nil {
+			return fmt.Errorf("param '%s': %w", p.Name, err)
+		}
+		if p.Default != nil {
+			if _, err = conform(typ, p.Default); err != nil {
+				return fmt.Errorf("default of param '%s': %w", p.Name, err)
+			}
+		}
+	}
+	return nil
+}
+
+func (ps Params) Equals(other Params) bool {
+	if len(ps) != len(other) {
+		return false
+	}
+	for i := range ps {
+		if !ps[i].Equals(other[i]) {
+			return false
+		}
+	}
+	return true
+}
+
+// Bind checks the args against the params and returns the args with defaults filled in. Ints are
+// converted to doubles for params of type Double since the two can't be told apart in json. Args
+// that are not declared are rejected unless the query declares no params at all.
+func (ps Params) Bind(args value.Dict) (value.Dict, error) {
+	if len(ps) == 0 {
+		return args, nil
+	}
+	declared := make(map[string]struct{}, len(ps))
+	bound := make(map[string]value.Value, len(ps))
+	for _, p := range ps {
+		declared[p.Name] = struct{}{}
+		v, ok := args.Get(p.Name)
+		if !ok {
+			if !p.Optional() {
+				return value.Dict{}, fmt.Errorf("missing required argument '%s' of type '%s'", p.Name, p.Type)
+			}
+			v = p.Default
+		}
+		typ, err := value.ParseType(p.Type)
+		if err != nil {
+			return value.Dict{}, fmt.Errorf("param '%s': %w", p.Name, err)
+		}
+		if v, err = conform(typ, v); err != nil {
+			return value.Dict{}, fmt.Errorf("invalid argument '%s': %w", p.Name, err)
+		}
+		bound[p.Name] = v
+	}
+	for k := range args.Iter() {
+		if _, ok := declared[k]; !ok {
+			return value.Dict{}, fmt.Errorf("unexpected argument '%s', query only accepts %v", k, ps.names())
+		}
+	}
+	return value.NewDict(bound), nil
+}
+
+func (ps Params) names() []string {
+	ret := make([]string, len(ps))
+	for i, p := range ps {
+		ret[i] = p.Name
+	}
+	return ret
+}
+
+func conform(typ value.Type, v value.Value) (value.Value, error) {
+	if err := typ.Validate(v); err != nil {
+		if i, ok := v.(value.Int); ok && value.TypeName(typ) == value.TypeName(value.Types.Double) {
+			return value.Double(i), nil
+		}
+		return nil, err
+	}
+	return v, nil
+}
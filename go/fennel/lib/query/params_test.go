@@ -0,0 +1,88 @@
+package query
+
+import (
+	"encoding/json"
+	"testing"
+
+	"fennel/lib/value"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParams_Validate(t *testing.T) {
+	valid := Params{
+		{Name: "uid", Type: "Int"},
+		{Name: "limit", Type: "Double", Default: value.Int(10), Description: "max items"},
+		{Name: "tags", Type: "List[String]", Default: value.NewList(value.String("a"))},
+	}
+	assert.NoError(t, valid.Validate())
+	assert.NoError(t, Params{}.Validate())
+
+	for _, ps := range []Params{
+		{{Name: "", Type: "Int"}},
+		{{Name: "x", Type: "Int"}, {Name: "x", Type: "String"}},
+		{{Name: "x", Type: "Integer"}},
+		{{Name: "x", Type: "Int", Default: value.String("1")}},
+	} {
+		assert.Error(t, ps.Validate())
+	}
+}
+
+func TestParams_Bind(t *testing.T) {
+	ps := Params{
+		{Name: "uid", Type: "Int"},
+		{Name: "limit", Type: "Double", Default: value.Double(10)},
+	}
+	scenarios := []struct {
+		args     value.Dict
+		expected value.Dict
+		err      bool
+	}{
+		{
+			value.NewDict(map[string]value.Value{"uid": value.Int(1)}),
+			value.NewDict(map[string]value.Value{"uid": value.Int(1), "limit": value.Double(10)}),
+			false,
+		},
+		{
+			value.NewDict(map[string]value.Value{"uid": value.Int(1), "limit": value.Int(5)}),
+			value.NewDict(map[string]value.Value{"uid": value.Int(1), "limit": value.Double(5)}),
+			false,
+		},
+		// missing required arg
+		{value.NewDict(map[string]value.Value{"limit": value.Int(5)}), value.Dict{}, true},
+		// wrong type
+		{value.NewDict(map[string]value.Value{"uid": value.String("1")}), value.Dict{}, true},
+		// undeclared arg
+		{value.NewDict(map[string]value.Value{"uid": value.Int(1), "other": value.Int(1)}), value.Dict{}, true},
+	}
+	for _, scenario := range scenarios {
+		found, err := ps.Bind(scenario.args)
+		if scenario.err {
+			assert.Error(t, err)
+			continue
+		}
+		assert.NoError(t, err)
+		assert.True(t, scenario.expected.Equal(found), found.String())
+	}
+
+	// queries without params accept any args
+	args := value.NewDict(map[string]value.Value{"anything": value.Nil})
+	found, err := Params{}.Bind(args)
+	assert.NoError(t, err)
+	assert.True(t, args.Equal(found))
+}
+
+func TestParams_JSON(t *testing.T) {
+	ps := Params{
+		{Name: "uid", Type: "Int"},
+		{Name: "limit", Type: "Double", Default: value.Double(2.5), Description: "max items"},
+		{Name: "tags", Type: "List[String]", Default: value.NewList(value.String("a"))},
+	}
+	ser, err := json.Marshal(ps)
+	assert.NoError(t, err)
+	var found Params
+	assert.NoError(t, json.Unmarshal(ser, &found))
+	assert.True(t, ps.Equals(found))
+
+	assert.Error(t, json.Unmarshal([]byte(`[{"name": "x", "type": "Int", "default": {"bad"}}]`), &found))
+}
@@ -17,9 +17,13 @@ import (
 type QuerySer struct {
 	QueryId     uint64           `db:"query_id" json:"id"`
 	Name        string           `db:"name" json:"name"`
+	Version     uint32           `db:"version" json:"version"`
 	Timestamp   ftypes.Timestamp `db:"timestamp" json:"timestamp"`
 	QuerySer    []byte           `db:"query_ser" json:"-"`
 	Description string           `db:"description" json:"description"`
+	// ParamsSer is the json encoded Params
+	ParamsSer []byte `db:"params_ser" json:"-"`
+	Params    Params `db:"-" json:"params,omitempty"`
 }
 
 type BoundQuery struct {
@@ -0,0 +1,53 @@
+package ratelimit
+
+import (
+	"context"
+	"time"
+
+	"fennel/redis"
+
+	goredis "github.com/go-redis/redis/v8"
+)
+
+// DailyQuota counts the units (e.g. actions & queries) used in the current UTC day. The count is
+// kept in redis so that all replicas of a service enforce the same quota.
+type DailyQuota struct {
+	client redis.Client
+}
+
+func NewDailyQuota(client redis.Client) DailyQuota {
+	return DailyQuota{client: client}
+}
+
+func (q DailyQuota) key(now time.Time) string {
+	return q.client.PrefixedName("ratelimit:quota:" + now.UTC().Format("2006-01-02"))
+}
+
+// Add adds n units to the count of the day of now
+func (q DailyQuota) Add(ctx context.Context, n int64, now time.Time) error {
+	if n <= 0 {
+		return nil
+	}
+	key := q.key(now)
+	pipe := q.client.Client().TxPipeline()
+	pipe.IncrBy(ctx, key, n)
+	// keep the count around for a day longer so that it doesn't expire while the day is still going
+	pipe.Expire(ctx, key, 48*time.Hour)
+	_, err := pipe.Exec(ctx)
+	return err
+}
+
+// Used returns the number of units used in the day of now
+func (q DailyQuota) Used(ctx context.Context, now time.Time) (int64, error) {
+	used, err := q.client.Client().Get(ctx, q.key(now)).Int64()
+	if err == goredis.Nil {
+		return 0, nil
+	}
+	return used, err
+}
+
+// UntilReset returns how long until the quota is reset at the end of the UTC day of now
+func UntilReset(now time.Time) time.Duration {
+	now = now.UTC()
+	return time.Date(now.Year(), now.Month(), now.Day()+1, 0, 0, 0, 0, time.UTC).Sub(now)
+}
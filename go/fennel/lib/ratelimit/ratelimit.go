@@ -0,0 +1,169 @@
+package ratelimit
+
+import (
+	"context"
+	"log"
+	"math"
+	"sync"
+	"time"
+)
+
+/*
+	Requests are rate limited using token buckets. A bucket holds at most Burst tokens and is
+	refilled at Rate tokens per second, every request takes one token and is rejected if the bucket
+	is empty. Buckets are kept in memory by default and can be kept in redis instead so that all
+	replicas of a service share the same buckets.
+*/
+
+type RateLimitArgs struct {
+	// requests per second allowed per api key, zero or negative means unlimited
+	LogRateLimit      float64 `arg:"--log-rate-limit,env:LOG_RATE_LIMIT" default:"0" json:"log_rate_limit,omitempty"`
+	QueryRateLimit    float64 `arg:"--query-rate-limit,env:QUERY_RATE_LIMIT" default:"0" json:"query_rate_limit,omitempty"`
+	RunQueryRateLimit float64 `arg:"--run-query-rate-limit,env:RUN_QUERY_RATE_LIMIT" default:"0" json:"run_query_rate_limit,omitempty"`
+	// number of seconds worth of requests that can be made in a burst
+	RateLimitBurstSeconds float64 `arg:"--rate-limit-burst-seconds,env:RATE_LIMIT_BURST_SECONDS" default:"10" json:"rate_limit_burst_seconds,omitempty"`
+	// if set, the buckets of the endpoints are kept in redis and shared by all replicas, the daily
+	// quota of the tier is always kept in redis
+	RateLimitRedis bool `arg:"--rate-limit-redis,env:RATE_LIMIT_REDIS" json:"rate_limit_redis,omitempty"`
+}
+
+// Limit is the rate & burst of a token bucket
+type Limit struct {
+	// tokens added per second
+	Rate float64
+	// max tokens in the bucket
+	Burst float64
+}
+
+// PerSecond returns the limit for the given requests per second allowing a burst of the given
+// number of seconds worth of requests
+func PerSecond(rate, burstSeconds float64) Limit {
+	return Limit{Rate: rate, Burst: math.Max(1, rate*burstSeconds)}
+}
+
+// PerDay returns the limit for the given requests per day, all of which can be made in a burst
+func PerDay(requests int64) Limit {
+	return Limit{Rate: float64(requests) / (24 * 60 * 60), Burst: float64(requests)}
+}
+
+func (l Limit) Unlimited() bool {
+	return l.Rate <= 0
+}
+
+// Result is the outcome of taking a token from a bucket
+type Result struct {
+	Allowed bool
+	// Limit is the size of the bucket
+	Limit int64
+	// Remaining is the number of whole tokens left in the bucket
+	Remaining int64
+	// RetryAfter is how long until a token is available, zero if the request was allowed
+	RetryAfter time.Duration
+	// Reset is how long until the bucket is full again
+	Reset time.Duration
+}
+
+func newResult(l Limit, allowed bool, tokens float64) Result {
+	r := Result{
+		Allowed:   allowed,
+		Limit:     int64(l.Burst),
+		Remaining: int64(math.Max(0, math.Floor(tokens))),
+		Reset:     seconds((l.Burst - tokens) / l.Rate),
+	}
+	if !allowed {
+		r.RetryAfter = seconds((1 - tokens) / l.Rate)
+	}
+	return r
+}
+
+func seconds(s float64) time.Duration {
+	return time.Duration(math.Ceil(math.Max(0, s) * float64(time.Second)))
+}
+
+// Store keeps the token buckets
+type Store interface {
+	// Take takes a token from the bucket with the given key
+	Take(ctx context.Context, key string, l Limit, now time.Time) (Result, error)
+}
+
+// Limiter takes tokens from the buckets of a store. If the store fails (e.g. redis is down), it
+// falls back to in-memory buckets so that requests are still limited per replica.
+type Limiter struct {
+	store    Store
+	fallback *LocalStore
+	now      func() time.Time
+}
+
+func NewLimiter(store Store, now func() time.Time) *Limiter {
+	fallback := NewLocalStore()
+	if store == nil {
+		store = fallback
+	}
+	return &Limiter{store: store, fallback: fallback, now: now}
+}
+
+// Take takes a token from the bucket with the given key, unlimited buckets always allow requests
+func (lm *Limiter) Take(ctx context.Context, key string, l Limit) Result {
+	if l.Unlimited() {
+		return Result{Allowed: true}
+	}
+	now := lm.now()
+	r, err := lm.store.Take(ctx, key, l, now)
+	if err != nil {
+		log.Printf("failed to take token from rate limit bucket '%s', using local bucket: %v", key, err)
+		r, _ = lm.fallback.Take(ctx, key, l, now)
+	}
+	return r
+}
+
+// number of buckets after which full buckets are dropped from a LocalStore
+const maxLocalBuckets = 100_000
+
+type bucket struct {
+	tokens float64
+	last   time.Time
+}
+
+// LocalStore keeps token buckets in memory
+type LocalStore struct {
+	mu      sync.Mutex
+	buckets map[string]*bucket
+}
+
+var _ Store = (*LocalStore)(nil)
+
+func NewLocalStore() *LocalStore {
+	return &LocalStore{buckets: make(map[string]*bucket)}
+}
+
+func (s *LocalStore) Take(_ context.Context, key string, l Limit, now time.Time) (Result, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	b, ok := s.buckets[key]
+	if !ok {
+		if len(s.buckets) >= maxLocalBuckets {
+			s.prune(now)
+		}
+		b = &bucket{tokens: l.Burst, last: now}
+		s.buckets[key] = b
+	}
+	if elapsed := now.Sub(b.last).Seconds(); elapsed > 0 {
+		b.tokens = math.Min(l.Burst, b.tokens+elapsed*l.Rate)
+		b.last = now
+	}
+	allowed := b.tokens >= 1
+	if allowed {
+		b.tokens -= 1
+	}
+	return newResult(l, allowed, b.tokens), nil
+}
+
+// prune drops buckets that haven't been used for a minute, these are likely full and would be
+// recreated full anyway
+func (s *LocalStore) prune(now time.Time) {
+	for k, b := range s.buckets {
+		if now.Sub(b.last) > time.Minute {
+			delete(s.buckets, k)
+		}
+	}
+}
@@ -0,0 +1,121 @@
+package ratelimit
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"fennel/lib/ftypes"
+	"fennel/redis"
+	"fennel/resource"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func testStore(t *testing.T, s Store) {
+	ctx := context.Background()
+	now := time.Unix(1000, 0)
+	l := Limit{Rate: 2, Burst: 3}
+
+	// a full bucket allows a burst of requests
+	for i := 2; i >= 0; i-- {
+		r, err := s.Take(ctx, "a", l, now)
+		require.NoError(t, err)
+		assert.True(t, r.Allowed)
+		assert.Equal(t, int64(3), r.Limit)
+		assert.Equal(t, int64(i), r.Remaining)
+	}
+	r, err := s.Take(ctx, "a", l, now)
+	require.NoError(t, err)
+	assert.False(t, r.Allowed)
+	assert.Equal(t, int64(0), r.Remaining)
+	assert.Equal(t, 500*time.Millisecond, r.RetryAfter)
+	assert.Equal(t, 1500*time.Millisecond, r.Reset)
+
+	// other buckets are not affected
+	r, err = s.Take(ctx, "b", l, now)
+	require.NoError(t, err)
+	assert.True(t, r.Allowed)
+
+	// bucket is refilled at the rate
+	r, err = s.Take(ctx, "a", l, now.Add(500*time.Millisecond))
+	require.NoError(t, err)
+	assert.True(t, r.Allowed)
+	r, err = s.Take(ctx, "a", l, now.Add(500*time.Millisecond))
+	require.NoError(t, err)
+	assert.False(t, r.Allowed)
+
+	// but never beyond the burst
+	r, err = s.Take(ctx, "a", l, now.Add(time.Hour))
+	require.NoError(t, err)
+	assert.True(t, r.Allowed)
+	assert.Equal(t, int64(2), r.Remaining)
+}
+
+func TestLocalStore(t *testing.T) {
+	testStore(t, NewLocalStore())
+}
+
+func TestRedisStore(t *testing.T) {
+	mr, err := miniredis.Run()
+	require.NoError(t, err)
+	defer mr.Close()
+	client, err := redis.MiniRedisConfig{MiniRedis: mr, Scope: resource.NewTierScope(ftypes.RealmID(1))}.Materialize()
+	require.NoError(t, err)
+	defer client.Close()
+	testStore(t, NewRedisStore(client.(redis.Client)))
+}
+
+type failingStore struct{}
+
+func (failingStore) Take(context.Context, string, Limit, time.Time) (Result, error) {
+	return Result{}, errors.New("unavailable")
+}
+
+func TestLimiter(t *testing.T) {
+	ctx := context.Background()
+	now := time.Unix(1000, 0)
+	lm := NewLimiter(failingStore{}, func() time.Time { return now })
+
+	// unlimited buckets always allow requests
+	for i := 0; i < 10; i++ {
+		assert.True(t, lm.Take(ctx, "a", Limit{}).Allowed)
+	}
+	// requests are still limited when the store fails
+	l := PerSecond(1, 2)
+	assert.True(t, lm.Take(ctx, "a", l).Allowed)
+	assert.True(t, lm.Take(ctx, "a", l).Allowed)
+	assert.False(t, lm.Take(ctx, "a", l).Allowed)
+
+	assert.Equal(t, Limit{Rate: 1, Burst: 86400}, PerDay(86400))
+	assert.Equal(t, Limit{Rate: 0.01, Burst: 1}, PerSecond(0.01, 10))
+}
+
+func TestDailyQuota(t *testing.T) {
+	ctx := context.Background()
+	mr, err := miniredis.Run()
+	require.NoError(t, err)
+	defer mr.Close()
+	client, err := redis.MiniRedisConfig{MiniRedis: mr, Scope: resource.NewTierScope(ftypes.RealmID(1))}.Materialize()
+	require.NoError(t, err)
+	q := NewDailyQuota(client.(redis.Client))
+	now := time.Date(2022, 6, 1, 23, 0, 0, 0, time.UTC)
+	used, err := q.Used(ctx, now)
+	require.NoError(t, err)
+	assert.Equal(t, int64(0), used)
+
+	require.NoError(t, q.Add(ctx, 3, now))
+	require.NoError(t, NewDailyQuota(client.(redis.Client)).Add(ctx, 2, now))
+	used, err = q.Used(ctx, now)
+	require.NoError(t, err)
+	assert.Equal(t, int64(5), used)
+	assert.Equal(t, time.Hour, UntilReset(now))
+
+	// every day has its own count
+	used, err = q.Used(ctx, now.Add(time.Hour))
+	require.NoError(t, err)
+	assert.Equal(t, int64(0), used)
+}
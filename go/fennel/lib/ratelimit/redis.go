@@ -0,0 +1,75 @@
+package ratelimit
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"time"
+
+	"fennel/redis"
+
+	goredis "github.com/go-redis/redis/v8"
+)
+
+// takeScript atomically refills the bucket at KEYS[1] and takes a token from it. Timestamps are in
+// milliseconds and the bucket expires once it would be full again.
+var takeScript = goredis.NewScript(`
+local rate = tonumber(ARGV[1])
+local burst = tonumber(ARGV[2])
+local now = tonumber(ARGV[3])
+local state = redis.call('HMGET', KEYS[1], 'tokens', 'ts')
+local tokens = tonumber(state[1])
+local ts = tonumber(state[2])
+if tokens == nil or ts == nil then
+	tokens = burst
+	ts = now
+end
+if now > ts then
+	tokens = math.min(burst, tokens + (now - ts) * rate)
+	ts = now
+end
+local allowed = 0
+if tokens >= 1 then
+	tokens = tokens - 1
+	allowed = 1
+end
+redis.call('HSET', KEYS[1], 'tokens', tostring(tokens), 'ts', tostring(ts))
+redis.call('PEXPIRE', KEYS[1], math.ceil((burst - tokens) / rate) + 1000)
+return {allowed, tostring(tokens)}
+`)
+
+// RedisStore keeps token buckets in redis so that they are shared across replicas
+type RedisStore struct {
+	client redis.Client
+}
+
+var _ Store = RedisStore{}
+
+func NewRedisStore(client redis.Client) RedisStore {
+	return RedisStore{client: client}
+}
+
+func (s RedisStore) Take(ctx context.Context, key string, l Limit, now time.Time) (Result, error) {
+	res, err := takeScript.Run(ctx, s.client.Client(), []string{s.client.PrefixedName(key)},
+		l.Rate/1000, l.Burst, now.UnixMilli()).Result()
+	if err != nil {
+		return Result{}, err
+	}
+	vals, ok := res.([]interface{})
+	if !ok || len(vals) != 2 {
+		return Result{}, fmt.Errorf("unexpected response from rate limit script: %v", res)
+	}
+	allowed, ok := vals[0].(int64)
+	if !ok {
+		return Result{}, fmt.Errorf("unexpected response from rate limit script: %v", res)
+	}
+	str, ok := vals[1].(string)
+	if !ok {
+		return Result{}, fmt.Errorf("unexpected response from rate limit script: %v", res)
+	}
+	tokens, err := strconv.ParseFloat(str, 64)
+	if err != nil {
+		return Result{}, err
+	}
+	return newResult(l, allowed == 1, tokens), nil
+}
@@ -0,0 +1,215 @@
+package replay
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// gorSeparator separates the payloads in the files written by gor's --output-file
+const gorSeparator = "\n🐵🙈🙉\n"
+
+// gorRequest is the type of payloads that are requests, other types are the responses of the requests
+const gorRequest = '1'
+
+// Request is a captured http request
+type Request struct {
+	Time   time.Time
+	Method string
+	// Path includes the query string, if any
+	Path   string
+	Header http.Header
+	Body   []byte
+}
+
+// Endpoint returns the path without the query string and the version prefix, e.g. "/query"
+func (r Request) Endpoint() string {
+	p := r.Path
+	if i := strings.IndexByte(p, '?'); i >= 0 {
+		p = p[:i]
+	}
+	for _, prefix := range []string{"/internal/v1", "/v1"} {
+		if strings.HasPrefix(p, prefix+"/") {
+			return p[len(prefix):]
+		}
+	}
+	return p
+}
+
+// ReadGor reads the requests captured by gor, calling fn with each of them in the order they were
+// captured. Payloads that are not requests are skipped.
+func ReadGor(r io.Reader, fn func(Request) error) error {
+	scanner := bufio.NewScanner(r)
+	// requests carry queries that can be large
+	scanner.Buffer(make([]byte, 0, 64*1024), 64*1024*1024)
+	scanner.Split(splitGor)
+	for scanner.Scan() {
+		payload := scanner.Bytes()
+		if len(bytes.TrimSpace(payload)) == 0 {
+			continue
+		}
+		req, ok, err := parseGorPayload(payload)
+		if err != nil {
+			return err
+		}
+		if !ok {
+			continue
+		}
+		if err := fn(req); err != nil {
+			return err
+		}
+	}
+	return scanner.Err()
+}
+
+func splitGor(data []byte, atEOF bool) (int, []byte, error) {
+	if i := bytes.Index(data, []byte(gorSeparator)); i >= 0 {
+		return i + len(gorSeparator), data[:i], nil
+	}
+	if atEOF && len(data) > 0 {
+		return len(data), data, nil
+	}
+	return 0, nil, nil
+}
+
+// parseGorPayload parses a payload, which is a header line "<type> <id> <timestamp in nanos> ..."
+// followed by the raw http message
+func parseGorPayload(payload []byte) (Request, bool, error) {
+	nl := bytes.IndexByte(payload, '\n')
+	if nl < 0 {
+		return Request{}, false, fmt.Errorf("malformed gor payload: missing header")
+	}
+	header := strings.Fields(string(payload[:nl]))
+	if len(header) < 3 {
+		return Request{}, false, fmt.Errorf("malformed gor payload header: '%s'", payload[:nl])
+	}
+	if header[0][0] != gorRequest {
+		return Request{}, false, nil
+	}
+	nanos, err := strconv.ParseInt(header[2], 10, 64)
+	if err != nil {
+		return Request{}, false, fmt.Errorf("malformed gor payload timestamp: '%s'", header[2])
+	}
+	httpReq, err := http.ReadRequest(bufio.NewReader(bytes.NewReader(payload[nl+1:])))
+	if err != nil {
+		return Request{}, false, fmt.Errorf("failed to parse captured request: %w", err)
+	}
+	defer httpReq.Body.Close()
+	body, err := io.ReadAll(httpReq.Body)
+	if err != nil {
+		return Request{}, false, fmt.Errorf("failed to read body of captured request: %w", err)
+	}
+	return Request{
+		Time:   time.Unix(0, nanos),
+		Method: httpReq.Method,
+		Path:   httpReq.URL.RequestURI(),
+		Header: httpReq.Header,
+		Body:   body,
+	}, true, nil
+}
+
+// jsonRequest is a line of the requests.jsonl format
+type jsonRequest struct {
+	// Timestamp is either in RFC3339 or in unix seconds
+	Timestamp json.RawMessage   `json:"timestamp"`
+	Method    string            `json:"method"`
+	Path      string            `json:"path"`
+	Headers   map[string]string `json:"headers,omitempty"`
+	// Body is either a string, which is sent as is, or any other json value which is sent as json
+	Body json.RawMessage `json:"body,omitempty"`
+}
+
+// ReadJSONL reads requests in the requests.jsonl format, one json object per line with the
+// timestamp, method, path, headers and body of the request, calling fn with each of them
+func ReadJSONL(r io.Reader, fn func(Request) error) error {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), 64*1024*1024)
+	line := 0
+	for scanner.Scan() {
+		line++
+		if len(bytes.TrimSpace(scanner.Bytes())) == 0 {
+			continue
+		}
+		var jr jsonRequest
+		if err := json.Unmarshal(scanner.Bytes(), &jr); err != nil {
+			return fmt.Errorf("line %d: invalid request: %w", line, err)
+		}
+		req, err := jr.toRequest()
+		if err != nil {
+			return fmt.Errorf("line %d: %w", line, err)
+		}
+		if err := fn(req); err != nil {
+			return err
+		}
+	}
+	return scanner.Err()
+}
+
+func (jr jsonRequest) toRequest() (Request, error) {
+	req := Request{Method: jr.Method, Path: jr.Path, Header: make(http.Header)}
+	if len(req.Method) == 0 {
+		req.Method = http.MethodPost
+	}
+	if len(req.Path) == 0 {
+		return Request{}, fmt.Errorf("request has no path")
+	}
+	for k, v := range jr.Headers {
+		req.Header.Set(k, v)
+	}
+	if len(jr.Timestamp) > 0 {
+		var secs int64
+		var s string
+		if err := json.Unmarshal(jr.Timestamp, &secs); err == nil {
+			req.Time = time.Unix(secs, 0)
+		} else if err := json.Unmarshal(jr.Timestamp, &s); err == nil {
+			if req.Time, err = time.Parse(time.RFC3339, s); err != nil {
+				return Request{}, fmt.Errorf("invalid timestamp: %w", err)
+			}
+		} else {
+			return Request{}, fmt.Errorf("invalid timestamp: '%s'", jr.Timestamp)
+		}
+	}
+	if len(jr.Body) > 0 {
+		var s string
+		if err := json.Unmarshal(jr.Body, &s); err == nil {
+			req.Body = []byte(s)
+		} else {
+			req.Body = jr.Body
+		}
+	}
+	return req, nil
+}
+
+// Filter selects the requests to replay
+type Filter struct {
+	// Endpoints, e.g. "/query", all endpoints are selected if empty
+	Endpoints []string
+	// From and To bound the time the requests were captured at, unbounded if zero
+	From time.Time
+	To   time.Time
+}
+
+func (f Filter) Match(r Request) bool {
+	if !f.From.IsZero() && r.Time.Before(f.From) {
+		return false
+	}
+	if !f.To.IsZero() && !r.Time.Before(f.To) {
+		return false
+	}
+	if len(f.Endpoints) == 0 {
+		return true
+	}
+	endpoint := r.Endpoint()
+	for _, e := range f.Endpoints {
+		if e == endpoint {
+			return true
+		}
+	}
+	return false
+}
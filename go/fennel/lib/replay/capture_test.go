@@ -0,0 +1,73 @@
+package replay
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestReadGor(t *testing.T) {
+	capture := strings.Join([]string{
+		"1 8a3e 1654077600000000000 0\nPOST /query HTTP/1.1\r\nHost: fennel\r\nContent-Type: application/json\r\nContent-Length: 7\r\n\r\n{\"a\":1}",
+		"2 8a3e 1654077600100000000 100\nHTTP/1.1 200 OK\r\nContent-Length: 2\r\n\r\n{}",
+		"1 8a3f 1654077601000000000 0\nGET /v1/aggregate_value?name=a HTTP/1.1\r\nHost: fennel\r\n\r\n",
+	}, gorSeparator) + gorSeparator
+
+	var reqs []Request
+	require.NoError(t, ReadGor(strings.NewReader(capture), func(r Request) error {
+		reqs = append(reqs, r)
+		return nil
+	}))
+	require.Len(t, reqs, 2)
+	assert.Equal(t, "POST", reqs[0].Method)
+	assert.Equal(t, "/query", reqs[0].Path)
+	assert.Equal(t, "/query", reqs[0].Endpoint())
+	assert.Equal(t, `{"a":1}`, string(reqs[0].Body))
+	assert.Equal(t, "application/json", reqs[0].Header.Get("Content-Type"))
+	assert.Equal(t, time.Unix(1654077600, 0), reqs[0].Time)
+	assert.Equal(t, "GET", reqs[1].Method)
+	assert.Equal(t, "/v1/aggregate_value?name=a", reqs[1].Path)
+	assert.Equal(t, "/aggregate_value", reqs[1].Endpoint())
+	assert.Empty(t, reqs[1].Body)
+
+	assert.Error(t, ReadGor(strings.NewReader("1 8a3e\nPOST /query HTTP/1.1\r\n\r\n"), func(r Request) error { return nil }))
+	assert.Error(t, ReadGor(strings.NewReader("1 8a3e 1654077600000000000 0\nnot http"), func(r Request) error { return nil }))
+}
+
+func TestReadJSONL(t *testing.T) {
+	lines := `{"timestamp": "2022-06-01T10:00:00Z", "path": "/query", "body": {"a": 1}}
+
+{"timestamp": 1654077601, "method": "GET", "path": "/v1/aggregate_value", "headers": {"Authorization": "Bearer k"}, "body": "raw"}
+`
+	var reqs []Request
+	require.NoError(t, ReadJSONL(strings.NewReader(lines), func(r Request) error {
+		reqs = append(reqs, r)
+		return nil
+	}))
+	require.Len(t, reqs, 2)
+	assert.Equal(t, Request{Time: time.Unix(1654077600, 0).UTC(), Method: "POST", Path: "/query", Header: reqs[0].Header, Body: []byte(`{"a": 1}`)}, reqs[0])
+	assert.Equal(t, time.Unix(1654077601, 0), reqs[1].Time)
+	assert.Equal(t, "GET", reqs[1].Method)
+	assert.Equal(t, "Bearer k", reqs[1].Header.Get("Authorization"))
+	assert.Equal(t, "raw", string(reqs[1].Body))
+
+	for _, invalid := range []string{`{"method": "GET"}`, `{"path": "/query", "timestamp": "yesterday"}`, `not json`} {
+		assert.Error(t, ReadJSONL(strings.NewReader(invalid), func(r Request) error { return nil }), invalid)
+	}
+}
+
+func TestFilter(t *testing.T) {
+	at := time.Unix(1654077600, 0)
+	query := Request{Time: at, Path: "/internal/v1/query"}
+	log := Request{Time: at.Add(time.Hour), Path: "/log"}
+
+	assert.True(t, Filter{}.Match(query))
+	assert.True(t, Filter{Endpoints: []string{"/query", "/run_query"}}.Match(query))
+	assert.False(t, Filter{Endpoints: []string{"/query", "/run_query"}}.Match(log))
+	assert.True(t, Filter{From: at, To: at.Add(time.Hour)}.Match(query))
+	assert.False(t, Filter{From: at, To: at.Add(time.Hour)}.Match(log))
+	assert.False(t, Filter{From: at.Add(time.Second)}.Match(query))
+}
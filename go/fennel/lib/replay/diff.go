@@ -0,0 +1,107 @@
+package replay
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"math"
+	"sort"
+)
+
+// maxDiffs is the number of differences reported per response
+const maxDiffs = 10
+
+// Diff compares two json documents and returns the differences between them, identified by their
+// path in the documents. Numbers are equal if they differ by at most tolerance, relative to the
+// larger of them when it is larger than 1. Documents that are not json are compared byte by byte.
+func Diff(a, b []byte, tolerance float64) []string {
+	var va, vb interface{}
+	if json.Unmarshal(a, &va) != nil || json.Unmarshal(b, &vb) != nil {
+		if bytes.Equal(bytes.TrimSpace(a), bytes.TrimSpace(b)) {
+			return nil
+		}
+		return []string{fmt.Sprintf("$: '%s' != '%s'", truncate(a), truncate(b))}
+	}
+	var diffs []string
+	diff("$", va, vb, tolerance, &diffs)
+	return diffs
+}
+
+func diff(path string, a, b interface{}, tolerance float64, diffs *[]string) {
+	if len(*diffs) >= maxDiffs {
+		return
+	}
+	switch a := a.(type) {
+	case float64:
+		if b, ok := b.(float64); ok {
+			if math.Abs(a-b) > tolerance*math.Max(1, math.Max(math.Abs(a), math.Abs(b))) {
+				*diffs = append(*diffs, fmt.Sprintf("%s: %v != %v", path, a, b))
+			}
+			return
+		}
+	case []interface{}:
+		if b, ok := b.([]interface{}); ok {
+			if len(a) != len(b) {
+				*diffs = append(*diffs, fmt.Sprintf("%s: length %d != %d", path, len(a), len(b)))
+				return
+			}
+			for i := range a {
+				diff(fmt.Sprintf("%s[%d]", path, i), a[i], b[i], tolerance, diffs)
+			}
+			return
+		}
+	case map[string]interface{}:
+		if b, ok := b.(map[string]interface{}); ok {
+			keys := make(map[string]struct{}, len(a)+len(b))
+			for k := range a {
+				keys[k] = struct{}{}
+			}
+			for k := range b {
+				keys[k] = struct{}{}
+			}
+			sorted := make([]string, 0, len(keys))
+			for k := range keys {
+				sorted = append(sorted, k)
+			}
+			sort.Strings(sorted)
+			for _, k := range sorted {
+				va, oka := a[k]
+				vb, okb := b[k]
+				switch {
+				case !oka:
+					*diffs = append(*diffs, fmt.Sprintf("%s.%s: missing != %s", path, k, marshal(vb)))
+				case !okb:
+					*diffs = append(*diffs, fmt.Sprintf("%s.%s: %s != missing", path, k, marshal(va)))
+				default:
+					diff(path+"."+k, va, vb, tolerance, diffs)
+				}
+				if len(*diffs) >= maxDiffs {
+					return
+				}
+			}
+			return
+		}
+	default:
+		// strings, bools and nulls
+		if a == b {
+			return
+		}
+	}
+	*diffs = append(*diffs, fmt.Sprintf("%s: %s != %s", path, marshal(a), marshal(b)))
+}
+
+func marshal(v interface{}) string {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Sprintf("%v", v)
+	}
+	return truncate(b)
+}
+
+func truncate(b []byte) string {
+	const max = 100
+	if len(b) > max {
+		return string(b[:max]) + "..."
+	}
+	return string(b)
+}
@@ -0,0 +1,277 @@
+package replay
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// DefaultCompared are the endpoints whose responses are compared by default. Other endpoints, e.g.
+// logging actions, are only replayed against the target.
+var DefaultCompared = []string{"/query", "/run_query"}
+
+// maxMismatches is the number of mismatches kept in the report
+const maxMismatches = 100
+
+// Replayer replays requests against a target tier and, for the compared endpoints, against a baseline
+// tier as well, reporting the responses that differ between them
+type Replayer struct {
+	// Target and Baseline are base urls, e.g. http://localhost:2425. Responses are not compared if
+	// Baseline is empty.
+	Target   string
+	Baseline string
+	// Rate is the number of requests replayed per second, unlimited if 0
+	Rate float64
+	// Concurrency is the maximum number of requests in flight
+	Concurrency int
+	// Tolerance is the relative difference allowed between numbers in compared responses
+	Tolerance float64
+	// Compared are the endpoints whose responses are compared
+	Compared []string
+	// Header is set on every request in addition to the captured headers, e.g. to set an api key
+	Header http.Header
+	Client *http.Client
+}
+
+// Mismatch is a request whose responses differ between the target and the baseline
+type Mismatch struct {
+	Method         string
+	Path           string
+	TargetStatus   int
+	BaselineStatus int
+	Diffs          []string
+}
+
+// Report summarizes a replay
+type Report struct {
+	Sent       int
+	Failed     int
+	Compared   int
+	Mismatched int
+	// Mismatches has upto the first 100 mismatches
+	Mismatches []Mismatch
+	// Errors has upto the first 100 errors
+	Errors []string
+
+	targetLatency   []time.Duration
+	baselineLatency []time.Duration
+	// latency of the target minus the latency of the baseline for compared requests
+	deltas []time.Duration
+}
+
+// Run replays the requests until the channel is closed or the context is cancelled
+func (r Replayer) Run(ctx context.Context, reqs <-chan Request) *Report {
+	client := r.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+	concurrency := r.Concurrency
+	if concurrency <= 0 {
+		concurrency = 1
+	}
+	var tick <-chan time.Time
+	if r.Rate > 0 {
+		ticker := time.NewTicker(time.Duration(float64(time.Second) / r.Rate))
+		defer ticker.Stop()
+		tick = ticker.C
+	}
+	report := &Report{}
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	sem := make(chan struct{}, concurrency)
+loop:
+	for {
+		var req Request
+		var ok bool
+		select {
+		case <-ctx.Done():
+			break loop
+		case req, ok = <-reqs:
+			if !ok {
+				break loop
+			}
+		}
+		if tick != nil {
+			select {
+			case <-ctx.Done():
+				break loop
+			case <-tick:
+			}
+		}
+		select {
+		case <-ctx.Done():
+			break loop
+		case sem <- struct{}{}:
+		}
+		wg.Add(1)
+		go func(req Request) {
+			defer wg.Done()
+			defer func() { <-sem }()
+			res := r.replay(ctx, client, req)
+			mu.Lock()
+			defer mu.Unlock()
+			report.add(req, res, r.Tolerance)
+		}(req)
+	}
+	wg.Wait()
+	return report
+}
+
+type response struct {
+	status  int
+	body    []byte
+	latency time.Duration
+	err     error
+}
+
+type result struct {
+	target   response
+	baseline *response
+}
+
+func (r Replayer) replay(ctx context.Context, client *http.Client, req Request) result {
+	if len(r.Baseline) == 0 || !r.compared(req) {
+		return result{target: r.send(ctx, client, r.Target, req)}
+	}
+	var res result
+	var baseline response
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		baseline = r.send(ctx, client, r.Baseline, req)
+	}()
+	res.target = r.send(ctx, client, r.Target, req)
+	wg.Wait()
+	res.baseline = &baseline
+	return res
+}
+
+func (r Replayer) compared(req Request) bool {
+	compared := r.Compared
+	if compared == nil {
+		compared = DefaultCompared
+	}
+	endpoint := req.Endpoint()
+	for _, e := range compared {
+		if e == endpoint {
+			return true
+		}
+	}
+	return false
+}
+
+func (r Replayer) send(ctx context.Context, client *http.Client, base string, req Request) response {
+	httpReq, err := http.NewRequestWithContext(ctx, req.Method, strings.TrimSuffix(base, "/")+req.Path, bytes.NewReader(req.Body))
+	if err != nil {
+		return response{err: err}
+	}
+	for k, vs := range req.Header {
+		// these are set for the new connection
+		if k == "Content-Length" || k == "Host" || k == "Connection" {
+			continue
+		}
+		httpReq.Header[k] = vs
+	}
+	for k, vs := range r.Header {
+		httpReq.Header[k] = vs
+	}
+	start := time.Now()
+	resp, err := client.Do(httpReq)
+	if err != nil {
+		return response{err: err}
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	return response{status: resp.StatusCode, body: body, latency: time.Since(start), err: err}
+}
+
+func (rp *Report) add(req Request, res result, tolerance float64) {
+	rp.Sent++
+	if res.target.err != nil {
+		rp.fail(req, "target", res.target.err)
+		return
+	}
+	rp.targetLatency = append(rp.targetLatency, res.target.latency)
+	if res.baseline == nil {
+		return
+	}
+	if res.baseline.err != nil {
+		rp.fail(req, "baseline", res.baseline.err)
+		return
+	}
+	rp.baselineLatency = append(rp.baselineLatency, res.baseline.latency)
+	rp.deltas = append(rp.deltas, res.target.latency-res.baseline.latency)
+	rp.Compared++
+	var diffs []string
+	if res.target.status != res.baseline.status {
+		diffs = []string{fmt.Sprintf("status: %d != %d", res.target.status, res.baseline.status)}
+	} else {
+		diffs = Diff(res.target.body, res.baseline.body, tolerance)
+	}
+	if len(diffs) == 0 {
+		return
+	}
+	rp.Mismatched++
+	if len(rp.Mismatches) < maxMismatches {
+		rp.Mismatches = append(rp.Mismatches, Mismatch{
+			Method:         req.Method,
+			Path:           req.Path,
+			TargetStatus:   res.target.status,
+			BaselineStatus: res.baseline.status,
+			Diffs:          diffs,
+		})
+	}
+}
+
+func (rp *Report) fail(req Request, tier string, err error) {
+	rp.Failed++
+	if len(rp.Errors) < maxMismatches {
+		rp.Errors = append(rp.Errors, fmt.Sprintf("%s %s on %s: %v", req.Method, req.Path, tier, err))
+	}
+}
+
+// Write writes a human readable summary of the report
+func (rp *Report) Write(w io.Writer) error {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "sent: %d, failed: %d, compared: %d, mismatched: %d\n", rp.Sent, rp.Failed, rp.Compared, rp.Mismatched)
+	fmt.Fprintf(&sb, "target latency:   %s\n", percentiles(rp.targetLatency))
+	if len(rp.baselineLatency) > 0 {
+		fmt.Fprintf(&sb, "baseline latency: %s\n", percentiles(rp.baselineLatency))
+		fmt.Fprintf(&sb, "latency delta (target - baseline): %s\n", percentiles(rp.deltas))
+	}
+	for _, m := range rp.Mismatches {
+		fmt.Fprintf(&sb, "mismatch: %s %s (status %d vs %d)\n", m.Method, m.Path, m.TargetStatus, m.BaselineStatus)
+		for _, d := range m.Diffs {
+			fmt.Fprintf(&sb, "\t%s\n", d)
+		}
+	}
+	for _, e := range rp.Errors {
+		fmt.Fprintf(&sb, "error: %s\n", e)
+	}
+	_, err := io.WriteString(w, sb.String())
+	return err
+}
+
+func percentiles(ds []time.Duration) string {
+	if len(ds) == 0 {
+		return "n/a"
+	}
+	sorted := append([]time.Duration{}, ds...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+	var sum time.Duration
+	for _, d := range sorted {
+		sum += d
+	}
+	at := func(p float64) time.Duration {
+		return sorted[int(p*float64(len(sorted)-1))]
+	}
+	return fmt.Sprintf("mean=%s p50=%s p90=%s p99=%s max=%s",
+		sum/time.Duration(len(sorted)), at(0.5), at(0.9), at(0.99), sorted[len(sorted)-1])
+}
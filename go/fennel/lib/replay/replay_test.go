@@ -0,0 +1,100 @@
+package replay
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDiff(t *testing.T) {
+	scenarios := []struct {
+		a, b     string
+		expected []string
+	}{
+		{`{"a": [1, 2.5, "x"], "b": null}`, `{"b": null, "a": [1, 2.5, "x"]}`, nil},
+		{`{"a": 1.0000001}`, `{"a": 1}`, nil},
+		{`{"a": 1000000.1}`, `{"a": 1000000}`, nil},
+		{`{"a": 1.1}`, `{"a": 1}`, []string{"$.a: 1.1 != 1"}},
+		{`{"a": [1, 2]}`, `{"a": [1]}`, []string{"$.a: length 2 != 1"}},
+		{`{"a": {"b": "x"}, "c": true}`, `{"a": {"b": "y"}, "d": true}`, []string{`$.a.b: "x" != "y"`, "$.c: true != missing", "$.d: missing != true"}},
+		{`[1, "1"]`, `[1, 1]`, []string{`$[1]: "1" != 1`}},
+		{`not json`, `not json`, nil},
+		{`not json`, `{}`, []string{"$: 'not json' != '{}'"}},
+	}
+	for _, scenario := range scenarios {
+		assert.Equal(t, scenario.expected, Diff([]byte(scenario.a), []byte(scenario.b), 1e-6), scenario.a)
+	}
+}
+
+func TestReplay(t *testing.T) {
+	// the baseline and the target only differ in the response to queries for 'b'
+	var targetCalls, baselineCalls int32
+	server := func(calls *int32, b string) *httptest.Server {
+		return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			atomic.AddInt32(calls, 1)
+			if r.Header.Get("Authorization") != "Bearer k" {
+				w.WriteHeader(http.StatusUnauthorized)
+				return
+			}
+			body, _ := io.ReadAll(r.Body)
+			switch string(body) {
+			case "a":
+				fmt.Fprint(w, `{"score": 0.5}`)
+			case "b":
+				fmt.Fprint(w, b)
+			default:
+				w.WriteHeader(http.StatusBadRequest)
+			}
+		}))
+	}
+	target := server(&targetCalls, `{"score": 0.7}`)
+	defer target.Close()
+	baseline := server(&baselineCalls, `{"score": 0.6}`)
+	defer baseline.Close()
+
+	reqs := make(chan Request, 4)
+	reqs <- Request{Method: "POST", Path: "/query", Body: []byte("a")}
+	reqs <- Request{Method: "POST", Path: "/v1/query", Body: []byte("b")}
+	reqs <- Request{Method: "POST", Path: "/run_query", Body: []byte("c")}
+	reqs <- Request{Method: "POST", Path: "/log", Body: []byte("a")}
+	close(reqs)
+	replayer := Replayer{
+		Target:      target.URL,
+		Baseline:    baseline.URL,
+		Rate:        1000,
+		Concurrency: 2,
+		Tolerance:   1e-6,
+		Header:      http.Header{"Authorization": []string{"Bearer k"}},
+	}
+	report := replayer.Run(context.Background(), reqs)
+	assert.Equal(t, 4, report.Sent)
+	assert.Equal(t, 0, report.Failed)
+	// logs are only sent to the target
+	assert.Equal(t, 3, report.Compared)
+	assert.Equal(t, int32(4), atomic.LoadInt32(&targetCalls))
+	assert.Equal(t, int32(3), atomic.LoadInt32(&baselineCalls))
+	assert.Equal(t, 1, report.Mismatched)
+	assert.Equal(t, []Mismatch{{
+		Method: "POST", Path: "/v1/query", TargetStatus: 200, BaselineStatus: 200, Diffs: []string{"$.score: 0.7 != 0.6"},
+	}}, report.Mismatches)
+
+	var sb strings.Builder
+	assert.NoError(t, report.Write(&sb))
+	assert.Contains(t, sb.String(), "sent: 4, failed: 0, compared: 3, mismatched: 1")
+	assert.Contains(t, sb.String(), "latency delta")
+
+	// requests to a tier that is down fail
+	reqs = make(chan Request, 1)
+	reqs <- Request{Method: "POST", Path: "/query", Body: []byte("a")}
+	close(reqs)
+	report = Replayer{Target: "http://127.0.0.1:1"}.Run(context.Background(), reqs)
+	assert.Equal(t, 1, report.Failed)
+	assert.Len(t, report.Errors, 1)
+}
@@ -0,0 +1,275 @@
+package schema
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"fennel/lib/ftypes"
+	"fennel/lib/value"
+)
+
+const (
+	ACTION  = "action"
+	PROFILE = "profile"
+
+	// records that don't satisfy the schema are rejected with an error
+	REJECT = "reject"
+	// records that don't satisfy the schema are dropped and kept aside in a quarantine table
+	QUARANTINE = "quarantine"
+)
+
+var ErrNotFound = errors.New("schema not found")
+
+// Field is a field of a dict value
+type Field struct {
+	Name     string `json:"name"`
+	Type     string `json:"type"`
+	Nullable bool   `json:"nullable"`
+	Optional bool   `json:"optional"`
+}
+
+// Schema declares the type of the metadata of actions of an action type or of the values of a
+// profile key of an otype. Types are named as in value.ParseType. Dict values can instead declare
+// their fields, in which case fields that are not declared are allowed and not checked.
+type Schema struct {
+	Target     string            `json:"target"`
+	ActionType ftypes.ActionType `json:"action_type,omitempty"`
+	OType      ftypes.OType      `json:"otype,omitempty"`
+	Key        string            `json:"key,omitempty"`
+	Type       string            `json:"type"`
+	Nullable   bool              `json:"nullable"`
+	Fields     []Field           `json:"fields,omitempty"`
+	OnInvalid  string            `json:"on_invalid"`
+	Version    uint64            `json:"version"`
+}
+
+// SchemaSer is the serialized schema as stored in the db
+type SchemaSer struct {
+	Name        string `db:"name"`
+	Version     uint64 `db:"version"`
+	SchemaSer   []byte `db:"schema_ser"`
+	LastUpdated string `db:"last_updated"`
+}
+
+// QuarantinedRecord is a record that was dropped because it did not satisfy its schema
+type QuarantinedRecord struct {
+	ID        uint64           `db:"id" json:"id"`
+	Name      string           `db:"name" json:"name"`
+	Record    string           `db:"record" json:"record"`
+	Error     string           `db:"error" json:"error"`
+	Timestamp ftypes.Timestamp `db:"timestamp" json:"timestamp"`
+}
+
+func ActionSchemaName(actionType ftypes.ActionType) string {
+	return ACTION + "/" + string(actionType)
+}
+
+func ProfileSchemaName(otype ftypes.OType, key string) string {
+	return PROFILE + "/" + string(otype) + "/" + key
+}
+
+// Name returns the name that identifies what the schema applies to
+func (s Schema) Name() string {
+	if s.Target == ACTION {
+		return ActionSchemaName(s.ActionType)
+	}
+	return ProfileSchemaName(s.OType, s.Key)
+}
+
+// Validate checks that the schema is well-formed
+func (s Schema) Validate() error {
+	switch s.Target {
+	case ACTION:
+		if len(s.ActionType) == 0 {
+			return fmt.Errorf("action_type is required for action schemas")
+		}
+		if len(s.OType) > 0 || len(s.Key) > 0 {
+			return fmt.Errorf("otype and key should not be set for action schemas")
+		}
+	case PROFILE:
+		if len(s.OType) == 0 || len(s.Key) == 0 {
+			return fmt.Errorf("otype and key are required for profile schemas")
+		}
+		if len(s.ActionType) > 0 {
+			return fmt.Errorf("action_type should not be set for profile schemas")
+		}
+	default:
+		return fmt.Errorf("invalid target: '%s', should be one of %v", s.Target, []string{ACTION, PROFILE})
+	}
+	if s.OnInvalid != "" && s.OnInvalid != REJECT && s.OnInvalid != QUARANTINE {
+		return fmt.Errorf("invalid on_invalid: '%s', should be one of %v", s.OnInvalid, []string{REJECT, QUARANTINE})
+	}
+	if len(s.Fields) > 0 {
+		if s.Type != "" && !strings.EqualFold(s.Type, "dict") {
+			return fmt.Errorf("fields can only be declared for values of type Dict but type is '%s'", s.Type)
+		}
+		seen := make(map[string]struct{}, len(s.Fields))
+		for _, f := range s.Fields {
+			if len(f.Name) == 0 {
+				return fmt.Errorf("field name is required")
+			}
+			if _, ok := seen[f.Name]; ok {
+				return fmt.Errorf("field '%s' is declared more than once", f.Name)
+			}
+			seen[f.Name] = struct{}{}
+			if _, err := value.ParseType(f.Type); err != nil {
+				return fmt.Errorf("field '%s': %w", f.Name, err)
+			}
+		}
+		return nil
+	}
+	if len(s.Type) == 0 {
+		return fmt.Errorf("either type or fields are required")
+	}
+	_, err := value.ParseType(s.Type)
+	return err
+}
+
+// Quarantine returns true if records that don't satisfy the schema should be quarantined instead of rejected
+func (s Schema) Quarantine() bool {
+	return s.OnInvalid == QUARANTINE
+}
+
+// Check returns an error if the value does not satisfy the schema. Ints are accepted where doubles
+// are expected since the two can't be told apart in json (e.g. 1.0 is parsed as an int)
+func (s Schema) Check(v value.Value) error {
+	if v == value.Nil {
+		if s.Nullable {
+			return nil
+		}
+		return fmt.Errorf("value can not be null")
+	}
+	if len(s.Fields) == 0 {
+		return checkType(s.Type, v)
+	}
+	d, ok := v.(value.Dict)
+	if !ok {
+		return fmt.Errorf("expected a dict but found '%s'", v)
+	}
+	for _, f := range s.Fields {
+		fv, ok := d.Get(f.Name)
+		if !ok {
+			if f.Optional {
+				continue
+			}
+			return fmt.Errorf("required field '%s' is missing", f.Name)
+		}
+		if fv == value.Nil {
+			if f.Nullable {
+				continue
+			}
+			return fmt.Errorf("field '%s' can not be null", f.Name)
+		}
+		if err := checkType(f.Type, fv); err != nil {
+			return fmt.Errorf("field '%s': %w", f.Name, err)
+		}
+	}
+	return nil
+}
+
+func checkType(name string, v value.Value) error {
+	typ, err := value.ParseType(name)
+	if err != nil {
+		return err
+	}
+	if err = typ.Validate(v); err != nil {
+		if _, ok := v.(value.Int); ok && value.TypeName(typ) == value.TypeName(value.Types.Double) {
+			return nil
+		}
+		return err
+	}
+	return nil
+}
+
+// CheckEvolution returns an error if the schema can not be updated to next. Updates must not make
+// records that satisfy the schema invalid: fields can be added only if they are optional, types can
+// only be widened (e.g. Int to Double) and fields can be made optional or nullable but not the reverse.
+func (s Schema) CheckEvolution(next Schema) error {
+	if s.Name() != next.Name() {
+		return fmt.Errorf("schema of '%s' can not be updated to a schema of '%s'", s.Name(), next.Name())
+	}
+	if s.Nullable && !next.Nullable {
+		return fmt.Errorf("value can not be made non-nullable")
+	}
+	if len(next.Fields) == 0 {
+		if !widens(s.valueType(), next.valueType()) {
+			return fmt.Errorf("type can not be changed from '%s' to '%s'", s.valueType(), next.valueType())
+		}
+		return nil
+	}
+	if len(s.Fields) == 0 {
+		return fmt.Errorf("fields can not be declared for values of an existing schema without fields")
+	}
+	nextFields := make(map[string]Field, len(next.Fields))
+	for _, f := range next.Fields {
+		nextFields[f.Name] = f
+	}
+	for _, f := range s.Fields {
+		nf, ok := nextFields[f.Name]
+		if !ok {
+			return fmt.Errorf("field '%s' can not be removed", f.Name)
+		}
+		delete(nextFields, f.Name)
+		if !widens(f.Type, nf.Type) {
+			return fmt.Errorf("type of field '%s' can not be changed from '%s' to '%s'", f.Name, f.Type, nf.Type)
+		}
+		if f.Optional && !nf.Optional {
+			return fmt.Errorf("field '%s' can not be made required", f.Name)
+		}
+		if f.Nullable && !nf.Nullable {
+			return fmt.Errorf("field '%s' can not be made non-nullable", f.Name)
+		}
+	}
+	for _, f := range next.Fields {
+		if _, ok := nextFields[f.Name]; ok && !f.Optional {
+			return fmt.Errorf("new field '%s' should be optional", f.Name)
+		}
+	}
+	return nil
+}
+
+func (s Schema) valueType() string {
+	if len(s.Fields) > 0 {
+		return "Dict"
+	}
+	return s.Type
+}
+
+// widens returns true if every value of type 'from' is also a value of type 'to'
+func widens(from, to string) bool {
+	ft, err := value.ParseType(from)
+	if err != nil {
+		return false
+	}
+	tt, err := value.ParseType(to)
+	if err != nil {
+		return false
+	}
+	return widensType(value.TypeName(ft), value.TypeName(tt))
+}
+
+func widensType(from, to string) bool {
+	if from == to || to == "Any" {
+		return true
+	}
+	switch from {
+	case "Int":
+		return to == "Double" || to == "Number" || to == "ID"
+	case "Double":
+		return to == "Number"
+	case "String":
+		return to == "ID"
+	}
+	for _, prefix := range []string{"List", "Dict"} {
+		if strings.HasPrefix(from, prefix+"[") {
+			if to == prefix {
+				return true
+			}
+			if strings.HasPrefix(to, prefix+"[") {
+				return widensType(from[len(prefix)+1:len(from)-1], to[len(prefix)+1:len(to)-1])
+			}
+		}
+	}
+	return false
+}
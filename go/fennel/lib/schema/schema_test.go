@@ -0,0 +1,146 @@
+package schema
+
+import (
+	"testing"
+
+	"fennel/lib/value"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func clickSchema(fields ...Field) Schema {
+	return Schema{Target: ACTION, ActionType: "click", Fields: fields}
+}
+
+func TestSchema_Validate(t *testing.T) {
+	valid := []Schema{
+		clickSchema(Field{Name: "x", Type: "Int"}),
+		{Target: PROFILE, OType: "user", Key: "age", Type: "int", OnInvalid: QUARANTINE},
+		{Target: PROFILE, OType: "user", Key: "tags", Type: "List[String]", Nullable: true},
+		{Target: ACTION, ActionType: "view", Type: "Dict", Fields: []Field{{Name: "x", Type: "Any"}}},
+	}
+	for _, s := range valid {
+		assert.NoError(t, s.Validate(), s)
+	}
+	invalid := []Schema{
+		{Target: "item", ActionType: "click", Type: "Int"},
+		{Target: ACTION, Type: "Int"},
+		{Target: ACTION, ActionType: "click", OType: "user", Type: "Int"},
+		{Target: PROFILE, OType: "user", Type: "Int"},
+		{Target: PROFILE, OType: "user", Key: "age", ActionType: "click", Type: "Int"},
+		{Target: PROFILE, OType: "user", Key: "age"},
+		{Target: PROFILE, OType: "user", Key: "age", Type: "float"},
+		{Target: PROFILE, OType: "user", Key: "age", Type: "Int", OnInvalid: "drop"},
+		{Target: ACTION, ActionType: "click", Type: "List", Fields: []Field{{Name: "x", Type: "Int"}}},
+		clickSchema(Field{Name: "", Type: "Int"}),
+		clickSchema(Field{Name: "x", Type: "Int"}, Field{Name: "x", Type: "String"}),
+		clickSchema(Field{Name: "x", Type: "Integer"}),
+	}
+	for _, s := range invalid {
+		assert.Error(t, s.Validate(), s)
+	}
+	assert.Equal(t, "action/click", clickSchema().Name())
+	assert.Equal(t, "profile/user/age", Schema{Target: PROFILE, OType: "user", Key: "age"}.Name())
+}
+
+func TestSchema_Check(t *testing.T) {
+	s := clickSchema(
+		Field{Name: "price", Type: "Double"},
+		Field{Name: "item", Type: "ID", Optional: true},
+		Field{Name: "tags", Type: "List[String]", Nullable: true},
+	)
+	d := func(m map[string]value.Value) value.Value {
+		return value.NewDict(m)
+	}
+	valid := []value.Value{
+		d(map[string]value.Value{"price": value.Double(1.5), "tags": value.NewList()}),
+		// ints are accepted as doubles
+		d(map[string]value.Value{"price": value.Int(1), "tags": value.Nil, "item": value.String("abc")}),
+		// undeclared fields are not checked
+		d(map[string]value.Value{"price": value.Int(1), "tags": value.NewList(value.String("a")), "other": value.Bool(true)}),
+	}
+	for _, v := range valid {
+		assert.NoError(t, s.Check(v), v.String())
+	}
+	invalid := []value.Value{
+		value.Nil,
+		value.NewList(),
+		d(map[string]value.Value{"tags": value.NewList()}),
+		d(map[string]value.Value{"price": value.String("1.5"), "tags": value.NewList()}),
+		d(map[string]value.Value{"price": value.Nil, "tags": value.NewList()}),
+		d(map[string]value.Value{"price": value.Double(1), "tags": value.NewList(value.Int(1))}),
+		d(map[string]value.Value{"price": value.Double(1), "tags": value.Nil, "item": value.Double(1)}),
+		d(map[string]value.Value{"price": value.Double(1), "tags": value.Nil, "item": value.Nil}),
+	}
+	for _, v := range invalid {
+		assert.Error(t, s.Check(v), v.String())
+	}
+
+	s = Schema{Target: PROFILE, OType: "user", Key: "age", Type: "Int", Nullable: true}
+	assert.NoError(t, s.Check(value.Int(3)))
+	assert.NoError(t, s.Check(value.Nil))
+	assert.Error(t, s.Check(value.Double(3.5)))
+}
+
+func TestSchema_CheckEvolution(t *testing.T) {
+	base := clickSchema(
+		Field{Name: "x", Type: "Int"},
+		Field{Name: "y", Type: "String", Optional: true, Nullable: true},
+	)
+	compatible := []Schema{
+		base,
+		// add an optional field
+		clickSchema(base.Fields[0], base.Fields[1], Field{Name: "z", Type: "Bool", Optional: true}),
+		// widen int to double
+		clickSchema(Field{Name: "x", Type: "Double"}, base.Fields[1]),
+		clickSchema(Field{Name: "x", Type: "Number"}, base.Fields[1]),
+		// make a field optional or nullable
+		clickSchema(Field{Name: "x", Type: "Int", Optional: true, Nullable: true}, base.Fields[1]),
+		// change the order of fields
+		clickSchema(base.Fields[1], base.Fields[0]),
+		// value is a dict
+		{Target: ACTION, ActionType: "click", Type: "Dict"},
+		{Target: ACTION, ActionType: "click", Type: "Any"},
+	}
+	for _, next := range compatible {
+		assert.NoError(t, base.CheckEvolution(next), next)
+	}
+	incompatible := []Schema{
+		// add a required field
+		clickSchema(base.Fields[0], base.Fields[1], Field{Name: "z", Type: "Bool"}),
+		// remove a field
+		clickSchema(base.Fields[0]),
+		// narrow or change a type
+		clickSchema(Field{Name: "x", Type: "String"}, base.Fields[1]),
+		clickSchema(base.Fields[0], Field{Name: "y", Type: "Int", Optional: true, Nullable: true}),
+		// make a field required or non-nullable
+		clickSchema(base.Fields[0], Field{Name: "y", Type: "String", Nullable: true}),
+		clickSchema(base.Fields[0], Field{Name: "y", Type: "String", Optional: true}),
+		{Target: ACTION, ActionType: "click", Type: "Int"},
+		// different action type
+		{Target: ACTION, ActionType: "view", Fields: base.Fields},
+	}
+	for _, next := range incompatible {
+		assert.Error(t, base.CheckEvolution(next), next)
+	}
+
+	profile := Schema{Target: PROFILE, OType: "user", Key: "scores", Type: "List[Int]"}
+	for _, typ := range []string{"List[Int]", "List[Double]", "List[Number]", "List", "Any"} {
+		next := profile
+		next.Type = typ
+		assert.NoError(t, profile.CheckEvolution(next), typ)
+	}
+	for _, typ := range []string{"List[String]", "Int", "Dict[Int]"} {
+		next := profile
+		next.Type = typ
+		assert.Error(t, profile.CheckEvolution(next), typ)
+	}
+	next := profile
+	next.Fields = []Field{{Name: "x", Type: "Int"}}
+	next.Type = ""
+	assert.Error(t, profile.CheckEvolution(next))
+	nullable := profile
+	nullable.Nullable = true
+	assert.NoError(t, profile.CheckEvolution(nullable))
+	assert.Error(t, nullable.CheckEvolution(profile))
+}
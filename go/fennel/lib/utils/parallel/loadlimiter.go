@@ -132,12 +132,14 @@ func (s *customSem) notifyWaiters() {
 
 var semMap = make(map[string]*customSem)
 
-func Acquire(ctx context.Context, name string, units float64) {
+// Acquire blocks until the units of the quota are available or ctx is done. It returns ctx.Err()
+// if the units could not be acquired, in which case they must not be released.
+func Acquire(ctx context.Context, name string, units float64) error {
 	sem := semMap[name]
 	if sem == nil {
-		return
+		return nil
 	}
-	_ = sem.acquire(ctx, int64(units))
+	return sem.acquire(ctx, int64(units))
 }
 
 func AcquireHighPriority(name string, units float64) {
@@ -0,0 +1,22 @@
+package utils
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+)
+
+// WithinDir returns the cleaned path, relative paths are relative to the base dir, or an error if
+// the path is outside of the base dir
+func WithinDir(base, path string) (string, error) {
+	base = filepath.Clean(base)
+	if !filepath.IsAbs(path) {
+		path = filepath.Join(base, path)
+	}
+	path = filepath.Clean(path)
+	rel, err := filepath.Rel(base, path)
+	if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return "", fmt.Errorf("path '%s' is outside of '%s'", path, base)
+	}
+	return path, nil
+}
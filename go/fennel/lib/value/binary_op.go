@@ -1,8 +1,10 @@
 package value
 
 import (
+	"bytes"
 	"fmt"
 	"math"
+	"time"
 )
 
 func route(l Value, opt string, other Value) (Value, error) {
@@ -41,6 +43,8 @@ func route(l Value, opt string, other Value) (Value, error) {
 		return contains(l, other)
 	case "^":
 		return power(l, other)
+	case "@":
+		return dot(l, other)
 	}
 	return Nil, nil
 }
@@ -119,8 +123,27 @@ func add(left Value, right Value) (Value, error) {
 			}
 			return List{values: v}, nil
 		}
+	case Time:
+		if secs, ok := asFloat(right); ok {
+			return left.add(secs), nil
+		}
+	case Bytes:
+		switch right := right.(type) {
+		case Bytes:
+			v := make(Bytes, 0, len(left)+len(right))
+			return append(append(v, left...), right...), nil
+		}
+	case Vector:
+		if right, ok := right.(Vector); ok {
+			return elementwise(left, right, "+", func(a, b float32) float32 { return a + b })
+		}
 	}
-	return nil, fmt.Errorf("'+' only supported between numbers, strings and lists. Got '%s' and '%s'", left.String(), right.String())
+	if rt, ok := right.(Time); ok {
+		if secs, ok := asFloat(left); ok {
+			return rt.add(secs), nil
+		}
+	}
+	return nil, fmt.Errorf("'+' only supported between numbers, strings, lists, bytes, vectors and times & numbers. Got '%s' and '%s'", left.String(), right.String())
 }
 
 func sub(left Value, right Value) (Value, error) {
@@ -139,8 +162,19 @@ func sub(left Value, right Value) (Value, error) {
 		case Double:
 			return Double(float64(left) - float64(right)), nil
 		}
+	case Time:
+		if rt, ok := right.(Time); ok {
+			return Double(left.t.Sub(rt.t).Seconds()), nil
+		}
+		if secs, ok := asFloat(right); ok {
+			return left.add(-secs), nil
+		}
+	case Vector:
+		if right, ok := right.(Vector); ok {
+			return elementwise(left, right, "-", func(a, b float32) float32 { return a - b })
+		}
 	}
-	return nil, fmt.Errorf("'-' only supported between numbers. Got '%s' and '%s'", left.String(), right.String())
+	return nil, fmt.Errorf("'-' only supported between numbers, vectors, times & numbers and times. Got '%s' and '%s'", left.String(), right.String())
 }
 
 func div(left Value, right Value) (Value, error) {
@@ -208,8 +242,61 @@ func mul(left Value, right Value) (Value, error) {
 		case Double:
 			return Double(float64(left) * float64(right)), nil
 		}
+	case Vector:
+		if x, ok := asFloat(right); ok {
+			return left.scale(float32(x)), nil
+		}
+	}
+	if rv, ok := right.(Vector); ok {
+		if x, ok := asFloat(left); ok {
+			return rv.scale(float32(x)), nil
+		}
+	}
+	return nil, fmt.Errorf("'*' only supported between numbers and vectors & numbers. Got '%s' and '%s'", left.String(), right.String())
+}
+
+func (v Vector) scale(x float32) Vector {
+	ret := make(Vector, len(v))
+	for i := range v {
+		ret[i] = v[i] * x
 	}
-	return nil, fmt.Errorf("'*' only supported between numbers. Got '%s' and '%s'", left.String(), right.String())
+	return ret
+}
+
+func elementwise(left, right Vector, opt string, f func(a, b float32) float32) (Value, error) {
+	if len(left) != len(right) {
+		return nil, fmt.Errorf("'%s' only supported between vectors of the same dimension. Got %d and %d", opt, len(left), len(right))
+	}
+	ret := make(Vector, len(left))
+	for i := range left {
+		ret[i] = f(left[i], right[i])
+	}
+	return ret, nil
+}
+
+// Dot returns the dot product of vectors of the same dimension
+func Dot(left, right Vector) (float64, error) {
+	if len(left) != len(right) {
+		return 0, fmt.Errorf("'@' only supported between vectors of the same dimension. Got %d and %d", len(left), len(right))
+	}
+	var ret float64
+	for i := range left {
+		ret += float64(left[i]) * float64(right[i])
+	}
+	return ret, nil
+}
+
+func dot(left Value, right Value) (Value, error) {
+	lv, lok := left.(Vector)
+	rv, rok := right.(Vector)
+	if !lok || !rok {
+		return nil, fmt.Errorf("'@' only supported between vectors. Got '%s' and '%s'", left.String(), right.String())
+	}
+	d, err := Dot(lv, rv)
+	if err != nil {
+		return nil, err
+	}
+	return Double(d), nil
 }
 
 func eq(left Value, right Value) (Value, error) {
@@ -280,7 +367,10 @@ func lt(left Value, right Value) (Value, error) {
 			return Bool(float64(left) < float64(right)), nil
 		}
 	}
-	return nil, fmt.Errorf("'<' only supported between numbers. Got '%s' and '%s'", left.String(), right.String())
+	if c, ok := compare(left, right); ok {
+		return Bool(c < 0), nil
+	}
+	return nil, fmt.Errorf("'<' only supported between numbers, times and bytes. Got '%s' and '%s'", left.String(), right.String())
 }
 
 func lte(left Value, right Value) (Value, error) {
@@ -300,7 +390,10 @@ func lte(left Value, right Value) (Value, error) {
 			return Bool(float64(left) <= float64(right)), nil
 		}
 	}
-	return nil, fmt.Errorf("'<=' only supported between numbers. Got '%s' and '%s'", left.String(), right.String())
+	if c, ok := compare(left, right); ok {
+		return Bool(c <= 0), nil
+	}
+	return nil, fmt.Errorf("'<=' only supported between numbers, times and bytes. Got '%s' and '%s'", left.String(), right.String())
 }
 
 func gt(left Value, right Value) (Value, error) {
@@ -320,7 +413,10 @@ func gt(left Value, right Value) (Value, error) {
 			return Bool(float64(left) > float64(right)), nil
 		}
 	}
-	return nil, fmt.Errorf("'>' only supported between numbers. Got '%s' and '%s'", left.String(), right.String())
+	if c, ok := compare(left, right); ok {
+		return Bool(c > 0), nil
+	}
+	return nil, fmt.Errorf("'>' only supported between numbers, times and bytes. Got '%s' and '%s'", left.String(), right.String())
 }
 
 func gte(left Value, right Value) (Value, error) {
@@ -340,7 +436,47 @@ func gte(left Value, right Value) (Value, error) {
 			return Bool(float64(left) >= float64(right)), nil
 		}
 	}
-	return nil, fmt.Errorf("'>=' only supported between numbers. Got '%s' and '%s'", left.String(), right.String())
+	if c, ok := compare(left, right); ok {
+		return Bool(c >= 0), nil
+	}
+	return nil, fmt.Errorf("'>=' only supported between numbers, times and bytes. Got '%s' and '%s'", left.String(), right.String())
+}
+
+// asFloat returns the float of a number, e.g. the seconds added to a time or the factor of a vector
+func asFloat(v Value) (float64, bool) {
+	switch v := v.(type) {
+	case Int:
+		return float64(v), true
+	case Double:
+		return float64(v), true
+	}
+	return 0, false
+}
+
+func (t Time) add(secs float64) Time {
+	return Time{t: t.t.Add(time.Duration(secs * float64(time.Second)))}
+}
+
+// compare compares times by their instant and bytes lexicographically
+func compare(left Value, right Value) (int, bool) {
+	switch left := left.(type) {
+	case Time:
+		if right, ok := right.(Time); ok {
+			switch {
+			case left.t.Before(right.t):
+				return -1, true
+			case left.t.After(right.t):
+				return 1, true
+			default:
+				return 0, true
+			}
+		}
+	case Bytes:
+		if right, ok := right.(Bytes); ok {
+			return bytes.Compare(left, right), true
+		}
+	}
+	return 0, false
 }
 
 func power(left Value, right Value) (Value, error) {
@@ -384,5 +520,27 @@ func index(left Value, right Value) (Value, error) {
 		}
 		return ret, nil
 	}
-	return nil, fmt.Errorf("'index' operation supported only on lists or dicts but got: '%T' instead", left)
+	if asBytes, ok := left.(Bytes); ok {
+		asInt, ok := right.(Int)
+		if !ok {
+			return Nil, fmt.Errorf("can only index bytes with int but got: '%s' instead", right)
+		}
+		idx := int(asInt)
+		if idx < 0 || idx >= len(asBytes) {
+			return nil, fmt.Errorf("index '%d' out of bounds for bytes of length: '%d'", idx, len(asBytes))
+		}
+		return Int(asBytes[idx]), nil
+	}
+	if asVector, ok := left.(Vector); ok {
+		asInt, ok := right.(Int)
+		if !ok {
+			return Nil, fmt.Errorf("can only index a vector with int but got: '%s' instead", right)
+		}
+		idx := int(asInt)
+		if idx < 0 || idx >= len(asVector) {
+			return nil, fmt.Errorf("index '%d' out of bounds for vector of length: '%d'", idx, len(asVector))
+		}
+		return Double(asVector[idx]), nil
+	}
+	return nil, fmt.Errorf("'index' operation supported only on lists, dicts, bytes or vectors but got: '%T' instead", left)
 }
@@ -3,6 +3,7 @@ package value
 import (
 	"fmt"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 )
@@ -265,6 +266,81 @@ func TestConcatenation(t *testing.T) {
 	verifyBinaryOp(t, l1, l2, NewList(Int(1), Nil, Double(2), Bool(false)), "+")
 }
 
+func TestTimeOps(t *testing.T) {
+	ist := time.FixedZone("", 19800)
+	t1 := NewTime(time.Date(2022, 6, 1, 10, 0, 0, 0, time.UTC))
+	// the same instant in another zone
+	t2 := NewTime(time.Date(2022, 6, 1, 15, 30, 0, 0, ist))
+	t3 := NewTime(time.Date(2022, 6, 1, 10, 0, 30, 0, time.UTC))
+
+	verifyBinaryOp(t, t1, t2, Bool(true), "==")
+	verifyBinaryOp(t, t1, t3, Bool(false), "==")
+	verifyBinaryOp(t, t1, t3, Bool(true), "<")
+	verifyBinaryOp(t, t2, t3, Bool(true), "<=")
+	verifyBinaryOp(t, t3, t2, Bool(true), ">")
+	verifyBinaryOp(t, t1, t2, Bool(true), ">=")
+
+	verifyBinaryOp(t, t3, t1, Double(30), "-")
+	verifyBinaryOp(t, t1, t3, Double(-30), "-")
+	verifyBinaryOp(t, t1, Int(30), t3, "+")
+	verifyBinaryOp(t, Double(30), t1, t3, "+")
+	verifyBinaryOp(t, t3, Double(30), t1, "-")
+	// arithmetic keeps the zone of the time
+	ret, err := t2.Op("+", Int(3600))
+	assert.NoError(t, err)
+	assert.Equal(t, "2022-06-01T16:30:00+05:30", ret.(Time).Time().Format(time.RFC3339))
+
+	verifyBinaryError(t, t1, String("a"), []string{"+", "-", "<", ">", "<=", ">="})
+	verifyBinaryError(t, Int(1), t1, []string{"-", "<"})
+	verifyBinaryError(t, t1, t2, []string{"+", "*", "/"})
+}
+
+func TestBytesOps(t *testing.T) {
+	b1 := Bytes("ab")
+	b2 := Bytes{0, 255}
+	verifyBinaryOp(t, b1, b2, Bytes{'a', 'b', 0, 255}, "+")
+	verifyBinaryOp(t, b1, Bytes("ab"), Bool(true), "==")
+	verifyBinaryOp(t, b1, String("ab"), Bool(false), "==")
+	verifyBinaryOp(t, b2, b1, Bool(true), "<")
+	verifyBinaryOp(t, b1, Bytes("abc"), Bool(true), "<=")
+	verifyBinaryOp(t, b2, Int(1), Int(255), "[]")
+	verifyBinaryError(t, b2, Int(2), []string{"[]", "+", "<"})
+	verifyUnaryOp(t, "len", b2, Int(2))
+}
+
+func TestVectorOps(t *testing.T) {
+	v1 := Vector{1, 2, 3}
+	v2 := Vector{0.5, -1, 0}
+	verifyBinaryOp(t, v1, v2, Vector{1.5, 1, 3}, "+")
+	verifyBinaryOp(t, v1, v2, Vector{0.5, 3, 3}, "-")
+	verifyBinaryOp(t, v1, Int(2), Vector{2, 4, 6}, "*")
+	verifyBinaryOp(t, Double(0.5), v1, Vector{0.5, 1, 1.5}, "*")
+	verifyBinaryOp(t, v1, v2, Double(-1.5), "@")
+	verifyBinaryOp(t, v1, Vector{1, 2, 3}, Bool(true), "==")
+	verifyBinaryOp(t, v1, NewList(Double(1), Double(2), Double(3)), Bool(false), "==")
+	verifyBinaryOp(t, v1, Int(1), Double(2), "[]")
+	verifyUnaryOp(t, "len", v1, Int(3))
+
+	verifyBinaryError(t, v1, Vector{1, 2}, []string{"+", "-", "@"})
+	verifyBinaryError(t, v1, v2, []string{"*", "/", "<"})
+	verifyBinaryError(t, v1, NewList(Int(1), Int(2), Int(3)), []string{"+", "@"})
+	verifyBinaryError(t, v1, Int(3), []string{"[]", "+"})
+}
+
+func TestToVector(t *testing.T) {
+	v, err := ToVector(NewList(Int(1), Double(-2.5)))
+	assert.NoError(t, err)
+	assert.Equal(t, Vector{1, -2.5}, v)
+	assert.Equal(t, NewList(Double(1), Double(-2.5)), v.List())
+	v, err = ToVector(Vector{3})
+	assert.NoError(t, err)
+	assert.Equal(t, Vector{3}, v)
+	_, err = ToVector(NewList(Int(1), String("a")))
+	assert.Error(t, err)
+	_, err = ToVector(Int(1))
+	assert.Error(t, err)
+}
+
 func TestContains_Valid(t *testing.T) {
 	scenarios := []struct {
 		left  Value
@@ -43,6 +43,13 @@ func ToProtoValue(v Value) (PValue, error) {
 		return PValue{Node: &PValue_Dict{Dict: &pvd}}, nil
 	case nil_:
 		return PValue{Node: &PValue_Nil{}}, nil
+	case Time:
+		_, offset := t.t.Zone()
+		return PValue{Node: &PValue_Time{Time: &PVTime{UnixNanos: t.t.UnixNano(), ZoneOffset: int32(offset)}}}, nil
+	case Bytes:
+		return PValue{Node: &PValue_Bytes{Bytes: []byte(t)}}, nil
+	case Vector:
+		return PValue{Node: &PValue_Vector{Vector: &PVVector{Values: []float32(t)}}}, nil
 	default:
 		return PValue{Node: &PValue_Nil{}}, fmt.Errorf("invalid value: %v", v)
 	}
@@ -86,6 +93,18 @@ func FromProtoValue(pv *PValue) (Value, error) {
 	if _, ok := pv.Node.(*PValue_Nil); ok {
 		return Nil, nil
 	}
+	if pvt, ok := pv.Node.(*PValue_Time); ok {
+		return zoned(pvt.Time.GetUnixNanos(), int(pvt.Time.GetZoneOffset())), nil
+	}
+	if pvb, ok := pv.Node.(*PValue_Bytes); ok {
+		return Bytes(pvb.Bytes), nil
+	}
+	if pvv, ok := pv.Node.(*PValue_Vector); ok {
+		if pvv.Vector.GetValues() == nil {
+			return Vector{}, nil
+		}
+		return Vector(pvv.Vector.GetValues()), nil
+	}
 
 	// TODO(mohit): Remove this hack once Vitess supports consistent marshaling and unmarshaling support as
 	// regular proto
@@ -1,7 +1,10 @@
 package value
 
 import (
+	"encoding/base64"
 	"fmt"
+	"math"
+	"time"
 
 	"github.com/buger/jsonparser"
 )
@@ -20,12 +23,24 @@ func Clean(v Value) Value {
 	return v
 }
 
+// FromJSON parses plain json, objects are always parsed as dicts
 func FromJSON(data []byte) (Value, error) {
+	return fromJSON(data, false)
+}
+
+// FromTaggedJSON parses json written by ToJSON, which also has the tagged encoding of extended
+// types, e.g. {"$time": "2022-06-01T10:00:00Z"}, and of dicts that look like them. It should only
+// be used for json that was written by ToJSON, plain json can be misread as extended types.
+func FromTaggedJSON(data []byte) (Value, error) {
+	return fromJSON(data, true)
+}
+
+func fromJSON(data []byte, tagged bool) (Value, error) {
 	vdata, vtype, _, err := jsonparser.Get(data)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create parser: %w", err)
 	}
-	return ParseJSON(vdata, vtype)
+	return parseJSON(vdata, vtype, tagged)
 }
 
 func ToJSON(val Value) []byte {
@@ -35,7 +50,17 @@ func ToJSON(val Value) []byte {
 	return []byte(val.String())
 }
 
+// ParseJSON parses plain json, see FromJSON
 func ParseJSON(vdata []byte, vtype jsonparser.ValueType) (Value, error) {
+	return parseJSON(vdata, vtype, false)
+}
+
+// ParseTaggedJSON parses json written by ToJSON, see FromTaggedJSON
+func ParseTaggedJSON(vdata []byte, vtype jsonparser.ValueType) (Value, error) {
+	return parseJSON(vdata, vtype, true)
+}
+
+func parseJSON(vdata []byte, vtype jsonparser.ValueType, tagged bool) (Value, error) {
 	switch vtype {
 	case jsonparser.Boolean:
 		return parseJSONBoolean(vdata)
@@ -44,9 +69,9 @@ func ParseJSON(vdata []byte, vtype jsonparser.ValueType) (Value, error) {
 	case jsonparser.String:
 		return ParseJSONString(vdata)
 	case jsonparser.Array:
-		return parseJSONArray(vdata)
+		return parseJSONArray(vdata, tagged)
 	case jsonparser.Object:
-		return parseJSONObject(vdata)
+		return parseJSONObject(vdata, tagged)
 	case jsonparser.Null:
 		return Nil, nil
 	default:
@@ -82,7 +107,7 @@ func ParseJSONString(data []byte) (Value, error) {
 	}
 }
 
-func parseJSONArray(data []byte) (Value, error) {
+func parseJSONArray(data []byte, tagged bool) (Value, error) {
 	var ret List
 	var errors []error
 	handler := func(vdata []byte, vtype jsonparser.ValueType, _ int, err error) {
@@ -90,7 +115,7 @@ func parseJSONArray(data []byte) (Value, error) {
 			errors = append(errors, err)
 			return
 		}
-		v, err := ParseJSON(vdata, vtype)
+		v, err := parseJSON(vdata, vtype, tagged)
 		if err != nil {
 			errors = append(errors, err)
 			return
@@ -109,14 +134,22 @@ func parseJSONArray(data []byte) (Value, error) {
 	return ret, nil
 }
 
-func parseJSONObject(data []byte) (Value, error) {
+func parseJSONObject(data []byte, tagged bool) (Value, error) {
+	d, err := parseJSONDict(data, tagged)
+	if err != nil || !tagged || d.Len() != 1 {
+		return d, err
+	}
+	return parseTagged(data, d), nil
+}
+
+func parseJSONDict(data []byte, tagged bool) (Dict, error) {
 	ret := NewDict(map[string]Value{})
 	handler := func(key []byte, vdata []byte, vtype jsonparser.ValueType, _ int) error {
 		k, err := jsonparser.ParseString(key)
 		if err != nil {
 			return err
 		}
-		v, err := ParseJSON(vdata, vtype)
+		v, err := parseJSON(vdata, vtype, tagged)
 		if err != nil {
 			return err
 		}
@@ -125,7 +158,67 @@ func parseJSONObject(data []byte) (Value, error) {
 	}
 	err := jsonparser.ObjectEach(data, handler)
 	if err != nil {
-		return nil, err
+		return Dict{}, err
+	}
+	return ret, nil
+}
+
+// parseTagged returns the time, bytes, vector or escaped dict of the tagged json encoding, other
+// dicts are returned as is. So are dicts whose payload is not a valid tagged value, such as dicts
+// stored before values were tagged, e.g. {"$time":"soon"}.
+func parseTagged(data []byte, d Dict) Value {
+	if _, ok := d.Get(DICT_TAG); ok {
+		// the escaped dict is taken as is, even if it looks like a tagged value itself
+		if inner, vtype, _, err := jsonparser.Get(data, DICT_TAG); err == nil && vtype == jsonparser.Object {
+			if escaped, err := parseJSONDict(inner, true); err == nil {
+				return escaped
+			}
+		}
+	}
+	if v, ok := d.Get(TIME_TAG); ok {
+		if s, ok := v.(String); ok {
+			if t, err := time.Parse(time.RFC3339Nano, string(s)); err == nil {
+				_, offset := t.Zone()
+				return zoned(t.UnixNano(), offset)
+			}
+		}
+	}
+	if v, ok := d.Get(BYTES_TAG); ok {
+		if s, ok := v.(String); ok {
+			if b, err := base64.StdEncoding.DecodeString(string(s)); err == nil {
+				return Bytes(b)
+			}
+		}
+	}
+	if v, ok := d.Get(VECTOR_TAG); ok {
+		if l, ok := v.(List); ok {
+			if vec, err := parseVector(l); err == nil {
+				return vec
+			}
+		}
+	}
+	return d
+}
+
+// parseVector returns the vector of the elements of the tagged json of a vector, which are numbers
+// or the strings of non-finite numbers
+func parseVector(l List) (Value, error) {
+	ret := make(Vector, len(l.values))
+	for i, e := range l.values {
+		switch e {
+		case String(vectorNaN):
+			ret[i] = float32(math.NaN())
+		case String(vectorPosInf):
+			ret[i] = float32(math.Inf(1))
+		case String(vectorNegInf):
+			ret[i] = float32(math.Inf(-1))
+		default:
+			x, err := ToVector(NewList(e))
+			if err != nil {
+				return nil, fmt.Errorf("invalid vector, element '%d' is: '%s'", i, e)
+			}
+			ret[i] = x[0]
+		}
 	}
 	return ret, nil
 }
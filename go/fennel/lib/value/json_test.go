@@ -1,8 +1,11 @@
 package value
 
 import (
+	"encoding/json"
 	"fmt"
+	"math"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 )
@@ -61,6 +64,67 @@ func TestJSON(t *testing.T) {
 	}
 }
 
+func TestTaggedJSON(t *testing.T) {
+	t1 := NewTime(time.Date(2022, 6, 1, 15, 30, 0, 500, time.FixedZone("", 19800)))
+	b := Bytes("hello")
+	tests := []struct {
+		str string
+		val Value
+	}{
+		{`{"$time":"2022-06-01T15:30:00.0000005+05:30"}`, t1},
+		{`{"$time":"2022-06-01T10:00:00Z"}`, NewTime(time.Date(2022, 6, 1, 10, 0, 0, 0, time.UTC))},
+		{`{"$bytes":"aGVsbG8="}`, b},
+		{`{"b":{"$bytes":"aGVsbG8="},"t":{"$time":"2022-06-01T15:30:00.0000005+05:30"}}`, NewDict(map[string]Value{"t": t1, "b": b})},
+		{`{"$vector":[0.5,-1,3]}`, Vector{0.5, -1, 3}},
+		{`{"$vector":[]}`, Vector{}},
+		// dicts that look like tagged values are escaped
+		{`{"$dict":{"$time":1}}`, NewDict(map[string]Value{"$time": Int(1)})},
+		{`{"$dict":{"$time":"2022-06-01T10:00:00Z"}}`, NewDict(map[string]Value{"$time": String("2022-06-01T10:00:00Z")})},
+		{`{"$dict":{"$dict":{"$time":"2022-06-01T10:00:00Z"}}}`, NewDict(map[string]Value{"$dict": NewTime(time.Date(2022, 6, 1, 10, 0, 0, 0, time.UTC))})},
+	}
+	for _, tst := range tests {
+		val, err := FromTaggedJSON([]byte(tst.str))
+		assert.NoError(t, err, tst.str)
+		assert.Equal(t, tst.val, val, tst.str)
+		assert.Equal(t, tst.str, string(ToJSON(tst.val)))
+	}
+	// dicts stored before values were tagged are read as is if they are not valid tagged values
+	legacy := []struct {
+		str string
+		val Value
+	}{
+		{`{"$time":"soon"}`, NewDict(map[string]Value{"$time": String("soon")})},
+		{`{"$bytes":"not base64"}`, NewDict(map[string]Value{"$bytes": String("not base64")})},
+		{`{"$vector":["a"]}`, NewDict(map[string]Value{"$vector": NewList(String("a"))})},
+		{`{"meta":{"$time":"soon"}}`, NewDict(map[string]Value{"meta": NewDict(map[string]Value{"$time": String("soon")})})},
+	}
+	for _, tst := range legacy {
+		val, err := FromTaggedJSON([]byte(tst.str))
+		assert.NoError(t, err, tst.str)
+		assert.Equal(t, tst.val, val, tst.str)
+	}
+	// non-finite elements of vectors are written as strings so that the json stays valid
+	nonFinite := Vector{float32(math.NaN()), float32(math.Inf(1)), float32(math.Inf(-1)), 1}
+	ser := ToJSON(nonFinite)
+	assert.Equal(t, `{"$vector":["NaN","Infinity","-Infinity",1]}`, string(ser))
+	assert.True(t, json.Valid(ser))
+	val, err := FromTaggedJSON(ser)
+	assert.NoError(t, err)
+	vec := val.(Vector)
+	assert.True(t, math.IsNaN(float64(vec[0])))
+	assert.Equal(t, nonFinite[1:], vec[1:])
+	val, err = FromTaggedJSON([]byte(`{"$vector":["Inf"]}`))
+	assert.NoError(t, err)
+	assert.IsType(t, Dict{}, val)
+
+	// plain json is never read as tagged values
+	for _, str := range []string{`{"$time":"2022-06-01T10:00:00Z"}`, `{"$time":"yesterday"}`, `{"$dict":{"a":1}}`} {
+		val, err := FromJSON([]byte(str))
+		assert.NoError(t, err, str)
+		assert.IsType(t, Dict{}, val, str)
+	}
+}
+
 func TestInvalidDict(t *testing.T) {
 	dStr := `{1:3.14,"k2":128,"k3":"abc"}`
 	_, err := FromJSON([]byte(dStr))
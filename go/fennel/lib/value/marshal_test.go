@@ -94,6 +94,40 @@ func TestRexparserMarshal(t *testing.T) {
 	assert.Equal(t, bytes, 1)
 }
 
+func TestExtendedMarshal(t *testing.T) {
+	values := []Value{
+		NewTime(time.Date(2022, 6, 1, 10, 0, 0, 123, time.UTC)),
+		NewTime(time.Date(1960, 1, 1, 0, 0, 0, 0, time.FixedZone("", -4*3600))),
+		Bytes{},
+		Bytes{0, 1, 2, 255},
+		Vector{},
+		Vector{0.5, -1, 3.25e10},
+	}
+	value := NewList(append(values, NewDict(map[string]Value{"t": values[0], "b": values[3]}))...)
+	data, err := value.Marshal()
+	assert.NoError(t, err)
+	value2, n, err := ParseValue(data)
+	assert.NoError(t, err)
+	assert.Equal(t, value, value2)
+	assert.Equal(t, len(data), n)
+	verifyMarshalUnMarshal(t, value)
+	for _, v := range values {
+		verifyMarshalUnMarshal(t, v)
+	}
+	// vectors are packed
+	data, err = Vector{1, 2, 3}.Marshal()
+	assert.NoError(t, err)
+	assert.Len(t, data, 14)
+	// times keep their zone
+	_, offset := value2.(List).values[1].(Time).Time().Zone()
+	assert.Equal(t, -4*3600, offset)
+
+	for _, malformed := range [][]byte{{TIME}, {TIME, 2}, {BYTES}, {BYTES, 3, 1, 2}, {VECTOR, 1, 0, 0, 0}, {EXTENDED}} {
+		_, _, err = ParseValue(malformed)
+		assert.Error(t, err, malformed)
+	}
+}
+
 func TestUnequalMarshal(t *testing.T) {
 	i := Int(2)
 	d := Double(3.0)
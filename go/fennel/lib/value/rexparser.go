@@ -23,6 +23,24 @@ const NULL = 0x00
 const TRUE = 0x1
 const FALSE = 0x2
 
+// Extended types use all 8 bits of the first byte, with 0xE0 as the first 3 bits
+const EXTENDED = 0xE0
+const TIME = 0xE1
+const BYTES = 0xE2
+const VECTOR = 0xE3
+
+// Tags of the json encoding of extended types, e.g. {"$time": "2022-06-01T10:00:00Z"}
+const TIME_TAG = "$time"
+const BYTES_TAG = "$bytes"
+const VECTOR_TAG = "$vector"
+
+// DICT_TAG escapes dicts whose only key is a tag so that they are not read as tagged values
+const DICT_TAG = "$dict"
+
+func isTag(key string) bool {
+	return key == TIME_TAG || key == BYTES_TAG || key == VECTOR_TAG || key == DICT_TAG
+}
+
 const MAX_ALLOC_SIZE = 10000000
 
 // Errors
@@ -33,6 +51,9 @@ var (
 	MalformedStringError  = errors.New("malformed string serialization")
 	MalformedListError    = errors.New("malformed list serialization")
 	MalformedDoubleError  = errors.New("insufficient or illegitimate bytes for double")
+	MalformedTimeError    = errors.New("malformed time serialization")
+	MalformedBytesError   = errors.New("malformed bytes serialization")
+	MalformedVectorError  = errors.New("malformed vector serialization")
 )
 
 func EncodeTypeWithNum(t byte, n int64) ([]byte, error) {
@@ -113,6 +134,8 @@ func ParseValue(data []byte) (Value, int, error) {
 			f := math.Float64frombits(d)
 			return Double(f), dataLen, nil
 		}
+	case EXTENDED:
+		return parseExtended(data)
 	default:
 		if data[0] == NULL {
 			return Nil, 1, nil
@@ -122,6 +145,47 @@ func ParseValue(data []byte) (Value, int, error) {
 	}
 }
 
+func parseExtended(data []byte) (Value, int, error) {
+	switch data[0] {
+	case TIME:
+		nanos, n := binlib.Varint(data[1:])
+		if n <= 0 {
+			return nil, 0, MalformedTimeError
+		}
+		offset, m := binlib.Varint(data[1+n:])
+		if m <= 0 {
+			return nil, 0, MalformedTimeError
+		}
+		return zoned(nanos, int(offset)), 1 + n + m, nil
+	case BYTES:
+		length, n := binlib.Uvarint(data[1:])
+		if n <= 0 || uint64(len(data)-1-n) < length {
+			return nil, 0, MalformedBytesError
+		}
+		start := 1 + n
+		b := make(Bytes, length)
+		copy(b, data[start:start+int(length)])
+		return b, start + int(length), nil
+	case VECTOR:
+		dim, n := binlib.Uvarint(data[1:])
+		if n <= 0 || uint64(len(data)-1-n)/4 < dim {
+			return nil, 0, MalformedVectorError
+		}
+		if dim > MAX_ALLOC_SIZE {
+			return nil, 0, fmt.Errorf("vector size is too large")
+		}
+		offset := 1 + n
+		v := make(Vector, dim)
+		for i := range v {
+			v[i] = math.Float32frombits(binlib.LittleEndian.Uint32(data[offset:]))
+			offset += 4
+		}
+		return v, offset, nil
+	default:
+		return nil, 0, fmt.Errorf("unsupported extended type: %x", data[0])
+	}
+}
+
 func parseBoolean(data byte) (Value, error) {
 	if data == TRUE {
 		return Bool(true), nil
@@ -3,6 +3,7 @@ package value
 import (
 	"fmt"
 	"reflect"
+	"strings"
 )
 
 type Type interface {
@@ -116,6 +117,9 @@ type typeIndex struct {
 	Bool   Type
 	List   Type
 	Dict   Type
+	Time   Type
+	Bytes  Type
+	Vector Type
 	Any    Type
 	// Other types
 	ID            Type
@@ -136,9 +140,74 @@ func init() {
 	Types.String = baseType{"String", reflect.TypeOf(String("hi"))}
 	Types.List = baseType{"List", reflect.TypeOf(NewList(Int(1), Double(3.4)))}
 	Types.Dict = baseType{"Dict", reflect.TypeOf(Dict{})}
+	Types.Time = baseType{"Time", reflect.TypeOf(Time{})}
+	Types.Bytes = baseType{"Bytes", reflect.TypeOf(Bytes{})}
+	Types.Vector = baseType{"Vector", reflect.TypeOf(Vector{})}
 	// Set other types (ensure subtypes are set before using them)
 	Types.ID = compoundType{"Int or String", []Type{Types.Int, Types.String}}
 	Types.Number = compoundType{"Number", []Type{Types.Int, Types.Double}}
 	Types.ListOfBools = listType{name: "List of Bools", elemType: Types.Bool}
 	Types.ListOfNumbers = listType{name: "List of Numbers", elemType: Types.Number}
 }
+
+// ParseType returns the type with the given name. Names are case-insensitive and are one of
+// Int, Double, String, Bool, List, Dict, Time, Bytes, Vector, Any, Number & ID, or List[T] / Dict[T] for lists & dicts
+// whose elements are all of type T e.g. "List[Number]" or "Dict[List[String]]"
+func ParseType(name string) (Type, error) {
+	name = strings.TrimSpace(name)
+	lower := strings.ToLower(name)
+	for _, prefix := range []string{"list[", "dict["} {
+		if strings.HasPrefix(lower, prefix) && strings.HasSuffix(lower, "]") {
+			elemType, err := ParseType(name[len(prefix) : len(name)-1])
+			if err != nil {
+				return nil, err
+			}
+			if prefix == "list[" {
+				return listType{name: fmt.Sprintf("List[%s]", TypeName(elemType)), elemType: elemType}, nil
+			}
+			return dictType{name: fmt.Sprintf("Dict[%s]", TypeName(elemType)), elemType: elemType}, nil
+		}
+	}
+	switch lower {
+	case "int":
+		return Types.Int, nil
+	case "double":
+		return Types.Double, nil
+	case "string":
+		return Types.String, nil
+	case "bool":
+		return Types.Bool, nil
+	case "list":
+		return Types.List, nil
+	case "dict":
+		return Types.Dict, nil
+	case "time":
+		return Types.Time, nil
+	case "bytes":
+		return Types.Bytes, nil
+	case "vector":
+		return Types.Vector, nil
+	case "any":
+		return Types.Any, nil
+	case "number":
+		return Types.Number, nil
+	case "id":
+		return Types.ID, nil
+	default:
+		return nil, fmt.Errorf("unknown type: '%s'", name)
+	}
+}
+
+// TypeName returns the name of the type that can be parsed back by ParseType
+func TypeName(t Type) string {
+	switch t.String() {
+	case Types.ID.String():
+		return "ID"
+	case Types.ListOfBools.String():
+		return "List[Bool]"
+	case Types.ListOfNumbers.String():
+		return "List[Number]"
+	default:
+		return t.String()
+	}
+}
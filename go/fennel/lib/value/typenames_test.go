@@ -0,0 +1,47 @@
+package value
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseType(t *testing.T) {
+	scenarios := []struct {
+		name  string
+		valid []Value
+		bad   []Value
+	}{
+		{"int", []Value{Int(1)}, []Value{Double(1), String("1")}},
+		{"Double", []Value{Double(1)}, []Value{Int(1)}},
+		{"Number", []Value{Int(1), Double(1)}, []Value{String("1")}},
+		{"ID", []Value{Int(1), String("1")}, []Value{Double(1)}},
+		{"any", []Value{Nil, Int(1), NewList()}, nil},
+		{"Time", []Value{NewTime(time.Now())}, []Value{Int(1), String("2022-06-01T10:00:00Z")}},
+		{"bytes", []Value{Bytes("a")}, []Value{String("a")}},
+		{"Vector", []Value{Vector{1, 2}}, []Value{NewList(Double(1), Double(2))}},
+		{"List[Int]", []Value{NewList(), NewList(Int(1))}, []Value{NewList(Double(1)), Int(1)}},
+		{"dict[list[string]]", []Value{NewDict(map[string]Value{"a": NewList(String("x"))})}, []Value{NewDict(map[string]Value{"a": String("x")})}},
+	}
+	for _, scenario := range scenarios {
+		typ, err := ParseType(scenario.name)
+		assert.NoError(t, err, scenario.name)
+		for _, v := range scenario.valid {
+			assert.NoError(t, typ.Validate(v), scenario.name)
+		}
+		for _, v := range scenario.bad {
+			assert.Error(t, typ.Validate(v), scenario.name)
+		}
+		// names round trip
+		typ2, err := ParseType(TypeName(typ))
+		assert.NoError(t, err)
+		assert.Equal(t, TypeName(typ), TypeName(typ2))
+	}
+	for _, name := range []string{"", "float", "List[", "List[float]"} {
+		_, err := ParseType(name)
+		assert.Error(t, err, name)
+	}
+	assert.Equal(t, "ID", TypeName(Types.ID))
+	assert.Equal(t, "List[Number]", TypeName(Types.ListOfNumbers))
+}
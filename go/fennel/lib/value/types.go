@@ -1,6 +1,8 @@
 package value
 
 import (
+	"bytes"
+	"encoding/base64"
 	binlib "encoding/binary"
 	"encoding/json"
 	"fennel/lib/utils/binary"
@@ -11,6 +13,7 @@ import (
 	"sort"
 	"strconv"
 	"strings"
+	"time"
 )
 
 const float64EqualityThreshold = 1e-9
@@ -36,6 +39,9 @@ var _ Value = String("")
 var _ Value = List{}
 var _ Value = Dict{}
 var _ Value = nil_{}
+var _ Value = Time{}
+var _ Value = Bytes{}
+var _ Value = Vector{}
 
 type Int int64
 
@@ -248,6 +254,225 @@ func (n nil_) Marshal() ([]byte, error) {
 	return []byte{NULL}, nil
 }
 
+// Time is an instant with the time zone it was given in. Times are equal if they are the same
+// instant, even if their zones differ, and arithmetic keeps the zone of the time.
+type Time struct {
+	t time.Time
+}
+
+func NewTime(t time.Time) Time {
+	return Time{t: t.Round(0)}
+}
+
+// Time returns the time.Time of the value
+func (t Time) Time() time.Time {
+	return t.t
+}
+
+func (t Time) isValue() {}
+func (t Time) Equal(v Value) bool {
+	switch v := v.(type) {
+	case Time:
+		return t.t.Equal(v.t)
+	default:
+		return false
+	}
+}
+
+// String returns the tagged json of the time, e.g. {"$time":"2022-06-01T10:00:00+05:30"}
+func (t Time) String() string {
+	sb := strings.Builder{}
+	sb.WriteString(`{"`)
+	sb.WriteString(TIME_TAG)
+	sb.WriteString(`":"`)
+	sb.WriteString(t.t.Format(time.RFC3339Nano))
+	sb.WriteString(`"}`)
+	return sb.String()
+}
+func (t Time) Clone() Value {
+	return t
+}
+func (t Time) Op(opt string, other Value) (Value, error) {
+	return route(t, opt, other)
+}
+func (t Time) OpUnary(opt string) (Value, error) {
+	return routeUnary(opt, t)
+}
+func (t Time) MarshalJSON() ([]byte, error) {
+	return []byte(t.String()), nil
+}
+func (t Time) Marshal() ([]byte, error) {
+	// Schema: TIME | varint of the nanoseconds since epoch | varint of the zone offset in seconds
+	ret := make([]byte, 1+2*binlib.MaxVarintLen64)
+	ret[0] = TIME
+	n := 1 + binlib.PutVarint(ret[1:], t.t.UnixNano())
+	_, offset := t.t.Zone()
+	n += binlib.PutVarint(ret[n:], int64(offset))
+	return ret[:n], nil
+}
+
+// zoned returns the time of unix nanoseconds in the zone of the offset, in UTC if the offset is 0
+func zoned(nanos int64, offset int) Time {
+	t := time.Unix(0, nanos).UTC()
+	if offset != 0 {
+		t = t.In(time.FixedZone("", offset))
+	}
+	return Time{t: t}
+}
+
+// Bytes is a binary payload
+type Bytes []byte
+
+func (b Bytes) isValue() {}
+func (b Bytes) Equal(v Value) bool {
+	switch v := v.(type) {
+	case Bytes:
+		return bytes.Equal(b, v)
+	default:
+		return false
+	}
+}
+
+// String returns the tagged json of the bytes, e.g. {"$bytes":"aGVsbG8="}
+func (b Bytes) String() string {
+	sb := strings.Builder{}
+	sb.WriteString(`{"`)
+	sb.WriteString(BYTES_TAG)
+	sb.WriteString(`":"`)
+	sb.WriteString(base64.StdEncoding.EncodeToString(b))
+	sb.WriteString(`"}`)
+	return sb.String()
+}
+func (b Bytes) Clone() Value {
+	return append(Bytes{}, b...)
+}
+func (b Bytes) Op(opt string, other Value) (Value, error) {
+	return route(b, opt, other)
+}
+func (b Bytes) OpUnary(opt string) (Value, error) {
+	return routeUnary(opt, b)
+}
+func (b Bytes) MarshalJSON() ([]byte, error) {
+	return []byte(b.String()), nil
+}
+func (b Bytes) Marshal() ([]byte, error) {
+	// Schema: BYTES | uvarint of the length | bytes
+	ret := make([]byte, 1+binlib.MaxVarintLen64, 1+binlib.MaxVarintLen64+len(b))
+	ret[0] = BYTES
+	n := 1 + binlib.PutUvarint(ret[1:], uint64(len(b)))
+	return append(ret[:n], b...), nil
+}
+
+// Vector is a dense vector of float32, e.g. an embedding. It is stored packed, unlike a list of
+// doubles whose elements are boxed values.
+type Vector []float32
+
+// ToVector returns the vector of a vector or of a list of numbers
+func ToVector(v Value) (Vector, error) {
+	switch v := v.(type) {
+	case Vector:
+		return v, nil
+	case List:
+		ret := make(Vector, len(v.values))
+		for i, e := range v.values {
+			switch e := e.(type) {
+			case Int:
+				ret[i] = float32(e)
+			case Double:
+				ret[i] = float32(e)
+			default:
+				return nil, fmt.Errorf("expected a list of numbers but element '%d' is: '%s'", i, e)
+			}
+		}
+		return ret, nil
+	default:
+		return nil, fmt.Errorf("expected a vector or a list of numbers but got: '%s'", v)
+	}
+}
+
+// List returns the list of doubles of the vector
+func (v Vector) List() List {
+	values := make([]Value, len(v))
+	for i, x := range v {
+		values[i] = Double(x)
+	}
+	return NewList(values...)
+}
+
+func (v Vector) isValue() {}
+func (v Vector) Equal(other Value) bool {
+	switch other := other.(type) {
+	case Vector:
+		if len(v) != len(other) {
+			return false
+		}
+		for i := range v {
+			if v[i] != other[i] {
+				return false
+			}
+		}
+		return true
+	default:
+		return false
+	}
+}
+
+// Non-finite elements of vectors are written as these strings since json has no numbers for them
+const (
+	vectorNaN    = "NaN"
+	vectorPosInf = "Infinity"
+	vectorNegInf = "-Infinity"
+)
+
+// String returns the tagged json of the vector, e.g. {"$vector":[0.5,-1,2.25]}, non-finite elements
+// are written as strings, e.g. {"$vector":[0.5,"NaN","-Infinity"]}
+func (v Vector) String() string {
+	sb := strings.Builder{}
+	sb.WriteString(`{"`)
+	sb.WriteString(VECTOR_TAG)
+	sb.WriteString(`":[`)
+	for i, x := range v {
+		if i > 0 {
+			sb.WriteString(",")
+		}
+		switch f := float64(x); {
+		case math.IsNaN(f):
+			sb.WriteString(`"` + vectorNaN + `"`)
+		case math.IsInf(f, 1):
+			sb.WriteString(`"` + vectorPosInf + `"`)
+		case math.IsInf(f, -1):
+			sb.WriteString(`"` + vectorNegInf + `"`)
+		default:
+			sb.WriteString(strconv.FormatFloat(f, 'g', -1, 32))
+		}
+	}
+	sb.WriteString(`]}`)
+	return sb.String()
+}
+func (v Vector) Clone() Value {
+	return append(Vector{}, v...)
+}
+func (v Vector) Op(opt string, other Value) (Value, error) {
+	return route(v, opt, other)
+}
+func (v Vector) OpUnary(opt string) (Value, error) {
+	return routeUnary(opt, v)
+}
+func (v Vector) MarshalJSON() ([]byte, error) {
+	return []byte(v.String()), nil
+}
+func (v Vector) Marshal() ([]byte, error) {
+	// Schema: VECTOR | uvarint of the dimension | 4 bytes of each float32, little endian
+	ret := make([]byte, 1+binlib.MaxVarintLen64+4*len(v))
+	ret[0] = VECTOR
+	n := 1 + binlib.PutUvarint(ret[1:], uint64(len(v)))
+	for _, x := range v {
+		binlib.LittleEndian.PutUint32(ret[n:], math.Float32bits(x))
+		n += 4
+	}
+	return ret[:n], nil
+}
+
 type List struct {
 	values []Value
 }
@@ -449,9 +674,21 @@ func (d Dict) String() string {
 	// we sort these strings so that each dictionary gets a unique representation
 	sort.Strings(s)
 	sb := strings.Builder{}
+	escape := false
+	if d.Len() == 1 {
+		for k := range d.values {
+			escape = isTag(k)
+		}
+	}
+	if escape {
+		sb.WriteString(`{"` + DICT_TAG + `":`)
+	}
 	sb.WriteString("{")
 	sb.WriteString(strings.Join(s, ","))
 	sb.WriteString("}")
+	if escape {
+		sb.WriteString("}")
+	}
 	return sb.String()
 }
 
@@ -28,6 +28,10 @@ func len_(v Value) (Value, error) {
 		return Int(v.Len()), nil
 	case Dict:
 		return Int(v.Len()), nil
+	case Bytes:
+		return Int(len(v)), nil
+	case Vector:
+		return Int(len(v)), nil
 	}
 	return nil, fmt.Errorf("'len' only supported on booleans")
 }
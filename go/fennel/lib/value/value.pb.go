@@ -34,6 +34,9 @@ type PValue struct {
 	//	*PValue_Dict
 	//	*PValue_Table
 	//	*PValue_Nil
+	//	*PValue_Time
+	//	*PValue_Bytes
+	//	*PValue_Vector
 	Node isPValue_Node `protobuf_oneof:"node"`
 }
 
@@ -132,6 +135,27 @@ func (x *PValue) GetNil() *PVNil {
 	return nil
 }
 
+func (x *PValue) GetTime() *PVTime {
+	if x, ok := x.GetNode().(*PValue_Time); ok {
+		return x.Time
+	}
+	return nil
+}
+
+func (x *PValue) GetBytes() []byte {
+	if x, ok := x.GetNode().(*PValue_Bytes); ok {
+		return x.Bytes
+	}
+	return nil
+}
+
+func (x *PValue) GetVector() *PVVector {
+	if x, ok := x.GetNode().(*PValue_Vector); ok {
+		return x.Vector
+	}
+	return nil
+}
+
 type isPValue_Node interface {
 	isPValue_Node()
 }
@@ -165,7 +189,20 @@ type PValue_Table struct {
 }
 
 type PValue_Nil struct {
-	Nil *PVNil `protobuf:"bytes,8,opt,name=Nil,proto3,oneof"` // [deprecated] PVTuple Tuple = 9;
+	Nil *PVNil `protobuf:"bytes,8,opt,name=Nil,proto3,oneof"`
+}
+
+type PValue_Time struct {
+	// [deprecated] PVTuple Tuple = 9;
+	Time *PVTime `protobuf:"bytes,10,opt,name=Time,proto3,oneof"`
+}
+
+type PValue_Bytes struct {
+	Bytes []byte `protobuf:"bytes,11,opt,name=Bytes,proto3,oneof"`
+}
+
+type PValue_Vector struct {
+	Vector *PVVector `protobuf:"bytes,12,opt,name=Vector,proto3,oneof"`
 }
 
 func (*PValue_Int) isPValue_Node() {}
@@ -184,6 +221,12 @@ func (*PValue_Table) isPValue_Node() {}
 
 func (*PValue_Nil) isPValue_Node() {}
 
+func (*PValue_Time) isPValue_Node() {}
+
+func (*PValue_Bytes) isPValue_Node() {}
+
+func (*PValue_Vector) isPValue_Node() {}
+
 type PVList struct {
 	state         protoimpl.MessageState
 	sizeCache     protoimpl.SizeCache
@@ -363,10 +406,114 @@ func (*PVNil) Descriptor() ([]byte, []int) {
 	return file_value_proto_rawDescGZIP(), []int{4}
 }
 
+type PVTime struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	// nanoseconds since the unix epoch
+	UnixNanos int64 `protobuf:"varint,1,opt,name=unix_nanos,json=unixNanos,proto3" json:"unix_nanos,omitempty"`
+	// offset of the time zone in seconds east of UTC
+	ZoneOffset int32 `protobuf:"varint,2,opt,name=zone_offset,json=zoneOffset,proto3" json:"zone_offset,omitempty"`
+}
+
+func (x *PVTime) Reset() {
+	*x = PVTime{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_value_proto_msgTypes[5]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *PVTime) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*PVTime) ProtoMessage() {}
+
+func (x *PVTime) ProtoReflect() protoreflect.Message {
+	mi := &file_value_proto_msgTypes[5]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use PVTime.ProtoReflect.Descriptor instead.
+func (*PVTime) Descriptor() ([]byte, []int) {
+	return file_value_proto_rawDescGZIP(), []int{5}
+}
+
+func (x *PVTime) GetUnixNanos() int64 {
+	if x != nil {
+		return x.UnixNanos
+	}
+	return 0
+}
+
+func (x *PVTime) GetZoneOffset() int32 {
+	if x != nil {
+		return x.ZoneOffset
+	}
+	return 0
+}
+
+type PVVector struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Values []float32 `protobuf:"fixed32,1,rep,packed,name=values,proto3" json:"values,omitempty"`
+}
+
+func (x *PVVector) Reset() {
+	*x = PVVector{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_value_proto_msgTypes[6]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *PVVector) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*PVVector) ProtoMessage() {}
+
+func (x *PVVector) ProtoReflect() protoreflect.Message {
+	mi := &file_value_proto_msgTypes[6]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use PVVector.ProtoReflect.Descriptor instead.
+func (*PVVector) Descriptor() ([]byte, []int) {
+	return file_value_proto_rawDescGZIP(), []int{6}
+}
+
+func (x *PVVector) GetValues() []float32 {
+	if x != nil {
+		return x.Values
+	}
+	return nil
+}
+
 var File_value_proto protoreflect.FileDescriptor
 
 var file_value_proto_rawDesc = []byte{
-	0x0a, 0x0b, 0x76, 0x61, 0x6c, 0x75, 0x65, 0x2e, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x22, 0xea, 0x01,
+	0x0a, 0x0b, 0x76, 0x61, 0x6c, 0x75, 0x65, 0x2e, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x22, 0xc6, 0x02,
 	0x0a, 0x06, 0x50, 0x56, 0x61, 0x6c, 0x75, 0x65, 0x12, 0x12, 0x0a, 0x03, 0x49, 0x6e, 0x74, 0x18,
 	0x01, 0x20, 0x01, 0x28, 0x03, 0x48, 0x00, 0x52, 0x03, 0x49, 0x6e, 0x74, 0x12, 0x18, 0x0a, 0x06,
 	0x44, 0x6f, 0x75, 0x62, 0x6c, 0x65, 0x18, 0x02, 0x20, 0x01, 0x28, 0x01, 0x48, 0x00, 0x52, 0x06,
@@ -381,22 +528,35 @@ var file_value_proto_rawDesc = []byte{
 	0x01, 0x28, 0x0b, 0x32, 0x08, 0x2e, 0x50, 0x56, 0x54, 0x61, 0x62, 0x6c, 0x65, 0x48, 0x00, 0x52,
 	0x05, 0x54, 0x61, 0x62, 0x6c, 0x65, 0x12, 0x1a, 0x0a, 0x03, 0x4e, 0x69, 0x6c, 0x18, 0x08, 0x20,
 	0x01, 0x28, 0x0b, 0x32, 0x06, 0x2e, 0x50, 0x56, 0x4e, 0x69, 0x6c, 0x48, 0x00, 0x52, 0x03, 0x4e,
-	0x69, 0x6c, 0x42, 0x06, 0x0a, 0x04, 0x6e, 0x6f, 0x64, 0x65, 0x22, 0x29, 0x0a, 0x06, 0x50, 0x56,
-	0x4c, 0x69, 0x73, 0x74, 0x12, 0x1f, 0x0a, 0x06, 0x76, 0x61, 0x6c, 0x75, 0x65, 0x73, 0x18, 0x01,
-	0x20, 0x03, 0x28, 0x0b, 0x32, 0x07, 0x2e, 0x50, 0x56, 0x61, 0x6c, 0x75, 0x65, 0x52, 0x06, 0x76,
-	0x61, 0x6c, 0x75, 0x65, 0x73, 0x22, 0x79, 0x0a, 0x06, 0x50, 0x56, 0x44, 0x69, 0x63, 0x74, 0x12,
-	0x2b, 0x0a, 0x06, 0x76, 0x61, 0x6c, 0x75, 0x65, 0x73, 0x18, 0x01, 0x20, 0x03, 0x28, 0x0b, 0x32,
-	0x13, 0x2e, 0x50, 0x56, 0x44, 0x69, 0x63, 0x74, 0x2e, 0x56, 0x61, 0x6c, 0x75, 0x65, 0x73, 0x45,
-	0x6e, 0x74, 0x72, 0x79, 0x52, 0x06, 0x76, 0x61, 0x6c, 0x75, 0x65, 0x73, 0x1a, 0x42, 0x0a, 0x0b,
-	0x56, 0x61, 0x6c, 0x75, 0x65, 0x73, 0x45, 0x6e, 0x74, 0x72, 0x79, 0x12, 0x10, 0x0a, 0x03, 0x6b,
-	0x65, 0x79, 0x18, 0x01, 0x20, 0x01, 0x28, 0x09, 0x52, 0x03, 0x6b, 0x65, 0x79, 0x12, 0x1d, 0x0a,
-	0x05, 0x76, 0x61, 0x6c, 0x75, 0x65, 0x18, 0x02, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x07, 0x2e, 0x50,
-	0x56, 0x61, 0x6c, 0x75, 0x65, 0x52, 0x05, 0x76, 0x61, 0x6c, 0x75, 0x65, 0x3a, 0x02, 0x38, 0x01,
-	0x22, 0x26, 0x0a, 0x07, 0x50, 0x56, 0x54, 0x61, 0x62, 0x6c, 0x65, 0x12, 0x1b, 0x0a, 0x04, 0x72,
-	0x6f, 0x77, 0x73, 0x18, 0x01, 0x20, 0x03, 0x28, 0x0b, 0x32, 0x07, 0x2e, 0x50, 0x56, 0x44, 0x69,
-	0x63, 0x74, 0x52, 0x04, 0x72, 0x6f, 0x77, 0x73, 0x22, 0x07, 0x0a, 0x05, 0x50, 0x56, 0x4e, 0x69,
-	0x6c, 0x42, 0x12, 0x5a, 0x10, 0x66, 0x65, 0x6e, 0x6e, 0x65, 0x6c, 0x2f, 0x6c, 0x69, 0x62, 0x2f,
-	0x76, 0x61, 0x6c, 0x75, 0x65, 0x62, 0x06, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x33,
+	0x69, 0x6c, 0x12, 0x1d, 0x0a, 0x04, 0x54, 0x69, 0x6d, 0x65, 0x18, 0x0a, 0x20, 0x01, 0x28, 0x0b,
+	0x32, 0x07, 0x2e, 0x50, 0x56, 0x54, 0x69, 0x6d, 0x65, 0x48, 0x00, 0x52, 0x04, 0x54, 0x69, 0x6d,
+	0x65, 0x12, 0x16, 0x0a, 0x05, 0x42, 0x79, 0x74, 0x65, 0x73, 0x18, 0x0b, 0x20, 0x01, 0x28, 0x0c,
+	0x48, 0x00, 0x52, 0x05, 0x42, 0x79, 0x74, 0x65, 0x73, 0x12, 0x23, 0x0a, 0x06, 0x56, 0x65, 0x63,
+	0x74, 0x6f, 0x72, 0x18, 0x0c, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x09, 0x2e, 0x50, 0x56, 0x56, 0x65,
+	0x63, 0x74, 0x6f, 0x72, 0x48, 0x00, 0x52, 0x06, 0x56, 0x65, 0x63, 0x74, 0x6f, 0x72, 0x42, 0x06,
+	0x0a, 0x04, 0x6e, 0x6f, 0x64, 0x65, 0x22, 0x29, 0x0a, 0x06, 0x50, 0x56, 0x4c, 0x69, 0x73, 0x74,
+	0x12, 0x1f, 0x0a, 0x06, 0x76, 0x61, 0x6c, 0x75, 0x65, 0x73, 0x18, 0x01, 0x20, 0x03, 0x28, 0x0b,
+	0x32, 0x07, 0x2e, 0x50, 0x56, 0x61, 0x6c, 0x75, 0x65, 0x52, 0x06, 0x76, 0x61, 0x6c, 0x75, 0x65,
+	0x73, 0x22, 0x79, 0x0a, 0x06, 0x50, 0x56, 0x44, 0x69, 0x63, 0x74, 0x12, 0x2b, 0x0a, 0x06, 0x76,
+	0x61, 0x6c, 0x75, 0x65, 0x73, 0x18, 0x01, 0x20, 0x03, 0x28, 0x0b, 0x32, 0x13, 0x2e, 0x50, 0x56,
+	0x44, 0x69, 0x63, 0x74, 0x2e, 0x56, 0x61, 0x6c, 0x75, 0x65, 0x73, 0x45, 0x6e, 0x74, 0x72, 0x79,
+	0x52, 0x06, 0x76, 0x61, 0x6c, 0x75, 0x65, 0x73, 0x1a, 0x42, 0x0a, 0x0b, 0x56, 0x61, 0x6c, 0x75,
+	0x65, 0x73, 0x45, 0x6e, 0x74, 0x72, 0x79, 0x12, 0x10, 0x0a, 0x03, 0x6b, 0x65, 0x79, 0x18, 0x01,
+	0x20, 0x01, 0x28, 0x09, 0x52, 0x03, 0x6b, 0x65, 0x79, 0x12, 0x1d, 0x0a, 0x05, 0x76, 0x61, 0x6c,
+	0x75, 0x65, 0x18, 0x02, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x07, 0x2e, 0x50, 0x56, 0x61, 0x6c, 0x75,
+	0x65, 0x52, 0x05, 0x76, 0x61, 0x6c, 0x75, 0x65, 0x3a, 0x02, 0x38, 0x01, 0x22, 0x26, 0x0a, 0x07,
+	0x50, 0x56, 0x54, 0x61, 0x62, 0x6c, 0x65, 0x12, 0x1b, 0x0a, 0x04, 0x72, 0x6f, 0x77, 0x73, 0x18,
+	0x01, 0x20, 0x03, 0x28, 0x0b, 0x32, 0x07, 0x2e, 0x50, 0x56, 0x44, 0x69, 0x63, 0x74, 0x52, 0x04,
+	0x72, 0x6f, 0x77, 0x73, 0x22, 0x07, 0x0a, 0x05, 0x50, 0x56, 0x4e, 0x69, 0x6c, 0x22, 0x48, 0x0a,
+	0x06, 0x50, 0x56, 0x54, 0x69, 0x6d, 0x65, 0x12, 0x1d, 0x0a, 0x0a, 0x75, 0x6e, 0x69, 0x78, 0x5f,
+	0x6e, 0x61, 0x6e, 0x6f, 0x73, 0x18, 0x01, 0x20, 0x01, 0x28, 0x03, 0x52, 0x09, 0x75, 0x6e, 0x69,
+	0x78, 0x4e, 0x61, 0x6e, 0x6f, 0x73, 0x12, 0x1f, 0x0a, 0x0b, 0x7a, 0x6f, 0x6e, 0x65, 0x5f, 0x6f,
+	0x66, 0x66, 0x73, 0x65, 0x74, 0x18, 0x02, 0x20, 0x01, 0x28, 0x05, 0x52, 0x0a, 0x7a, 0x6f, 0x6e,
+	0x65, 0x4f, 0x66, 0x66, 0x73, 0x65, 0x74, 0x22, 0x22, 0x0a, 0x08, 0x50, 0x56, 0x56, 0x65, 0x63,
+	0x74, 0x6f, 0x72, 0x12, 0x16, 0x0a, 0x06, 0x76, 0x61, 0x6c, 0x75, 0x65, 0x73, 0x18, 0x01, 0x20,
+	0x03, 0x28, 0x02, 0x52, 0x06, 0x76, 0x61, 0x6c, 0x75, 0x65, 0x73, 0x42, 0x12, 0x5a, 0x10, 0x66,
+	0x65, 0x6e, 0x6e, 0x65, 0x6c, 0x2f, 0x6c, 0x69, 0x62, 0x2f, 0x76, 0x61, 0x6c, 0x75, 0x65, 0x62,
+	0x06, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x33,
 }
 
 var (
@@ -411,29 +571,33 @@ func file_value_proto_rawDescGZIP() []byte {
 	return file_value_proto_rawDescData
 }
 
-var file_value_proto_msgTypes = make([]protoimpl.MessageInfo, 6)
+var file_value_proto_msgTypes = make([]protoimpl.MessageInfo, 8)
 var file_value_proto_goTypes = []interface{}{
-	(*PValue)(nil),  // 0: PValue
-	(*PVList)(nil),  // 1: PVList
-	(*PVDict)(nil),  // 2: PVDict
-	(*PVTable)(nil), // 3: PVTable
-	(*PVNil)(nil),   // 4: PVNil
-	nil,             // 5: PVDict.ValuesEntry
+	(*PValue)(nil),   // 0: PValue
+	(*PVList)(nil),   // 1: PVList
+	(*PVDict)(nil),   // 2: PVDict
+	(*PVTable)(nil),  // 3: PVTable
+	(*PVNil)(nil),    // 4: PVNil
+	(*PVTime)(nil),   // 5: PVTime
+	(*PVVector)(nil), // 6: PVVector
+	nil,              // 7: PVDict.ValuesEntry
 }
 var file_value_proto_depIdxs = []int32{
-	1, // 0: PValue.List:type_name -> PVList
-	2, // 1: PValue.Dict:type_name -> PVDict
-	3, // 2: PValue.Table:type_name -> PVTable
-	4, // 3: PValue.Nil:type_name -> PVNil
-	0, // 4: PVList.values:type_name -> PValue
-	5, // 5: PVDict.values:type_name -> PVDict.ValuesEntry
-	2, // 6: PVTable.rows:type_name -> PVDict
-	0, // 7: PVDict.ValuesEntry.value:type_name -> PValue
-	8, // [8:8] is the sub-list for method output_type
-	8, // [8:8] is the sub-list for method input_type
-	8, // [8:8] is the sub-list for extension type_name
-	8, // [8:8] is the sub-list for extension extendee
-	0, // [0:8] is the sub-list for field type_name
+	1,  // 0: PValue.List:type_name -> PVList
+	2,  // 1: PValue.Dict:type_name -> PVDict
+	3,  // 2: PValue.Table:type_name -> PVTable
+	4,  // 3: PValue.Nil:type_name -> PVNil
+	5,  // 4: PValue.Time:type_name -> PVTime
+	6,  // 5: PValue.Vector:type_name -> PVVector
+	0,  // 6: PVList.values:type_name -> PValue
+	7,  // 7: PVDict.values:type_name -> PVDict.ValuesEntry
+	2,  // 8: PVTable.rows:type_name -> PVDict
+	0,  // 9: PVDict.ValuesEntry.value:type_name -> PValue
+	10, // [10:10] is the sub-list for method output_type
+	10, // [10:10] is the sub-list for method input_type
+	10, // [10:10] is the sub-list for extension type_name
+	10, // [10:10] is the sub-list for extension extendee
+	0,  // [0:10] is the sub-list for field type_name
 }
 
 func init() { file_value_proto_init() }
@@ -502,6 +666,30 @@ func file_value_proto_init() {
 				return nil
 			}
 		}
+		file_value_proto_msgTypes[5].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*PVTime); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_value_proto_msgTypes[6].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*PVVector); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
 	}
 	file_value_proto_msgTypes[0].OneofWrappers = []interface{}{
 		(*PValue_Int)(nil),
@@ -512,6 +700,9 @@ func file_value_proto_init() {
 		(*PValue_Dict)(nil),
 		(*PValue_Table)(nil),
 		(*PValue_Nil)(nil),
+		(*PValue_Time)(nil),
+		(*PValue_Bytes)(nil),
+		(*PValue_Vector)(nil),
 	}
 	type x struct{}
 	out := protoimpl.TypeBuilder{
@@ -519,7 +710,7 @@ func file_value_proto_init() {
 			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
 			RawDescriptor: file_value_proto_rawDesc,
 			NumEnums:      0,
-			NumMessages:   6,
+			NumMessages:   8,
 			NumExtensions: 0,
 			NumServices:   0,
 		},
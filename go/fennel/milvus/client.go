@@ -31,6 +31,8 @@ const (
 
 var supportedHyperParameters = hp.HyperParamRegistry{
 	"knn": map[string]hp.HyperParameterInfo{
+		// knn aggregates can also be served by the embedded index, see package ann
+		"backend":        {Default: "milvus", Type: reflect.String, Options: []string{"milvus", "embedded"}},
 		"metric":         {Default: "ip", Type: reflect.String, Options: []string{"ip", "l2", "hamming", "jaccard"}},
 		"index":          {Default: "hnsw", Type: reflect.String, Options: []string{"flat", "ivf_flat", "hnsw", "annoy"}},
 		"nList":          {Default: 1024, Type: reflect.Int, Options: nil},
@@ -177,7 +179,7 @@ func (c Client) InsertStream(ctx context.Context, agg aggregate.Aggregate, table
 		key := id.(value.Int)
 		ids = append(ids, int64(key))
 		timestamps = append(timestamps, int64(ts_int))
-		vector, err := toList(v.(value.List))
+		vector, err := toVector(v)
 		if err != nil {
 			return err
 		}
@@ -243,7 +245,7 @@ func (c Client) GetNeighbors(ctx context.Context, agg aggregate.Aggregate, vecto
 	milvusVectors := make([]entity.Vector, len(vectors))
 
 	for i := 0; i < len(vectors); i++ {
-		vector, err := toList(vectors[i].(value.List))
+		vector, err := toVector(vectors[i])
 		if err != nil {
 			return nil, err
 		}
@@ -332,7 +334,7 @@ func (c Client) GetEmbedding(ctx context.Context, agg aggregate.Aggregate, keys
 			} else {
 				floatVectors := c.Data()
 				for i := 0; i < len(floatVectors); i++ {
-					allResults[i] = FromList(floatVectors[i])
+					allResults[i] = value.Vector(floatVectors[i])
 				}
 			}
 			break
@@ -427,17 +429,13 @@ func getDouble(v value.Value) (float32, error) {
 	return 0, fmt.Errorf("value [%s] is not a $$ number", v.String())
 }
 
-func toList(l value.List) ([]float32, error) {
-	var err error
-	ret := make([]float32, l.Len())
-	for i := 0; i < l.Len(); i++ {
-		v, _ := l.At(i)
-		ret[i], err = getDouble(v)
-		if err != nil {
-			return nil, err
-		}
+// toVector returns the float32s of a vector or of a list of numbers
+func toVector(v value.Value) ([]float32, error) {
+	vec, err := value.ToVector(v)
+	if err != nil {
+		return nil, err
 	}
-	return ret, nil
+	return []float32(vec), nil
 }
 
 func FromList(l []float32) value.List {
@@ -193,7 +193,7 @@ func TestClient_InsertStream_GetEmbedding(t *testing.T) {
 		for j := 0; j < 10; j++ {
 			vec[j] = float32(i)
 		}
-		assert.Equal(t, FromList(vec), result[i])
+		assert.Equal(t, value.Vector(vec), result[i])
 	}
 }
 
@@ -118,6 +118,21 @@ func Fetch(ctx context.Context, tier tier.Tier, request action.ActionFetchReques
 	}
 }
 
+// Scan returns upto limit actions with an ID greater than afterID and a timestamp greater than
+// minTimestamp, in increasing order of action ID. It is used to scan the actions in ranges of IDs.
+func Scan(ctx context.Context, tier tier.Tier, afterID ftypes.IDType, minTimestamp ftypes.Timestamp, limit int) ([]action.Action, error) {
+	ctx, t := timer.Start(ctx, tier.ID, "model.action.scan")
+	defer t.Stop()
+	actions := make([]actionSer, 0, limit)
+	err := tier.DB.SelectContext(ctx, &actions,
+		"SELECT * FROM actionlog WHERE action_id > ? AND timestamp > ? ORDER BY action_id LIMIT ?",
+		afterID, minTimestamp, limit)
+	if err != nil {
+		return nil, err
+	}
+	return deserialize(actions...)
+}
+
 func serializeAction(a action.Action) actionSer {
 	return actionSer{
 		ActionID:   a.ActionID,
@@ -146,7 +161,7 @@ func deserialize(alSer ...actionSer) ([]action.Action, error) {
 			RequestID:  ser.RequestID,
 		}
 		var val value.Value
-		val, err := value.FromJSON(ser.Metadata)
+		val, err := value.FromTaggedJSON(ser.Metadata)
 		if err != nil {
 			return nil, err
 		}
@@ -139,3 +139,26 @@ func makeTestActionSer(k int) actionSer {
 		Metadata:   []byte(strconv.Itoa(k+8) + ".0"),
 	}
 }
+
+func TestScan(t *testing.T) {
+	tier := test.Tier(t)
+	defer test.Teardown(tier)
+	ctx := context.Background()
+
+	actions := make([]action.Action, 5)
+	for i := range actions {
+		actions[i] = action.Action{ActorID: "1", ActorType: "2", TargetType: "3", TargetID: "4", ActionType: "5", Metadata: value.Nil, Timestamp: ftypes.Timestamp(10 + i), RequestID: "6"}
+	}
+	assert.NoError(t, InsertBatch(ctx, tier, actions))
+
+	// actions are scanned in increasing order of id, skipping the old ones
+	found, err := Scan(ctx, tier, 0, 10, 2)
+	assert.NoError(t, err)
+	assert.Len(t, found, 2)
+	assert.Equal(t, ftypes.Timestamp(11), found[0].Timestamp)
+	assert.Equal(t, ftypes.Timestamp(12), found[1].Timestamp)
+	found, err = Scan(ctx, tier, found[1].ActionID, 10, 10)
+	assert.NoError(t, err)
+	assert.Len(t, found, 2)
+	assert.Equal(t, ftypes.Timestamp(14), found[1].Timestamp)
+}
@@ -0,0 +1,40 @@
+package apikey
+
+import (
+	"context"
+	"fmt"
+
+	"fennel/lib/apikey"
+	"fennel/tier"
+)
+
+func RetrieveAll(ctx context.Context, tier tier.Tier) ([]apikey.Key, error) {
+	var keys []apikey.Key
+	if err := tier.DB.SelectContext(ctx, &keys, `SELECT key_id, hash, scopes, expires_at FROM api_key`); err != nil {
+		return nil, err
+	}
+	return keys, nil
+}
+
+// ReplaceAll replaces the keys of the tier with the given keys, keys that are not given are revoked
+func ReplaceAll(ctx context.Context, tier tier.Tier, keys []apikey.Key) error {
+	txn, err := tier.DB.BeginTxx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to start txn: %v", err)
+	}
+	defer txn.Rollback()
+	if _, err = txn.ExecContext(ctx, `DELETE FROM api_key`); err != nil {
+		return fmt.Errorf("failed to exec statement: %w", err)
+	}
+	for _, k := range keys {
+		_, err = txn.ExecContext(ctx, `INSERT INTO api_key (key_id, hash, scopes, expires_at) VALUES (?, ?, ?, ?)`,
+			k.ID, k.Hash, k.Scopes, k.ExpiresAt)
+		if err != nil {
+			return fmt.Errorf("failed to exec statement: %w", err)
+		}
+	}
+	if err = txn.Commit(); err != nil {
+		return fmt.Errorf("failed to replace api keys in db: %v", err)
+	}
+	return nil
+}
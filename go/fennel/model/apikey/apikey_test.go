@@ -0,0 +1,35 @@
+package apikey
+
+import (
+	"context"
+	"testing"
+
+	"fennel/lib/apikey"
+	"fennel/test"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestReplaceAll(t *testing.T) {
+	tier := test.Tier(t)
+	defer test.Teardown(tier)
+	ctx := context.Background()
+
+	keys, err := RetrieveAll(ctx, tier)
+	require.NoError(t, err)
+	assert.Empty(t, keys)
+
+	k1 := apikey.Key{ID: "1", Hash: apikey.Hash("1"), Scopes: "log"}
+	k2 := apikey.Key{ID: "2", Hash: apikey.Hash("2"), Scopes: "query,log", ExpiresAt: 100}
+	require.NoError(t, ReplaceAll(ctx, tier, []apikey.Key{k1, k2}))
+	keys, err = RetrieveAll(ctx, tier)
+	require.NoError(t, err)
+	assert.ElementsMatch(t, []apikey.Key{k1, k2}, keys)
+
+	// keys that are not given are removed
+	require.NoError(t, ReplaceAll(ctx, tier, []apikey.Key{k2}))
+	keys, err = RetrieveAll(ctx, tier)
+	require.NoError(t, err)
+	assert.Equal(t, []apikey.Key{k2}, keys)
+}
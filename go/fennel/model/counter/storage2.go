@@ -82,7 +82,7 @@ func (s splitStore) GetMulti(
 			if len(vals[g][index]) == 0 {
 				res[i][j] = defaults[i]
 			} else {
-				v, err := value.FromJSON([]byte(vals[g][index]))
+				v, err := value.FromTaggedJSON([]byte(vals[g][index]))
 				if err != nil {
 					return nil, fmt.Errorf("failed to parse '%s' as value.Value", vals[g][index])
 				}
@@ -32,14 +32,17 @@ func StoreSource(ctx context.Context, tier tier.Tier, src data_integration.Sourc
 		sql := `INSERT INTO bigquery_source (name,  project_id, dataset_id, source_id) VALUES (?, ?, ?, ?)`
 		_, err = tier.DB.QueryContext(ctx, sql, srcDerived.Name, srcDerived.ProjectId, srcDerived.DatasetId, srcId)
 	case data_integration.Postgres:
-		sql := `INSERT INTO postgres_source (name, host, port, db_name, jdbc_params, source_id) VALUES (?, ?, ?, ?, ?, ?)`
-		_, err = tier.DB.QueryContext(ctx, sql, srcDerived.Name, srcDerived.Host, srcDerived.Port, srcDerived.Dbname, srcDerived.JdbcParams, srcId)
+		sql := `INSERT INTO postgres_source (name, host, port, db_name, jdbc_params, source_id, credentials_secret) VALUES (?, ?, ?, ?, ?, ?, ?)`
+		_, err = tier.DB.QueryContext(ctx, sql, srcDerived.Name, srcDerived.Host, srcDerived.Port, srcDerived.Dbname, srcDerived.JdbcParams, srcId, srcDerived.CredentialsSecret)
 	case data_integration.MySQL:
 		sql := `INSERT INTO mysql_source (name, host, port, db_name, jdbc_params, source_id) VALUES (?, ?, ?, ?, ?, ?)`
 		_, err = tier.DB.QueryContext(ctx, sql, srcDerived.Name, srcDerived.Host, srcDerived.Port, srcDerived.Dbname, srcDerived.JdbcParams, srcId)
 	case data_integration.Snowflake:
 		sql := `INSERT INTO snowflake_source (name, host, warehouse, role, db_name, jdbc_params, db_schema, source_id) VALUES (?, ?, ?, ?, ?, ?, ?, ?)`
 		_, err = tier.DB.QueryContext(ctx, sql, srcDerived.Name, srcDerived.Host, srcDerived.Warehouse, srcDerived.Role, srcDerived.Dbname, srcDerived.JdbcParams, srcDerived.Schema, srcId)
+	case data_integration.Local:
+		sql := `INSERT INTO local_source (name, dir, format, delimiter, source_id) VALUES (?, ?, ?, ?, ?)`
+		_, err = tier.DB.QueryContext(ctx, sql, srcDerived.Name, srcDerived.Dir, srcDerived.Format, srcDerived.Delimiter, srcId)
 	default:
 		err = fmt.Errorf("unsupported source type: %T found during storing source", src)
 	}
@@ -76,6 +79,10 @@ func RetrieveSource(ctx context.Context, tier tier.Tier, srcName string) (data_i
 		var src data_integration.Snowflake
 		err = tier.DB.GetContext(ctx, &src, "SELECT * FROM snowflake_source WHERE name = ?", srcName)
 		return src, err
+	case "Local":
+		var src data_integration.Local
+		err = tier.DB.GetContext(ctx, &src, "SELECT * FROM local_source WHERE name = ?", srcName)
+		return src, err
 	default:
 		return nil, fmt.Errorf("unsupported source type: %s found during retrieving source", srcSer.Type)
 	}
@@ -97,6 +104,8 @@ func DeleteSource(ctx context.Context, tier tier.Tier, src data_integration.Sour
 		_, err = tier.DB.ExecContext(ctx, "DELETE FROM mysql_source WHERE name = ?", srcDerived.Name)
 	case data_integration.Snowflake:
 		_, err = tier.DB.ExecContext(ctx, "DELETE FROM snowflake_source WHERE name = ?", srcDerived.Name)
+	case data_integration.Local:
+		_, err = tier.DB.ExecContext(ctx, "DELETE FROM local_source WHERE name = ?", srcDerived.Name)
 	default:
 		err = fmt.Errorf("unsupported source type: %T found during deleting source", srcDerived)
 	}
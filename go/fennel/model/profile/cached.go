@@ -24,14 +24,70 @@ const cacheVersion = 0
 // Public API for profile model (includes caching)
 //================================================
 
-func Set(ctx context.Context, tier tier.Tier, profile profile.ProfileItem) error {
-	return cachedProvider{base: dbProvider{}}.set(ctx, tier, profile)
+func Set(ctx context.Context, tier tier.Tier, profileItem profile.ProfileItem) error {
+	if err := writeHistory(ctx, tier, []profile.ProfileItem{profileItem}); err != nil {
+		return err
+	}
+	return cachedProvider{base: dbProvider{}}.set(ctx, tier, profileItem)
 }
 
+// SetBatch sets the profiles and appends them to the history of profiles whose policy retains history
 func SetBatch(ctx context.Context, tier tier.Tier, profiles []profile.ProfileItem) error {
+	// history is written first, so that retries of failed writes fill in any gaps
+	if err := writeHistory(ctx, tier, profiles); err != nil {
+		return err
+	}
 	return cachedProvider{base: dbProvider{}}.setBatch(ctx, tier, profiles)
 }
 
+// SetBatchWithChanges is like SetBatch but also returns the changes that were applied, profiles
+// that were older than the ones already set are not changed. The old values are read from the
+// database before the write, so changes by concurrent writers of the same profiles may be missed.
+func SetBatchWithChanges(ctx context.Context, tier tier.Tier, profiles []profile.ProfileItem) ([]profile.Change, error) {
+	ctx, t := timer.Start(ctx, tier.ID, "model.profile.set_batch_with_changes")
+	defer t.Stop()
+	// update times are filled in on a copy so that the changes have the same update times as the profiles
+	profiles = append([]profile.ProfileItem(nil), profiles...)
+	latest := make(map[profile.ProfileItemKey]profile.ProfileItem, len(profiles))
+	keys := make([]profile.ProfileItemKey, 0, len(profiles))
+	for i := range profiles {
+		if profiles[i].UpdateTime == 0 {
+			profiles[i].UpdateTime = uint64(tier.Clock.Now().UnixMicro())
+		}
+		p := profiles[i]
+		pk := p.GetProfileKey()
+		if l, ok := latest[pk]; !ok {
+			keys = append(keys, pk)
+			latest[pk] = p
+		} else if p.UpdateTime > l.UpdateTime {
+			latest[pk] = p
+		}
+	}
+	old, err := getCurrent(ctx, tier, keys)
+	if err != nil {
+		return nil, err
+	}
+	if err = SetBatch(ctx, tier, profiles); err != nil {
+		return nil, err
+	}
+	changes := make([]profile.Change, 0, len(keys))
+	for _, pk := range keys {
+		p := latest[pk]
+		prev, ok := old[pk]
+		if !ok {
+			prev = profile.NewProfileItem(pk.OType, pk.Oid, pk.Key, value.Nil, 0)
+		} else if p.UpdateTime <= prev.UpdateTime {
+			continue
+		}
+		changes = append(changes, profile.Change{
+			OType: pk.OType, Oid: pk.Oid, Key: pk.Key,
+			OldValue: prev.Value, NewValue: p.Value,
+			OldUpdateTime: prev.UpdateTime, UpdateTime: p.UpdateTime,
+		})
+	}
+	return changes, nil
+}
+
 func Get(ctx context.Context, tier tier.Tier, profileKey profile.ProfileItemKey) (profile.ProfileItem, error) {
 	return cachedProvider{base: dbProvider{}}.get(ctx, tier, profileKey)
 }
@@ -185,9 +241,9 @@ func (c cachedProvider) query(ctx context.Context, tier tier.Tier, otype ftypes.
 func getValueFromCache(v interface{}) (value.Value, error) {
 	switch t := v.(type) {
 	case []byte:
-		return value.FromJSON(t)
+		return value.FromTaggedJSON(t)
 	case string:
-		return value.FromJSON([]byte(t))
+		return value.FromTaggedJSON([]byte(t))
 	default:
 		return nil, fmt.Errorf("value not of type []byte or string: %v", v)
 	}
@@ -43,7 +43,7 @@ type profileItemSer struct {
 
 func (ser *profileItemSer) toProfileItem() (profile.ProfileItem, error) {
 	pr := profile.NewProfileItem(ser.OType, ser.Oid, ser.Key, value.Nil, ser.UpdateTime)
-	val, err := value.FromJSON(ser.Value)
+	val, err := value.FromTaggedJSON(ser.Value)
 	if err != nil {
 		return pr, err
 	}
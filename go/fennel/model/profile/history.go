@@ -0,0 +1,248 @@
+package profile
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"fennel/lib/ftypes"
+	"fennel/lib/profile"
+	"fennel/lib/timer"
+	"fennel/lib/value"
+	"fennel/tier"
+)
+
+// writeHistory appends the profiles whose policy has a history retention to the profile history
+func writeHistory(ctx context.Context, tier tier.Tier, profiles []profile.ProfileItem) error {
+	ctx, t := timer.Start(ctx, tier.ID, "model.profile.db.writeHistory")
+	defer t.Stop()
+	policies, err := cachedPolicies(ctx, tier)
+	if err != nil || len(policies) == 0 {
+		return err
+	}
+	sql := `INSERT IGNORE INTO profile_history (otype, oid, zkey, version, value) VALUES `
+	vals := make([]interface{}, 0)
+	for _, p := range profiles {
+		if policy, ok := policies.For(p.OType, p.Key); !ok || policy.HistoryRetention == 0 {
+			continue
+		}
+		ser := toProfileItemSer(p)
+		if ser.UpdateTime == 0 {
+			ser.UpdateTime = uint64(time.Now().UnixMicro())
+		}
+		sql += "(?, ?, ?, ?, ?),"
+		vals = append(vals, ser.OType, ser.Oid, ser.Key, ser.UpdateTime, ser.Value)
+	}
+	if len(vals) == 0 {
+		return nil
+	}
+	_, err = tier.DB.ExecContext(ctx, strings.TrimSuffix(sql, ","), vals...)
+	return err
+}
+
+// GetHistory returns the values of the profile that were set between start and end (both in
+// microseconds and inclusive), oldest first. Only values set while the profile had a policy with a
+// history retention are returned.
+func GetHistory(ctx context.Context, tier tier.Tier, pk profile.ProfileItemKey, start, end uint64) ([]profile.ProfileItem, error) {
+	ctx, t := timer.Start(ctx, tier.ID, "model.profile.db.getHistory")
+	defer t.Stop()
+	rows := make([]profileItemSer, 0)
+	err := tier.DB.SelectContext(ctx, &rows, `
+		SELECT otype, oid, zkey, value, version
+		FROM profile_history
+		WHERE otype = ? AND oid = ? AND zkey = ? AND version >= ? AND version <= ?
+		ORDER BY version`, pk.OType, pk.Oid, pk.Key, start, end)
+	if err != nil {
+		return nil, err
+	}
+	ret := make([]profile.ProfileItem, len(rows))
+	for i := range rows {
+		if ret[i], err = rows[i].toProfileItem(); err != nil {
+			return nil, err
+		}
+	}
+	return ret, nil
+}
+
+// GetBatchAsOf returns the values the profiles had at asOf (in microseconds), the value of
+// profiles that were not set at that time is value.Nil. Values set before asOf are found in the
+// history if the profile has a history retention that covers asOf, or otherwise if the current
+// value was already set at asOf.
+func GetBatchAsOf(ctx context.Context, tier tier.Tier, profileKeys []profile.ProfileItemKey, asOf uint64) ([]profile.ProfileItem, error) {
+	ctx, t := timer.Start(ctx, tier.ID, "model.profile.db.getBatchAsOf")
+	defer t.Stop()
+	if len(profileKeys) == 0 {
+		return []profile.ProfileItem{}, nil
+	}
+	unique := make(map[profile.ProfileItemKey]struct{}, len(profileKeys))
+	in := make([]string, 0, len(profileKeys))
+	keyVals := make([]interface{}, 0, 3*len(profileKeys))
+	for _, pk := range profileKeys {
+		if _, ok := unique[pk]; ok {
+			continue
+		}
+		unique[pk] = struct{}{}
+		in = append(in, "(?, ?, ?)")
+		keyVals = append(keyVals, pk.OType, pk.Oid, pk.Key)
+	}
+	inClause := "(" + strings.Join(in, ",") + ")"
+
+	current := make([]profileItemSer, 0)
+	err := tier.DB.SelectContext(ctx, &current, `
+		SELECT otype, oid, zkey, value, version
+		FROM profile
+		WHERE (otype, oid, zkey) IN `+inClause+` AND version <= ?`, append(keyVals, asOf)...)
+	if err != nil {
+		return nil, err
+	}
+	history := make([]profileItemSer, 0)
+	err = tier.DB.SelectContext(ctx, &history, `
+		SELECT h.otype, h.oid, h.zkey, h.value, h.version
+		FROM profile_history h
+		WHERE (h.otype, h.oid, h.zkey) IN `+inClause+` AND h.version = (
+			SELECT MAX(version) FROM profile_history
+			WHERE otype = h.otype AND oid = h.oid AND zkey = h.zkey AND version <= ?
+		)`, append(keyVals, asOf)...)
+	if err != nil {
+		return nil, err
+	}
+	// the latest version at asOf is either in the history or is the current value
+	latest := make(map[profile.ProfileItemKey]profileItemSer, len(unique))
+	for _, rows := range [][]profileItemSer{current, history} {
+		for _, r := range rows {
+			pk := profile.NewProfileItemKey(r.OType, r.Oid, r.Key)
+			if l, ok := latest[pk]; !ok || r.UpdateTime > l.UpdateTime {
+				latest[pk] = r
+			}
+		}
+	}
+	ret := make([]profile.ProfileItem, len(profileKeys))
+	for i, pk := range profileKeys {
+		ser, ok := latest[pk]
+		if !ok {
+			ret[i] = profile.NewProfileItem(pk.OType, pk.Oid, pk.Key, value.Nil, 0)
+			continue
+		}
+		if ret[i], err = ser.toProfileItem(); err != nil {
+			return nil, err
+		}
+	}
+	return ret, nil
+}
+
+// DeleteHistory deletes the history of profiles with the otype and key (or all keys of the otype
+// except the excluded ones if key is profile.AnyKey) that is older than cutoff (in microseconds).
+// The last value set before cutoff is kept so that the value as of any time after cutoff is known.
+func DeleteHistory(ctx context.Context, tier tier.Tier, otype ftypes.OType, key string, exclude []string, cutoff uint64) (int64, error) {
+	cond, vals := keyCondition(otype, key, exclude)
+	res, err := tier.DB.ExecContext(ctx, `
+		DELETE h FROM profile_history h
+		JOIN (
+			SELECT otype, oid, zkey, MAX(version) AS version
+			FROM profile_history
+			WHERE `+cond+` AND version < ?
+			GROUP BY otype, oid, zkey
+		) k ON h.otype = k.otype AND h.oid = k.oid AND h.zkey = k.zkey AND h.version < k.version`,
+		append(vals, cutoff)...)
+	if err != nil {
+		return 0, err
+	}
+	return res.RowsAffected()
+}
+
+// DeleteExpired deletes up to limit profiles with the otype and key (or all keys of the otype
+// except the excluded ones if key is profile.AnyKey) that were last updated before cutoff (in
+// microseconds) and returns the deletions as changes of the profiles to value.Nil
+func DeleteExpired(ctx context.Context, tier tier.Tier, otype ftypes.OType, key string, exclude []string, cutoff uint64, limit int) ([]profile.Change, error) {
+	cond, vals := keyCondition(otype, key, exclude)
+	txn, err := tier.DB.BeginTxx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to start txn: %v", err)
+	}
+	defer txn.Rollback()
+	// the rows are locked so that profiles updated concurrently are neither deleted nor missed
+	rows := make([]profileItemSer, 0)
+	err = txn.SelectContext(ctx, &rows, `
+		SELECT otype, oid, zkey, value, version FROM profile WHERE `+cond+` AND version < ? LIMIT ? FOR UPDATE`,
+		append(vals, cutoff, limit)...)
+	if err != nil || len(rows) == 0 {
+		return nil, err
+	}
+	in := make([]string, len(rows))
+	keyVals := make([]interface{}, 0, 3*len(rows))
+	cacheKeys := make([]string, len(rows))
+	changes := make([]profile.Change, len(rows))
+	now := uint64(tier.Clock.Now().UnixMicro())
+	for i, r := range rows {
+		p, err := r.toProfileItem()
+		if err != nil {
+			return nil, err
+		}
+		in[i] = "(?, ?, ?)"
+		keyVals = append(keyVals, r.OType, r.Oid, r.Key)
+		cacheKeys[i] = makeKey(p.GetProfileKey())
+		changes[i] = profile.Change{
+			OType: p.OType, Oid: p.Oid, Key: p.Key,
+			OldValue: p.Value, NewValue: value.Nil,
+			OldUpdateTime: p.UpdateTime, UpdateTime: now,
+		}
+	}
+	_, err = txn.ExecContext(ctx, `
+		DELETE FROM profile WHERE (otype, oid, zkey) IN (`+strings.Join(in, ",")+`)`, keyVals...)
+	if err != nil {
+		return nil, err
+	}
+	if err = txn.Commit(); err != nil {
+		return nil, err
+	}
+	if err = tier.Cache.Delete(ctx, cacheKeys...); err != nil {
+		return nil, err
+	}
+	return changes, nil
+}
+
+func keyCondition(otype ftypes.OType, key string, exclude []string) (string, []interface{}) {
+	if key != profile.AnyKey {
+		return "otype = ? AND zkey = ?", []interface{}{otype, key}
+	}
+	if len(exclude) == 0 {
+		return "otype = ?", []interface{}{otype}
+	}
+	vals := []interface{}{otype}
+	for _, k := range exclude {
+		vals = append(vals, k)
+	}
+	return "otype = ? AND zkey NOT IN (?" + strings.Repeat(", ?", len(exclude)-1) + ")", vals
+}
+
+// getCurrent returns the profiles, with their update times, as they are in the database. Profiles
+// that are not set are not in the returned map.
+func getCurrent(ctx context.Context, tier tier.Tier, profileKeys []profile.ProfileItemKey) (map[profile.ProfileItemKey]profile.ProfileItem, error) {
+	ret := make(map[profile.ProfileItemKey]profile.ProfileItem, len(profileKeys))
+	if len(profileKeys) == 0 {
+		return ret, nil
+	}
+	in := make([]string, len(profileKeys))
+	keyVals := make([]interface{}, 0, 3*len(profileKeys))
+	for i, pk := range profileKeys {
+		in[i] = "(?, ?, ?)"
+		keyVals = append(keyVals, pk.OType, pk.Oid, pk.Key)
+	}
+	rows := make([]profileItemSer, 0)
+	err := tier.DB.SelectContext(ctx, &rows, `
+		SELECT otype, oid, zkey, value, version
+		FROM profile
+		WHERE (otype, oid, zkey) IN (`+strings.Join(in, ",")+`)`, keyVals...)
+	if err != nil {
+		return nil, err
+	}
+	for _, r := range rows {
+		p, err := r.toProfileItem()
+		if err != nil {
+			return nil, err
+		}
+		ret[p.GetProfileKey()] = p
+	}
+	return ret, nil
+}
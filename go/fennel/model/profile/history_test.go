@@ -0,0 +1,68 @@
+package profile
+
+import (
+	"context"
+	"testing"
+
+	"fennel/lib/profile"
+	"fennel/lib/value"
+	"fennel/test"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHistory(t *testing.T) {
+	tier := test.Tier(t)
+	defer test.Teardown(tier)
+	ctx := context.Background()
+
+	assert.NoError(t, SetPolicy(ctx, tier, profile.Policy{OType: "user", Key: profile.AnyKey, HistoryRetention: 3600}))
+	pk := profile.NewProfileItemKey("user", "1", "age")
+	for i := 1; i <= 3; i++ {
+		assert.NoError(t, Set(ctx, tier, profile.NewProfileItem("user", "1", "age", value.Int(i), uint64(i*10))))
+	}
+	// profiles without a policy have no history
+	assert.NoError(t, Set(ctx, tier, profile.NewProfileItem("video", "1", "age", value.Int(1), 10)))
+
+	history, err := GetHistory(ctx, tier, pk, 0, 100)
+	assert.NoError(t, err)
+	assert.Len(t, history, 3)
+	for i, p := range history {
+		assert.Equal(t, value.Int(i+1), p.Value)
+		assert.Equal(t, uint64((i+1)*10), p.UpdateTime)
+	}
+	history, err = GetHistory(ctx, tier, profile.NewProfileItemKey("video", "1", "age"), 0, 100)
+	assert.NoError(t, err)
+	assert.Empty(t, history)
+
+	keys := []profile.ProfileItemKey{pk, profile.NewProfileItemKey("user", "2", "age")}
+	found, err := GetBatchAsOf(ctx, tier, keys, 25)
+	assert.NoError(t, err)
+	assert.Equal(t, value.Int(2), found[0].Value)
+	assert.Equal(t, value.Nil, found[1].Value)
+	found, err = GetBatchAsOf(ctx, tier, keys, 5)
+	assert.NoError(t, err)
+	assert.Equal(t, value.Nil, found[0].Value)
+
+	// deleting the history before 25 keeps the value set at 20
+	n, err := DeleteHistory(ctx, tier, "user", profile.AnyKey, nil, 25)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(1), n)
+	found, err = GetBatchAsOf(ctx, tier, keys, 25)
+	assert.NoError(t, err)
+	assert.Equal(t, value.Int(2), found[0].Value)
+
+	// the profile expires once its last update is older than the cutoff
+	deleted, err := DeleteExpired(ctx, tier, "user", profile.AnyKey, []string{"name"}, 30, 10)
+	assert.NoError(t, err)
+	assert.Empty(t, deleted)
+	deleted, err = DeleteExpired(ctx, tier, "user", profile.AnyKey, []string{"name"}, 31, 10)
+	assert.NoError(t, err)
+	assert.Len(t, deleted, 1)
+	assert.Equal(t, value.Int(3), deleted[0].OldValue)
+	assert.Equal(t, uint64(30), deleted[0].OldUpdateTime)
+	assert.Equal(t, value.Nil, deleted[0].NewValue)
+	got, err := Get(ctx, tier, pk)
+	assert.NoError(t, err)
+	assert.Equal(t, value.Nil, got.Value)
+}
@@ -0,0 +1,146 @@
+package profile
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+
+	"fennel/lib/ftypes"
+	"fennel/lib/profile"
+	"fennel/tier"
+)
+
+type jobSer struct {
+	profile.Job
+	ErrorSamples []byte `db:"error_samples"`
+}
+
+func (j jobSer) toJob() (profile.Job, error) {
+	job := j.Job
+	job.ErrorSamples = make([]string, 0)
+	if len(j.ErrorSamples) > 0 {
+		if err := json.Unmarshal(j.ErrorSamples, &job.ErrorSamples); err != nil {
+			return profile.Job{}, fmt.Errorf("invalid error samples of job %d: %w", j.ID, err)
+		}
+	}
+	return job, nil
+}
+
+// InsertJob inserts a pending job and returns its id
+func InsertJob(ctx context.Context, tier tier.Tier, request profile.JobRequest) (uint64, error) {
+	now := tier.Clock.Now().Unix()
+	res, err := tier.DB.ExecContext(ctx, `
+		INSERT INTO profile_job (type, format, location, otype, status, error_samples, error, created_at, updated_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		request.Type, request.Format, request.Location, request.OType, profile.JobPending, []byte("[]"), "", now, now)
+	if err != nil {
+		return 0, err
+	}
+	id, err := res.LastInsertId()
+	if err != nil {
+		return 0, err
+	}
+	return uint64(id), nil
+}
+
+// UpdateJob updates the status and progress of the job
+func UpdateJob(ctx context.Context, tier tier.Tier, job profile.Job) error {
+	samples, err := json.Marshal(job.ErrorSamples)
+	if err != nil {
+		return err
+	}
+	_, err = tier.DB.ExecContext(ctx, `
+		UPDATE profile_job
+		SET status = ?, rows_read = ?, rows_written = ?, rows_failed = ?, error_samples = ?, error = ?, updated_at = ?
+		WHERE id = ?`,
+		job.Status, job.RowsRead, job.RowsWritten, job.RowsFailed, samples, job.Error, tier.Clock.Now().Unix(), job.ID)
+	return err
+}
+
+// ClaimJob marks the oldest pending job, or a running job whose lease expired before now, as
+// running until leaseUntil and returns it. It returns false if there is no job to claim.
+func ClaimJob(ctx context.Context, tier tier.Tier, now, leaseUntil int64) (profile.Job, bool, error) {
+	txn, err := tier.DB.BeginTxx(ctx, nil)
+	if err != nil {
+		return profile.Job{}, false, fmt.Errorf("failed to start txn: %v", err)
+	}
+	defer txn.Rollback()
+	var ser jobSer
+	err = txn.GetContext(ctx, &ser, `
+		SELECT * FROM profile_job
+		WHERE status = ? OR (status = ? AND lease_until < ?)
+		ORDER BY id LIMIT 1 FOR UPDATE`, profile.JobPending, profile.JobRunning, now)
+	if err == sql.ErrNoRows {
+		return profile.Job{}, false, nil
+	} else if err != nil {
+		return profile.Job{}, false, err
+	}
+	// jobs that were claimed before start over
+	_, err = txn.ExecContext(ctx, `
+		UPDATE profile_job
+		SET status = ?, rows_read = 0, rows_written = 0, rows_failed = 0, error_samples = ?, error = '', updated_at = ?, lease_until = ?
+		WHERE id = ?`, profile.JobRunning, []byte("[]"), now, leaseUntil, ser.ID)
+	if err != nil {
+		return profile.Job{}, false, err
+	}
+	if err = txn.Commit(); err != nil {
+		return profile.Job{}, false, err
+	}
+	job := profile.Job{ID: ser.ID, JobRequest: ser.JobRequest, Status: profile.JobRunning, ErrorSamples: []string{},
+		CreatedAt: ser.CreatedAt, UpdatedAt: now, LeaseUntil: leaseUntil}
+	return job, true, nil
+}
+
+// RenewJobLease extends the lease of a running job
+func RenewJobLease(ctx context.Context, tier tier.Tier, id uint64, leaseUntil int64) error {
+	_, err := tier.DB.ExecContext(ctx, `UPDATE profile_job SET lease_until = ? WHERE id = ? AND status = ?`,
+		leaseUntil, id, profile.JobRunning)
+	return err
+}
+
+func RetrieveJob(ctx context.Context, tier tier.Tier, id uint64) (profile.Job, error) {
+	var ser jobSer
+	if err := tier.DB.GetContext(ctx, &ser, `SELECT * FROM profile_job WHERE id = ?`, id); err != nil {
+		return profile.Job{}, err
+	}
+	return ser.toJob()
+}
+
+// RetrieveJobs returns the latest jobs, newest first
+func RetrieveJobs(ctx context.Context, tier tier.Tier, limit int) ([]profile.Job, error) {
+	sers := make([]jobSer, 0)
+	if err := tier.DB.SelectContext(ctx, &sers, `SELECT * FROM profile_job ORDER BY id DESC LIMIT ?`, limit); err != nil {
+		return nil, err
+	}
+	jobs := make([]profile.Job, len(sers))
+	for i, ser := range sers {
+		var err error
+		if jobs[i], err = ser.toJob(); err != nil {
+			return nil, err
+		}
+	}
+	return jobs, nil
+}
+
+// Scan returns up to limit profiles of the otype, ordered by oid and key, that come after the
+// given oid and key. It is used to page through all profiles of an otype.
+func Scan(ctx context.Context, tier tier.Tier, otype ftypes.OType, afterOid ftypes.OidType, afterKey string, limit int) ([]profile.ProfileItem, error) {
+	rows := make([]profileItemSer, 0)
+	err := tier.DB.SelectContext(ctx, &rows, `
+		SELECT otype, oid, zkey, value, version
+		FROM profile
+		WHERE otype = ? AND (oid, zkey) > (?, ?)
+		ORDER BY oid, zkey
+		LIMIT ?`, otype, afterOid, afterKey, limit)
+	if err != nil {
+		return nil, err
+	}
+	ret := make([]profile.ProfileItem, len(rows))
+	for i := range rows {
+		if ret[i], err = rows[i].toProfileItem(); err != nil {
+			return nil, err
+		}
+	}
+	return ret, nil
+}
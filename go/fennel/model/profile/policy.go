@@ -0,0 +1,64 @@
+package profile
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"fennel/lib/ftypes"
+	"fennel/lib/profile"
+	"fennel/tier"
+)
+
+// policies rarely change and are read on every write of profiles, so they are cached
+const policyCacheDuration = 30 * time.Second
+
+const policyCacheNamespace = "ProfilePolicy"
+const policyCacheKey = "profile_policies"
+
+func SetPolicy(ctx context.Context, tier tier.Tier, policy profile.Policy) error {
+	if err := policy.Validate(); err != nil {
+		return fmt.Errorf("invalid policy: %v", err)
+	}
+	_, err := tier.DB.ExecContext(ctx, `
+		INSERT INTO profile_policy (otype, zkey, history_retention, ttl) VALUES (?, ?, ?, ?)
+		ON DUPLICATE KEY UPDATE history_retention = VALUES(history_retention), ttl = VALUES(ttl)`,
+		policy.OType, policy.Key, policy.HistoryRetention, policy.TTL)
+	if err != nil {
+		return err
+	}
+	tier.Invalidations.Invalidate(ctx, policyCacheNamespace, policyCacheKey)
+	return nil
+}
+
+func DeletePolicy(ctx context.Context, tier tier.Tier, otype ftypes.OType, key string) error {
+	_, err := tier.DB.ExecContext(ctx, `DELETE FROM profile_policy WHERE otype = ? AND zkey = ?`, otype, key)
+	if err != nil {
+		return err
+	}
+	tier.Invalidations.Invalidate(ctx, policyCacheNamespace, policyCacheKey)
+	return nil
+}
+
+func RetrievePolicies(ctx context.Context, tier tier.Tier) (profile.Policies, error) {
+	policies := make(profile.Policies, 0)
+	err := tier.DB.SelectContext(ctx, &policies, `SELECT otype, zkey, history_retention, ttl FROM profile_policy`)
+	if err != nil {
+		return nil, err
+	}
+	return policies, nil
+}
+
+func cachedPolicies(ctx context.Context, tier tier.Tier) (profile.Policies, error) {
+	if v, ok := tier.PCache.Get(policyCacheKey, policyCacheNamespace); ok {
+		if policies, ok := v.(profile.Policies); ok {
+			return policies, nil
+		}
+	}
+	policies, err := RetrievePolicies(ctx, tier)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get profile policies: %w", err)
+	}
+	tier.PCache.SetWithTTL(policyCacheKey, policies, int64(len(policies)), policyCacheDuration, policyCacheNamespace)
+	return policies, nil
+}
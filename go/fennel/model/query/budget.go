@@ -0,0 +1,30 @@
+package query
+
+import (
+	"context"
+	"database/sql"
+
+	"fennel/lib/ftypes"
+	"fennel/tier"
+)
+
+// InsertBudget sets the serialized budget of the query, replacing its previous budget
+func InsertBudget(ctx context.Context, tier tier.Tier, name string, budgetSer []byte, updatedAt ftypes.Timestamp) error {
+	_, err := tier.DB.ExecContext(ctx, `
+		INSERT INTO query_budget (query_name, budget_ser, updated_at) VALUES (?, ?, ?)
+		ON DUPLICATE KEY UPDATE budget_ser = VALUES(budget_ser), updated_at = VALUES(updated_at)`,
+		name, budgetSer, updatedAt)
+	return err
+}
+
+// RetrieveBudget returns the serialized budget of the query
+func RetrieveBudget(ctx context.Context, tier tier.Tier, name string) ([]byte, error) {
+	var budgetSer []byte
+	err := tier.DB.GetContext(ctx, &budgetSer, "SELECT budget_ser FROM query_budget WHERE query_name = ?", name)
+	if err == sql.ErrNoRows {
+		return nil, ErrNotFound
+	} else if err != nil {
+		return nil, err
+	}
+	return budgetSer, nil
+}
@@ -4,6 +4,8 @@ import (
 	"context"
 	"database/sql"
 	"errors"
+	"fmt"
+
 	"fennel/lib/ftypes"
 	"fennel/lib/query"
 	"fennel/tier"
@@ -11,22 +13,57 @@ import (
 
 var ErrNotFound = errors.New("Query not found")
 
-func Insert(tier tier.Tier, name string, timestamp ftypes.Timestamp, querySer []byte, description string) (uint64, error) {
-	sql := "INSERT INTO query_ast (name, timestamp, query_ser, description) VALUES (?, ?, ?, ?);"
-	res, err := tier.DB.Exec(sql, name, timestamp, querySer, description)
+// Insert stores the query as the next version of the query with the given name and returns the
+// id and version of the stored query. The first version of a query is also the target of its
+// DefaultAlias, so that later versions only run by default once the alias is moved to them.
+func Insert(tier tier.Tier, name string, timestamp ftypes.Timestamp, querySer []byte, description string, paramsSer []byte) (uint64, uint32, error) {
+	txn, err := tier.DB.Beginx()
 	if err != nil {
-		return 0, err
+		return 0, 0, fmt.Errorf("failed to start txn: %v", err)
+	}
+	defer txn.Rollback()
+	var latest uint32
+	if err = txn.Get(&latest, "SELECT COALESCE(MAX(version), 0) FROM query_ast WHERE name = ? FOR UPDATE", name); err != nil {
+		return 0, 0, err
+	}
+	version := latest + 1
+	sql := "INSERT INTO query_ast (name, version, timestamp, query_ser, description, params_ser) VALUES (?, ?, ?, ?, ?, ?);"
+	res, err := txn.Exec(sql, name, version, timestamp, querySer, description, paramsSer)
+	if err != nil {
+		return 0, 0, err
 	}
 	queryID, err := res.LastInsertId()
 	if err != nil {
-		return 0, err
+		return 0, 0, err
 	}
-	return uint64(queryID), nil
+	if version == 1 {
+		_, err = txn.Exec("INSERT INTO query_alias (query_name, alias, version, updated_at) VALUES (?, ?, ?, ?)",
+			name, query.DefaultAlias, version, timestamp)
+		if err != nil {
+			return 0, 0, err
+		}
+	}
+	if err = txn.Commit(); err != nil {
+		return 0, 0, fmt.Errorf("failed to insert query in db: %v", err)
+	}
+	return uint64(queryID), version, nil
 }
 
+// Retrieve returns the latest version of the query with the given name
 func Retrieve(ctx context.Context, tier tier.Tier, name string) (query.QuerySer, error) {
 	var query query.QuerySer
-	err := tier.DB.GetContext(ctx, &query, "SELECT * FROM query_ast WHERE name = ? limit 1", name)
+	err := tier.DB.GetContext(ctx, &query, "SELECT * FROM query_ast WHERE name = ? ORDER BY version DESC LIMIT 1", name)
+	if err != nil && err == sql.ErrNoRows {
+		return query, ErrNotFound
+	} else if err != nil {
+		return query, err
+	}
+	return query, nil
+}
+
+func RetrieveVersion(ctx context.Context, tier tier.Tier, name string, version uint32) (query.QuerySer, error) {
+	var query query.QuerySer
+	err := tier.DB.GetContext(ctx, &query, "SELECT * FROM query_ast WHERE name = ? AND version = ?", name, version)
 	if err != nil && err == sql.ErrNoRows {
 		return query, ErrNotFound
 	} else if err != nil {
@@ -35,11 +72,75 @@ func Retrieve(ctx context.Context, tier tier.Tier, name string) (query.QuerySer,
 	return query, nil
 }
 
+// RetrieveVersions returns all versions of the query with the given name, oldest first
+func RetrieveVersions(ctx context.Context, tier tier.Tier, name string) ([]query.QuerySer, error) {
+	var queries []query.QuerySer
+	err := tier.DB.SelectContext(ctx, &queries, "SELECT * FROM query_ast WHERE name = ? ORDER BY version", name)
+	if err != nil {
+		return nil, err
+	}
+	return queries, nil
+}
+
+// RetrieveAll returns the latest version of every query
 func RetrieveAll(ctx context.Context, tier tier.Tier) ([]query.QuerySer, error) {
 	var queries []query.QuerySer
-	err := tier.DB.SelectContext(ctx, &queries, "SELECT * FROM query_ast")
+	err := tier.DB.SelectContext(ctx, &queries, `
+		SELECT * FROM query_ast q
+		WHERE version = (SELECT MAX(version) FROM query_ast WHERE name = q.name)`)
 	if err != nil {
 		return nil, err
 	}
 	return queries, nil
 }
+
+// InsertAlias records a change of the alias, older targets are kept so that they can be rolled back to
+func InsertAlias(ctx context.Context, tier tier.Tier, alias query.Alias) (uint64, error) {
+	res, err := tier.DB.ExecContext(ctx,
+		"INSERT INTO query_alias (query_name, alias, version, split_ser, updated_at) VALUES (?, ?, ?, ?, ?)",
+		alias.QueryName, alias.Alias, alias.Version, alias.SplitSer, alias.UpdatedAt)
+	if err != nil {
+		return 0, err
+	}
+	id, err := res.LastInsertId()
+	if err != nil {
+		return 0, err
+	}
+	return uint64(id), nil
+}
+
+// RetrieveAliasHistory returns up to limit targets of the alias, the current target first
+func RetrieveAliasHistory(ctx context.Context, tier tier.Tier, name, alias string, limit uint32) ([]query.Alias, error) {
+	var aliases []query.Alias
+	err := tier.DB.SelectContext(ctx, &aliases,
+		"SELECT * FROM query_alias WHERE query_name = ? AND alias = ? ORDER BY id DESC LIMIT ?", name, alias, limit)
+	if err != nil {
+		return nil, err
+	}
+	return aliases, nil
+}
+
+// RetrieveAlias returns the current target of the alias
+func RetrieveAlias(ctx context.Context, tier tier.Tier, name, alias string) (query.Alias, error) {
+	aliases, err := RetrieveAliasHistory(ctx, tier, name, alias, 1)
+	if err != nil {
+		return query.Alias{}, err
+	}
+	if len(aliases) == 0 {
+		return query.Alias{}, ErrNotFound
+	}
+	return aliases[0], nil
+}
+
+// RetrieveAliases returns the current target of every alias of the query
+func RetrieveAliases(ctx context.Context, tier tier.Tier, name string) ([]query.Alias, error) {
+	var aliases []query.Alias
+	err := tier.DB.SelectContext(ctx, &aliases, `
+		SELECT * FROM query_alias a
+		WHERE query_name = ? AND id = (SELECT MAX(id) FROM query_alias WHERE query_name = a.query_name AND alias = a.alias)
+		ORDER BY alias`, name)
+	if err != nil {
+		return nil, err
+	}
+	return aliases, nil
+}
@@ -23,20 +23,75 @@ func TestInsertGet(t *testing.T) {
 
 	// set a couple of queries and verify we can get them
 	ts1 := ftypes.Timestamp(1)
-	query1 := query.QuerySer{QueryId: 0, Name: "name", Timestamp: ts1, QuerySer: []byte("hello"), Description: "description"}
+	query1 := query.QuerySer{QueryId: 0, Name: "name", Version: 1, Timestamp: ts1, QuerySer: []byte("hello"), Description: "description"}
 
-	queryID1, err := Insert(tier, query1.Name, query1.Timestamp, query1.QuerySer, "description")
+	queryID1, version, err := Insert(tier, query1.Name, query1.Timestamp, query1.QuerySer, "description", nil)
 	assert.NoError(t, err)
+	assert.Equal(t, uint32(1), version)
 	query1.QueryId = queryID1
+	// the default alias of a new query points at its first version
+	alias, err := RetrieveAlias(context.Background(), tier, "name", query.DefaultAlias)
+	assert.NoError(t, err)
+	assert.Equal(t, uint32(1), alias.Version)
+	assert.Equal(t, ts1, alias.UpdatedAt)
 
 	verifyRetrieve(t, tier, "name", query1)
 	verifyRetrieve(t, tier, query1.Name, query1)
 
 	ts2 := ftypes.Timestamp(3)
-	query2 := query.QuerySer{QueryId: 0, Name: "query2", Timestamp: ts2, QuerySer: []byte("bye"), Description: "description2"}
-	queryID2, err := Insert(tier, query2.Name, query2.Timestamp, query2.QuerySer, "description2")
+	query2 := query.QuerySer{QueryId: 0, Name: "query2", Version: 1, Timestamp: ts2, QuerySer: []byte("bye"), Description: "description2", ParamsSer: []byte("[]")}
+	queryID2, _, err := Insert(tier, query2.Name, query2.Timestamp, query2.QuerySer, "description2", query2.ParamsSer)
 	assert.NoError(t, err)
 	query2.QueryId = queryID2
 
 	verifyRetrieve(t, tier, "query2", query2)
+
+	// a query stored under an existing name becomes its next version
+	query3 := query.QuerySer{QueryId: 0, Name: "name", Version: 2, Timestamp: ts2, QuerySer: []byte("hello again"), Description: "description"}
+	queryID3, version, err := Insert(tier, query3.Name, query3.Timestamp, query3.QuerySer, "description", nil)
+	assert.NoError(t, err)
+	assert.Equal(t, uint32(2), version)
+	query3.QueryId = queryID3
+
+	verifyRetrieve(t, tier, "name", query3)
+	found, err := RetrieveVersion(context.Background(), tier, "name", 1)
+	assert.NoError(t, err)
+	assert.Equal(t, query1, found)
+	_, err = RetrieveVersion(context.Background(), tier, "name", 3)
+	assert.Equal(t, ErrNotFound, err)
+
+	all, err := RetrieveAll(context.Background(), tier)
+	assert.NoError(t, err)
+	assert.ElementsMatch(t, []query.QuerySer{query2, query3}, all)
+}
+
+func TestAlias(t *testing.T) {
+	tier := test.Tier(t)
+	defer test.Teardown(tier)
+	ctx := context.Background()
+
+	_, err := RetrieveAlias(ctx, tier, "name", "prod")
+	assert.Equal(t, ErrNotFound, err)
+
+	a1 := query.Alias{QueryName: "name", Alias: "prod", Version: 1, UpdatedAt: 1}
+	a1.ID, err = InsertAlias(ctx, tier, a1)
+	assert.NoError(t, err)
+	a2 := query.Alias{QueryName: "name", Alias: "prod", UpdatedAt: 2, SplitSer: []byte(`{"arg":"uid"}`)}
+	a2.ID, err = InsertAlias(ctx, tier, a2)
+	assert.NoError(t, err)
+	a3 := query.Alias{QueryName: "name", Alias: "staging", Version: 3, UpdatedAt: 3}
+	a3.ID, err = InsertAlias(ctx, tier, a3)
+	assert.NoError(t, err)
+
+	found, err := RetrieveAlias(ctx, tier, "name", "prod")
+	assert.NoError(t, err)
+	assert.Equal(t, a2, found)
+
+	history, err := RetrieveAliasHistory(ctx, tier, "name", "prod", 5)
+	assert.NoError(t, err)
+	assert.Equal(t, []query.Alias{a2, a1}, history)
+
+	aliases, err := RetrieveAliases(ctx, tier, "name")
+	assert.NoError(t, err)
+	assert.Equal(t, []query.Alias{a2, a3}, aliases)
 }
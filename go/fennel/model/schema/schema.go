@@ -0,0 +1,86 @@
+package schema
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+
+	"fennel/lib/schema"
+	"fennel/tier"
+)
+
+// Set writes the serialized schema with the given name. The version is only updated if the stored schema
+// is at the previous version so that concurrent updates of the same schema don't overwrite each other
+func Set(ctx context.Context, tier tier.Tier, name string, version uint64, schemaSer []byte) error {
+	if version == 1 {
+		_, err := tier.DB.ExecContext(ctx, `INSERT INTO schema_registry (name, version, schema_ser) VALUES (?, ?, ?)`, name, version, schemaSer)
+		return err
+	}
+	res, err := tier.DB.ExecContext(ctx, `UPDATE schema_registry SET version = ?, schema_ser = ? WHERE name = ? AND version = ?`,
+		version, schemaSer, name, version-1)
+	if err != nil {
+		return err
+	}
+	if n, err := res.RowsAffected(); err != nil {
+		return err
+	} else if n == 0 {
+		return fmt.Errorf("schema '%s' was updated concurrently", name)
+	}
+	return nil
+}
+
+func Retrieve(ctx context.Context, tier tier.Tier, name string) (schema.SchemaSer, error) {
+	var ser schema.SchemaSer
+	err := tier.DB.GetContext(ctx, &ser, `SELECT * FROM schema_registry WHERE name = ?`, name)
+	if err == sql.ErrNoRows {
+		return ser, schema.ErrNotFound
+	}
+	return ser, err
+}
+
+func RetrieveAll(ctx context.Context, tier tier.Tier) ([]schema.SchemaSer, error) {
+	var sers []schema.SchemaSer
+	if err := tier.DB.SelectContext(ctx, &sers, `SELECT * FROM schema_registry ORDER BY name`); err != nil {
+		return nil, err
+	}
+	return sers, nil
+}
+
+func Delete(ctx context.Context, tier tier.Tier, name string) error {
+	res, err := tier.DB.ExecContext(ctx, `DELETE FROM schema_registry WHERE name = ?`, name)
+	if err != nil {
+		return err
+	}
+	if n, err := res.RowsAffected(); err != nil {
+		return err
+	} else if n == 0 {
+		return schema.ErrNotFound
+	}
+	return nil
+}
+
+// Quarantine stores records that did not satisfy their schema
+func Quarantine(ctx context.Context, tier tier.Tier, records []schema.QuarantinedRecord) error {
+	if len(records) == 0 {
+		return nil
+	}
+	sql := `INSERT INTO schema_quarantine (name, record, error, timestamp) VALUES `
+	vals := make([]interface{}, 0, 4*len(records))
+	for _, r := range records {
+		vals = append(vals, r.Name, r.Record, r.Error, r.Timestamp)
+	}
+	sql += strings.TrimSuffix(strings.Repeat("(?, ?, ?, ?),", len(records)), ",")
+	_, err := tier.DB.ExecContext(ctx, sql, vals...)
+	return err
+}
+
+// RetrieveQuarantined returns the latest records quarantined by the schema with the given name
+func RetrieveQuarantined(ctx context.Context, tier tier.Tier, name string, limit uint64) ([]schema.QuarantinedRecord, error) {
+	var records []schema.QuarantinedRecord
+	err := tier.DB.SelectContext(ctx, &records, `SELECT * FROM schema_quarantine WHERE name = ? ORDER BY id DESC LIMIT ?`, name, limit)
+	if err != nil {
+		return nil, err
+	}
+	return records, nil
+}
@@ -2,15 +2,15 @@ package action
 
 import (
 	"context"
-	"fennel/client"
 	actionL "fennel/lib/action"
 	"fennel/lib/ftypes"
+	tierC "fennel/mothership/controller/tier"
 	tierL "fennel/mothership/lib/tier"
-	"net/http"
+	"fennel/secrets"
 )
 
-func Actions(c context.Context, tier tierL.Tier, actionType, actorType, actorID, targetType, targetID string) ([]actionL.Action, error) {
-	cli, err := client.NewClient(tier.ApiUrl, http.DefaultClient)
+func Actions(c context.Context, sc secrets.Client, tier tierL.Tier, actionType, actorType, actorID, targetType, targetID string) ([]actionL.Action, error) {
+	cli, err := tierC.DataPlaneClient(c, sc, tier)
 	if err != nil {
 		return nil, err
 	}
@@ -2,14 +2,14 @@ package feature
 
 import (
 	"context"
-	"fennel/client"
 	featureL "fennel/lib/feature"
+	tierC "fennel/mothership/controller/tier"
 	tierL "fennel/mothership/lib/tier"
-	"net/http"
+	"fennel/secrets"
 )
 
-func Features(c context.Context, tier tierL.Tier) ([]featureL.Row, error) {
-	cli, err := client.NewClient(tier.ApiUrl, http.DefaultClient)
+func Features(c context.Context, sc secrets.Client, tier tierL.Tier) ([]featureL.Row, error) {
+	cli, err := tierC.DataPlaneClient(c, sc, tier)
 	if err != nil {
 		return nil, err
 	}
@@ -2,16 +2,16 @@ package profile
 
 import (
 	"context"
-	"fennel/client"
 	"fennel/lib/sql"
-	"net/http"
 
 	profileL "fennel/lib/profile"
+	tierC "fennel/mothership/controller/tier"
 	tierL "fennel/mothership/lib/tier"
+	"fennel/secrets"
 )
 
-func Profiles(c context.Context, tier tierL.Tier, otype, oid string, pagination sql.Pagination) ([]profileL.ProfileItem, error) {
-	cli, err := client.NewClient(tier.ApiUrl, http.DefaultClient)
+func Profiles(c context.Context, sc secrets.Client, tier tierL.Tier, otype, oid string, pagination sql.Pagination) ([]profileL.ProfileItem, error) {
+	cli, err := tierC.DataPlaneClient(c, sc, tier)
 	if err != nil {
 		return nil, err
 	}
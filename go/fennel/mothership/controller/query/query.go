@@ -2,14 +2,14 @@ package feature
 
 import (
 	"context"
-	"fennel/client"
 	queryL "fennel/lib/query"
+	tierC "fennel/mothership/controller/tier"
 	tierL "fennel/mothership/lib/tier"
-	"net/http"
+	"fennel/secrets"
 )
 
-func ListQueries(c context.Context, tier tierL.Tier) ([]queryL.QuerySer, error) {
-	cli, err := client.NewClient(tier.ApiUrl, http.DefaultClient)
+func ListQueries(c context.Context, sc secrets.Client, tier tierL.Tier) ([]queryL.QuerySer, error) {
+	cli, err := tierC.DataPlaneClient(c, sc, tier)
 	if err != nil {
 		return nil, err
 	}
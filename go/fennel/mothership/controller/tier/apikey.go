@@ -0,0 +1,146 @@
+package tier
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"fennel/client"
+	"fennel/lib/apikey"
+	tierL "fennel/mothership/lib/tier"
+	"fennel/secrets"
+
+	"gorm.io/gorm"
+)
+
+// CreateApiKey issues a new api key for the tier and returns it in plain text, this is the only
+// time the key can be seen since only its hash is stored
+func CreateApiKey(ctx context.Context, db *gorm.DB, sc secrets.Client, tier tierL.Tier, name, scopes string, ttl time.Duration) (string, tierL.ApiKey, error) {
+	key, row, err := newApiKey(tier, name, scopes, ttl)
+	if err != nil {
+		return "", row, err
+	}
+	if err = db.WithContext(ctx).Create(&row).Error; err != nil {
+		return "", row, err
+	}
+	return key, row, SyncApiKeys(ctx, db, sc, tier)
+}
+
+// RotateApiKey issues a new key with the same name and scopes as the given key. The old key keeps
+// working for the grace period so that clients can switch to the new key.
+func RotateApiKey(ctx context.Context, db *gorm.DB, sc secrets.Client, tier tierL.Tier, id uint, grace time.Duration) (string, tierL.ApiKey, error) {
+	var old tierL.ApiKey
+	if err := db.WithContext(ctx).Take(&old, "api_key_id = ? AND tier_id = ?", id, tier.ID).Error; err != nil {
+		return "", old, err
+	}
+	if old.RevokedAt != 0 {
+		return "", old, fmt.Errorf("api key '%s' is revoked", old.Name)
+	}
+	key, row, err := newApiKey(tier, old.Name, old.Scopes, 0)
+	if err != nil {
+		return "", row, err
+	}
+	row.ExpiresAt = old.ExpiresAt
+	expiresAt := time.Now().Add(grace).Unix()
+	err = db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&row).Error; err != nil {
+			return err
+		}
+		if old.ExpiresAt != 0 && old.ExpiresAt < expiresAt {
+			return nil
+		}
+		return tx.Model(&old).Update("expires_at", expiresAt).Error
+	})
+	if err != nil {
+		return "", row, err
+	}
+	return key, row, SyncApiKeys(ctx, db, sc, tier)
+}
+
+// RevokeApiKey revokes the key, it is rejected by the data plane as soon as the keys are synced
+func RevokeApiKey(ctx context.Context, db *gorm.DB, sc secrets.Client, tier tierL.Tier, id uint) error {
+	res := db.WithContext(ctx).Model(&tierL.ApiKey{}).
+		Where("api_key_id = ? AND tier_id = ? AND revoked_at = 0", id, tier.ID).
+		Update("revoked_at", time.Now().Unix())
+	if res.Error != nil {
+		return res.Error
+	}
+	if res.RowsAffected == 0 {
+		return fmt.Errorf("api key %d not found", id)
+	}
+	return SyncApiKeys(ctx, db, sc, tier)
+}
+
+func FetchApiKeys(ctx context.Context, db *gorm.DB, tierID uint) (keys []tierL.ApiKey, err error) {
+	if tierID == 0 {
+		return nil, errors.New("tier id is 0")
+	}
+	err = db.WithContext(ctx).Find(&keys, "tier_id = ?", tierID).Error
+	return keys, err
+}
+
+// SyncApiKeys pushes the active keys of the tier to its data plane
+func SyncApiKeys(ctx context.Context, db *gorm.DB, sc secrets.Client, tier tierL.Tier) error {
+	rows, err := FetchApiKeys(ctx, db, tier.ID)
+	if err != nil {
+		return err
+	}
+	now := time.Now()
+	keys := make([]apikey.Key, 0, len(rows))
+	for i := range rows {
+		if rows[i].Active(now) {
+			keys = append(keys, rows[i].ToKey())
+		}
+	}
+	cli, err := DataPlaneClient(ctx, sc, tier)
+	if err != nil {
+		return err
+	}
+	return cli.SyncApiKeys(keys)
+}
+
+// DataPlaneClient returns a client of the data plane of the tier that is authenticated with the
+// admin key of the mothership. The key is kept in a secret that the data plane reads it from, it is
+// created the first time the mothership calls the tier and is never stored in the db.
+func DataPlaneClient(ctx context.Context, sc secrets.Client, tier tierL.Tier) (*client.Client, error) {
+	name := apikey.MothershipKeySecret(uint64(tier.ID))
+	key, err := sc.Get(ctx, name)
+	if errors.Is(err, secrets.ErrNotFound) {
+		if key, _, _, err = apikey.Generate(); err != nil {
+			return nil, err
+		}
+		err = sc.Set(ctx, name, key)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get the api key of tier %d: %w", tier.ID, err)
+	}
+	cli, err := client.NewClient(tier.ApiUrl, http.DefaultClient)
+	if err != nil {
+		return nil, err
+	}
+	cli.SetApiKey(key)
+	return cli, nil
+}
+
+func newApiKey(tier tierL.Tier, name, scopes string, ttl time.Duration) (string, tierL.ApiKey, error) {
+	if _, err := apikey.ParseScopes(scopes); err != nil {
+		return "", tierL.ApiKey{}, err
+	}
+	key, id, hash, err := apikey.Generate()
+	if err != nil {
+		return "", tierL.ApiKey{}, err
+	}
+	row := tierL.ApiKey{
+		TierID: tier.ID,
+		Name:   name,
+		KeyID:  id,
+		Hash:   hash,
+		Scopes: scopes,
+	}
+	if ttl > 0 {
+		row.ExpiresAt = time.Now().Add(ttl).Unix()
+	}
+	return key, row, nil
+}
@@ -0,0 +1,48 @@
+package tier
+
+import (
+	"time"
+
+	"fennel/lib/apikey"
+
+	"gorm.io/plugin/soft_delete"
+)
+
+// ApiKey is an api key of a tier, only the hash of the key is stored
+type ApiKey struct {
+	ID uint `gorm:"column:api_key_id;primaryKey"`
+
+	TierID uint
+	Name   string
+	KeyID  string
+	Hash   string
+	// comma separated scopes, see fennel/lib/apikey
+	Scopes string
+	// unix time (in seconds) after which the key is no longer valid, zero if it never expires
+	ExpiresAt int64
+	// unix time (in seconds) at which the key was revoked, zero if it is not revoked
+	RevokedAt int64
+
+	DeletedAt soft_delete.DeletedAt `gorm:"softDelete:milli"`
+	CreatedAt int64                 `gorm:"autoUpdateTime:milli"`
+	UpdatedAt int64                 `gorm:"autoUpdateTime:milli"`
+}
+
+func (ApiKey) TableName() string {
+	return "api_key"
+}
+
+// Active returns true if the key is neither revoked nor expired
+func (k *ApiKey) Active(now time.Time) bool {
+	return k.RevokedAt == 0 && !k.ToKey().Expired(now)
+}
+
+// ToKey returns the key as known to the data plane
+func (k *ApiKey) ToKey() apikey.Key {
+	return apikey.Key{
+		ID:        k.KeyID,
+		Hash:      k.Hash,
+		Scopes:    k.Scopes,
+		ExpiresAt: k.ExpiresAt,
+	}
+}
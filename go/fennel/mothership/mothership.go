@@ -7,6 +7,7 @@ import (
 	"fennel/db"
 	"fennel/lib/ftypes"
 	"fennel/resource"
+	"fennel/secrets"
 )
 
 type MothershipArgs struct {
@@ -16,12 +17,15 @@ type MothershipArgs struct {
 	MysqlUsername      string         `arg:"--mothership_mysql_user,env:MOTHERSHIP_MYSQL_USERNAME"`
 	MysqlPassword      string         `arg:"--mothership_mysql_password,env:MOTHERSHIP_MYSQL_PASSWORD"`
 	MothershipEndpoint string         `arg:"--mothership_endpoint,env:MOTHERSHIP_ENDPOINT"`
+	secrets.SecretsArgs
 }
 
 type Mothership struct {
 	ID       ftypes.RealmID
 	DB       db.Connection
 	Endpoint string
+	// Secrets keeps the api keys the mothership uses to call the data planes of tiers
+	Secrets secrets.Client
 }
 
 func CreateFromArgs(args *MothershipArgs) (mothership Mothership, err error) {
@@ -45,5 +49,6 @@ func CreateFromArgs(args *MothershipArgs) (mothership Mothership, err error) {
 		ID:       mothershipID,
 		DB:       sqlConn.(db.Connection),
 		Endpoint: args.MothershipEndpoint,
+		Secrets:  secrets.NewClient(args.SecretsArgs),
 	}, nil
 }
@@ -169,4 +169,18 @@ var Schema = db.Schema{
                 DROP COLUMN memory_db_instance_id,
                 DROP COLUMN elasticache_instance_id;
         `,
+	27: `CREATE TABLE IF NOT EXISTS api_key (
+                api_key_id INT UNSIGNED NOT NULL PRIMARY KEY AUTO_INCREMENT,
+                tier_id INT UNSIGNED NOT NULL,
+                name VARCHAR(64) NOT NULL,
+                key_id VARCHAR(32) NOT NULL UNIQUE,
+                hash VARCHAR(64) NOT NULL,
+                scopes VARCHAR(255) NOT NULL,
+                expires_at BIGINT NOT NULL DEFAULT 0,
+                revoked_at BIGINT NOT NULL DEFAULT 0,
+                deleted_at BIGINT UNSIGNED NOT NULL,
+                created_at BIGINT UNSIGNED NOT NULL,
+                updated_at BIGINT UNSIGNED NOT NULL,
+                INDEX tier_id_index (tier_id)
+        );`,
 }
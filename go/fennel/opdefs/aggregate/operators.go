@@ -28,7 +28,7 @@ type AggValue struct {
 func (a AggValue) New(
 	args value.Dict, bootargs map[string]interface{},
 ) (operators.Operator, error) {
-	tr, err := bootarg.GetTier(bootargs)
+	tr, err := bootarg.RequireTier(bootargs)
 	if err != nil {
 		return nil, err
 	}
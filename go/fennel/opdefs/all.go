@@ -22,6 +22,7 @@ import (
 	_ "fennel/opdefs/std/rename"
 	_ "fennel/opdefs/std/repeat"
 	_ "fennel/opdefs/std/set"
+	_ "fennel/opdefs/std/window"
 	_ "fennel/opdefs/std/zip"
 
 	_ "fennel/opdefs/remote"
@@ -1 +1,55 @@
 package embedding
+
+import (
+	"context"
+	"log"
+
+	"fennel/engine/operators"
+	"fennel/lib/value"
+)
+
+func init() {
+	if err := operators.Register(concatOp{}); err != nil {
+		log.Fatalf("Failed to register embedding.concat operator: %v", err)
+	}
+}
+
+// concatOp concatenates a list of vectors into a single vector
+type concatOp struct{}
+
+func (c concatOp) New(
+	args value.Dict, bootargs map[string]interface{},
+) (operators.Operator, error) {
+	return concatOp{}, nil
+}
+
+func (c concatOp) Apply(_ context.Context, _ operators.Kwargs, in operators.InputIter, outs *value.List) error {
+	for in.HasMore() {
+		heads, kwargs, err := in.Next()
+		if err != nil {
+			return err
+		}
+		vecs, err := vectorsOf(heads, kwargs)
+		if err != nil {
+			return err
+		}
+		dim := 0
+		for _, v := range vecs {
+			dim += len(v)
+		}
+		ret := make(value.Vector, 0, dim)
+		for _, v := range vecs {
+			ret = append(ret, v...)
+		}
+		outs.Append(ret)
+	}
+	return nil
+}
+
+func (c concatOp) Signature() *operators.Signature {
+	return operators.NewSignature("embedding", "concat").
+		Input([]value.Type{value.Types.Any}).
+		ParamWithHelp("of", value.Types.Any, false, true, value.Nil, "ContextKwarg: Expr that is evaluated to provide the list of vectors, the operand itself is used if not provided")
+}
+
+var _ operators.Operator = concatOp{}
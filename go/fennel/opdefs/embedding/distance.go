@@ -1 +1,77 @@
 package embedding
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"math"
+
+	"fennel/engine/operators"
+	"fennel/lib/value"
+)
+
+func init() {
+	if err := operators.Register(distanceOp{}); err != nil {
+		log.Fatalf("Failed to register embedding.distance operator: %v", err)
+	}
+}
+
+// distanceOp computes the distance between the operand vector and another vector
+type distanceOp struct{}
+
+func (d distanceOp) New(
+	args value.Dict, bootargs map[string]interface{},
+) (operators.Operator, error) {
+	return distanceOp{}, nil
+}
+
+func (d distanceOp) Apply(_ context.Context, staticKwargs operators.Kwargs, in operators.InputIter, outs *value.List) error {
+	metric := string(staticKwargs.GetUnsafe("metric").(value.String))
+	if metric != "l2" && metric != "cosine" {
+		return fmt.Errorf("unsupported metric for embedding.distance: '%s', expected 'l2' or 'cosine'", metric)
+	}
+	for in.HasMore() {
+		heads, kwargs, err := in.Next()
+		if err != nil {
+			return err
+		}
+		left, err := vectorOf(heads, kwargs)
+		if err != nil {
+			return err
+		}
+		right, err := value.ToVector(kwargs.GetUnsafe("to"))
+		if err != nil {
+			return fmt.Errorf("invalid 'to' vector for embedding.distance: %w", err)
+		}
+		dot, err := value.Dot(left, right)
+		if err != nil {
+			return err
+		}
+		var dist float64
+		switch metric {
+		case "l2":
+			// |a - b|^2 = |a|^2 + |b|^2 - 2 a.b, clamped to avoid negative rounding errors
+			aa, _ := value.Dot(left, left)
+			bb, _ := value.Dot(right, right)
+			dist = math.Sqrt(math.Max(0, aa+bb-2*dot))
+		case "cosine":
+			norms := norm(left) * norm(right)
+			if norms == 0 {
+				return fmt.Errorf("cosine distance is not defined for zero vectors")
+			}
+			dist = 1 - dot/norms
+		}
+		outs.Append(value.Double(dist))
+	}
+	return nil
+}
+
+func (d distanceOp) Signature() *operators.Signature {
+	return operators.NewSignature("embedding", "distance").
+		Input([]value.Type{value.Types.Any}).
+		ParamWithHelp("metric", value.Types.String, true, true, value.String("l2"), "StaticKwarg: String param, either 'l2' (default) or 'cosine'").
+		ParamWithHelp("of", value.Types.Any, false, true, value.Nil, "ContextKwarg: Expr that is evaluated to provide the vector, the operand itself is used if not provided").
+		ParamWithHelp("to", value.Types.Any, false, false, value.Nil, "ContextKwarg: Expr that is evaluated to provide the vector to measure the distance to")
+}
+
+var _ operators.Operator = distanceOp{}
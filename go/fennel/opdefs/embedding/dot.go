@@ -1 +1,92 @@
 package embedding
+
+import (
+	"context"
+	"fmt"
+	"log"
+
+	"fennel/engine/operators"
+	"fennel/lib/value"
+)
+
+func init() {
+	if err := operators.Register(dotOp{}); err != nil {
+		log.Fatalf("Failed to register embedding.dot operator: %v", err)
+	}
+}
+
+// dotOp computes the dot product of the operand vector with another vector
+type dotOp struct{}
+
+func (d dotOp) New(
+	args value.Dict, bootargs map[string]interface{},
+) (operators.Operator, error) {
+	return dotOp{}, nil
+}
+
+func (d dotOp) Apply(_ context.Context, _ operators.Kwargs, in operators.InputIter, outs *value.List) error {
+	for in.HasMore() {
+		heads, kwargs, err := in.Next()
+		if err != nil {
+			return err
+		}
+		left, err := vectorOf(heads, kwargs)
+		if err != nil {
+			return err
+		}
+		right, err := value.ToVector(kwargs.GetUnsafe("with"))
+		if err != nil {
+			return fmt.Errorf("invalid 'with' vector for embedding.dot: %w", err)
+		}
+		dot, err := value.Dot(left, right)
+		if err != nil {
+			return err
+		}
+		outs.Append(value.Double(dot))
+	}
+	return nil
+}
+
+func (d dotOp) Signature() *operators.Signature {
+	return operators.NewSignature("embedding", "dot").
+		Input([]value.Type{value.Types.Any}).
+		ParamWithHelp("of", value.Types.Any, false, true, value.Nil, "ContextKwarg: Expr that is evaluated to provide the vector, the operand itself is used if not provided").
+		ParamWithHelp("with", value.Types.Any, false, false, value.Nil, "ContextKwarg: Expr that is evaluated to provide the vector to take the dot product with")
+}
+
+var _ operators.Operator = dotOp{}
+
+// vectorOf returns the vector given by the 'of' kwarg if set, else the operand itself.
+// Both vectors and lists of numbers are accepted.
+func vectorOf(heads []value.Value, kwargs operators.Kwargs) (value.Vector, error) {
+	v, ok := kwargs.Get("of")
+	if !ok || v == value.Nil {
+		v = heads[0]
+	}
+	vec, err := value.ToVector(v)
+	if err != nil {
+		return nil, fmt.Errorf("expected a vector but found '%s': %w", v, err)
+	}
+	return vec, nil
+}
+
+// vectorsOf is like vectorOf but expects a list of vectors, all of the same dimension.
+func vectorsOf(heads []value.Value, kwargs operators.Kwargs) ([]value.Vector, error) {
+	v, ok := kwargs.Get("of")
+	if !ok || v == value.Nil {
+		v = heads[0]
+	}
+	l, ok := v.(value.List)
+	if !ok {
+		return nil, fmt.Errorf("expected a list of vectors but found '%s'", v)
+	}
+	vecs := make([]value.Vector, l.Len())
+	for i, e := range l.Values() {
+		vec, err := value.ToVector(e)
+		if err != nil {
+			return nil, fmt.Errorf("expected a vector but found '%s': %w", e, err)
+		}
+		vecs[i] = vec
+	}
+	return vecs, nil
+}
@@ -0,0 +1,64 @@
+package embedding
+
+import (
+	"testing"
+
+	"fennel/lib/value"
+	"fennel/test/optest"
+	"fennel/tier"
+)
+
+func TestVectorOps(t *testing.T) {
+	tr := tier.Tier{}
+	empty := value.NewDict(nil)
+	inputs := []value.Value{
+		value.Vector{3, 4},
+		value.NewList(value.Int(1), value.Double(0)),
+	}
+	with := func(k string, v value.Value) []value.Dict {
+		return []value.Dict{
+			value.NewDict(map[string]value.Value{k: v}),
+			value.NewDict(map[string]value.Value{k: v}),
+		}
+	}
+	optest.AssertEqual(t, tr, normOp{}, empty, [][]value.Value{inputs}, []value.Dict{empty, empty},
+		[]value.Value{value.Double(5), value.Double(1)})
+	optest.AssertEqual(t, tr, dotOp{}, empty, [][]value.Value{inputs}, with("with", value.Vector{1, 2}),
+		[]value.Value{value.Double(11), value.Double(1)})
+	optest.AssertEqual(t, tr, distanceOp{}, empty, [][]value.Value{inputs}, with("to", value.Vector{0, 0}),
+		[]value.Value{value.Double(5), value.Double(1)})
+	optest.AssertEqual(t, tr, distanceOp{}, value.NewDict(map[string]value.Value{"metric": value.String("cosine")}),
+		[][]value.Value{inputs}, with("to", value.NewList(value.Int(0), value.Int(2))),
+		[]value.Value{value.Double(0.2), value.Double(1)})
+	// the 'of' kwarg takes precedence over the operand
+	optest.AssertEqual(t, tr, normOp{}, empty, [][]value.Value{inputs}, with("of", value.Vector{0, 2}),
+		[]value.Value{value.Double(2), value.Double(2)})
+
+	optest.AssertError(t, tr, dotOp{}, empty, [][]value.Value{inputs}, with("with", value.Vector{1, 2, 3}))
+	optest.AssertError(t, tr, normOp{}, empty, [][]value.Value{{value.String("a")}}, []value.Dict{empty})
+	optest.AssertError(t, tr, distanceOp{}, value.NewDict(map[string]value.Value{"metric": value.String("cosine")}),
+		[][]value.Value{inputs}, with("to", value.Vector{0, 0}))
+	optest.AssertError(t, tr, distanceOp{}, value.NewDict(map[string]value.Value{"metric": value.String("l1")}),
+		[][]value.Value{inputs}, with("to", value.Vector{0, 0}))
+}
+
+func TestVectorListOps(t *testing.T) {
+	tr := tier.Tier{}
+	empty := value.NewDict(nil)
+	inputs := []value.Value{
+		value.NewList(value.Vector{1, 2}, value.NewList(value.Int(3), value.Int(6))),
+		value.NewList(),
+	}
+	optest.AssertEqual(t, tr, sumOp{}, empty, [][]value.Value{inputs}, []value.Dict{empty, empty},
+		[]value.Value{value.Vector{4, 8}, value.Nil})
+	optest.AssertEqual(t, tr, meanOp{}, empty, [][]value.Value{inputs}, []value.Dict{empty, empty},
+		[]value.Value{value.Vector{2, 4}, value.Nil})
+	optest.AssertEqual(t, tr, concatOp{}, empty, [][]value.Value{inputs}, []value.Dict{empty, empty},
+		[]value.Value{value.Vector{1, 2, 3, 6}, value.Vector{}})
+
+	mismatched := [][]value.Value{{value.NewList(value.Vector{1, 2}, value.Vector{1})}}
+	optest.AssertError(t, tr, sumOp{}, empty, mismatched, []value.Dict{empty})
+	optest.AssertError(t, tr, meanOp{}, empty, mismatched, []value.Dict{empty})
+	optest.AssertEqual(t, tr, concatOp{}, empty, mismatched, []value.Dict{empty}, []value.Value{value.Vector{1, 2, 1}})
+	optest.AssertError(t, tr, concatOp{}, empty, [][]value.Value{{value.Vector{1, 2}}}, []value.Dict{empty})
+}
@@ -0,0 +1,115 @@
+package embedding
+
+import (
+	"context"
+	"fmt"
+	"log"
+
+	"fennel/controller/aggregate"
+	"fennel/engine/interpreter/bootarg"
+	"fennel/engine/operators"
+	"fennel/lib/ftypes"
+	"fennel/lib/value"
+	"fennel/tier"
+)
+
+func init() {
+	if err := operators.Register(knnOp{}); err != nil {
+		log.Fatalf("Failed to register embedding.knn operator: %v", err)
+	}
+}
+
+// knnOp looks up the nearest neighbors of vectors in a knn aggregate
+type knnOp struct {
+	tier tier.Tier
+}
+
+func (k knnOp) New(
+	args value.Dict, bootargs map[string]interface{},
+) (operators.Operator, error) {
+	tr, err := bootarg.RequireTier(bootargs)
+	if err != nil {
+		return nil, err
+	}
+	return knnOp{tr}, nil
+}
+
+func (k knnOp) Apply(ctx context.Context, staticKwargs operators.Kwargs, in operators.InputIter, outs *value.List) error {
+	type batch struct {
+		name    ftypes.AggName
+		k       int
+		vectors []value.Value
+		ptrs    []int
+	}
+	// rows are batched by aggregate & k so that each batch is a single call to the index
+	var batches []*batch
+	index := make(map[string]*batch)
+	var rows []value.Value
+	for in.HasMore() {
+		heads, contextKwargs, err := in.Next()
+		if err != nil {
+			return err
+		}
+		name := ftypes.AggName(contextKwargs.GetUnsafe("name").(value.String))
+		topK := int(contextKwargs.GetUnsafe("k").(value.Int))
+		if topK <= 0 {
+			return fmt.Errorf("k should be positive but got: %d", topK)
+		}
+		vector, ok := contextKwargs.Get("vector")
+		if !ok || vector == value.Nil {
+			vector = heads[0]
+		}
+		vec, err := value.ToVector(vector)
+		if err != nil {
+			return fmt.Errorf("expected vector to be a vector or a list of numbers but found: '%s'", vector)
+		}
+		key := fmt.Sprintf("%s:%d", name, topK)
+		b, ok := index[key]
+		if !ok {
+			b = &batch{name: name, k: topK}
+			index[key] = b
+			batches = append(batches, b)
+		}
+		b.vectors = append(b.vectors, vec)
+		b.ptrs = append(b.ptrs, len(rows))
+		rows = append(rows, heads[0])
+	}
+	exact := bool(staticKwargs.GetUnsafe("exact").(value.Bool))
+	res := make([]value.Value, len(rows))
+	for _, b := range batches {
+		neighbors, err := aggregate.Neighbors(ctx, k.tier, b.name, b.vectors, b.k, exact)
+		if err != nil {
+			return err
+		}
+		for i, ptr := range b.ptrs {
+			res[ptr] = neighbors[i]
+		}
+	}
+	field := string(staticKwargs.GetUnsafe("field").(value.String))
+	outs.Grow(len(rows))
+	for i, row := range rows {
+		if len(field) == 0 {
+			outs.Append(res[i])
+			continue
+		}
+		d, ok := row.(value.Dict)
+		if !ok {
+			return fmt.Errorf("when setting a field, operands for embedding.knn are required to be dicts, please convert them to a dict using map")
+		}
+		d.Set(field, res[i])
+		outs.Append(d)
+	}
+	return nil
+}
+
+func (k knnOp) Signature() *operators.Signature {
+	return operators.NewSignature("embedding", "knn").
+		Input([]value.Type{value.Types.Any}).
+		ParamWithHelp("field", value.Types.String, true, true, value.String(""), "StaticKwarg: String param that is used as key post evaluation of this operator").
+		ParamWithHelp("exact", value.Types.Bool, true, true, value.Bool(false), "StaticKwarg: Bool param, if true neighbors are found by comparing with every vector instead of searching the index. Only supported by the embedded backend").
+		ParamWithHelp("name", value.Types.String, false, false, value.Nil, "ContextKwarg: Expr of type string when evaluated provides the name of the knn aggregate to be used.").
+		ParamWithHelp("k", value.Types.Int, false, false, value.Nil, "ContextKwarg: Expr of type int when evaluated provides the number of neighbors to return.").
+		ParamWithHelp("vector", value.Types.Any, false, true, value.Nil, "ContextKwarg: Expr that is evaluated to provide the query vector, the operand itself is used if not provided.")
+}
+
+var _ operators.Operator = knnOp{}
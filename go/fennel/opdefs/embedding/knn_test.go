@@ -0,0 +1,77 @@
+package embedding
+
+import (
+	"context"
+	"testing"
+
+	"fennel/ann"
+	"fennel/engine/ast"
+	libaggregate "fennel/lib/aggregate"
+	"fennel/lib/value"
+	"fennel/test"
+	"fennel/test/optest"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestKnn(t *testing.T) {
+	tier := test.Tier(t)
+	defer test.Teardown(tier)
+	ctx := context.Background()
+
+	agg := libaggregate.Aggregate{
+		Name:   "embeddings",
+		Source: libaggregate.SOURCE_PROFILE,
+		Query:  ast.MakeInt(0),
+		Options: libaggregate.Options{
+			AggType:         libaggregate.KNN,
+			Dim:             2,
+			HyperParameters: `{"backend": "embedded", "metric": "l2"}`,
+		},
+		Id:     1,
+		Active: true,
+	}
+	tier.AggregateDefs.Store(agg.Name, agg)
+	index := tier.AnnClient.MustGet()
+	assert.NoError(t, index.CreateKNNIndex(ctx, agg, tier.ID))
+	table := value.NewList()
+	for i, v := range [][]float64{{0, 0}, {1, 0}, {5, 5}} {
+		table.Append(value.NewDict(map[string]value.Value{
+			"groupkey":  value.NewList(value.Double(v[0]), value.Double(v[1])),
+			"value":     value.Int(i + 1),
+			"timestamp": value.Int(0),
+		}))
+	}
+	assert.NoError(t, index.InsertStream(ctx, agg, table, tier.ID))
+
+	neighbor := func(id int64, score float64) value.Value {
+		return value.NewDict(map[string]value.Value{ann.PrimaryField: value.Int(id), ann.ScoreField: value.Double(score)})
+	}
+	inputs := []value.Value{
+		value.NewDict(map[string]value.Value{"v": value.NewList(value.Int(0), value.Int(0))}),
+		value.NewDict(map[string]value.Value{"v": value.NewList(value.Int(5), value.Int(4))}),
+	}
+	contextKwargs := []value.Dict{
+		value.NewDict(map[string]value.Value{"name": value.String(agg.Name), "k": value.Int(2), "vector": value.NewList(value.Int(0), value.Int(0))}),
+		value.NewDict(map[string]value.Value{"name": value.String(agg.Name), "k": value.Int(1), "vector": value.NewList(value.Int(5), value.Int(4))}),
+	}
+	for _, exact := range []bool{false, true} {
+		static := value.NewDict(map[string]value.Value{"field": value.String("nn"), "exact": value.Bool(exact)})
+		optest.AssertEqual(t, tier, &knnOp{tier}, static, [][]value.Value{inputs}, contextKwargs, []value.Value{
+			value.NewDict(map[string]value.Value{"v": value.NewList(value.Int(0), value.Int(0)), "nn": value.NewList(neighbor(1, 0), neighbor(2, 1))}),
+			value.NewDict(map[string]value.Value{"v": value.NewList(value.Int(5), value.Int(4)), "nn": value.NewList(neighbor(3, 1))}),
+		})
+	}
+
+	// operand itself is used as the vector if not given
+	optest.AssertEqual(t, tier, &knnOp{tier}, value.NewDict(nil),
+		[][]value.Value{{value.NewList(value.Int(1), value.Int(0))}},
+		[]value.Dict{value.NewDict(map[string]value.Value{"name": value.String(agg.Name), "k": value.Int(1)})},
+		[]value.Value{value.NewList(neighbor(2, 0))},
+	)
+	// k should be positive
+	optest.AssertError(t, tier, &knnOp{tier}, value.NewDict(nil),
+		[][]value.Value{{value.NewList(value.Int(1), value.Int(0))}},
+		[]value.Dict{value.NewDict(map[string]value.Value{"name": value.String(agg.Name), "k": value.Int(0)})},
+	)
+}
@@ -1 +1,59 @@
 package embedding
+
+import (
+	"context"
+	"log"
+
+	"fennel/engine/operators"
+	"fennel/lib/value"
+)
+
+func init() {
+	if err := operators.Register(meanOp{}); err != nil {
+		log.Fatalf("Failed to register embedding.mean operator: %v", err)
+	}
+}
+
+// meanOp computes the elementwise mean of a list of vectors
+type meanOp struct{}
+
+func (m meanOp) New(
+	args value.Dict, bootargs map[string]interface{},
+) (operators.Operator, error) {
+	return meanOp{}, nil
+}
+
+func (m meanOp) Apply(_ context.Context, _ operators.Kwargs, in operators.InputIter, outs *value.List) error {
+	for in.HasMore() {
+		heads, kwargs, err := in.Next()
+		if err != nil {
+			return err
+		}
+		vecs, err := vectorsOf(heads, kwargs)
+		if err != nil {
+			return err
+		}
+		if len(vecs) == 0 {
+			outs.Append(value.Nil)
+			continue
+		}
+		total, err := sum(vecs)
+		if err != nil {
+			return err
+		}
+		mean, err := total.Op("*", value.Double(1/float64(len(vecs))))
+		if err != nil {
+			return err
+		}
+		outs.Append(mean)
+	}
+	return nil
+}
+
+func (m meanOp) Signature() *operators.Signature {
+	return operators.NewSignature("embedding", "mean").
+		Input([]value.Type{value.Types.Any}).
+		ParamWithHelp("of", value.Types.Any, false, true, value.Nil, "ContextKwarg: Expr that is evaluated to provide the list of vectors, the operand itself is used if not provided")
+}
+
+var _ operators.Operator = meanOp{}
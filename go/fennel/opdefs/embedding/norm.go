@@ -1 +1,54 @@
 package embedding
+
+import (
+	"context"
+	"log"
+	"math"
+
+	"fennel/engine/operators"
+	"fennel/lib/value"
+)
+
+func init() {
+	if err := operators.Register(normOp{}); err != nil {
+		log.Fatalf("Failed to register embedding.norm operator: %v", err)
+	}
+}
+
+// normOp computes the l2 norm of the operand vector
+type normOp struct{}
+
+func (n normOp) New(
+	args value.Dict, bootargs map[string]interface{},
+) (operators.Operator, error) {
+	return normOp{}, nil
+}
+
+func (n normOp) Apply(_ context.Context, _ operators.Kwargs, in operators.InputIter, outs *value.List) error {
+	for in.HasMore() {
+		heads, kwargs, err := in.Next()
+		if err != nil {
+			return err
+		}
+		vec, err := vectorOf(heads, kwargs)
+		if err != nil {
+			return err
+		}
+		outs.Append(value.Double(norm(vec)))
+	}
+	return nil
+}
+
+func (n normOp) Signature() *operators.Signature {
+	return operators.NewSignature("embedding", "norm").
+		Input([]value.Type{value.Types.Any}).
+		ParamWithHelp("of", value.Types.Any, false, true, value.Nil, "ContextKwarg: Expr that is evaluated to provide the vector, the operand itself is used if not provided")
+}
+
+var _ operators.Operator = normOp{}
+
+func norm(v value.Vector) float64 {
+	// dot of a vector with itself never fails
+	sq, _ := value.Dot(v, v)
+	return math.Sqrt(sq)
+}
@@ -1 +1,67 @@
 package embedding
+
+import (
+	"context"
+	"log"
+
+	"fennel/engine/operators"
+	"fennel/lib/value"
+)
+
+func init() {
+	if err := operators.Register(sumOp{}); err != nil {
+		log.Fatalf("Failed to register embedding.sum operator: %v", err)
+	}
+}
+
+// sumOp computes the elementwise sum of a list of vectors
+type sumOp struct{}
+
+func (s sumOp) New(
+	args value.Dict, bootargs map[string]interface{},
+) (operators.Operator, error) {
+	return sumOp{}, nil
+}
+
+func (s sumOp) Apply(_ context.Context, _ operators.Kwargs, in operators.InputIter, outs *value.List) error {
+	for in.HasMore() {
+		heads, kwargs, err := in.Next()
+		if err != nil {
+			return err
+		}
+		vecs, err := vectorsOf(heads, kwargs)
+		if err != nil {
+			return err
+		}
+		if len(vecs) == 0 {
+			outs.Append(value.Nil)
+			continue
+		}
+		total, err := sum(vecs)
+		if err != nil {
+			return err
+		}
+		outs.Append(total)
+	}
+	return nil
+}
+
+func (s sumOp) Signature() *operators.Signature {
+	return operators.NewSignature("embedding", "sum").
+		Input([]value.Type{value.Types.Any}).
+		ParamWithHelp("of", value.Types.Any, false, true, value.Nil, "ContextKwarg: Expr that is evaluated to provide the list of vectors, the operand itself is used if not provided")
+}
+
+var _ operators.Operator = sumOp{}
+
+func sum(vecs []value.Vector) (value.Vector, error) {
+	total := append(value.Vector{}, vecs[0]...)
+	for _, v := range vecs[1:] {
+		sum, err := total.Op("+", v)
+		if err != nil {
+			return nil, err
+		}
+		total = sum.(value.Vector)
+	}
+	return total, nil
+}
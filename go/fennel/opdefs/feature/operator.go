@@ -29,16 +29,22 @@ func init() {
 
 type featureLog struct {
 	tier tier.Tier
+	// query is the version of the stored query being run, if any
+	query bootarg.QueryVersion
+	// seed of the query, 0 if it is not seeded
+	seed int64
 }
 
 func (f featureLog) New(
 	args value.Dict, bootargs map[string]interface{},
 ) (operators.Operator, error) {
-	tr, err := bootarg.GetTier(bootargs)
+	tr, err := bootarg.RequireTier(bootargs)
 	if err != nil {
 		return nil, err
 	}
-	return featureLog{tr}, nil
+	qv, _ := bootarg.GetQueryVersion(bootargs)
+	seed, _ := bootarg.GetSeed(bootargs)
+	return featureLog{tr, qv, seed}, nil
 }
 
 func (f featureLog) Apply(ctx context.Context, static operators.Kwargs, in operators.InputIter, out *value.List) error {
@@ -67,6 +73,9 @@ func (f featureLog) Apply(ctx context.Context, static operators.Kwargs, in opera
 			ModelName:       modelName,
 			ModelVersion:    modelVersion,
 			ModelPrediction: float64(kwargs.GetUnsafe("model_prediction").(value.Double)),
+			QueryName:       f.query.Name,
+			QueryVersion:    f.query.Version,
+			Seed:            f.seed,
 		}
 		if err = feature.Log(ctx, f.tier, msg); err != nil {
 			return err
@@ -78,7 +78,7 @@ func TestFeatureLog_Apply(t *testing.T) {
 		outputs[i], err = r.GetValue()
 		assert.NoError(t, err)
 	}
-	optest.AssertElementsMatch(t, tier, &featureLog{tier}, static, [][]value.Value{inputs}, kwargs, outputs)
+	optest.AssertElementsMatch(t, tier, &featureLog{tier: tier}, static, [][]value.Value{inputs}, kwargs, outputs)
 	for _, r := range rows {
 		rowptr, err := feature2.Read(context.Background(), consumer)
 		assert.NoError(t, err)
@@ -22,7 +22,7 @@ type predictOperator struct {
 }
 
 func (p predictOperator) New(args value.Dict, bootargs map[string]interface{}) (operators.Operator, error) {
-	tr, err := bootarg.GetTier(bootargs)
+	tr, err := bootarg.RequireTier(bootargs)
 	if err != nil {
 		return nil, err
 	}
@@ -13,6 +13,7 @@ import (
 
 	"fennel/engine/interpreter/bootarg"
 	"fennel/engine/operators"
+	"fennel/lib/budget"
 	"fennel/lib/value"
 	"fennel/tier"
 )
@@ -44,16 +45,18 @@ func init() {
 // - Implement support for client-certificates or self-signed server certificates.
 type RemoteHttp struct {
 	tr tier.Tier
+	// tracker bounds the number of requests the query can make
+	tracker *budget.Tracker
 }
 
 func (r RemoteHttp) New(
 	args value.Dict, bootargs map[string]interface{},
 ) (operators.Operator, error) {
-	tr, err := bootarg.GetTier(bootargs)
+	tr, err := bootarg.RequireTier(bootargs)
 	if err != nil {
 		return nil, err
 	}
-	return RemoteHttp{tr}, nil
+	return RemoteHttp{tr, bootarg.GetTracker(bootargs)}, nil
 }
 
 var _ operators.Operator = RemoteHttp{}
@@ -118,7 +121,7 @@ func (r RemoteHttp) Apply(ctx context.Context, staticKwargs operators.Kwargs, in
 						return
 					}
 				}
-				resp, err = http.Get(url)
+				resp, err = r.send(ctx, http.MethodGet, url, nil)
 			case "POST":
 				body := contextKwargs.GetUnsafe("body").String()
 				if cacheTtl >= 0 {
@@ -128,7 +131,7 @@ func (r RemoteHttp) Apply(ctx context.Context, staticKwargs operators.Kwargs, in
 						return
 					}
 				}
-				resp, err = http.Post(url, "application/json", strings.NewReader(body))
+				resp, err = r.send(ctx, http.MethodPost, url, strings.NewReader(body))
 			}
 			if err != nil {
 				result.err = fmt.Errorf("http error when calling %s: %w", url, err)
@@ -180,6 +183,22 @@ func (r RemoteHttp) Apply(ctx context.Context, staticKwargs operators.Kwargs, in
 	return nil
 }
 
+// send makes the request unless the query is out of remote calls, the request is cancelled along
+// with the query
+func (r RemoteHttp) send(ctx context.Context, method, url string, body io.Reader) (*http.Response, error) {
+	if err := r.tracker.RemoteCall("remote.http"); err != nil {
+		return nil, err
+	}
+	req, err := http.NewRequestWithContext(ctx, method, url, body)
+	if err != nil {
+		return nil, err
+	}
+	if method == http.MethodPost {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	return http.DefaultClient.Do(req)
+}
+
 func (r RemoteHttp) Signature() *operators.Signature {
 	return operators.NewSignature("remote", "http").
 		ParamWithHelp("url", value.Types.String, false, false, nil,
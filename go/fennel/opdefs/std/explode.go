@@ -4,18 +4,23 @@ import (
 	"context"
 	"fmt"
 
+	"fennel/engine/interpreter/bootarg"
 	"fennel/engine/operators"
+	"fennel/lib/budget"
 	"fennel/lib/value"
 )
 
-type ExplodeOperator struct{}
+type ExplodeOperator struct {
+	// tracker bounds the number of rows the operator can explode into
+	tracker *budget.Tracker
+}
 
 var _ operators.Operator = ExplodeOperator{}
 
 func (e ExplodeOperator) New(
 	args value.Dict, bootargs map[string]interface{},
 ) (operators.Operator, error) {
-	return ExplodeOperator{}, nil
+	return ExplodeOperator{tracker: bootarg.GetTracker(bootargs)}, nil
 }
 
 func (e ExplodeOperator) Signature() *operators.Signature {
@@ -25,9 +30,12 @@ func (e ExplodeOperator) Signature() *operators.Signature {
 		Input([]value.Type{value.Types.Dict})
 }
 
-func (e ExplodeOperator) Apply(_ context.Context, staticKwargs operators.Kwargs, in operators.InputIter, out *value.List) error {
+func (e ExplodeOperator) Apply(ctx context.Context, staticKwargs operators.Kwargs, in operators.InputIter, out *value.List) error {
 	field, _ := staticKwargs.Get("field")
 	for in.HasMore() {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
 		rows, _, err := in.Next()
 		if err != nil {
 			return err
@@ -54,7 +62,9 @@ func (e ExplodeOperator) Apply(_ context.Context, staticKwargs operators.Kwargs,
 					newRow.Set(kstr, value.Nil)
 					out.Append(newRow)
 				} else {
-					out.Grow(vs.Len())
+					if err := e.grow(out, vs.Len()); err != nil {
+						return err
+					}
 					for i := 0; i < vs.Len(); i++ {
 						v, _ := vs.At(i)
 						newRow := rowVal.Clone().(value.Dict)
@@ -113,7 +123,9 @@ func (e ExplodeOperator) Apply(_ context.Context, staticKwargs operators.Kwargs,
 				out.Append(newRow)
 			} else {
 				// explode each key
-				out.Grow(expectedLength)
+				if err := e.grow(out, expectedLength); err != nil {
+					return err
+				}
 				for i := 0; i < expectedLength; i++ {
 					newRow := rowVal.Clone().(value.Dict)
 					for ki := 0; ki < keys.Len(); ki++ {
@@ -135,6 +147,15 @@ func (e ExplodeOperator) Apply(_ context.Context, staticKwargs operators.Kwargs,
 	return nil
 }
 
+// grow makes space for n more rows in the output, failing if the rows would exceed the budget of the query
+func (e ExplodeOperator) grow(out *value.List, n int) error {
+	if err := e.tracker.Rows("std.explode", out.Len()+n); err != nil {
+		return err
+	}
+	out.Grow(n)
+	return nil
+}
+
 func validateKey(key value.Value, rowVal value.Dict) (string, error) {
 	k, ok := key.(value.String)
 	if !ok {
@@ -0,0 +1,143 @@
+package group
+
+import (
+	"context"
+	"fmt"
+	"log"
+
+	"fennel/engine/operators"
+	"fennel/lib/value"
+)
+
+func init() {
+	if err := operators.Register(groupAggregator{}); err != nil {
+		log.Fatalf("Failed to register std.groupagg operator: %v", err)
+	}
+}
+
+// reducers supported by std.groupagg. Each takes all the values of a group (in input order)
+// and reduces them to a single value
+var reducers = map[string]func(vals []value.Value) (value.Value, error){
+	"sum":   sum,
+	"mean":  mean,
+	"min":   minimum,
+	"max":   maximum,
+	"count": count,
+	"first": first,
+}
+
+type groupAggregator struct{}
+
+func (g groupAggregator) New(
+	args value.Dict, bootargs map[string]interface{},
+) (operators.Operator, error) {
+	return groupAggregator{}, nil
+}
+
+func (g groupAggregator) Apply(_ context.Context, staticKwargs operators.Kwargs, in operators.InputIter, out *value.List) error {
+	aggregate := string(staticKwargs.GetUnsafe("aggregate").(value.String))
+	reduce, ok := reducers[aggregate]
+	if !ok {
+		return fmt.Errorf("std.groupagg: unsupported aggregate '%s'", aggregate)
+	}
+	groups := make([]string, 0)
+	bys := make([]value.Value, 0)
+	elements := make(map[string][]value.Value)
+	for in.HasMore() {
+		heads, kwargs, err := in.Next()
+		if err != nil {
+			return err
+		}
+		of := kwargs.GetUnsafe("of")
+		if of == value.Nil {
+			of = heads[0]
+		}
+		by := kwargs.GetUnsafe("by")
+		key := by.String()
+		if _, ok := elements[key]; !ok {
+			groups = append(groups, key)
+			bys = append(bys, by)
+		}
+		elements[key] = append(elements[key], of)
+	}
+	out.Grow(len(groups))
+	for i, g := range groups {
+		v, err := reduce(elements[g])
+		if err != nil {
+			return fmt.Errorf("std.groupagg: failed to compute '%s' of group [%s]: %w", aggregate, bys[i], err)
+		}
+		out.Append(value.NewDict(map[string]value.Value{
+			"group": bys[i],
+			"value": v,
+		}))
+	}
+	return nil
+}
+
+func (g groupAggregator) Signature() *operators.Signature {
+	return operators.NewSignature("std", "groupagg").
+		ParamWithHelp("aggregate", value.Types.String, true, false, value.Nil, "StaticKwargs: Aggregate to compute per group, one of: sum, mean, min, max, count, first").
+		ParamWithHelp("by", value.Types.Any, false, false, nil, "ContextKwargs: Groups by the value of this expr").
+		ParamWithHelp("of", value.Types.Any, false, true, value.Nil, "ContextKwargs: Expr that is aggregated, defaults to the input row")
+}
+
+var _ operators.Operator = groupAggregator{}
+
+func sum(vals []value.Value) (value.Value, error) {
+	var ret value.Value = value.Int(0)
+	for _, v := range vals {
+		if err := value.Types.Number.Validate(v); err != nil {
+			return nil, fmt.Errorf("value [%s] is not a number", v)
+		}
+		var err error
+		if ret, err = ret.Op("+", v); err != nil {
+			return nil, err
+		}
+	}
+	return ret, nil
+}
+
+func mean(vals []value.Value) (value.Value, error) {
+	total, err := sum(vals)
+	if err != nil {
+		return nil, err
+	}
+	return total.Op("/", value.Int(len(vals)))
+}
+
+func extremum(vals []value.Value, op string) (value.Value, error) {
+	var ret value.Value
+	for i, v := range vals {
+		if err := value.Types.Number.Validate(v); err != nil {
+			return nil, fmt.Errorf("value [%s] is not a number", v)
+		}
+		if i == 0 {
+			ret = v
+			continue
+		}
+		better, err := v.Op(op, ret)
+		if err != nil {
+			return nil, err
+		}
+		if better.(value.Bool) {
+			ret = v
+		}
+	}
+	return ret, nil
+}
+
+func minimum(vals []value.Value) (value.Value, error) {
+	return extremum(vals, "<")
+}
+
+func maximum(vals []value.Value) (value.Value, error) {
+	return extremum(vals, ">")
+}
+
+func count(vals []value.Value) (value.Value, error) {
+	return value.Int(len(vals)), nil
+}
+
+func first(vals []value.Value) (value.Value, error) {
+	return vals[0], nil
+}
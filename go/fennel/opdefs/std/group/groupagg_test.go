@@ -0,0 +1,73 @@
+package group
+
+import (
+	"testing"
+
+	"fennel/lib/value"
+	"fennel/test/optest"
+	"fennel/tier"
+)
+
+func TestGroupAggregator_Apply(t *testing.T) {
+	t.Parallel()
+	op := groupAggregator{}
+	inputs := []value.Value{
+		value.NewDict(map[string]value.Value{"x": value.Int(1)}),
+		value.NewDict(map[string]value.Value{"x": value.Int(2)}),
+		value.NewDict(map[string]value.Value{"x": value.Int(3)}),
+		value.NewDict(map[string]value.Value{"x": value.Int(4)}),
+	}
+	context := []value.Dict{
+		value.NewDict(map[string]value.Value{"by": value.String("a"), "of": value.Int(5)}),
+		value.NewDict(map[string]value.Value{"by": value.String("b"), "of": value.Double(2.5)}),
+		value.NewDict(map[string]value.Value{"by": value.String("a"), "of": value.Int(1)}),
+		value.NewDict(map[string]value.Value{"by": value.String("b"), "of": value.Int(4)}),
+	}
+	result := func(a, b value.Value) []value.Value {
+		return []value.Value{
+			value.NewDict(map[string]value.Value{"group": value.String("a"), "value": a}),
+			value.NewDict(map[string]value.Value{"group": value.String("b"), "value": b}),
+		}
+	}
+	scenarios := []struct {
+		aggregate string
+		expected  []value.Value
+	}{
+		{"sum", result(value.Int(6), value.Double(6.5))},
+		{"mean", result(value.Double(3), value.Double(3.25))},
+		{"min", result(value.Int(1), value.Double(2.5))},
+		{"max", result(value.Int(5), value.Int(4))},
+		{"count", result(value.Int(2), value.Int(2))},
+		{"first", result(value.Int(5), value.Double(2.5))},
+	}
+	for _, scene := range scenarios {
+		static := value.NewDict(map[string]value.Value{"aggregate": value.String(scene.aggregate)})
+		optest.AssertEqual(t, tier.Tier{}, op, static, [][]value.Value{inputs}, context, scene.expected)
+	}
+
+	// without 'of', the row itself is aggregated
+	optest.AssertEqual(t, tier.Tier{}, op, value.NewDict(map[string]value.Value{"aggregate": value.String("sum")}),
+		[][]value.Value{{value.Int(1), value.Int(2), value.Int(3)}},
+		[]value.Dict{
+			value.NewDict(map[string]value.Value{"by": value.Bool(true)}),
+			value.NewDict(map[string]value.Value{"by": value.Bool(false)}),
+			value.NewDict(map[string]value.Value{"by": value.Bool(true)}),
+		},
+		[]value.Value{
+			value.NewDict(map[string]value.Value{"group": value.Bool(true), "value": value.Int(4)}),
+			value.NewDict(map[string]value.Value{"group": value.Bool(false), "value": value.Int(2)}),
+		},
+	)
+
+	// unknown aggregate
+	optest.AssertError(t, tier.Tier{}, op, value.NewDict(map[string]value.Value{"aggregate": value.String("median")}),
+		[][]value.Value{inputs}, context)
+	// sum of non numbers
+	optest.AssertError(t, tier.Tier{}, op, value.NewDict(map[string]value.Value{"aggregate": value.String("sum")}),
+		[][]value.Value{inputs}, []value.Dict{
+			value.NewDict(map[string]value.Value{"by": value.String("a"), "of": value.String("x")}),
+			value.NewDict(map[string]value.Value{"by": value.String("a"), "of": value.Int(1)}),
+			value.NewDict(map[string]value.Value{"by": value.String("a"), "of": value.Int(1)}),
+			value.NewDict(map[string]value.Value{"by": value.String("a"), "of": value.Int(1)}),
+		})
+}
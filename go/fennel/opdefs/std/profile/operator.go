@@ -14,11 +14,36 @@ import (
 	"fennel/lib/ftypes"
 	libprofile "fennel/lib/profile"
 	"fennel/lib/value"
+	"fennel/pcache"
 	"fennel/tier"
 )
 
 var cacheValueDuration = 2 * time.Minute
 
+// profiles are served for a while after they expire while they are refreshed in the background
+var cacheGraceDuration = 1 * time.Minute
+
+// profiles that do not exist are cached for less time since they are likely to be set soon
+const missingCacheDuration = 10 * time.Second
+
+func cacheOptions() pcache.LoadOptions {
+	return pcache.LoadOptions{
+		Namespace: profile.CACHE_NAMESPACE,
+		TTL:       cacheValueDuration,
+		Grace:     cacheGraceDuration,
+		Negative: func(v interface{}) bool {
+			return v == nil || v == value.Nil
+		},
+		NegativeTTL: missingCacheDuration,
+		Cost: func(key string, v interface{}) int64 {
+			if val, ok := v.(value.Value); ok {
+				return int64(len(key) + len(val.String()))
+			}
+			return int64(len(key))
+		},
+	}
+}
+
 func init() {
 	err := operators.Register(profileOp{})
 	if err != nil {
@@ -35,7 +60,7 @@ type profileOp struct {
 func (p profileOp) New(
 	args value.Dict, bootargs map[string]interface{},
 ) (operators.Operator, error) {
-	tr, err := bootarg.GetTier(bootargs)
+	tr, err := bootarg.RequireTier(bootargs)
 	if err != nil {
 		return nil, err
 	}
@@ -56,6 +81,7 @@ func (p profileOp) New(
 func (p profileOp) Apply(ctx context.Context, staticKwargs operators.Kwargs, in operators.InputIter, out *value.List) (err error) {
 	var reqs []libprofile.ProfileItemKey
 	var rows []value.Value
+	var asOfs []int64
 	for in.HasMore() {
 		heads, kwargs, err := in.Next()
 		if err != nil {
@@ -69,12 +95,13 @@ func (p profileOp) Apply(ctx context.Context, staticKwargs operators.Kwargs, in
 		}
 		reqs = append(reqs, req)
 		rows = append(rows, rowVal)
+		asOfs = append(asOfs, int64(kwargs.GetUnsafe("as_of").(value.Int)))
 	}
 	var vals []value.Value
 	if p.mockID != 0 {
 		vals = mock.GetProfiles(reqs, p.mockID)
 	} else {
-		vals, err = p.getProfiles(ctx, reqs)
+		vals, err = p.getProfilesAsOf(ctx, reqs, asOfs)
 		if err != nil {
 			return err
 		}
@@ -102,6 +129,43 @@ func (p profileOp) Apply(ctx context.Context, staticKwargs operators.Kwargs, in
 	return nil
 }
 
+// getProfilesAsOf returns the values of the profiles at the given times, times are in seconds
+// since epoch and profiles with a time of zero get their current value
+func (p profileOp) getProfilesAsOf(ctx context.Context, profileKeys []libprofile.ProfileItemKey, asOfs []int64) ([]value.Value, error) {
+	// group the keys by time, the current values are read through the cache
+	groups := make(map[int64][]int)
+	for i, asOf := range asOfs {
+		groups[asOf] = append(groups[asOf], i)
+	}
+	res := make([]value.Value, len(profileKeys))
+	for asOf, ptrs := range groups {
+		keys := make([]libprofile.ProfileItemKey, len(ptrs))
+		for i, ptr := range ptrs {
+			keys[i] = profileKeys[ptr]
+		}
+		var vals []value.Value
+		if asOf == 0 {
+			var err error
+			if vals, err = p.getProfiles(ctx, keys); err != nil {
+				return nil, err
+			}
+		} else {
+			items, err := profile.GetBatchAsOf(ctx, p.tier, keys, time.Unix(asOf, 0))
+			if err != nil {
+				return nil, err
+			}
+			vals = make([]value.Value, len(items))
+			for i := range items {
+				vals[i] = items[i].Value
+			}
+		}
+		for i, ptr := range ptrs {
+			res[ptr] = vals[i]
+		}
+	}
+	return res, nil
+}
+
 func (p profileOp) getProfiles(ctx context.Context, profileKeys []libprofile.ProfileItemKey) ([]value.Value, error) {
 	res := make([]value.Value, len(profileKeys))
 	if disableCache, present := os.LookupEnv("DISABLE_CACHE"); present && disableCache == "1" {
@@ -112,59 +176,56 @@ func (p profileOp) getProfiles(ctx context.Context, profileKeys []libprofile.Pro
 		for i, v := range vals {
 			res[i] = v.Value
 		}
+		return res, nil
 	}
 
-	var uncached []libprofile.ProfileItemKey
-	var ptrs []int
-	// GetBatched returns nil for profiles that were not found
-	// store in cache as it is, to avoid querying DB for profiles that we know do not exist
-	// and for profile operator to set default correctly
+	keys := make([]string, len(profileKeys))
+	pks := make(map[string]libprofile.ProfileItemKey, len(profileKeys))
 	for i, pi := range profileKeys {
-		key := p.getKey(pi)
-		v, ok := p.tier.PCache.Get(key, "Profile")
-		if !ok {
-			// did not find profile, filter out
-			uncached = append(uncached, pi)
-			ptrs = append(ptrs, i)
-		} else {
-			// found profile
-			if v == nil {
-				// if nil, store as it is
-				res[i] = value.Nil
-			} else {
-				val, ok := v.(value.Value)
-				if !ok {
-					// this should never happen, but if it happens
-					// we pretend it wasn't in cache and log error
-					log.Printf("unexpected error in profile op: expected v to be a value but found '%v' instead", v)
-					uncached = append(uncached, pi)
-					ptrs = append(ptrs, i)
-				} else {
-					res[i] = val
-				}
-			}
-		}
+		keys[i] = p.getKey(pi)
+		pks[keys[i]] = pi
 	}
-	// now get uncached profiles
-	vals, err := profile.GetBatch(ctx, p.tier, uncached)
+	// GetBatch returns value.Nil for profiles that were not found, these are cached as well to avoid
+	// querying DB for profiles that we know do not exist and for profile operator to set default correctly
+	vals, err := p.tier.PCache.GetOrLoad(ctx, keys, cacheOptions(), func(ctx context.Context, keys []string) ([]interface{}, error) {
+		uncached := make([]libprofile.ProfileItemKey, len(keys))
+		for i, k := range keys {
+			uncached[i] = pks[k]
+		}
+		items, err := profile.GetBatch(ctx, p.tier, uncached)
+		if err != nil {
+			return nil, err
+		}
+		ret := make([]interface{}, len(items))
+		for i := range items {
+			ret[i] = items[i].Value
+		}
+		return ret, nil
+	})
 	if err != nil {
 		return nil, err
 	}
-	// add them to cache
-	for i, pi := range uncached {
-		key := p.getKey(pi)
-		p.tier.PCache.SetWithTTL(key, vals[i].Value, int64(len(key)+len(vals[i].Value.String())) /* cost */, cacheValueDuration, "Profile")
-	}
-
-	// finally, return result
 	for i, v := range vals {
-		res[ptrs[i]] = v.Value
+		switch v := v.(type) {
+		case nil:
+			res[i] = value.Nil
+		case value.Value:
+			res[i] = v
+		default:
+			// this should never happen, but if it happens we log the error and read the profile again
+			log.Printf("unexpected error in profile op: expected v to be a value but found '%v' instead", v)
+			item, err := profile.Get(ctx, p.tier, profileKeys[i])
+			if err != nil {
+				return nil, err
+			}
+			res[i] = item.Value
+		}
 	}
 	return res, nil
 }
 
 func (p profileOp) getKey(pi libprofile.ProfileItemKey) string {
-	return fmt.Sprintf("profile:%s:%s:%s", pi.OType, pi.Oid, pi.Key)
+	return profile.CacheKey(pi)
 }
 
 func (p profileOp) Signature() *operators.Signature {
@@ -174,6 +235,7 @@ func (p profileOp) Signature() *operators.Signature {
 		Param("oid", value.Types.ID, false, false, value.Nil).
 		Param("key", value.Types.String, false, false, value.Nil).
 		Param("version", value.Types.Int, false, true, value.Int(0)).
+		Param("as_of", value.Types.Int, false, true, value.Int(0)).
 		Param("field", value.Types.String, true, true, value.String("")).
 		Param("default", value.Types.Any, true, true, value.Nil)
 }
@@ -124,6 +124,7 @@ func TestProfileOpCacheMultiple(t *testing.T) {
 	ctx := context.Background()
 
 	cacheValueDuration = time.Second * 10
+	cacheGraceDuration = 0
 	query := &ast.OpCall{
 		Operands:  []ast.Ast{&ast.Var{Name: "actions"}},
 		Vars:      []string{"a"},
@@ -233,6 +234,7 @@ func TestProfileOpCacheMultiple(t *testing.T) {
 	verifyMultiple(t, i, query, expected3)
 
 	cacheValueDuration = 2 * time.Minute
+	cacheGraceDuration = 1 * time.Minute
 }
 
 func verifyMultiple(t *testing.T, i *interpreter.Interpreter, query ast.Ast, expected []value.Dict) {
@@ -101,6 +101,7 @@ func TestProfileOpCache(t *testing.T) {
 	defer test.Teardown(tier)
 	ctx := context.Background()
 	cacheValueDuration = time.Second * 10
+	cacheGraceDuration = 0
 
 	otype, oid, key, val, ver := ftypes.OType("user"), 223, "age", value.Int(7), uint64(4)
 	query := &ast.OpCall{
@@ -146,6 +147,7 @@ func TestProfileOpCache(t *testing.T) {
 	expected2.Set("profile_value", val)
 	verify(t, i, query, expected2)
 	cacheValueDuration = 2 * time.Minute
+	cacheGraceDuration = 1 * time.Minute
 }
 
 func verify(t *testing.T, i *interpreter.Interpreter, query ast.Ast, expected value.Dict) {
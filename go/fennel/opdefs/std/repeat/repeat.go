@@ -4,7 +4,9 @@ import (
 	"context"
 	"fmt"
 
+	"fennel/engine/interpreter/bootarg"
 	"fennel/engine/operators"
+	"fennel/lib/budget"
 	"fennel/lib/value"
 )
 
@@ -14,16 +16,22 @@ func init() {
 	}
 }
 
-type repeater struct{}
+type repeater struct {
+	// tracker bounds the number of rows the operator can repeat into
+	tracker *budget.Tracker
+}
 
 func (r repeater) New(
 	args value.Dict, bootargs map[string]interface{},
 ) (operators.Operator, error) {
-	return repeater{}, nil
+	return repeater{tracker: bootarg.GetTracker(bootargs)}, nil
 }
 
-func (r repeater) Apply(_ context.Context, _ operators.Kwargs, in operators.InputIter, out *value.List) error {
+func (r repeater) Apply(ctx context.Context, _ operators.Kwargs, in operators.InputIter, out *value.List) error {
 	for in.HasMore() {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
 		heads, kwargs, err := in.Next()
 		if err != nil {
 			return err
@@ -32,6 +40,9 @@ func (r repeater) Apply(_ context.Context, _ operators.Kwargs, in operators.Inpu
 		if count < 0 {
 			return fmt.Errorf("repeat: negative repeat count")
 		}
+		if err := r.tracker.Rows("std.repeat", out.Len()+int(count)); err != nil {
+			return err
+		}
 		for i := int64(0); i < count; i++ {
 			if i == 0 {
 				out.Append(heads[0])
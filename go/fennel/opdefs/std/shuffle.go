@@ -5,18 +5,22 @@ import (
 	"math/rand"
 	"reflect"
 
+	"fennel/engine/interpreter/bootarg"
 	"fennel/engine/operators"
 	"fennel/lib/value"
 )
 
-type ShuffleOperator struct{}
+type ShuffleOperator struct {
+	// rnd is the source the rows are shuffled with, the global source is used if the query is not seeded
+	rnd *rand.Rand
+}
 
 var _ operators.Operator = ShuffleOperator{}
 
 func (op ShuffleOperator) New(
 	args value.Dict, bootargs map[string]interface{},
 ) (operators.Operator, error) {
-	return ShuffleOperator{}, nil
+	return ShuffleOperator{bootarg.Rand(bootargs)}, nil
 }
 
 func (op ShuffleOperator) Signature() *operators.Signature {
@@ -33,7 +37,11 @@ func (op ShuffleOperator) Apply(_ context.Context, _ operators.Kwargs, in operat
 		row := heads[0]
 		rows = append(rows, row)
 	}
-	rand.Shuffle(len(rows), reflect.Swapper(rows))
+	if op.rnd != nil {
+		op.rnd.Shuffle(len(rows), reflect.Swapper(rows))
+	} else {
+		rand.Shuffle(len(rows), reflect.Swapper(rows))
+	}
 	out.Append(rows...)
 	return nil
 }
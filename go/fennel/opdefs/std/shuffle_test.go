@@ -1,11 +1,18 @@
 package std
 
 import (
+	"context"
 	"testing"
 
+	"fennel/engine/ast"
+	"fennel/engine/interpreter"
+	"fennel/engine/interpreter/bootarg"
 	"fennel/lib/value"
 	"fennel/test/optest"
 	"fennel/tier"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func TestShuffleOperator_Apply(t *testing.T) {
@@ -22,3 +29,49 @@ func TestShuffleOperator_Apply(t *testing.T) {
 	copy(outTable, intable)
 	optest.AssertElementsMatch(t, tr, &ShuffleOperator{}, staticKwargs, [][]value.Value{intable}, contextKwargs, outTable)
 }
+
+func TestShuffleOperator_Seeded(t *testing.T) {
+	rows := make([]value.Value, 20)
+	for i := range rows {
+		rows[i] = value.Int(i)
+	}
+	shuffle := func(from string) *ast.OpCall {
+		return &ast.OpCall{Namespace: "std", Name: "shuffle", Operands: []ast.Ast{ast.MakeVar(from)}, Kwargs: ast.MakeDict(nil)}
+	}
+	// shuffles the rows twice, in parallel, and then shuffles the first shuffle again
+	tree := ast.MakeQuery([]*ast.Statement{
+		ast.MakeStatement("both", ast.MakeList(shuffle("rows"), shuffle("rows"))),
+		ast.MakeStatement("", ast.MakeList(ast.MakeVar("both"), shuffle("rows"))),
+	})
+	run := func(bootargs map[string]interface{}, args value.Dict) []value.Value {
+		args.Set("rows", value.NewList(rows...))
+		i, err := interpreter.NewInterpreter(context.Background(), bootargs, args)
+		require.NoError(t, err)
+		res, err := tree.AcceptValue(i)
+		require.NoError(t, err)
+		both := res.(value.List).Values()[0].(value.List).Values()
+		ret := append(both, res.(value.List).Values()[1])
+		for _, r := range ret {
+			assert.ElementsMatch(t, rows, r.(value.List).Values())
+		}
+		return ret
+	}
+	seeded := func(seed int64) []value.Value {
+		bootargs := bootarg.Create(tier.Tier{})
+		bootarg.SetSeed(bootargs, seed)
+		return run(bootargs, value.NewDict(nil))
+	}
+
+	first := seeded(42)
+	for i := 0; i < 5; i++ {
+		assert.Equal(t, first, seeded(42))
+	}
+	// the seed can be set in the args as well
+	assert.Equal(t, first, run(bootarg.Create(tier.Tier{}), value.NewDict(map[string]value.Value{bootarg.SEED_ARG: value.Int(42)})))
+	// shuffles at different positions are different
+	assert.NotEqual(t, first[0], first[1])
+	assert.NotEqual(t, first[0], first[2])
+	assert.NotEqual(t, first[1], first[2])
+	// and so are runs with different seeds
+	assert.NotEqual(t, first, seeded(43))
+}
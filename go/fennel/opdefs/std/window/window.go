@@ -0,0 +1,335 @@
+package window
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"math"
+	"sort"
+
+	"fennel/engine/operators"
+	"fennel/lib/value"
+)
+
+func init() {
+	if err := operators.Register(windowOp{}); err != nil {
+		log.Fatalf("Failed to register std.window operator: %v", err)
+	}
+}
+
+// functions supported by std.window, each is computed independently within every partition
+var functions = map[string]func(rows []row, opts options) error{
+	"row_number":   rowNumber,
+	"rank":         rank,
+	"dense_rank":   denseRank,
+	"percent_rank": percentRank,
+	"cumsum":       cumsum,
+	"lag":          lag,
+	"lead":         lead,
+	"minmax":       minmax,
+	"zscore":       zscore,
+}
+
+type windowOp struct{}
+
+var _ operators.Operator = windowOp{}
+
+type row struct {
+	idx    int
+	order  value.Value
+	of     value.Value
+	result value.Value
+}
+
+type options struct {
+	offset   int
+	default_ value.Value
+}
+
+func (op windowOp) New(
+	args value.Dict, bootargs map[string]interface{},
+) (operators.Operator, error) {
+	return windowOp{}, nil
+}
+
+func (op windowOp) Signature() *operators.Signature {
+	return operators.NewSignature("std", "window").
+		ParamWithHelp("function", value.Types.String, true, false, value.Nil,
+			"StaticKwargs: Window function to compute, one of: row_number, rank, dense_rank, percent_rank, cumsum, lag, lead, minmax, zscore").
+		ParamWithHelp("field", value.Types.String, true, false, value.Nil, "StaticKwargs: Field of the input dict where the result is set").
+		ParamWithHelp("reverse", value.Types.Bool, true, true, value.Bool(false), "StaticKwargs: Set true to order rows in descending order within a partition").
+		ParamWithHelp("offset", value.Types.Int, true, true, value.Int(1), "StaticKwargs: Number of rows to look behind (lag) or ahead (lead)").
+		ParamWithHelp("default", value.Types.Any, true, true, value.Nil, "StaticKwargs: Value used by lag/lead when the offset row does not exist").
+		ParamWithHelp("partition", value.Types.Any, false, true, value.Nil, "ContextKwargs: Rows with the same value of this expr are in the same partition").
+		ParamWithHelp("order", value.Types.Any, false, true, value.Nil, "ContextKwargs: Expr (number or string) to order rows by within a partition, defaults to input order").
+		ParamWithHelp("of", value.Types.Any, false, true, value.Nil, "ContextKwargs: Expr that cumsum, lag, lead, minmax and zscore are computed on").
+		Input([]value.Type{value.Types.Dict})
+}
+
+func (op windowOp) Apply(_ context.Context, staticKwargs operators.Kwargs, in operators.InputIter, out *value.List) error {
+	fname := string(staticKwargs.GetUnsafe("function").(value.String))
+	fn, ok := functions[fname]
+	if !ok {
+		return fmt.Errorf("std.window: unsupported function '%s'", fname)
+	}
+	field := string(staticKwargs.GetUnsafe("field").(value.String))
+	reverse := bool(staticKwargs.GetUnsafe("reverse").(value.Bool))
+	opts := options{
+		offset:   int(staticKwargs.GetUnsafe("offset").(value.Int)),
+		default_: staticKwargs.GetUnsafe("default"),
+	}
+	if opts.offset < 0 {
+		return fmt.Errorf("std.window: offset can not be negative, got: %d", opts.offset)
+	}
+
+	var inputs []value.Dict
+	partitions := make([]string, 0)
+	rows := make(map[string][]row)
+	for in.HasMore() {
+		heads, kwargs, err := in.Next()
+		if err != nil {
+			return err
+		}
+		partition := kwargs.GetUnsafe("partition").String()
+		if _, ok := rows[partition]; !ok {
+			partitions = append(partitions, partition)
+		}
+		rows[partition] = append(rows[partition], row{
+			idx:   len(inputs),
+			order: kwargs.GetUnsafe("order"),
+			of:    kwargs.GetUnsafe("of"),
+		})
+		inputs = append(inputs, heads[0].(value.Dict))
+	}
+
+	results := make([]value.Value, len(inputs))
+	for _, p := range partitions {
+		prows := rows[p]
+		if err := sortRows(prows, reverse); err != nil {
+			return err
+		}
+		if err := fn(prows, opts); err != nil {
+			return fmt.Errorf("std.window: failed to compute '%s': %w", fname, err)
+		}
+		for _, r := range prows {
+			results[r.idx] = r.result
+		}
+	}
+	out.Grow(len(inputs))
+	for i, d := range inputs {
+		d.Set(field, results[i])
+		out.Append(d)
+	}
+	return nil
+}
+
+// sortRows stably sorts the rows of a partition by their order key. If no row has an order key,
+// the input order is retained
+func sortRows(rows []row, reverse bool) error {
+	var err error
+	sort.SliceStable(rows, func(i, j int) bool {
+		c, e := compare(rows[i].order, rows[j].order)
+		if e != nil {
+			err = e
+		}
+		if reverse {
+			return c > 0
+		}
+		return c < 0
+	})
+	return err
+}
+
+// compare returns -1, 0 or 1 depending on whether left is smaller, equal or larger than right
+func compare(left, right value.Value) (int, error) {
+	switch l := left.(type) {
+	case value.Int, value.Double:
+		lf, _ := toFloat(l)
+		rf, ok := toFloat(right)
+		if !ok {
+			break
+		}
+		switch {
+		case lf < rf:
+			return -1, nil
+		case lf > rf:
+			return 1, nil
+		}
+		return 0, nil
+	case value.String:
+		r, ok := right.(value.String)
+		if !ok {
+			break
+		}
+		switch {
+		case l < r:
+			return -1, nil
+		case l > r:
+			return 1, nil
+		}
+		return 0, nil
+	default:
+		if left == value.Nil && right == value.Nil {
+			return 0, nil
+		}
+	}
+	return 0, fmt.Errorf("std.window: order keys should all be numbers or all be strings. Got [%s] and [%s]", left, right)
+}
+
+func toFloat(v value.Value) (float64, bool) {
+	switch v := v.(type) {
+	case value.Int:
+		return float64(v), true
+	case value.Double:
+		return float64(v), true
+	}
+	return 0, false
+}
+
+// sameOrder returns whether the rows are peers in the sort order, e.g. order keys 1 and 1.0 are
+// peers
+func sameOrder(left, right row) bool {
+	c, err := compare(left.order, right.order)
+	return err == nil && c == 0
+}
+
+func rowNumber(rows []row, _ options) error {
+	for i := range rows {
+		rows[i].result = value.Int(i + 1)
+	}
+	return nil
+}
+
+func rank(rows []row, _ options) error {
+	for i := range rows {
+		if i > 0 && sameOrder(rows[i], rows[i-1]) {
+			rows[i].result = rows[i-1].result
+		} else {
+			rows[i].result = value.Int(i + 1)
+		}
+	}
+	return nil
+}
+
+func denseRank(rows []row, _ options) error {
+	curr := 0
+	for i := range rows {
+		if i == 0 || !sameOrder(rows[i], rows[i-1]) {
+			curr++
+		}
+		rows[i].result = value.Int(curr)
+	}
+	return nil
+}
+
+func percentRank(rows []row, opts options) error {
+	if err := rank(rows, opts); err != nil {
+		return err
+	}
+	for i := range rows {
+		if len(rows) == 1 {
+			rows[i].result = value.Double(0)
+			continue
+		}
+		r := rows[i].result.(value.Int)
+		rows[i].result = value.Double(float64(r-1) / float64(len(rows)-1))
+	}
+	return nil
+}
+
+func cumsum(rows []row, _ options) error {
+	var sum value.Value = value.Int(0)
+	for i := range rows {
+		if err := value.Types.Number.Validate(rows[i].of); err != nil {
+			return fmt.Errorf("value [%s] is not a number", rows[i].of)
+		}
+		var err error
+		if sum, err = sum.Op("+", rows[i].of); err != nil {
+			return err
+		}
+		rows[i].result = sum
+	}
+	return nil
+}
+
+func lag(rows []row, opts options) error {
+	for i := range rows {
+		if j := i - opts.offset; j >= 0 {
+			rows[i].result = rows[j].of
+		} else {
+			rows[i].result = opts.default_
+		}
+	}
+	return nil
+}
+
+func lead(rows []row, opts options) error {
+	for i := range rows {
+		if j := i + opts.offset; j < len(rows) {
+			rows[i].result = rows[j].of
+		} else {
+			rows[i].result = opts.default_
+		}
+	}
+	return nil
+}
+
+func numbers(rows []row) ([]float64, error) {
+	ret := make([]float64, len(rows))
+	for i := range rows {
+		f, ok := toFloat(rows[i].of)
+		if !ok {
+			return nil, fmt.Errorf("value [%s] is not a number", rows[i].of)
+		}
+		ret[i] = f
+	}
+	return ret, nil
+}
+
+// minmax scales values of the partition to [0, 1]. If all values are equal, they are all set to 0
+func minmax(rows []row, _ options) error {
+	vals, err := numbers(rows)
+	if err != nil {
+		return err
+	}
+	lo, hi := math.Inf(1), math.Inf(-1)
+	for _, v := range vals {
+		lo = math.Min(lo, v)
+		hi = math.Max(hi, v)
+	}
+	for i, v := range vals {
+		if hi == lo {
+			rows[i].result = value.Double(0)
+		} else {
+			rows[i].result = value.Double((v - lo) / (hi - lo))
+		}
+	}
+	return nil
+}
+
+// zscore standardizes values of the partition to zero mean and unit (population) standard deviation.
+// If the standard deviation is zero, all values are set to 0
+func zscore(rows []row, _ options) error {
+	vals, err := numbers(rows)
+	if err != nil {
+		return err
+	}
+	mean := 0.0
+	for _, v := range vals {
+		mean += v
+	}
+	mean /= float64(len(vals))
+	variance := 0.0
+	for _, v := range vals {
+		variance += (v - mean) * (v - mean)
+	}
+	std := math.Sqrt(variance / float64(len(vals)))
+	for i, v := range vals {
+		if std == 0 {
+			rows[i].result = value.Double(0)
+		} else {
+			rows[i].result = value.Double((v - mean) / std)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,157 @@
+package window
+
+import (
+	"testing"
+
+	"fennel/lib/value"
+	"fennel/test/optest"
+	"fennel/tier"
+)
+
+func rows(n int) []value.Value {
+	ret := make([]value.Value, n)
+	for i := range ret {
+		ret[i] = value.NewDict(map[string]value.Value{"id": value.Int(i)})
+	}
+	return ret
+}
+
+func withResult(field string, results ...value.Value) []value.Value {
+	ret := make([]value.Value, len(results))
+	for i, r := range results {
+		ret[i] = value.NewDict(map[string]value.Value{"id": value.Int(i), field: r})
+	}
+	return ret
+}
+
+func static(function string, extra map[string]value.Value) value.Dict {
+	ret := value.NewDict(map[string]value.Value{"function": value.String(function), "field": value.String("out")})
+	for k, v := range extra {
+		ret.Set(k, v)
+	}
+	return ret
+}
+
+func TestWindow_Apply(t *testing.T) {
+	t.Parallel()
+	op := windowOp{}
+	tr := tier.Tier{}
+	// two partitions interleaved in the input
+	context := []value.Dict{
+		value.NewDict(map[string]value.Value{"partition": value.String("a"), "order": value.Int(3), "of": value.Int(10)}),
+		value.NewDict(map[string]value.Value{"partition": value.String("b"), "order": value.Int(1), "of": value.Int(1)}),
+		value.NewDict(map[string]value.Value{"partition": value.String("a"), "order": value.Double(1.5), "of": value.Int(20)}),
+		value.NewDict(map[string]value.Value{"partition": value.String("a"), "order": value.Int(3), "of": value.Int(30)}),
+		value.NewDict(map[string]value.Value{"partition": value.String("b"), "order": value.Int(0), "of": value.Int(3)}),
+	}
+	scenarios := []struct {
+		static   value.Dict
+		expected []value.Value
+	}{
+		{
+			static("row_number", nil),
+			withResult("out", value.Int(2), value.Int(2), value.Int(1), value.Int(3), value.Int(1)),
+		},
+		{
+			static("rank", nil),
+			withResult("out", value.Int(2), value.Int(2), value.Int(1), value.Int(2), value.Int(1)),
+		},
+		{
+			static("rank", map[string]value.Value{"reverse": value.Bool(true)}),
+			withResult("out", value.Int(1), value.Int(1), value.Int(3), value.Int(1), value.Int(2)),
+		},
+		{
+			static("dense_rank", map[string]value.Value{"reverse": value.Bool(true)}),
+			withResult("out", value.Int(1), value.Int(1), value.Int(2), value.Int(1), value.Int(2)),
+		},
+		{
+			static("percent_rank", nil),
+			withResult("out", value.Double(0.5), value.Double(1), value.Double(0), value.Double(0.5), value.Double(0)),
+		},
+		{
+			static("cumsum", nil),
+			withResult("out", value.Int(30), value.Int(4), value.Int(20), value.Int(60), value.Int(3)),
+		},
+		{
+			static("lag", nil),
+			withResult("out", value.Int(20), value.Int(3), value.Nil, value.Int(10), value.Nil),
+		},
+		{
+			static("lead", map[string]value.Value{"default": value.Int(-1)}),
+			withResult("out", value.Int(30), value.Int(-1), value.Int(10), value.Int(-1), value.Int(1)),
+		},
+		{
+			static("lag", map[string]value.Value{"offset": value.Int(2)}),
+			withResult("out", value.Nil, value.Nil, value.Nil, value.Int(20), value.Nil),
+		},
+		{
+			static("minmax", nil),
+			withResult("out", value.Double(0), value.Double(0), value.Double(0.5), value.Double(1), value.Double(1)),
+		},
+		{
+			static("zscore", nil),
+			withResult("out", value.Double(-1.224744871391589), value.Double(-1), value.Double(0), value.Double(1.224744871391589), value.Double(1)),
+		},
+	}
+	for _, scene := range scenarios {
+		optest.AssertEqual(t, tr, op, scene.static, [][]value.Value{rows(5)}, context, scene.expected)
+	}
+}
+
+func TestWindow_NoPartitionOrOrder(t *testing.T) {
+	t.Parallel()
+	op := windowOp{}
+	tr := tier.Tier{}
+	// without partition & order, the whole input is a single partition in input order
+	context := []value.Dict{
+		value.NewDict(map[string]value.Value{"of": value.Int(1)}),
+		value.NewDict(map[string]value.Value{"of": value.Int(2)}),
+		value.NewDict(map[string]value.Value{"of": value.Int(3)}),
+	}
+	optest.AssertEqual(t, tr, op, static("cumsum", nil), [][]value.Value{rows(3)}, context,
+		withResult("out", value.Int(1), value.Int(3), value.Int(6)))
+	optest.AssertEqual(t, tr, op, static("row_number", nil), [][]value.Value{rows(3)}, context,
+		withResult("out", value.Int(1), value.Int(2), value.Int(3)))
+}
+
+func TestWindow_MixedNumericOrder(t *testing.T) {
+	t.Parallel()
+	op := windowOp{}
+	tr := tier.Tier{}
+	// 1 and 1.0 sort as equal, so they are ranked as peers as well
+	context := []value.Dict{
+		value.NewDict(map[string]value.Value{"order": value.Int(1)}),
+		value.NewDict(map[string]value.Value{"order": value.Double(1)}),
+		value.NewDict(map[string]value.Value{"order": value.Int(2)}),
+	}
+	optest.AssertEqual(t, tr, op, static("rank", nil), [][]value.Value{rows(3)}, context,
+		withResult("out", value.Int(1), value.Int(1), value.Int(3)))
+	optest.AssertEqual(t, tr, op, static("dense_rank", nil), [][]value.Value{rows(3)}, context,
+		withResult("out", value.Int(1), value.Int(1), value.Int(2)))
+}
+
+func TestWindow_Errors(t *testing.T) {
+	t.Parallel()
+	op := windowOp{}
+	tr := tier.Tier{}
+	numbers := []value.Dict{
+		value.NewDict(map[string]value.Value{"order": value.Int(1), "of": value.Int(1)}),
+		value.NewDict(map[string]value.Value{"order": value.Int(2), "of": value.Int(2)}),
+	}
+	// unknown function
+	optest.AssertError(t, tr, op, static("ntile", nil), [][]value.Value{rows(2)}, numbers)
+	// negative offset
+	optest.AssertError(t, tr, op, static("lag", map[string]value.Value{"offset": value.Int(-1)}), [][]value.Value{rows(2)}, numbers)
+	// mixed order keys
+	optest.AssertError(t, tr, op, static("rank", nil), [][]value.Value{rows(2)}, []value.Dict{
+		value.NewDict(map[string]value.Value{"order": value.Int(1)}),
+		value.NewDict(map[string]value.Value{"order": value.String("x")}),
+	})
+	// cumsum over non-numbers
+	optest.AssertError(t, tr, op, static("cumsum", nil), [][]value.Value{rows(2)}, []value.Dict{
+		value.NewDict(map[string]value.Value{"of": value.Int(1)}),
+		value.NewDict(map[string]value.Value{"of": value.String("x")}),
+	})
+	// input must be dicts
+	optest.AssertError(t, tr, op, static("rank", nil), [][]value.Value{{value.Int(1), value.Int(2)}}, numbers)
+}
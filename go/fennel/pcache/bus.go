@@ -0,0 +1,179 @@
+package pcache
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"sync"
+	"time"
+
+	"fennel/redis"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"go.uber.org/zap"
+)
+
+// INVALIDATION_CHANNEL is the redis channel on which invalidations are published
+const INVALIDATION_CHANNEL = "pcache_invalidations"
+
+const resubscribeInterval = time.Second
+
+var invalidationLag = promauto.NewHistogramVec(prometheus.HistogramOpts{
+	Name:    "pcache_invalidation_lag_seconds",
+	Help:    "Time between an invalidation being published and it being applied by another process",
+	Buckets: []float64{0.001, 0.005, 0.01, 0.05, 0.1, 0.5, 1, 5, 30},
+}, []string{"namespace"})
+
+var invalidationsPublished = promauto.NewCounterVec(prometheus.CounterOpts{
+	Name: "pcache_invalidations_published",
+	Help: "Number of invalidations published per namespace and status",
+}, []string{"namespace", "status"})
+
+var invalidationsReceived = promauto.NewCounterVec(prometheus.CounterOpts{
+	Name: "pcache_invalidations_received",
+	Help: "Number of invalidations received from other processes per namespace",
+}, []string{"namespace"})
+
+// Invalidation evicts keys of a namespace of the PCache, or the whole namespace if no keys are given
+type Invalidation struct {
+	Namespace string   `json:"namespace"`
+	Keys      []string `json:"keys,omitempty"`
+	// Origin identifies the bus that published the invalidation, so that it is not applied twice
+	Origin string `json:"origin"`
+	// PublishedAt is the time, in unix micros, at which the invalidation was published
+	PublishedAt int64 `json:"published_at"`
+}
+
+// Bus broadcasts invalidations of the PCache to every process of the tier, so that writes
+// in one process are not masked by values cached by the others.
+type Bus struct {
+	cache  PCache
+	client redis.Client
+	origin string
+	logger *zap.Logger
+
+	mu    sync.RWMutex
+	hooks map[string][]func(key string)
+}
+
+func NewBus(cache PCache, client redis.Client, logger *zap.Logger) *Bus {
+	origin := make([]byte, 8)
+	_, _ = rand.Read(origin)
+	return &Bus{
+		cache:  cache,
+		client: client,
+		origin: hex.EncodeToString(origin),
+		logger: logger,
+		hooks:  make(map[string][]func(key string)),
+	}
+}
+
+// OnInvalidate registers a hook that is called with every key of the namespace that is invalidated,
+// and with an empty key when the whole namespace is. It is used to invalidate caches other than the PCache.
+func (b *Bus) OnInvalidate(namespace string, hook func(key string)) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.hooks[namespace] = append(b.hooks[namespace], hook)
+}
+
+// Invalidate evicts the keys of the namespace, or the whole namespace if no keys are given, in this
+// process and publishes the invalidation to the other processes of the tier. Failures to publish are
+// logged and not returned since the writes that cause invalidations have already succeeded by then;
+// the other processes catch up once their cached values expire.
+func (b *Bus) Invalidate(ctx context.Context, namespace string, keys ...string) {
+	if b == nil {
+		return
+	}
+	inv := Invalidation{
+		Namespace:   namespace,
+		Keys:        keys,
+		Origin:      b.origin,
+		PublishedAt: time.Now().UnixMicro(),
+	}
+	b.apply(inv)
+	if err := b.publish(ctx, inv); err != nil {
+		invalidationsPublished.WithLabelValues(namespace, "failure").Inc()
+		b.logger.Warn("failed to publish pcache invalidation", zap.String("namespace", namespace), zap.Error(err))
+		return
+	}
+	invalidationsPublished.WithLabelValues(namespace, "success").Inc()
+}
+
+func (b *Bus) publish(ctx context.Context, inv Invalidation) error {
+	msg, err := json.Marshal(inv)
+	if err != nil {
+		return err
+	}
+	return b.client.Publish(ctx, INVALIDATION_CHANNEL, msg)
+}
+
+// Run applies invalidations published by other processes until the context is cancelled,
+// resubscribing if the subscription fails.
+func (b *Bus) Run(ctx context.Context) {
+	for {
+		if err := b.listen(ctx); err != nil {
+			b.logger.Warn("pcache invalidation subscription failed", zap.Error(err))
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(resubscribeInterval):
+		}
+	}
+}
+
+func (b *Bus) listen(ctx context.Context) error {
+	sub, err := b.client.Subscribe(ctx, INVALIDATION_CHANNEL)
+	if err != nil {
+		return err
+	}
+	defer sub.Close()
+	// wait for the confirmation of the subscription so that errors are surfaced
+	if _, err := sub.Receive(ctx); err != nil {
+		return fmt.Errorf("failed to subscribe: %w", err)
+	}
+	msgs := sub.Channel()
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case msg, ok := <-msgs:
+			if !ok {
+				return fmt.Errorf("subscription closed")
+			}
+			var inv Invalidation
+			if err := json.Unmarshal([]byte(msg.Payload), &inv); err != nil {
+				b.logger.Warn("failed to parse pcache invalidation", zap.String("payload", msg.Payload), zap.Error(err))
+				continue
+			}
+			if inv.Origin == b.origin {
+				continue
+			}
+			b.apply(inv)
+			invalidationsReceived.WithLabelValues(inv.Namespace).Inc()
+			invalidationLag.WithLabelValues(inv.Namespace).Observe(time.Since(time.UnixMicro(inv.PublishedAt)).Seconds())
+		}
+	}
+}
+
+func (b *Bus) apply(inv Invalidation) {
+	if len(inv.Keys) == 0 {
+		b.cache.DelNamespace(inv.Namespace)
+	}
+	for _, k := range inv.Keys {
+		b.cache.Del(k)
+	}
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	for _, hook := range b.hooks[inv.Namespace] {
+		if len(inv.Keys) == 0 {
+			hook("")
+		}
+		for _, k := range inv.Keys {
+			hook(k)
+		}
+	}
+}
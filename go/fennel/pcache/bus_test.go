@@ -0,0 +1,84 @@
+package pcache
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"fennel/lib/ftypes"
+	"fennel/redis"
+	"fennel/resource"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+func TestBus(t *testing.T) {
+	mr, err := miniredis.Run()
+	require.NoError(t, err)
+	r, err := redis.MiniRedisConfig{MiniRedis: mr, Scope: resource.NewTierScope(ftypes.RealmID(1))}.Materialize()
+	require.NoError(t, err)
+	client := r.(redis.Client)
+	defer client.Close()
+
+	// two processes of the same tier, each with its own cache
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	var caches []PCache
+	var buses []*Bus
+	for i := 0; i < 2; i++ {
+		cache, err := NewPCache(1<<10, 1<<4)
+		require.NoError(t, err)
+		bus := NewBus(cache, client, zap.NewNop())
+		go bus.Run(ctx)
+		caches = append(caches, cache)
+		buses = append(buses, bus)
+	}
+	var mu sync.Mutex
+	var hooked []string
+	buses[1].OnInvalidate("ns", func(key string) {
+		mu.Lock()
+		defer mu.Unlock()
+		hooked = append(hooked, key)
+	})
+	set := func() {
+		for _, c := range caches {
+			assert.True(t, c.SetWithTTL("a", 1, 0, time.Minute, "ns"))
+			assert.True(t, c.SetWithTTL("b", 2, 0, time.Minute, "ns"))
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	found := func(c PCache, key string) bool {
+		_, ok := c.Get(key, "ns")
+		return ok
+	}
+	// wait for both buses to subscribe
+	assert.Eventually(t, func() bool {
+		channels := mr.PubSubChannels("")
+		return len(channels) == 1 && mr.PubSubNumSub(channels[0])[channels[0]] == 2
+	}, 5*time.Second, 10*time.Millisecond)
+
+	set()
+	buses[0].Invalidate(ctx, "ns", "a")
+	// applied locally right away and by the other process eventually
+	assert.False(t, found(caches[0], "a"))
+	assert.True(t, found(caches[0], "b"))
+	assert.Eventually(t, func() bool { return !found(caches[1], "a") }, 5*time.Second, 10*time.Millisecond)
+	assert.True(t, found(caches[1], "b"))
+
+	set()
+	buses[0].Invalidate(ctx, "ns")
+	assert.False(t, found(caches[0], "b"))
+	assert.Eventually(t, func() bool { return !found(caches[1], "a") && !found(caches[1], "b") }, 5*time.Second, 10*time.Millisecond)
+
+	mu.Lock()
+	defer mu.Unlock()
+	assert.Equal(t, []string{"a", ""}, hooked)
+
+	// a nil bus does nothing
+	var nilBus *Bus
+	nilBus.Invalidate(ctx, "ns")
+}
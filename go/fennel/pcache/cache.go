@@ -1,15 +1,32 @@
 package pcache
 
 import (
+	"sync"
+	"sync/atomic"
+	"time"
+
 	"github.com/prometheus/client_golang/prometheus"
 	"github.com/prometheus/client_golang/prometheus/promauto"
-	"time"
 
 	"github.com/dgraph-io/ristretto"
 )
 
 type PCache struct {
 	Cache *ristretto.Cache
+	// generation of each namespace, entries set in an older generation of their namespace are treated
+	// as missing. Key type is string, value type is *uint64.
+	generations *sync.Map
+	// loads in progress, see GetOrLoad
+	flights *flights
+}
+
+// entry is what is stored in the underlying cache for values that are set with a namespace
+type entry struct {
+	value      interface{}
+	namespace  string
+	generation uint64
+	// time after which the value is stale, zero if the value is only evicted by its TTL
+	staleAt time.Time
 }
 
 var cacheHits = promauto.NewCounterVec(
@@ -53,7 +70,9 @@ func NewPCache(maxCost int64, averageItemCost int64) (PCache, error) {
 		return PCache{}, err
 	}
 	return PCache{
-		Cache: cache,
+		Cache:       cache,
+		generations: new(sync.Map),
+		flights:     newFlights(),
 	}, nil
 }
 
@@ -62,7 +81,8 @@ func (pc *PCache) Set(key, value interface{}, cost int64) bool {
 }
 
 func (pc *PCache) SetWithTTL(key, value interface{}, cost int64, ttl time.Duration, namespace string) bool {
-	ok := pc.Cache.SetWithTTL(key, value, cost, ttl)
+	e := entry{value: value, namespace: namespace, generation: atomic.LoadUint64(pc.generation(namespace))}
+	ok := pc.Cache.SetWithTTL(key, e, cost, ttl)
 	if ok {
 		cacheSets.WithLabelValues(namespace).Inc()
 	}
@@ -71,6 +91,13 @@ func (pc *PCache) SetWithTTL(key, value interface{}, cost int64, ttl time.Durati
 
 func (pc *PCache) Get(key interface{}, namespace string) (interface{}, bool) {
 	val, ok := pc.Cache.Get(key)
+	if e, isEntry := val.(entry); ok && isEntry {
+		if pc.current(e) && !e.stale() {
+			val = e.value
+		} else {
+			val, ok = nil, false
+		}
+	}
 	if ok {
 		cacheHits.WithLabelValues(namespace).Inc()
 	} else {
@@ -82,3 +109,40 @@ func (pc *PCache) Get(key interface{}, namespace string) (interface{}, bool) {
 func (pc *PCache) GetTTL(key interface{}) (time.Duration, bool) {
 	return pc.Cache.GetTTL(key)
 }
+
+// Del evicts the key from the cache, values of the key that are being loaded by GetOrLoad are not
+// cached as they may have been loaded before the key was deleted
+func (pc *PCache) Del(key interface{}) {
+	if k, ok := key.(string); ok && pc.flights != nil {
+		pc.flights.Lock()
+		defer pc.flights.Unlock()
+		pc.flights.discard(k)
+	}
+	pc.Cache.Del(key)
+}
+
+// DelNamespace evicts every key that was set with the given namespace
+func (pc *PCache) DelNamespace(namespace string) {
+	atomic.AddUint64(pc.generation(namespace), 1)
+}
+
+// current returns whether the entry was set in the current generation of its namespace
+func (pc *PCache) current(e entry) bool {
+	return e.generation == atomic.LoadUint64(pc.generation(e.namespace))
+}
+
+func (e entry) stale() bool {
+	return !e.staleAt.IsZero() && time.Now().After(e.staleAt)
+}
+
+func (pc *PCache) generation(namespace string) *uint64 {
+	if pc.generations == nil {
+		// the cache was not created with NewPCache
+		return new(uint64)
+	}
+	if g, ok := pc.generations.Load(namespace); ok {
+		return g.(*uint64)
+	}
+	g, _ := pc.generations.LoadOrStore(namespace, new(uint64))
+	return g.(*uint64)
+}
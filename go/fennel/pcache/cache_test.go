@@ -49,3 +49,35 @@ func TestPCache_Get(t *testing.T) {
 	_, ok = cache.Get(key2, "Test")
 	assert.False(t, ok)
 }
+
+func TestPCache_Del(t *testing.T) {
+	cache, err := NewPCache(1<<10, 1<<4)
+	assert.NoError(t, err)
+
+	assert.True(t, cache.SetWithTTL("a", 1, 0, time.Minute, "ns1"))
+	assert.True(t, cache.SetWithTTL("b", 2, 0, time.Minute, "ns1"))
+	assert.True(t, cache.SetWithTTL("c", 3, 0, time.Minute, "ns2"))
+	time.Sleep(10 * time.Millisecond)
+
+	cache.Del("a")
+	_, ok := cache.Get("a", "ns1")
+	assert.False(t, ok)
+	v, ok := cache.Get("b", "ns1")
+	assert.True(t, ok)
+	assert.Equal(t, 2, v)
+
+	// only keys of the namespace are evicted
+	cache.DelNamespace("ns1")
+	_, ok = cache.Get("b", "ns1")
+	assert.False(t, ok)
+	v, ok = cache.Get("c", "ns2")
+	assert.True(t, ok)
+	assert.Equal(t, 3, v)
+
+	// keys set after the namespace was evicted are found
+	assert.True(t, cache.SetWithTTL("b", 4, 0, time.Minute, "ns1"))
+	time.Sleep(10 * time.Millisecond)
+	v, ok = cache.Get("b", "ns1")
+	assert.True(t, ok)
+	assert.Equal(t, 4, v)
+}
@@ -0,0 +1,253 @@
+package pcache
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+var cacheStale = promauto.NewCounterVec(
+	prometheus.CounterOpts{
+		Name: "pcache_stale_namespace",
+		Help: "Number of P Cache stale values served while being refreshed per namespace.",
+	},
+	[]string{"namespace"},
+)
+
+var cacheCoalesced = promauto.NewCounterVec(
+	prometheus.CounterOpts{
+		Name: "pcache_coalesced_namespace",
+		Help: "Number of P Cache misses that waited on a load of the same key already in progress per namespace.",
+	},
+	[]string{"namespace"},
+)
+
+// defaultLoadTimeout bounds loads of GetOrLoad whose options do not set a timeout
+const defaultLoadTimeout = 30 * time.Second
+
+// Loader loads the values of the keys, returning them in the same order as the keys
+type Loader func(ctx context.Context, keys []string) ([]interface{}, error)
+
+// LoadOptions configures how values loaded by GetOrLoad are cached
+type LoadOptions struct {
+	Namespace string
+	// TTL is how long a loaded value is fresh for
+	TTL time.Duration
+	// Grace is how long after the TTL a value is still served, while it is refreshed in the background
+	Grace time.Duration
+	// Negative reports values that are cached for NegativeTTL instead, e.g. values that were not found.
+	// These are not served once they expire.
+	Negative    func(v interface{}) bool
+	NegativeTTL time.Duration
+	// Cost returns the cost of caching the value, a cost of 0 is used if not set
+	Cost func(key string, v interface{}) int64
+	// Timeout bounds each load, defaultLoadTimeout is used if not set
+	Timeout time.Duration
+}
+
+// GetOrLoad returns the values of the keys, loading the ones that are not cached with load.
+// Concurrent loads of the same key are coalesced into one, and values that are stale but within
+// the grace period are returned right away while they are refreshed in the background. Loads run
+// with the values of ctx but are not cancelled with it, since other calls may be waiting on them,
+// and each call only waits on them until its own ctx is done.
+func (pc *PCache) GetOrLoad(ctx context.Context, keys []string, opts LoadOptions, load Loader) ([]interface{}, error) {
+	if pc.flights == nil {
+		// the cache was not created with NewPCache
+		return load(ctx, keys)
+	}
+	ret := make([]interface{}, len(keys))
+	var missing, stale []int
+	for i, k := range keys {
+		v, ok := pc.Cache.Get(k)
+		e, isEntry := v.(entry)
+		if !ok || !isEntry || !pc.current(e) {
+			cacheMisses.WithLabelValues(opts.Namespace).Inc()
+			missing = append(missing, i)
+			continue
+		}
+		ret[i] = e.value
+		if e.stale() {
+			cacheStale.WithLabelValues(opts.Namespace).Inc()
+			stale = append(stale, i)
+		} else {
+			cacheHits.WithLabelValues(opts.Namespace).Inc()
+		}
+	}
+	if len(missing) == 0 && len(stale) == 0 {
+		return ret, nil
+	}
+
+	// keys that are not being loaded already are loaded by this call, the rest wait on the loads in progress
+	waits := make(map[int]*flight, len(missing))
+	var loads, refreshes []string
+	var loadFlights, refreshFlights []*flight
+	pc.flights.Lock()
+	for _, i := range missing {
+		f, started := pc.flights.start(pc, opts.Namespace, keys[i])
+		if started {
+			loads = append(loads, keys[i])
+			loadFlights = append(loadFlights, f)
+		} else {
+			cacheCoalesced.WithLabelValues(opts.Namespace).Inc()
+		}
+		waits[i] = f
+	}
+	for _, i := range stale {
+		if f, started := pc.flights.start(pc, opts.Namespace, keys[i]); started {
+			refreshes = append(refreshes, keys[i])
+			refreshFlights = append(refreshFlights, f)
+		}
+	}
+	pc.flights.Unlock()
+
+	if len(refreshes) > 0 {
+		go pc.land(ctx, refreshes, refreshFlights, opts, load)
+	}
+	if len(loads) > 0 {
+		go pc.land(ctx, loads, loadFlights, opts, load)
+	}
+	for i, f := range waits {
+		select {
+		case <-f.done:
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+		if f.err != nil {
+			return nil, f.err
+		}
+		ret[i] = f.value
+	}
+	return ret, nil
+}
+
+// land runs the load of the flights, caches the loaded values and wakes up everyone waiting on them.
+// The load is not cancelled with ctx, which is the context of the call that started it, but is
+// bounded by the timeout of the options.
+func (pc *PCache) land(ctx context.Context, keys []string, fs []*flight, opts LoadOptions, load Loader) {
+	timeout := opts.Timeout
+	if timeout <= 0 {
+		timeout = defaultLoadTimeout
+	}
+	ctx, cancel := context.WithTimeout(detachedContext{ctx}, timeout)
+	defer cancel()
+	vals, err := runLoad(ctx, keys, load)
+	if err == nil && len(vals) != len(keys) {
+		err = fmt.Errorf("loader returned %d values for %d keys", len(vals), len(keys))
+	}
+	// values are cached with the lock held, so that keys that are deleted meanwhile are not cached
+	pc.flights.Lock()
+	defer pc.flights.Unlock()
+	for i, f := range fs {
+		if err != nil {
+			f.err = err
+		} else {
+			f.value = vals[i]
+			pc.set(keys[i], f, opts)
+		}
+		pc.flights.finish(opts.Namespace, keys[i], f)
+	}
+}
+
+// set caches the value loaded by the flight unless the key was deleted while loading
+func (pc *PCache) set(key string, f *flight, opts LoadOptions) {
+	if f.discarded {
+		return
+	}
+	ttl, grace := opts.TTL, opts.Grace
+	if opts.Negative != nil && opts.Negative(f.value) {
+		ttl, grace = opts.NegativeTTL, 0
+	}
+	if ttl <= 0 {
+		return
+	}
+	var cost int64
+	if opts.Cost != nil {
+		cost = opts.Cost(key, f.value)
+	}
+	// the value is cached in the generation the load started in, so that it is dropped if the
+	// namespace was invalidated while loading
+	e := entry{value: f.value, namespace: opts.Namespace, generation: f.generation, staleAt: time.Now().Add(ttl)}
+	if pc.Cache.SetWithTTL(key, e, cost, ttl+grace) {
+		cacheSets.WithLabelValues(opts.Namespace).Inc()
+	}
+}
+
+// runLoad runs the load, a panic of the load is returned as an error since the load runs in its
+// own goroutine
+func runLoad(ctx context.Context, keys []string, load Loader) (vals []interface{}, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("load of %d keys panicked: %v", len(keys), r)
+		}
+	}()
+	return load(ctx, keys)
+}
+
+// detachedContext has the values of its parent but is never cancelled
+type detachedContext struct {
+	parent context.Context
+}
+
+func (c detachedContext) Deadline() (time.Time, bool)       { return time.Time{}, false }
+func (c detachedContext) Done() <-chan struct{}             { return nil }
+func (c detachedContext) Err() error                        { return nil }
+func (c detachedContext) Value(key interface{}) interface{} { return c.parent.Value(key) }
+
+// flight is a load of a key in progress
+type flight struct {
+	done       chan struct{}
+	generation uint64
+	// discarded is set if the key was deleted while loading, the loaded value is then not cached
+	discarded bool
+	value     interface{}
+	err       error
+}
+
+type flights struct {
+	sync.Mutex
+	// inflight has the loads in progress by key and then by namespace
+	inflight map[string]map[string]*flight
+}
+
+func newFlights() *flights {
+	return &flights{inflight: make(map[string]map[string]*flight)}
+}
+
+// start returns the load of the key in progress, or starts one if there is none. It must be called
+// with the lock held.
+func (fs *flights) start(pc *PCache, namespace, key string) (*flight, bool) {
+	if f, ok := fs.inflight[key][namespace]; ok {
+		return f, false
+	}
+	f := &flight{done: make(chan struct{}), generation: atomic.LoadUint64(pc.generation(namespace))}
+	if _, ok := fs.inflight[key]; !ok {
+		fs.inflight[key] = make(map[string]*flight)
+	}
+	fs.inflight[key][namespace] = f
+	return f, true
+}
+
+// finish marks the load as done. It must be called with the lock held.
+func (fs *flights) finish(namespace, key string, f *flight) {
+	if fs.inflight[key][namespace] == f {
+		delete(fs.inflight[key], namespace)
+		if len(fs.inflight[key]) == 0 {
+			delete(fs.inflight, key)
+		}
+	}
+	close(f.done)
+}
+
+// discard stops the loads of the key in progress from caching their values, later calls start new
+// loads. It must be called with the lock held.
+func (fs *flights) discard(key string) {
+	for _, f := range fs.inflight[key] {
+		f.discarded = true
+	}
+	delete(fs.inflight, key)
+}
@@ -0,0 +1,267 @@
+package pcache
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// counter returns a loader that returns the number of times each key was loaded
+func counter() (Loader, func(key string) int64) {
+	var mu sync.Mutex
+	loads := make(map[string]int64)
+	load := func(ctx context.Context, keys []string) ([]interface{}, error) {
+		mu.Lock()
+		defer mu.Unlock()
+		ret := make([]interface{}, len(keys))
+		for i, k := range keys {
+			loads[k]++
+			ret[i] = loads[k]
+		}
+		return ret, nil
+	}
+	count := func(key string) int64 {
+		mu.Lock()
+		defer mu.Unlock()
+		return loads[key]
+	}
+	return load, count
+}
+
+func TestGetOrLoad(t *testing.T) {
+	ctx := context.Background()
+	cache, err := NewPCache(1<<10, 1<<4)
+	require.NoError(t, err)
+	opts := LoadOptions{Namespace: "test", TTL: time.Minute}
+	load, count := counter()
+
+	vals, err := cache.GetOrLoad(ctx, []string{"a", "b", "a"}, opts, load)
+	require.NoError(t, err)
+	assert.Equal(t, []interface{}{int64(1), int64(1), int64(1)}, vals)
+	assert.Equal(t, int64(1), count("a"))
+	time.Sleep(10 * time.Millisecond)
+
+	vals, err = cache.GetOrLoad(ctx, []string{"b", "c"}, opts, load)
+	require.NoError(t, err)
+	assert.Equal(t, []interface{}{int64(1), int64(1)}, vals)
+	assert.Equal(t, int64(1), count("b"))
+
+	// errors are returned and not cached
+	_, err = cache.GetOrLoad(ctx, []string{"d"}, opts, func(ctx context.Context, keys []string) ([]interface{}, error) {
+		return nil, fmt.Errorf("failed")
+	})
+	assert.Error(t, err)
+	_, err = cache.GetOrLoad(ctx, []string{"d"}, opts, func(ctx context.Context, keys []string) ([]interface{}, error) {
+		return nil, nil
+	})
+	assert.Error(t, err)
+	vals, err = cache.GetOrLoad(ctx, []string{"d"}, opts, load)
+	require.NoError(t, err)
+	assert.Equal(t, []interface{}{int64(1)}, vals)
+}
+
+func TestGetOrLoadCoalesces(t *testing.T) {
+	ctx := context.Background()
+	cache, err := NewPCache(1<<10, 1<<4)
+	require.NoError(t, err)
+	opts := LoadOptions{Namespace: "test", TTL: time.Minute}
+
+	var loads int32
+	release := make(chan struct{})
+	load := func(ctx context.Context, keys []string) ([]interface{}, error) {
+		atomic.AddInt32(&loads, 1)
+		<-release
+		return []interface{}{"v"}, nil
+	}
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			vals, err := cache.GetOrLoad(ctx, []string{"k"}, opts, load)
+			assert.NoError(t, err)
+			assert.Equal(t, []interface{}{"v"}, vals)
+		}()
+	}
+	// wait for every call to either start or join the load
+	time.Sleep(50 * time.Millisecond)
+	close(release)
+	wg.Wait()
+	assert.Equal(t, int32(1), atomic.LoadInt32(&loads))
+
+	// waiting on a load in progress respects the context
+	block := make(chan struct{})
+	defer close(block)
+	go func() {
+		_, _ = cache.GetOrLoad(ctx, []string{"slow"}, opts, func(ctx context.Context, keys []string) ([]interface{}, error) {
+			<-block
+			return []interface{}{"v"}, nil
+		})
+	}()
+	time.Sleep(10 * time.Millisecond)
+	cctx, cancel := context.WithTimeout(ctx, 10*time.Millisecond)
+	defer cancel()
+	_, err = cache.GetOrLoad(cctx, []string{"slow"}, opts, load)
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+
+	// the load is not cancelled with the call that started it, others waiting on it get its value
+	started, finish := make(chan struct{}), make(chan struct{})
+	lctx, lcancel := context.WithCancel(ctx)
+	done := make(chan error)
+	go func() {
+		_, err := cache.GetOrLoad(lctx, []string{"leader"}, opts, func(ctx context.Context, keys []string) ([]interface{}, error) {
+			close(started)
+			<-finish
+			return []interface{}{"v"}, ctx.Err()
+		})
+		done <- err
+	}()
+	<-started
+	waiter := make(chan []interface{})
+	go func() {
+		vals, err := cache.GetOrLoad(ctx, []string{"leader"}, opts, load)
+		assert.NoError(t, err)
+		waiter <- vals
+	}()
+	time.Sleep(10 * time.Millisecond)
+	lcancel()
+	assert.ErrorIs(t, <-done, context.Canceled)
+	close(finish)
+	assert.Equal(t, []interface{}{"v"}, <-waiter)
+
+	// loads are bounded by their timeout
+	_, err = cache.GetOrLoad(ctx, []string{"timeout"}, LoadOptions{Namespace: "test", TTL: time.Minute, Timeout: 10 * time.Millisecond},
+		func(ctx context.Context, keys []string) ([]interface{}, error) {
+			<-ctx.Done()
+			return nil, ctx.Err()
+		})
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+}
+
+func TestGetOrLoadStale(t *testing.T) {
+	ctx := context.Background()
+	cache, err := NewPCache(1<<10, 1<<4)
+	require.NoError(t, err)
+	opts := LoadOptions{Namespace: "test", TTL: 50 * time.Millisecond, Grace: time.Minute}
+	load, count := counter()
+
+	vals, err := cache.GetOrLoad(ctx, []string{"a"}, opts, load)
+	require.NoError(t, err)
+	assert.Equal(t, []interface{}{int64(1)}, vals)
+	time.Sleep(100 * time.Millisecond)
+
+	// the stale value is served while it is refreshed
+	vals, err = cache.GetOrLoad(ctx, []string{"a"}, opts, load)
+	require.NoError(t, err)
+	assert.Equal(t, []interface{}{int64(1)}, vals)
+	// but not by Get
+	_, ok := cache.Get("a", "test")
+	assert.False(t, ok)
+	assert.Eventually(t, func() bool {
+		vals, err := cache.GetOrLoad(ctx, []string{"a"}, opts, load)
+		return err == nil && vals[0] == int64(2)
+	}, time.Second, 10*time.Millisecond)
+	assert.Equal(t, int64(2), count("a"))
+}
+
+func TestGetOrLoadNegative(t *testing.T) {
+	ctx := context.Background()
+	cache, err := NewPCache(1<<10, 1<<4)
+	require.NoError(t, err)
+	opts := LoadOptions{
+		Namespace:   "test",
+		TTL:         time.Minute,
+		Grace:       time.Minute,
+		Negative:    func(v interface{}) bool { return v == nil },
+		NegativeTTL: 50 * time.Millisecond,
+	}
+	var missing int32 = 1
+	load := func(ctx context.Context, keys []string) ([]interface{}, error) {
+		if atomic.LoadInt32(&missing) == 1 {
+			return []interface{}{nil}, nil
+		}
+		return []interface{}{"found"}, nil
+	}
+	vals, err := cache.GetOrLoad(ctx, []string{"a"}, opts, load)
+	require.NoError(t, err)
+	assert.Equal(t, []interface{}{nil}, vals)
+	time.Sleep(10 * time.Millisecond)
+
+	atomic.StoreInt32(&missing, 0)
+	vals, err = cache.GetOrLoad(ctx, []string{"a"}, opts, load)
+	require.NoError(t, err)
+	assert.Equal(t, []interface{}{nil}, vals)
+
+	// negative values are not served after they expire
+	time.Sleep(100 * time.Millisecond)
+	vals, err = cache.GetOrLoad(ctx, []string{"a"}, opts, load)
+	require.NoError(t, err)
+	assert.Equal(t, []interface{}{"found"}, vals)
+}
+
+func TestGetOrLoadInvalidated(t *testing.T) {
+	ctx := context.Background()
+	cache, err := NewPCache(1<<10, 1<<4)
+	require.NoError(t, err)
+	opts := LoadOptions{Namespace: "test", TTL: time.Minute}
+	load, count := counter()
+
+	// the namespace is invalidated while the value is being loaded, so the value is not cached
+	_, err = cache.GetOrLoad(ctx, []string{"a"}, opts, func(ctx context.Context, keys []string) ([]interface{}, error) {
+		cache.DelNamespace("test")
+		return load(ctx, keys)
+	})
+	require.NoError(t, err)
+	time.Sleep(10 * time.Millisecond)
+	vals, err := cache.GetOrLoad(ctx, []string{"a"}, opts, load)
+	require.NoError(t, err)
+	assert.Equal(t, []interface{}{int64(2)}, vals)
+	assert.Equal(t, int64(2), count("a"))
+}
+
+func TestGetOrLoadDeleted(t *testing.T) {
+	ctx := context.Background()
+	cache, err := NewPCache(1<<10, 1<<4)
+	require.NoError(t, err)
+	opts := LoadOptions{Namespace: "test", TTL: time.Minute}
+
+	// the key is deleted, e.g. as it was written, while its value is being loaded
+	started, release := make(chan struct{}), make(chan struct{})
+	old := make(chan []interface{})
+	go func() {
+		vals, err := cache.GetOrLoad(ctx, []string{"a"}, opts, func(ctx context.Context, keys []string) ([]interface{}, error) {
+			close(started)
+			<-release
+			return []interface{}{"old"}, nil
+		})
+		assert.NoError(t, err)
+		old <- vals
+	}()
+	<-started
+	cache.Del("a")
+
+	// loads that start after the delete don't wait on the load that started before it
+	load := func(ctx context.Context, keys []string) ([]interface{}, error) {
+		return []interface{}{"new"}, nil
+	}
+	vals, err := cache.GetOrLoad(ctx, []string{"a"}, opts, load)
+	require.NoError(t, err)
+	assert.Equal(t, []interface{}{"new"}, vals)
+	time.Sleep(10 * time.Millisecond)
+
+	// the load that started before the delete completes but does not cache its value
+	close(release)
+	assert.Equal(t, []interface{}{"old"}, <-old)
+	time.Sleep(10 * time.Millisecond)
+	vals, err = cache.GetOrLoad(ctx, []string{"a"}, opts, func(ctx context.Context, keys []string) ([]interface{}, error) {
+		return []interface{}{"loaded again"}, nil
+	})
+	require.NoError(t, err)
+	assert.Equal(t, []interface{}{"new"}, vals)
+}
@@ -271,6 +271,27 @@ func (c Client) TTL(ctx context.Context, key string) (time.Duration, error) {
 	return c.client.TTL(ctx, key).Result()
 }
 
+// Publish posts the message on the channel of the tier
+func (c Client) Publish(ctx context.Context, channel string, message interface{}) error {
+	ctx, t := timer.Start(ctx, c.ID(), "redis.publish")
+	defer t.Stop()
+
+	return c.client.Publish(ctx, c.tieredKey(channel), message).Err()
+}
+
+// Subscribe subscribes to the channels of the tier. Messages are received on PubSub.Channel() and the
+// subscription should be closed by the caller. It is not supported by clients that wrap a transaction.
+func (c Client) Subscribe(ctx context.Context, channels ...string) (*redis.PubSub, error) {
+	switch client := c.client.(type) {
+	case *redis.ClusterClient:
+		return client.Subscribe(ctx, c.mTieredKey(channels)...), nil
+	case *redis.Client:
+		return client.Subscribe(ctx, c.mTieredKey(channels)...), nil
+	default:
+		return nil, fmt.Errorf("subscribe is not supported by redis client of type %T", c.client)
+	}
+}
+
 func (c Client) tieredKey(k string) string {
 	return c.PrefixedName(k)
 }
@@ -6,6 +6,7 @@ import (
 	"io"
 	"os"
 	"strings"
+	"time"
 
 	"github.com/aws/aws-sdk-go/aws"
 	"github.com/aws/aws-sdk-go/aws/awserr"
@@ -82,6 +83,39 @@ func (c Client) ListFiles(bucketName, pathPrefix, continuationToken string) ([]s
 	return files, nil
 }
 
+// Object is a file in a bucket along with the time it was last modified
+type Object struct {
+	Key          string
+	LastModified time.Time
+}
+
+// ListObjects is like ListFiles but also returns the last modified time of each of the files
+func (c Client) ListObjects(bucketName, pathPrefix string) ([]Object, error) {
+	_, t := timer.Start(context.Background(), 0, "s3client.ListObjects")
+	defer t.Stop()
+	input := s3.ListObjectsV2Input{
+		Bucket:  aws.String(bucketName),
+		Prefix:  aws.String(pathPrefix),
+		MaxKeys: aws.Int64(1000),
+	}
+	var objects []Object
+	err := c.client.ListObjectsV2Pages(&input, func(output *s3.ListObjectsV2Output, _ bool) bool {
+		for _, obj := range output.Contents {
+			objects = append(objects, Object{Key: *obj.Key, LastModified: *obj.LastModified})
+		}
+		return true
+	})
+	if err != nil {
+		if aerr, ok := err.(awserr.Error); ok {
+			if aerr.Code() == s3.ErrCodeNoSuchBucket {
+				return []Object{}, nil
+			}
+		}
+		return nil, err
+	}
+	return objects, nil
+}
+
 func (c Client) Upload(file io.Reader, path, bucketName string) error {
 	_, t := timer.Start(context.Background(), 0, "s3client.Upload")
 	defer t.Stop()
@@ -113,6 +147,22 @@ func (c Client) Exists(path string, bucketName string) (bool, error) {
 	return true, nil
 }
 
+// ETag returns the entity tag of the file, which changes whenever the file is written, or an
+// empty string if the file does not exist
+func (c Client) ETag(path string, bucketName string) (string, error) {
+	output, err := c.client.HeadObject(&s3.HeadObjectInput{
+		Bucket: aws.String(bucketName),
+		Key:    aws.String(path),
+	})
+	if err != nil {
+		if aerr, ok := err.(awserr.Error); ok && aerr.Code() == "NotFound" {
+			return "", nil
+		}
+		return "", err
+	}
+	return aws.StringValue(output.ETag), nil
+}
+
 func (c Client) Download(path, bucketName string) ([]byte, error) {
 	_, t := timer.Start(context.Background(), 0, "s3client.Download")
 	defer t.Stop()
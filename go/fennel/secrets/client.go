@@ -0,0 +1,120 @@
+package secrets
+
+import (
+	"context"
+	"errors"
+	"sync"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/secretsmanager"
+)
+
+/*
+	Client keeps credentials that the tier needs in plaintext (e.g. to connect to the database of a
+	native postgres source) out of the tier's own database. Only the name of the secret is stored
+	alongside the object that uses it.
+*/
+
+var ErrNotFound = errors.New("secret not found")
+
+type SecretsArgs struct {
+	Region string `arg:"--region,env:AWS_REGION,help:AWS region"`
+}
+
+type Client interface {
+	Get(ctx context.Context, name string) (string, error)
+	// Set creates the secret or replaces its value if it already exists
+	Set(ctx context.Context, name, secret string) error
+	// Delete deletes the secret, it is not an error if it does not exist
+	Delete(ctx context.Context, name string) error
+}
+
+type awsClient struct {
+	client *secretsmanager.SecretsManager
+}
+
+var _ Client = awsClient{}
+
+// NewClient returns a client that keeps secrets in AWS Secrets Manager
+func NewClient(args SecretsArgs) Client {
+	sess := session.Must(session.NewSession(
+		&aws.Config{
+			Region:                        aws.String(args.Region),
+			CredentialsChainVerboseErrors: aws.Bool(true),
+		},
+	))
+	return awsClient{client: secretsmanager.New(sess)}
+}
+
+func (c awsClient) Get(ctx context.Context, name string) (string, error) {
+	output, err := c.client.GetSecretValueWithContext(ctx, &secretsmanager.GetSecretValueInput{
+		SecretId: aws.String(name),
+	})
+	if isNotFound(err) {
+		return "", ErrNotFound
+	} else if err != nil {
+		return "", err
+	}
+	return aws.StringValue(output.SecretString), nil
+}
+
+func (c awsClient) Set(ctx context.Context, name, secret string) error {
+	_, err := c.client.PutSecretValueWithContext(ctx, &secretsmanager.PutSecretValueInput{
+		SecretId:     aws.String(name),
+		SecretString: aws.String(secret),
+	})
+	if !isNotFound(err) {
+		return err
+	}
+	_, err = c.client.CreateSecretWithContext(ctx, &secretsmanager.CreateSecretInput{
+		Name:         aws.String(name),
+		SecretString: aws.String(secret),
+	})
+	return err
+}
+
+func (c awsClient) Delete(ctx context.Context, name string) error {
+	_, err := c.client.DeleteSecretWithContext(ctx, &secretsmanager.DeleteSecretInput{
+		SecretId:                   aws.String(name),
+		ForceDeleteWithoutRecovery: aws.Bool(true),
+	})
+	if isNotFound(err) {
+		return nil
+	}
+	return err
+}
+
+func isNotFound(err error) bool {
+	var aerr awserr.Error
+	return errors.As(err, &aerr) && aerr.Code() == secretsmanager.ErrCodeResourceNotFoundException
+}
+
+// memClient keeps secrets in memory, it is used by tests and local tiers
+type memClient struct {
+	secrets *sync.Map
+}
+
+var _ Client = memClient{}
+
+func NewMemClient() Client {
+	return memClient{secrets: &sync.Map{}}
+}
+
+func (m memClient) Get(_ context.Context, name string) (string, error) {
+	if v, ok := m.secrets.Load(name); ok {
+		return v.(string), nil
+	}
+	return "", ErrNotFound
+}
+
+func (m memClient) Set(_ context.Context, name, secret string) error {
+	m.secrets.Store(name, secret)
+	return nil
+}
+
+func (m memClient) Delete(_ context.Context, name string) error {
+	m.secrets.Delete(name)
+	return nil
+}
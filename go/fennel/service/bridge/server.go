@@ -495,7 +495,7 @@ func (s *server) Profiles(c *gin.Context) {
 	}
 
 	tier, _ := ginL.CurrentTier(c)
-	profiles, err := profileC.Profiles(c.Request.Context(), tier, form.Otype, form.Oid, form.Pagination)
+	profiles, err := profileC.Profiles(c.Request.Context(), s.mothership.Secrets, tier, form.Otype, form.Oid, form.Pagination)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{
 			"error": "Fail to read profiles, please try again later.",
@@ -530,7 +530,7 @@ func (s *server) Actions(c *gin.Context) {
 	}
 
 	tier, _ := ginL.CurrentTier(c)
-	actions, err := actionC.Actions(c.Request.Context(), tier, form.ActionType, form.ActorType, form.ActorID, form.TargetType, form.TargetID)
+	actions, err := actionC.Actions(c.Request.Context(), s.mothership.Secrets, tier, form.ActionType, form.ActorType, form.ActorID, form.TargetType, form.TargetID)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{
 			"error": "Fail to read actions, please try again later.",
@@ -557,7 +557,7 @@ func (s *server) Actions(c *gin.Context) {
 
 func (s *server) StoredQueries(c *gin.Context) {
 	tier, _ := ginL.CurrentTier(c)
-	queries, err := queryC.ListQueries(c.Request.Context(), tier)
+	queries, err := queryC.ListQueries(c.Request.Context(), s.mothership.Secrets, tier)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{
 			"error": "Fail to read stored queries, please try again later.",
@@ -573,7 +573,7 @@ func (s *server) StoredQueries(c *gin.Context) {
 
 func (s *server) Features(c *gin.Context) {
 	tier, _ := ginL.CurrentTier(c)
-	features, err := featureC.Features(c.Request.Context(), tier)
+	features, err := featureC.Features(c.Request.Context(), s.mothership.Secrets, tier)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{
 			"error": "Fail to read actions, please try again later.",
@@ -12,6 +12,7 @@ import (
 	"os"
 	"os/exec"
 	"os/signal"
+	"sync/atomic"
 	"syscall"
 	"time"
 
@@ -35,6 +36,7 @@ import (
 	"fennel/lib/action"
 	libaggregate "fennel/lib/aggregate"
 	"fennel/lib/ftypes"
+	"fennel/lib/offline"
 	"fennel/lib/phaser"
 	"fennel/lib/profile"
 	profilelib "fennel/lib/profile"
@@ -58,6 +60,10 @@ import (
 
 const INT_REST_VERSION = "/internal/v1"
 const CONNECTOR_CONSUMERS = 4
+const NATIVE_CONNECTOR_BATCH_SIZE = 10000
+const NATIVE_CONNECTOR_POLL_INTERVAL = 30 * time.Second
+const OFFLINE_AGG_POLL_INTERVAL = time.Minute
+const OFFLINE_AGG_LOCK_TTL = 24 * time.Hour
 
 var backlog_stats = promauto.NewGaugeVec(prometheus.GaugeOpts{
 	Name: "aggregator_backlog",
@@ -433,6 +439,39 @@ func processConnector(tr tier.Tier, conn data_integration.Connector, stopCh <-ch
 	return nil
 }
 
+// processNativeConnector runs a connector that is not run by airbyte, reading batches from its source
+// until it catches up and then polling the source for new data. The connector is reloaded before
+// every batch, so updates of the connector apply from the next batch on.
+func processNativeConnector(tr tier.Tier, conn data_integration.Connector, stopCh <-chan struct{}) {
+	go func(tr tier.Tier, conn data_integration.Connector, stopCh <-chan struct{}) {
+		ctx := context.Background()
+		runner := connector.NewNativeRunner(tr, conn.Name)
+		defer runner.Close()
+		metricLabelName := fmt.Sprintf("%s:%s", conn.Name, conn.Destination)
+		for run := 0; true; run++ {
+			n, err := runner.Run(ctx, NATIVE_CONNECTOR_BATCH_SIZE)
+			if err != nil {
+				tr.Logger.Error("Error while processing native connector", zap.String("name", conn.Name), zap.Error(err))
+			} else if n > 0 {
+				tr.Logger.Info("Processed native connector", zap.String("name", conn.Name), zap.Int("run", run), zap.Int("values", n))
+				incomingStreamlogs.WithLabelValues(metricLabelName).Add(float64(n))
+				select {
+				case <-stopCh:
+					return
+				default:
+					continue
+				}
+			}
+			// wait for new data in the source or after errors
+			select {
+			case <-stopCh:
+				return
+			case <-time.After(NATIVE_CONNECTOR_POLL_INTERVAL):
+			}
+		}
+	}(tr, conn, stopCh)
+}
+
 func startUsageCountersDBInsertion(tr tier.Tier) error {
 	consumer, err := tr.NewKafkaConsumer(kafka.ConsumerConfig{
 		Scope:        resource.NewTierScope(tr.ID),
@@ -506,6 +545,25 @@ func startProfileDBInsertion(tr tier.Tier) error {
 	return nil
 }
 
+// startProfileJobProcessing runs the bulk imports & exports of profiles that are requested through
+// the api server, one at a time
+func startProfileJobProcessing(tr tier.Tier) {
+	go func(tr tier.Tier) {
+		ticker := time.NewTicker(time.Second * 10)
+		for ; true; <-ticker.C {
+			for {
+				ran, err := profile2.RunNextJob(context.Background(), tr)
+				if err != nil {
+					tr.Logger.Error("error while running profile job", zap.Error(err))
+				}
+				if err != nil || !ran {
+					break
+				}
+			}
+		}
+	}(tr)
+}
+
 func startAggregateProcessing(tr tier.Tier) error {
 	go func(tr tier.Tier) {
 		// Map from aggregate name to channel to stop the aggregate processing.
@@ -545,6 +603,76 @@ func startAggregateProcessing(tr tier.Tier) error {
 	return nil
 }
 
+// startOfflineAggregateProcessing runs offline aggregates on their cron schedule when they are computed by
+// the native engine. Replicas race for a lock on each scheduled run so that only one of them runs it, and a
+// run is skipped if the previous run of the aggregate is still in progress.
+func startOfflineAggregateProcessing(tr tier.Tier) error {
+	go func(tr tier.Tier) {
+		nextRuns := make(map[ftypes.AggName]time.Time)
+		running := make(map[ftypes.AggName]*int32)
+		ticker := time.NewTicker(OFFLINE_AGG_POLL_INTERVAL)
+		for ; true; <-ticker.C {
+			aggs, err := aggregate.RetrieveActive(context.Background(), tr)
+			if err != nil {
+				tr.Logger.Error("error while retrieving active aggregates", zap.Error(err))
+				continue
+			}
+			now := tr.Clock.Now()
+			aggNames := make(map[ftypes.AggName]struct{}, len(aggs))
+			for _, agg := range aggs {
+				if !agg.IsOffline() {
+					continue
+				}
+				aggNames[agg.Name] = struct{}{}
+				cron, err := offline.ParseCron(agg.Options.CronSchedule)
+				if err != nil {
+					tr.Logger.Error("Invalid cron schedule of offline aggregate", zap.String("name", string(agg.Name)), zap.Error(err))
+					continue
+				}
+				next, ok := nextRuns[agg.Name]
+				if !ok {
+					nextRuns[agg.Name] = cron.Next(now)
+					running[agg.Name] = new(int32)
+					continue
+				}
+				if now.Before(next) {
+					continue
+				}
+				nextRuns[agg.Name] = cron.Next(now)
+				lock := fmt.Sprintf("offline_agg_run:%s:%d", agg.Name, next.Unix())
+				acquired, err := tr.Redis.SetNX(context.Background(), lock, 1, OFFLINE_AGG_LOCK_TTL)
+				if err != nil {
+					tr.Logger.Error("Could not acquire lock of offline aggregate run", zap.String("name", string(agg.Name)), zap.Error(err))
+					continue
+				}
+				if !acquired {
+					continue
+				}
+				if !atomic.CompareAndSwapInt32(running[agg.Name], 0, 1) {
+					tr.Logger.Warn("Skipping run of offline aggregate as the previous run is in progress", zap.String("name", string(agg.Name)))
+					continue
+				}
+				go func(agg libaggregate.Aggregate, running *int32) {
+					defer atomic.StoreInt32(running, 0)
+					tr.Logger.Info("Running offline aggregate", zap.String("name", string(agg.Name)))
+					if err := aggregate.RunOffline(context.Background(), tr, agg); err != nil {
+						aggregate_errors.WithLabelValues(string(agg.Name)).Inc()
+						tr.Logger.Error("Error while running offline aggregate", zap.String("name", string(agg.Name)), zap.Error(err))
+					}
+				}(agg, running[agg.Name])
+			}
+			// Forget the schedule of aggregates that are no longer active.
+			for a := range nextRuns {
+				if _, ok := aggNames[a]; !ok {
+					delete(nextRuns, a)
+					delete(running, a)
+				}
+			}
+		}
+	}(tr)
+	return nil
+}
+
 func startPhaserProcessing(tr tier.Tier) error {
 	go func(tr tier.Tier) {
 		processedPhasers := make(map[string]chan<- struct{})
@@ -594,6 +722,12 @@ func startConnectorProcessing(tr tier.Tier) error {
 				if _, ok := processedConnectors[conn.Name]; !ok {
 					log.Printf("Retrieved a new connector: %s", conn.Name)
 					ch := make(chan struct{})
+					if conn.ConnId == "" {
+						// native connectors are not run by airbyte, countaggr reads their source itself
+						processNativeConnector(tr, conn, ch)
+						processedConnectors[conn.Name] = ch
+						continue
+					}
 
 					// streamlog from which each of the connector reads data has multiple partitions, to improve
 					// the throughput, we run multiple consumers in the same consumer group.
@@ -669,6 +803,8 @@ func main() {
 		panic(err)
 	}
 
+	startProfileJobProcessing(tr)
+
 	if err = startPhaserProcessing(tr); err != nil {
 		panic(err)
 	}
@@ -681,6 +817,12 @@ func main() {
 		panic(err)
 	}
 
+	if tr.Args.IsNative() {
+		if err = startOfflineAggregateProcessing(tr); err != nil {
+			panic(err)
+		}
+	}
+
 	// Install python packages.
 	err = installPythonPackages()
 	if err != nil {
@@ -0,0 +1,282 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"fennel/lib/apikey"
+	apikeymodel "fennel/model/apikey"
+	"fennel/secrets"
+	"fennel/tier"
+
+	"github.com/gorilla/mux"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+// how often the cached api keys are reloaded from the db, this bounds how long a revoked key keeps working
+const apiKeyRefreshInterval = 30 * time.Second
+
+// unknown keys trigger a reload at most this often, so a newly created mothership key is accepted
+// without waiting for the next refresh
+const apiKeyMissReloadInterval = 5 * time.Second
+
+var authFailures = promauto.NewCounterVec(
+	prometheus.CounterOpts{
+		Name: "http_auth_failures_total",
+		Help: "Total number of requests rejected because of a missing, invalid or insufficiently scoped api key.",
+	},
+	[]string{"path", "reason"},
+)
+
+// routeScopes is the scope required by each route, routes that are not listed require the admin scope.
+// Routes whose scope depends on the method are keyed by "<method> <path>".
+var routeScopes = map[string]apikey.Scope{
+	"/log":                                   apikey.SCOPE_LOG,
+	"/log_multi":                             apikey.SCOPE_LOG,
+	"/set":                                   apikey.SCOPE_LOG,
+	"/set_profiles":                          apikey.SCOPE_LOG,
+	"POST " + INT_REST_VERSION + "/profiles": apikey.SCOPE_LOG,
+	INT_REST_VERSION + "/log":                apikey.SCOPE_LOG,
+	EXT_REST_VERSION + "/actions":            apikey.SCOPE_LOG,
+	EXT_REST_VERSION + "/profiles":           apikey.SCOPE_LOG,
+
+	"/fetch":                                apikey.SCOPE_QUERY,
+	"/get":                                  apikey.SCOPE_QUERY,
+	"/get_multi":                            apikey.SCOPE_QUERY,
+	"/query":                                apikey.SCOPE_QUERY,
+	"/run_query":                            apikey.SCOPE_QUERY,
+	"/queries":                              apikey.SCOPE_QUERY,
+	"/get_operators":                        apikey.SCOPE_QUERY,
+	"/schemas":                              apikey.SCOPE_QUERY,
+	"/retrieve_aggregate":                   apikey.SCOPE_QUERY,
+	"/aggregate_value":                      apikey.SCOPE_QUERY,
+	"/batch_aggregate_value":                apikey.SCOPE_QUERY,
+	"GET " + INT_REST_VERSION + "/profiles": apikey.SCOPE_QUERY,
+	"GET " + INT_REST_VERSION + "/profiles/history": apikey.SCOPE_QUERY,
+	"GET " + INT_REST_VERSION + "/profiles/changes": apikey.SCOPE_QUERY,
+	"GET " + INT_REST_VERSION + "/aggregate":        apikey.SCOPE_QUERY,
+	"GET " + INT_REST_VERSION + "/schema":           apikey.SCOPE_QUERY,
+	INT_REST_VERSION + "/query_profiles":            apikey.SCOPE_QUERY,
+	INT_REST_VERSION + "/query":                     apikey.SCOPE_QUERY,
+	INT_REST_VERSION + "/aggregate/compute":         apikey.SCOPE_QUERY,
+	INT_REST_VERSION + "/operators":                 apikey.SCOPE_QUERY,
+	INT_REST_VERSION + "/functions":                 apikey.SCOPE_QUERY,
+	EXT_REST_VERSION + "/query":                     apikey.SCOPE_QUERY,
+	EXT_REST_VERSION + "/usage_counters":            apikey.SCOPE_QUERY,
+}
+
+func routeScope(path, method string) apikey.Scope {
+	if scope, ok := routeScopes[method+" "+path]; ok {
+		return scope
+	}
+	if scope, ok := routeScopes[path]; ok {
+		return scope
+	}
+	return apikey.SCOPE_ADMIN
+}
+
+// keySet is the locally cached set of api keys of the tier keyed by their id. It is reloaded from
+// the db when it is older than apiKeyRefreshInterval, requests keep using the cached keys while
+// they are being reloaded.
+type keySet struct {
+	mu        sync.RWMutex
+	keys      map[string]apikey.Key
+	loadedAt  time.Time
+	reloading sync.Mutex
+}
+
+func newKeySet() *keySet {
+	return &keySet{}
+}
+
+func (ks *keySet) get(ctx context.Context, tr tier.Tier) (map[string]apikey.Key, error) {
+	ks.mu.RLock()
+	keys, loadedAt := ks.keys, ks.loadedAt
+	ks.mu.RUnlock()
+	if keys != nil && tr.Clock.Now().Sub(loadedAt) < apiKeyRefreshInterval {
+		return keys, nil
+	}
+	if keys == nil {
+		// nothing to fall back to, wait for the keys to be loaded
+		ks.reloading.Lock()
+		defer ks.reloading.Unlock()
+		ks.mu.RLock()
+		keys = ks.keys
+		ks.mu.RUnlock()
+		if keys != nil {
+			return keys, nil
+		}
+		return ks.reload(ctx, tr)
+	}
+	if ks.reloading.TryLock() {
+		defer ks.reloading.Unlock()
+		if fresh, err := ks.reload(ctx, tr); err != nil {
+			log.Printf("failed to reload api keys, using cached keys: %v", err)
+		} else {
+			keys = fresh
+		}
+	}
+	return keys, nil
+}
+
+// refresh reloads the keys when the key with the given id is not cached, as long as they were not
+// reloaded within apiKeyMissReloadInterval
+func (ks *keySet) refresh(ctx context.Context, tr tier.Tier, keys map[string]apikey.Key) map[string]apikey.Key {
+	ks.mu.RLock()
+	loadedAt := ks.loadedAt
+	ks.mu.RUnlock()
+	if tr.Clock.Now().Sub(loadedAt) < apiKeyMissReloadInterval || !ks.reloading.TryLock() {
+		return keys
+	}
+	defer ks.reloading.Unlock()
+	fresh, err := ks.reload(ctx, tr)
+	if err != nil {
+		log.Printf("failed to reload api keys, using cached keys: %v", err)
+		return keys
+	}
+	return fresh
+}
+
+func (ks *keySet) reload(ctx context.Context, tr tier.Tier) (map[string]apikey.Key, error) {
+	all, err := apikeymodel.RetrieveAll(ctx, tr)
+	if err != nil {
+		return nil, err
+	}
+	keys := make(map[string]apikey.Key, len(all)+1)
+	for _, k := range all {
+		keys[k.ID] = k
+	}
+	// the key of the mothership is not synced to the db but read from the secret it is created in
+	secret, err := tr.SecretsClient.Get(ctx, apikey.MothershipKeySecret(uint64(tr.ID)))
+	switch {
+	case errors.Is(err, secrets.ErrNotFound):
+	case err != nil:
+		return nil, fmt.Errorf("failed to get the api key of the mothership: %w", err)
+	default:
+		id, err := apikey.ParseID(secret)
+		if err != nil {
+			return nil, fmt.Errorf("invalid api key of the mothership: %w", err)
+		}
+		keys[id] = apikey.Key{ID: id, Hash: apikey.Hash(secret), Scopes: string(apikey.SCOPE_ADMIN)}
+	}
+	ks.mu.Lock()
+	ks.keys, ks.loadedAt = keys, tr.Clock.Now()
+	ks.mu.Unlock()
+	return keys, nil
+}
+
+// authenticate is a middleware that requires requests to have an api key, as a bearer token in
+// the Authorization header, that grants the scope of the route. Requests are rejected until keys
+// are issued for the tier, unless authentication is disabled in the args of the tier.
+func (s server) authenticate(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		path, _ := mux.CurrentRoute(req).GetPathTemplate()
+		token := strings.TrimSpace(strings.TrimPrefix(req.Header.Get("Authorization"), "Bearer "))
+		switch keyID, code, err := s.authorize(req.Context(), path, token, routeScope(path, req.Method)); code {
+		case http.StatusOK:
+			next.ServeHTTP(w, req.WithContext(withKeyID(req.Context(), keyID)))
+		case http.StatusUnauthorized:
+			handleUnauthorized(w, err)
+		case http.StatusForbidden:
+			http.Error(w, err.Error(), http.StatusForbidden)
+		default:
+			handleServiceUnavailable(w, "failed to load api keys: ", err)
+		}
+	})
+}
+
+// authorize checks that the api key in plain text grants the scope and returns the id of the key
+// along with the http status code of the outcome and the reason the key was rejected. The id is
+// empty when authentication is disabled.
+func (s server) authorize(ctx context.Context, path, token string, scope apikey.Scope) (string, int, error) {
+	if s.tier.Args.DisableAuth {
+		return "", http.StatusOK, nil
+	}
+	keys, err := s.keys.get(ctx, s.tier)
+	if err != nil {
+		return "", http.StatusServiceUnavailable, err
+	}
+	if len(token) == 0 {
+		authFailures.WithLabelValues(path, "missing").Inc()
+		return "", http.StatusUnauthorized, fmt.Errorf("api key is required")
+	}
+	id, err := apikey.ParseID(token)
+	if err != nil {
+		authFailures.WithLabelValues(path, "malformed").Inc()
+		return "", http.StatusUnauthorized, err
+	}
+	key, ok := keys[id]
+	if !ok {
+		key, ok = s.keys.refresh(ctx, s.tier, keys)[id]
+	}
+	if !ok || !key.Matches(token) || key.Expired(s.tier.Clock.Now()) {
+		authFailures.WithLabelValues(path, "invalid").Inc()
+		return "", http.StatusUnauthorized, fmt.Errorf("invalid api key")
+	}
+	if !key.Allows(scope) {
+		authFailures.WithLabelValues(path, "scope").Inc()
+		return "", http.StatusForbidden, fmt.Errorf("api key does not have the '%s' scope", scope)
+	}
+	return id, http.StatusOK, nil
+}
+
+// SyncApiKeys replaces the api keys of the tier, it is called by the mothership whenever keys are
+// issued, rotated or revoked
+func (s server) SyncApiKeys(w http.ResponseWriter, req *http.Request) {
+	data, err := readRequest(req)
+	if err != nil {
+		handleBadRequest(w, "", err)
+		return
+	}
+	var keys []apikey.Key
+	if err := json.Unmarshal(data, &keys); err != nil {
+		handleBadRequest(w, "invalid request: ", err)
+		return
+	}
+	for _, k := range keys {
+		if len(k.ID) == 0 || len(k.Hash) != 64 {
+			handleBadRequest(w, "invalid request: ", fmt.Errorf("malformed api key '%s'", k.ID))
+			return
+		}
+		if _, err := apikey.ParseScopes(k.Scopes); err != nil {
+			handleBadRequest(w, "invalid request: ", err)
+			return
+		}
+	}
+	if err = apikeymodel.ReplaceAll(req.Context(), s.tier, keys); err != nil {
+		handleInternalServerError(w, "", err)
+		return
+	}
+	if _, err = s.keys.reload(req.Context(), s.tier); err != nil {
+		handleInternalServerError(w, "", err)
+		return
+	}
+	handleSuccessfulRequest(w)
+}
+
+type keyIDContextKey struct{}
+
+// withKeyID returns a context that carries the id of the api key the request was authenticated with
+func withKeyID(ctx context.Context, keyID string) context.Context {
+	return context.WithValue(ctx, keyIDContextKey{}, keyID)
+}
+
+// authenticatedKeyID returns the id of the api key the request was authenticated with, it is empty
+// if authentication is disabled
+func authenticatedKeyID(ctx context.Context) string {
+	keyID, _ := ctx.Value(keyIDContextKey{}).(string)
+	return keyID
+}
+
+func handleUnauthorized(w http.ResponseWriter, err error) {
+	w.Header().Set("WWW-Authenticate", "Bearer")
+	http.Error(w, err.Error(), http.StatusUnauthorized)
+}
@@ -0,0 +1,56 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"fennel/lib/apikey"
+	"fennel/secrets"
+	"fennel/tier"
+
+	"github.com/raulk/clock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRouteScope(t *testing.T) {
+	assert.Equal(t, apikey.SCOPE_LOG, routeScope(EXT_REST_VERSION+"/actions", "POST"))
+	assert.Equal(t, apikey.SCOPE_QUERY, routeScope("/query", "POST"))
+	assert.Equal(t, apikey.SCOPE_LOG, routeScope(INT_REST_VERSION+"/profiles", "POST"))
+	assert.Equal(t, apikey.SCOPE_QUERY, routeScope(INT_REST_VERSION+"/profiles", "GET"))
+	assert.Equal(t, apikey.SCOPE_QUERY, routeScope(INT_REST_VERSION+"/aggregate", "GET"))
+	// changing aggregates and models requires the admin scope
+	assert.Equal(t, apikey.SCOPE_ADMIN, routeScope(INT_REST_VERSION+"/aggregate", "DELETE"))
+	assert.Equal(t, apikey.SCOPE_ADMIN, routeScope(INT_REST_VERSION+"/model", "POST"))
+	assert.Equal(t, apikey.SCOPE_ADMIN, routeScope(INT_REST_VERSION+"/api_keys", "POST"))
+}
+
+func TestAuthorize(t *testing.T) {
+	ctx := context.Background()
+	clk := clock.NewMock()
+	tr := tier.Tier{ID: 1, Clock: clk, SecretsClient: secrets.NewMemClient()}
+	s := server{tier: tr, keys: &keySet{keys: map[string]apikey.Key{}, loadedAt: clk.Now()}}
+	key, id, hash, err := apikey.Generate()
+	require.NoError(t, err)
+
+	// tiers without keys reject every request
+	_, code, err := s.authorize(ctx, "/query", "", apikey.SCOPE_QUERY)
+	assert.Equal(t, http.StatusUnauthorized, code)
+	assert.Error(t, err)
+	_, code, _ = s.authorize(ctx, "/query", key, apikey.SCOPE_QUERY)
+	assert.Equal(t, http.StatusUnauthorized, code)
+
+	s.keys.keys[id] = apikey.Key{ID: id, Hash: hash, Scopes: string(apikey.SCOPE_LOG)}
+	keyID, code, _ := s.authorize(ctx, "/log", key, apikey.SCOPE_LOG)
+	assert.Equal(t, http.StatusOK, code)
+	assert.Equal(t, id, keyID)
+	_, code, _ = s.authorize(ctx, "/query", key, apikey.SCOPE_QUERY)
+	assert.Equal(t, http.StatusForbidden, code)
+
+	// unless authentication is disabled
+	s.keys.keys = map[string]apikey.Key{}
+	s.tier.Args.DisableAuth = true
+	_, code, _ = s.authorize(ctx, "/query", "", apikey.SCOPE_QUERY)
+	assert.Equal(t, http.StatusOK, code)
+}
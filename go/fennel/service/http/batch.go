@@ -0,0 +1,149 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	schema2 "fennel/controller/schema"
+	"fennel/lib/ingest"
+
+	"go.uber.org/zap"
+)
+
+// idempotencyTTL is how long the result of a batch is kept for retries with the same idempotency key
+const idempotencyTTL = 24 * time.Hour
+
+// idempotencyPending is stored for an idempotency key while its batch is being logged
+const idempotencyPending = "pending"
+
+// idempotencyPendingTTL is how long an idempotency key is held while its batch is being logged, so
+// that the key is released if the replica dies before storing the result of the batch
+const idempotencyPendingTTL = 5 * time.Minute
+
+// storedBatch is the response to a batch that is stored under its idempotency key
+type storedBatch struct {
+	StatusCode int                `json:"status_code"`
+	Result     ingest.BatchResult `json:"result"`
+}
+
+func batchOptions(req *http.Request) (ingest.BatchOptions, error) {
+	opts := ingest.BatchOptions{IdempotencyKey: req.Header.Get(ingest.IdempotencyKeyHeader)}
+	if atomic := req.URL.Query().Get(ingest.AtomicParam); len(atomic) > 0 {
+		var err error
+		if opts.Atomic, err = strconv.ParseBool(atomic); err != nil {
+			return opts, fmt.Errorf("invalid %s: '%s'", ingest.AtomicParam, atomic)
+		}
+	}
+	return opts, opts.Validate()
+}
+
+func idempotencyRedisKey(endpoint, key string) string {
+	return fmt.Sprintf("batch_idempotency:%s:%s", endpoint, key)
+}
+
+// logBatch logs a batch with logFn and writes its result. Batches of atomic requests
+// with invalid records are rejected with a bad request. If the request has an idempotency key, the
+// response is stored and returned as is to later requests with the same key.
+func (m server) logBatch(w http.ResponseWriter, req *http.Request, endpoint string, logFn func(context.Context, ingest.BatchOptions) (ingest.BatchResult, error)) {
+	opts, err := batchOptions(req)
+	if err != nil {
+		handleBadRequest(w, "invalid request: ", err)
+		return
+	}
+	ctx := req.Context()
+	var key string
+	if len(opts.IdempotencyKey) > 0 {
+		key = idempotencyRedisKey(endpoint, opts.IdempotencyKey)
+		ok, err := m.tier.Redis.SetNX(ctx, key, idempotencyPending, idempotencyPendingTTL)
+		if err != nil {
+			// the batch is logged without the idempotency key rather than failing the request
+			m.tier.Logger.Warn("failed to check idempotency key, logging batch without it", zap.String("key", key), zap.Error(err))
+			key = ""
+		} else if !ok {
+			m.replayBatch(w, ctx, key)
+			return
+		}
+	}
+	result, err := logFn(ctx, opts)
+	if err != nil {
+		if len(key) > 0 {
+			// the batch can be retried with the same key
+			if derr := m.tier.Redis.Del(ctx, key); derr != nil {
+				m.tier.Logger.Warn("failed to release idempotency key", zap.String("key", key), zap.Error(derr))
+			}
+		}
+		handleInternalServerError(w, "", err)
+		return
+	}
+	status := http.StatusOK
+	if !result.Committed {
+		status = http.StatusBadRequest
+	}
+	if len(key) > 0 {
+		// the result replaces the pending marker and is kept for the full ttl
+		ser, err := json.Marshal(storedBatch{StatusCode: status, Result: result})
+		if err == nil {
+			err = m.tier.Redis.Set(ctx, key, string(ser), idempotencyTTL)
+		}
+		if err != nil {
+			m.tier.Logger.Warn("failed to store result of batch", zap.String("key", key), zap.Error(err))
+		}
+	}
+	writeJSONStatus(w, status, result)
+}
+
+// replayBatch writes the stored response of the batch with the idempotency key
+func (m server) replayBatch(w http.ResponseWriter, ctx context.Context, key string) {
+	v, err := m.tier.Redis.Get(ctx, key)
+	if err != nil {
+		handleInternalServerError(w, "failed to get result of batch: ", err)
+		return
+	}
+	s, _ := v.(string)
+	if s == idempotencyPending {
+		http.Error(w, "a batch with the same idempotency key is being logged", http.StatusConflict)
+		return
+	}
+	var stored storedBatch
+	if err = json.Unmarshal([]byte(s), &stored); err != nil {
+		handleInternalServerError(w, "failed to parse result of batch: ", err)
+		return
+	}
+	stored.Result.Replayed = true
+	writeJSONStatus(w, stored.StatusCode, stored.Result)
+}
+
+func writeJSONStatus(w http.ResponseWriter, status int, v interface{}) {
+	ser, err := json.Marshal(v)
+	if err != nil {
+		handleInternalServerError(w, "", err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_, _ = w.Write(ser)
+}
+
+// setInvalidRecords marks the records that did not satisfy their schema as invalid and returns which
+// records are valid, errs are the errors of the records at ids in the batch
+func setInvalidRecords(result *ingest.BatchResult, errs []error, ids []int) []bool {
+	valid := make([]bool, len(errs))
+	for j, err := range errs {
+		if valid[j] = err == nil; valid[j] {
+			continue
+		}
+		var invalid schema2.InvalidRecordError
+		if errors.As(err, &invalid) {
+			// errors are indexed by the position of the record in the batch
+			invalid.Index = ids[j]
+			err = invalid
+		}
+		result.SetInvalid(ids[j], err)
+	}
+	return valid
+}
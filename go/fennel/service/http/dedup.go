@@ -0,0 +1,53 @@
+package main
+
+import (
+	"context"
+	"time"
+
+	actionlib "fennel/lib/action"
+	"fennel/lib/dedup"
+	"fennel/lib/ftypes"
+	"fennel/tier"
+
+	"go.uber.org/zap"
+)
+
+// defaultDedupWindow is used when the dedup windows are not configured or are invalid
+const defaultDedupWindow = 6 * time.Hour
+
+func newDeduper(tr *tier.Tier) *dedup.Deduper {
+	args := tr.Args.DedupArgs
+	if args.DedupWindow <= 0 {
+		args.DedupWindow = defaultDedupWindow
+	}
+	windows, err := dedup.ParseWindows(args.DedupWindow, args.DedupWindows)
+	if err != nil {
+		tr.Logger.Error("invalid dedup windows, using the default window for all action types", zap.Error(err))
+		windows, _ = dedup.ParseWindows(args.DedupWindow, "")
+	}
+	return dedup.NewDeduper(dedup.NewRedisStore(tr.Redis), windows, args.DedupFilterCapacity, tr.Logger, tr.Clock.Now)
+}
+
+// dedupActions returns the actions that were not logged before with the same dedup key, actions
+// with an empty dedup key are never considered duplicates. Actions that could not be checked
+// against other replicas, and may be duplicates, are kept and counted. It also returns the dedup
+// status of each of the actions.
+func (m server) dedupActions(ctx context.Context, path string, actions []actionlib.Action, dedupKeys []string) ([]actionlib.Action, []dedup.Status) {
+	actionTypes := make([]ftypes.ActionType, len(actions))
+	for i, a := range actions {
+		actionTypes[i] = a.ActionType
+	}
+	statuses := m.deduper.Check(ctx, dedupKeys, actionTypes)
+	batch := make([]actionlib.Action, 0, len(actions))
+	for i, a := range actions {
+		switch statuses[i] {
+		case dedup.Duplicate:
+			totalDedupedActions.WithLabelValues(path, string(a.ActionType)).Inc()
+			continue
+		case dedup.PossibleDuplicate:
+			totalPossibleDuplicateActions.WithLabelValues(path, string(a.ActionType)).Inc()
+		}
+		batch = append(batch, a)
+	}
+	return batch, statuses
+}
@@ -0,0 +1,19 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGetActionsFromRest_DedupKey(t *testing.T) {
+	data := []byte(`[
+		{"actorId": 1, "actorType": "user", "targetId": 2, "targetType": "video", "actionType": "view", "requestId": 3, "dedupKey": "k1"},
+		{"actorId": 1, "actorType": "user", "targetId": 2, "targetType": "video", "actionType": "view", "requestId": 3}
+	]`)
+	actions, dedupKeys, err := GetActionsFromRest(data)
+	require.NoError(t, err)
+	assert.Len(t, actions, 2)
+	assert.Equal(t, []string{"k1", ""}, dedupKeys)
+}
@@ -0,0 +1,350 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"fennel/controller/action"
+	aggregate2 "fennel/controller/aggregate"
+	profile2 "fennel/controller/profile"
+	query2 "fennel/controller/query"
+	schema2 "fennel/controller/schema"
+	"fennel/engine"
+	"fennel/engine/ast"
+	"fennel/engine/interpreter/bootarg"
+	actionlib "fennel/lib/action"
+	"fennel/lib/aggregate"
+	"fennel/lib/apikey"
+	"fennel/lib/budget"
+	"fennel/lib/ftypes"
+	profilelib "fennel/lib/profile"
+	"fennel/lib/rpc"
+	"fennel/lib/timer"
+	usagelib "fennel/lib/usage"
+	"fennel/lib/value"
+
+	"github.com/Unleash/unleash-client-go/v3"
+	grpc_middleware "github.com/grpc-ecosystem/go-grpc-middleware"
+	grpc_prometheus "github.com/grpc-ecosystem/go-grpc-prometheus"
+	"go.opentelemetry.io/contrib/instrumentation/google.golang.org/grpc/otelgrpc"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// methodScopes is the scope required by each rpc of the DataPlane service
+var methodScopes = map[string]apikey.Scope{
+	"/dataplane.DataPlane/LogActions":          apikey.SCOPE_LOG,
+	"/dataplane.DataPlane/SetProfiles":         apikey.SCOPE_LOG,
+	"/dataplane.DataPlane/GetProfiles":         apikey.SCOPE_QUERY,
+	"/dataplane.DataPlane/Query":               apikey.SCOPE_QUERY,
+	"/dataplane.DataPlane/RunStoredQuery":      apikey.SCOPE_QUERY,
+	"/dataplane.DataPlane/BatchAggregateValue": apikey.SCOPE_QUERY,
+}
+
+// methodEndpoints is the rate limited endpoint of each rpc, rpcs that are not listed are not rate limited
+var methodEndpoints = map[string]string{
+	"/dataplane.DataPlane/LogActions":     logEndpoint,
+	"/dataplane.DataPlane/Query":          queryEndpoint,
+	"/dataplane.DataPlane/RunStoredQuery": runQueryEndpoint,
+}
+
+// grpcServer serves the DataPlane rpc service using the same controllers as the http server
+type grpcServer struct {
+	s server
+	rpc.UnimplementedDataPlaneServer
+}
+
+var _ rpc.DataPlaneServer = grpcServer{}
+
+func newGrpcServer(s server) *grpc.Server {
+	gs := grpc.NewServer(
+		grpc.UnaryInterceptor(grpc_middleware.ChainUnaryServer(
+			tracingInterceptor,
+			grpc_prometheus.UnaryServerInterceptor,
+			otelgrpc.UnaryServerInterceptor(),
+			s.authInterceptor,
+			s.rateLimitInterceptor,
+		)),
+	)
+	rpc.RegisterDataPlaneServer(gs, grpcServer{s: s})
+	grpc_prometheus.Register(gs)
+	return gs
+}
+
+func tracingInterceptor(ctx context.Context, req any, _ *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+	return handler(timer.WithTracing(ctx), req)
+}
+
+// bearerToken returns the api key sent in the "authorization" metadata of the rpc
+func bearerToken(ctx context.Context) string {
+	md, ok := metadata.FromIncomingContext(ctx)
+	if !ok {
+		return ""
+	}
+	vals := md.Get("authorization")
+	if len(vals) == 0 {
+		return ""
+	}
+	return strings.TrimSpace(strings.TrimPrefix(vals[0], "Bearer "))
+}
+
+func (s server) authInterceptor(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+	scope, ok := methodScopes[info.FullMethod]
+	if !ok {
+		scope = apikey.SCOPE_ADMIN
+	}
+	switch keyID, code, err := s.authorize(ctx, info.FullMethod, bearerToken(ctx), scope); code {
+	case http.StatusOK:
+		return handler(withKeyID(ctx, keyID), req)
+	case http.StatusUnauthorized:
+		return nil, status.Error(codes.Unauthenticated, err.Error())
+	case http.StatusForbidden:
+		return nil, status.Error(codes.PermissionDenied, err.Error())
+	default:
+		return nil, status.Errorf(codes.Unavailable, "failed to load api keys: %v", err)
+	}
+}
+
+func (s server) rateLimitInterceptor(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+	endpoint, ok := methodEndpoints[info.FullMethod]
+	if !ok {
+		return handler(ctx, req)
+	}
+	if _, _, err := s.takeToken(ctx, endpoint, authenticatedKeyID(ctx)); err != nil {
+		return nil, status.Error(codes.ResourceExhausted, err.Error())
+	}
+	return handler(ctx, req)
+}
+
+// toStatus converts errors of the controllers to grpc errors
+func toStatus(err error) error {
+	var invalid schema2.InvalidRecordError
+	if errors.As(err, &invalid) {
+		return status.Errorf(codes.InvalidArgument, "invalid request: %v; no record was logged", err)
+	}
+	var invalidArgs query2.InvalidArgsError
+	if errors.As(err, &invalidArgs) {
+		return status.Error(codes.InvalidArgument, err.Error())
+	}
+	var exceeded budget.ExceededError
+	if errors.As(err, &exceeded) {
+		return status.Error(codes.ResourceExhausted, err.Error())
+	}
+	return status.Error(codes.Internal, err.Error())
+}
+
+func (g grpcServer) LogActions(ctx context.Context, req *rpc.LogActionsRequest) (*rpc.LogActionsResponse, error) {
+	if len(req.DedupKeys) > 0 && len(req.DedupKeys) != len(req.Actions) {
+		return nil, status.Errorf(codes.InvalidArgument, "expected %d dedup keys but got %d", len(req.Actions), len(req.DedupKeys))
+	}
+	actions := make([]actionlib.Action, len(req.Actions))
+	for i, pa := range req.Actions {
+		a, err := actionlib.FromProtoAction(pa)
+		if err != nil {
+			return nil, status.Errorf(codes.InvalidArgument, "invalid action: %v; no action was logged", err)
+		}
+		if err = a.Validate(); err != nil {
+			return nil, status.Errorf(codes.InvalidArgument, "invalid action: %v; no action was logged", err)
+		}
+		incomingActions.WithLabelValues("grpc", string(a.ActionType)).Inc()
+		actions[i] = a
+	}
+	dedupKeys := req.DedupKeys
+	if len(dedupKeys) == 0 {
+		dedupKeys = make([]string, len(actions))
+	}
+	valid, err := schema2.CheckActions(ctx, g.s.tier, actions)
+	if err != nil {
+		return nil, toStatus(err)
+	}
+	actions, dedupKeys = filterValid(actions, valid), filterValid(dedupKeys, valid)
+	batch, _ := g.s.dedupActions(ctx, "grpc", actions, dedupKeys)
+	if err = action.BatchInsert(ctx, g.s.tier, batch); err != nil {
+		return nil, toStatus(err)
+	}
+	for _, a := range batch {
+		totalActions.WithLabelValues("grpc", string(a.ActionType)).Inc()
+	}
+	g.s.usageController.IncCounter(&usagelib.UsageCountersProto{
+		Actions: uint64(len(actions)),
+	})
+	return &rpc.LogActionsResponse{}, nil
+}
+
+func (g grpcServer) SetProfiles(ctx context.Context, req *rpc.SetProfilesRequest) (*rpc.SetProfilesResponse, error) {
+	profiles := make([]profilelib.ProfileItem, len(req.Profiles))
+	for i, pp := range req.Profiles {
+		p, err := profilelib.FromProtoProfileItem(pp)
+		if err != nil {
+			return nil, status.Errorf(codes.InvalidArgument, "invalid profile: %v", err)
+		}
+		if err = p.Validate(); err != nil {
+			return nil, status.Errorf(codes.InvalidArgument, "invalid profile: %v", err)
+		}
+		profiles[i] = p
+	}
+	valid, err := schema2.CheckProfiles(ctx, g.s.tier, profiles)
+	if err != nil {
+		return nil, toStatus(err)
+	}
+	if err = profile2.SetMulti(ctx, g.s.tier, filterValid(profiles, valid)); err != nil {
+		return nil, toStatus(err)
+	}
+	return &rpc.SetProfilesResponse{}, nil
+}
+
+func (g grpcServer) GetProfiles(ctx context.Context, req *rpc.GetProfilesRequest) (*rpc.GetProfilesResponse, error) {
+	keys := make([]profilelib.ProfileItemKey, len(req.Keys))
+	for i, k := range req.Keys {
+		keys[i] = profilelib.NewProfileItemKey(ftypes.OType(k.Otype), ftypes.OidType(k.Oid), k.Key)
+		if err := keys[i].Validate(); err != nil {
+			return nil, status.Errorf(codes.InvalidArgument, "invalid profile key: %v", err)
+		}
+	}
+	profiles, err := profile2.GetBatch(ctx, g.s.tier, keys)
+	if err != nil {
+		return nil, toStatus(err)
+	}
+	values := make([]value.Value, len(profiles))
+	for i := range profiles {
+		values[i] = profiles[i].Value
+	}
+	pvalues, err := toProtoValues(values)
+	if err != nil {
+		return nil, toStatus(err)
+	}
+	return &rpc.GetProfilesResponse{Values: pvalues}, nil
+}
+
+func (g grpcServer) Query(ctx context.Context, req *rpc.QueryRequest) (*rpc.QueryResponse, error) {
+	if unleash.IsEnabled("disable-query-calls") {
+		totalUnleashQueryRequestsDropped.Inc()
+		return queryResponse(value.NewDict(nil))
+	}
+	ctx, span := timer.Start(ctx, g.s.tier.ID, "server.grpc.Query")
+	defer span.Stop()
+	if req.Ast == nil {
+		return nil, status.Error(codes.InvalidArgument, "ast is required")
+	}
+	tree, err := ast.FromProtoAst(req.Ast)
+	if err != nil {
+		return nil, status.Errorf(codes.InvalidArgument, "invalid ast: %v", err)
+	}
+	args, err := fromProtoArgs(req.Args)
+	if err != nil {
+		return nil, err
+	}
+	seed := req.Seed
+	if seed == 0 {
+		if seed, err = argsSeed(args); err != nil {
+			return nil, status.Error(codes.InvalidArgument, err.Error())
+		}
+	}
+	bootargs := bootarg.Create(g.s.tier)
+	bootarg.SetBudget(bootargs, g.s.tier.Args.BudgetArgs.Default())
+	return g.exec(ctx, bootargs, tree, args, seed)
+}
+
+func (g grpcServer) RunStoredQuery(ctx context.Context, req *rpc.RunStoredQueryRequest) (*rpc.QueryResponse, error) {
+	args, err := fromProtoArgs(req.Args)
+	if err != nil {
+		return nil, err
+	}
+	tree, args, version, err := query2.Bind(ctx, g.s.tier, req.Name, req.Version, args)
+	if err != nil {
+		return nil, toStatus(err)
+	}
+	b, err := query2.RunBudget(ctx, g.s.tier, req.Name)
+	if err != nil {
+		return nil, toStatus(err)
+	}
+	bootargs := bootarg.Create(g.s.tier)
+	bootarg.SetQueryVersion(bootargs, bootarg.QueryVersion{Name: req.Name, Version: version})
+	bootarg.SetBudget(bootargs, b)
+	seed := req.Seed
+	if seed == 0 {
+		seed = newSeed()
+	}
+	resp, err := g.exec(ctx, bootargs, tree, args, seed)
+	if err != nil {
+		return nil, err
+	}
+	resp.Version = version
+	return resp, nil
+}
+
+func (g grpcServer) exec(ctx context.Context, bootargs map[string]interface{}, tree ast.Ast, args value.Dict, seed int64) (*rpc.QueryResponse, error) {
+	bootarg.SetSeed(bootargs, seed)
+	executor := engine.NewQueryExecutor(bootargs)
+	ret, err := executor.Exec(ctx, tree, args)
+	if err != nil {
+		return nil, toStatus(err)
+	}
+	g.s.usageController.IncCounter(&usagelib.UsageCountersProto{Queries: 1})
+	resp, err := queryResponse(ret)
+	if err != nil {
+		return nil, err
+	}
+	resp.Seed = seed
+	return resp, nil
+}
+
+func (g grpcServer) BatchAggregateValue(ctx context.Context, req *rpc.BatchAggregateValueRequest) (*rpc.BatchAggregateValueResponse, error) {
+	batch := make([]aggregate.GetAggValueRequest, len(req.Requests))
+	for i, r := range req.Requests {
+		key, err := value.FromProtoValue(r.Key)
+		if err != nil {
+			return nil, status.Errorf(codes.InvalidArgument, "invalid key: %v", err)
+		}
+		kwargs, err := fromProtoArgs(r.Kwargs)
+		if err != nil {
+			return nil, err
+		}
+		batch[i] = aggregate.GetAggValueRequest{AggName: ftypes.AggName(r.AggName), Key: key, Kwargs: kwargs}
+	}
+	ret, err := aggregate2.BatchValue(ctx, g.s.tier, batch)
+	if err != nil {
+		return nil, toStatus(err)
+	}
+	pvalues, err := toProtoValues(ret)
+	if err != nil {
+		return nil, toStatus(err)
+	}
+	return &rpc.BatchAggregateValueResponse{Values: pvalues}, nil
+}
+
+func fromProtoArgs(pd *value.PVDict) (value.Dict, error) {
+	if pd == nil {
+		return value.NewDict(nil), nil
+	}
+	args, err := value.FromProtoDict(pd)
+	if err != nil {
+		return value.Dict{}, status.Errorf(codes.InvalidArgument, "invalid args: %v", err)
+	}
+	return args, nil
+}
+
+func queryResponse(v value.Value) (*rpc.QueryResponse, error) {
+	pv, err := value.ToProtoValue(v)
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "failed to convert result to proto: %v", err)
+	}
+	return &rpc.QueryResponse{Result: &pv}, nil
+}
+
+func toProtoValues(values []value.Value) ([]*value.PValue, error) {
+	ret := make([]*value.PValue, len(values))
+	for i, v := range values {
+		pv, err := value.ToProtoValue(v)
+		if err != nil {
+			return nil, fmt.Errorf("failed to convert value to proto: %w", err)
+		}
+		ret[i] = &pv
+	}
+	return ret, nil
+}
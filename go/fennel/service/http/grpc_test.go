@@ -0,0 +1,93 @@
+package main
+
+import (
+	"context"
+	"net"
+	"testing"
+	"time"
+
+	"fennel/client"
+	usagecontroller "fennel/controller/usage"
+	"fennel/engine/ast"
+	"fennel/lib/action"
+	"fennel/lib/ftypes"
+	profilelib "fennel/lib/profile"
+	"fennel/lib/value"
+	"fennel/test"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/test/bufconn"
+)
+
+func startTestGrpcServer(t *testing.T, controller *server) *client.GrpcClient {
+	lis := bufconn.Listen(1 << 20)
+	gs := newGrpcServer(*controller)
+	go func() { _ = gs.Serve(lis) }()
+	t.Cleanup(gs.Stop)
+	c, err := client.NewGrpcClient("bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) { return lis.DialContext(ctx) }),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+	)
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = c.Close() })
+	return c
+}
+
+func TestGrpcServer(t *testing.T) {
+	tier := test.Tier(t)
+	defer test.Teardown(tier)
+	ctx, cancelFn := context.WithCancel(context.Background())
+	defer cancelFn()
+	usageController := usagecontroller.NewController(ctx, &tier, 10*time.Second, 50, 50, 1000)
+	controller := NewServer(&tier, usageController)
+	c := startTestGrpcServer(t, controller)
+
+	// log actions
+	actions := []action.Action{
+		{ActorType: "user", ActorID: "1", TargetType: "video", TargetID: "2", ActionType: "click", RequestID: "1", Metadata: value.Int(1), Timestamp: 100},
+		{ActorType: "user", ActorID: "2", TargetType: "video", TargetID: "2", ActionType: "click", RequestID: "1", Metadata: value.Int(2), Timestamp: 100},
+	}
+	require.NoError(t, c.LogActions(ctx, actions, nil))
+	assert.Error(t, c.LogActions(ctx, actions, []string{"a"}))
+
+	// set and get profiles
+	profiles := []profilelib.ProfileItem{
+		profilelib.NewProfileItem("user", "1", "age", value.Int(30), 1),
+		profilelib.NewProfileItem("user", "2", "age", value.Int(40), 1),
+	}
+	require.NoError(t, c.SetProfiles(ctx, profiles))
+	found, err := c.GetProfiles(ctx, []profilelib.ProfileItemKey{
+		profilelib.NewProfileItemKey("user", "2", "age"),
+		profilelib.NewProfileItemKey("user", ftypes.OidType("1"), "age"),
+		profilelib.NewProfileItemKey("user", "3", "age"),
+	})
+	require.NoError(t, err)
+	assert.Equal(t, []value.Value{value.Int(40), value.Int(30), value.Nil}, found)
+
+	// query
+	tree := &ast.IfElse{
+		Condition: &ast.Binary{Left: ast.MakeInt(5), Op: "<", Right: &ast.Var{Name: "x"}},
+		ThenDo:    ast.MakeString("left"),
+		ElseDo:    ast.MakeString("right"),
+	}
+	ret, err := c.Query(ctx, tree, value.NewDict(map[string]value.Value{"x": value.Int(7)}))
+	require.NoError(t, err)
+	assert.Equal(t, value.String("left"), ret)
+	_, err = c.Query(ctx, tree, value.NewDict(nil))
+	assert.Error(t, err)
+
+	// stored queries
+	_, err = c.RunQuery(ctx, "myquery", value.NewDict(nil))
+	assert.Error(t, err)
+	srv := startTestServer(controller)
+	defer srv.Close()
+	hc, err := client.NewClient(srv.URL, srv.Client())
+	require.NoError(t, err)
+	require.NoError(t, hc.StoreQuery("myquery", tree, ""))
+	ret, err = c.RunQuery(ctx, "myquery", value.NewDict(map[string]value.Value{"x": value.Int(3)}))
+	require.NoError(t, err)
+	assert.Equal(t, value.String("right"), ret)
+}
@@ -6,6 +6,7 @@ import (
 	"fmt"
 	"log"
 	"math/rand"
+	"net"
 	"net/http"
 	"os"
 	"os/signal"
@@ -14,9 +15,11 @@ import (
 	"time"
 
 	"fennel/controller/usage"
+	"fennel/engine/interpreter"
 	httplib "fennel/lib/http"
 	"fennel/lib/timer"
 	"fennel/lib/utils/memory"
+	"fennel/lib/utils/parallel"
 	_ "fennel/opdefs"
 	"fennel/service/common"
 	inspector "fennel/service/inspector/server"
@@ -116,6 +119,8 @@ func main() {
 	}
 	arg.MustParse(&flags)
 	log.SetFlags(log.LstdFlags | log.Lshortfile)
+	// statements of queries mostly wait on remote calls, so many more of them than cores can run at once
+	parallel.InitQuota(interpreter.STATEMENT_QUOTA, 64*parallel.AllCPUs/parallel.OneCPU)
 	tier, err := tier.CreateFromArgs(&flags.TierArgs)
 	if err != nil {
 		panic(fmt.Sprintf("Failed to setup tier connectors: %v", err))
@@ -169,6 +174,20 @@ func main() {
 		}
 	}()
 
+	// Serve the rpc interface of the data plane along with the http server.
+	grpcAddr := fmt.Sprintf(":%d", httplib.GRPC_PORT)
+	lis, err := net.Listen("tcp", grpcAddr)
+	if err != nil {
+		log.Fatalf("failed to listen on %s: %v", grpcAddr, err)
+	}
+	grpcSrv := newGrpcServer(*controller)
+	go func() {
+		log.Printf("starting grpc service on %s...", grpcAddr)
+		if err := grpcSrv.Serve(lis); err != nil {
+			log.Fatalf("Serve(): %v", err)
+		}
+	}()
+
 	// start profile writer
 	go func() {
 		profiler.StartProfileExporter(tier.S3Client)
@@ -197,5 +216,6 @@ func main() {
 	if err := srv.Shutdown(ctx); err != nil {
 		log.Fatalf("server shutdown failed: %v", err)
 	}
+	grpcSrv.GracefulStop()
 	log.Println("server exited properly...")
 }
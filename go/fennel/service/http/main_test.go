@@ -22,12 +22,15 @@ import (
 	"fennel/engine/ast"
 	"fennel/kafka"
 	"fennel/lib/aggregate"
+	"fennel/lib/budget"
 	"fennel/resource"
 
 	"fennel/client"
 	"fennel/lib/action"
 	"fennel/lib/ftypes"
+	"fennel/lib/ingest"
 	profilelib "fennel/lib/profile"
+	libquery "fennel/lib/query"
 	"fennel/lib/value"
 	"fennel/test"
 
@@ -60,7 +63,7 @@ func add(t *testing.T, c *client.Client, a action.Action) {
 
 // addBatch logs multiple actions with no dedup key for any of them
 func addBatch(t *testing.T, c *client.Client, as []action.Action) {
-	err := c.LogActions(as, nil)
+	_, err := c.LogActions(as, nil, ingest.BatchOptions{})
 	assert.NoError(t, err)
 }
 
@@ -171,7 +174,7 @@ func TestLogFetchServerClient(t *testing.T) {
 		RequestID:  "6",
 		Metadata:   value.Nil,
 	}
-	err = c.LogActions([]action.Action{d3, d3, d3}, nil)
+	_, err = c.LogActions([]action.Action{d3, d3, d3}, nil, ingest.BatchOptions{})
 	assert.NoError(t, err)
 	assert.NoError(t, action2.TransferToDB(ctx, tier, consumer))
 	// logged three actions with no dedup key, should get back three actions
@@ -187,7 +190,7 @@ func TestLogFetchServerClient(t *testing.T) {
 		RequestID:  "6",
 		Metadata:   value.Nil,
 	}
-	err = c.LogActions([]action.Action{d4, d4, d4}, []string{"dedup_multi", "dedup_multi", "dedup_multi"})
+	_, err = c.LogActions([]action.Action{d4, d4, d4}, []string{"dedup_multi", "dedup_multi", "dedup_multi"}, ingest.BatchOptions{})
 	assert.NoError(t, err)
 	assert.NoError(t, action2.TransferToDB(ctx, tier, consumer))
 	// logged three actions with same dedup key, should get back one action
@@ -204,8 +207,8 @@ func TestLogFetchServerClient(t *testing.T) {
 		RequestID:  "6",
 		Metadata:   value.Nil,
 	}
-	err = c.LogActions([]action.Action{d5, d5, d5, d5, d5},
-		[]string{"dedup_mix_1", "", "dedup_mix_2", "dedup_mix_2", "dedup_mix_1"})
+	_, err = c.LogActions([]action.Action{d5, d5, d5, d5, d5},
+		[]string{"dedup_mix_1", "", "dedup_mix_2", "dedup_mix_2", "dedup_mix_1"}, ingest.BatchOptions{})
 	assert.NoError(t, err)
 	assert.NoError(t, action2.TransferToDB(ctx, tier, consumer))
 	// of the 5 actions, only three of them will be set
@@ -287,11 +290,76 @@ func TestActionDedupedPerActionType(t *testing.T) {
 		Metadata:   value.Nil,
 	}
 
-	assert.NoError(t, c.LogActions([]action.Action{a1, a2}, []string{"same_key", "same_key"}))
+	_, err = c.LogActions([]action.Action{a1, a2}, []string{"same_key", "same_key"}, ingest.BatchOptions{})
+	assert.NoError(t, err)
 	assert.NoError(t, action2.TransferToDB(ctx, tier, consumer))
 	verifyFetch(t, c, action.ActionFetchRequest{ActorID: "1"}, []action.Action{a1, a2})
 }
 
+func TestLogMultiPerRecordStatus(t *testing.T) {
+	tier := test.Tier(t)
+	defer test.Teardown(tier)
+	ctx, cancelFn := context.WithCancel(context.Background())
+	defer cancelFn()
+	usageController := usagecontroller.NewController(ctx, &tier, 10*time.Second, 50, 50, 1000)
+	controller := NewServer(&tier, usageController)
+	defer controller.Close()
+	server := startTestServer(controller)
+	defer server.Close()
+	c, err := client.NewClient(server.URL, server.Client())
+	assert.NoError(t, err)
+
+	consumer, err := tier.NewKafkaConsumer(kafka.ConsumerConfig{
+		Scope:        resource.NewTierScope(tier.ID),
+		Topic:        action.ACTIONLOG_KAFKA_TOPIC,
+		GroupID:      "somegroup",
+		OffsetPolicy: kafka.DefaultOffsetPolicy,
+	})
+	assert.NoError(t, err)
+	defer consumer.Close()
+
+	a := action.Action{
+		ActorID:    "1",
+		ActorType:  "2",
+		TargetID:   "3",
+		TargetType: "4",
+		ActionType: "batch",
+		Timestamp:  5,
+		RequestID:  "6",
+		Metadata:   value.Nil,
+	}
+	invalid := a
+	invalid.ActorType = ""
+	actions := []action.Action{a, invalid, a}
+	dedupKeys := []string{"k1", "", "k1"}
+
+	// atomic batches with invalid records are not logged
+	result, err := c.LogActions(actions, dedupKeys, ingest.BatchOptions{Atomic: true})
+	assert.Error(t, err)
+	assert.False(t, result.Committed)
+	assert.Equal(t, []ingest.Status{ingest.Skipped, ingest.Invalid, ingest.Skipped},
+		[]ingest.Status{result.Records[0].Status, result.Records[1].Status, result.Records[2].Status})
+
+	// otherwise the valid records are logged and the others reported
+	result, err = c.LogActions(actions, dedupKeys, ingest.BatchOptions{IdempotencyKey: "batch-1"})
+	assert.NoError(t, err)
+	assert.True(t, result.Committed)
+	assert.False(t, result.Replayed)
+	assert.Equal(t, ingest.Accepted, result.Records[0].Status)
+	assert.Equal(t, ingest.Invalid, result.Records[1].Status)
+	assert.NotEmpty(t, result.Records[1].Reason)
+	assert.Equal(t, ingest.Duplicate, result.Records[2].Status)
+
+	// retries with the same idempotency key return the first result without logging again
+	retried, err := c.LogActions(actions, dedupKeys, ingest.BatchOptions{IdempotencyKey: "batch-1"})
+	assert.NoError(t, err)
+	assert.True(t, retried.Replayed)
+	assert.Equal(t, result.Records, retried.Records)
+
+	assert.NoError(t, action2.TransferToDB(ctx, tier, consumer))
+	verifyFetch(t, c, action.ActionFetchRequest{ActionType: "batch"}, []action.Action{a})
+}
+
 // TODO: add more tests covering more error conditions
 func TestProfileServerClient(t *testing.T) {
 	// create a service
@@ -354,7 +422,8 @@ func TestSetProfilesQueuesToKafka(t *testing.T) {
 		profileList2 = append(profileList2, p)
 	}
 
-	assert.NoError(t, c.SetProfiles(profileList2))
+	_, err = c.SetProfiles(profileList2, ingest.BatchOptions{})
+	assert.NoError(t, err)
 
 	consumer, err := tier.NewKafkaConsumer(kafka.ConsumerConfig{
 		Scope:        resource.NewTierScope(tier.ID),
@@ -472,6 +541,87 @@ func TestStoreRunQuery(t *testing.T) {
 	assert.Error(t, err)
 }
 
+func TestQueryBudget(t *testing.T) {
+	tier := test.Tier(t)
+	defer test.Teardown(tier)
+	tier.Args.BudgetArgs = budget.BudgetArgs{QueryMaxRows: 100, QueryTimeout: time.Second}
+
+	ctx, cancelFn := context.WithCancel(context.Background())
+	defer cancelFn()
+	usageController := usagecontroller.NewController(ctx, &tier, 10*time.Second, 50, 50, 1000)
+	controller := NewServer(&tier, usageController)
+	server := startTestServer(controller)
+	defer server.Close()
+	c, err := client.NewClient(server.URL, server.Client())
+	assert.NoError(t, err)
+
+	name := "repeated"
+	assert.NoError(t, c.StoreQuery(name, &ast.OpCall{
+		Namespace: "std",
+		Name:      "repeat",
+		Operands:  []ast.Ast{ast.MakeList(ast.MakeInt(1), ast.MakeInt(2))},
+		Vars:      []string{"x"},
+		Kwargs:    ast.MakeDict(map[string]ast.Ast{"count": ast.MakeInt(5)}),
+	}, ""))
+	found, err := c.RunQuery(name, value.NewDict(nil))
+	assert.NoError(t, err)
+	assert.Equal(t, 10, found.(value.List).Len())
+
+	// the query overrides the rows of the tier and keeps its timeout
+	assert.Error(t, c.SetQueryBudget("unknown", budget.Budget{MaxRows: 5}))
+	assert.NoError(t, c.SetQueryBudget(name, budget.Budget{MaxRows: 5}))
+	b, runBudget, err := c.QueryBudget(name)
+	assert.NoError(t, err)
+	assert.Equal(t, budget.Budget{MaxRows: 5}, b)
+	assert.Equal(t, budget.Budget{MaxRows: 5, TimeoutMs: 1000}, runBudget)
+	_, err = c.RunQuery(name, value.NewDict(nil))
+	assert.ErrorContains(t, err, "operator 'std.repeat' exceeded the query budget of 5 rows")
+}
+
+func TestQueryVersions(t *testing.T) {
+	tier := test.Tier(t)
+	defer test.Teardown(tier)
+
+	ctx, cancelFn := context.WithCancel(context.Background())
+	defer cancelFn()
+	usageController := usagecontroller.NewController(ctx, &tier, 10*time.Second, 50, 50, 1000)
+	controller := NewServer(&tier, usageController)
+	server := startTestServer(controller)
+	defer server.Close()
+	c, err := client.NewClient(server.URL, server.Client())
+	assert.NoError(t, err)
+
+	name := "ranking"
+	assert.NoError(t, c.StoreQuery(name, ast.MakeString("v1"), ""))
+	assert.NoError(t, c.StoreQuery(name, ast.MakeString("v2"), ""))
+	versions, err := c.QueryVersions(name)
+	assert.NoError(t, err)
+	assert.Len(t, versions, 2)
+
+	// the latest version runs by default
+	found, version, err := c.RunQueryVersion(name, "", value.NewDict(nil))
+	assert.NoError(t, err)
+	assert.Equal(t, value.String("v2"), found)
+	assert.Equal(t, uint32(2), version)
+
+	_, err = c.SetQueryAlias(libquery.Alias{QueryName: name, Alias: "stable", Version: 1})
+	assert.NoError(t, err)
+	found, version, err = c.RunQueryVersion(name, "stable", value.NewDict(nil))
+	assert.NoError(t, err)
+	assert.Equal(t, value.String("v1"), found)
+	assert.Equal(t, uint32(1), version)
+
+	_, err = c.SetQueryAlias(libquery.Alias{QueryName: name, Alias: "stable", Version: 2})
+	assert.NoError(t, err)
+	alias, err := c.RollbackQueryAlias(name, "stable")
+	assert.NoError(t, err)
+	assert.Equal(t, uint32(1), alias.Version)
+
+	aliases, err := c.QueryAliases(name)
+	assert.NoError(t, err)
+	assert.Len(t, aliases, 1)
+}
+
 func TestServer_AggregateValue_Valid(t *testing.T) {
 	tier := test.Tier(t)
 	defer test.Teardown(tier)
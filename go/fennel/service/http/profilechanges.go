@@ -0,0 +1,127 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	profile2 "fennel/controller/profile"
+	"fennel/kafka"
+	"fennel/lib/ftypes"
+	profilelib "fennel/lib/profile"
+)
+
+const (
+	defaultChangesLimit   = 1000
+	defaultChangesTimeout = 10 * time.Second
+	maxChangesTimeout     = 30 * time.Second
+)
+
+// changeRequest parses the query params of a request to read profile changes:
+//
+//	subscriber: name of the subscriber, required
+//	from: 'earliest' or 'latest' (default), where a new subscriber starts reading
+//	offsets: <partition>:<offset>,... to move the subscriber to before reading
+//	otype, key: filter changes to these otypes and keys, can be repeated
+//	limit: maximum number of changes to read at a time
+//	timeout_ms: how long to wait for changes
+func changeRequest(req *http.Request) (profile2.ChangeRequest, error) {
+	params := req.URL.Query()
+	cr := profile2.ChangeRequest{
+		Subscriber: params.Get("subscriber"),
+		From:       params.Get("from"),
+		Limit:      defaultChangesLimit,
+		Timeout:    defaultChangesTimeout,
+	}
+	if len(cr.From) == 0 {
+		cr.From = kafka.LatestOffsetPolicy
+	}
+	offsets := params.Get("offsets")
+	if len(offsets) == 0 {
+		// server-sent events clients send the id of the last event they got when reconnecting
+		offsets = req.Header.Get("Last-Event-ID")
+	}
+	var err error
+	if cr.Offsets, err = profilelib.ParseOffsets(offsets); err != nil {
+		return cr, err
+	}
+	for _, otype := range params["otype"] {
+		cr.Filter.OTypes = append(cr.Filter.OTypes, ftypes.OType(otype))
+	}
+	cr.Filter.Keys = params["key"]
+	if s := params.Get("limit"); len(s) > 0 {
+		if cr.Limit, err = strconv.Atoi(s); err != nil {
+			return cr, fmt.Errorf("invalid limit: '%s'", s)
+		}
+	}
+	if s := params.Get("timeout_ms"); len(s) > 0 {
+		ms, err := strconv.Atoi(s)
+		if err != nil || ms < 0 {
+			return cr, fmt.Errorf("invalid timeout_ms: '%s'", s)
+		}
+		cr.Timeout = time.Duration(ms) * time.Millisecond
+	}
+	if cr.Timeout > maxChangesTimeout {
+		cr.Timeout = maxChangesTimeout
+	}
+	return cr, cr.Validate()
+}
+
+// GetProfileChanges long-polls for the changes of profiles. If the client accepts server-sent
+// events, batches of changes are streamed as events, with the offsets after the batch as the id of
+// the event, until the client disconnects.
+func (m server) GetProfileChanges(w http.ResponseWriter, req *http.Request) {
+	cr, err := changeRequest(req)
+	if err != nil {
+		handleBadRequest(w, "invalid request: ", err)
+		return
+	}
+	reader, err := profile2.NewChangeReader(m.tier, cr)
+	if err != nil {
+		handleInternalServerError(w, "", err)
+		return
+	}
+	defer reader.Close()
+	if !strings.Contains(req.Header.Get("Accept"), "text/event-stream") {
+		batch, err := reader.Read(req.Context())
+		if err != nil {
+			handleInternalServerError(w, "", err)
+			return
+		}
+		writeJSON(w, batch)
+		return
+	}
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		handleInternalServerError(w, "", fmt.Errorf("streaming is not supported"))
+		return
+	}
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.WriteHeader(http.StatusOK)
+	flusher.Flush()
+	for req.Context().Err() == nil {
+		batch, err := reader.Read(req.Context())
+		if err != nil {
+			if req.Context().Err() == nil {
+				fmt.Fprintf(w, "event: error\ndata: %s\n\n", strconv.Quote(err.Error()))
+				flusher.Flush()
+			}
+			return
+		}
+		if len(batch.Changes) == 0 {
+			// keep the connection alive
+			fmt.Fprint(w, ": ping\n\n")
+		} else {
+			ser, err := json.Marshal(batch.Changes)
+			if err != nil {
+				return
+			}
+			fmt.Fprintf(w, "event: changes\nid: %s\ndata: %s\n\n", batch.Offsets, ser)
+		}
+		flusher.Flush()
+	}
+}
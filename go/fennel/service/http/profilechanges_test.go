@@ -0,0 +1,39 @@
+package main
+
+import (
+	"net/http/httptest"
+	"testing"
+
+	"fennel/kafka"
+	"fennel/lib/ftypes"
+	profilelib "fennel/lib/profile"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestChangeRequest(t *testing.T) {
+	req := httptest.NewRequest("GET", "/internal/v1/profiles/changes?subscriber=indexer&otype=user&otype=video&key=age&limit=5&timeout_ms=100000", nil)
+	cr, err := changeRequest(req)
+	assert.NoError(t, err)
+	assert.Equal(t, "indexer", cr.Subscriber)
+	assert.Equal(t, kafka.LatestOffsetPolicy, cr.From)
+	assert.Equal(t, []ftypes.OType{"user", "video"}, cr.Filter.OTypes)
+	assert.Equal(t, []string{"age"}, cr.Filter.Keys)
+	assert.Equal(t, 5, cr.Limit)
+	assert.Equal(t, maxChangesTimeout, cr.Timeout)
+	assert.Empty(t, cr.Offsets)
+
+	// the offsets of server-sent events clients are in the id of the last event
+	req = httptest.NewRequest("GET", "/internal/v1/profiles/changes?subscriber=indexer&from=earliest", nil)
+	req.Header.Set("Last-Event-ID", "0:12,1:3")
+	cr, err = changeRequest(req)
+	assert.NoError(t, err)
+	assert.Equal(t, kafka.EarliestOffsetPolicy, cr.From)
+	assert.Equal(t, profilelib.Offsets{0: 12, 1: 3}, cr.Offsets)
+	assert.Equal(t, defaultChangesTimeout, cr.Timeout)
+
+	for _, query := range []string{"", "subscriber=a&from=now", "subscriber=a&limit=0", "subscriber=a&offsets=x", "subscriber=a&timeout_ms=-1"} {
+		_, err = changeRequest(httptest.NewRequest("GET", "/internal/v1/profiles/changes?"+query, nil))
+		assert.Error(t, err, query)
+	}
+}
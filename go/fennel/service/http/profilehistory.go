@@ -0,0 +1,101 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	profile2 "fennel/controller/profile"
+	"fennel/lib/ftypes"
+	profilelib "fennel/lib/profile"
+)
+
+// GetProfileHistory returns the values of a profile that were set between the 'start' and 'end'
+// query params, given in seconds since epoch. 'end' defaults to now.
+func (m server) GetProfileHistory(w http.ResponseWriter, req *http.Request) {
+	params := req.URL.Query()
+	pk := profilelib.ProfileItemKey{
+		OType: ftypes.OType(params.Get("otype")),
+		Oid:   ftypes.OidType(params.Get("oid")),
+		Key:   params.Get("key"),
+	}
+	start, err := secondsParam(params.Get("start"), time.Unix(0, 0))
+	if err != nil {
+		handleBadRequest(w, "invalid 'start': ", err)
+		return
+	}
+	end, err := secondsParam(params.Get("end"), m.tier.Clock.Now())
+	if err != nil {
+		handleBadRequest(w, "invalid 'end': ", err)
+		return
+	}
+	if err = pk.Validate(); err != nil {
+		handleBadRequest(w, "invalid request: ", err)
+		return
+	}
+	history, err := profile2.GetHistory(req.Context(), m.tier, pk, start, end)
+	if err != nil {
+		handleInternalServerError(w, "", err)
+		return
+	}
+	writeJSON(w, history)
+}
+
+func (m server) ListProfilePolicies(w http.ResponseWriter, req *http.Request) {
+	policies, err := profile2.Policies(req.Context(), m.tier)
+	if err != nil {
+		handleInternalServerError(w, "", err)
+		return
+	}
+	writeJSON(w, policies)
+}
+
+func (m server) SetProfilePolicy(w http.ResponseWriter, req *http.Request) {
+	data, err := readRequest(req)
+	if err != nil {
+		handleBadRequest(w, "", err)
+		return
+	}
+	var policy profilelib.Policy
+	if err = json.Unmarshal(data, &policy); err != nil {
+		handleBadRequest(w, "invalid request: ", err)
+		return
+	}
+	if err = policy.Validate(); err != nil {
+		handleBadRequest(w, "invalid request: ", err)
+		return
+	}
+	if err = profile2.SetPolicy(req.Context(), m.tier, policy); err != nil {
+		handleInternalServerError(w, "", err)
+		return
+	}
+	handleSuccessfulRequest(w)
+}
+
+func (m server) DeleteProfilePolicy(w http.ResponseWriter, req *http.Request) {
+	params := req.URL.Query()
+	otype, key := params.Get("otype"), params.Get("key")
+	if len(otype) == 0 || len(key) == 0 {
+		handleBadRequest(w, "", fmt.Errorf("query params 'otype' and 'key' are required"))
+		return
+	}
+	if err := profile2.DeletePolicy(req.Context(), m.tier, ftypes.OType(otype), key); err != nil {
+		handleInternalServerError(w, "", err)
+		return
+	}
+	handleSuccessfulRequest(w)
+}
+
+// secondsParam parses a time given in seconds since epoch, returning def when it is empty
+func secondsParam(s string, def time.Time) (time.Time, error) {
+	if len(s) == 0 {
+		return def, nil
+	}
+	n, err := strconv.ParseInt(s, 10, 64)
+	if err != nil || n < 0 {
+		return time.Time{}, fmt.Errorf("expected seconds since epoch but found: '%s'", s)
+	}
+	return time.Unix(n, 0), nil
+}
@@ -0,0 +1,64 @@
+package main
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	profile2 "fennel/controller/profile"
+	profilelib "fennel/lib/profile"
+)
+
+func (m server) StartProfileJob(w http.ResponseWriter, req *http.Request) {
+	data, err := readRequest(req)
+	if err != nil {
+		handleBadRequest(w, "", err)
+		return
+	}
+	var request profilelib.JobRequest
+	if err = json.Unmarshal(data, &request); err != nil {
+		handleBadRequest(w, "invalid request: ", err)
+		return
+	}
+	job, err := profile2.StartJob(req.Context(), m.tier, request)
+	var invalid profile2.InvalidJobError
+	if errors.As(err, &invalid) {
+		handleBadRequest(w, "invalid request: ", err)
+		return
+	} else if err != nil {
+		handleInternalServerError(w, "", err)
+		return
+	}
+	writeJSON(w, job)
+}
+
+// GetProfileJobs returns the job with the 'id' query param or, if no id is given, the latest jobs
+func (m server) GetProfileJobs(w http.ResponseWriter, req *http.Request) {
+	idStr := req.URL.Query().Get("id")
+	if len(idStr) == 0 {
+		jobs, err := profile2.Jobs(req.Context(), m.tier)
+		if err != nil {
+			handleInternalServerError(w, "", err)
+			return
+		}
+		writeJSON(w, jobs)
+		return
+	}
+	id, err := strconv.ParseUint(idStr, 10, 64)
+	if err != nil {
+		handleBadRequest(w, "", fmt.Errorf("invalid job id: '%s'", idStr))
+		return
+	}
+	job, err := profile2.GetJob(req.Context(), m.tier, id)
+	if errors.Is(err, sql.ErrNoRows) {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	} else if err != nil {
+		handleInternalServerError(w, "", err)
+		return
+	}
+	writeJSON(w, job)
+}
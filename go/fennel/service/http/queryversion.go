@@ -0,0 +1,170 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	query2 "fennel/controller/query"
+	"fennel/lib/budget"
+	"fennel/lib/query"
+
+	"github.com/buger/jsonparser"
+)
+
+// queryVersionHeader is set on the response of a stored query to the version of the query that
+// was run, so that clients can attribute results to versions when traffic is split
+const queryVersionHeader = "X-Query-Version"
+
+// versionRef returns the optional "version" of a run query request, which is either a version
+// number or an alias
+func versionRef(data []byte) (string, error) {
+	v, vType, _, err := jsonparser.Get(data, "version")
+	switch {
+	case err == jsonparser.KeyPathNotFoundError:
+		return "", nil
+	case err != nil:
+		return "", err
+	case vType == jsonparser.String:
+		return jsonparser.ParseString(v)
+	case vType == jsonparser.Number:
+		n, err := jsonparser.ParseInt(v)
+		if err != nil || n <= 0 {
+			return "", fmt.Errorf("expected 'version' to be a positive integer or an alias but found: '%s'", v)
+		}
+		return strconv.FormatInt(n, 10), nil
+	default:
+		return "", fmt.Errorf("expected 'version' to be a positive integer or an alias but found: '%s'", v)
+	}
+}
+
+func (m server) ListQueryVersions(w http.ResponseWriter, req *http.Request) {
+	name := req.URL.Query().Get("name")
+	if len(name) == 0 {
+		handleBadRequest(w, "", fmt.Errorf("query param 'name' is required"))
+		return
+	}
+	versions, err := query2.Versions(req.Context(), m.tier, name)
+	if err != nil {
+		handleInternalServerError(w, "", err)
+		return
+	}
+	writeJSON(w, versions)
+}
+
+func (m server) ListQueryAliases(w http.ResponseWriter, req *http.Request) {
+	name := req.URL.Query().Get("name")
+	if len(name) == 0 {
+		handleBadRequest(w, "", fmt.Errorf("query param 'name' is required"))
+		return
+	}
+	aliases, err := query2.Aliases(req.Context(), m.tier, name)
+	if err != nil {
+		handleInternalServerError(w, "", err)
+		return
+	}
+	writeJSON(w, aliases)
+}
+
+func (m server) SetQueryAlias(w http.ResponseWriter, req *http.Request) {
+	data, err := readRequest(req)
+	if err != nil {
+		handleBadRequest(w, "", err)
+		return
+	}
+	var alias query.Alias
+	if err = json.Unmarshal(data, &alias); err != nil {
+		handleBadRequest(w, "invalid request: ", err)
+		return
+	}
+	if err = alias.Validate(); err != nil {
+		handleBadRequest(w, "invalid alias: ", err)
+		return
+	}
+	alias, err = query2.SetAlias(req.Context(), m.tier, alias)
+	if err != nil {
+		handleInternalServerError(w, "", err)
+		return
+	}
+	writeJSON(w, alias)
+}
+
+func (m server) RollbackQueryAlias(w http.ResponseWriter, req *http.Request) {
+	data, err := readRequest(req)
+	if err != nil {
+		handleBadRequest(w, "", err)
+		return
+	}
+	var request struct {
+		QueryName string `json:"query_name"`
+		Alias     string `json:"alias"`
+	}
+	if err = json.Unmarshal(data, &request); err != nil {
+		handleBadRequest(w, "invalid request: ", err)
+		return
+	}
+	alias, err := query2.Rollback(req.Context(), m.tier, request.QueryName, request.Alias)
+	if err != nil {
+		handleInternalServerError(w, "", err)
+		return
+	}
+	writeJSON(w, alias)
+}
+
+// GetQueryBudget returns the budget set on the query along with the budget it is run with
+func (m server) GetQueryBudget(w http.ResponseWriter, req *http.Request) {
+	name := req.URL.Query().Get("name")
+	if len(name) == 0 {
+		handleBadRequest(w, "", fmt.Errorf("query param 'name' is required"))
+		return
+	}
+	b, err := query2.GetBudget(req.Context(), m.tier, name)
+	if err != nil {
+		handleInternalServerError(w, "", err)
+		return
+	}
+	writeJSON(w, struct {
+		Budget    budget.Budget `json:"budget"`
+		RunBudget budget.Budget `json:"run_budget"`
+	}{b, m.tier.Args.BudgetArgs.Default().Override(b)})
+}
+
+// SetQueryBudget sets the budget of the query, limits that are not set are taken from the tier
+func (m server) SetQueryBudget(w http.ResponseWriter, req *http.Request) {
+	data, err := readRequest(req)
+	if err != nil {
+		handleBadRequest(w, "", err)
+		return
+	}
+	var request struct {
+		QueryName string        `json:"query_name"`
+		Budget    budget.Budget `json:"budget"`
+	}
+	if err = json.Unmarshal(data, &request); err != nil {
+		handleBadRequest(w, "invalid request: ", err)
+		return
+	}
+	if len(request.QueryName) == 0 {
+		handleBadRequest(w, "", fmt.Errorf("'query_name' is required"))
+		return
+	}
+	if err = request.Budget.Validate(); err != nil {
+		handleBadRequest(w, "invalid budget: ", err)
+		return
+	}
+	if err = query2.SetBudget(req.Context(), m.tier, request.QueryName, request.Budget); err != nil {
+		handleInternalServerError(w, "", err)
+		return
+	}
+	writeJSON(w, request.Budget)
+}
+
+func writeJSON(w http.ResponseWriter, v interface{}) {
+	ser, err := json.Marshal(v)
+	if err != nil {
+		handleInternalServerError(w, "", err)
+		return
+	}
+	_, _ = w.Write(ser)
+}
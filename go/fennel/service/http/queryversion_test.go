@@ -0,0 +1,28 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestVersionRef(t *testing.T) {
+	for body, expected := range map[string]string{
+		`{"name": "q"}`:                  "",
+		`{"name": "q", "version": 3}`:    "3",
+		`{"name": "q", "version": "3"}`:  "3",
+		`{"name": "q", "version": "ab"}`: "ab",
+	} {
+		found, err := versionRef([]byte(body))
+		assert.NoError(t, err)
+		assert.Equal(t, expected, found)
+	}
+	for _, body := range []string{
+		`{"name": "q", "version": 0}`,
+		`{"name": "q", "version": 1.5}`,
+		`{"name": "q", "version": [1]}`,
+	} {
+		_, err := versionRef([]byte(body))
+		assert.Error(t, err, body)
+	}
+}
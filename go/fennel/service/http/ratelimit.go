@@ -0,0 +1,141 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+
+	"fennel/controller/usage"
+	"fennel/lib/ratelimit"
+	usagelib "fennel/lib/usage"
+	"fennel/tier"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+// endpoints that have their own rate limits
+const (
+	logEndpoint      = "log"
+	queryEndpoint    = "query"
+	runQueryEndpoint = "run_query"
+)
+
+var rateLimited = promauto.NewCounterVec(
+	prometheus.CounterOpts{
+		Name: "http_rate_limited_requests_total",
+		Help: "Total number of requests rejected because of rate limits.",
+	},
+	[]string{"endpoint", "limit"},
+)
+
+func newLimiter(tr *tier.Tier) *ratelimit.Limiter {
+	var store ratelimit.Store
+	if tr.Args.RateLimitRedis {
+		store = ratelimit.NewRedisStore(tr.Redis)
+	}
+	return ratelimit.NewLimiter(store, tr.Clock.Now)
+}
+
+func (s server) endpointLimit(endpoint string) ratelimit.Limit {
+	args := s.tier.Args.RateLimitArgs
+	switch endpoint {
+	case logEndpoint:
+		return ratelimit.PerSecond(args.LogRateLimit, args.RateLimitBurstSeconds)
+	case queryEndpoint:
+		return ratelimit.PerSecond(args.QueryRateLimit, args.RateLimitBurstSeconds)
+	case runQueryEndpoint:
+		return ratelimit.PerSecond(args.RunQueryRateLimit, args.RateLimitBurstSeconds)
+	}
+	return ratelimit.Limit{}
+}
+
+// rateLimited wraps the handler so that requests are rejected once either the tier-wide daily
+// quota of actions & queries or the limit of the endpoint for the api key the request was
+// authenticated with is exhausted. Requests share a single bucket per endpoint when authentication
+// is disabled.
+func (s server) rateLimited(endpoint string, handler http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, req *http.Request) {
+		r, limited, err := s.takeToken(req.Context(), endpoint, authenticatedKeyID(req.Context()))
+		if err != nil {
+			handleRateLimited(w, r, err)
+			return
+		}
+		if limited {
+			setRateLimitHeaders(w, r)
+		}
+		handler(w, req)
+	}
+}
+
+// takeToken checks the tier-wide daily quota and takes a token from the bucket of the endpoint for
+// the api key. It returns an error if the request should be rejected along with the result of the
+// limit that rejected it, and otherwise whether the endpoint is rate limited at all.
+func (s server) takeToken(ctx context.Context, endpoint, keyID string) (ratelimit.Result, bool, error) {
+	if r, err := s.checkQuota(ctx); err != nil {
+		rateLimited.WithLabelValues(endpoint, "tier").Inc()
+		return r, true, err
+	}
+	limit := s.endpointLimit(endpoint)
+	r := s.limiter.Take(ctx, fmt.Sprintf("ratelimit:%s:%s", endpoint, keyID), limit)
+	if !r.Allowed {
+		rateLimited.WithLabelValues(endpoint, "endpoint").Inc()
+		return r, true, fmt.Errorf("rate limit of %g requests per second exceeded for '%s'", limit.Rate, endpoint)
+	}
+	return r, !limit.Unlimited(), nil
+}
+
+// checkQuota returns an error once the actions & queries of the day, as counted in redis by all
+// replicas, reach the request limit of the tier. Requests are let through if redis is unavailable.
+func (s server) checkQuota(ctx context.Context) (ratelimit.Result, error) {
+	if s.tier.RequestLimit <= 0 {
+		return ratelimit.Result{}, nil
+	}
+	now := s.tier.Clock.Now()
+	used, err := s.quota.Used(ctx, now)
+	if err != nil {
+		log.Printf("failed to get the daily quota used by the tier: %v", err)
+		return ratelimit.Result{}, nil
+	}
+	if used < s.tier.RequestLimit {
+		return ratelimit.Result{}, nil
+	}
+	reset := ratelimit.UntilReset(now)
+	return ratelimit.Result{Limit: s.tier.RequestLimit, RetryAfter: reset, Reset: reset},
+		fmt.Errorf("daily limit of %d actions and queries exceeded", s.tier.RequestLimit)
+}
+
+// quotaCounter counts the actions & queries of the usage counters against the daily quota of the tier
+type quotaCounter struct {
+	usage.UsageController
+	tier  *tier.Tier
+	quota ratelimit.DailyQuota
+}
+
+func (c quotaCounter) IncCounter(u *usagelib.UsageCountersProto) {
+	c.UsageController.IncCounter(u)
+	if c.tier.RequestLimit <= 0 {
+		return
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	if err := c.quota.Add(ctx, int64(u.Actions+u.Queries), c.tier.Clock.Now()); err != nil {
+		log.Printf("failed to add to the daily quota used by the tier: %v", err)
+	}
+}
+
+func setRateLimitHeaders(w http.ResponseWriter, r ratelimit.Result) {
+	w.Header().Set("X-RateLimit-Limit", strconv.FormatInt(r.Limit, 10))
+	w.Header().Set("X-RateLimit-Remaining", strconv.FormatInt(r.Remaining, 10))
+	w.Header().Set("X-RateLimit-Reset", strconv.FormatInt(int64(math.Ceil(r.Reset.Seconds())), 10))
+}
+
+func handleRateLimited(w http.ResponseWriter, r ratelimit.Result, err error) {
+	setRateLimitHeaders(w, r)
+	w.Header().Set("Retry-After", strconv.FormatInt(int64(math.Ceil(r.RetryAfter.Seconds())), 10))
+	handleTooManyRequests(w, "request rejected: ", err)
+}
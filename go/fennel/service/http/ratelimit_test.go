@@ -0,0 +1,77 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"fennel/lib/ratelimit"
+	usagelib "fennel/lib/usage"
+	"fennel/redis"
+	"fennel/resource"
+	"fennel/tier"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/raulk/clock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRateLimited(t *testing.T) {
+	ck := clock.NewMock()
+	mr, err := miniredis.Run()
+	require.NoError(t, err)
+	defer mr.Close()
+	client, err := redis.MiniRedisConfig{MiniRedis: mr, Scope: resource.NewTierScope(1)}.Materialize()
+	require.NoError(t, err)
+	tr := tier.Tier{Clock: ck, RequestLimit: -1, Redis: client.(redis.Client)}
+	tr.Args.RateLimitArgs = ratelimit.RateLimitArgs{QueryRateLimit: 1, RateLimitBurstSeconds: 2}
+	s := server{tier: tr, limiter: newLimiter(&tr), quota: ratelimit.NewDailyQuota(tr.Redis)}
+	handler := func(w http.ResponseWriter, req *http.Request) {}
+
+	do := func(endpoint, keyID string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("POST", "/query", nil)
+		req = req.WithContext(withKeyID(req.Context(), keyID))
+		w := httptest.NewRecorder()
+		s.rateLimited(endpoint, handler)(w, req)
+		return w
+	}
+	w := do(queryEndpoint, "")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "2", w.Header().Get("X-RateLimit-Limit"))
+	assert.Equal(t, "1", w.Header().Get("X-RateLimit-Remaining"))
+	assert.Equal(t, http.StatusOK, do(queryEndpoint, "").Code)
+	w = do(queryEndpoint, "")
+	assert.Equal(t, http.StatusTooManyRequests, w.Code)
+	assert.Equal(t, "1", w.Header().Get("Retry-After"))
+	assert.Equal(t, "0", w.Header().Get("X-RateLimit-Remaining"))
+
+	// each authenticated api key has its own bucket
+	assert.Equal(t, http.StatusOK, do(queryEndpoint, "000000000001").Code)
+	// other endpoints have their own limits
+	w = do(runQueryEndpoint, "")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Empty(t, w.Header().Get("X-RateLimit-Limit"))
+
+	ck.Add(time.Second)
+	assert.Equal(t, http.StatusOK, do(queryEndpoint, "").Code)
+
+	// tier-wide daily limit counts the actions & queries of all replicas across endpoints
+	s.tier.RequestLimit = 3
+	counter := quotaCounter{UsageController: nopUsage{}, tier: &s.tier, quota: ratelimit.NewDailyQuota(tr.Redis)}
+	counter.IncCounter(&usagelib.UsageCountersProto{Actions: 2})
+	assert.Equal(t, http.StatusOK, do(runQueryEndpoint, "").Code)
+	other := quotaCounter{UsageController: nopUsage{}, tier: &s.tier, quota: ratelimit.NewDailyQuota(tr.Redis)}
+	other.IncCounter(&usagelib.UsageCountersProto{Queries: 1})
+	w = do(logEndpoint, "")
+	assert.Equal(t, http.StatusTooManyRequests, w.Code)
+	assert.Equal(t, "3", w.Header().Get("X-RateLimit-Limit"))
+	// the quota is reset at the end of the day
+	ck.Add(24 * time.Hour)
+	assert.Equal(t, http.StatusOK, do(logEndpoint, "").Code)
+}
+
+type nopUsage struct{}
+
+func (nopUsage) IncCounter(*usagelib.UsageCountersProto) {}
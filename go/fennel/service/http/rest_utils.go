@@ -34,6 +34,7 @@ var ActionKeys = map[string]bool{
 	"timestamp":  false,
 	"requestId":  true,
 	"metadata":   false,
+	"dedupKey":   false,
 }
 
 func GetProfilesFromRest(data []byte) ([]profilelib.ProfileItem, error) {
@@ -48,20 +49,25 @@ func GetProfilesFromRest(data []byte) ([]profilelib.ProfileItem, error) {
 	return profiles, nil
 }
 
-func GetActionsFromRest(data []byte) ([]actionlib.Action, error) {
+// GetActionsFromRest returns the actions in the request along with their dedup keys, which are
+// empty for actions without one
+func GetActionsFromRest(data []byte) ([]actionlib.Action, []string, error) {
 	var request []restAction
 	if err := json.Unmarshal(data, &request); err != nil {
-		return nil, err
+		return nil, nil, err
 	}
 	actions := make([]actionlib.Action, len(request))
+	dedupKeys := make([]string, len(request))
 	for i, r := range request {
 		actions[i] = r.action
+		dedupKeys[i] = r.dedupKey
 	}
-	return actions, nil
+	return actions, dedupKeys, nil
 }
 
 type restAction struct {
-	action actionlib.Action
+	action   actionlib.Action
+	dedupKey string
 }
 
 func (a *restAction) UnmarshalJSON(data []byte) error {
@@ -74,6 +80,7 @@ func (a *restAction) UnmarshalJSON(data []byte) error {
 		ActionType ftypes.ActionType `json:"actionType"`
 		Timestamp  float64           `json:"timestamp"`
 		RequestID  json.RawMessage   `json:"requestId"`
+		DedupKey   string            `json:"dedupKey"`
 	}
 
 	err := verifyFields(data, ActionKeys)
@@ -106,6 +113,7 @@ func (a *restAction) UnmarshalJSON(data []byte) error {
 		return fmt.Errorf("error unmarshalling action json: %w", err)
 	}
 	a.action.RequestID = ftypes.RequestID(requestId)
+	a.dedupKey = fields.DedupKey
 
 	vdata, vtype, _, err := jsonparser.Get(data, "metadata")
 	if err != nil {
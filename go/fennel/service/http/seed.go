@@ -0,0 +1,61 @@
+package main
+
+import (
+	"fmt"
+	"math/rand"
+
+	"fennel/engine/interpreter/bootarg"
+	"fennel/lib/value"
+
+	"github.com/buger/jsonparser"
+)
+
+// querySeedHeader is set on the response of a query to the seed it was run with, running the
+// query again with the same seed reproduces the result
+const querySeedHeader = "X-Query-Seed"
+
+// requestSeed returns the optional "seed" of a run query request, or a new seed if it is not set
+func requestSeed(data []byte) (int64, error) {
+	v, vType, _, err := jsonparser.Get(data, "seed")
+	switch {
+	case err == jsonparser.KeyPathNotFoundError:
+		return newSeed(), nil
+	case err != nil:
+		return 0, err
+	case vType != jsonparser.Number:
+		return 0, fmt.Errorf("expected 'seed' to be an integer but found: '%s'", v)
+	}
+	seed, err := jsonparser.ParseInt(v)
+	if err != nil {
+		return 0, fmt.Errorf("expected 'seed' to be an integer but found: '%s'", v)
+	}
+	if seed == 0 {
+		return newSeed(), nil
+	}
+	return seed, nil
+}
+
+// argsSeed returns the seed set in the args of a query, or a new seed if it is not set
+func argsSeed(args value.Dict) (int64, error) {
+	v, ok := args.Get(bootarg.SEED_ARG)
+	if !ok {
+		return newSeed(), nil
+	}
+	seed, ok := v.(value.Int)
+	if !ok {
+		return 0, fmt.Errorf("expected '%s' to be an integer but found: '%s'", bootarg.SEED_ARG, v)
+	}
+	if seed == 0 {
+		return newSeed(), nil
+	}
+	return int64(seed), nil
+}
+
+// newSeed returns a random seed, seeds are never 0 since 0 stands for queries that are not seeded
+func newSeed() int64 {
+	for {
+		if seed := rand.Int63(); seed != 0 {
+			return seed
+		}
+	}
+}
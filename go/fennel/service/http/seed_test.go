@@ -0,0 +1,38 @@
+package main
+
+import (
+	"testing"
+
+	"fennel/lib/value"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRequestSeed(t *testing.T) {
+	seed, err := requestSeed([]byte(`{"name": "q", "seed": -42}`))
+	assert.NoError(t, err)
+	assert.Equal(t, int64(-42), seed)
+	for _, body := range []string{`{"name": "q"}`, `{"name": "q", "seed": 0}`} {
+		seed, err := requestSeed([]byte(body))
+		assert.NoError(t, err)
+		assert.NotEqual(t, int64(0), seed)
+	}
+	for _, body := range []string{
+		`{"name": "q", "seed": "42"}`,
+		`{"name": "q", "seed": 1.5}`,
+	} {
+		_, err := requestSeed([]byte(body))
+		assert.Error(t, err, body)
+	}
+}
+
+func TestArgsSeed(t *testing.T) {
+	seed, err := argsSeed(value.NewDict(map[string]value.Value{"__seed__": value.Int(42)}))
+	assert.NoError(t, err)
+	assert.Equal(t, int64(42), seed)
+	seed, err = argsSeed(value.NewDict(nil))
+	assert.NoError(t, err)
+	assert.NotEqual(t, int64(0), seed)
+	_, err = argsSeed(value.NewDict(map[string]value.Value{"__seed__": value.String("42")}))
+	assert.Error(t, err)
+}
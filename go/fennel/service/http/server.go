@@ -13,11 +13,9 @@ import (
 	"net/http"
 	_ "net/http/pprof"
 	"strconv"
-	"strings"
 	"time"
 
 	"fennel/controller/usage"
-	"fennel/redis"
 
 	"fennel/controller/action"
 	aggregate2 "fennel/controller/aggregate"
@@ -26,16 +24,24 @@ import (
 	"fennel/controller/modelstore"
 	profile2 "fennel/controller/profile"
 	query2 "fennel/controller/query"
+	"fennel/controller/querytest"
+	schema2 "fennel/controller/schema"
 	"fennel/engine"
+	"fennel/engine/functions"
 	"fennel/engine/interpreter/bootarg"
 	"fennel/engine/operators"
 	actionlib "fennel/lib/action"
 	"fennel/lib/aggregate"
+	"fennel/lib/budget"
 	"fennel/lib/data_integration"
+	"fennel/lib/dedup"
 	"fennel/lib/ftypes"
+	"fennel/lib/ingest"
 	profilelib "fennel/lib/profile"
 	"fennel/lib/query"
+	"fennel/lib/ratelimit"
 	"fennel/lib/sagemaker"
+	schemalib "fennel/lib/schema"
 	"fennel/lib/sql"
 	"fennel/lib/timer"
 	"fennel/lib/value"
@@ -51,8 +57,6 @@ import (
 	"github.com/prometheus/client_golang/prometheus/promauto"
 )
 
-const dedupTTL = 6 * time.Hour
-
 const EXT_REST_VERSION = "/v1"
 const INT_REST_VERSION = "/internal/v1"
 
@@ -80,6 +84,14 @@ var totalDedupedActions = promauto.NewCounterVec(
 	[]string{"path", "action_type"},
 )
 
+var totalPossibleDuplicateActions = promauto.NewCounterVec(
+	prometheus.CounterOpts{
+		Name: "possible_duplicate_actions_total",
+		Help: "Total number of actions logged while dedup was degraded that may be duplicates.",
+	},
+	[]string{"path", "action_type"},
+)
+
 var totalUnleashQueryRequestsDropped = promauto.NewCounter(
 	prometheus.CounterOpts{
 		Name: "unleash_query_request_dropped",
@@ -104,6 +116,10 @@ func readRequest(req *http.Request) ([]byte, error) {
 type server struct {
 	tier            tier.Tier
 	usageController usage.UsageController
+	keys            *keySet
+	limiter         *ratelimit.Limiter
+	quota           ratelimit.DailyQuota
+	deduper         *dedup.Deduper
 }
 
 func (s server) Close() {
@@ -112,38 +128,17 @@ func (s server) Close() {
 func NewServer(tier *tier.Tier, usageController usage.UsageController) *server {
 	return &server{
 		tier:            *tier,
-		usageController: usageController,
-	}
-}
-
-func (s *server) LimitFunc(ctx context.Context, rateLimit int64) bool {
-	if rateLimit <= 0 {
-		return true
-	}
-	endTime := uint64(s.tier.Clock.Now().Unix())
-	startTime := usagelib.DailyFold(endTime)
-	return func() int64 {
-		counter, err := usagemodel.GetUsageCounters(ctx, s.tier, startTime, endTime)
-		if err != nil {
-			log.Printf("failed to get usage counters from db: %s", err)
-			return 0
-		}
-		return int64(counter.Queries + counter.Actions)
-	}() <= rateLimit
-}
-
-func (s *server) SetRateLimit(handler func(http.ResponseWriter, *http.Request)) func(http.ResponseWriter, *http.Request) {
-	log.Printf("request limit for tier is %d", s.tier.RequestLimit)
-	return func(res http.ResponseWriter, req *http.Request) {
-		if s.LimitFunc(req.Context(), s.tier.RequestLimit) {
-			handler(res, req)
-		} else {
-			handleTooManyRequests(res, "request rejected: ", fmt.Errorf("number of requests in the day more than the limit of %d", s.tier.RequestLimit))
-		}
+		usageController: quotaCounter{UsageController: usageController, tier: tier, quota: ratelimit.NewDailyQuota(tier.Redis)},
+		keys:            newKeySet(),
+		limiter:         newLimiter(tier),
+		quota:           ratelimit.NewDailyQuota(tier.Redis),
+		deduper:         newDeduper(tier),
 	}
 }
 
 func (s server) setHandlers(router *mux.Router) {
+	router.Use(s.authenticate)
+
 	// OLDER END POINTS WILL BE DEPRECATED
 
 	// Endpoints used by python client
@@ -151,14 +146,16 @@ func (s server) setHandlers(router *mux.Router) {
 	router.HandleFunc("/get", s.GetProfile)
 	router.HandleFunc("/set", s.SetProfile)
 	router.HandleFunc("/set_profiles", s.SetProfiles)
-	router.HandleFunc("/log", s.Log)
-	router.HandleFunc("/log_multi", s.SetRateLimit(s.LogMulti))
+	router.HandleFunc("/log", s.rateLimited(logEndpoint, s.Log))
+	router.HandleFunc("/log_multi", s.rateLimited(logEndpoint, s.LogMulti))
 	router.HandleFunc("/get_multi", s.GetProfileMulti)
-	router.HandleFunc("/query", s.SetRateLimit(s.Query))
+	router.HandleFunc("/query", s.rateLimited(queryEndpoint, s.Query))
 	router.HandleFunc("/store_query", s.StoreQuery)
 	router.HandleFunc("/get_operators", s.GetOperators)
-	router.HandleFunc("/run_query", s.SetRateLimit(s.RunQuery))
+	router.HandleFunc("/run_query", s.rateLimited(runQueryEndpoint, s.RunQuery))
 	router.HandleFunc("/queries", s.ListQueries)
+	router.HandleFunc("/store_schema", s.StoreSchema)
+	router.HandleFunc("/schemas", s.ListSchemas)
 
 	// Endpoints used by aggregate
 	router.HandleFunc("/store_aggregate", s.StoreAggregate)
@@ -179,10 +176,24 @@ func (s server) setHandlers(router *mux.Router) {
 	router.HandleFunc(INT_REST_VERSION+"/profiles", s.GetProfileMulti).Methods("GET")
 	router.HandleFunc(INT_REST_VERSION+"/query_profiles", s.QueryProfiles).Methods("POST")
 	router.HandleFunc(INT_REST_VERSION+"/profiles", s.SetProfiles).Methods("POST")
-	router.HandleFunc(INT_REST_VERSION+"/log", s.SetRateLimit(s.LogMulti)).Methods("POST")
-
-	router.HandleFunc(INT_REST_VERSION+"/query", s.SetRateLimit(s.Query))
+	router.HandleFunc(INT_REST_VERSION+"/profiles/history", s.GetProfileHistory).Methods("GET")
+	router.HandleFunc(INT_REST_VERSION+"/profiles/policy", s.ListProfilePolicies).Methods("GET")
+	router.HandleFunc(INT_REST_VERSION+"/profiles/policy", s.SetProfilePolicy).Methods("POST")
+	router.HandleFunc(INT_REST_VERSION+"/profiles/policy", s.DeleteProfilePolicy).Methods("DELETE")
+	router.HandleFunc(INT_REST_VERSION+"/profiles/changes", s.GetProfileChanges).Methods("GET")
+	router.HandleFunc(INT_REST_VERSION+"/profiles/jobs", s.StartProfileJob).Methods("POST")
+	router.HandleFunc(INT_REST_VERSION+"/profiles/jobs", s.GetProfileJobs).Methods("GET")
+	router.HandleFunc(INT_REST_VERSION+"/log", s.rateLimited(logEndpoint, s.LogMulti)).Methods("POST")
+
+	router.HandleFunc(INT_REST_VERSION+"/query", s.rateLimited(queryEndpoint, s.Query))
 	router.HandleFunc(INT_REST_VERSION+"/query/store", s.StoreQuery).Methods("POST")
+	router.HandleFunc(INT_REST_VERSION+"/query/test", s.rateLimited(queryEndpoint, s.TestQuery)).Methods("POST")
+	router.HandleFunc(INT_REST_VERSION+"/query/versions", s.ListQueryVersions).Methods("GET")
+	router.HandleFunc(INT_REST_VERSION+"/query/alias", s.ListQueryAliases).Methods("GET")
+	router.HandleFunc(INT_REST_VERSION+"/query/alias", s.SetQueryAlias).Methods("POST")
+	router.HandleFunc(INT_REST_VERSION+"/query/alias/rollback", s.RollbackQueryAlias).Methods("POST")
+	router.HandleFunc(INT_REST_VERSION+"/query/budget", s.GetQueryBudget).Methods("GET")
+	router.HandleFunc(INT_REST_VERSION+"/query/budget", s.SetQueryBudget).Methods("POST")
 
 	// Endpoints used by aggregate
 	router.HandleFunc(INT_REST_VERSION+"/aggregate", s.StoreAggregate).Methods("POST")
@@ -203,30 +214,27 @@ func (s server) setHandlers(router *mux.Router) {
 	router.HandleFunc(INT_REST_VERSION+"/source", s.StoreSource).Methods("POST")
 	router.HandleFunc(INT_REST_VERSION+"/source", s.DeleteSource).Methods("DELETE")
 
+	// Endpoints used for the schema registry
+	router.HandleFunc(INT_REST_VERSION+"/schema", s.StoreSchema).Methods("POST")
+	router.HandleFunc(INT_REST_VERSION+"/schema", s.ListSchemas).Methods("GET")
+	router.HandleFunc(INT_REST_VERSION+"/schema", s.DeleteSchema).Methods("DELETE")
+	router.HandleFunc(INT_REST_VERSION+"/schema/quarantine", s.GetQuarantined).Methods("GET")
+
+	// Endpoints used by the mothership
+	router.HandleFunc(INT_REST_VERSION+"/api_keys", s.SyncApiKeys).Methods("POST")
+
 	// Misc endpoints
 	router.HandleFunc(INT_REST_VERSION+"/operators", s.GetOperators).Methods("GET")
+	router.HandleFunc(INT_REST_VERSION+"/functions", s.GetFunctions).Methods("GET")
 
 	// ----------------------------------External Endpoints-----------------------------------------------
 
-	router.HandleFunc(EXT_REST_VERSION+"/actions", s.SetRateLimit(s.LogActions))
+	router.HandleFunc(EXT_REST_VERSION+"/actions", s.rateLimited(logEndpoint, s.LogActions))
 	router.HandleFunc(EXT_REST_VERSION+"/profiles", s.LogProfiles)
-	router.HandleFunc(EXT_REST_VERSION+"/query", s.SetRateLimit(s.RunQuery))
+	router.HandleFunc(EXT_REST_VERSION+"/query", s.rateLimited(runQueryEndpoint, s.RunQuery))
 	router.HandleFunc(EXT_REST_VERSION+"/usage_counters", s.GetusageCounters)
 }
 
-func constructDedupKey(dedupKey string, actionType ftypes.ActionType) string {
-	// add action type to the `dedupKey`, so that the actions are deduplicated at the
-	// granularity of action type
-	//
-	// NOTE: It is possible that the incoming requests could have handled this explicitly,
-	// we add the action type as a sanity check
-	var b strings.Builder
-	b.WriteString(dedupKey)
-	b.WriteString(":")
-	b.WriteString(string(actionType))
-	return b.String()
-}
-
 func (m server) Log(w http.ResponseWriter, req *http.Request) {
 	data, err := readRequest(req)
 	if err != nil {
@@ -246,25 +254,30 @@ func (m server) Log(w http.ResponseWriter, req *http.Request) {
 
 	incomingActions.WithLabelValues("log", string(a.ActionType)).Inc()
 
+	valid, err := schema2.CheckActions(req.Context(), m.tier, []actionlib.Action{a})
+	if err != nil {
+		handleSchemaError(w, err)
+		return
+	}
+	if !valid[0] {
+		// the action was quarantined
+		handleSuccessfulRequest(w)
+		return
+	}
+
 	dedupKey, err := jsonparser.GetString(data, "DedupKey")
 	if err != nil {
 		handleBadRequest(w, "invalid request: ", err)
 		return
 	}
-	// If dedupKey is non-empty, request is ignored if dedupKey is already present in redis.
-	// Try to set if it does not exist. If it succeeds, proceed normally.
-	// Otherwise, drop request.
-	if dedupKey != "" {
-		ok, err := m.tier.Redis.SetNX(req.Context(), constructDedupKey(dedupKey, a.ActionType), 1, dedupTTL)
-		if err != nil {
-			handleInternalServerError(w, "", err)
-			return
-		}
-		if !ok {
-			totalDedupedActions.WithLabelValues("log", string(a.ActionType)).Inc()
-			return
-		}
+	// If dedupKey is non-empty, request is ignored if an action with the same dedupKey was logged
+	// within the dedup window of its action type
+	batch, _ := m.dedupActions(req.Context(), "log", []actionlib.Action{a}, []string{dedupKey})
+	if len(batch) == 0 {
+		handleSuccessfulRequest(w)
+		return
 	}
+	a = batch[0]
 	// fwd to controller
 	if err = action.Insert(req.Context(), m.tier, a); err != nil {
 		handleInternalServerError(w, "", err)
@@ -277,69 +290,74 @@ func (m server) Log(w http.ResponseWriter, req *http.Request) {
 	handleSuccessfulRequest(w)
 }
 
+// LogMulti logs a batch of actions, each with an optional dedup key, and responds with the status of
+// each action
 func (m server) LogMulti(w http.ResponseWriter, req *http.Request) {
 	data, err := readRequest(req)
 	if err != nil {
 		handleBadRequest(w, "", err)
 		return
 	}
-	var actions []actionlib.Action
-	var dedupItems []struct{ DedupKey string }
-	if err := json.Unmarshal(data, &actions); err != nil {
+	var items []json.RawMessage
+	if err := json.Unmarshal(data, &items); err != nil {
 		http.Error(w, fmt.Sprintf("invalid request: %v; no action was logged", err), http.StatusBadRequest)
 		log.Printf("Error: %v", err)
 		return
 	}
+	m.logBatch(w, req, "log_multi", func(ctx context.Context, opts ingest.BatchOptions) (ingest.BatchResult, error) {
+		return m.logActionItems(ctx, items, opts.Atomic)
+	})
+}
 
-	for _, a := range actions {
-		incomingActions.WithLabelValues("log_multi", string(a.ActionType)).Inc()
-	}
-
-	if err := json.Unmarshal(data, &dedupItems); err != nil {
-		http.Error(w, fmt.Sprintf("invalid request: %v; no action was logged", err), http.StatusBadRequest)
-		log.Printf("Error: %v", err)
-		return
-	}
-	var batch []actionlib.Action
-	var keys []string
-	var vals []interface{}
-	var ttls []time.Duration
+// logActionItems parses, validates, dedups and logs the actions, invalid actions are skipped
+func (m server) logActionItems(ctx context.Context, items []json.RawMessage, atomic bool) (ingest.BatchResult, error) {
+	result := ingest.NewBatchResult(len(items))
+	var actions []actionlib.Action
+	var dedupKeys []string
 	var ids []int
-	for i, d := range dedupItems {
-		if d.DedupKey == "" {
-			// If dedup key is empty, add action to batch directly
-			batch = append(batch, actions[i])
-		} else {
-			// otherwise, store them for duplication check
-			keys = append(keys, constructDedupKey(d.DedupKey, actions[i].ActionType))
-			vals = append(vals, 1)
-			ttls = append(ttls, dedupTTL)
-			ids = append(ids, i)
+	for i, item := range items {
+		var a actionlib.Action
+		var dedupItem struct{ DedupKey string }
+		if err := json.Unmarshal(item, &a); err != nil {
+			result.SetInvalid(i, err)
+			continue
 		}
-	}
-
-	// Check for dedup with a pipeline
-	// TODO: Better variable name for ok
-	ok, err := m.tier.Redis.SetNXPipelined(req.Context(), keys, vals, ttls)
-	if err != nil {
-		handleInternalServerError(w, "", err)
-		return
-	}
-
-	for i := range ok {
-		// if redis set command failed for whatever reason (this seem to be common when any one
-		// of the shard is full), instead of dropping it, use it
-		if ok[i] == redis.NotFoundSet || ok[i] == redis.Error {
-			// If dedup key of an action was not set, add to batch
-			batch = append(batch, actions[ids[i]])
-		} else {
-			totalDedupedActions.WithLabelValues("log_multi", string(actions[ids[i]].ActionType)).Inc()
+		if err := json.Unmarshal(item, &dedupItem); err != nil {
+			result.SetInvalid(i, err)
+			continue
+		}
+		if err := a.Validate(); err != nil {
+			result.SetInvalid(i, err)
+			continue
+		}
+		incomingActions.WithLabelValues("log_multi", string(a.ActionType)).Inc()
+		actions = append(actions, a)
+		dedupKeys = append(dedupKeys, dedupItem.DedupKey)
+		ids = append(ids, i)
+	}
+	errs, err := schema2.CheckActionsEach(ctx, m.tier, actions, atomic)
+	if err != nil {
+		return result, err
+	}
+	valid := setInvalidRecords(&result, errs, ids)
+	if atomic && result.Invalid > 0 {
+		result.Abort()
+		return result, nil
+	}
+	// invalid actions are dropped along with their dedup keys
+	actions, dedupKeys, ids = filterValid(actions, valid), filterValid(dedupKeys, valid), filterValid(ids, valid)
+	batch, statuses := m.dedupActions(ctx, "log_multi", actions, dedupKeys)
+	for j, status := range statuses {
+		switch status {
+		case dedup.Duplicate:
+			result.SetDuplicate(ids[j])
+		case dedup.PossibleDuplicate:
+			result.SetPossibleDuplicate(ids[j])
 		}
 	}
 	// fwd to controller
-	if err = action.BatchInsert(req.Context(), m.tier, batch); err != nil {
-		handleInternalServerError(w, "", err)
-		return
+	if err = action.BatchInsert(ctx, m.tier, batch); err != nil {
+		return result, err
 	}
 	// increment metrics after successfully writing to the system
 	for _, a := range batch {
@@ -348,7 +366,7 @@ func (m server) LogMulti(w http.ResponseWriter, req *http.Request) {
 	m.usageController.IncCounter(&usagelib.UsageCountersProto{
 		Actions: uint64(len(actions)),
 	})
-	handleSuccessfulRequest(w)
+	return result, nil
 }
 
 func (m server) LogActions(w http.ResponseWriter, req *http.Request) {
@@ -358,20 +376,27 @@ func (m server) LogActions(w http.ResponseWriter, req *http.Request) {
 		return
 	}
 
-	actions, err := GetActionsFromRest(data)
+	actions, dedupKeys, err := GetActionsFromRest(data)
 	if err != nil {
 		http.Error(w, fmt.Sprintf("invalid request: %v; no action was logged", err), http.StatusBadRequest)
 		log.Printf("Error: %v", err)
 		return
 	}
+	valid, err := schema2.CheckActions(req.Context(), m.tier, actions)
+	if err != nil {
+		handleSchemaError(w, err)
+		return
+	}
+	actions, dedupKeys = filterValid(actions, valid), filterValid(dedupKeys, valid)
+	batch, _ := m.dedupActions(req.Context(), "actions", actions, dedupKeys)
 
 	// fwd to controller
-	if err = action.BatchInsert(req.Context(), m.tier, actions); err != nil {
+	if err = action.BatchInsert(req.Context(), m.tier, batch); err != nil {
 		handleInternalServerError(w, "", err)
 		return
 	}
 	// increment metrics after successfully writing to the system
-	for _, a := range actions {
+	for _, a := range batch {
 		totalActions.WithLabelValues("log_multi", string(a.ActionType)).Inc()
 	}
 
@@ -446,6 +471,16 @@ func (m server) SetProfile(w http.ResponseWriter, req *http.Request) {
 		handleBadRequest(w, "invalid request: ", err)
 		return
 	}
+	valid, err := schema2.CheckProfiles(req.Context(), m.tier, []profilelib.ProfileItem{request})
+	if err != nil {
+		handleSchemaError(w, err)
+		return
+	}
+	if !valid[0] {
+		// the profile was quarantined
+		handleSuccessfulRequest(w)
+		return
+	}
 	// send to controller
 	if err = profile2.Set(req.Context(), m.tier, request); err != nil {
 		handleInternalServerError(w, "", err)
@@ -454,23 +489,55 @@ func (m server) SetProfile(w http.ResponseWriter, req *http.Request) {
 	handleSuccessfulRequest(w)
 }
 
+// SetProfiles sets a batch of profiles and responds with the status of each profile
 func (m server) SetProfiles(w http.ResponseWriter, req *http.Request) {
 	data, err := readRequest(req)
 	if err != nil {
 		handleBadRequest(w, "", err)
 		return
 	}
-	var request []profilelib.ProfileItem
-	if err := json.Unmarshal(data, &request); err != nil {
+	var items []json.RawMessage
+	if err := json.Unmarshal(data, &items); err != nil {
 		handleBadRequest(w, "invalid request: ", err)
 		return
 	}
+	m.logBatch(w, req, "set_profiles", func(ctx context.Context, opts ingest.BatchOptions) (ingest.BatchResult, error) {
+		return m.setProfileItems(ctx, items, opts.Atomic)
+	})
+}
+
+// setProfileItems parses, validates and sets the profiles, invalid profiles are skipped
+func (m server) setProfileItems(ctx context.Context, items []json.RawMessage, atomic bool) (ingest.BatchResult, error) {
+	result := ingest.NewBatchResult(len(items))
+	var profiles []profilelib.ProfileItem
+	var ids []int
+	for i, item := range items {
+		var p profilelib.ProfileItem
+		if err := json.Unmarshal(item, &p); err != nil {
+			result.SetInvalid(i, err)
+			continue
+		}
+		if err := p.Validate(); err != nil {
+			result.SetInvalid(i, err)
+			continue
+		}
+		profiles = append(profiles, p)
+		ids = append(ids, i)
+	}
+	errs, err := schema2.CheckProfilesEach(ctx, m.tier, profiles, atomic)
+	if err != nil {
+		return result, err
+	}
+	valid := setInvalidRecords(&result, errs, ids)
+	if atomic && result.Invalid > 0 {
+		result.Abort()
+		return result, nil
+	}
 	// send to controller
-	if err = profile2.SetMulti(req.Context(), m.tier, request); err != nil {
-		handleInternalServerError(w, "", err)
-		return
+	if err = profile2.SetMulti(ctx, m.tier, filterValid(profiles, valid)); err != nil {
+		return result, err
 	}
-	handleSuccessfulRequest(w)
+	return result, nil
 }
 
 func (m server) LogProfiles(w http.ResponseWriter, req *http.Request) {
@@ -484,6 +551,12 @@ func (m server) LogProfiles(w http.ResponseWriter, req *http.Request) {
 		handleInternalServerError(w, "", err)
 		return
 	}
+	valid, err := schema2.CheckProfiles(req.Context(), m.tier, profiles)
+	if err != nil {
+		handleSchemaError(w, err)
+		return
+	}
+	profiles = filterValid(profiles, valid)
 	// send to controller
 	if err = profile2.SetMulti(req.Context(), m.tier, profiles); err != nil {
 		handleInternalServerError(w, "", err)
@@ -581,18 +654,53 @@ func (m server) Query(w http.ResponseWriter, req *http.Request) {
 			}
 		}()
 	}
+	seed, err := argsSeed(args)
+	if err != nil {
+		handleBadRequest(w, "", err)
+		return
+	}
 	// execute the tree
-	executor := engine.NewQueryExecutor(bootarg.Create(m.tier))
+	bootargs := bootarg.Create(m.tier)
+	bootarg.SetSeed(bootargs, seed)
+	bootarg.SetBudget(bootargs, m.tier.Args.BudgetArgs.Default())
+	executor := engine.NewQueryExecutor(bootargs)
 	ret, err := executor.Exec(cCtx, tree, args)
 	if err != nil {
-		handleInternalServerError(w, "", err)
+		handleQueryError(w, err)
 		return
 	}
 	m.usageController.IncCounter(&usagelib.UsageCountersProto{Queries: 1})
+	w.Header().Set(querySeedHeader, strconv.FormatInt(seed, 10))
 	_, _ = w.Write(value.ToJSON(ret))
 
 }
 
+// TestQuery runs the cases of a query test with the mocked operators swapped in and returns the
+// result of each case, failing cases are reported in the response and not as an error
+func (m server) TestQuery(w http.ResponseWriter, req *http.Request) {
+	data, err := readRequest(req)
+	if err != nil {
+		handleBadRequest(w, "", err)
+		return
+	}
+	suite, err := querytest.Parse(data)
+	if err != nil {
+		handleBadRequest(w, "", err)
+		return
+	}
+	report, err := querytest.Run(req.Context(), m.tier, suite)
+	if err != nil {
+		handleBadRequest(w, "", err)
+		return
+	}
+	ser, err := json.Marshal(report)
+	if err != nil {
+		handleInternalServerError(w, "", err)
+		return
+	}
+	_, _ = w.Write(ser)
+}
+
 func (m server) ListQueries(w http.ResponseWriter, req *http.Request) {
 	queries, err := query2.List(req.Context(), m.tier)
 	if err != nil {
@@ -633,13 +741,27 @@ func (m server) StoreQuery(w http.ResponseWriter, req *http.Request) {
 		handleBadRequest(w, "", err)
 		return
 	}
-	_, err = query2.Insert(req.Context(), m.tier, name, q, description)
+	var params query.Params
+	if pData, _, _, err := jsonparser.Get(data, "params"); err == nil {
+		if err = json.Unmarshal(pData, &params); err != nil {
+			handleBadRequest(w, "invalid params: ", err)
+			return
+		}
+	} else if err != jsonparser.KeyPathNotFoundError {
+		handleBadRequest(w, "", err)
+		return
+	}
+	if err = params.Validate(); err != nil {
+		handleBadRequest(w, "invalid params: ", err)
+		return
+	}
+	version, err := query2.Insert(req.Context(), m.tier, name, q, description, params)
 	if err != nil {
 		handleInternalServerError(w, "", err)
 		return
 	}
-	// if storing succeeds, just return empty response
-	handleSuccessfulRequest(w)
+	// if storing succeeds, return the version of the query
+	_, _ = w.Write([]byte(fmt.Sprintf(`{"version":%d}`, version)))
 }
 
 func (m server) GetusageCounters(w http.ResponseWriter, req *http.Request) {
@@ -728,19 +850,45 @@ func (m server) RunQuery(w http.ResponseWriter, req *http.Request) {
 			return
 		}
 	}
-	tree, err := query2.Get(req.Context(), m.tier, name)
+	ref, err := versionRef(data)
+	if err != nil {
+		handleBadRequest(w, "", err)
+		return
+	}
+	seed, err := requestSeed(data)
+	if err != nil {
+		handleBadRequest(w, "", err)
+		return
+	}
+	tree, args, version, err := query2.Bind(req.Context(), m.tier, name, ref, args)
+	if err != nil {
+		var invalid query2.InvalidArgsError
+		if errors.As(err, &invalid) {
+			handleBadRequest(w, "", err)
+			return
+		}
+		handleInternalServerError(w, "", err)
+		return
+	}
+	b, err := query2.RunBudget(req.Context(), m.tier, name)
 	if err != nil {
 		handleInternalServerError(w, "", err)
 		return
 	}
 	// execute the tree
-	executor := engine.NewQueryExecutor(bootarg.Create(m.tier))
+	bootargs := bootarg.Create(m.tier)
+	bootarg.SetQueryVersion(bootargs, bootarg.QueryVersion{Name: name, Version: version})
+	bootarg.SetSeed(bootargs, seed)
+	bootarg.SetBudget(bootargs, b)
+	executor := engine.NewQueryExecutor(bootargs)
 	ret, err := executor.Exec(req.Context(), tree, args)
 	if err != nil {
-		handleInternalServerError(w, "", err)
+		handleQueryError(w, err)
 		return
 	}
 	m.usageController.IncCounter(&usagelib.UsageCountersProto{Queries: 1})
+	w.Header().Set(queryVersionHeader, strconv.FormatUint(uint64(version), 10))
+	w.Header().Set(querySeedHeader, strconv.FormatInt(seed, 10))
 	_, _ = w.Write(value.ToJSON(ret))
 }
 
@@ -939,6 +1087,105 @@ func (m server) DeleteSource(w http.ResponseWriter, req *http.Request) {
 	}
 }
 
+func (m server) StoreSchema(w http.ResponseWriter, req *http.Request) {
+	data, err := readRequest(req)
+	if err != nil {
+		handleBadRequest(w, "", err)
+		return
+	}
+	var sch schemalib.Schema
+	if err := json.Unmarshal(data, &sch); err != nil {
+		handleBadRequest(w, "invalid request: ", err)
+		return
+	}
+	if err := sch.Validate(); err != nil {
+		handleBadRequest(w, "invalid schema: ", err)
+		return
+	}
+	// call controller
+	stored, err := schema2.Store(req.Context(), m.tier, sch)
+	if err != nil {
+		var invalid schema2.InvalidEvolutionError
+		if errors.As(err, &invalid) {
+			handleBadRequest(w, "", err)
+			return
+		}
+		handleInternalServerError(w, "", err)
+		return
+	}
+	ser, err := json.Marshal(stored)
+	if err != nil {
+		handleInternalServerError(w, "", err)
+		return
+	}
+	_, _ = w.Write(ser)
+}
+
+func (m server) ListSchemas(w http.ResponseWriter, req *http.Request) {
+	schemas, err := schema2.List(req.Context(), m.tier)
+	if err != nil {
+		handleInternalServerError(w, "", err)
+		return
+	}
+	ser, err := json.Marshal(schemas)
+	if err != nil {
+		handleInternalServerError(w, "", err)
+		return
+	}
+	_, _ = w.Write(ser)
+}
+
+func (m server) DeleteSchema(w http.ResponseWriter, req *http.Request) {
+	data, err := readRequest(req)
+	if err != nil {
+		handleBadRequest(w, "", err)
+		return
+	}
+	var schemaReq struct {
+		Name string `json:"Name"`
+	}
+	if err := json.Unmarshal(data, &schemaReq); err != nil {
+		handleBadRequest(w, "invalid request: ", err)
+		return
+	}
+	if err = schema2.Delete(req.Context(), m.tier, schemaReq.Name); err != nil {
+		if errors.Is(err, schemalib.ErrNotFound) {
+			handleBadRequest(w, "", fmt.Errorf("schema '%s' not found", schemaReq.Name))
+			return
+		}
+		handleInternalServerError(w, "", err)
+		return
+	}
+	handleSuccessfulRequest(w)
+}
+
+func (m server) GetQuarantined(w http.ResponseWriter, req *http.Request) {
+	data, err := readRequest(req)
+	if err != nil {
+		handleBadRequest(w, "", err)
+		return
+	}
+	schemaReq := struct {
+		Name  string `json:"Name"`
+		Limit uint64 `json:"Limit"`
+	}{Limit: 100}
+	if err := json.Unmarshal(data, &schemaReq); err != nil {
+		handleBadRequest(w, "invalid request: ", err)
+		return
+	}
+	records, err := schema2.RetrieveQuarantined(req.Context(), m.tier, schemaReq.Name, schemaReq.Limit)
+	if err != nil {
+		handleInternalServerError(w, "", err)
+		return
+	}
+	ser, err := json.Marshal(records)
+	if err != nil {
+		handleInternalServerError(w, "", err)
+		return
+	}
+	_, _ = w.Write(ser)
+}
+
 func (m server) AggregateValue(w http.ResponseWriter, req *http.Request) {
 	data, err := readRequest(req)
 	if err != nil {
@@ -1092,6 +1339,15 @@ func (m server) GetOperators(w http.ResponseWriter, req *http.Request) {
 	_, _ = w.Write(data)
 }
 
+func (m server) GetFunctions(w http.ResponseWriter, req *http.Request) {
+	data, err := functions.GetFunctionsJSON()
+	if err != nil {
+		handleInternalServerError(w, "", err)
+		return
+	}
+	_, _ = w.Write(data)
+}
+
 // handleSuccessfulRequest explicitly writes `StatusOk` to the ResponseWriter instance.
 //
 // this should be used for methods which do not write anything back as part of the response body i.e. do not call
@@ -1100,6 +1356,38 @@ func handleSuccessfulRequest(w http.ResponseWriter) {
 	w.WriteHeader(http.StatusOK)
 }
 
+// handleSchemaError responds with a bad request if records did not satisfy their schema
+func handleSchemaError(w http.ResponseWriter, err error) {
+	var invalid schema2.InvalidRecordError
+	if errors.As(err, &invalid) {
+		http.Error(w, fmt.Sprintf("invalid request: %v; no record was logged", err), http.StatusBadRequest)
+		log.Printf("Error: %v", err)
+		return
+	}
+	handleInternalServerError(w, "", err)
+}
+
+// handleQueryError responds with a bad request if the query exceeded its budget
+func handleQueryError(w http.ResponseWriter, err error) {
+	var exceeded budget.ExceededError
+	if errors.As(err, &exceeded) {
+		handleBadRequest(w, "query exceeded its budget: ", err)
+		return
+	}
+	handleInternalServerError(w, "", err)
+}
+
+// filterValid returns the items that are marked valid
+func filterValid[T any](items []T, valid []bool) []T {
+	ret := make([]T, 0, len(items))
+	for i, item := range items {
+		if valid[i] {
+			ret = append(ret, item)
+		}
+	}
+	return ret
+}
+
 func handleBadRequest(w http.ResponseWriter, errorPrefix string, err error) {
 	http.Error(w, fmt.Sprintf("%s%v", errorPrefix, err), http.StatusBadRequest)
 	log.Printf("Error: %v", err)
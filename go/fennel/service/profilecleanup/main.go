@@ -0,0 +1,80 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"math/rand"
+	"time"
+
+	profile2 "fennel/controller/profile"
+	libprofile "fennel/lib/profile"
+	"fennel/model/profile"
+	"fennel/tier"
+
+	"github.com/alexflint/go-arg"
+	"go.uber.org/zap"
+)
+
+type ProfileCleanupArgs struct {
+	BatchSize int `arg:"--batch_size,env:BATCH_SIZE" default:"1000" json:"batch_size,omitempty"`
+}
+
+// cleanup enforces the TTL and history retention of each profile policy
+func cleanup(tr tier.Tier, batchSize int) error {
+	ctx := context.Background()
+	policies, err := profile.RetrievePolicies(ctx, tr)
+	if err != nil {
+		return err
+	}
+	now := tr.Clock.Now()
+	for _, p := range policies {
+		var exclude []string
+		if p.Key == libprofile.AnyKey {
+			exclude = policies.Overrides(p.OType)
+		}
+		if p.TTL > 0 {
+			cutoff := now.Add(-time.Duration(p.TTL) * time.Second)
+			total := 0
+			for {
+				n, err := profile2.DeleteExpired(ctx, tr, p.OType, p.Key, exclude, cutoff, batchSize)
+				if err != nil {
+					return fmt.Errorf("failed to delete expired profiles of [%s, %s]: %w", p.OType, p.Key, err)
+				}
+				total += n
+				if n == 0 {
+					break
+				}
+			}
+			tr.Logger.Info("deleted expired profiles", zap.String("otype", string(p.OType)), zap.String("key", p.Key), zap.Int("count", total))
+		}
+		if p.HistoryRetention > 0 {
+			cutoff := uint64(now.Add(-time.Duration(p.HistoryRetention) * time.Second).UnixMicro())
+			n, err := profile.DeleteHistory(ctx, tr, p.OType, p.Key, exclude, cutoff)
+			if err != nil {
+				return fmt.Errorf("failed to delete profile history of [%s, %s]: %w", p.OType, p.Key, err)
+			}
+			tr.Logger.Info("deleted profile history", zap.String("otype", string(p.OType)), zap.String("key", p.Key), zap.Int64("count", n))
+		}
+	}
+	return nil
+}
+
+func main() {
+	// seed random number generator so that all uses of rand work well
+	rand.Seed(time.Now().UnixNano())
+	var flags struct {
+		tier.TierArgs
+		ProfileCleanupArgs
+	}
+	arg.MustParse(&flags)
+	log.SetFlags(log.LstdFlags | log.Lshortfile)
+
+	tier, err := tier.CreateFromArgs(&flags.TierArgs)
+	if err != nil {
+		panic(fmt.Sprintf("Failed to setup tier connectors: %v", err))
+	}
+	if err = cleanup(tier, flags.BatchSize); err != nil {
+		panic(err)
+	}
+}
@@ -0,0 +1,43 @@
+package main
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	libprofile "fennel/lib/profile"
+	"fennel/lib/value"
+	"fennel/model/profile"
+	"fennel/test"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCleanup(t *testing.T) {
+	tier := test.Tier(t)
+	defer test.Teardown(tier)
+	ctx := context.Background()
+
+	assert.NoError(t, profile.SetPolicy(ctx, tier, libprofile.Policy{OType: "user", Key: libprofile.AnyKey, TTL: 60}))
+	// keys with their own policy are not governed by the policy of the otype
+	assert.NoError(t, profile.SetPolicy(ctx, tier, libprofile.Policy{OType: "user", Key: "name", TTL: 0}))
+
+	now := tier.Clock.Now()
+	old := uint64(now.Add(-2 * time.Minute).UnixMicro())
+	recent := uint64(now.UnixMicro())
+	items := []libprofile.ProfileItem{
+		libprofile.NewProfileItem("user", "1", "age", value.Int(1), old),
+		libprofile.NewProfileItem("user", "2", "age", value.Int(2), recent),
+		libprofile.NewProfileItem("user", "1", "name", value.String("a"), old),
+		libprofile.NewProfileItem("video", "1", "age", value.Int(3), old),
+	}
+	assert.NoError(t, profile.SetBatch(ctx, tier, items))
+
+	assert.NoError(t, cleanup(tier, 1))
+	expected := []value.Value{value.Nil, value.Int(2), value.String("a"), value.Int(3)}
+	for i, item := range items {
+		found, err := profile.Get(ctx, tier, libprofile.NewProfileItemKey(item.OType, item.Oid, item.Key))
+		assert.NoError(t, err)
+		assert.Equal(t, expected[i], found.Value)
+	}
+}
@@ -0,0 +1,150 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"fennel/controller/querytest"
+	"fennel/lib/value"
+	_ "fennel/opdefs"
+	"fennel/tier"
+
+	"github.com/alexflint/go-arg"
+)
+
+type QueryTestArgs struct {
+	Inputs  []string `arg:"positional,required" help:"query test files or directories of query test files ending in .json"`
+	URL     string   `arg:"--url" help:"base url of a tier to run the tests on, e.g. http://localhost:2425. If not set, tests are run locally and only mocked operators can read data from outside the query"`
+	Headers []string `arg:"--header" help:"header set on requests to the tier, e.g. 'Authorization: Bearer <key>'"`
+	Verbose bool     `arg:"-v,--verbose" help:"print the output of passing cases as well"`
+}
+
+func main() {
+	var args QueryTestArgs
+	arg.MustParse(&args)
+
+	files, err := testFiles(args.Inputs)
+	if err != nil {
+		log.Fatal(err)
+	}
+	failed := 0
+	for _, name := range files {
+		data, err := os.ReadFile(name)
+		if err != nil {
+			log.Fatal(err)
+		}
+		report, err := run(args, data)
+		if err != nil {
+			log.Fatalf("%s: %v", name, err)
+		}
+		for _, r := range report.Results {
+			status := "PASS"
+			if !r.Passed {
+				status = "FAIL"
+			}
+			fmt.Printf("%s\t%s: %s\n", status, name, r.Name)
+			if !r.Passed || args.Verbose {
+				if r.Output != nil {
+					fmt.Printf("\toutput: %s\n", r.Output)
+				}
+				if len(r.Error) > 0 {
+					fmt.Printf("\terror: %s\n", r.Error)
+				}
+				if len(r.Message) > 0 {
+					fmt.Printf("\t%s\n", r.Message)
+				}
+			}
+		}
+		failed += report.Failed
+	}
+	if failed > 0 {
+		fmt.Printf("%d cases failed\n", failed)
+		os.Exit(1)
+	}
+}
+
+func run(args QueryTestArgs, data []byte) (querytest.Report, error) {
+	if len(args.URL) == 0 {
+		suite, err := querytest.Parse(data)
+		if err != nil {
+			return querytest.Report{}, err
+		}
+		return querytest.Run(context.Background(), tier.Tier{}, suite)
+	}
+	req, err := http.NewRequest(http.MethodPost, strings.TrimSuffix(args.URL, "/")+"/internal/v1/query/test", bytes.NewReader(data))
+	if err != nil {
+		return querytest.Report{}, err
+	}
+	for _, h := range args.Headers {
+		k, v, ok := strings.Cut(h, ":")
+		if !ok {
+			return querytest.Report{}, fmt.Errorf("invalid --header: '%s', expected 'key: value'", h)
+		}
+		req.Header.Add(strings.TrimSpace(k), strings.TrimSpace(v))
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return querytest.Report{}, err
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return querytest.Report{}, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return querytest.Report{}, fmt.Errorf("server error: %d, %s", resp.StatusCode, body)
+	}
+	var ser struct {
+		Passed  int `json:"passed"`
+		Failed  int `json:"failed"`
+		Results []struct {
+			Name    string          `json:"name"`
+			Passed  bool            `json:"passed"`
+			Output  json.RawMessage `json:"output"`
+			Error   string          `json:"error"`
+			Message string          `json:"message"`
+		} `json:"results"`
+	}
+	if err := json.Unmarshal(body, &ser); err != nil {
+		return querytest.Report{}, fmt.Errorf("invalid response: %w", err)
+	}
+	report := querytest.Report{Passed: ser.Passed, Failed: ser.Failed}
+	for _, r := range ser.Results {
+		res := querytest.Result{Name: r.Name, Passed: r.Passed, Error: r.Error, Message: r.Message}
+		if len(r.Output) > 0 {
+			if res.Output, err = value.FromTaggedJSON(r.Output); err != nil {
+				return querytest.Report{}, fmt.Errorf("invalid output of case '%s': %w", r.Name, err)
+			}
+		}
+		report.Results = append(report.Results, res)
+	}
+	return report, nil
+}
+
+func testFiles(inputs []string) ([]string, error) {
+	var files []string
+	for _, input := range inputs {
+		info, err := os.Stat(input)
+		if err != nil {
+			return nil, err
+		}
+		if !info.IsDir() {
+			files = append(files, input)
+			continue
+		}
+		matches, err := filepath.Glob(filepath.Join(input, "*.json"))
+		if err != nil {
+			return nil, err
+		}
+		files = append(files, matches...)
+	}
+	return files, nil
+}
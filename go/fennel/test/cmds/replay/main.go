@@ -0,0 +1,204 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"sort"
+	"strings"
+	"syscall"
+	"time"
+
+	"fennel/lib/replay"
+	"fennel/s3"
+
+	"github.com/alexflint/go-arg"
+)
+
+type ReplayArgs struct {
+	Inputs      []string      `arg:"positional" help:"capture files or directories of capture files, files ending in .jsonl are read in the requests.jsonl format and the rest as gor files"`
+	Bucket      string        `arg:"--bucket" help:"s3 bucket to read the capture files from instead"`
+	Prefix      string        `arg:"--prefix" help:"prefix of the capture files in the s3 bucket, e.g. 2022/06/01"`
+	Region      string        `arg:"--region" default:"us-west-2"`
+	Target      string        `arg:"--target,required" help:"base url of the tier to replay requests against"`
+	Baseline    string        `arg:"--baseline" help:"base url of the tier to compare responses of query requests with"`
+	Endpoints   []string      `arg:"--endpoints" help:"endpoints to replay, e.g. /query, the compared endpoints if not set"`
+	AllowWrites bool          `arg:"--allow-writes" help:"allow replaying endpoints that are not compared, which may write to the target, all endpoints are replayed if --endpoints is not set"`
+	Compared    []string      `arg:"--compared" help:"endpoints whose responses are compared, /query and /run_query if not set"`
+	From        string        `arg:"--from" help:"replay requests captured at or after this time, in RFC3339"`
+	To          string        `arg:"--to" help:"replay requests captured before this time, in RFC3339"`
+	Rate        float64       `arg:"--rate" default:"10" help:"requests replayed per second, 0 for no limit"`
+	Concurrency int           `arg:"--concurrency" default:"16"`
+	Tolerance   float64       `arg:"--tolerance" default:"1e-6" help:"relative difference allowed between numbers in compared responses"`
+	Headers     []string      `arg:"--header" help:"header set on every request, e.g. 'Authorization: Bearer <key>'"`
+	Timeout     time.Duration `arg:"--timeout" default:"30s" help:"timeout of each request"`
+}
+
+func main() {
+	var args ReplayArgs
+	arg.MustParse(&args)
+
+	endpoints, err := replayedEndpoints(args)
+	if err != nil {
+		log.Fatal(err)
+	}
+	filter := replay.Filter{Endpoints: endpoints}
+	if len(args.From) > 0 {
+		if filter.From, err = time.Parse(time.RFC3339, args.From); err != nil {
+			log.Fatalf("invalid --from: %v", err)
+		}
+	}
+	if len(args.To) > 0 {
+		if filter.To, err = time.Parse(time.RFC3339, args.To); err != nil {
+			log.Fatalf("invalid --to: %v", err)
+		}
+	}
+	header := make(http.Header)
+	for _, h := range args.Headers {
+		k, v, ok := strings.Cut(h, ":")
+		if !ok {
+			log.Fatalf("invalid --header: '%s', expected 'key: value'", h)
+		}
+		header.Add(strings.TrimSpace(k), strings.TrimSpace(v))
+	}
+	replayer := replay.Replayer{
+		Target:      args.Target,
+		Baseline:    args.Baseline,
+		Rate:        args.Rate,
+		Concurrency: args.Concurrency,
+		Tolerance:   args.Tolerance,
+		Compared:    args.Compared,
+		Header:      header,
+		Client:      &http.Client{Timeout: args.Timeout},
+	}
+
+	ctx, cancel := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer cancel()
+	reqs := make(chan replay.Request, args.Concurrency)
+	go func() {
+		defer close(reqs)
+		send := func(r replay.Request) error {
+			if !filter.Match(r) {
+				return nil
+			}
+			select {
+			case reqs <- r:
+				return nil
+			case <-ctx.Done():
+				return ctx.Err()
+			}
+		}
+		if err := readAll(args, send); err != nil && ctx.Err() == nil {
+			log.Printf("failed to read captured requests: %v", err)
+			cancel()
+		}
+	}()
+	start := time.Now()
+	report := replayer.Run(ctx, reqs)
+	log.Printf("replayed requests in %s", time.Since(start))
+	if err := report.Write(os.Stdout); err != nil {
+		log.Fatal(err)
+	}
+	if report.Mismatched > 0 || report.Failed > 0 {
+		os.Exit(1)
+	}
+}
+
+// replayedEndpoints returns the endpoints to replay. Only the compared endpoints, which are read-only,
+// are replayed unless writes are allowed explicitly, nil means all endpoints.
+func replayedEndpoints(args ReplayArgs) ([]string, error) {
+	compared := args.Compared
+	if len(compared) == 0 {
+		compared = replay.DefaultCompared
+	}
+	if args.AllowWrites {
+		return args.Endpoints, nil
+	}
+	if len(args.Endpoints) == 0 {
+		return compared, nil
+	}
+	for _, e := range args.Endpoints {
+		found := false
+		for _, c := range compared {
+			found = found || c == e
+		}
+		if !found {
+			return nil, fmt.Errorf("endpoint '%s' is not compared and may write to the target, set --allow-writes to replay it", e)
+		}
+	}
+	return args.Endpoints, nil
+}
+
+// readAll reads the requests of every capture file in order of their names, which start with the
+// time they were captured at
+func readAll(args ReplayArgs, fn func(replay.Request) error) error {
+	if len(args.Bucket) > 0 {
+		client := s3.NewClient(s3.S3Args{Region: args.Region})
+		objects, err := client.ListObjects(args.Bucket, args.Prefix)
+		if err != nil {
+			return fmt.Errorf("failed to list capture files: %w", err)
+		}
+		sort.Slice(objects, func(i, j int) bool { return objects[i].Key < objects[j].Key })
+		for _, obj := range objects {
+			data, err := client.Download(obj.Key, args.Bucket)
+			if err != nil {
+				return fmt.Errorf("failed to download '%s': %w", obj.Key, err)
+			}
+			if err := read(obj.Key, bytes.NewReader(data), fn); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	var files []string
+	for _, input := range args.Inputs {
+		info, err := os.Stat(input)
+		if err != nil {
+			return err
+		}
+		if !info.IsDir() {
+			files = append(files, input)
+			continue
+		}
+		entries, err := os.ReadDir(input)
+		if err != nil {
+			return err
+		}
+		for _, e := range entries {
+			if !e.IsDir() {
+				files = append(files, filepath.Join(input, e.Name()))
+			}
+		}
+	}
+	for _, name := range files {
+		f, err := os.Open(name)
+		if err != nil {
+			return err
+		}
+		err = read(name, f, fn)
+		f.Close()
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func read(name string, r io.Reader, fn func(replay.Request) error) error {
+	var err error
+	if strings.HasSuffix(name, ".jsonl") {
+		err = replay.ReadJSONL(r, fn)
+	} else {
+		err = replay.ReadGor(r, fn)
+	}
+	if err != nil {
+		return fmt.Errorf("%s: %w", name, err)
+	}
+	return nil
+}
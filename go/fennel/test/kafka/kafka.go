@@ -41,10 +41,11 @@ func CreateMockKafka(tierID ftypes.RealmID) (map[string]fkafka.FProducer, tier.K
 			return nil, fmt.Errorf("unrecognized topic: %v", config.Topic)
 		}
 		kConsumer, err := fkafka.MockConsumerConfig{
-			Broker:  broker,
-			Topic:   config.Topic,
-			GroupID: config.GroupID,
-			Scope:   config.Scope,
+			Broker:           broker,
+			Topic:            config.Topic,
+			GroupID:          config.GroupID,
+			Scope:            config.Scope,
+			AssignPartitions: config.AssignPartitions,
 		}.Materialize()
 		if err != nil {
 			return nil, err
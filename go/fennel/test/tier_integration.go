@@ -35,6 +35,8 @@ func Tier(t *testing.T) tier.Tier {
 	var flags tier.TierArgs
 	arg.Parse(&flags)
 	flags.Dev = true
+	// api keys are only issued by the mothership
+	flags.DisableAuth = true
 	flags.PlaneID = tn.PlaneID
 	flags.TierID = ftypes.RealmID(rand.Uint32())
 	err := flags.Valid()
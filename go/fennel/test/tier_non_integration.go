@@ -3,6 +3,7 @@
 package test
 
 import (
+	"context"
 	"fmt"
 	"math/rand"
 	"os"
@@ -12,18 +13,22 @@ import (
 
 	"github.com/raulk/clock"
 
+	"fennel/ann"
 	"fennel/glue"
+	"fennel/lib/apikey"
 	"fennel/lib/ftypes"
 	unleashlib "fennel/lib/unleash"
 	"fennel/modelstore"
 	"fennel/pcache"
 	"fennel/redis"
 	"fennel/s3"
+	"fennel/secrets"
 	"fennel/test/kafka"
 	"fennel/test/nitrous"
 	"fennel/tier"
 
 	"github.com/Unleash/unleash-client-go/v3"
+	"github.com/samber/mo"
 	"github.com/stretchr/testify/assert"
 	"go.uber.org/zap"
 )
@@ -72,6 +77,15 @@ func Tier(t *testing.T) tier.Tier {
 		unleash.WithUrl(faker.Url()))
 	assert.NoError(t, err)
 
+	annClient, err := ann.NewClient(ann.AnnArgs{AnnDir: t.TempDir()}, s3Client)
+	assert.NoError(t, err)
+
+	aggregateDefs := new(sync.Map)
+	invalidations := tier.NewInvalidationBus(PCache, redClient, aggregateDefs, logger)
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+	go invalidations.Run(ctx)
+
 	clock := clock.NewMock()
 	_, nitrousClient := nitrous.NewLocalClient(t, tierID, clock)
 
@@ -80,6 +94,7 @@ func Tier(t *testing.T) tier.Tier {
 		DB:               db,
 		Cache:            Cache,
 		PCache:           PCache,
+		Invalidations:    invalidations,
 		Redis:            redClient,
 		NitrousClient:    nitrousClient,
 		Producers:        producers,
@@ -87,10 +102,14 @@ func Tier(t *testing.T) tier.Tier {
 		NewKafkaConsumer: consumerCreator,
 		S3Client:         s3Client,
 		GlueClient:       glueClient,
+		SecretsClient:    secrets.NewMemClient(),
 		ModelStore:       modelStore,
+		AnnClient:        mo.Some(annClient),
 		Logger:           logger,
-		AggregateDefs:    new(sync.Map),
+		AggregateDefs:    aggregateDefs,
 		RequestLimit:     -1,
+		// api keys are only issued by the mothership
+		Args: tier.TierArgs{ApiKeyArgs: apikey.ApiKeyArgs{DisableAuth: true}},
 	}
 }
 
@@ -222,4 +222,110 @@ var Schema = db.Schema{
 	30: `ALTER TABLE actionlog MODIFY target_id VARCHAR(128);`,
 	31: `ALTER TABLE actionlog MODIFY request_id VARCHAR(128);`,
 	32: `ALTER TABLE profile MODIFY oid VARCHAR(128);`,
+	// ==================== BEGIN Schema for native connectors ======================
+	33: `ALTER TABLE s3_source MODIFY format ENUM('csv','parquet', 'avro', 'json') NOT NULL;`,
+	34: `CREATE TABLE IF NOT EXISTS local_source (
+			name VARCHAR(255) NOT NULL,
+			source_id VARCHAR(255) NOT NULL,
+			dir VARCHAR(1024) NOT NULL,
+			format ENUM('csv', 'json') NOT NULL,
+			delimiter VARCHAR(1) NOT NULL DEFAULT ',',
+			last_updated timestamp default now() on update now(),
+			PRIMARY KEY (name),
+			FOREIGN KEY (name) REFERENCES source(name) ON DELETE CASCADE
+		);`,
+	35: `ALTER TABLE postgres_source ADD COLUMN credentials_secret VARCHAR(255) NOT NULL DEFAULT '';`,
+	// ==================== END Schema for native connectors ======================
+	// ==================== BEGIN Schema for schema registry ======================
+	36: `CREATE TABLE IF NOT EXISTS schema_registry (
+			name VARCHAR(512) NOT NULL,
+			version INT UNSIGNED NOT NULL,
+			schema_ser BLOB NOT NULL,
+			last_updated timestamp default now() on update now(),
+			PRIMARY KEY (name)
+		);`,
+	37: `CREATE TABLE IF NOT EXISTS schema_quarantine (
+			id BIGINT UNSIGNED NOT NULL AUTO_INCREMENT,
+			name VARCHAR(512) NOT NULL,
+			record BLOB NOT NULL,
+			error TEXT NOT NULL,
+			timestamp BIGINT UNSIGNED NOT NULL,
+			PRIMARY KEY (id),
+			INDEX (name, timestamp)
+		);`,
+	// ==================== END Schema for schema registry ======================
+	38: `CREATE TABLE IF NOT EXISTS api_key (
+			key_id VARCHAR(32) NOT NULL,
+			hash VARCHAR(64) NOT NULL,
+			scopes VARCHAR(255) NOT NULL,
+			expires_at BIGINT NOT NULL DEFAULT 0,
+			PRIMARY KEY (key_id)
+		);`,
+	39: `ALTER TABLE query_ast ADD COLUMN params_ser BLOB;`,
+	// every stored query is an immutable version, existing queries become their first version
+	40: `ALTER TABLE query_ast ADD COLUMN version INT UNSIGNED NOT NULL DEFAULT 1;`,
+	// names were not unique before queries were versioned, so queries stored under the same name are
+	// numbered in the order they were stored before versions are made unique
+	41: `UPDATE query_ast q JOIN (
+			SELECT a.query_id, COUNT(*) AS version FROM query_ast a
+			JOIN query_ast b ON b.name = a.name AND b.query_id <= a.query_id
+			GROUP BY a.query_id
+		) v ON v.query_id = q.query_id SET q.version = v.version;
+		ALTER TABLE query_ast ADD UNIQUE INDEX (name, version);`,
+	// query_alias keeps every change of an alias, the current target is the row with the largest id.
+	// Queries stored before they were versioned keep running the query that was stored first under
+	// their name, which is the one that was run until then.
+	42: `CREATE TABLE IF NOT EXISTS query_alias (
+			id BIGINT UNSIGNED NOT NULL AUTO_INCREMENT,
+			query_name VARCHAR(64) NOT NULL,
+			alias VARCHAR(64) NOT NULL,
+			version INT UNSIGNED NOT NULL DEFAULT 0,
+			split_ser BLOB,
+			updated_at BIGINT NOT NULL,
+			PRIMARY KEY (id),
+			INDEX (query_name, alias)
+		);
+		INSERT INTO query_alias (query_name, alias, version, updated_at)
+			SELECT name, 'prod', 1, UNIX_TIMESTAMP() FROM query_ast WHERE version = 1;`,
+	// profile_history keeps old values of profiles whose policy has a history retention
+	43: `CREATE TABLE IF NOT EXISTS profile_history (
+			otype VARCHAR(255) NOT NULL,
+			oid VARCHAR(128) NOT NULL,
+			zkey VARCHAR(255) NOT NULL,
+			version BIGINT UNSIGNED NOT NULL,
+			value BLOB NOT NULL,
+			PRIMARY KEY (otype, oid, zkey, version)
+		);`,
+	44: `CREATE TABLE IF NOT EXISTS profile_policy (
+			otype VARCHAR(255) NOT NULL,
+			zkey VARCHAR(255) NOT NULL,
+			history_retention BIGINT UNSIGNED NOT NULL DEFAULT 0,
+			ttl BIGINT UNSIGNED NOT NULL DEFAULT 0,
+			PRIMARY KEY (otype, zkey)
+		);`,
+	45: `CREATE TABLE IF NOT EXISTS profile_job (
+			id BIGINT UNSIGNED NOT NULL AUTO_INCREMENT,
+			type VARCHAR(32) NOT NULL,
+			format VARCHAR(32) NOT NULL,
+			location VARCHAR(1024) NOT NULL,
+			otype VARCHAR(255) NOT NULL DEFAULT '',
+			status VARCHAR(32) NOT NULL,
+			rows_read BIGINT UNSIGNED NOT NULL DEFAULT 0,
+			rows_written BIGINT UNSIGNED NOT NULL DEFAULT 0,
+			rows_failed BIGINT UNSIGNED NOT NULL DEFAULT 0,
+			error_samples BLOB NOT NULL,
+			error TEXT NOT NULL,
+			created_at BIGINT NOT NULL,
+			updated_at BIGINT NOT NULL,
+			lease_until BIGINT NOT NULL DEFAULT 0,
+			PRIMARY KEY (id),
+			INDEX (status)
+		);`,
+	// query_budget keeps the budget of stored queries that override the default budget of the tier
+	46: `CREATE TABLE IF NOT EXISTS query_budget (
+			query_name VARCHAR(64) NOT NULL,
+			budget_ser BLOB NOT NULL,
+			updated_at BIGINT NOT NULL,
+			PRIMARY KEY (query_name)
+		);`,
 }
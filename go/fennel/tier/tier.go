@@ -12,6 +12,7 @@ import (
 	"github.com/raulk/clock"
 
 	"fennel/airbyte"
+	"fennel/ann"
 	"fennel/eventbridge"
 	"fennel/lib/instancemetadata"
 
@@ -21,9 +22,14 @@ import (
 	"fennel/glue"
 	libkafka "fennel/kafka"
 	"fennel/lib/aggregate"
+	"fennel/lib/apikey"
+	"fennel/lib/budget"
 	"fennel/lib/cache"
+	"fennel/lib/dedup"
 	"fennel/lib/ftypes"
 	libnitrous "fennel/lib/nitrous"
+	"fennel/lib/offline"
+	"fennel/lib/ratelimit"
 	"fennel/lib/timer"
 	unleashlib "fennel/lib/unleash"
 	"fennel/milvus"
@@ -34,6 +40,7 @@ import (
 	"fennel/resource"
 	"fennel/s3"
 	"fennel/sagemaker"
+	"fennel/secrets"
 
 	"github.com/samber/mo"
 	"go.uber.org/zap"
@@ -46,8 +53,15 @@ type TierArgs struct {
 	modelstore.ModelStoreArgs   `json:"modelstore_._model_store_args"`
 	glue.GlueArgs               `json:"glue_._glue_args"`
 	eventbridge.EventBridgeArgs `json:"eventbridge_._event_bridge_args"`
+	secrets.SecretsArgs         `json:"secrets_._secrets_args"`
 	timer.TracerArgs            `json:"tracer_._tracer_args"`
 	milvus.MilvusArgs           `json:"milvus_._milvus_args"`
+	ann.AnnArgs                 `json:"ann_._ann_args"`
+	ratelimit.RateLimitArgs     `json:"ratelimit_._rate_limit_args"`
+	dedup.DedupArgs             `json:"dedup_._dedup_args"`
+	offline.OfflineArgs         `json:"offline_._offline_args"`
+	budget.BudgetArgs           `json:"budget_._budget_args"`
+	apikey.ApiKeyArgs           `json:"apikey_._api_key_args"`
 
 	Region string `arg:"--aws-region,env:AWS_REGION" json:"aws_region,omitempty"`
 	// MSK configuration
@@ -71,6 +85,12 @@ type TierArgs struct {
 	OfflineAggBucket string         `arg:"--offline-agg-bucket,env:OFFLINE_AGG_BUCKET" json:"offline_agg_bucket,omitempty"`
 	UnleashEndpoint  string         `arg:"--unleash-endpoint,env:UNLEASH_ENDPOINT" json:"unleash_endpoint,omitempty"`
 	AirbyteServer    string         `arg:"--airbyte-server,env:AIRBYTE_SERVER_ADDRESS" json:"airbyte_server,omitempty"`
+	// ProfileJobDir is the directory under which profile jobs can read and write local files, jobs
+	// can only use S3 locations if it is not set
+	ProfileJobDir string `arg:"--profile-job-dir,env:PROFILE_JOB_DIR" json:"profile_job_dir,omitempty"`
+	// LocalSourceDir is the directory under which Local sources can read files, Local sources can
+	// not be created if it is not set
+	LocalSourceDir string `arg:"--local-source-dir,env:LOCAL_SOURCE_DIR" json:"local_source_dir,omitempty"`
 
 	InstanceMetadataServiceAddr string `arg:"--instance-metadata-service-addr,env:INSTANCE_METADATA_SERVICE_ADDR" json:"instance_metadata_service_addr,omitempty"`
 }
@@ -139,14 +159,18 @@ type Tier struct {
 	S3Client          s3.Client
 	GlueClient        glue.GlueClient
 	EventBridgeClient eventbridge.Client
+	SecretsClient     secrets.Client
 	AirbyteClient     mo.Option[airbyte.Client]
 	SagemakerClient   sagemaker.SMClient
 	MilvusClient      mo.Option[milvus.Client]
+	AnnClient         mo.Option[ann.Client]
 	NitrousClient     nitrous.NitrousClient
 	ModelStore        *modelstore.ModelStore
 	Args              TierArgs
 	// In-process caches for the tier, has very short TTL ( order of minutes )
 	PCache pcache.PCache
+	// Invalidations of the in-process caches, broadcast to every process of the tier
+	Invalidations *pcache.Bus
 	// Cache of aggregate name to aggregate definitions - key type is string,
 	// value type is aggregate.Aggregate. Consider change this to something
 	// that wrap sync.Map and exposes a nicer API.
@@ -324,10 +348,21 @@ func CreateFromArgs(args *TierArgs) (tier Tier, err error) {
 		return tier, fmt.Errorf("failed to create sagemaker client: %v", err)
 	}
 
-	logger.Info("Creating AWS clients for S3, Glue, and ModelStore")
+	logger.Info("Creating AWS clients for S3, Glue, Secrets Manager and ModelStore")
 	s3client := s3.NewClient(args.S3Args)
 	glueclient := glue.NewGlueClient(args.GlueArgs)
 	eventbridgeclient := eventbridge.NewClient(args.EventBridgeArgs)
+	secretsclient := secrets.NewClient(args.SecretsArgs)
+
+	annClient := mo.None[ann.Client]()
+	if args.AnnArgs.AnnBucket != "" || args.AnnArgs.AnnDir != "" {
+		logger.Info("Loading embedded knn indices", zap.String("bucket", args.AnnArgs.AnnBucket), zap.String("dir", args.AnnArgs.AnnDir))
+		client, err := ann.NewClient(args.AnnArgs, s3client)
+		if err != nil {
+			return tier, fmt.Errorf("failed to create embedded knn client: %v", err)
+		}
+		annClient = mo.Some(client)
+	}
 
 	modelStore := modelstore.NewModelStore(args.ModelStoreArgs, tierID)
 
@@ -404,6 +439,10 @@ func CreateFromArgs(args *TierArgs) (tier Tier, err error) {
 	aggregateDefs := new(sync.Map)
 	populateAggregateCache(aggregateDefs, sqlConn, logger)
 
+	logger.Info("Subscribing to process-level cache invalidations")
+	invalidations := NewInvalidationBus(pCache, redisClient.(redis.Client), aggregateDefs, logger)
+	go invalidations.Run(context.Background())
+
 	return Tier{
 		DB:                sqlConn.(db.Connection),
 		Redis:             redisClient.(redis.Client),
@@ -413,13 +452,16 @@ func CreateFromArgs(args *TierArgs) (tier Tier, err error) {
 		Logger:            logger,
 		Cache:             redis.NewCache(cacheClient.(redis.Client)),
 		PCache:            pCache,
+		Invalidations:     invalidations,
 		NewKafkaConsumer:  consumerCreator,
 		SagemakerClient:   smclient,
 		NitrousClient:     nitrousClient,
 		S3Client:          s3client,
 		GlueClient:        glueclient,
 		EventBridgeClient: eventbridgeclient,
+		SecretsClient:     secretsclient,
 		MilvusClient:      milvusClient,
+		AnnClient:         annClient,
 		AirbyteClient:     airbyteClient,
 		ModelStore:        modelStore,
 		Args:              *args,
@@ -458,6 +500,23 @@ func CreateKafka(tierID, planeID ftypes.RealmID, server, username, password, sas
 	return producers, nil
 }
 
+// NewInvalidationBus returns a bus that invalidates the process-level cache and the aggregate defs
+// cached by the tier. The caller is expected to run it.
+func NewInvalidationBus(pCache pcache.PCache, client redis.Client, aggregateDefs *sync.Map, logger *zap.Logger) *pcache.Bus {
+	bus := pcache.NewBus(pCache, client, logger)
+	bus.OnInvalidate(aggregate.DEFS_CACHE_NAMESPACE, func(key string) {
+		if len(key) == 0 {
+			aggregateDefs.Range(func(k, _ interface{}) bool {
+				aggregateDefs.Delete(k)
+				return true
+			})
+			return
+		}
+		aggregateDefs.Delete(ftypes.AggName(key))
+	})
+	return bus
+}
+
 // populateAggregateCache retrieves all active aggregates and sets them on the cache
 //
 // NOTE: this works on best effort basis i.e. does not return an error and may not update the cache at all